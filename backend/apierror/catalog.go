@@ -0,0 +1,133 @@
+package apierror
+
+import "net/http"
+
+// Code 稳定的错误码，前端和自动化脚本据此判断错误类型，不应依赖 message 文本
+type Code string
+
+// Category 错误分类
+type Category string
+
+const (
+	CategoryInvalid     Category = "invalid_request" // 请求参数或内容有误
+	CategoryAuth        Category = "auth"            // 认证和访问控制
+	CategoryNotFound    Category = "not_found"       // 资源不存在
+	CategoryConflict    Category = "conflict"        // 与当前状态冲突
+	CategoryEnvironment Category = "environment"     // 运行环境不满足（平台、核心、权限）
+	CategoryUpstream    Category = "upstream"        // 外部服务或远程实例出错
+	CategoryInternal    Category = "internal"        // 服务内部错误
+)
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeValidation       Code = "validation_failed"
+	CodePayloadTooLarge  Code = "payload_too_large"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeReadOnlyToken    Code = "read_only_token"
+	CodeAccessDenied     Code = "access_denied"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodeEditConflict     Code = "edit_conflict"
+	CodeCoreRunning      Code = "core_already_running"
+	CodeCoreNotRunning   Code = "core_not_running"
+	CodePortInUse        Code = "port_in_use"
+	CodeBusy             Code = "operation_in_progress"
+	CodeCoreNotInstalled Code = "core_not_installed"
+	CodeUnsupported      Code = "unsupported_platform"
+	CodePermission       Code = "permission_denied"
+	CodeDataLocked       Code = "data_locked"
+	CodeUpstream         Code = "upstream_error"
+	CodeUnavailable      Code = "service_unavailable"
+	CodeTimeout          Code = "timeout"
+	CodeInternal         Code = "internal_error"
+)
+
+// Entry 错误码目录中的一项
+type Entry struct {
+	Code     Code     `json:"code"`
+	Category Category `json:"category"`
+	Status   int      `json:"status"` // 通常对应的 HTTP 状态码
+	Hint     string   `json:"hint"`   // 处理建议
+}
+
+// catalog 错误码目录，新增错误码时在此登记，已发布的错误码不要改名
+var catalog = []Entry{
+	{CodeBadRequest, CategoryInvalid, http.StatusBadRequest, "检查请求参数后重试"},
+	{CodeValidation, CategoryInvalid, http.StatusBadRequest, "根据 details 中列出的问题修改后重新提交"},
+	{CodePayloadTooLarge, CategoryInvalid, http.StatusRequestEntityTooLarge, "减小请求体，或在服务配置中调大上限"},
+	{CodeRateLimited, CategoryInvalid, http.StatusTooManyRequests, "按 Retry-After 等待后重试"},
+	{CodeUnauthorized, CategoryAuth, http.StatusUnauthorized, "重新登录或检查 API 令牌"},
+	{CodeForbidden, CategoryAuth, http.StatusForbidden, "使用有权限的账号或令牌"},
+	{CodeReadOnlyToken, CategoryAuth, http.StatusForbidden, "修改操作需要 admin 权限的令牌"},
+	{CodeAccessDenied, CategoryAuth, http.StatusForbidden, "检查管理界面的来源地址白名单和客户端证书设置"},
+	{CodeNotFound, CategoryNotFound, http.StatusNotFound, "刷新列表，资源可能已被删除"},
+	{CodeConflict, CategoryConflict, http.StatusConflict, "刷新当前状态后重试"},
+	{CodeEditConflict, CategoryConflict, http.StatusPreconditionFailed, "配置已在其他页面修改，刷新后重新编辑"},
+	{CodeCoreRunning, CategoryConflict, http.StatusConflict, "核心已在运行，如需应用新配置请使用重启"},
+	{CodeCoreNotRunning, CategoryConflict, http.StatusConflict, "先启动核心"},
+	{CodePortInUse, CategoryConflict, http.StatusConflict, "结束占用端口的进程，或在设置中修改端口"},
+	{CodeBusy, CategoryConflict, http.StatusConflict, "等待当前操作完成后重试"},
+	{CodeCoreNotInstalled, CategoryEnvironment, http.StatusBadRequest, "在核心管理页面下载核心"},
+	{CodeUnsupported, CategoryEnvironment, http.StatusBadRequest, "该功能仅在 Linux 上可用"},
+	{CodePermission, CategoryEnvironment, http.StatusInternalServerError, "以 root 运行或授予所需的权限（如 CAP_NET_ADMIN）"},
+	{CodeDataLocked, CategoryEnvironment, http.StatusServiceUnavailable, "设置 PROXYSTATION_MASTER_PASSWORD 环境变量后重启服务"},
+	{CodeUpstream, CategoryUpstream, http.StatusBadGateway, "检查网络连接和远程服务状态后重试"},
+	{CodeUnavailable, CategoryUpstream, http.StatusServiceUnavailable, "核心或依赖的服务未就绪，确认核心已启动后重试"},
+	{CodeTimeout, CategoryUpstream, http.StatusGatewayTimeout, "检查网络连接后重试"},
+	{CodeInternal, CategoryInternal, http.StatusInternalServerError, "查看服务日志了解详细原因"},
+}
+
+var catalogIndex = func() map[Code]Entry {
+	index := make(map[Code]Entry, len(catalog))
+	for _, e := range catalog {
+		index[e.Code] = e
+	}
+	return index
+}()
+
+// Catalog 返回错误码目录
+func Catalog() []Entry {
+	return append([]Entry(nil), catalog...)
+}
+
+// Lookup 查找错误码，未登记时按内部错误处理
+func Lookup(code Code) Entry {
+	if e, ok := catalogIndex[code]; ok {
+		return e
+	}
+	return Entry{Code: code, Category: CategoryInternal, Status: http.StatusInternalServerError}
+}
+
+// codeForStatus 未指定错误码时根据 HTTP 状态码推断
+func codeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodeEditConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeUpstream
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
@@ -0,0 +1,125 @@
+// Package apierror 统一的 API 错误响应
+//
+// 错误响应保持 {code, message} 格式（code 非 0 表示失败），并增加 error 对象：
+//
+//	{"code": 1, "message": "核心文件未找到，请先下载核心",
+//	 "error": {"code": "core_not_installed", "category": "environment", "hint": "在核心管理页面下载核心"}}
+//
+// 前端和自动化脚本应根据 error.code 判断错误类型，message 仅用于展示
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error 带错误码的错误，可在 service 层返回，由 handler 原样转换为响应
+type Error struct {
+	Code    Code
+	Message string
+	Details interface{} // 可选的详细信息，如校验错误列表、端口冲突列表
+	Err     error       // 原始错误
+}
+
+// New 创建带错误码的错误
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap 为已有错误附加错误码，message 使用原始错误的文本
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// WithDetails 返回附加了详细信息的副本（New 创建的错误可能作为包级变量共享）
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Detail 响应中的 error 对象
+type Detail struct {
+	Code     Code        `json:"code"`
+	Category Category    `json:"category"`
+	Hint     string      `json:"hint,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// From 将任意错误转换为带错误码的错误
+// 未携带错误码时先识别常见的系统错误，再根据 HTTP 状态码推断
+func From(err error, status int) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Message != err.Error() {
+			// 被 fmt.Errorf 包装过，保留完整的错误文本
+			wrapped := *apiErr
+			wrapped.Message = err.Error()
+			return &wrapped
+		}
+		return apiErr
+	}
+
+	code := codeForStatus(status)
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		code = CodePortInUse
+	case errors.Is(err, os.ErrPermission):
+		code = CodePermission
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		code = CodeTimeout
+	}
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// Body 生成错误响应体
+// 校验失败沿用 code 2；details 同时放在 data 中，兼容按 data 读取错误列表的旧版前端
+func Body(err *Error) gin.H {
+	entry := Lookup(err.Code)
+	body := gin.H{
+		"code":    1,
+		"message": err.Message,
+		"error": Detail{
+			Code:     err.Code,
+			Category: entry.Category,
+			Hint:     entry.Hint,
+			Details:  err.Details,
+		},
+	}
+	if err.Code == CodeValidation {
+		body["code"] = 2
+	}
+	if err.Details != nil {
+		body["data"] = err.Details
+	}
+	return body
+}
+
+// Respond 返回错误响应
+func Respond(c *gin.Context, status int, err error) {
+	c.JSON(status, Body(From(err, status)))
+}
+
+// Message 返回错误响应，错误码根据 HTTP 状态码推断
+func Message(c *gin.Context, status int, message string) {
+	c.JSON(status, Body(New(codeForStatus(status), message)))
+}
+
+// Abort 返回错误响应并中止后续处理（用于中间件）
+func Abort(c *gin.Context, status int, err error) {
+	c.AbortWithStatusJSON(status, Body(From(err, status)))
+}
@@ -0,0 +1,293 @@
+// Package clashapi Clash 兼容 API 客户端，Mihomo 和 sing-box（clash_api）共用
+package clashapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// transport 所有客户端共享的连接池
+// 流式接口（/traffic、/logs）不设整体超时，普通请求通过 context 控制超时
+var transport = &http.Transport{
+	DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+	ResponseHeaderTimeout: 30 * time.Second,
+	IdleConnTimeout:       90 * time.Second,
+	MaxIdleConnsPerHost:   10,
+}
+
+var httpClient = &http.Client{Transport: transport}
+
+// defaultTimeout 普通请求的默认超时（纳秒），调用方的 context 已有截止时间时不使用
+var defaultTimeout atomic.Int64
+
+func init() {
+	defaultTimeout.Store(int64(5 * time.Second))
+}
+
+// SetDefaultTimeout 设置普通请求的默认超时，<= 0 时保持原值
+func SetDefaultTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		defaultTimeout.Store(int64(timeout))
+	}
+}
+
+// APIError 核心 API 返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("核心 API 返回 %d: %s", e.StatusCode, e.Message)
+}
+
+// Client Clash API 客户端
+type Client struct {
+	addr   string
+	secret string
+}
+
+// New 创建客户端，addr 为 host:port
+func New(addr, secret string) *Client {
+	return &Client{addr: addr, secret: secret}
+}
+
+// Addr API 地址（host:port）
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Transport 共享的连接池，供反向代理使用
+func (c *Client) Transport() http.RoundTripper {
+	return transport
+}
+
+// Header 认证请求头
+func (c *Client) Header() http.Header {
+	header := http.Header{}
+	if c.secret != "" {
+		header.Set("Authorization", "Bearer "+c.secret)
+	}
+	return header
+}
+
+// Do 发送原始请求，调用方负责关闭响应体
+// body 为 io.Reader 时原样发送，其他非 nil 值编码为 JSON
+func (c *Client) Do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+c.addr+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.Header()
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return httpClient.Do(req)
+}
+
+// doJSON 发送请求并解析 JSON 响应，out 为 nil 时丢弃响应体
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(defaultTimeout.Load()))
+		defer cancel()
+	}
+
+	resp, err := c.Do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readAPIError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析核心 API 响应失败: %w", err)
+	}
+	return nil
+}
+
+// readAPIError 读取错误响应，优先使用 JSON 中的 message
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var body struct {
+		Message string `json:"message"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		msg = body.Message
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+// Stream 打开流式接口（/traffic、/logs、/memory 等），调用方负责关闭响应体
+func (c *Client) Stream(ctx context.Context, path, rawQuery string) (*http.Response, error) {
+	if rawQuery != "" {
+		path += "?" + rawQuery
+	}
+	resp, err := c.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+// Version 获取核心版本
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	if err := c.doJSON(ctx, http.MethodGet, "/version", nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Proxies 获取所有节点和代理组
+func (c *Client) Proxies(ctx context.Context) (map[string]Proxy, error) {
+	var body struct {
+		Proxies map[string]Proxy `json:"proxies"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/proxies", nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Proxies, nil
+}
+
+// SelectProxy 切换代理组选择的节点
+func (c *Client) SelectProxy(ctx context.Context, group, name string) error {
+	return c.doJSON(ctx, http.MethodPut, "/proxies/"+url.PathEscape(group), map[string]string{"name": name}, nil)
+}
+
+// ProxyDelay 测试节点延迟，返回毫秒
+func (c *Client) ProxyDelay(ctx context.Context, name, testURL string, timeout time.Duration) (int, error) {
+	// HTTP 超时比测速超时多留出余量
+	ctx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
+	defer cancel()
+
+	path := fmt.Sprintf("/proxies/%s/delay?url=%s&timeout=%s",
+		url.PathEscape(name), url.QueryEscape(testURL), strconv.FormatInt(timeout.Milliseconds(), 10))
+	var body struct {
+		Delay int `json:"delay"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &body); err != nil {
+		return 0, err
+	}
+	return body.Delay, nil
+}
+
+// Connections 获取活动连接
+func (c *Client) Connections(ctx context.Context) (*ConnectionsSnapshot, error) {
+	var snapshot ConnectionsSnapshot
+	if err := c.doJSON(ctx, http.MethodGet, "/connections", nil, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Connections == nil {
+		snapshot.Connections = []Connection{}
+	}
+	return &snapshot, nil
+}
+
+// CloseConnection 关闭指定连接
+func (c *Client) CloseConnection(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/connections/"+url.PathEscape(id), nil, nil)
+}
+
+// CloseAllConnections 关闭所有连接
+func (c *Client) CloseAllConnections(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodDelete, "/connections", nil, nil)
+}
+
+// ProxyProviders 获取代理集合
+func (c *Client) ProxyProviders(ctx context.Context) (map[string]ProxyProvider, error) {
+	var body struct {
+		Providers map[string]ProxyProvider `json:"providers"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/providers/proxies", nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Providers, nil
+}
+
+// UpdateProxyProvider 重新拉取代理集合
+func (c *Client) UpdateProxyProvider(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodPut, "/providers/proxies/"+url.PathEscape(name), nil, nil)
+}
+
+// HealthCheckProxyProvider 对代理集合执行健康检查
+func (c *Client) HealthCheckProxyProvider(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodGet, "/providers/proxies/"+url.PathEscape(name)+"/healthcheck", nil, nil)
+}
+
+// RuleProviders 获取规则集合
+func (c *Client) RuleProviders(ctx context.Context) (map[string]RuleProvider, error) {
+	var body struct {
+		Providers map[string]RuleProvider `json:"providers"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/providers/rules", nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Providers, nil
+}
+
+// UpdateRuleProvider 重新拉取规则集合
+func (c *Client) UpdateRuleProvider(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodPut, "/providers/rules/"+url.PathEscape(name), nil, nil)
+}
+
+// DNSQuery 通过核心的 DNS 模块解析域名（仅 Mihomo 支持），qtype 为 A、AAAA 等
+func (c *Client) DNSQuery(ctx context.Context, name, qtype string) (*DNSQueryResult, error) {
+	query := url.Values{"name": {name}}
+	if qtype != "" {
+		query.Set("type", qtype)
+	}
+	var result DNSQueryResult
+	if err := c.doJSON(ctx, http.MethodGet, "/dns/query?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReloadConfig 通过 PUT /configs 加载配置内容（Mihomo 支持 payload 方式）
+func (c *Client) ReloadConfig(ctx context.Context, payload string, force bool) error {
+	path := "/configs"
+	if force {
+		path += "?force=true"
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+	}
+	return c.doJSON(ctx, http.MethodPut, path, map[string]string{"payload": payload}, nil)
+}
@@ -0,0 +1,137 @@
+package clashapi
+
+// Version 核心版本信息（GET /version）
+type Version struct {
+	Version string `json:"version"`
+	Meta    bool   `json:"meta,omitempty"`    // Mihomo
+	Premium bool   `json:"premium,omitempty"` // Clash Premium / sing-box
+}
+
+// DelayHistory 单次延迟测试记录
+type DelayHistory struct {
+	Time  string `json:"time"`
+	Delay int    `json:"delay"`
+}
+
+// Proxy 节点或代理组（GET /proxies）
+type Proxy struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Now     string         `json:"now,omitempty"` // 代理组当前选择
+	All     []string       `json:"all,omitempty"` // 代理组成员
+	UDP     bool           `json:"udp"`
+	Hidden  bool           `json:"hidden,omitempty"`
+	History []DelayHistory `json:"history"`
+}
+
+// groupTypes 代理组及内置出站类型（Mihomo 与 sing-box 的 Clash API 返回值）
+var groupTypes = map[string]bool{
+	"Selector":    true,
+	"URLTest":     true,
+	"Fallback":    true,
+	"LoadBalance": true,
+	"Relay":       true,
+	"Direct":      true,
+	"Reject":      true,
+	"RejectDrop":  true,
+	"Compatible":  true,
+	"Pass":        true,
+	"Dns":         true,
+	"Block":       true,
+}
+
+// IsNode 是否为实际节点（排除代理组和内置出站）
+func (p Proxy) IsNode() bool {
+	return !groupTypes[p.Type]
+}
+
+// ConnectionMetadata 连接元数据
+type ConnectionMetadata struct {
+	Network           string `json:"network"`
+	Type              string `json:"type"`
+	SourceIP          string `json:"sourceIP"`
+	DestinationIP     string `json:"destinationIP"`
+	SourcePort        string `json:"sourcePort"`
+	DestinationPort   string `json:"destinationPort"`
+	Host              string `json:"host"`
+	DNSMode           string `json:"dnsMode"`
+	Process           string `json:"process"`
+	ProcessPath       string `json:"processPath"`
+	SpecialProxy      string `json:"specialProxy,omitempty"`
+	SpecialRules      string `json:"specialRules,omitempty"`
+	RemoteDestination string `json:"remoteDestination,omitempty"`
+	SniffHost         string `json:"sniffHost,omitempty"`
+}
+
+// Connection 单条活动连接
+type Connection struct {
+	ID          string             `json:"id"`
+	Metadata    ConnectionMetadata `json:"metadata"`
+	Upload      int64              `json:"upload"`
+	Download    int64              `json:"download"`
+	Start       string             `json:"start"`
+	Chains      []string           `json:"chains"`
+	Rule        string             `json:"rule"`
+	RulePayload string             `json:"rulePayload"`
+	PID         int                `json:"pid,omitempty"` // 核心不返回，由调用方补充
+	Geo         *GeoInfo           `json:"geo,omitempty"` // 目标地址归属，核心不返回，由调用方补充
+}
+
+// GeoInfo IP 归属信息
+type GeoInfo struct {
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"` // ISO 3166 国家代码（大写）
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"asOrg,omitempty"`
+}
+
+// ConnectionsSnapshot 连接列表快照（GET /connections）
+type ConnectionsSnapshot struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+}
+
+// ProxyProvider 代理集合（GET /providers/proxies）
+type ProxyProvider struct {
+	Name             string            `json:"name"`
+	Type             string            `json:"type"`
+	VehicleType      string            `json:"vehicleType"` // HTTP / File / Inline / Compatible
+	Proxies          []Proxy           `json:"proxies"`
+	TestURL          string            `json:"testUrl,omitempty"`
+	UpdatedAt        string            `json:"updatedAt,omitempty"`
+	SubscriptionInfo *SubscriptionInfo `json:"subscriptionInfo,omitempty"`
+}
+
+// SubscriptionInfo 代理集合订阅的流量信息
+type SubscriptionInfo struct {
+	Upload   int64 `json:"Upload"`
+	Download int64 `json:"Download"`
+	Total    int64 `json:"Total"`
+	Expire   int64 `json:"Expire"`
+}
+
+// RuleProvider 规则集合（GET /providers/rules）
+type RuleProvider struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	VehicleType string `json:"vehicleType"`
+	Behavior    string `json:"behavior"`
+	Format      string `json:"format,omitempty"`
+	RuleCount   int    `json:"ruleCount"`
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+}
+
+// DNSAnswer DNS 查询结果中的单条记录
+type DNSAnswer struct {
+	Name string `json:"name"`
+	Type int    `json:"type"` // 1=A, 5=CNAME, 28=AAAA
+	TTL  int    `json:"TTL"`
+	Data string `json:"data"`
+}
+
+// DNSQueryResult DNS 查询结果（GET /dns/query）
+type DNSQueryResult struct {
+	Status int         `json:"Status"` // RCODE，0 表示成功
+	Answer []DNSAnswer `json:"Answer"`
+}
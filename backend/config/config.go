@@ -16,6 +16,8 @@ type Config struct {
 	Proxy    ProxyConfig    `yaml:"proxy"`
 	Log      LogConfig      `yaml:"log"`
 	Security SecurityConfig `yaml:"security"`
+	Limits   LimitsConfig   `yaml:"limits"`
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
 }
 
 // ServerConfig HTTP 服务器配置
@@ -54,6 +56,22 @@ type SecurityConfig struct {
 	Password string `yaml:"password"`
 }
 
+// LimitsConfig API 限流和请求大小限制
+type LimitsConfig struct {
+	RateLimit      float64 `yaml:"rate_limit"`      // 每个 IP 每秒请求数，0 表示不限流
+	Burst          int     `yaml:"burst"`           // 突发请求数
+	MaxBodySize    int64   `yaml:"max_body_size"`   // 请求体大小上限（字节），0 表示不限制
+	MaxUploadSize  int64   `yaml:"max_upload_size"` // 上传接口（如恢复备份）的请求体大小上限（字节）
+	ExemptLoopback bool    `yaml:"exempt_loopback"` // 本机请求不限流
+}
+
+// TimeoutsConfig 外部请求超时（秒），0 表示使用默认值
+type TimeoutsConfig struct {
+	CoreAPI int `yaml:"core_api"` // 核心 API（Mihomo / sing-box clash_api）普通请求
+	Fetch   int `yaml:"fetch"`    // 下载订阅等远程资源
+	Dial    int `yaml:"dial"`     // 建立连接
+}
+
 // IsDevMode 检测是否为开发模式
 // 开发模式：通过环境变量 DEV_MODE=1 或 go run 运行
 func IsDevMode() bool {
@@ -125,6 +143,18 @@ func DefaultConfig() *Config {
 			Username: "admin",
 			Password: "admin123",
 		},
+		Limits: LimitsConfig{
+			RateLimit:      30,
+			Burst:          100,
+			MaxBodySize:    4 << 20,
+			MaxUploadSize:  64 << 20,
+			ExemptLoopback: true,
+		},
+		Timeouts: TimeoutsConfig{
+			CoreAPI: 5,
+			Fetch:   30,
+			Dial:    10,
+		},
 	}
 }
 
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 事件类型
+const (
+	CoreStarted        = "core.started"        // 核心启动成功
+	CoreStopped        = "core.stopped"        // 核心停止（主动停止或崩溃后放弃重启）
+	CoreCrashed        = "core.crashed"        // 核心异常退出
+	AutoStartProgress  = "core.autostart"      // 开机自动启动的阶段进度
+	ConfigGenerated    = "config.generated"    // 配置重新生成
+	TransparentChanged = "transparent.changed" // 透明代理模式变化
+	TransparentRevert  = "transparent.revert"  // 看门狗检测到断网，透明代理已自动关闭
+	NodeHealthChanged  = "node.health.changed" // 节点可用状态变化
+	NodesAllDead       = "node.all_dead"       // 健康检查后所有节点均不可用
+	SubscriptionFailed = "subscription.failed" // 订阅更新失败
+)
+
+const (
+	// subscriberBuffer 每个订阅者的缓冲事件数，消费过慢时丢弃新事件
+	subscriberBuffer = 64
+	// recentEvents 保留的最近事件数，用于断线重连时通过 Last-Event-ID 补发
+	recentEvents = 100
+	// heartbeatInterval 心跳间隔，避免代理或浏览器断开空闲连接
+	heartbeatInterval = 15 * time.Second
+)
+
+// Event 状态变化事件
+type Event struct {
+	ID   int64       `json:"id"`
+	Type string      `json:"type"`
+	Time string      `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Bus 事件总线，向所有订阅者广播事件
+type Bus struct {
+	mu          sync.Mutex
+	nextID      int64
+	recent      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		nextID:      1,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish 广播事件，不会阻塞调用方
+func (b *Bus) Publish(eventType string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event := Event{
+		ID:   b.nextID,
+		Type: eventType,
+		Time: time.Now().Format("2006-01-02 15:04:05"),
+		Data: data,
+	}
+	b.nextID++
+
+	b.recent = append(b.recent, event)
+	if len(b.recent) > recentEvents {
+		b.recent = b.recent[len(b.recent)-recentEvents:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe 订阅事件，返回 afterID 之后的历史事件和事件通道，不再使用时需调用 cancel
+func (b *Bus) Subscribe(afterID int64) (missed []Event, ch <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if afterID > 0 {
+		for _, e := range b.recent {
+			if e.ID > afterID {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	sub := make(chan Event, subscriberBuffer)
+	b.subscribers[sub] = struct{}{}
+	return missed, sub, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, sub)
+	}
+}
+
+// HandleSSE 以 Server-Sent Events 推送事件
+// 参数: types 逗号分隔的事件类型过滤（支持前缀，如 core）；断线重连时浏览器自动携带 Last-Event-ID
+func (b *Bus) HandleSSE(c *gin.Context) {
+	var filter []string
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter = append(filter, t)
+		}
+	}
+	lastID, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+
+	missed, ch, cancel := b.Subscribe(lastID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 禁止 nginx 等反向代理缓冲
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// 告知浏览器断线后的重连间隔
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	for _, e := range missed {
+		if matchType(filter, e.Type) {
+			writeEvent(c.Writer, e)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			if !matchType(filter, e.Type) {
+				continue
+			}
+			writeEvent(c.Writer, e)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// matchType 事件类型是否满足过滤条件，未指定过滤时全部匹配
+func matchType(filter []string, eventType string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if eventType == f || strings.HasPrefix(eventType, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEvent 按 SSE 格式写入一个事件
+func writeEvent(w io.Writer, e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
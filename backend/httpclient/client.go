@@ -0,0 +1,73 @@
+// Package httpclient 访问外部资源（订阅、规则集、GitHub 等）共用的 HTTP 客户端
+// 客户端不设整体超时，由调用方通过 context 控制超时和取消（如随 API 请求一起取消）
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	dialTimeout  atomic.Int64
+	fetchTimeout atomic.Int64
+)
+
+func init() {
+	dialTimeout.Store(int64(10 * time.Second))
+	fetchTimeout.Store(int64(30 * time.Second))
+}
+
+// Transport 共享的连接池，遵循 HTTP_PROXY / HTTPS_PROXY 环境变量
+var Transport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: time.Duration(dialTimeout.Load()), KeepAlive: 30 * time.Second}
+		return dialer.DialContext(ctx, network, addr)
+	},
+	ForceAttemptHTTP2:     true,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+	ExpectContinueTimeout: time.Second,
+	IdleConnTimeout:       90 * time.Second,
+	MaxIdleConns:          50,
+	MaxIdleConnsPerHost:   8,
+}
+
+// Client 共享的 HTTP 客户端
+var Client = &http.Client{Transport: Transport}
+
+// Configure 设置建立连接和下载远程资源的超时，<= 0 时保持原值
+func Configure(dial, fetch time.Duration) {
+	if dial > 0 {
+		dialTimeout.Store(int64(dial))
+	}
+	if fetch > 0 {
+		fetchTimeout.Store(int64(fetch))
+	}
+}
+
+// FetchTimeout 下载远程资源（如订阅）的默认超时
+func FetchTimeout() time.Duration {
+	return time.Duration(fetchTimeout.Load())
+}
+
+// WithTimeout 为 context 附加超时，已有更早的截止时间时保持不变
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Get 使用共享客户端发送 GET 请求，调用方负责关闭响应体
+// 超时由 ctx 控制，响应体读取完成前不要取消 ctx
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Client.Do(req)
+}
@@ -53,8 +53,8 @@ func main() {
 	}()
 
 	fmt.Printf("ProxyStation v%s 已启动\n", Version)
-	fmt.Printf("API 地址: http://localhost:%d\n", cfg.Server.Port)
-	fmt.Printf("Web 界面: http://localhost:%d\n", cfg.Server.Port)
+	fmt.Printf("API 地址: %s/api\n", srv.ListenURL())
+	fmt.Printf("Web 界面: %s\n", srv.ListenURL())
 
 	// 优雅退出
 	quit := make(chan os.Signal, 1)
@@ -62,6 +62,12 @@ func main() {
 	<-quit
 
 	fmt.Println("\n正在关闭服务...")
+	// 关闭过程中再次收到信号则立即退出
+	go func() {
+		<-quit
+		fmt.Println("强制退出")
+		os.Exit(1)
+	}()
 	srv.Shutdown()
 	fmt.Println("服务已关闭")
 }
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessControl 管理界面访问控制：来源地址白名单和客户端证书
+// 本机请求始终允许，避免配置错误后无法恢复（经本机反向代理访问时应在反向代理上限制来源）
+type AccessControl struct {
+	mu                sync.RWMutex
+	networks          []*net.IPNet
+	requireClientCert bool
+}
+
+// NewAccessControl 创建访问控制，默认不限制
+func NewAccessControl() *AccessControl {
+	return &AccessControl{}
+}
+
+// SetAllowedCIDRs 设置允许访问的来源地址，支持 CIDR 和单个 IP，为空时不限制
+func (a *AccessControl) SetAllowedCIDRs(cidrs []string) error {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return &net.ParseError{Type: "IP address", Text: cidr}
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, network)
+	}
+
+	a.mu.Lock()
+	a.networks = networks
+	a.mu.Unlock()
+	return nil
+}
+
+// SetRequireClientCert 设置是否要求经过校验的客户端证书（mTLS）
+func (a *AccessControl) SetRequireClientCert(require bool) {
+	a.mu.Lock()
+	a.requireClientCert = require
+	a.mu.Unlock()
+}
+
+// allowed 检查来源地址和客户端证书
+func (a *AccessControl) allowed(c *gin.Context) (bool, string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// 使用连接的对端地址，不信任 X-Forwarded-For
+	ip := net.ParseIP(c.RemoteIP())
+	if ip != nil && ip.IsLoopback() {
+		return true, ""
+	}
+
+	if len(a.networks) > 0 {
+		matched := false
+		for _, network := range a.networks {
+			if ip != nil && network.Contains(ip) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, "来源地址不在允许列表中"
+		}
+	}
+
+	if a.requireClientCert && (c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0) {
+		return false, "需要有效的客户端证书"
+	}
+	return true, ""
+}
+
+// Middleware 拒绝不满足访问控制的请求（包括前端页面），返回 403
+func (a *AccessControl) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, reason := a.allowed(c); !ok {
+			apierror.Abort(c, http.StatusForbidden, apierror.New(apierror.CodeAccessDenied, "禁止访问: "+reason))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAccessEntries 内存中保留的访问记录条数
+const maxAccessEntries = 1000
+
+// AccessEntry 一条 API 访问记录
+type AccessEntry struct {
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"` // 不含查询参数（可能包含令牌）
+	Route    string    `json:"route,omitempty"`
+	Status   int       `json:"status"`
+	Latency  float64   `json:"latency"`  // 毫秒
+	ClientIP string    `json:"clientIp"` // TCP 连接的对端地址
+	Size     int       `json:"size"`     // 响应字节数
+}
+
+// AccessLog ProxyStation 自身 API 的访问日志（与核心日志分开），开启后记录到内存并输出 JSON 行到控制台
+type AccessLog struct {
+	enabled atomic.Bool
+	mu      sync.RWMutex
+	entries []AccessEntry
+	next    int // 环形缓冲区写入位置
+	full    bool
+}
+
+// NewAccessLog 创建访问日志，默认关闭
+func NewAccessLog() *AccessLog {
+	return &AccessLog{entries: make([]AccessEntry, maxAccessEntries)}
+}
+
+// SetEnabled 开启或关闭访问日志
+func (l *AccessLog) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+// Enabled 访问日志是否开启
+func (l *AccessLog) Enabled() bool {
+	return l.enabled.Load()
+}
+
+// Middleware 记录 API 和 WebSocket 请求（不记录前端静态文件）
+func (l *AccessLog) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !l.enabled.Load() || (!strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/ws/")) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		entry := AccessEntry{
+			Time:     start,
+			Method:   c.Request.Method,
+			Path:     path,
+			Route:    c.FullPath(),
+			Status:   c.Writer.Status(),
+			Latency:  float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP: c.RemoteIP(),
+			Size:     c.Writer.Size(),
+		}
+		l.record(entry)
+
+		if line, err := json.Marshal(entry); err == nil {
+			gin.DefaultWriter.Write(append(line, '\n'))
+		}
+	}
+}
+
+func (l *AccessLog) record(entry AccessEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Recent 返回最近的访问记录（从新到旧），minStatus > 0 时只返回状态码不小于它的记录
+func (l *AccessLog) Recent(limit, minStatus int) []AccessEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	result := make([]AccessEntry, 0, limit)
+	for i := 1; i <= count && len(result) < limit; i++ {
+		entry := l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if minStatus > 0 && entry.Status < minStatus {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// Clear 清空访问记录
+func (l *AccessLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next = 0
+	l.full = false
+}
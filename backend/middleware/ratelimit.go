@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitedPath 是否为需要限流和限制大小的接口（API、WebSocket 和配置托管，不包括前端静态文件）
+func limitedPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/ws/") || strings.HasPrefix(path, "/sub/")
+}
+
+// bucket 令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter 按 IP 的令牌桶限流器
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+// allow 消耗一个令牌，不足时返回需要等待的时间
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup 定期删除已回满的空闲令牌桶
+func (l *rateLimiter) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		l.mu.Lock()
+		for ip, b := range l.buckets {
+			if now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// RateLimit 按客户端 IP 限流，超出时返回 429
+// rate 为每秒请求数，burst 为允许的突发请求数；rate <= 0 时不限流
+func RateLimit(rate float64, burst int, exemptLoopback bool) gin.HandlerFunc {
+	if rate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+
+	limiter := &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+	go limiter.cleanup(time.Minute)
+
+	return func(c *gin.Context) {
+		if !limitedPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		// 使用 TCP 连接的对端地址，X-Forwarded-For 可由客户端伪造
+		ip := c.RemoteIP()
+		if exemptLoopback {
+			if parsed := net.ParseIP(ip); parsed != nil && parsed.IsLoopback() {
+				c.Next()
+				return
+			}
+		}
+
+		if ok, wait := limiter.allow(ip, time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			apierror.Abort(c, http.StatusTooManyRequests, apierror.New(apierror.CodeRateLimited, "请求过于频繁，请稍后再试"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// uploadPaths 上传文件的接口，使用单独的大小上限
+var uploadPaths = map[string]bool{
+	"/api/restore": true,
+}
+
+// MaxBodySize 限制请求体大小，超出时返回 413；上传接口使用 maxUpload 上限，上限 <= 0 时不限制
+func MaxBodySize(maxBytes, maxUpload int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || !limitedPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		maxBytes := maxBytes
+		if uploadPaths[c.Request.URL.Path] {
+			maxBytes = maxUpload
+		}
+		if maxBytes <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			apierror.Abort(c, http.StatusRequestEntityTooLarge, apierror.New(apierror.CodePayloadTooLarge, "请求体过大，上限为 "+formatBytes(maxBytes)))
+			return
+		}
+		// 未声明长度（分块传输）时在读取过程中限制
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// formatBytes 格式化字节数
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return strconv.FormatFloat(float64(n)/(1<<20), 'f', -1, 64) + " MB"
+	case n >= 1<<10:
+		return strconv.FormatFloat(float64(n)/(1<<10), 'f', -1, 64) + " KB"
+	}
+	return strconv.FormatInt(n, 10) + " B"
+}
@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxSummaryBody 生成摘要时最多读取的请求体大小
+	maxSummaryBody = 64 * 1024
+	// maxSummaryLen 摘要最大长度
+	maxSummaryLen = 500
+	// maxValueLen 摘要中单个值的最大长度
+	maxValueLen = 40
+)
+
+// sensitiveKeys 摘要中需要遮蔽的字段（包含匹配，不区分大小写）
+var sensitiveKeys = []string{"password", "secret", "token", "uuid", "key", "auth", "content"}
+
+// Handler 审计日志 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("", h.List)
+	r.DELETE("", h.Clear)
+}
+
+// Middleware 记录所有修改状态的请求（非 GET/HEAD/OPTIONS）
+// 需在认证中间件之前注册，以便同时记录未授权的请求
+func (h *Handler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		summary := summarizeRequest(c)
+
+		c.Next()
+
+		scope, _ := c.Get("authScope")
+		scopeStr, _ := scope.(string)
+		h.service.Record(Entry{
+			Time:         start.Format("2006-01-02 15:04:05"),
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.Path,
+			Route:        c.FullPath(),
+			Status:       c.Writer.Status(),
+			SourceIP:     c.RemoteIP(),
+			ForwardedFor: c.GetHeader("X-Forwarded-For"),
+			Scope:        scopeStr,
+			Summary:      summary,
+			Latency:      time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+// summarizeRequest 生成请求参数摘要，敏感字段遮蔽，读取后恢复请求体
+func summarizeRequest(c *gin.Context) string {
+	var parts []string
+	for key, values := range c.Request.URL.Query() {
+		parts = append(parts, key+"="+summarizeValue(key, strings.Join(values, ",")))
+	}
+	sort.Strings(parts)
+
+	if c.Request.Body != nil {
+		buf, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxSummaryBody))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), c.Request.Body))
+		if body := summarizeBody(buf, c.Request.ContentLength); body != "" {
+			parts = append(parts, body)
+		}
+	}
+
+	summary := strings.Join(parts, " ")
+	if len(summary) > maxSummaryLen {
+		summary = truncate(summary, maxSummaryLen)
+	}
+	return summary
+}
+
+// summarizeBody JSON 请求体只记录顶层字段，其他内容只记录大小
+func summarizeBody(buf []byte, contentLength int64) string {
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return ""
+	}
+	var data interface{}
+	if contentLength > maxSummaryBody || json.Unmarshal(buf, &data) != nil {
+		size := contentLength
+		if size < 0 {
+			size = int64(len(buf))
+		}
+		return fmt.Sprintf("<%d bytes>", size)
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			fields = append(fields, key+"="+summarizeValue(key, v[key]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case []interface{}:
+		return fmt.Sprintf("[%d items]", len(v))
+	default:
+		return summarizeValue("", v)
+	}
+}
+
+// summarizeValue 单个值的摘要
+func summarizeValue(key string, value interface{}) string {
+	lower := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(lower, s) {
+			return "***"
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(truncate(v, maxValueLen))
+	case map[string]interface{}:
+		return "{…}"
+	case []interface{}:
+		return fmt.Sprintf("[%d items]", len(v))
+	default:
+		return truncate(fmt.Sprint(v), maxValueLen)
+	}
+}
+
+// truncate 按字符截断
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// List 分页查询审计日志
+// 参数: page, pageSize, method, path, ip, status(success/error), since, until (2006-01-02 15:04:05 或 RFC3339)
+func (h *Handler) List(c *gin.Context) {
+	q := Query{
+		Method:   c.Query("method"),
+		Path:     c.Query("path"),
+		SourceIP: c.Query("ip"),
+		Status:   c.Query("status"),
+	}
+	q.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	q.PageSize, _ = strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+	var err error
+	if q.Since, err = parseTime(c.Query("since")); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "since 格式错误: "+err.Error())
+		return
+	}
+	if q.Until, err = parseTime(c.Query("until")); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "until 格式错误: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.List(q),
+	})
+}
+
+// parseTime 解析时间参数，为空时返回零值
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Clear 清空审计日志（清空操作本身会被记录）
+func (h *Handler) Clear(c *gin.Context) {
+	if err := h.service.Clear(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
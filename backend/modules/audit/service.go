@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxEntries 内存中保留的最近记录数
+	maxEntries = 5000
+	// maxFileSize 审计日志文件超过此大小时轮转为 audit.log.1
+	maxFileSize = 5 * 1024 * 1024
+)
+
+// Entry 审计记录
+type Entry struct {
+	ID           int64  `json:"id"`
+	Time         string `json:"time"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Route        string `json:"route,omitempty"` // 路由模板，如 /api/proxy/template/rules/:id
+	Status       int    `json:"status"`
+	SourceIP     string `json:"sourceIp"`               // TCP 连接的对端地址
+	ForwardedFor string `json:"forwardedFor,omitempty"` // X-Forwarded-For 请求头，客户端可伪造，仅供参考
+	Scope        string `json:"scope,omitempty"`        // 认证权限范围，未启用认证时为空
+	Summary      string `json:"summary,omitempty"`
+	Latency      int64  `json:"latency"` // 毫秒
+}
+
+// Query 查询条件
+type Query struct {
+	Page     int
+	PageSize int
+	Method   string
+	Path     string // 路径包含
+	SourceIP string
+	Status   string // success / error
+	Since    time.Time
+	Until    time.Time
+}
+
+// Page 分页结果
+type Page struct {
+	Items    []Entry `json:"items"`
+	Total    int     `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"pageSize"`
+}
+
+// Service 审计日志服务
+type Service struct {
+	mu      sync.RWMutex
+	entries []Entry
+	nextID  int64
+	dataDir string
+}
+
+// NewService 创建审计日志服务
+func NewService(dataDir string) *Service {
+	s := &Service{dataDir: dataDir, nextID: 1}
+	s.load()
+	return s
+}
+
+func (s *Service) logPath() string {
+	return filepath.Join(s.dataDir, "audit.log")
+}
+
+// load 从日志文件加载最近的记录（JSON Lines）
+func (s *Service) load() {
+	f, err := os.Open(s.logPath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		s.entries = append(s.entries, e)
+		if e.ID >= s.nextID {
+			s.nextID = e.ID + 1
+		}
+	}
+	if len(s.entries) > maxEntries {
+		s.entries = s.entries[len(s.entries)-maxEntries:]
+	}
+}
+
+// Record 记录一条审计日志
+func (s *Service) Record(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID = s.nextID
+	s.nextID++
+	s.entries = append(s.entries, e)
+	if len(s.entries) > maxEntries {
+		s.entries = s.entries[len(s.entries)-maxEntries:]
+	}
+
+	if err := s.appendFile(e); err != nil {
+		fmt.Printf("⚠️ 写入审计日志失败: %v\n", err)
+	}
+}
+
+// appendFile 追加到日志文件，超过大小上限时先轮转（调用时需持有 s.mu 锁）
+func (s *Service) appendFile(e Entry) error {
+	path := s.logPath()
+	if info, err := os.Stat(path); err == nil && info.Size() > maxFileSize {
+		os.Rename(path, path+".1")
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// List 按条件分页查询，最新的记录在前
+func (s *Service) List(q Query) Page {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 || q.PageSize > 500 {
+		q.PageSize = 50
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []Entry
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if q.match(s.entries[i]) {
+			matched = append(matched, s.entries[i])
+		}
+	}
+
+	result := Page{Items: []Entry{}, Total: len(matched), Page: q.Page, PageSize: q.PageSize}
+	start := (q.Page - 1) * q.PageSize
+	if start < len(matched) {
+		end := start + q.PageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		result.Items = matched[start:end]
+	}
+	return result
+}
+
+// match 记录是否满足查询条件
+func (q Query) match(e Entry) bool {
+	if q.Method != "" && !strings.EqualFold(e.Method, q.Method) {
+		return false
+	}
+	if q.Path != "" && !strings.Contains(e.Path, q.Path) {
+		return false
+	}
+	if q.SourceIP != "" && e.SourceIP != q.SourceIP {
+		return false
+	}
+	switch q.Status {
+	case "success":
+		if e.Status >= 400 {
+			return false
+		}
+	case "error":
+		if e.Status < 400 {
+			return false
+		}
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", e.Time, time.Local)
+		if err != nil {
+			return false
+		}
+		if !q.Since.IsZero() && t.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && t.After(q.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear 清空审计日志
+func (s *Service) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+	os.Remove(s.logPath() + ".1")
+	if err := os.Remove(s.logPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
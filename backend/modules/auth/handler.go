@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strings"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,18 +20,89 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes 注册路由
+// 登录、登出和状态检查无需认证，修改认证设置的接口需要认证
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	auth := r.Group("/auth")
 	{
 		auth.GET("/config", h.GetConfig)
 		auth.POST("/login", h.Login)
 		auth.POST("/logout", h.Logout)
-		auth.PUT("/enabled", h.SetEnabled)
-		auth.PUT("/username", h.UpdateUsername)
-		auth.PUT("/password", h.UpdatePassword)
-		auth.PUT("/avatar", h.UpdateAvatar)
 		auth.GET("/check", h.CheckAuth)
 	}
+
+	protected := auth.Group("", h.AuthMiddleware())
+	{
+		protected.PUT("/enabled", h.SetEnabled)
+		protected.PUT("/username", h.UpdateUsername)
+		protected.PUT("/password", h.UpdatePassword)
+		protected.PUT("/avatar", h.UpdateAvatar)
+		protected.PUT("/session", h.SetSessionTTL)
+		protected.GET("/tokens", h.ListAPITokens)
+		protected.POST("/tokens", h.CreateAPIToken)
+		protected.DELETE("/tokens/:id", h.DeleteAPIToken)
+	}
+}
+
+// readOnlyRoutes 只读令牌可访问的接口（仅 GET），不包含配置、模板、节点和订阅等含凭据的接口
+var readOnlyRoutes = map[string]bool{
+	"/api/system/info":              true,
+	"/api/system/resources":         true,
+	"/api/proxy/status":             true,
+	"/api/proxy/logs":               true,
+	"/api/proxy/logs/crashes":       true,
+	"/api/proxy/connections":        true,
+	"/api/proxy/stats/traffic":      true,
+	"/api/proxy/transparent/status": true,
+	"/api/core/status":              true,
+	"/api/core/versions":            true,
+	"/api/geodata/status":           true,
+	"/api/nodes/health":             true,
+	"/api/nodes/speedtest":          true,
+	"/api/speedtest/history":        true,
+	"/api/events":                   true,
+	"/ws/traffic":                   true,
+	"/ws/logs":                      true,
+	"/ws/connections":               true,
+}
+
+// publicRoutes 无需认证的路由（gin 路由模板）
+var publicRoutes = map[string]bool{
+	"/api/auth/login":  true,
+	"/api/auth/config": true,
+	"/api/auth/check":  true,
+}
+
+// ReadOnlyRoute 路由是否允许只读令牌访问（用于生成 API 文档）
+func ReadOnlyRoute(route string) bool {
+	return readOnlyRoutes[route]
+}
+
+// allowScope 令牌权限范围是否允许访问当前请求
+func allowScope(c *gin.Context, scope string) bool {
+	if scope == ScopeAdmin {
+		return true
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return readOnlyRoutes[route]
+}
+
+// requestToken 从请求中获取令牌：Authorization 头、cookie，WebSocket 握手和 SSE 还支持 token 查询参数
+func requestToken(c *gin.Context) string {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		token, _ = c.Cookie("ProxyStation-token")
+	}
+	if token == "" && (strings.EqualFold(c.GetHeader("Upgrade"), "websocket") ||
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")) {
+		token = c.Query("token")
+	}
+	return strings.TrimPrefix(token, "Bearer ")
 }
 
 // AuthMiddleware 认证中间件
@@ -42,31 +115,23 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 登录和配置检查接口不需要认证
-		path := c.Request.URL.Path
-		if strings.HasSuffix(path, "/auth/login") ||
-			strings.HasSuffix(path, "/auth/config") ||
-			strings.HasSuffix(path, "/auth/check") {
+		// 按匹配到的路由精确判断，避免 /mihomo/*path 等通配路由以相同后缀绕过认证
+		if publicRoutes[c.FullPath()] {
 			c.Next()
 			return
 		}
 
-		// 从请求头获取令牌
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			// 尝试从 cookie 获取
-			token, _ = c.Cookie("ProxyStation-token")
+		scope, ok := h.service.ValidateToken(requestToken(c))
+		if !ok {
+			apierror.Abort(c, http.StatusUnauthorized, apierror.New(apierror.CodeUnauthorized, "未授权，请先登录"))
+			return
 		}
 
-		// 移除 Bearer 前缀
-		token = strings.TrimPrefix(token, "Bearer ")
-
-		if token == "" || !h.service.ValidateToken(token) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权，请先登录",
-			})
-			c.Abort()
+		if !allowScope(c, scope) {
+			apierror.Abort(c, http.StatusForbidden, apierror.New(apierror.CodeReadOnlyToken, "当前令牌为只读权限，无法执行此操作"))
 			return
 		}
+		c.Set("authScope", scope)
 
 		c.Next()
 	}
@@ -87,15 +152,11 @@ func (h *Handler) CheckAuth(c *gin.Context) {
 		return
 	}
 
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token, _ = c.Cookie("ProxyStation-token")
-	}
-	token = strings.TrimPrefix(token, "Bearer ")
-
+	scope, ok := h.service.ValidateToken(requestToken(c))
 	c.JSON(http.StatusOK, gin.H{
 		"enabled":       true,
-		"authenticated": h.service.ValidateToken(token),
+		"authenticated": ok,
+		"scope":         scope,
 	})
 }
 
@@ -107,18 +168,18 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	token, err := h.service.Login(req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	// 设置 cookie
-	c.SetCookie("ProxyStation-token", token, 86400, "/", "", false, true)
+	// 设置 cookie，有效期与会话一致（WebSocket 连接依赖 cookie 认证）
+	c.SetCookie("ProxyStation-token", token, int(h.service.SessionTTL().Seconds()), "/", "", c.Request.TLS != nil, true)
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":    token,
@@ -129,13 +190,7 @@ func (h *Handler) Login(c *gin.Context) {
 
 // Logout 登出
 func (h *Handler) Logout(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token, _ = c.Cookie("ProxyStation-token")
-	}
-	token = strings.TrimPrefix(token, "Bearer ")
-
-	h.service.Logout(token)
+	h.service.Logout(requestToken(c))
 
 	// 清除 cookie
 	c.SetCookie("ProxyStation-token", "", -1, "/", "", false, true)
@@ -150,12 +205,12 @@ func (h *Handler) SetEnabled(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	if err := h.service.SetEnabled(req.Enabled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -169,12 +224,12 @@ func (h *Handler) UpdateUsername(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	if err := h.service.UpdateUsername(req.Username); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -189,12 +244,12 @@ func (h *Handler) UpdatePassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	if err := h.service.UpdatePassword(req.OldPassword, req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -208,14 +263,73 @@ func (h *Handler) UpdateAvatar(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	if err := h.service.UpdateAvatar(req.Avatar); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "头像已更新"})
 }
+
+// SetSessionTTL 设置会话有效期
+func (h *Handler) SetSessionTTL(c *gin.Context) {
+	var req struct {
+		SessionTTL int `json:"sessionTTL"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	if err := h.service.SetSessionTTL(req.SessionTTL); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话有效期已更新"})
+}
+
+// ListAPITokens 列出 API 令牌
+func (h *Handler) ListAPITokens(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.ListAPITokens())
+}
+
+// CreateAPIToken 创建 API 令牌，令牌明文只在此返回一次
+func (h *Handler) CreateAPIToken(c *gin.Context) {
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	token, info, err := h.service.CreateAPIToken(req.Name, req.Scope)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":   token,
+		"info":    info,
+		"message": "API 令牌已生成，请妥善保存，之后将无法再次查看",
+	})
+}
+
+// DeleteAPIToken 删除 API 令牌
+func (h *Handler) DeleteAPIToken(c *gin.Context) {
+	if err := h.service.DeleteAPIToken(c.Param("id")); err != nil {
+		apierror.Respond(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API 令牌已删除"})
+}
@@ -3,27 +3,62 @@ package auth
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// defaultSessionTTL 默认会话有效期（小时）
+	defaultSessionTTL = 24
+	// maxSessionTTL 会话有效期上限（小时）
+	maxSessionTTL = 24 * 30
+	// apiTokenPrefix API 令牌前缀，便于与会话令牌区分，格式为 ps_<id>_<secret>
+	apiTokenPrefix = "ps_"
+	// legacyTokenID 旧版本单个 API 令牌迁移后的 ID
+	legacyTokenID = "legacy"
 )
 
+// 令牌权限范围
+const (
+	ScopeRead  = "read"  // 只读：状态、日志、流量等，不包含配置和节点凭据
+	ScopeAdmin = "admin" // 完全控制
+)
+
+// APIToken API 令牌（只保存哈希）
+type APIToken struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Scope      string `json:"scope"` // read / admin
+	Hash       string `json:"hash,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt,omitempty"`
+}
+
 // AuthConfig 认证配置
 type AuthConfig struct {
-	Enabled  bool   `json:"enabled"`  // 是否启用认证
-	Username string `json:"username"` // 用户名
-	Password string `json:"password"` // 密码哈希
-	Avatar   string `json:"avatar"`   // 头像 (base64 或 URL)
+	Enabled    bool       `json:"enabled"`              // 是否启用认证
+	Username   string     `json:"username"`             // 用户名
+	Password   string     `json:"password"`             // 密码哈希 (bcrypt)
+	Avatar     string     `json:"avatar"`               // 头像 (base64 或 URL)
+	SessionTTL int        `json:"sessionTTL"`           // 会话有效期（小时）
+	APITokens  []APIToken `json:"apiTokens,omitempty"`  // API 令牌
+	APIToken   string     `json:"apiToken,omitempty"`   // 旧版本单个 API 令牌哈希，加载时迁移到 APITokens
+	APITokenAt string     `json:"apiTokenAt,omitempty"` // 旧版本 API 令牌生成时间
 }
 
 // Session 会话
 type Session struct {
-	Token     string    `json:"token"`
 	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
 	ExpiresAt time.Time `json:"expiresAt"`
 }
 
@@ -31,7 +66,8 @@ type Session struct {
 type Service struct {
 	mu       sync.RWMutex
 	config   AuthConfig
-	sessions map[string]*Session
+	sessions map[string]*Session // key 为令牌的 SHA-256，文件中不保存明文令牌
+	verified map[string]string   // 已验证的 API 令牌（SHA-256 → 令牌 ID），避免每次请求都做 bcrypt 比较
 	dataDir  string
 }
 
@@ -39,9 +75,12 @@ type Service struct {
 func NewService(dataDir string) *Service {
 	s := &Service{
 		sessions: make(map[string]*Session),
+		verified: make(map[string]string),
 		dataDir:  dataDir,
 	}
 	s.loadConfig()
+	s.loadSessions()
+	go s.cleanupLoop()
 	return s
 }
 
@@ -50,38 +89,144 @@ func (s *Service) configPath() string {
 	return filepath.Join(s.dataDir, "auth.json")
 }
 
+// 会话文件路径
+func (s *Service) sessionsPath() string {
+	return filepath.Join(s.dataDir, "sessions.json")
+}
+
 // loadConfig 加载配置
 func (s *Service) loadConfig() {
 	data, err := os.ReadFile(s.configPath())
 	if err != nil {
 		// 使用默认配置
 		s.config = AuthConfig{
-			Enabled:  false,
-			Username: "admin",
-			Password: s.hashPassword("admin123"),
-			Avatar:   "",
+			Enabled:    false,
+			Username:   "admin",
+			Password:   hashSecret("admin123"),
+			Avatar:     "",
+			SessionTTL: defaultSessionTTL,
 		}
 		s.saveConfig()
 		return
 	}
 	json.Unmarshal(data, &s.config)
+	if s.config.SessionTTL <= 0 {
+		s.config.SessionTTL = defaultSessionTTL
+	}
+	// 迁移旧版本的单个 API 令牌为完全控制权限
+	if s.config.APIToken != "" {
+		s.config.APITokens = append(s.config.APITokens, APIToken{
+			ID:        legacyTokenID,
+			Name:      "默认令牌",
+			Scope:     ScopeAdmin,
+			Hash:      s.config.APIToken,
+			CreatedAt: s.config.APITokenAt,
+		})
+		s.config.APIToken = ""
+		s.config.APITokenAt = ""
+		s.saveConfig()
+	}
 }
 
-// saveConfig 保存配置
+// saveConfig 保存配置（包含密码哈希，仅所有者可读）
 func (s *Service) saveConfig() error {
 	data, err := json.MarshalIndent(s.config, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.configPath(), data, 0644)
+	return os.WriteFile(s.configPath(), data, 0600)
+}
+
+// loadSessions 加载持久化的会话，跳过已过期的会话
+func (s *Service) loadSessions() {
+	data, err := os.ReadFile(s.sessionsPath())
+	if err != nil {
+		return
+	}
+	var sessions map[string]*Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return
+	}
+	now := time.Now()
+	for key, session := range sessions {
+		if session != nil && now.Before(session.ExpiresAt) {
+			s.sessions[key] = session
+		}
+	}
+}
+
+// saveSessions 保存会话（调用时需持有 s.mu 锁）
+func (s *Service) saveSessions() {
+	data, err := json.MarshalIndent(s.sessions, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(s.sessionsPath(), data, 0600); err != nil {
+		fmt.Printf("⚠️ 保存会话失败: %v\n", err)
+	}
 }
 
-// hashPassword 密码哈希
-func (s *Service) hashPassword(password string) string {
+// cleanupLoop 定期清理过期会话
+func (s *Service) cleanupLoop() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		if s.pruneSessions() {
+			s.saveSessions()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// pruneSessions 删除过期会话，返回是否有变化（调用时需持有 s.mu 锁）
+func (s *Service) pruneSessions() bool {
+	now := time.Now()
+	changed := false
+	for key, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// hashSecret 使用 bcrypt 哈希密码或令牌
+func hashSecret(secret string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return ""
+	}
+	return string(hash)
+}
+
+// legacyHash 旧版本的 SHA-256 密码哈希，仅用于迁移
+func legacyHash(password string) string {
 	hash := sha256.Sum256([]byte(password + "proxystation-salt"))
 	return hex.EncodeToString(hash[:])
 }
 
+// isLegacyHash 是否为旧版本的 SHA-256 哈希
+func isLegacyHash(hash string) bool {
+	return len(hash) == sha256.Size*2 && !strings.HasPrefix(hash, "$2")
+}
+
+// checkPassword 校验密码，返回是否需要升级为 bcrypt 哈希
+func (s *Service) checkPassword(password string) (ok bool, upgrade bool) {
+	if isLegacyHash(s.config.Password) {
+		legacy := legacyHash(password)
+		return subtle.ConstantTimeCompare([]byte(legacy), []byte(s.config.Password)) == 1, true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(s.config.Password), []byte(password)) == nil, false
+}
+
+// tokenKey 令牌在内存和文件中的键
+func tokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 // generateToken 生成令牌
 func (s *Service) generateToken() string {
 	b := make([]byte, 32)
@@ -101,9 +246,11 @@ func (s *Service) GetConfig() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return map[string]interface{}{
-		"enabled":  s.config.Enabled,
-		"username": s.config.Username,
-		"avatar":   s.config.Avatar,
+		"enabled":    s.config.Enabled,
+		"username":   s.config.Username,
+		"avatar":     s.config.Avatar,
+		"sessionTTL": s.config.SessionTTL,
+		"apiTokens":  len(s.config.APITokens),
 	}
 }
 
@@ -115,6 +262,24 @@ func (s *Service) SetEnabled(enabled bool) error {
 	return s.saveConfig()
 }
 
+// SessionTTL 会话有效期
+func (s *Service) SessionTTL() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Duration(s.config.SessionTTL) * time.Hour
+}
+
+// SetSessionTTL 设置会话有效期（小时），只影响之后创建的会话
+func (s *Service) SetSessionTTL(hours int) error {
+	if hours <= 0 || hours > maxSessionTTL {
+		return fmt.Errorf("会话有效期需在 1-%d 小时之间", maxSessionTTL)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.SessionTTL = hours
+	return s.saveConfig()
+}
+
 // Login 登录
 func (s *Service) Login(username, password string) (string, error) {
 	s.mu.Lock()
@@ -124,17 +289,27 @@ func (s *Service) Login(username, password string) (string, error) {
 		return "", fmt.Errorf("用户名或密码错误")
 	}
 
-	if s.hashPassword(password) != s.config.Password {
+	ok, upgrade := s.checkPassword(password)
+	if !ok {
 		return "", fmt.Errorf("用户名或密码错误")
 	}
+	// 旧版本 SHA-256 哈希登录成功后升级为 bcrypt
+	if upgrade {
+		s.config.Password = hashSecret(password)
+		s.saveConfig()
+		fmt.Println("🔐 密码哈希已升级为 bcrypt")
+	}
 
 	// 生成会话
 	token := s.generateToken()
-	s.sessions[token] = &Session{
-		Token:     token,
+	now := time.Now()
+	s.pruneSessions()
+	s.sessions[tokenKey(token)] = &Session{
 		Username:  username,
-		ExpiresAt: time.Now().Add(24 * time.Hour), // 24小时过期
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(s.config.SessionTTL) * time.Hour),
 	}
+	s.saveSessions()
 
 	return token, nil
 }
@@ -143,25 +318,156 @@ func (s *Service) Login(username, password string) (string, error) {
 func (s *Service) Logout(token string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.sessions, token)
+	key := tokenKey(token)
+	if _, ok := s.sessions[key]; ok {
+		delete(s.sessions, key)
+		s.saveSessions()
+	}
 }
 
-// ValidateToken 验证令牌
-func (s *Service) ValidateToken(token string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// ValidateToken 验证令牌（会话令牌或 API 令牌），返回令牌的权限范围
+// 登录会话拥有完全控制权限
+func (s *Service) ValidateToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		return s.validateAPIToken(token)
+	}
 
-	session, ok := s.sessions[token]
+	key := tokenKey(token)
+	s.mu.RLock()
+	session, ok := s.sessions[key]
+	s.mu.RUnlock()
 	if !ok {
-		return false
+		return "", false
 	}
 
 	if time.Now().After(session.ExpiresAt) {
-		delete(s.sessions, token)
-		return false
+		s.mu.Lock()
+		delete(s.sessions, key)
+		s.saveSessions()
+		s.mu.Unlock()
+		return "", false
+	}
+
+	return ScopeAdmin, true
+}
+
+// validateAPIToken 验证 API 令牌，验证通过的令牌缓存在内存中
+func (s *Service) validateAPIToken(token string) (string, bool) {
+	key := tokenKey(token)
+	id := legacyTokenID
+	if parts := strings.SplitN(strings.TrimPrefix(token, apiTokenPrefix), "_", 2); len(parts) == 2 {
+		id = parts[0]
+	}
+
+	s.mu.RLock()
+	cachedID, cached := s.verified[key]
+	t := s.findAPIToken(id)
+	var hash string
+	if t != nil {
+		hash = t.Hash
+	}
+	s.mu.RUnlock()
+	if t == nil || (cached && cachedID != id) {
+		return "", false
+	}
+	if !cached && bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) != nil {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 验证期间令牌可能已被删除
+	t = s.findAPIToken(id)
+	if t == nil || t.Hash != hash {
+		return "", false
+	}
+	s.verified[key] = id
+	s.touchAPIToken(t)
+	return t.Scope, true
+}
+
+// findAPIToken 按 ID 查找 API 令牌（调用时需持有 s.mu 锁）
+func (s *Service) findAPIToken(id string) *APIToken {
+	for i := range s.config.APITokens {
+		if s.config.APITokens[i].ID == id {
+			return &s.config.APITokens[i]
+		}
+	}
+	return nil
+}
+
+// touchAPIToken 更新令牌最后使用时间，每小时最多写一次文件（调用时需持有 s.mu 锁）
+func (s *Service) touchAPIToken(t *APIToken) {
+	now := time.Now()
+	last, err := time.ParseInLocation("2006-01-02 15:04:05", t.LastUsedAt, time.Local)
+	t.LastUsedAt = now.Format("2006-01-02 15:04:05")
+	if err != nil || now.Sub(last) > time.Hour {
+		s.saveConfig()
+	}
+}
+
+// ListAPITokens 列出 API 令牌（不含哈希）
+func (s *Service) ListAPITokens() []APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tokens := make([]APIToken, len(s.config.APITokens))
+	for i, t := range s.config.APITokens {
+		t.Hash = ""
+		tokens[i] = t
 	}
+	return tokens
+}
 
-	return true
+// CreateAPIToken 创建 API 令牌，明文只返回这一次
+func (s *Service) CreateAPIToken(name, scope string) (string, *APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("令牌名称不能为空")
+	}
+	if scope != ScopeRead && scope != ScopeAdmin {
+		return "", nil, fmt.Errorf("不支持的权限范围: %s", scope)
+	}
+
+	id := s.generateToken()[:8]
+	token := apiTokenPrefix + id + "_" + s.generateToken()
+	t := APIToken{
+		ID:        id,
+		Name:      name,
+		Scope:     scope,
+		Hash:      hashSecret(token),
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.APITokens = append(s.config.APITokens, t)
+	if err := s.saveConfig(); err != nil {
+		return "", nil, err
+	}
+	t.Hash = ""
+	return token, &t, nil
+}
+
+// DeleteAPIToken 删除 API 令牌
+func (s *Service) DeleteAPIToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.config.APITokens {
+		if t.ID != id {
+			continue
+		}
+		s.config.APITokens = append(s.config.APITokens[:i], s.config.APITokens[i+1:]...)
+		for key, tokenID := range s.verified {
+			if tokenID == id {
+				delete(s.verified, key)
+			}
+		}
+		return s.saveConfig()
+	}
+	return fmt.Errorf("令牌不存在")
 }
 
 // UpdateUsername 更新用户名
@@ -182,7 +488,7 @@ func (s *Service) UpdatePassword(oldPassword, newPassword string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.hashPassword(oldPassword) != s.config.Password {
+	if ok, _ := s.checkPassword(oldPassword); !ok {
 		return fmt.Errorf("原密码错误")
 	}
 
@@ -190,9 +496,10 @@ func (s *Service) UpdatePassword(oldPassword, newPassword string) error {
 		return fmt.Errorf("密码长度至少6位")
 	}
 
-	s.config.Password = s.hashPassword(newPassword)
+	s.config.Password = hashSecret(newPassword)
 	// 清除所有会话，要求重新登录
 	s.sessions = make(map[string]*Session)
+	s.saveSessions()
 	return s.saveConfig()
 }
 
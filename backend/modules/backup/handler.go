@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// passwordHeader 备份密码请求头（避免密码出现在 URL 和访问日志中）
+const passwordHeader = "X-Backup-Password"
+
+// Handler 备份与恢复 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/backup", h.Backup)
+	r.POST("/restore", h.Restore)
+}
+
+// Backup 下载备份
+// 参数: history=true 包含配置历史；请求头 X-Backup-Password 非空时加密备份
+func (h *Handler) Backup(c *gin.Context) {
+	opts := Options{
+		History:  c.Query("history") == "true",
+		Password: c.GetHeader(passwordHeader),
+	}
+
+	filename := fmt.Sprintf("proxystation-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	if opts.Password != "" {
+		filename += ".enc"
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，出错时只能中断传输
+	if err := h.service.WriteBackup(c.Writer, opts); err != nil {
+		fmt.Printf("⚠️ 生成备份失败: %v\n", err)
+		c.Abort()
+	}
+}
+
+// Restore 上传并恢复备份
+// 支持 multipart（file 字段，password 字段）或直接上传文件内容（密码通过 X-Backup-Password 请求头）
+func (h *Handler) Restore(c *gin.Context) {
+	var (
+		body     io.Reader = c.Request.Body
+		password           = c.GetHeader(passwordHeader)
+	)
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			apierror.Message(c, http.StatusBadRequest, "读取上传文件失败: "+err.Error())
+			return
+		}
+		defer f.Close()
+		body = f
+		if p := c.PostForm("password"); p != "" {
+			password = p
+		}
+	}
+
+	result, err := h.service.Restore(body, password)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "备份已恢复，请重启 ProxyStation 使配置生效",
+		"data":    result,
+	})
+}
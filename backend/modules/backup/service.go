@@ -0,0 +1,479 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// manifestName 备份清单文件名
+	manifestName = "manifest.json"
+	// appName 备份清单中的应用标识
+	appName = "ProxyStation"
+	// encryptedMagic 加密备份的文件头
+	encryptedMagic = "PSENC1"
+	// maxRestoreSize 恢复时解压后的总大小上限
+	maxRestoreSize = 512 << 20
+	// maxRestoreFiles 恢复时的文件数上限
+	maxRestoreFiles = 20000
+)
+
+// excludedDirs 不备份的目录：核心程序、运行时文件、PID 文件、日志以及备份本身
+var excludedDirs = []string{"cores", "runtime", "run", "logs", "backups"}
+
+// excludedFiles 不备份的文件：会话、审计日志和本机相关的缓存
+var excludedFiles = map[string]bool{
+	"sessions.json":             true,
+	"audit.log":                 true,
+	"audit.log.1":               true,
+	"browser_proxy_backup.json": true,
+	"system_proxy_backup.json":  true,
+	"oui.txt":                   true,
+	"core_status.json":          true,
+	"delay_cache.json":          true,
+	"speed_cache.json":          true,
+	"node_health.json":          true,
+}
+
+// excludedExts 不备份的扩展名：GEO 数据可重新下载，临时文件无意义
+var excludedExts = []string{".dat", ".mmdb", ".metadb", ".tmp"}
+
+// Options 备份选项
+type Options struct {
+	History  bool   // 包含配置历史
+	Password string // 非空时加密备份
+}
+
+// Manifest 备份清单
+type Manifest struct {
+	App       string   `json:"app"`
+	Version   string   `json:"version"`
+	CreatedAt string   `json:"createdAt"`
+	Hostname  string   `json:"hostname,omitempty"`
+	History   bool     `json:"history"`
+	Files     []string `json:"files"`
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Manifest        *Manifest `json:"manifest"`
+	Restored        int       `json:"restored"`
+	Skipped         []string  `json:"skipped,omitempty"`
+	PreRestore      string    `json:"preRestore"` // 恢复前自动备份的文件
+	RestartRequired bool      `json:"restartRequired"`
+}
+
+// Service 备份与恢复服务
+type Service struct {
+	dataDir string
+	version string
+	mu      sync.Mutex // 防止同时恢复
+}
+
+// NewService 创建备份服务
+func NewService(dataDir string) *Service {
+	return &Service{dataDir: dataDir}
+}
+
+// SetVersion 设置写入备份清单的程序版本
+func (s *Service) SetVersion(version string) {
+	s.version = version
+}
+
+// IsEncrypted 数据是否为加密备份
+func IsEncrypted(header []byte) bool {
+	return bytes.HasPrefix(header, []byte(encryptedMagic))
+}
+
+// excluded 相对路径（/ 分隔）是否不参与备份和恢复
+func excluded(rel string, history bool) bool {
+	first := strings.SplitN(rel, "/", 2)[0]
+	for _, dir := range excludedDirs {
+		if first == dir {
+			return true
+		}
+	}
+	// 规则集目录只保留用户自定义的文本规则，下载的规则集可重新获取
+	if first == "ruleset" && rel != "ruleset" && !strings.HasPrefix(rel, "ruleset/text") {
+		return true
+	}
+	if !history && (rel == "configs/history" || strings.HasPrefix(rel, "configs/history/")) {
+		return true
+	}
+	if excludedFiles[rel] || strings.HasPrefix(path.Base(rel), ".") {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(rel))
+	for _, e := range excludedExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// collectFiles 列出需要备份的文件（相对路径）
+func (s *Service) collectFiles(history bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(s.dataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dataDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if excluded(rel, history) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// WriteBackup 将数据目录打包为 tar.gz 写入 w，设置密码时整体加密
+func (s *Service) WriteBackup(w io.Writer, opts Options) error {
+	files, err := s.collectFiles(opts.History)
+	if err != nil {
+		return fmt.Errorf("读取数据目录失败: %w", err)
+	}
+
+	if opts.Password == "" {
+		return s.writeArchive(w, files, opts.History)
+	}
+
+	var buf bytes.Buffer
+	if err := s.writeArchive(&buf, files, opts.History); err != nil {
+		return err
+	}
+	sealed, err := encrypt(buf.Bytes(), opts.Password)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sealed)
+	return err
+}
+
+// writeArchive 写入清单和文件
+func (s *Service) writeArchive(w io.Writer, files []string, history bool) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	hostname, _ := os.Hostname()
+	manifest, _ := json.MarshalIndent(Manifest{
+		App:       appName,
+		Version:   s.version,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Hostname:  hostname,
+		History:   history,
+		Files:     files,
+	}, "", "  ")
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		if err := addFile(tw, filepath.Join(s.dataDir, filepath.FromSlash(rel)), "data/"+rel); err != nil {
+			return fmt.Errorf("打包 %s 失败: %w", rel, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
+
+// SaveSnapshot 在 backups 目录保存一份未加密的备份，返回文件路径
+func (s *Service) SaveSnapshot(prefix string) (string, error) {
+	dir := filepath.Join(s.dataDir, "backups")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", prefix, time.Now().Format("20060102-150405")))
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := s.WriteBackup(f, Options{History: true}); err != nil {
+		f.Close()
+		os.Remove(p)
+		return "", err
+	}
+	return p, f.Close()
+}
+
+// Restore 校验并恢复备份，恢复前自动保存当前数据
+// 服务在内存中的状态不会刷新，恢复后需要重启 ProxyStation
+func (s *Service) Restore(r io.Reader, password string) (*RestoreResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(len(encryptedMagic))
+	var archive io.Reader = br
+	if IsEncrypted(header) {
+		if password == "" {
+			return nil, errPasswordRequired
+		}
+		sealed, err := io.ReadAll(io.LimitReader(br, maxRestoreSize))
+		if err != nil {
+			return nil, err
+		}
+		plain, err := decrypt(sealed, password)
+		if err != nil {
+			return nil, err
+		}
+		archive = bytes.NewReader(plain)
+	}
+
+	// 先解压到临时目录，全部校验通过后再替换
+	tmpDir, err := os.MkdirTemp(s.dataDir, ".restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest, files, skipped, err := extract(archive, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	preRestore, err := s.SaveSnapshot("pre-restore")
+	if err != nil {
+		return nil, fmt.Errorf("备份当前数据失败: %w", err)
+	}
+
+	for _, rel := range files {
+		dst := filepath.Join(s.dataDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(filepath.Join(tmpDir, filepath.FromSlash(rel)), dst); err != nil {
+			return nil, fmt.Errorf("恢复 %s 失败: %w（恢复前的数据已备份到 %s）", rel, err, preRestore)
+		}
+	}
+
+	fmt.Printf("📦 已从备份恢复 %d 个文件（备份时间 %s，版本 %s）\n", len(files), manifest.CreatedAt, manifest.Version)
+	return &RestoreResult{
+		Manifest:        manifest,
+		Restored:        len(files),
+		Skipped:         skipped,
+		PreRestore:      preRestore,
+		RestartRequired: true,
+	}, nil
+}
+
+var (
+	errPasswordRequired = errors.New("备份已加密，请提供密码")
+	errWrongPassword    = errors.New("密码错误或备份已损坏")
+)
+
+// extract 校验并解压备份到 dir，返回清单、解压的文件和跳过的文件
+func extract(r io.Reader, dir string) (*Manifest, []string, []string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("不是有效的备份文件: %w", err)
+	}
+	defer gz.Close()
+
+	var (
+		manifest *Manifest
+		files    []string
+		skipped  []string
+		total    int64
+	)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("备份文件已损坏: %w", err)
+		}
+
+		if hdr.Name == manifestName {
+			data, err := io.ReadAll(io.LimitReader(tr, 4<<20))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil || manifest.App != appName {
+				return nil, nil, nil, fmt.Errorf("备份清单无效")
+			}
+			continue
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		rel := strings.TrimPrefix(hdr.Name, "data/")
+		clean := path.Clean(rel)
+		if rel == hdr.Name || clean != rel || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, nil, nil, fmt.Errorf("备份中包含非法路径: %s", hdr.Name)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, nil, fmt.Errorf("备份中包含不支持的文件类型: %s", hdr.Name)
+		}
+		if excluded(clean, true) {
+			skipped = append(skipped, clean)
+			continue
+		}
+
+		total += hdr.Size
+		if total > maxRestoreSize || len(files) >= maxRestoreFiles {
+			return nil, nil, nil, fmt.Errorf("备份文件过大")
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(clean))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, nil, nil, err
+		}
+		// 不保留可执行权限，原本仅所有者可读的文件保持 0600
+		mode := os.FileMode(0644)
+		if hdr.Mode&0077 == 0 {
+			mode = 0600
+		}
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		_, err = io.CopyN(f, tr, hdr.Size)
+		f.Close()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("解压 %s 失败: %w", clean, err)
+		}
+		files = append(files, clean)
+	}
+
+	if manifest == nil {
+		return nil, nil, nil, fmt.Errorf("备份中缺少 %s", manifestName)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("备份中没有可恢复的文件")
+	}
+	return manifest, files, skipped, nil
+}
+
+// deriveKey 使用 scrypt 从密码派生 AES-256 密钥
+func deriveKey(password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+}
+
+// encrypt 加密备份：文件头 + 16 字节盐 + nonce + AES-GCM 密文
+func encrypt(plain []byte, password string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+len(salt)+len(nonce)+len(plain)+gcm.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, []byte(encryptedMagic)), nil
+}
+
+// decrypt 解密备份
+func decrypt(sealed []byte, password string) ([]byte, error) {
+	sealed = sealed[len(encryptedMagic):]
+	if len(sealed) < 16 {
+		return nil, errWrongPassword
+	}
+	key, err := deriveKey(password, sealed[:16])
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[16:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errWrongPassword
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(encryptedMagic))
+	if err != nil {
+		return nil, errWrongPassword
+	}
+	return plain, nil
+}
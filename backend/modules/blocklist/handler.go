@@ -0,0 +1,108 @@
+package blocklist
+
+import (
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 广告拦截 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/config", h.GetConfig)
+	r.PUT("/config", h.SetConfig)
+	r.PUT("/lists/:id/enabled", h.SetListEnabled)
+	r.GET("/status", h.GetStatus)
+	r.POST("/update", h.Update)
+}
+
+// GetConfig 获取配置（列表、白名单、更新设置）
+func (h *Handler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// SetConfig 保存配置，规则变化时重新生成核心配置
+func (h *Handler) SetConfig(c *gin.Context) {
+	var cfg Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.service.SetConfig(cfg); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// SetListEnabled 启用或停用单个列表
+func (h *Handler) SetListEnabled(c *gin.Context) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.service.SetListEnabled(c.Param("id"), req.Enabled); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// GetStatus 获取合并后的规则状态
+func (h *Handler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetStatus(),
+	})
+}
+
+// Update 异步下载所有启用的列表
+func (h *Handler) Update(c *gin.Context) {
+	if h.service.IsUpdating() {
+		apierror.Message(c, http.StatusConflict, "正在更新中，请稍后再试")
+		return
+	}
+
+	go h.service.Update()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "开始更新广告拦截列表",
+	})
+}
@@ -0,0 +1,103 @@
+package blocklist
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+)
+
+// hostsIgnored hosts 文件中不作为拦截域名的条目
+var hostsIgnored = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true, "broadcasthost": true,
+	"ip6-localhost": true, "ip6-loopback": true, "ip6-localnet": true, "ip6-mcastprefix": true,
+	"ip6-allnodes": true, "ip6-allrouters": true, "ip6-allhosts": true, "0.0.0.0": true,
+}
+
+// parseList 解析拦截列表，自动识别每行的格式：
+// hosts（0.0.0.0 example.com）、adblock（||example.com^，@@|| 为例外）、纯域名（example.com）
+// 带路径、通配符或修饰符的 adblock 规则无法转换为域名规则，会被忽略
+func parseList(r io.Reader) (blocked, allowed []string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '#' || line[0] == '[' {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@||"):
+			if d, ok := parseAdblockDomain(line[4:]); ok {
+				allowed = append(allowed, d)
+			}
+		case strings.HasPrefix(line, "||"):
+			if d, ok := parseAdblockDomain(line[2:]); ok {
+				blocked = append(blocked, d)
+			}
+		default:
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
+				for _, f := range fields[1:] {
+					if d := strings.ToLower(f); !hostsIgnored[d] && isDomain(d) {
+						blocked = append(blocked, d)
+					}
+				}
+			} else if len(fields) == 1 {
+				if d := strings.ToLower(strings.TrimPrefix(fields[0], "+.")); isDomain(d) {
+					blocked = append(blocked, d)
+				}
+			}
+		}
+	}
+	return blocked, allowed, scanner.Err()
+}
+
+// parseAdblockDomain 解析 "example.com^" 或 "example.com^$important"
+func parseAdblockDomain(rule string) (string, bool) {
+	if i := strings.IndexByte(rule, '$'); i >= 0 {
+		if rule[i+1:] != "important" {
+			return "", false
+		}
+		rule = rule[:i]
+	}
+	rule = strings.TrimSuffix(rule, "^")
+	rule = strings.TrimSuffix(rule, "|")
+	d := strings.ToLower(rule)
+	return d, isDomain(d)
+}
+
+// isDomain 是否为合法域名（至少两级，只包含字母、数字、- 和 _）
+func isDomain(s string) bool {
+	if len(s) > 253 || !strings.Contains(s, ".") || net.ParseIP(s) != nil {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// coveredBy 域名本身或其父域名是否在集合中
+func coveredBy(domain string, set map[string]bool) bool {
+	for {
+		if set[domain] {
+			return true
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
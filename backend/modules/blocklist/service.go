@@ -0,0 +1,539 @@
+package blocklist
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultLists 内置的拦截列表（首次使用时添加）
+var defaultLists = []List{
+	{ID: "adguard-dns", Name: "AdGuard DNS filter", URL: "https://adguardteam.github.io/AdGuardSDNSFilter/Filters/filter.txt", Enabled: true},
+	{ID: "anti-ad", Name: "anti-AD", URL: "https://anti-ad.net/easylist.txt"},
+	{ID: "stevenblack", Name: "StevenBlack hosts", URL: "https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts"},
+}
+
+// listIDPattern 列表 ID 同时用作缓存文件名
+var listIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+const (
+	defaultUpdateInterval = 24 // 小时
+	maxListSize           = 64 << 20
+)
+
+// List 拦截列表（hosts / adblock / 纯域名格式）
+type List struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Enabled     bool   `json:"enabled"`
+	DomainCount int    `json:"domainCount"` // 最近一次下载解析出的域名数
+	LastUpdate  string `json:"lastUpdate,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+// Config 广告拦截配置（blocklist.json）
+type Config struct {
+	Enabled        bool     `json:"enabled"`
+	Lists          []*List  `json:"lists"`
+	Whitelist      []string `json:"whitelist"`      // 不拦截的域名（包含子域名）
+	AutoUpdate     bool     `json:"autoUpdate"`     // 定时更新列表
+	UpdateInterval int      `json:"updateInterval"` // 更新间隔（小时）
+	LastCheck      string   `json:"lastCheck"`
+}
+
+// Status 合并后的规则状态
+type Status struct {
+	Updating    bool   `json:"updating"`
+	LastCheck   string `json:"lastCheck"`
+	DomainCount int    `json:"domainCount"` // 合并去重后生成到规则中的域名数
+	BuiltAt     string `json:"builtAt,omitempty"`
+}
+
+// Rules 生成到核心配置中的规则文件（由 server 转换后注入代理模块）
+type Rules struct {
+	MihomoPath  string   // Mihomo text 格式规则集合，每行 +.domain
+	SingBoxPath string   // Sing-Box source 格式规则集
+	Whitelist   []string // 父域名被拦截时需要例外的域名
+}
+
+// Service 广告拦截列表服务
+type Service struct {
+	dataDir     string
+	configPath  string
+	config      *Config
+	updating    bool
+	domainCount int
+	builtAt     string
+	whitelist   []string // 生成规则时需要例外的域名
+	digest      string   // 生效规则的校验值，用于判断是否需要重新生成配置
+	onChanged   func() error
+	mu          sync.RWMutex
+}
+
+// NewService 创建广告拦截服务
+func NewService(dataDir string) *Service {
+	s := &Service{
+		dataDir:    filepath.Join(dataDir, "blocklist"),
+		configPath: filepath.Join(dataDir, "blocklist.json"),
+		config: &Config{
+			Whitelist:      []string{},
+			AutoUpdate:     true,
+			UpdateInterval: defaultUpdateInterval,
+		},
+	}
+	for _, l := range defaultLists {
+		l := l
+		s.config.Lists = append(s.config.Lists, &l)
+	}
+	s.load()
+	os.MkdirAll(s.dataDir, 0755)
+
+	s.mu.Lock()
+	if err := s.rebuild(); err != nil {
+		fmt.Printf("⚠️ 生成广告拦截规则失败: %v\n", err)
+	}
+	s.mu.Unlock()
+
+	go s.autoUpdateLoop()
+	return s
+}
+
+// SetOnChanged 设置拦截规则变化后的回调（用于重新生成配置）
+func (s *Service) SetOnChanged(fn func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChanged = fn
+}
+
+// load 加载配置
+func (s *Service) load() {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, s.config); err != nil {
+		fmt.Printf("⚠️ 解析广告拦截配置失败: %v\n", err)
+		return
+	}
+	if s.config.UpdateInterval <= 0 {
+		s.config.UpdateInterval = defaultUpdateInterval
+	}
+}
+
+// save 保存配置（调用方需持有锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.configPath, data, 0644)
+}
+
+func (s *Service) listPath(id string) string {
+	return filepath.Join(s.dataDir, "list-"+id+".txt")
+}
+
+func (s *Service) mihomoPath() string {
+	return filepath.Join(s.dataDir, "blocklist.txt")
+}
+
+func (s *Service) singBoxPath() string {
+	return filepath.Join(s.dataDir, "blocklist.json")
+}
+
+// GetConfig 获取配置
+func (s *Service) GetConfig() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg := *s.config
+	cfg.Lists = make([]*List, 0, len(s.config.Lists))
+	for _, l := range s.config.Lists {
+		copied := *l
+		cfg.Lists = append(cfg.Lists, &copied)
+	}
+	cfg.Whitelist = append([]string{}, s.config.Whitelist...)
+	return cfg
+}
+
+// SetConfig 更新配置（列表、白名单、更新间隔），新增的列表需要更新后才会生效
+func (s *Service) SetConfig(cfg Config) error {
+	ids := make(map[string]bool)
+	for _, l := range cfg.Lists {
+		if l == nil {
+			return fmt.Errorf("列表不能为空")
+		}
+		l.Name = strings.TrimSpace(l.Name)
+		l.URL = strings.TrimSpace(l.URL)
+		if !strings.HasPrefix(l.URL, "http://") && !strings.HasPrefix(l.URL, "https://") {
+			return fmt.Errorf("列表地址无效: %s", l.URL)
+		}
+		if l.Name == "" {
+			l.Name = l.URL
+		}
+		if l.ID == "" {
+			l.ID = uuid.New().String()
+		}
+		if !listIDPattern.MatchString(l.ID) {
+			return fmt.Errorf("列表 ID 无效: %s", l.ID)
+		}
+		if ids[l.ID] {
+			return fmt.Errorf("列表 ID 重复: %s", l.ID)
+		}
+		ids[l.ID] = true
+	}
+	whitelist := make([]string, 0, len(cfg.Whitelist))
+	for _, d := range cfg.Whitelist {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "+."))
+		if d == "" {
+			continue
+		}
+		if !isDomain(d) {
+			return fmt.Errorf("白名单域名无效: %s", d)
+		}
+		whitelist = append(whitelist, d)
+	}
+	if cfg.UpdateInterval <= 0 {
+		cfg.UpdateInterval = defaultUpdateInterval
+	}
+
+	s.mu.Lock()
+	// 保留已有列表的下载状态
+	previous := make(map[string]*List, len(s.config.Lists))
+	for _, l := range s.config.Lists {
+		previous[l.ID] = l
+	}
+	for _, l := range cfg.Lists {
+		if old, ok := previous[l.ID]; ok && old.URL == l.URL {
+			l.DomainCount, l.LastUpdate, l.LastError = old.DomainCount, old.LastUpdate, old.LastError
+		} else {
+			l.DomainCount, l.LastUpdate, l.LastError = 0, "", ""
+			os.Remove(s.listPath(l.ID))
+		}
+	}
+	cfg.Whitelist = whitelist
+	cfg.LastCheck = s.config.LastCheck
+	s.config = &cfg
+	if err := s.save(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	changed, err := s.rebuildChanged()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.notifyChanged(changed)
+}
+
+// SetListEnabled 启用或停用单个列表
+func (s *Service) SetListEnabled(id string, enabled bool) error {
+	s.mu.Lock()
+	var found bool
+	for _, l := range s.config.Lists {
+		if l.ID == id {
+			l.Enabled, found = enabled, true
+		}
+	}
+	if !found {
+		s.mu.Unlock()
+		return fmt.Errorf("列表不存在")
+	}
+	if err := s.save(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	changed, err := s.rebuildChanged()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.notifyChanged(changed)
+}
+
+// GetStatus 获取状态
+func (s *Service) GetStatus() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Status{
+		Updating:    s.updating,
+		LastCheck:   s.config.LastCheck,
+		DomainCount: s.domainCount,
+		BuiltAt:     s.builtAt,
+	}
+}
+
+// IsUpdating 是否正在更新
+func (s *Service) IsUpdating() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updating
+}
+
+// GetRules 获取生成到核心配置中的规则文件，未启用或没有可用域名时返回 nil
+func (s *Service) GetRules() *Rules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.config.Enabled || s.domainCount == 0 {
+		return nil
+	}
+	return &Rules{
+		MihomoPath:  s.mihomoPath(),
+		SingBoxPath: s.singBoxPath(),
+		Whitelist:   append([]string{}, s.whitelist...),
+	}
+}
+
+// Update 下载所有启用的列表并重新生成规则
+func (s *Service) Update() error {
+	s.mu.Lock()
+	if s.updating {
+		s.mu.Unlock()
+		return fmt.Errorf("正在更新中，请稍后再试")
+	}
+	s.updating = true
+	var lists []List
+	for _, l := range s.config.Lists {
+		if l.Enabled {
+			lists = append(lists, *l)
+		}
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.updating = false
+		s.mu.Unlock()
+	}()
+
+	results := make(map[string]List, len(lists))
+	for _, l := range lists {
+		count, err := s.download(l)
+		l.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
+		l.LastError = ""
+		if err != nil {
+			l.LastError = err.Error()
+			fmt.Printf("⚠️ 广告拦截列表更新失败: %s: %v\n", l.Name, err)
+		} else {
+			l.DomainCount = count
+			fmt.Printf("🛡️ 广告拦截列表已更新: %s (%d 个域名)\n", l.Name, count)
+		}
+		results[l.ID] = l
+	}
+
+	s.mu.Lock()
+	for _, l := range s.config.Lists {
+		if r, ok := results[l.ID]; ok {
+			l.DomainCount, l.LastUpdate, l.LastError = r.DomainCount, r.LastUpdate, r.LastError
+		}
+	}
+	s.config.LastCheck = time.Now().Format("2006-01-02 15:04:05")
+	s.save()
+	changed, err := s.rebuildChanged()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.notifyChanged(changed)
+}
+
+// download 下载列表并校验能否解析，返回解析出的拦截域名数
+func (s *Service) download(l List) (int, error) {
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Get(l.URL)
+	if err != nil {
+		return 0, fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxListSize+1))
+	if err != nil {
+		return 0, fmt.Errorf("下载失败: %w", err)
+	}
+	if len(data) > maxListSize {
+		return 0, fmt.Errorf("列表超过 %d MB", maxListSize>>20)
+	}
+
+	blocked, _, err := parseList(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("解析失败: %w", err)
+	}
+	if len(blocked) == 0 {
+		return 0, fmt.Errorf("列表中没有可识别的域名")
+	}
+
+	tmpPath := s.listPath(l.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return 0, fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.listPath(l.ID)); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("写入文件失败: %w", err)
+	}
+	return len(blocked), nil
+}
+
+// rebuildChanged 重新生成规则，返回生效的规则是否变化（调用方需持有锁）
+func (s *Service) rebuildChanged() (bool, error) {
+	before := s.digest
+	if err := s.rebuild(); err != nil {
+		return false, err
+	}
+	return s.digest != before, nil
+}
+
+// notifyChanged 规则变化时调用回调（不能持有锁）
+func (s *Service) notifyChanged(changed bool) error {
+	s.mu.RLock()
+	fn := s.onChanged
+	s.mu.RUnlock()
+	if !changed || fn == nil {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return fmt.Errorf("规则已更新，但应用到核心失败: %w", err)
+	}
+	return nil
+}
+
+// rebuild 合并启用的列表，去掉白名单和已被父域名覆盖的域名，写入 Mihomo 和 Sing-Box 规则文件（调用方需持有锁）
+func (s *Service) rebuild() error {
+	blockedSet := make(map[string]bool)
+	allowedSet := make(map[string]bool)
+	for _, d := range s.config.Whitelist {
+		allowedSet[d] = true
+	}
+	for _, l := range s.config.Lists {
+		if !l.Enabled {
+			continue
+		}
+		f, err := os.Open(s.listPath(l.ID))
+		if err != nil {
+			continue // 尚未下载
+		}
+		blocked, allowed, err := parseList(f)
+		f.Close()
+		if err != nil {
+			fmt.Printf("⚠️ 解析广告拦截列表失败: %s: %v\n", l.Name, err)
+			continue
+		}
+		for _, d := range blocked {
+			blockedSet[d] = true
+		}
+		for _, d := range allowed {
+			allowedSet[d] = true
+		}
+	}
+
+	domains := make([]string, 0, len(blockedSet))
+	for d := range blockedSet {
+		if coveredBy(d, allowedSet) {
+			continue
+		}
+		// 父域名已被拦截时不需要单独列出
+		if i := strings.IndexByte(d, '.'); i >= 0 && coveredBy(d[i+1:], blockedSet) {
+			continue
+		}
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+
+	// 例外只需要保留父域名会被拦截的域名，其余的已经从列表中去掉
+	kept := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		kept[d] = true
+	}
+	whitelist := []string{}
+	for d := range allowedSet {
+		if i := strings.IndexByte(d, '.'); i >= 0 && coveredBy(d[i+1:], kept) {
+			whitelist = append(whitelist, d)
+		}
+	}
+	sort.Strings(whitelist)
+
+	var text strings.Builder
+	for _, d := range domains {
+		text.WriteString("+.")
+		text.WriteString(d)
+		text.WriteByte('\n')
+	}
+	ruleSet, err := json.Marshal(map[string]interface{}{
+		"version": 2,
+		"rules":   []map[string]interface{}{{"domain_suffix": domains}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.mihomoPath(), []byte(text.String())); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.singBoxPath(), ruleSet); err != nil {
+		return err
+	}
+
+	// 未启用时规则不生效，校验值为空
+	s.digest = ""
+	if s.config.Enabled && len(domains) > 0 {
+		sum := sha256.Sum256([]byte(text.String() + strings.Join(whitelist, ",")))
+		s.digest = hex.EncodeToString(sum[:])
+	}
+	s.domainCount = len(domains)
+	s.whitelist = whitelist
+	s.builtAt = time.Now().Format("2006-01-02 15:04:05")
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再替换，避免核心读到写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// autoUpdateLoop 定时自动更新
+func (s *Service) autoUpdateLoop() {
+	for {
+		time.Sleep(1 * time.Hour) // 每小时检查一次
+
+		s.mu.RLock()
+		run := s.config.Enabled && s.config.AutoUpdate
+		interval := s.config.UpdateInterval
+		lastCheck := s.config.LastCheck
+		s.mu.RUnlock()
+
+		if !run {
+			continue
+		}
+		if lastCheck != "" {
+			lastTime, err := time.ParseInLocation("2006-01-02 15:04:05", lastCheck, time.Local)
+			if err == nil && time.Since(lastTime) < time.Duration(interval)*time.Hour {
+				continue
+			}
+		}
+
+		if err := s.Update(); err != nil {
+			fmt.Printf("⚠️ 广告拦截列表自动更新: %v\n", err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"net/http"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -44,12 +46,9 @@ func (h *Handler) GetStatus(c *gin.Context) {
 }
 
 func (h *Handler) GetVersions(c *gin.Context) {
-	versions, err := h.service.GetLatestVersions()
+	versions, err := h.service.GetLatestVersions(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -66,18 +65,12 @@ func (h *Handler) SwitchCore(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SwitchCore(req.CoreType); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -87,9 +80,17 @@ func (h *Handler) SwitchCore(c *gin.Context) {
 	})
 }
 
+// DownloadCore 下载/更新核心
+// 查询参数 restart=true 时，替换完成后自动重启正在使用该核心的代理服务
 func (h *Handler) DownloadCore(c *gin.Context) {
 	coreType := c.Param("core")
-	go h.service.DownloadCore(coreType)
+	if _, ok := h.service.GetStatus().Cores[coreType]; !ok {
+		apierror.Message(c, http.StatusBadRequest, "unknown core type: "+coreType)
+		return
+	}
+
+	restart := c.Query("restart") == "true"
+	go h.service.UpdateCore(coreType, restart)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -110,12 +111,9 @@ func (h *Handler) GetDownloadProgress(c *gin.Context) {
 
 // RefreshVersions 手动刷新版本信息
 func (h *Handler) RefreshVersions(c *gin.Context) {
-	versions, err := h.service.RefreshVersions()
+	versions, err := h.service.RefreshVersions(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
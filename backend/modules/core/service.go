@@ -3,6 +3,9 @@ package core
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +17,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/httpclient"
 )
 
 type CoreType string
@@ -40,12 +46,17 @@ type Core struct {
 	LatestVersion string `json:"latestVersion"`
 	Installed     bool   `json:"installed"`
 	Path          string `json:"path"`
+	HasUpdate     bool   `json:"hasUpdate"`        // 是否有新版本
+	SHA256        string `json:"sha256,omitempty"` // 最近一次下载的压缩包校验值
 }
 
 type DownloadProgress struct {
 	Downloading bool    `json:"downloading"`
 	Progress    float64 `json:"progress"`
 	Speed       int64   `json:"speed"`
+	Verified    bool    `json:"verified"`        // 校验和是否通过
+	Restarted   bool    `json:"restarted"`       // 是否已自动重启服务
+	Stage       string  `json:"stage,omitempty"` // 当前阶段: downloading/verifying/installing/restarting/done
 	Error       string  `json:"error,omitempty"`
 }
 
@@ -55,7 +66,8 @@ type Service struct {
 	cores            map[string]*Core
 	downloadProgress map[string]*DownloadProgress
 	mu               sync.RWMutex
-	onCoreSwitch     func(coreType string) // 核心切换回调
+	onCoreSwitch     func(coreType string)       // 核心切换回调
+	onCoreUpdated    func(coreType string) error // 核心更新后回调（用于自动重启服务）
 }
 
 // 持久化状态
@@ -63,6 +75,7 @@ type SavedCoreStatus struct {
 	CurrentCore    string            `json:"currentCore"`
 	Versions       map[string]string `json:"versions"`
 	LatestVersions map[string]string `json:"latestVersions"`
+	Checksums      map[string]string `json:"checksums,omitempty"`
 	LastChecked    time.Time         `json:"lastChecked"`
 }
 
@@ -121,6 +134,12 @@ func (s *Service) loadSavedStatus() {
 			core.LatestVersion = latestVersion
 		}
 	}
+
+	for name, checksum := range saved.Checksums {
+		if core, ok := s.cores[name]; ok {
+			core.SHA256 = checksum
+		}
+	}
 }
 
 func (s *Service) saveStatus() error {
@@ -129,6 +148,7 @@ func (s *Service) saveStatus() error {
 		CurrentCore:    string(s.currentCore),
 		Versions:       make(map[string]string),
 		LatestVersions: make(map[string]string),
+		Checksums:      make(map[string]string),
 		LastChecked:    time.Now(),
 	}
 	for name, core := range s.cores {
@@ -138,6 +158,9 @@ func (s *Service) saveStatus() error {
 		if core.LatestVersion != "" {
 			saved.LatestVersions[name] = core.LatestVersion
 		}
+		if core.SHA256 != "" {
+			saved.Checksums[name] = core.SHA256
+		}
 	}
 	s.mu.RUnlock()
 
@@ -179,17 +202,7 @@ func (s *Service) getCoreVersion(coreType string) string {
 	binPath := s.getCoreBinaryPath(coreType)
 
 	// 执行核心获取版本
-	var cmd *exec.Cmd
-	switch coreType {
-	case "mihomo":
-		cmd = exec.Command(binPath, "-v")
-	case "singbox":
-		cmd = exec.Command(binPath, "version")
-	default:
-		return "unknown"
-	}
-
-	output, err := cmd.Output()
+	output, err := s.runVersionCommand(coreType, binPath)
 	if err != nil {
 		// 如果有保存的版本，使用保存的
 		if core, ok := s.cores[coreType]; ok && core.Version != "" {
@@ -208,6 +221,24 @@ func (s *Service) getCoreVersion(coreType string) string {
 	return "unknown"
 }
 
+// runVersionCommand 执行核心的版本命令（带超时，防止异常二进制卡住）
+func (s *Service) runVersionCommand(coreType, binPath string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch coreType {
+	case "mihomo":
+		cmd = exec.CommandContext(ctx, binPath, "-v")
+	case "singbox":
+		cmd = exec.CommandContext(ctx, binPath, "version")
+	default:
+		return nil, fmt.Errorf("unknown core type: %s", coreType)
+	}
+
+	return cmd.Output()
+}
+
 // parseVersionFromOutput 从输出中解析版本号
 func (s *Service) parseVersionFromOutput(coreType, output string) string {
 	lines := strings.Split(output, "\n")
@@ -246,8 +277,13 @@ func (s *Service) parseVersionFromOutput(coreType, output string) string {
 }
 
 func (s *Service) GetStatus() *CoreStatus {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, core := range s.cores {
+		core.HasUpdate = core.Installed && core.LatestVersion != "" &&
+			core.Version != "unknown" && core.Version != core.LatestVersion
+	}
 
 	return &CoreStatus{
 		CurrentCore: s.currentCore,
@@ -255,10 +291,11 @@ func (s *Service) GetStatus() *CoreStatus {
 	}
 }
 
-func (s *Service) GetLatestVersions() (map[string]string, error) {
+// GetLatestVersions 查询各核心的最新版本，ctx 取消时中止
+func (s *Service) GetLatestVersions(ctx context.Context) (map[string]string, error) {
 	versions := make(map[string]string)
 
-	mihomoVersion, err := s.fetchMihomoLatestVersion()
+	mihomoVersion, err := s.fetchMihomoLatestVersion(ctx)
 	if err == nil {
 		versions["mihomo"] = mihomoVersion
 		s.mu.Lock()
@@ -266,7 +303,7 @@ func (s *Service) GetLatestVersions() (map[string]string, error) {
 		s.mu.Unlock()
 	}
 
-	singboxVersion, err := s.fetchSingboxLatestVersion()
+	singboxVersion, err := s.fetchSingboxLatestVersion(ctx)
 	if err == nil {
 		versions["singbox"] = singboxVersion
 		s.mu.Lock()
@@ -277,35 +314,27 @@ func (s *Service) GetLatestVersions() (map[string]string, error) {
 	return versions, nil
 }
 
-func (s *Service) fetchMihomoLatestVersion() (string, error) {
-	resp, err := http.Get("https://api.github.com/repos/MetaCubeX/mihomo/releases/latest")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var release struct {
-		TagName string `json:"tag_name"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", err
-	}
+func (s *Service) fetchMihomoLatestVersion(ctx context.Context) (string, error) {
+	return fetchLatestVersion(ctx, "MetaCubeX/mihomo")
+}
 
-	// 去掉版本号前的 v 前缀
-	version := release.TagName
-	if len(version) > 0 && version[0] == 'v' {
-		version = version[1:]
-	}
-	return version, nil
+func (s *Service) fetchSingboxLatestVersion(ctx context.Context) (string, error) {
+	return fetchLatestVersion(ctx, "SagerNet/sing-box")
 }
 
-func (s *Service) fetchSingboxLatestVersion() (string, error) {
-	resp, err := http.Get("https://api.github.com/repos/SagerNet/sing-box/releases/latest")
+// fetchLatestVersion 查询 GitHub 仓库最新 Release 的版本号（去掉 v 前缀）
+func fetchLatestVersion(ctx context.Context, repo string) (string, error) {
+	ctx, cancel := httpclient.WithTimeout(ctx, httpclient.FetchTimeout())
+	defer cancel()
+
+	resp, err := httpclient.Get(ctx, "https://api.github.com/repos/"+repo+"/releases/latest")
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
 
 	var release struct {
 		TagName string `json:"tag_name"`
@@ -333,7 +362,7 @@ func (s *Service) SwitchCore(coreType string) error {
 	}
 
 	if !core.Installed {
-		return fmt.Errorf("core %s is not installed", coreType)
+		return apierror.New(apierror.CodeCoreNotInstalled, fmt.Sprintf("core %s is not installed", coreType))
 	}
 
 	s.currentCore = CoreType(coreType)
@@ -363,9 +392,72 @@ func (s *Service) GetCurrentCore() string {
 	return string(s.currentCore)
 }
 
+// SetOnCoreUpdated 设置核心更新完成回调
+func (s *Service) SetOnCoreUpdated(callback func(coreType string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCoreUpdated = callback
+}
+
+// UpdateCore 下载/更新核心，restart 为 true 时在替换完成后自动重启代理服务
+func (s *Service) UpdateCore(coreType string, restart bool) error {
+	if err := s.DownloadCore(coreType); err != nil {
+		return err
+	}
+	if !restart {
+		return nil
+	}
+
+	s.mu.Lock()
+	callback := s.onCoreUpdated
+	s.mu.Unlock()
+
+	if callback == nil {
+		return nil
+	}
+
+	s.setProgressStage(coreType, "restarting")
+	if err := callback(coreType); err != nil {
+		s.setProgressError(coreType, fmt.Sprintf("重启服务失败: %v", err))
+		return fmt.Errorf("重启服务失败: %v", err)
+	}
+
+	s.mu.Lock()
+	s.downloadProgress[coreType].Restarted = true
+	s.downloadProgress[coreType].Stage = "done"
+	s.mu.Unlock()
+	return nil
+}
+
+// setProgressError 记录下载错误
+func (s *Service) setProgressError(coreType, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if progress, ok := s.downloadProgress[coreType]; ok {
+		progress.Error = message
+	}
+}
+
+// setProgressStage 更新下载阶段
+func (s *Service) setProgressStage(coreType, stage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if progress, ok := s.downloadProgress[coreType]; ok {
+		progress.Stage = stage
+	}
+}
+
 func (s *Service) DownloadCore(coreType string) error {
 	s.mu.Lock()
-	s.downloadProgress[coreType] = &DownloadProgress{Downloading: true}
+	if _, ok := s.cores[coreType]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("unknown core type: %s", coreType)
+	}
+	if progress, ok := s.downloadProgress[coreType]; ok && progress.Downloading {
+		s.mu.Unlock()
+		return apierror.New(apierror.CodeBusy, fmt.Sprintf("%s 正在下载中", coreType))
+	}
+	s.downloadProgress[coreType] = &DownloadProgress{Downloading: true, Stage: "downloading"}
 	s.mu.Unlock()
 
 	defer func() {
@@ -383,14 +475,20 @@ func (s *Service) DownloadCore(coreType string) error {
 		return err
 	}
 
+	// 获取官方发布的校验和（获取失败时仅告警，不阻止下载）
+	expectedSum, err := s.fetchAssetChecksum(coreType, filepath.Base(officialURL))
+	if err != nil {
+		fmt.Printf("⚠️ 获取 %s 校验和失败: %v，将跳过校验\n", coreType, err)
+	}
+
 	// 尝试 CDN 下载
 	fmt.Printf("📦 尝试从 CDN 下载 %s: %s\n", coreType, cdnURL)
-	err = s.downloadFromURL(coreType, cdnURL)
+	err = s.downloadFromURL(coreType, cdnURL, expectedSum)
 	if err != nil {
 		fmt.Printf("⚠️ CDN 下载失败: %v，尝试官方地址...\n", err)
 		// 回退到官方地址
 		fmt.Printf("📦 尝试从官方下载 %s: %s\n", coreType, officialURL)
-		err = s.downloadFromURL(coreType, officialURL)
+		err = s.downloadFromURL(coreType, officialURL, expectedSum)
 		if err != nil {
 			s.mu.Lock()
 			s.downloadProgress[coreType].Error = err.Error()
@@ -399,18 +497,71 @@ func (s *Service) DownloadCore(coreType string) error {
 		}
 	}
 
+	s.setProgressStage(coreType, "done")
 	fmt.Printf("✅ %s 下载完成\n", coreType)
 	return nil
 }
 
-// downloadFromURL 从指定 URL 下载核心
-func (s *Service) downloadFromURL(coreType, downloadURL string) error {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 5 * time.Minute,
+// fetchAssetChecksum 从 GitHub Release 信息中获取资源文件的 SHA256
+func (s *Service) fetchAssetChecksum(coreType, assetName string) (string, error) {
+	var repo string
+	switch coreType {
+	case "mihomo":
+		repo = "MetaCubeX/mihomo"
+	case "singbox":
+		repo = "SagerNet/sing-box"
+	default:
+		return "", fmt.Errorf("unknown core type")
+	}
+
+	s.mu.RLock()
+	version := s.cores[coreType].LatestVersion
+	s.mu.RUnlock()
+
+	ctx, cancel := httpclient.WithTimeout(context.Background(), httpclient.FetchTimeout())
+	defer cancel()
+	resp, err := httpclient.Get(ctx, fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/v%s", repo, version))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var release struct {
+		Assets []struct {
+			Name   string `json:"name"`
+			Digest string `json:"digest"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != assetName {
+			continue
+		}
+		// digest 格式: sha256:<hex>
+		if sum, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok && sum != "" {
+			return strings.ToLower(sum), nil
+		}
+		return "", fmt.Errorf("release 未提供 %s 的校验和", assetName)
 	}
 
-	resp, err := client.Get(downloadURL)
+	return "", fmt.Errorf("release 中未找到 %s", assetName)
+}
+
+// downloadFromURL 从指定 URL 下载核心
+// expectedSum 不为空时校验压缩包的 SHA256
+func (s *Service) downloadFromURL(coreType, downloadURL, expectedSum string) error {
+	// 下载在后台进行，不随 API 请求取消
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resp, err := httpclient.Get(ctx, downloadURL)
 	if err != nil {
 		return fmt.Errorf("请求失败: %v", err)
 	}
@@ -423,7 +574,7 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 	os.MkdirAll(filepath.Join(s.dataDir, "cores"), 0755)
 
 	// 下载到临时文件
-	tmpFile := filepath.Join(s.dataDir, "cores", "download.tmp")
+	tmpFile := filepath.Join(s.dataDir, "cores", coreType+".download.tmp")
 	out, err := os.Create(tmpFile)
 	if err != nil {
 		return err
@@ -431,12 +582,18 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 
 	totalSize := resp.ContentLength
 	written := int64(0)
+	hasher := sha256.New()
 
 	buf := make([]byte, 32*1024)
 	for {
 		n, err := resp.Body.Read(buf)
 		if n > 0 {
-			out.Write(buf[:n])
+			if _, werr := out.Write(buf[:n]); werr != nil {
+				out.Close()
+				os.Remove(tmpFile)
+				return werr
+			}
+			hasher.Write(buf[:n])
 			written += int64(n)
 
 			s.mu.Lock()
@@ -455,21 +612,53 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 		}
 	}
 	out.Close()
+	defer os.Remove(tmpFile)
+
+	// 校验 SHA256
+	s.setProgressStage(coreType, "verifying")
+	actualSum := hex.EncodeToString(hasher.Sum(nil))
+	verified := false
+	if expectedSum != "" {
+		if actualSum != expectedSum {
+			return fmt.Errorf("校验和不匹配: 期望 %s, 实际 %s", expectedSum, actualSum)
+		}
+		verified = true
+		fmt.Printf("🔒 %s 校验和验证通过\n", coreType)
+	}
 
-	// 解压文件
+	// 先解压到临时文件，确认可以运行后再替换
+	s.setProgressStage(coreType, "installing")
 	binPath := s.getCoreBinaryPath(coreType)
-	if err := s.extractCore(tmpFile, binPath, coreType); err != nil {
-		os.Remove(tmpFile)
+	newPath := binPath + ".new"
+	if err := s.extractCore(tmpFile, newPath, coreType); err != nil {
+		os.Remove(newPath)
 		return fmt.Errorf("解压失败: %v", err)
 	}
-	os.Remove(tmpFile)
 
 	// 设置执行权限
-	os.Chmod(binPath, 0755)
+	os.Chmod(newPath, 0755)
+
+	output, err := s.runVersionCommand(coreType, newPath)
+	if err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("新核心无法运行: %v", err)
+	}
+	version := s.parseVersionFromOutput(coreType, string(output))
+
+	if err := s.swapBinary(newPath, binPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("替换核心失败: %v", err)
+	}
 
 	s.mu.Lock()
+	if version == "" {
+		version = s.cores[coreType].LatestVersion
+	}
 	s.cores[coreType].Installed = true
-	s.cores[coreType].Version = s.cores[coreType].LatestVersion
+	s.cores[coreType].Version = version
+	s.cores[coreType].SHA256 = actualSum
+	s.downloadProgress[coreType].Verified = verified
+	s.downloadProgress[coreType].Progress = 100
 	s.mu.Unlock()
 
 	// 持久化保存
@@ -478,6 +667,24 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 	return nil
 }
 
+// swapBinary 原子替换核心文件，旧版本保留为 .bak
+func (s *Service) swapBinary(newPath, binPath string) error {
+	backupPath := binPath + ".bak"
+	if _, err := os.Stat(binPath); err == nil {
+		os.Remove(backupPath)
+		if err := os.Rename(binPath, backupPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(newPath, binPath); err != nil {
+		// 恢复旧版本
+		os.Rename(backupPath, binPath)
+		return err
+	}
+	return nil
+}
+
 // extractCore 解压核心文件
 func (s *Service) extractCore(archivePath, destPath, coreType string) error {
 	file, err := os.Open(archivePath)
@@ -598,7 +805,7 @@ func (s *Service) Initialize(delaySeconds int) {
 		fmt.Printf("🔍 开始自动检测核心版本...\n")
 
 		// 1. 检测最新版本
-		s.GetLatestVersions()
+		s.GetLatestVersions(context.Background())
 
 		// 2. 检查是否需要自动下载 mihomo 核心
 		s.mu.RLock()
@@ -623,10 +830,10 @@ func (s *Service) Initialize(delaySeconds int) {
 }
 
 // RefreshVersions 手动刷新版本信息（前端点击刷新时调用）
-func (s *Service) RefreshVersions() (map[string]string, error) {
+func (s *Service) RefreshVersions(ctx context.Context) (map[string]string, error) {
 	fmt.Printf("🔄 手动刷新核心版本信息...\n")
 
-	versions, err := s.GetLatestVersions()
+	versions, err := s.GetLatestVersions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,115 @@
+package dnsserver
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// negativeCacheTTL 没有应答记录（NXDOMAIN / 空响应）时的缓存时间
+const negativeCacheTTL = 60 * time.Second
+
+// cacheEntry 缓存的响应
+type cacheEntry struct {
+	msg     dnsmessage.Message
+	expires time.Time
+	stored  time.Time
+}
+
+// dnsCache 按 名称+类型 缓存上游响应，满时优先淘汰已过期的条目
+type dnsCache struct {
+	size    int
+	entries map[string]*cacheEntry
+	mu      sync.Mutex
+}
+
+func newDNSCache(size int) *dnsCache {
+	return &dnsCache{size: size, entries: make(map[string]*cacheEntry)}
+}
+
+func cacheKey(q dnsmessage.Question) string {
+	return q.Name.String() + "|" + q.Type.String()
+}
+
+// get 读取缓存，返回的报文 TTL 已扣除缓存时长
+func (c *dnsCache) get(q dnsmessage.Question) (dnsmessage.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[cacheKey(q)]
+	if !ok {
+		return dnsmessage.Message{}, false
+	}
+	now := time.Now()
+	if now.After(e.expires) {
+		delete(c.entries, cacheKey(q))
+		return dnsmessage.Message{}, false
+	}
+
+	elapsed := uint32(now.Sub(e.stored).Seconds())
+	msg := e.msg
+	msg.Answers = append([]dnsmessage.Resource{}, e.msg.Answers...)
+	for i := range msg.Answers {
+		if msg.Answers[i].Header.TTL > elapsed {
+			msg.Answers[i].Header.TTL -= elapsed
+		} else {
+			msg.Answers[i].Header.TTL = 0
+		}
+	}
+	return msg, true
+}
+
+// put 写入缓存，TTL 取应答记录中的最小值
+func (c *dnsCache) put(q dnsmessage.Question, msg dnsmessage.Message) {
+	ttl := negativeCacheTTL
+	if len(msg.Answers) > 0 {
+		min := msg.Answers[0].Header.TTL
+		for _, rr := range msg.Answers[1:] {
+			if rr.Header.TTL < min {
+				min = rr.Header.TTL
+			}
+		}
+		ttl = time.Duration(min) * time.Second
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		c.evict()
+	}
+	now := time.Now()
+	c.entries[cacheKey(q)] = &cacheEntry{msg: msg, stored: now, expires: now.Add(ttl)}
+}
+
+// evict 清理过期条目，仍然满时随机淘汰一条（调用方需持有锁）
+func (c *dnsCache) evict() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, k)
+	}
+}
+
+// len 缓存条目数
+func (c *dnsCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// flush 清空缓存
+func (c *dnsCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
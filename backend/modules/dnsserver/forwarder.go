@@ -0,0 +1,278 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// tcpIdleTimeout TCP 连接空闲超时
+const tcpIdleTimeout = 10 * time.Second
+
+// Stats 查询统计（服务启动后累计）
+type Stats struct {
+	Queries   uint64 `json:"queries"`
+	CacheHits uint64 `json:"cacheHits"`
+	Blocked   uint64 `json:"blocked"`
+	Failed    uint64 `json:"failed"` // 所有上游均失败
+}
+
+// counters 查询计数器
+type counters struct {
+	queries, cacheHits, blocked, failed atomic.Uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Queries:   c.queries.Load(),
+		CacheHits: c.cacheHits.Load(),
+		Blocked:   c.blocked.Load(),
+		Failed:    c.failed.Load(),
+	}
+}
+
+// forwarder 运行中的 DNS 转发服务（UDP + TCP），配置变更时整体重建
+type forwarder struct {
+	upstreams     []upstream
+	rules         map[string][]upstream // 域名后缀 → 上游
+	blocked       map[string]bool       // 拦截的域名（包含子域名）
+	blockResponse string
+	cache         *dnsCache // CacheSize 为 0 时为 nil
+	stats         *counters
+
+	udp net.PacketConn
+	tcp net.Listener
+	wg  sync.WaitGroup
+}
+
+// newForwarder 根据配置构建转发器（不监听）
+func newForwarder(cfg Config, stats *counters) (*forwarder, error) {
+	f := &forwarder{
+		rules:         make(map[string][]upstream),
+		blocked:       make(map[string]bool),
+		blockResponse: cfg.BlockResponse,
+		stats:         stats,
+	}
+	for _, server := range cfg.Upstreams {
+		u, err := parseUpstream(server)
+		if err != nil {
+			return nil, err
+		}
+		f.upstreams = append(f.upstreams, u)
+	}
+	if len(f.upstreams) == 0 {
+		return nil, fmt.Errorf("至少需要一个上游 DNS")
+	}
+	for _, rule := range cfg.Rules {
+		var ups []upstream
+		for _, server := range rule.Upstreams {
+			u, err := parseUpstream(server)
+			if err != nil {
+				return nil, fmt.Errorf("域名 %s: %v", rule.Domain, err)
+			}
+			ups = append(ups, u)
+		}
+		f.rules[normalizeDomain(rule.Domain)] = ups
+	}
+	for _, domain := range cfg.Blocklist {
+		f.blocked[normalizeDomain(domain)] = true
+	}
+	if cfg.CacheSize > 0 {
+		f.cache = newDNSCache(cfg.CacheSize)
+	}
+	return f, nil
+}
+
+// normalizeDomain 统一为小写、去掉末尾的点和 "+." / "*." 前缀
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "+."), "*.")
+	return strings.TrimSuffix(domain, ".")
+}
+
+// matchSuffix 从完整域名开始逐级查找父域名，返回第一个匹配的后缀
+func matchSuffix(name string, has func(string) bool) (string, bool) {
+	for {
+		if has(name) {
+			return name, true
+		}
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			return "", false
+		}
+		name = name[i+1:]
+	}
+}
+
+// listen 开始监听 UDP 和 TCP
+func (f *forwarder) listen(addr string) error {
+	udp, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("监听 UDP %s 失败: %w", addr, err)
+	}
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("监听 TCP %s 失败: %w", addr, err)
+	}
+	f.udp, f.tcp = udp, tcp
+
+	f.wg.Add(2)
+	go f.serveUDP()
+	go f.serveTCP()
+	return nil
+}
+
+// close 关闭监听并等待处理循环退出
+func (f *forwarder) close() {
+	if f.udp != nil {
+		f.udp.Close()
+	}
+	if f.tcp != nil {
+		f.tcp.Close()
+	}
+	f.wg.Wait()
+}
+
+func (f *forwarder) serveUDP() {
+	defer f.wg.Done()
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := f.udp.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		query := append([]byte{}, buf[:n]...)
+		go func() {
+			if resp := f.handle(query); resp != nil {
+				f.udp.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+func (f *forwarder) serveTCP() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.tcp.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				resp := f.handle(query)
+				if resp == nil || writeTCPMessage(conn, resp) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// handle 处理一个查询报文：拦截 → 缓存 → 按域名选择上游转发，无法解析的报文返回 nil
+func (f *forwarder) handle(query []byte) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil || msg.Header.Response {
+		return nil
+	}
+	if len(msg.Questions) == 0 {
+		return reply(msg, dnsmessage.RCodeFormatError, nil)
+	}
+	q := msg.Questions[0]
+	name := normalizeDomain(q.Name.String())
+	f.stats.queries.Add(1)
+
+	if _, ok := matchSuffix(name, func(d string) bool { return f.blocked[d] }); ok {
+		f.stats.blocked.Add(1)
+		return f.blockedReply(msg)
+	}
+
+	if f.cache != nil {
+		if cached, ok := f.cache.get(q); ok {
+			f.stats.cacheHits.Add(1)
+			cached.Header.ID = msg.Header.ID
+			if resp, err := cached.Pack(); err == nil {
+				return resp
+			}
+		}
+	}
+
+	ups := f.upstreams
+	if suffix, ok := matchSuffix(name, func(d string) bool { _, ok := f.rules[d]; return ok }); ok {
+		ups = f.rules[suffix]
+	}
+	for _, u := range ups {
+		resp, err := u.exchange(context.Background(), query)
+		if err != nil {
+			continue
+		}
+		var answer dnsmessage.Message
+		if err := answer.Unpack(resp); err != nil || answer.Header.RCode == dnsmessage.RCodeServerFailure {
+			continue
+		}
+		if f.cache != nil && !answer.Header.Truncated &&
+			(answer.Header.RCode == dnsmessage.RCodeSuccess || answer.Header.RCode == dnsmessage.RCodeNameError) {
+			f.cache.put(q, answer)
+		}
+		return resp
+	}
+
+	f.stats.failed.Add(1)
+	return reply(msg, dnsmessage.RCodeServerFailure, nil)
+}
+
+// blockedReply 拦截响应：nxdomain 返回 NXDOMAIN，zero 对 A / AAAA 返回 0.0.0.0 / ::
+func (f *forwarder) blockedReply(msg dnsmessage.Message) []byte {
+	if f.blockResponse != BlockResponseZero {
+		return reply(msg, dnsmessage.RCodeNameError, nil)
+	}
+	q := msg.Questions[0]
+	header := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60}
+	switch q.Type {
+	case dnsmessage.TypeA:
+		return reply(msg, dnsmessage.RCodeSuccess, []dnsmessage.Resource{{Header: header, Body: &dnsmessage.AResource{}}})
+	case dnsmessage.TypeAAAA:
+		return reply(msg, dnsmessage.RCodeSuccess, []dnsmessage.Resource{{Header: header, Body: &dnsmessage.AAAAResource{}}})
+	default:
+		return reply(msg, dnsmessage.RCodeSuccess, nil)
+	}
+}
+
+// reply 构造本地响应
+func reply(query dnsmessage.Message, rcode dnsmessage.RCode, answers []dnsmessage.Resource) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.Header.ID,
+			Response:           true,
+			RecursionDesired:   query.Header.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              rcode,
+		},
+		Questions: query.Questions,
+		Answers:   answers,
+	}
+	resp, err := msg.Pack()
+	if err != nil {
+		return nil
+	}
+	return resp
+}
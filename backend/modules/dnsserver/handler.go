@@ -0,0 +1,84 @@
+package dnsserver
+
+import (
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 本地 DNS 服务 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/config", h.GetConfig)
+	r.PUT("/config", h.SetConfig)
+	r.GET("/status", h.GetStatus)
+	r.POST("/cache/flush", h.FlushCache)
+}
+
+// GetConfig 获取配置
+func (h *Handler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// SetConfig 保存配置并立即生效
+func (h *Handler) SetConfig(c *gin.Context) {
+	var cfg Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	saved, err := h.service.SetConfig(cfg)
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if saved {
+			status, message = http.StatusInternalServerError, "配置已保存，但启动失败: "+err.Error()
+		}
+		apierror.Message(c, status, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// GetStatus 获取运行状态和查询统计
+func (h *Handler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetStatus(),
+	})
+}
+
+// FlushCache 清空 DNS 缓存
+func (h *Handler) FlushCache(c *gin.Context) {
+	h.service.FlushCache()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,273 @@
+package dnsserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 拦截域名的响应方式
+const (
+	BlockResponseNXDomain = "nxdomain" // 返回 NXDOMAIN
+	BlockResponseZero     = "zero"     // A / AAAA 返回 0.0.0.0 / ::
+)
+
+const (
+	defaultListen    = "0.0.0.0:53"
+	defaultCacheSize = 4096
+	maxCacheSize     = 100000
+)
+
+// defaultUpstreams 默认上游（国内公共 DNS）
+var defaultUpstreams = []string{"223.5.5.5", "119.29.29.29"}
+
+// DomainRule 按域名指定上游，匹配该域名及其子域名，多条规则时最长后缀优先
+type DomainRule struct {
+	Domain    string   `json:"domain"`
+	Upstreams []string `json:"upstreams"`
+}
+
+// Config 本地 DNS 服务配置（dns_server.json）
+// 独立于代理核心运行，核心只做代理（不接管 DNS）时为局域网提供可控的 DNS
+type Config struct {
+	Enabled       bool         `json:"enabled"`
+	Listen        string       `json:"listen"`        // 监听地址，同时监听 UDP 和 TCP
+	Upstreams     []string     `json:"upstreams"`     // 默认上游，按顺序尝试
+	Rules         []DomainRule `json:"rules"`         // 按域名分流
+	Blocklist     []string     `json:"blocklist"`     // 拦截的域名（包含子域名）
+	BlockResponse string       `json:"blockResponse"` // nxdomain / zero
+	CacheSize     int          `json:"cacheSize"`     // 缓存条目数，0 关闭缓存
+}
+
+// Status 运行状态
+type Status struct {
+	Running      bool   `json:"running"`
+	Listen       string `json:"listen,omitempty"`
+	Error        string `json:"error,omitempty"` // 最近一次启动失败原因
+	CacheEntries int    `json:"cacheEntries"`
+	Stats        Stats  `json:"stats"`
+}
+
+// Service 本地 DNS 服务
+type Service struct {
+	configPath string
+	config     *Config
+	forwarder  *forwarder
+	stats      *counters
+	lastError  string
+	mu         sync.Mutex
+}
+
+// NewService 创建本地 DNS 服务，配置为启用时立即启动
+func NewService(dataDir string) *Service {
+	s := &Service{
+		configPath: filepath.Join(dataDir, "dns_server.json"),
+		config: &Config{
+			Listen:        defaultListen,
+			Upstreams:     append([]string{}, defaultUpstreams...),
+			BlockResponse: BlockResponseNXDomain,
+			CacheSize:     defaultCacheSize,
+		},
+		stats: &counters{},
+	}
+	s.load()
+
+	if s.config.Enabled {
+		s.mu.Lock()
+		if err := s.restart(); err != nil {
+			fmt.Printf("⚠️ 本地 DNS 服务启动失败: %v\n", err)
+		}
+		s.mu.Unlock()
+	}
+	return s
+}
+
+// load 加载配置
+func (s *Service) load() {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, s.config); err != nil {
+		fmt.Printf("⚠️ 解析本地 DNS 配置失败: %v\n", err)
+	}
+}
+
+// save 保存配置（调用方需持有锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.configPath, data, 0644)
+}
+
+// normalizeConfig 校验并规范化配置
+func normalizeConfig(cfg Config) (Config, error) {
+	cfg.Listen = strings.TrimSpace(cfg.Listen)
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+	if _, port, err := net.SplitHostPort(cfg.Listen); err != nil || port == "" {
+		return cfg, fmt.Errorf("监听地址无效: %s", cfg.Listen)
+	}
+
+	upstreams, err := normalizeUpstreams(cfg.Upstreams)
+	if err != nil {
+		return cfg, err
+	}
+	if len(upstreams) == 0 {
+		return cfg, fmt.Errorf("至少需要一个上游 DNS")
+	}
+	cfg.Upstreams = upstreams
+
+	rules := make([]DomainRule, 0, len(cfg.Rules))
+	seen := make(map[string]bool)
+	for _, r := range cfg.Rules {
+		domain := normalizeDomain(r.Domain)
+		if domain == "" {
+			return cfg, fmt.Errorf("分流规则缺少域名")
+		}
+		if seen[domain] {
+			return cfg, fmt.Errorf("分流规则域名重复: %s", domain)
+		}
+		seen[domain] = true
+		ups, err := normalizeUpstreams(r.Upstreams)
+		if err != nil {
+			return cfg, fmt.Errorf("域名 %s: %v", domain, err)
+		}
+		if len(ups) == 0 {
+			return cfg, fmt.Errorf("域名 %s 缺少上游 DNS", domain)
+		}
+		rules = append(rules, DomainRule{Domain: domain, Upstreams: ups})
+	}
+	cfg.Rules = rules
+
+	blocklist := make([]string, 0, len(cfg.Blocklist))
+	for _, d := range cfg.Blocklist {
+		if d = normalizeDomain(d); d != "" {
+			blocklist = append(blocklist, d)
+		}
+	}
+	cfg.Blocklist = blocklist
+
+	if cfg.BlockResponse == "" {
+		cfg.BlockResponse = BlockResponseNXDomain
+	}
+	if cfg.BlockResponse != BlockResponseNXDomain && cfg.BlockResponse != BlockResponseZero {
+		return cfg, fmt.Errorf("拦截响应只能是 nxdomain 或 zero")
+	}
+	if cfg.CacheSize < 0 || cfg.CacheSize > maxCacheSize {
+		return cfg, fmt.Errorf("缓存条目数需在 0-%d 之间", maxCacheSize)
+	}
+	return cfg, nil
+}
+
+// normalizeUpstreams 去掉空项并校验地址
+func normalizeUpstreams(servers []string) ([]string, error) {
+	result := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if server = strings.TrimSpace(server); server == "" {
+			continue
+		}
+		if _, err := parseUpstream(server); err != nil {
+			return nil, err
+		}
+		result = append(result, server)
+	}
+	return result, nil
+}
+
+// GetConfig 获取配置
+func (s *Service) GetConfig() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg := *s.config
+	cfg.Upstreams = append([]string{}, s.config.Upstreams...)
+	cfg.Rules = append([]DomainRule{}, s.config.Rules...)
+	cfg.Blocklist = append([]string{}, s.config.Blocklist...)
+	return cfg
+}
+
+// SetConfig 保存配置并按新配置重启服务（禁用时停止）
+// 配置无效时返回错误且不保存；配置已保存但启动失败时返回启动错误
+func (s *Service) SetConfig(cfg Config) (saved bool, err error) {
+	cfg, err = normalizeConfig(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = &cfg
+	if err := s.save(); err != nil {
+		return false, err
+	}
+	return true, s.restart()
+}
+
+// restart 停止当前转发器，启用时按配置重新启动（调用方需持有锁）
+func (s *Service) restart() error {
+	if s.forwarder != nil {
+		s.forwarder.close()
+		s.forwarder = nil
+		fmt.Println("✓ 本地 DNS 服务已停止")
+	}
+	s.lastError = ""
+	if !s.config.Enabled {
+		return nil
+	}
+
+	f, err := newForwarder(*s.config, s.stats)
+	if err == nil {
+		err = f.listen(s.config.Listen)
+	}
+	if err != nil {
+		s.lastError = err.Error()
+		return err
+	}
+	s.forwarder = f
+	fmt.Printf("🌐 本地 DNS 服务已启动: %s\n", s.config.Listen)
+	return nil
+}
+
+// GetStatus 获取运行状态
+func (s *Service) GetStatus() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Running: s.forwarder != nil,
+		Error:   s.lastError,
+		Stats:   s.stats.snapshot(),
+	}
+	if s.forwarder != nil {
+		status.Listen = s.config.Listen
+		if s.forwarder.cache != nil {
+			status.CacheEntries = s.forwarder.cache.len()
+		}
+	}
+	return status
+}
+
+// FlushCache 清空缓存
+func (s *Service) FlushCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forwarder != nil && s.forwarder.cache != nil {
+		s.forwarder.cache.flush()
+	}
+}
+
+// Stop 停止服务（程序退出时调用，不修改配置）
+func (s *Service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forwarder != nil {
+		s.forwarder.close()
+		s.forwarder = nil
+	}
+}
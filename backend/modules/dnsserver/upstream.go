@@ -0,0 +1,155 @@
+package dnsserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// upstreamTimeout 单个上游查询超时
+const upstreamTimeout = 5 * time.Second
+
+// upstream 解析后的上游 DNS 地址
+type upstream struct {
+	raw    string
+	scheme string // udp / tcp / tls / https
+	addr   string // host:port（https 为完整 URL）
+}
+
+// parseUpstream 解析上游地址，支持 1.1.1.1、udp://、tcp://、tls://、https://
+func parseUpstream(server string) (upstream, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return upstream{}, fmt.Errorf("上游 DNS 地址为空")
+	}
+
+	scheme, rest := "udp", server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, rest = strings.ToLower(server[:i]), server[i+3:]
+	}
+
+	switch scheme {
+	case "https":
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			return upstream{}, fmt.Errorf("DoH 地址无效: %s", server)
+		}
+		return upstream{raw: server, scheme: scheme, addr: server}, nil
+	case "udp", "tcp", "tls":
+		defaultPort := "53"
+		if scheme == "tls" {
+			defaultPort = "853"
+		}
+		host := strings.TrimSuffix(rest, "/")
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultPort)
+		}
+		if h, _, _ := net.SplitHostPort(host); h == "" {
+			return upstream{}, fmt.Errorf("上游 DNS 地址无效: %s", server)
+		}
+		return upstream{raw: server, scheme: scheme, addr: host}, nil
+	default:
+		return upstream{}, fmt.Errorf("不支持的上游 DNS 协议: %s", server)
+	}
+}
+
+// exchange 向上游发送原始查询报文并返回响应报文
+func (u upstream) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
+
+	switch u.scheme {
+	case "https":
+		return exchangeDoH(ctx, u.addr, query)
+	case "udp":
+		return exchangeConn(ctx, "udp", u.addr, query)
+	default:
+		return exchangeConn(ctx, u.scheme, u.addr, query)
+	}
+}
+
+// exchangeConn 通过 UDP / TCP / TLS 发送查询，TCP 和 TLS 使用 2 字节长度前缀
+func exchangeConn(ctx context.Context, network, addr string, query []byte) ([]byte, error) {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{}
+	if network == "tls" {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	if err := writeTCPMessage(conn, query); err != nil {
+		return nil, err
+	}
+	return readTCPMessage(conn)
+}
+
+// exchangeDoH 通过 DNS over HTTPS（RFC 8484 POST）发送查询
+func exchangeDoH(ctx context.Context, endpoint string, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH 服务器返回 HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+// writeTCPMessage 写入带长度前缀的 DNS 报文
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	packet := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(packet, uint16(len(msg)))
+	copy(packet[2:], msg)
+	_, err := w.Write(packet)
+	return err
+}
+
+// readTCPMessage 读取带长度前缀的 DNS 报文
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
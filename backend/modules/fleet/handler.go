@@ -0,0 +1,186 @@
+package fleet
+
+import (
+	"errors"
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 多实例配置同步 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/peers", h.List)
+	r.POST("/peers", h.Add)
+	r.PUT("/peers/:id", h.Update)
+	r.DELETE("/peers/:id", h.Delete)
+	r.POST("/peers/:id/check", h.Check) // 检查在线状态和令牌
+	r.GET("/bundle", h.GetBundle)       // 预览待推送的配置包
+	r.POST("/push", h.Push)
+	r.POST("/receive", h.Receive) // 接收其他实例推送的配置包
+}
+
+// errorStatus 实例不存在时返回 404
+func errorStatus(err error, fallback int) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// List 获取所有实例
+func (h *Handler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.List(),
+	})
+}
+
+// Add 添加实例
+func (h *Handler) Add(c *gin.Context) {
+	req := Peer{Enabled: true}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	peer, err := h.service.Add(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    peer,
+	})
+}
+
+// Update 更新实例
+func (h *Handler) Update(c *gin.Context) {
+	var req Peer
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	peer, err := h.service.Update(c.Param("id"), req)
+	if err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    peer,
+	})
+}
+
+// Delete 删除实例
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Check 检查实例，结果记录在实例状态中
+func (h *Handler) Check(c *gin.Context) {
+	peer, err := h.service.Check(c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    peer,
+	})
+}
+
+// GetBundle 预览待推送的配置包
+// 参数: template、nftTemplate 是否包含对应内容（默认都包含）
+func (h *Handler) GetBundle(c *gin.Context) {
+	bundle, err := h.service.Bundle(PushRequest{
+		Template:    c.DefaultQuery("template", "true") == "true",
+		NftTemplate: c.DefaultQuery("nftTemplate", "true") == "true",
+	})
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bundle,
+	})
+}
+
+// Push 推送配置到实例
+// 部分实例失败时仍返回 200，结果中包含每个实例的状态
+func (h *Handler) Push(c *gin.Context) {
+	var req PushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := h.service.Push(req)
+	if err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    results,
+	})
+}
+
+// Receive 导入推送的配置包
+func (h *Handler) Receive(c *gin.Context) {
+	var bundle Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.service.Receive(&bundle)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
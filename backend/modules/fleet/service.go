@@ -0,0 +1,506 @@
+package fleet
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/secure"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maskedToken  = "******"
+	checkTimeout = 10 * time.Second
+	// pushTimeout 远程实例导入后可能需要重启核心
+	pushTimeout = 60 * time.Second
+	// maxParallelPush 同时推送的实例数
+	maxParallelPush = 4
+)
+
+// ErrNotFound 实例不存在
+var ErrNotFound = apierror.New(apierror.CodeNotFound, "实例不存在")
+
+// Bundle 推送到远程实例的配置包
+// 只包含与设备无关的配置：代理组、规则、规则集、DNS 模板和 nftables 规则模板，
+// 节点、端口和网卡等本机设置不会推送
+type Bundle struct {
+	Source      string          `json:"source,omitempty"` // 推送方主机名
+	CreatedAt   int64           `json:"createdAt"`
+	Template    json.RawMessage `json:"template,omitempty"`    // 配置模板，为空时不修改
+	NftTemplate *string         `json:"nftTemplate,omitempty"` // nftables 规则模板，为 nil 时不修改，空字符串表示默认模板
+	Apply       bool            `json:"apply"`                 // 导入后核心运行中时重启生效
+}
+
+// ImportResult 远程实例导入配置包的结果
+type ImportResult struct {
+	Template    bool `json:"template"`    // 已更新配置模板
+	NftTemplate bool `json:"nftTemplate"` // 已更新 nftables 规则模板
+	Restarted   bool `json:"restarted"`   // 已重启核心
+}
+
+// Controller 导出和导入配置包，由 server 注入
+type Controller struct {
+	Export func() (*Bundle, error)
+	Import func(bundle *Bundle) (*ImportResult, error)
+}
+
+// PeerStatus 最近一次检查和推送的结果
+type PeerStatus struct {
+	Online     bool          `json:"online"`
+	Version    string        `json:"version,omitempty"`
+	LastCheck  int64         `json:"lastCheck,omitempty"`
+	LastPush   int64         `json:"lastPush,omitempty"`
+	PushOK     bool          `json:"pushOk"`
+	PushResult *ImportResult `json:"pushResult,omitempty"`
+	LastError  string        `json:"lastError,omitempty"`
+}
+
+// Peer 远程 ProxyStation 实例
+type Peer struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	URL      string     `json:"url"`      // 管理地址，如 http://192.168.1.2:8080
+	Token    string     `json:"token"`    // 远程实例的 API 令牌，推送需要 admin 权限
+	Enabled  bool       `json:"enabled"`  // 参与批量推送
+	Insecure bool       `json:"insecure"` // 跳过 HTTPS 证书校验（自签名证书）
+	Status   PeerStatus `json:"status"`
+}
+
+// PushRequest 推送参数
+type PushRequest struct {
+	Peers       []string `json:"peers"`       // 目标实例 ID，为空时推送到所有启用的实例
+	Template    bool     `json:"template"`    // 推送配置模板
+	NftTemplate bool     `json:"nftTemplate"` // 推送 nftables 规则模板
+	Apply       bool     `json:"apply"`       // 远程核心运行中时重启生效
+}
+
+// PushResult 单个实例的推送结果
+type PushResult struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	OK     bool          `json:"ok"`
+	Result *ImportResult `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// Service 多实例配置同步服务
+type Service struct {
+	dataDir    string
+	mu         sync.RWMutex
+	peers      []*Peer
+	controller *Controller
+}
+
+// NewService 创建服务
+func NewService(dataDir string) *Service {
+	s := &Service{dataDir: dataDir}
+	s.load()
+	return s
+}
+
+func (s *Service) configPath() string {
+	return filepath.Join(s.dataDir, "fleet.json")
+}
+
+func (s *Service) load() {
+	data, err := secure.ReadFile(s.configPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.peers); err != nil {
+		fmt.Printf("⚠️ 解析远程实例列表失败: %v\n", err)
+	}
+}
+
+// save 保存实例列表（调用时需持有 s.mu 锁），文件包含令牌，仅所有者可读
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.peers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return secure.WriteFile(s.configPath(), data, 0600)
+}
+
+// SetController 设置配置包导出/导入控制器
+func (s *Service) SetController(controller Controller) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.controller = &controller
+}
+
+func (s *Service) getController() (*Controller, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.controller == nil {
+		return nil, fmt.Errorf("代理服务未就绪")
+	}
+	return s.controller, nil
+}
+
+// masked 返回隐藏令牌的副本
+func (p *Peer) masked() Peer {
+	peer := *p
+	if peer.Token != "" {
+		peer.Token = maskedToken
+	}
+	return peer
+}
+
+// normalizePeer 校验地址并去掉末尾的斜杠
+func normalizePeer(p *Peer) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.URL = strings.TrimRight(strings.TrimSpace(p.URL), "/")
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的实例地址: %s", p.URL)
+	}
+	if p.Token == "" {
+		return fmt.Errorf("令牌不能为空")
+	}
+	if p.Name == "" {
+		p.Name = u.Host
+	}
+	return nil
+}
+
+// List 获取所有实例（令牌已隐藏）
+func (s *Service) List() []Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Peer, 0, len(s.peers))
+	for _, p := range s.peers {
+		result = append(result, p.masked())
+	}
+	return result
+}
+
+// find 查找实例（调用时需持有 s.mu 锁）
+func (s *Service) find(id string) (*Peer, int) {
+	for i, p := range s.peers {
+		if p.ID == id {
+			return p, i
+		}
+	}
+	return nil, -1
+}
+
+// Add 添加实例
+func (s *Service) Add(p Peer) (*Peer, error) {
+	if err := normalizePeer(&p); err != nil {
+		return nil, err
+	}
+	p.ID = uuid.New().String()
+	p.Status = PeerStatus{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers = append(s.peers, &p)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	peer := p.masked()
+	return &peer, nil
+}
+
+// Update 更新实例，令牌为空或为掩码时保留原令牌
+func (s *Service) Update(id string, p Peer) (*Peer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, _ := s.find(id)
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+	if p.Token == "" || p.Token == maskedToken {
+		p.Token = existing.Token
+	}
+	if err := normalizePeer(&p); err != nil {
+		return nil, err
+	}
+	p.ID = id
+	p.Status = existing.Status
+	*existing = p
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	peer := existing.masked()
+	return &peer, nil
+}
+
+// Delete 删除实例
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, index := s.find(id)
+	if index < 0 {
+		return ErrNotFound
+	}
+	s.peers = append(s.peers[:index], s.peers[index+1:]...)
+	return s.save()
+}
+
+// snapshot 获取实例副本，避免请求远程实例时持有锁
+func (s *Service) snapshot(id string) (Peer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, _ := s.find(id)
+	if p == nil {
+		return Peer{}, ErrNotFound
+	}
+	return *p, nil
+}
+
+// updateStatus 修改实例状态并保存，实例已被删除时忽略
+func (s *Service) updateStatus(id string, update func(status *PeerStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, _ := s.find(id)
+	if p == nil {
+		return
+	}
+	update(&p.Status)
+	if err := s.save(); err != nil {
+		fmt.Printf("⚠️ 保存远程实例状态失败: %v\n", err)
+	}
+}
+
+// Check 检查实例是否在线以及令牌是否有效
+func (s *Service) Check(id string) (*Peer, error) {
+	peer, err := s.snapshot(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Version string `json:"version"`
+	}
+	err = peer.call(http.MethodGet, "/api/system/info", nil, &info, checkTimeout)
+	s.updateStatus(id, func(status *PeerStatus) {
+		status.LastCheck = time.Now().Unix()
+		status.Online = err == nil
+		if err != nil {
+			status.LastError = err.Error()
+			return
+		}
+		status.Version = info.Version
+		status.LastError = ""
+	})
+
+	peer, _ = s.snapshot(id)
+	masked := peer.masked()
+	return &masked, nil
+}
+
+// Bundle 导出待推送的配置包
+func (s *Service) Bundle(req PushRequest) (*Bundle, error) {
+	controller, err := s.getController()
+	if err != nil {
+		return nil, err
+	}
+	if !req.Template && !req.NftTemplate {
+		return nil, fmt.Errorf("请至少选择一项推送内容")
+	}
+
+	bundle, err := controller.Export()
+	if err != nil {
+		return nil, err
+	}
+	if !req.Template {
+		bundle.Template = nil
+	}
+	if !req.NftTemplate {
+		bundle.NftTemplate = nil
+	}
+	bundle.Source, _ = os.Hostname()
+	bundle.CreatedAt = time.Now().Unix()
+	bundle.Apply = req.Apply
+	return bundle, nil
+}
+
+// Push 将配置包并行推送到实例，返回每个实例的结果
+func (s *Service) Push(req PushRequest) ([]PushResult, error) {
+	bundle, err := s.Bundle(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Peer
+	s.mu.RLock()
+	if len(req.Peers) == 0 {
+		for _, p := range s.peers {
+			if p.Enabled {
+				targets = append(targets, *p)
+			}
+		}
+	} else {
+		for _, id := range req.Peers {
+			p, _ := s.find(id)
+			if p == nil {
+				s.mu.RUnlock()
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+			}
+			targets = append(targets, *p)
+		}
+	}
+	s.mu.RUnlock()
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("没有可推送的实例")
+	}
+
+	results := make([]PushResult, len(targets))
+	sem := make(chan struct{}, maxParallelPush)
+	var wg sync.WaitGroup
+	for i, peer := range targets {
+		wg.Add(1)
+		go func(i int, peer Peer) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = s.pushTo(peer, bundle)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, r := range results {
+		if r.OK {
+			succeeded++
+		}
+	}
+	fmt.Printf("📤 配置已推送到 %d/%d 个实例\n", succeeded, len(results))
+	return results, nil
+}
+
+// pushTo 推送到单个实例并记录状态
+func (s *Service) pushTo(peer Peer, bundle *Bundle) PushResult {
+	result := PushResult{ID: peer.ID, Name: peer.Name}
+	var imported ImportResult
+	err := peer.call(http.MethodPost, "/api/fleet/receive", bundle, &imported, pushTimeout)
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Printf("⚠️ 推送配置到 %s 失败: %v\n", peer.Name, err)
+	} else {
+		result.OK = true
+		result.Result = &imported
+	}
+
+	s.updateStatus(peer.ID, func(status *PeerStatus) {
+		now := time.Now().Unix()
+		status.LastPush = now
+		status.PushOK = err == nil
+		status.PushResult = result.Result
+		status.LastError = result.Error
+		// 推送成功说明实例在线
+		if err == nil {
+			status.Online = true
+			status.LastCheck = now
+		}
+	})
+	return result
+}
+
+// Receive 导入其他实例推送的配置包
+func (s *Service) Receive(bundle *Bundle) (*ImportResult, error) {
+	controller, err := s.getController()
+	if err != nil {
+		return nil, err
+	}
+	if len(bundle.Template) == 0 && bundle.NftTemplate == nil {
+		return nil, fmt.Errorf("配置包为空")
+	}
+
+	result, err := controller.Import(bundle)
+	if err != nil {
+		return nil, err
+	}
+	source := bundle.Source
+	if source == "" {
+		source = "未知实例"
+	}
+	fmt.Printf("📥 已导入来自 %s 的配置（模板: %v, nftables: %v, 重启: %v）\n",
+		source, result.Template, result.NftTemplate, result.Restarted)
+	return result, nil
+}
+
+// call 请求远程实例 API 并解析 {code, message, data} 响应
+func (p *Peer) call(method, path string, body interface{}, out interface{}, timeout time.Duration) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, p.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if p.Insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("连接失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Error   json.RawMessage `json:"error"` // 错误详情，旧版本的认证中间件为字符串
+		Data    json.RawMessage `json:"data"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+	decodeErr := json.Unmarshal(data, &envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("令牌无效或已过期")
+	case resp.StatusCode == http.StatusForbidden && envelope.Message != "":
+		return errors.New(envelope.Message)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("令牌没有 admin 权限")
+	case resp.StatusCode == http.StatusNotFound && decodeErr != nil:
+		return fmt.Errorf("远程实例不支持配置推送，请升级")
+	case decodeErr != nil:
+		return fmt.Errorf("无效的响应 (HTTP %d)", resp.StatusCode)
+	case resp.StatusCode >= 400 || envelope.Code != 0:
+		message := envelope.Message
+		if message == "" {
+			json.Unmarshal(envelope.Error, &message)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, message)
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package geodata
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler GEO 数据 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("", h.GetFiles)
+	r.GET("/config", h.GetConfig)
+	r.PUT("/config", h.SetConfig)
+	r.GET("/status", h.GetStatus)
+	r.POST("/update", h.Update)
+	r.GET("/lookup", h.Lookup)
+}
+
+// GetFiles 获取数据文件及已安装版本
+func (h *Handler) GetFiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetFiles(),
+	})
+}
+
+// GetConfig 获取更新配置
+func (h *Handler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// SetConfig 保存更新配置
+func (h *Handler) SetConfig(c *gin.Context) {
+	var cfg Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.service.SetConfig(cfg); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfig(),
+	})
+}
+
+// GetStatus 获取更新状态和最近一次结果
+func (h *Handler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetStatus(),
+	})
+}
+
+// Update 异步检查并更新数据文件，?force=true 时强制重新下载
+func (h *Handler) Update(c *gin.Context) {
+	if h.service.IsUpdating() {
+		apierror.Message(c, http.StatusConflict, "正在更新中，请稍后再试")
+		return
+	}
+
+	force := c.Query("force") == "true"
+	go h.service.Update(force)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "开始更新 GEO 数据",
+	})
+}
+
+// Lookup 查询 IP 归属（国家 / ASN），?ip= 多个地址以逗号分隔，最多 100 个
+func (h *Handler) Lookup(c *gin.Context) {
+	ips := strings.Split(c.Query("ip"), ",")
+	if len(ips) > 100 {
+		apierror.Message(c, http.StatusBadRequest, "一次最多查询 100 个地址")
+		return
+	}
+
+	results := make([]IPInfo, 0, len(ips))
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if net.ParseIP(ip) == nil {
+			apierror.Message(c, http.StatusBadRequest, "无效的 IP 地址: "+ip)
+			return
+		}
+		info := h.service.LookupIP(ip)
+		if info == nil {
+			info = &IPInfo{IP: ip}
+		}
+		results = append(results, *info)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    results,
+	})
+}
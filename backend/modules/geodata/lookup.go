@@ -0,0 +1,131 @@
+package geodata
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// countryDatabases 国家查询使用的数据库（按顺序取第一个存在的文件）
+var countryDatabases = []string{"geoip.metadb", "country.mmdb"}
+
+// asnDatabase ASN 查询使用的数据库
+const asnDatabase = "GeoLite2-ASN.mmdb"
+
+// readerCheckInterval 检查数据库文件是否被替换的最短间隔
+const readerCheckInterval = 30 * time.Second
+
+// IPInfo IP 归属信息
+type IPInfo struct {
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"` // ISO 3166 国家代码（大写），MetaDB 中私有地址为 PRIVATE
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"asOrg,omitempty"`
+}
+
+// cachedReader 已打开的数据库及对应的文件修改时间
+type cachedReader struct {
+	reader    *mmdbReader
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// geoReaders 数据库读取器缓存，文件更新后自动重新加载
+type geoReaders struct {
+	mu      sync.Mutex
+	readers map[string]*cachedReader
+}
+
+// get 获取数据库读取器，文件不存在或无法解析时返回 nil
+func (g *geoReaders) get(path string) *mmdbReader {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.readers == nil {
+		g.readers = make(map[string]*cachedReader)
+	}
+
+	now := time.Now()
+	cached := g.readers[path]
+	if cached != nil && now.Sub(cached.checkedAt) < readerCheckInterval {
+		return cached.reader
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		delete(g.readers, path)
+		return nil
+	}
+	if cached != nil && info.ModTime().Equal(cached.modTime) {
+		cached.checkedAt = now
+		return cached.reader
+	}
+
+	reader, err := openMMDB(path)
+	if err != nil {
+		// 解析失败时记录修改时间，文件未变化前不再重试
+		fmt.Printf("⚠️ 加载 GEO 数据库失败: %s: %v\n", filepath.Base(path), err)
+	}
+	g.readers[path] = &cachedReader{reader: reader, modTime: info.ModTime(), checkedAt: now}
+	return reader
+}
+
+// LookupIP 查询 IP 的国家和 ASN，地址无效或数据库缺失时返回 nil
+func (s *Service) LookupIP(ip string) *IPInfo {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return nil
+	}
+
+	info := &IPInfo{IP: parsed.String()}
+	for _, name := range countryDatabases {
+		reader := s.geo.get(filepath.Join(s.dataDir, name))
+		if reader == nil {
+			continue
+		}
+		if record, err := reader.lookup(parsed); err == nil {
+			info.Country = countryFromRecord(record)
+		}
+		break
+	}
+	if reader := s.geo.get(filepath.Join(s.dataDir, asnDatabase)); reader != nil {
+		if record, err := reader.lookup(parsed); err == nil {
+			if m, ok := record.(map[string]interface{}); ok {
+				info.ASN = uint(asUint(m["autonomous_system_number"]))
+				info.ASOrg, _ = m["autonomous_system_organization"].(string)
+			}
+		}
+	}
+
+	if info.Country == "" && info.ASN == 0 {
+		return nil
+	}
+	return info
+}
+
+// countryFromRecord 从不同格式的记录中取国家代码
+// MaxMind: {"country": {"iso_code": "CN"}}；sing-geoip: "cn"；MetaDB: "cn" 或 ["cn", ...]
+func countryFromRecord(record interface{}) string {
+	switch v := record.(type) {
+	case string:
+		return strings.ToUpper(v)
+	case []interface{}:
+		for _, item := range v {
+			if code, ok := item.(string); ok && code != "" {
+				return strings.ToUpper(code)
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"country", "registered_country"} {
+			if c, ok := v[key].(map[string]interface{}); ok {
+				if code, ok := c["iso_code"].(string); ok && code != "" {
+					return strings.ToUpper(code)
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package geodata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// mmdbMetadataMarker 元数据起始标记，位于文件末尾 128KB 内
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbMaxDepth map/array 最大嵌套深度，避免损坏的文件造成过深递归
+const mmdbMaxDepth = 64
+
+// mmdbReader MaxMind DB 格式读取器（整个文件读入内存，只读，可并发查询）
+// 兼容 MaxMind GeoLite2、sing-geoip 和 Mihomo MetaDB，格式说明见 https://maxmind.github.io/MaxMind-DB/
+type mmdbReader struct {
+	buf          []byte
+	data         []byte // 数据段
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint // IPv6 数据库中 ::/96 对应的节点
+}
+
+// openMMDB 读取并解析 MMDB 文件
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMMDB(buf)
+}
+
+// parseMMDB 解析内存中的 MMDB 数据
+func parseMMDB(buf []byte) (*mmdbReader, error) {
+	start := len(buf) - 128*1024
+	if start < 0 {
+		start = 0
+	}
+	i := bytes.LastIndex(buf[start:], mmdbMetadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("不是有效的 MMDB 文件")
+	}
+	metaStart := start + i + len(mmdbMetadataMarker)
+	meta, _, err := (&mmdbDecoder{buf: buf[metaStart:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("解析元数据失败: %w", err)
+	}
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("元数据格式错误")
+	}
+
+	r := &mmdbReader{buf: buf}
+	r.nodeCount = uint(asUint(m["node_count"]))
+	r.recordSize = uint(asUint(m["record_size"]))
+	r.ipVersion = uint(asUint(m["ip_version"]))
+	r.databaseType, _ = m["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("不支持的记录长度: %d", r.recordSize)
+	}
+
+	dataEnd := uint(start + i)
+	// 先限制节点数再计算树大小，避免乘法溢出
+	if r.nodeCount == 0 || r.nodeCount > dataEnd {
+		return nil, fmt.Errorf("搜索树大小错误")
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > dataEnd {
+		return nil, fmt.Errorf("搜索树大小错误")
+	}
+	r.data = buf[treeSize+16 : start+i]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.nodeCount; j++ {
+			node = r.readRecord(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// readRecord 读取节点的左（bit=0）或右（bit=1）记录
+func (r *mmdbReader) readRecord(node uint, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		b := r.buf[off : off+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		off := node * 7
+		b := r.buf[off : off+7]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(r.buf[off : off+4]))
+	}
+}
+
+// lookup 查询 IP 对应的数据，未收录时返回 nil
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	bits := ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		bits = v4
+		node = r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	if bits == nil {
+		return nil, fmt.Errorf("无效的 IP 地址")
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = r.readRecord(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("搜索树数据错误")
+	}
+
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("数据偏移越界")
+	}
+	value, _, err := (&mmdbDecoder{buf: r.data}).decode(offset, 0)
+	return value, err
+}
+
+// mmdbDecoder 数据段解码器
+// 解码结果: string / float64 / []byte / uint64 / int64 / bool / map[string]interface{} / []interface{}
+type mmdbDecoder struct {
+	buf []byte
+}
+
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// decode 解码 offset 处的值，返回值和下一个值的偏移，depth 为当前嵌套深度
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("数据偏移越界")
+	}
+	if depth > mmdbMaxDepth {
+		return nil, 0, fmt.Errorf("数据嵌套过深")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == mmdbPointer {
+		size := uint(ctrl>>3) & 0x3
+		if offset+size+1 > uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("数据偏移越界")
+		}
+		b := d.buf[offset : offset+size+1]
+		var ptr uint
+		switch size {
+		case 0:
+			ptr = uint(ctrl&0x7)<<8 | uint(b[0])
+		case 1:
+			ptr = (uint(ctrl&0x7)<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			ptr = (uint(ctrl&0x7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			ptr = uint(binary.BigEndian.Uint32(b))
+		}
+		// 指针不能指向指针，避免损坏的文件造成死循环
+		if ptr < uint(len(d.buf)) && d.buf[ptr]>>5 == mmdbPointer {
+			return nil, 0, fmt.Errorf("指针嵌套")
+		}
+		value, _, err := d.decode(ptr, depth)
+		return value, offset + size + 1, err
+	}
+
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("数据偏移越界")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("数据偏移越界")
+		}
+		var v uint
+		for _, b := range d.buf[offset : offset+n] {
+			v = v<<8 | uint(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + v
+		case 30:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+
+	switch typ {
+	case mmdbMap:
+		// 每个键值对至少占 2 字节，超出剩余数据的长度必然是损坏的文件，避免按该长度分配内存
+		if size > (uint(len(d.buf))-offset)/2 {
+			return nil, 0, fmt.Errorf("map 长度越界")
+		}
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map 键不是字符串")
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		if size > uint(len(d.buf))-offset {
+			return nil, 0, fmt.Errorf("array 长度越界")
+		}
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case mmdbBool:
+		if size > 1 {
+			return nil, 0, fmt.Errorf("bool 值错误")
+		}
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("数据偏移越界")
+	}
+	b := d.buf[offset : offset+size]
+	offset += size
+	switch typ {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbBytes:
+		return append([]byte{}, b...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double 长度错误")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float 长度错误")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128:
+		// uint128 只保留低 64 位
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, offset, nil
+	case mmdbInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		// 省略的是前导零字节，负数总是占满 4 字节
+		return int64(int32(v)), offset, nil
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+	return nil, 0, fmt.Errorf("未知的数据类型: %d", typ)
+}
+
+// asUint 数值转换（元数据中的整数字段）
+func asUint(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		return uint64(n)
+	case float64:
+		return uint64(n)
+	}
+	return 0
+}
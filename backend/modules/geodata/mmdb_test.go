@@ -0,0 +1,284 @@
+package geodata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// mmdbEncoder 测试用的数据段编码器
+type mmdbEncoder struct {
+	bytes.Buffer
+}
+
+func (e *mmdbEncoder) ctrl(typ, size int) {
+	var ext []byte
+	if typ > 7 {
+		ext = []byte{byte(typ - 7)}
+		typ = 0
+	}
+	var extra []byte
+	switch {
+	case size < 29:
+	case size < 285:
+		extra = []byte{byte(size - 29)}
+		size = 29
+	case size < 65821:
+		v := size - 285
+		extra = []byte{byte(v >> 8), byte(v)}
+		size = 30
+	default:
+		v := size - 65821
+		extra = []byte{byte(v >> 16), byte(v >> 8), byte(v)}
+		size = 31
+	}
+	e.WriteByte(byte(typ<<5 | size))
+	e.Write(ext)
+	e.Write(extra)
+}
+
+func (e *mmdbEncoder) str(s string) {
+	e.ctrl(mmdbString, len(s))
+	e.WriteString(s)
+}
+
+func (e *mmdbEncoder) uint32(v uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	b = bytes.TrimLeft(b, "\x00")
+	e.ctrl(mmdbUint32, len(b))
+	e.Write(b)
+}
+
+func (e *mmdbEncoder) int32(v int32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	e.ctrl(mmdbInt32, 4)
+	e.Write(b)
+}
+
+func (e *mmdbEncoder) pointer(ptr int) {
+	e.WriteByte(byte(mmdbPointer<<5 | ptr>>8&0x7))
+	e.WriteByte(byte(ptr))
+}
+
+// mmdbNetwork 测试数据库中的网段及其数据在数据段中的偏移
+type mmdbNetwork struct {
+	cidr   string
+	offset int
+}
+
+// buildMMDB 生成 24 位记录的 MMDB 文件，IPv4 网段在 IPv6 数据库中映射到 ::/96
+func buildMMDB(t *testing.T, ipVersion int, networks []mmdbNetwork, data []byte) []byte {
+	t.Helper()
+
+	type trieNode struct {
+		child  [2]*trieNode
+		offset int
+	}
+	root := &trieNode{offset: -1}
+	for _, n := range networks {
+		ip, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, _ := ipnet.Mask.Size()
+		bits := ip.To16()
+		if v4 := ip.To4(); v4 != nil {
+			if ipVersion == 4 {
+				bits = v4
+			} else {
+				bits = append(make([]byte, 12), v4...)
+				ones += 96
+			}
+		}
+		node := root
+		for i := 0; i < ones; i++ {
+			bit := bits[i/8] >> (7 - uint(i%8)) & 1
+			if node.child[bit] == nil {
+				node.child[bit] = &trieNode{offset: -1}
+			}
+			node = node.child[bit]
+		}
+		node.offset = n.offset
+	}
+
+	// 按广度优先编号内部节点
+	var nodes []*trieNode
+	index := map[*trieNode]int{}
+	queue := []*trieNode{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.child {
+			if c != nil && c.offset < 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	nodeCount := len(nodes)
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for _, c := range n.child {
+			record := nodeCount // 未收录
+			if c != nil {
+				if c.offset >= 0 {
+					record = nodeCount + 16 + c.offset
+				} else {
+					record = index[c]
+				}
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data)
+	out.Write(mmdbMetadataMarker)
+
+	var meta mmdbEncoder
+	meta.ctrl(mmdbMap, 4)
+	meta.str("node_count")
+	meta.uint32(uint32(nodeCount))
+	meta.str("record_size")
+	meta.ctrl(mmdbUint16, 1)
+	meta.WriteByte(24)
+	meta.str("ip_version")
+	meta.ctrl(mmdbUint16, 1)
+	meta.WriteByte(byte(ipVersion))
+	meta.str("database_type")
+	meta.str("Test-Country")
+	out.Write(meta.Bytes())
+	return out.Bytes()
+}
+
+// testMMDBData 数据段：偏移 0 为 CN 国家记录，之后为通过指针复用 "iso_code" 键的 ASN 记录
+func testMMDBData() (data []byte, cnOffset, asnOffset int) {
+	var e mmdbEncoder
+	e.ctrl(mmdbMap, 1)
+	e.str("country")
+	e.ctrl(mmdbMap, 1)
+	isoKey := e.Len()
+	e.str("iso_code")
+	e.str("CN")
+
+	asnOffset = e.Len()
+	e.ctrl(mmdbMap, 3)
+	e.str("autonomous_system_number")
+	e.uint32(13335)
+	e.str("offset")
+	e.int32(-2)
+	e.pointer(isoKey)
+	e.str("US")
+	return e.Bytes(), 0, asnOffset
+}
+
+func TestMMDBLookup(t *testing.T) {
+	data, cn, asn := testMMDBData()
+	cnRecord := map[string]interface{}{"country": map[string]interface{}{"iso_code": "CN"}}
+	asnRecord := map[string]interface{}{"autonomous_system_number": uint64(13335), "offset": int64(-2), "iso_code": "US"}
+
+	tests := []struct {
+		name      string
+		ipVersion int
+		networks  []mmdbNetwork
+		ip        string
+		want      interface{}
+	}{
+		{"ipv4 tree", 4, []mmdbNetwork{{"1.0.1.0/24", cn}}, "1.0.1.8", cnRecord},
+		{"ipv4 tree miss", 4, []mmdbNetwork{{"1.0.1.0/24", cn}}, "1.0.2.8", nil},
+		{"ipv6 lookup in ipv4 tree", 4, []mmdbNetwork{{"1.0.1.0/24", cn}}, "2001:db8::1", nil},
+		{"ipv4 in ipv6 tree", 6, []mmdbNetwork{{"1.0.1.0/24", cn}, {"2606:4700::/32", asn}}, "1.0.1.8", cnRecord},
+		{"ipv6 in ipv6 tree", 6, []mmdbNetwork{{"1.0.1.0/24", cn}, {"2606:4700::/32", asn}}, "2606:4700::1111", asnRecord},
+		{"ipv4 miss in ipv6 tree", 6, []mmdbNetwork{{"1.0.1.0/24", cn}, {"2606:4700::/32", asn}}, "8.8.8.8", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseMMDB(buildMMDB(t, tt.ipVersion, tt.networks, data))
+			if err != nil {
+				t.Fatalf("parseMMDB: %v", err)
+			}
+			if r.databaseType != "Test-Country" {
+				t.Errorf("databaseType = %q", r.databaseType)
+			}
+			got, err := r.lookup(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatalf("lookup: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookup(%s) = %#v, want %#v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMMDBCorrupt(t *testing.T) {
+	data, cn, _ := testMMDBData()
+	valid := buildMMDB(t, 6, []mmdbNetwork{{"1.0.1.0/24", cn}}, data)
+	markerAt := bytes.LastIndex(valid, mmdbMetadataMarker)
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"empty", nil},
+		{"no metadata", valid[:markerAt]},
+		{"truncated metadata", valid[:len(valid)-5]},
+		{"truncated tree", valid[markerAt-len(data)-20:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMMDB(tt.buf); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMMDBDecodeCorrupt(t *testing.T) {
+	// 自引用的 map：值是指向自身的指针，只能靠深度限制终止
+	selfRef := []byte{mmdbMap<<5 | 1, mmdbString<<5 | 1, 'a', mmdbPointer << 5, 0}
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"truncated string", []byte{mmdbString<<5 | 5, 'a', 'b'}},
+		{"truncated size", []byte{mmdbString<<5 | 30, 0x01}},
+		{"truncated pointer", []byte{mmdbPointer<<5 | 0x18, 0x00}},
+		{"pointer out of range", []byte{mmdbPointer<<5 | 0x07, 0xff}},
+		{"pointer to pointer", []byte{mmdbPointer << 5, 0x02, mmdbPointer << 5, 0x00}},
+		{"missing extended type", []byte{0x01}},
+		{"huge map", []byte{mmdbMap<<5 | 31, 0xff, 0xff, 0xff}},
+		{"huge array", []byte{0x1f, 0x04, 0xff, 0xff, 0xff}},
+		{"map key not string", []byte{mmdbMap<<5 | 1, mmdbUint16<<5 | 0, mmdbUint16<<5 | 0}},
+		{"bad double", []byte{mmdbDouble<<5 | 3, 0, 0, 0}},
+		{"bad bool", []byte{0x02, 0x07}},
+		{"self reference", selfRef},
+		{"unknown type", []byte{0x00, 0x20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := (&mmdbDecoder{buf: tt.buf}).decode(0, 0); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMMDBLookupCorruptRecord(t *testing.T) {
+	// 网段记录指向数据段之外
+	data, _, _ := testMMDBData()
+	buf := buildMMDB(t, 4, []mmdbNetwork{{"1.0.1.0/24", len(data) + 100}}, data)
+	r, err := parseMMDB(buf)
+	if err != nil {
+		t.Fatalf("parseMMDB: %v", err)
+	}
+	if _, err := r.lookup(net.ParseIP("1.0.1.1")); err == nil {
+		t.Error("expected error")
+	}
+}
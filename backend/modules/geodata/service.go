@@ -0,0 +1,460 @@
+package geodata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMirrors 默认下载镜像（按顺序尝试），均为 MetaCubeX/meta-rules-dat 的 release 内容
+var defaultMirrors = []string{
+	"https://github.com/MetaCubeX/meta-rules-dat/releases/download/latest",
+	"https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@release",
+	"https://fastly.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@release",
+}
+
+// defaultFiles 默认管理的数据文件
+// geoip.metadb 为 Mihomo 默认的 MMDB 文件名，geosite.dat 供 GEOSITE 规则使用，GeoLite2-ASN.mmdb 用于查询连接和节点的 ASN
+var defaultFiles = []string{"geoip.metadb", "geosite.dat", "GeoLite2-ASN.mmdb"}
+
+// supportedFiles 支持管理的数据文件及说明
+var supportedFiles = map[string]string{
+	"geoip.metadb":      "GeoIP 数据库 (MetaDB)",
+	"geosite.dat":       "GeoSite 域名数据库",
+	"geoip.dat":         "GeoIP 数据库 (V2Ray 格式)",
+	"country.mmdb":      "MaxMind 国家数据库",
+	"GeoLite2-ASN.mmdb": "ASN 自治系统数据库",
+}
+
+// Config GEO 数据更新配置
+type Config struct {
+	Mirrors        []string `json:"mirrors"`        // 下载镜像，文件地址为 <mirror>/<文件名>
+	Files          []string `json:"files"`          // 需要管理的文件
+	VerifyChecksum bool     `json:"verifyChecksum"` // 要求镜像提供 .sha256sum 并校验
+	AutoUpdate     bool     `json:"autoUpdate"`     // 定时自动更新
+	UpdateInterval int      `json:"updateInterval"` // 更新间隔（小时）
+	LastCheck      string   `json:"lastCheck"`      // 最近一次检查时间
+}
+
+// InstalledFile 已安装文件的版本信息
+type InstalledFile struct {
+	Name        string `json:"name"`
+	SHA256      string `json:"sha256"`
+	Size        int64  `json:"size"`
+	Version     string `json:"version"` // 镜像返回的 Last-Modified，缺失时为校验值前 12 位
+	Mirror      string `json:"mirror"`
+	Verified    bool   `json:"verified"`
+	InstalledAt string `json:"installedAt"`
+}
+
+// FileStatus 文件状态（配置中的文件及其安装信息）
+type FileStatus struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Path        string         `json:"path"`
+	Exists      bool           `json:"exists"`
+	Installed   *InstalledFile `json:"installed,omitempty"`
+}
+
+// UpdateResult 单个文件的更新结果
+type UpdateResult struct {
+	Name    string `json:"name"`
+	Updated bool   `json:"updated"`
+	Version string `json:"version,omitempty"`
+	Mirror  string `json:"mirror,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UpdateStatus 更新状态
+type UpdateStatus struct {
+	Updating    bool           `json:"updating"`
+	LastCheck   string         `json:"lastCheck"`
+	LastResults []UpdateResult `json:"lastResults"`
+}
+
+// state 持久化内容（geodata.json）
+type state struct {
+	Config    *Config                   `json:"config"`
+	Installed map[string]*InstalledFile `json:"installed"`
+}
+
+// Service GEO 数据管理服务
+type Service struct {
+	dataDir     string
+	statePath   string
+	config      *Config
+	installed   map[string]*InstalledFile
+	updating    bool
+	lastResults []UpdateResult
+	onUpdated   func() error
+	geo         geoReaders // IP 归属查询
+	mu          sync.RWMutex
+}
+
+// NewService 创建 GEO 数据服务
+func NewService(dataDir string) *Service {
+	s := &Service{
+		dataDir:   dataDir,
+		statePath: filepath.Join(dataDir, "geodata.json"),
+		config: &Config{
+			Mirrors:        append([]string{}, defaultMirrors...),
+			Files:          append([]string{}, defaultFiles...),
+			VerifyChecksum: true,
+			AutoUpdate:     true,
+			UpdateInterval: 24,
+		},
+		installed:   make(map[string]*InstalledFile),
+		lastResults: []UpdateResult{},
+	}
+	s.load()
+	go s.autoUpdateLoop()
+	return s
+}
+
+// SetOnUpdated 设置数据文件被替换后的回调（用于重载核心）
+func (s *Service) SetOnUpdated(fn func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onUpdated = fn
+}
+
+// load 加载持久化状态
+func (s *Service) load() {
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return
+	}
+	st := state{Config: s.config, Installed: s.installed}
+	if err := json.Unmarshal(data, &st); err != nil {
+		fmt.Printf("⚠️ 解析 GEO 数据配置失败: %v\n", err)
+		return
+	}
+	if st.Installed != nil {
+		s.installed = st.Installed
+	}
+	if len(s.config.Mirrors) == 0 {
+		s.config.Mirrors = append([]string{}, defaultMirrors...)
+	}
+	if s.config.UpdateInterval <= 0 {
+		s.config.UpdateInterval = 24
+	}
+}
+
+// save 保存状态（调用方需持有锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(state{Config: s.config, Installed: s.installed}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.statePath, data, 0644)
+}
+
+// GetConfig 获取配置
+func (s *Service) GetConfig() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg := *s.config
+	cfg.Mirrors = append([]string{}, s.config.Mirrors...)
+	cfg.Files = append([]string{}, s.config.Files...)
+	return cfg
+}
+
+// SetConfig 更新配置
+func (s *Service) SetConfig(cfg Config) error {
+	mirrors := make([]string, 0, len(cfg.Mirrors))
+	for _, m := range cfg.Mirrors {
+		m = strings.TrimSuffix(strings.TrimSpace(m), "/")
+		if m == "" {
+			continue
+		}
+		if !strings.HasPrefix(m, "http://") && !strings.HasPrefix(m, "https://") {
+			return fmt.Errorf("无效的镜像地址: %s", m)
+		}
+		mirrors = append(mirrors, m)
+	}
+	if len(mirrors) == 0 {
+		return fmt.Errorf("至少需要一个镜像地址")
+	}
+	for _, name := range cfg.Files {
+		if _, ok := supportedFiles[name]; !ok {
+			return fmt.Errorf("不支持的数据文件: %s", name)
+		}
+	}
+	if cfg.UpdateInterval <= 0 {
+		cfg.UpdateInterval = 24
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg.Mirrors = mirrors
+	cfg.LastCheck = s.config.LastCheck
+	s.config = &cfg
+	return s.save()
+}
+
+// GetFiles 获取配置中各文件的状态
+func (s *Service) GetFiles() []FileStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]FileStatus, 0, len(s.config.Files))
+	for _, name := range s.config.Files {
+		f := FileStatus{
+			Name:        name,
+			Description: supportedFiles[name],
+			Path:        filepath.Join(s.dataDir, name),
+		}
+		if _, err := os.Stat(f.Path); err == nil {
+			f.Exists = true
+		}
+		if inst, ok := s.installed[name]; ok {
+			copied := *inst
+			f.Installed = &copied
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// GetStatus 获取更新状态
+func (s *Service) GetStatus() UpdateStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return UpdateStatus{
+		Updating:    s.updating,
+		LastCheck:   s.config.LastCheck,
+		LastResults: append([]UpdateResult{}, s.lastResults...),
+	}
+}
+
+// IsUpdating 是否正在更新
+func (s *Service) IsUpdating() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updating
+}
+
+// Update 检查并更新所有文件，force 为 true 时忽略校验值比对强制重新下载
+// 有文件被替换时调用 onUpdated 回调
+func (s *Service) Update(force bool) ([]UpdateResult, error) {
+	s.mu.Lock()
+	if s.updating {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("正在更新中，请稍后再试")
+	}
+	s.updating = true
+	cfg := *s.config
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.updating = false
+		s.mu.Unlock()
+	}()
+
+	results := make([]UpdateResult, 0, len(cfg.Files))
+	changed := false
+	for _, name := range cfg.Files {
+		result := s.updateFile(name, cfg.Mirrors, cfg.VerifyChecksum, force)
+		if result.Updated {
+			changed = true
+			fmt.Printf("🌍 GEO 数据已更新: %s (%s)\n", name, result.Version)
+		} else if result.Error != "" {
+			fmt.Printf("⚠️ GEO 数据更新失败: %s: %s\n", name, result.Error)
+		}
+		results = append(results, result)
+	}
+
+	s.mu.Lock()
+	s.config.LastCheck = time.Now().Format("2006-01-02 15:04:05")
+	s.lastResults = results
+	s.save()
+	onUpdated := s.onUpdated
+	s.mu.Unlock()
+
+	if changed && onUpdated != nil {
+		if err := onUpdated(); err != nil {
+			return results, fmt.Errorf("数据已更新，但重载核心失败: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// updateFile 依次尝试各镜像更新单个文件
+func (s *Service) updateFile(name string, mirrors []string, verify, force bool) UpdateResult {
+	result := UpdateResult{Name: name}
+	path := filepath.Join(s.dataDir, name)
+
+	s.mu.RLock()
+	var current string
+	if inst, ok := s.installed[name]; ok {
+		current = inst.SHA256
+	}
+	s.mu.RUnlock()
+	if _, err := os.Stat(path); err != nil {
+		current = ""
+	}
+
+	var errs []string
+	for _, mirror := range mirrors {
+		fileURL := mirror + "/" + name
+
+		expected, err := fetchChecksum(fileURL + ".sha256sum")
+		if err != nil {
+			if verify {
+				errs = append(errs, fmt.Sprintf("%s: 获取校验值失败: %v", mirror, err))
+				continue
+			}
+			expected = ""
+		}
+
+		// 远端校验值与已安装版本一致，无需下载
+		if !force && expected != "" && expected == current {
+			result.Mirror = mirror
+			return result
+		}
+
+		inst, err := s.download(fileURL, path, expected)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", mirror, err))
+			continue
+		}
+		inst.Name = name
+		inst.Mirror = mirror
+
+		result.Mirror = mirror
+		result.Version = inst.Version
+		// 未提供校验值时按下载内容判断是否变化
+		result.Updated = inst.SHA256 != current
+
+		s.mu.Lock()
+		s.installed[name] = inst
+		s.save()
+		s.mu.Unlock()
+		return result
+	}
+
+	result.Error = strings.Join(errs, "; ")
+	return result
+}
+
+// fetchChecksum 获取 .sha256sum 文件中的校验值（格式: <hex>  <文件名>）
+func fetchChecksum(checksumURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("校验文件格式无效")
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("校验文件格式无效")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// download 下载到临时文件，校验通过后原子替换目标文件
+func (s *Service) download(fileURL, path, expectedSum string) (*InstalledFile, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	}
+
+	tmpPath := path + ".download.tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hasher), resp.Body)
+	out.Close()
+	if err != nil {
+		return nil, fmt.Errorf("写入文件失败: %w", err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("下载的文件为空")
+	}
+
+	actualSum := hex.EncodeToString(hasher.Sum(nil))
+	if expectedSum != "" && actualSum != expectedSum {
+		return nil, fmt.Errorf("校验和不匹配: 期望 %s, 实际 %s", expectedSum, actualSum)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("替换文件失败: %w", err)
+	}
+
+	version := actualSum[:12]
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		version = lm.Local().Format("2006-01-02 15:04")
+	}
+
+	return &InstalledFile{
+		SHA256:      actualSum,
+		Size:        size,
+		Version:     version,
+		Verified:    expectedSum != "",
+		InstalledAt: time.Now().Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// autoUpdateLoop 定时自动更新
+func (s *Service) autoUpdateLoop() {
+	for {
+		time.Sleep(1 * time.Hour) // 每小时检查一次
+
+		s.mu.RLock()
+		autoUpdate := s.config.AutoUpdate
+		interval := s.config.UpdateInterval
+		lastCheck := s.config.LastCheck
+		s.mu.RUnlock()
+
+		if !autoUpdate {
+			continue
+		}
+		if lastCheck != "" {
+			lastTime, err := time.ParseInLocation("2006-01-02 15:04:05", lastCheck, time.Local)
+			if err == nil && time.Since(lastTime) < time.Duration(interval)*time.Hour {
+				continue
+			}
+		}
+
+		if _, err := s.Update(false); err != nil {
+			fmt.Printf("⚠️ GEO 数据自动更新: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package lan
+
+import (
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 局域网设备 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/devices", h.ListDevices)
+	r.POST("/oui/update", h.UpdateOUI)
+}
+
+// RegisterNetworkRoutes 注册本机网络相关路由
+func (h *Handler) RegisterNetworkRoutes(r *gin.RouterGroup) {
+	r.GET("/interfaces", h.ListInterfaces)
+}
+
+// ListDevices 列出局域网设备
+// 参数: leases=false 不读取 DHCP 租约，resolve=true 对没有主机名的设备反向解析
+func (h *Handler) ListDevices(c *gin.Context) {
+	devices, err := h.service.List(ListOptions{
+		Leases:  c.DefaultQuery("leases", "true") != "false",
+		Resolve: c.Query("resolve") == "true",
+	})
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    devices,
+	})
+}
+
+// UpdateOUI 下载 MAC 厂商前缀列表
+func (h *Handler) UpdateOUI(c *gin.Context) {
+	count, err := h.service.UpdateOUI()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"prefixes": count},
+	})
+}
@@ -0,0 +1,103 @@
+package lan
+
+import (
+	"net"
+	"net/http"
+	"sort"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 网卡类型
+const (
+	KindLoopback  = "loopback"
+	KindPhysical  = "physical"
+	KindBridge    = "bridge"
+	KindDocker    = "docker" // docker0 / 自定义网络的 br-xxxx 网桥和 veth
+	KindTunnel    = "tunnel" // tun / wireguard / ppp 等虚拟网卡
+	KindVirtual   = "virtual"
+	KindWireless  = "wireless"
+	KindVLAN      = "vlan"
+	KindUndefined = ""
+)
+
+// NetworkInterface 本机网卡
+type NetworkInterface struct {
+	Name         string   `json:"name"`
+	Index        int      `json:"index"`
+	MAC          string   `json:"mac,omitempty"`
+	MTU          int      `json:"mtu"`
+	Up           bool     `json:"up"`
+	Kind         string   `json:"kind,omitempty"`
+	Addresses    []string `json:"addresses"`
+	DefaultRoute bool     `json:"defaultRoute"`        // 默认路由出口，通常为 WAN
+	Suggested    string   `json:"suggested,omitempty"` // 建议的角色：lan / wan
+}
+
+// ListInterfaces 列出本机网卡，并根据默认路由和网卡类型给出 LAN/WAN 建议
+func ListInterfaces() ([]NetworkInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	defaults := defaultRouteInterfaces()
+
+	result := make([]NetworkInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		item := NetworkInterface{
+			Name:         iface.Name,
+			Index:        iface.Index,
+			MAC:          iface.HardwareAddr.String(),
+			MTU:          iface.MTU,
+			Up:           iface.Flags&net.FlagUp != 0,
+			Kind:         interfaceKind(iface),
+			Addresses:    []string{},
+			DefaultRoute: defaults[iface.Name],
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				item.Addresses = append(item.Addresses, addr.String())
+			}
+		}
+
+		switch {
+		case item.DefaultRoute:
+			item.Suggested = "wan"
+		case item.Kind == KindBridge || item.Kind == KindPhysical || item.Kind == KindWireless || item.Kind == KindVLAN:
+			if hasPrivateIPv4(item.Addresses) {
+				item.Suggested = "lan"
+			}
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result, nil
+}
+
+// hasPrivateIPv4 网卡是否配置了私有 IPv4 地址
+func hasPrivateIPv4(addrs []string) bool {
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr)
+		if err == nil && ip.To4() != nil && ip.IsPrivate() {
+			return true
+		}
+	}
+	return false
+}
+
+// ListInterfaces 列出本机网卡，供透明代理选择 LAN/WAN 网卡
+func (h *Handler) ListInterfaces(c *gin.Context) {
+	ifaces, err := ListInterfaces()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    ifaces,
+	})
+}
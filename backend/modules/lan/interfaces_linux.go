@@ -0,0 +1,78 @@
+//go:build linux
+
+package lan
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// interfaceKind 根据 /sys/class/net 判断网卡类型
+func interfaceKind(iface net.Interface) string {
+	if iface.Flags&net.FlagLoopback != 0 {
+		return KindLoopback
+	}
+	sysPath := filepath.Join("/sys/class/net", iface.Name)
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(sysPath, name))
+		return err == nil
+	}
+
+	switch {
+	case iface.Name == "docker0" || strings.HasPrefix(iface.Name, "veth") || isDockerBridge(iface.Name):
+		return KindDocker
+	case exists("bridge"):
+		return KindBridge
+	case exists("tun_flags") || strings.HasPrefix(iface.Name, "wg") || strings.HasPrefix(iface.Name, "ppp"):
+		return KindTunnel
+	case exists("wireless") || exists("phy80211"):
+		return KindWireless
+	case strings.Contains(iface.Name, "."):
+		return KindVLAN
+	case exists("device"):
+		return KindPhysical
+	}
+	return KindVirtual
+}
+
+// isDockerBridge docker 自定义网络的网桥名为 br- 加 12 位网络 ID
+func isDockerBridge(name string) bool {
+	id := strings.TrimPrefix(name, "br-")
+	if id == name || len(id) != 12 {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultRouteInterfaces 读取 IPv4/IPv6 默认路由所在的网卡
+func defaultRouteInterfaces() map[string]bool {
+	result := make(map[string]bool)
+
+	// /proc/net/route: Iface Destination Gateway ...，目标为 00000000 的是默认路由
+	if data, err := os.ReadFile("/proc/net/route"); err == nil {
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[1] == "00000000" {
+				result[fields[0]] = true
+			}
+		}
+	}
+
+	// /proc/net/ipv6_route: 目标 前缀长度 ... 网卡名（最后一列），::/0 为默认路由
+	if data, err := os.ReadFile("/proc/net/ipv6_route"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 10 && fields[0] == strings.Repeat("0", 32) && fields[1] == "00" && fields[9] != "lo" {
+				result[fields[9]] = true
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package lan
+
+import "net"
+
+// interfaceKind 非 Linux 只区分回环网卡
+func interfaceKind(iface net.Interface) string {
+	if iface.Flags&net.FlagLoopback != 0 {
+		return KindLoopback
+	}
+	return KindUndefined
+}
+
+// defaultRouteInterfaces 非 Linux 不判断默认路由（透明代理仅支持 Linux）
+func defaultRouteInterfaces() map[string]bool {
+	return map[string]bool{}
+}
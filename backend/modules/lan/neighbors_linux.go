@@ -0,0 +1,65 @@
+//go:build linux
+
+package lan
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// readNeighbors 读取 IPv4/IPv6 邻居表，ip 命令不可用时退回 /proc/net/arp（仅 IPv4）
+func readNeighbors() ([]neighbor, error) {
+	if output, err := exec.Command("ip", "neigh", "show").Output(); err == nil {
+		return parseIPNeigh(string(output)), nil
+	}
+
+	data, err := os.ReadFile("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	return parseProcARP(string(data)), nil
+}
+
+// parseIPNeigh 解析 ip neigh 输出：
+// 192.168.1.2 dev br-lan lladdr aa:bb:cc:dd:ee:ff REACHABLE
+// fe80::1 dev br-lan lladdr aa:bb:cc:dd:ee:ff router STALE
+func parseIPNeigh(output string) []neighbor {
+	var neighbors []neighbor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n := neighbor{IP: fields[0], State: fields[len(fields)-1]}
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				n.Interface = fields[i+1]
+			case "lladdr":
+				n.MAC = fields[i+1]
+			}
+		}
+		// 没有 lladdr 的是解析失败的记录（FAILED / INCOMPLETE）
+		if n.MAC == "" || n.Interface == "lo" {
+			continue
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
+
+// parseProcARP 解析 /proc/net/arp：
+// IP address  HW type  Flags  HW address  Mask  Device
+func parseProcARP(data string) []neighbor {
+	var neighbors []neighbor
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		// Flags 0x0 表示未完成解析
+		if len(fields) < 6 || fields[2] == "0x0" || fields[0] == "IP" {
+			continue
+		}
+		neighbors = append(neighbors, neighbor{IP: fields[0], MAC: fields[3], Interface: fields[5]})
+	}
+	return neighbors
+}
@@ -0,0 +1,43 @@
+//go:build !linux
+
+package lan
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// readNeighbors 通过 arp -a 读取 ARP 表（macOS / Windows）
+func readNeighbors() ([]neighbor, error) {
+	output, err := exec.Command("arp", "-a").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseARPTable(string(output)), nil
+}
+
+// parseARPTable 解析 arp -a 输出：
+// macOS:   router.lan (192.168.1.1) at aa:bb:cc:dd:ee:ff on en0 ifscope [ethernet]
+// Windows:   192.168.1.1           aa-bb-cc-dd-ee-ff     dynamic
+func parseARPTable(output string) []neighbor {
+	var neighbors []neighbor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 4 && fields[2] == "at":
+			n := neighbor{IP: strings.Trim(fields[1], "()"), MAC: fields[3]}
+			if fields[0] != "?" {
+				n.Hostname = fields[0]
+			}
+			for i := 4; i+1 < len(fields); i++ {
+				if fields[i] == "on" {
+					n.Interface = fields[i+1]
+				}
+			}
+			neighbors = append(neighbors, n)
+		case len(fields) == 3 && strings.Count(fields[1], "-") == 5:
+			neighbors = append(neighbors, neighbor{IP: fields[0], MAC: fields[1], State: fields[2]})
+		}
+	}
+	return neighbors
+}
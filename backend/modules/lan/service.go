@@ -0,0 +1,410 @@
+package lan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ouiURL IEEE 公布的 MAC 厂商前缀列表
+const ouiURL = "https://standards-oui.ieee.org/oui/oui.txt"
+
+// defaultLeaseFiles 常见 DHCP 服务的租约文件位置（dnsmasq / OpenWrt / ISC dhcpd）
+var defaultLeaseFiles = []string{
+	"/tmp/dhcp.leases",
+	"/var/lib/misc/dnsmasq.leases",
+	"/var/lib/dnsmasq/dnsmasq.leases",
+	"/var/lib/dhcp/dhcpd.leases",
+	"/var/lib/dhcpd/dhcpd.leases",
+}
+
+// Device 局域网设备
+type Device struct {
+	MAC         string     `json:"mac"`
+	IP          string     `json:"ip,omitempty"`   // IPv4 地址
+	IPv6        []string   `json:"ipv6,omitempty"` // IPv6 地址
+	Hostname    string     `json:"hostname,omitempty"`
+	Vendor      string     `json:"vendor,omitempty"`
+	Randomized  bool       `json:"randomized"` // 随机（本地管理）MAC，无法查询厂商
+	Interface   string     `json:"interface,omitempty"`
+	State       string     `json:"state,omitempty"` // 邻居表状态，如 REACHABLE / STALE
+	Online      bool       `json:"online"`          // 出现在邻居表中
+	Sources     []string   `json:"sources"`         // neighbor / dhcp
+	LeaseExpire *time.Time `json:"leaseExpire,omitempty"`
+}
+
+// neighbor 邻居表（ARP / NDP）中的一条记录
+type neighbor struct {
+	IP        string
+	MAC       string
+	Hostname  string
+	Interface string
+	State     string
+}
+
+// lease DHCP 租约
+type lease struct {
+	IP       string
+	MAC      string
+	Hostname string
+	Expire   *time.Time
+}
+
+// ListOptions 设备列表选项
+type ListOptions struct {
+	Leases  bool // 读取 DHCP 租约补充主机名和离线设备
+	Resolve bool // 没有主机名时反向解析
+}
+
+// Service 局域网设备发现
+type Service struct {
+	dataDir    string
+	leaseFiles []string
+
+	ouiMu      sync.Mutex
+	oui        map[string]string
+	ouiModTime time.Time
+}
+
+// NewService 创建服务
+func NewService(dataDir string) *Service {
+	return &Service{dataDir: dataDir, leaseFiles: defaultLeaseFiles}
+}
+
+// List 列出局域网设备：合并邻居表和 DHCP 租约，按 MAC 去重
+func (s *Service) List(opts ListOptions) ([]Device, error) {
+	neighbors, err := readNeighbors()
+	if err != nil {
+		return nil, fmt.Errorf("读取邻居表失败: %w", err)
+	}
+
+	devices := make(map[string]*Device)
+	get := func(mac string) *Device {
+		d, ok := devices[mac]
+		if !ok {
+			d = &Device{MAC: mac, Sources: []string{}}
+			devices[mac] = d
+		}
+		return d
+	}
+	addSource := func(d *Device, source string) {
+		for _, s := range d.Sources {
+			if s == source {
+				return
+			}
+		}
+		d.Sources = append(d.Sources, source)
+	}
+
+	for _, n := range neighbors {
+		mac, ok := normalizeMAC(n.MAC)
+		if !ok {
+			continue
+		}
+		d := get(mac)
+		d.Online = true
+		addSource(d, "neighbor")
+		if ip := net.ParseIP(n.IP); ip != nil && ip.To4() == nil {
+			d.IPv6 = append(d.IPv6, n.IP)
+		} else if d.IP == "" {
+			d.IP = n.IP
+		}
+		if d.Interface == "" {
+			d.Interface = n.Interface
+		}
+		if d.State == "" {
+			d.State = n.State
+		}
+		if d.Hostname == "" {
+			d.Hostname = n.Hostname
+		}
+	}
+
+	if opts.Leases {
+		for _, l := range s.readLeases() {
+			mac, ok := normalizeMAC(l.MAC)
+			if !ok {
+				continue
+			}
+			// 过期租约只补充在线设备的信息
+			expired := l.Expire != nil && l.Expire.Before(time.Now())
+			if _, online := devices[mac]; expired && !online {
+				continue
+			}
+			d := get(mac)
+			addSource(d, "dhcp")
+			if d.IP == "" {
+				d.IP = l.IP
+			}
+			if l.Hostname != "" {
+				d.Hostname = l.Hostname
+			}
+			if !expired {
+				d.LeaseExpire = l.Expire
+			}
+		}
+	}
+
+	oui := s.ouiTable()
+	result := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		hw, _ := net.ParseMAC(d.MAC)
+		d.Randomized = hw[0]&0x02 != 0
+		if !d.Randomized {
+			d.Vendor = oui[strings.ToUpper(strings.ReplaceAll(d.MAC[:8], ":", ""))]
+		}
+		sort.Strings(d.IPv6)
+		result = append(result, *d)
+	}
+
+	if opts.Resolve {
+		resolveHostnames(result)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Online != result[j].Online {
+			return result[i].Online
+		}
+		return ipLess(result[i].IP, result[j].IP)
+	})
+	return result, nil
+}
+
+// LookupIP 按 MAC 查找设备当前的 IPv4 地址，供设备策略引用
+func (s *Service) LookupIP(mac string) string {
+	mac, ok := normalizeMAC(mac)
+	if !ok {
+		return ""
+	}
+	neighbors, err := readNeighbors()
+	if err == nil {
+		for _, n := range neighbors {
+			if m, ok := normalizeMAC(n.MAC); ok && m == mac {
+				if ip := net.ParseIP(n.IP); ip != nil && ip.To4() != nil {
+					return n.IP
+				}
+			}
+		}
+	}
+	for _, l := range s.readLeases() {
+		if m, ok := normalizeMAC(l.MAC); ok && m == mac && (l.Expire == nil || l.Expire.After(time.Now())) {
+			return l.IP
+		}
+	}
+	return ""
+}
+
+// normalizeMAC 规范化 MAC（兼容 macOS arp 省略前导零的写法），过滤广播、组播和全零地址
+func normalizeMAC(raw string) (string, bool) {
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return "", false
+	}
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	hw, err := net.ParseMAC(strings.Join(parts, ":"))
+	if err != nil || len(hw) != 6 {
+		return "", false
+	}
+	if hw[0]&0x01 != 0 || hw.String() == "00:00:00:00:00:00" {
+		return "", false
+	}
+	return hw.String(), true
+}
+
+// ipLess 按 IP 数值排序，空地址排在最后
+func ipLess(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA == nil:
+		return false
+	case ipB == nil:
+		return true
+	}
+	return string(ipA.To16()) < string(ipB.To16())
+}
+
+// resolveHostnames 对没有主机名的设备反向解析（并发，整体限时）
+func resolveHostnames(devices []Device) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 16)
+	for i := range devices {
+		if devices[i].Hostname != "" || devices[i].IP == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(d *Device) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			names, err := net.DefaultResolver.LookupAddr(ctx, d.IP)
+			if err == nil && len(names) > 0 {
+				d.Hostname = strings.TrimSuffix(names[0], ".")
+			}
+		}(&devices[i])
+	}
+	wg.Wait()
+}
+
+// readLeases 读取所有存在的租约文件，后读取的记录覆盖先读取的
+func (s *Service) readLeases() []lease {
+	var leases []lease
+	for _, path := range s.leaseFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(string(data), "lease ") && strings.Contains(string(data), "{") {
+			leases = append(leases, parseISCLeases(string(data))...)
+		} else {
+			leases = append(leases, parseDnsmasqLeases(string(data))...)
+		}
+	}
+	return leases
+}
+
+// parseDnsmasqLeases 解析 dnsmasq 租约：<到期时间> <MAC> <IP> <主机名> <客户端 ID>
+func parseDnsmasqLeases(data string) []lease {
+	var leases []lease
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || net.ParseIP(fields[2]) == nil {
+			continue
+		}
+		l := lease{MAC: fields[1], IP: fields[2]}
+		if fields[3] != "*" {
+			l.Hostname = fields[3]
+		}
+		// 0 表示永久租约
+		if expire, err := strconv.ParseInt(fields[0], 10, 64); err == nil && expire > 0 {
+			t := time.Unix(expire, 0)
+			l.Expire = &t
+		}
+		leases = append(leases, l)
+	}
+	return leases
+}
+
+// parseISCLeases 解析 ISC dhcpd 租约文件中 binding state 为 active 的租约
+func parseISCLeases(data string) []lease {
+	var leases []lease
+	var current *lease
+	active := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ";")
+		switch {
+		case strings.HasPrefix(line, "lease ") && strings.HasSuffix(line, "{"):
+			current = &lease{IP: strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "lease "), "{"))}
+			active = false
+		case current == nil:
+			continue
+		case line == "}":
+			if active && current.MAC != "" {
+				leases = append(leases, *current)
+			}
+			current = nil
+		case strings.HasPrefix(line, "hardware ethernet "):
+			current.MAC = strings.TrimPrefix(line, "hardware ethernet ")
+		case strings.HasPrefix(line, "client-hostname "):
+			current.Hostname = strings.Trim(strings.TrimPrefix(line, "client-hostname "), `"`)
+		case line == "binding state active":
+			active = true
+		case strings.HasPrefix(line, "ends "):
+			// ends <星期> YYYY/MM/DD HH:MM:SS（UTC），never 表示永久
+			fields := strings.Fields(line)
+			if len(fields) == 4 {
+				if t, err := time.Parse("2006/01/02 15:04:05", fields[2]+" "+fields[3]); err == nil {
+					current.Expire = &t
+				}
+			}
+		}
+	}
+	return leases
+}
+
+// ouiPath 厂商前缀列表文件
+func (s *Service) ouiPath() string {
+	return filepath.Join(s.dataDir, "oui.txt")
+}
+
+// ouiTable 加载厂商前缀列表（文件更新后重新加载），文件不存在时返回空表
+func (s *Service) ouiTable() map[string]string {
+	s.ouiMu.Lock()
+	defer s.ouiMu.Unlock()
+
+	info, err := os.Stat(s.ouiPath())
+	if err != nil {
+		return nil
+	}
+	if s.oui != nil && info.ModTime().Equal(s.ouiModTime) {
+		return s.oui
+	}
+
+	file, err := os.Open(s.ouiPath())
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	// 格式: 286FB9     (base 16)		Nokia Shanghai Bell Co., Ltd.
+	table := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		prefix, vendor, ok := strings.Cut(scanner.Text(), "(base 16)")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || len(prefix) != 6 {
+			continue
+		}
+		table[strings.ToUpper(prefix)] = strings.TrimSpace(vendor)
+	}
+	s.oui = table
+	s.ouiModTime = info.ModTime()
+	return table
+}
+
+// UpdateOUI 下载 IEEE 厂商前缀列表，返回前缀数量
+func (s *Service) UpdateOUI() (int, error) {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(ouiURL)
+	if err != nil {
+		return 0, fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	}
+
+	tmpPath := s.ouiPath() + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("下载失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.ouiPath()); err != nil {
+		return 0, err
+	}
+	return len(s.ouiTable()), nil
+}
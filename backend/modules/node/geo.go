@@ -0,0 +1,242 @@
+package node
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	geoCacheTTL        = time.Hour        // 域名解析及归属查询结果的缓存时间
+	geoFailureTTL      = 10 * time.Minute // 解析失败的缓存时间
+	geoResolveTimeout  = 3 * time.Second
+	geoResolveParallel = 16
+)
+
+// GeoInfo 节点服务器的归属信息
+type GeoInfo struct {
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"` // ISO 3166 国家代码（大写）
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"asOrg,omitempty"`
+}
+
+// GeoResolver 查询 IP 归属（由 geodata 模块提供）
+type GeoResolver func(ip string) *GeoInfo
+
+// NodeWithGeo 附带服务器归属信息的节点
+type NodeWithGeo struct {
+	*Node
+	Geo *GeoInfo `json:"geo,omitempty"`
+}
+
+// CountrySummary 按国家统计的节点数量
+type CountrySummary struct {
+	Country string   `json:"country"` // 未知时为空
+	Count   int      `json:"count"`
+	Nodes   []string `json:"nodes"` // 节点 ID
+}
+
+type geoEntry struct {
+	info    *GeoInfo
+	expires time.Time
+}
+
+// geoCache 服务器地址 → 归属信息缓存
+type geoCache struct {
+	mu       sync.Mutex
+	entries  map[string]geoEntry
+	pending  map[string]bool // 后台查询中的地址
+	resolver GeoResolver
+}
+
+// SetGeoResolver 设置 IP 归属查询
+func (s *Service) SetGeoResolver(resolver GeoResolver) {
+	s.geo.mu.Lock()
+	defer s.geo.mu.Unlock()
+	s.geo.resolver = resolver
+	s.geo.entries = make(map[string]geoEntry)
+}
+
+// cachedGeo 读取缓存的归属信息
+func (s *Service) cachedGeo(server string) (*GeoInfo, bool) {
+	s.geo.mu.Lock()
+	defer s.geo.mu.Unlock()
+	if s.geo.resolver == nil {
+		return nil, true
+	}
+	entry, ok := s.geo.entries[server]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.info, true
+}
+
+// lookupServer 查询服务器地址的归属，域名先解析为 IP（取第一个地址）
+func (s *Service) lookupServer(server string) *GeoInfo {
+	s.geo.mu.Lock()
+	resolver := s.geo.resolver
+	s.geo.mu.Unlock()
+	if resolver == nil {
+		return nil
+	}
+
+	ip := server
+	if net.ParseIP(server) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), geoResolveTimeout)
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, server)
+		cancel()
+		if err != nil || len(addrs) == 0 {
+			s.cacheGeo(server, nil, geoFailureTTL)
+			return nil
+		}
+		ip = addrs[0].IP.String()
+	}
+
+	info := resolver(ip)
+	ttl := geoCacheTTL
+	if info == nil {
+		ttl = geoFailureTTL
+	}
+	s.cacheGeo(server, info, ttl)
+	return info
+}
+
+func (s *Service) cacheGeo(server string, info *GeoInfo, ttl time.Duration) {
+	s.geo.mu.Lock()
+	defer s.geo.mu.Unlock()
+	if s.geo.entries == nil {
+		s.geo.entries = make(map[string]geoEntry)
+	}
+	s.geo.entries[server] = geoEntry{info: info, expires: time.Now().Add(ttl)}
+	delete(s.geo.pending, server)
+}
+
+// resolveServers 并发查询多个服务器地址
+func (s *Service) resolveServers(servers []string) map[string]*GeoInfo {
+	var mu sync.Mutex
+	result := make(map[string]*GeoInfo, len(servers))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, geoResolveParallel)
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			info := s.lookupServer(server)
+			mu.Lock()
+			result[server] = info
+			mu.Unlock()
+		}(server)
+	}
+	wg.Wait()
+	return result
+}
+
+// ListWithGeo 获取所有节点并补充服务器归属信息
+// wait 为 false 时只使用缓存，未缓存的地址在后台查询，避免域名解析拖慢节点列表
+func (s *Service) ListWithGeo(wait bool) []NodeWithGeo {
+	nodes := s.ListAll()
+	result := make([]NodeWithGeo, len(nodes))
+	var misses []string
+	seen := make(map[string]bool)
+	for i, n := range nodes {
+		result[i].Node = n
+		if n.Server == "" {
+			continue
+		}
+		if info, ok := s.cachedGeo(n.Server); ok {
+			result[i].Geo = info
+		} else if !seen[n.Server] {
+			seen[n.Server] = true
+			misses = append(misses, n.Server)
+		}
+	}
+	if len(misses) == 0 {
+		return result
+	}
+
+	if !wait {
+		s.geo.mu.Lock()
+		if s.geo.pending == nil {
+			s.geo.pending = make(map[string]bool)
+		}
+		var todo []string
+		for _, server := range misses {
+			if !s.geo.pending[server] {
+				s.geo.pending[server] = true
+				todo = append(todo, server)
+			}
+		}
+		s.geo.mu.Unlock()
+		if len(todo) > 0 {
+			go s.resolveServers(todo)
+		}
+		return result
+	}
+
+	resolved := s.resolveServers(misses)
+	for i := range result {
+		if info, ok := resolved[result[i].Server]; ok {
+			result[i].Geo = info
+		}
+	}
+	return result
+}
+
+// countrySummary 按国家分组统计，数量多的在前
+func countrySummary(nodes []NodeWithGeo) []CountrySummary {
+	groups := make(map[string]*CountrySummary)
+	for _, n := range nodes {
+		country := ""
+		if n.Geo != nil {
+			country = n.Geo.Country
+		}
+		g, ok := groups[country]
+		if !ok {
+			g = &CountrySummary{Country: country, Nodes: []string{}}
+			groups[country] = g
+		}
+		g.Count++
+		g.Nodes = append(g.Nodes, n.ID)
+	}
+
+	result := make([]CountrySummary, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Country < result[j].Country
+	})
+	return result
+}
+
+// filterByCountry 只保留指定国家的节点
+func filterByCountry(nodes []NodeWithGeo, country string) []NodeWithGeo {
+	result := make([]NodeWithGeo, 0)
+	for _, n := range nodes {
+		if n.Geo != nil && strings.EqualFold(n.Geo.Country, country) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// GetCountries 按服务器所在国家统计节点
+func (h *Handler) GetCountries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    countrySummary(h.service.ListWithGeo(true)),
+	})
+}
@@ -1,9 +1,12 @@
 package node
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"ProxyStation/backend/apierror"
 	"ProxyStation/backend/modules/subscription"
 
 	"github.com/gin-gonic/gin"
@@ -14,21 +17,35 @@ type Handler struct {
 }
 
 func NewHandler(dataDir string, subService *subscription.Service) *Handler {
-	return &Handler{
+	h := &Handler{
 		service: NewService(dataDir, subService),
 	}
+	h.service.StartHealthChecker()
+	return h
 }
 
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("", h.List)
+	r.GET("/countries", h.GetCountries)
 	r.POST("/import", h.ImportURL)
-	r.POST("/manual", h.AddManual)
+	r.GET("/manual", h.ListManual)
+	r.POST("/manual", h.CreateManual)
 	r.POST("/manual/advanced", h.AddManualAdvanced)
+	r.GET("/manual/:id/reveal", h.RevealManual)
+	r.PUT("/manual/:id", h.UpdateManual)
+	r.PUT("/manual/:id/enabled", h.SetManualEnabled)
+	r.DELETE("/manual/:id", h.RemoveManual)
 	r.DELETE("/:id", h.Delete)
 	r.POST("/test", h.TestDelay)
 	r.POST("/test-batch", h.TestDelayBatch)
 	r.GET("/:id/share", h.GetShareURL)
 	r.GET("/protocols/:protocol/fields", h.GetProtocolFields)
+	r.GET("/health", h.GetHealth)
+	r.PUT("/health/config", h.SetHealthConfig)
+	r.POST("/health/check", h.CheckHealth)
+	r.GET("/speedtest", h.GetSpeedResults)
+	r.POST("/speedtest/batch", h.SpeedTestBatch)
+	r.POST("/:id/speedtest", h.SpeedTest)
 }
 
 // GetService 获取节点服务
@@ -38,7 +55,17 @@ func (h *Handler) GetService() *Service {
 
 // List 获取所有节点
 func (h *Handler) List(c *gin.Context) {
-	nodes := h.service.ListAll()
+	country := c.Query("country")
+	nodes := h.service.ListWithGeo(country != "")
+	if country != "" {
+		nodes = filterByCountry(nodes, country)
+	}
+	// 手动节点的凭据与分享链接脱敏，原始值需通过 /manual/:id/reveal 单独获取
+	for i := range nodes {
+		if nodes[i].Node.IsManual {
+			nodes[i].Node = maskedNode(nodes[i].Node)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -47,24 +74,36 @@ func (h *Handler) List(c *gin.Context) {
 }
 
 // ImportURL 从URL导入节点
+// url: 单条分享链接（返回单个节点）
+// content: 多行分享链接或 Base64 订阅内容（返回批量导入结果，含每条链接的错误）
 func (h *Handler) ImportURL(c *gin.Context) {
 	var req struct {
-		URL string `json:"url" binding:"required"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// 批量导入
+	if req.Content != "" || strings.Contains(strings.TrimSpace(req.URL), "\n") {
+		content := req.Content
+		if content == "" {
+			content = req.URL
+		}
+		h.importLinks(c, content)
+		return
+	}
+
+	if req.URL == "" {
+		apierror.Message(c, http.StatusBadRequest, "url 或 content 不能为空")
 		return
 	}
 
-	node, err := h.service.ImportURL(req.URL)
+	node, err := h.service.ImportURL(strings.TrimSpace(req.URL))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": "解析失败: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusBadRequest, "解析失败: "+err.Error())
 		return
 	}
 
@@ -75,36 +114,23 @@ func (h *Handler) ImportURL(c *gin.Context) {
 	})
 }
 
-// AddManual 手动添加节点
-func (h *Handler) AddManual(c *gin.Context) {
-	var req struct {
-		Name   string `json:"name" binding:"required"`
-		Type   string `json:"type" binding:"required"`
-		Server string `json:"server" binding:"required"`
-		Port   int    `json:"port" binding:"required"`
-		Config string `json:"config"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+// importLinks 批量导入分享链接
+func (h *Handler) importLinks(c *gin.Context, content string) {
+	result, err := h.service.ImportLinks(content)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	node, err := h.service.AddManual(req.Name, req.Type, req.Server, req.Port, req.Config)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+	if len(result.Imported) == 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, "未解析到任何有效节点").WithDetails(result))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
-		"message": "success",
-		"data":    node,
+		"message": fmt.Sprintf("成功导入 %d 个节点，失败 %d 个", len(result.Imported), len(result.Errors)),
+		"data":    result,
 	})
 }
 
@@ -112,10 +138,7 @@ func (h *Handler) AddManual(c *gin.Context) {
 func (h *Handler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.service.DeleteManual(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -134,10 +157,7 @@ func (h *Handler) TestDelay(c *gin.Context) {
 		Timeout int    `json:"timeout"` // 毫秒
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -169,10 +189,7 @@ func (h *Handler) TestDelayBatch(c *gin.Context) {
 		Timeout int      `json:"timeout"` // 毫秒
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -198,10 +215,7 @@ func (h *Handler) GetShareURL(c *gin.Context) {
 	id := c.Param("id")
 	url, err := h.service.GetShareURL(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -224,19 +238,13 @@ func (h *Handler) AddManualAdvanced(c *gin.Context) {
 		Config     map[string]interface{} `json:"config" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	node, err := h.service.AddManualAdvanced(req.Name, req.Type, req.Server, req.ServerPort, req.Config)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -252,10 +260,7 @@ func (h *Handler) GetProtocolFields(c *gin.Context) {
 	protocol := c.Param("protocol")
 	fields := GetProtocolFieldDefinitions(protocol)
 	if fields == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": "不支持的协议类型",
-		})
+		apierror.Message(c, http.StatusBadRequest, "不支持的协议类型")
 		return
 	}
 
@@ -268,3 +273,50 @@ func (h *Handler) GetProtocolFields(c *gin.Context) {
 		},
 	})
 }
+
+// GetHealth 获取节点健康状态
+func (h *Handler) GetHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"config":  h.service.GetHealthConfig(),
+			"results": h.service.GetHealth(),
+		},
+	})
+}
+
+// SetHealthConfig 更新健康检查配置
+func (h *Handler) SetHealthConfig(c *gin.Context) {
+	var req HealthConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	config, err := h.service.SetHealthConfig(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    config,
+	})
+}
+
+// CheckHealth 立即执行一次健康检查
+func (h *Handler) CheckHealth(c *gin.Context) {
+	if err := h.service.CheckHealth(); err != nil {
+		apierror.Respond(c, http.StatusConflict, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetHealth(),
+	})
+}
@@ -0,0 +1,272 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthConfig 节点健康检查配置
+type HealthConfig struct {
+	Enabled       bool `json:"enabled"`
+	Interval      int  `json:"interval"`      // 检查间隔（秒）
+	Timeout       int  `json:"timeout"`       // 单个节点超时（毫秒）
+	FailThreshold int  `json:"failThreshold"` // 连续失败多少次判定为不可用
+	ExcludeDead   bool `json:"excludeDead"`   // 重新生成配置时排除不可用节点
+}
+
+// NodeHealth 节点健康状态
+type NodeHealth struct {
+	NodeID              string `json:"nodeId"`
+	Name                string `json:"name"`
+	Healthy             bool   `json:"healthy"`
+	Delay               int    `json:"delay"` // 最近一次延迟 ms，0 表示失败
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	LastCheck           int64  `json:"lastCheck"`
+	LastSuccess         int64  `json:"lastSuccess,omitempty"`
+}
+
+// HealthChange 一次健康检查中可用状态发生变化的节点
+type HealthChange struct {
+	Changed []NodeHealth `json:"changed"`
+	Healthy int          `json:"healthy"` // 检查后可用节点数
+	Total   int          `json:"total"`
+}
+
+// healthChecker 节点健康检查状态
+type healthChecker struct {
+	mu       sync.RWMutex
+	config   HealthConfig
+	results  map[string]*NodeHealth
+	checking bool
+	onChange func(change HealthChange) // 节点可用状态变化时调用
+}
+
+func defaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Enabled:       false,
+		Interval:      300,
+		Timeout:       5000,
+		FailThreshold: 3,
+		ExcludeDead:   false,
+	}
+}
+
+func (s *Service) loadHealth() {
+	s.health.config = defaultHealthConfig()
+	s.health.results = make(map[string]*NodeHealth)
+
+	if data, err := os.ReadFile(filepath.Join(s.dataDir, "health_config.json")); err == nil {
+		json.Unmarshal(data, &s.health.config)
+	}
+	if data, err := os.ReadFile(filepath.Join(s.dataDir, "node_health.json")); err == nil {
+		json.Unmarshal(data, &s.health.results)
+	}
+}
+
+func (s *Service) saveHealthResults() error {
+	s.health.mu.RLock()
+	data, err := json.MarshalIndent(s.health.results, "", "  ")
+	s.health.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dataDir, "node_health.json"), data, 0644)
+}
+
+// GetHealthConfig 获取健康检查配置
+func (s *Service) GetHealthConfig() HealthConfig {
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+	return s.health.config
+}
+
+// SetHealthConfig 更新健康检查配置
+func (s *Service) SetHealthConfig(config HealthConfig) (HealthConfig, error) {
+	defaults := defaultHealthConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Interval < 30 {
+		return config, fmt.Errorf("检查间隔不能小于 30 秒")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.FailThreshold <= 0 {
+		config.FailThreshold = defaults.FailThreshold
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return config, err
+	}
+	if err := os.WriteFile(filepath.Join(s.dataDir, "health_config.json"), data, 0644); err != nil {
+		return config, err
+	}
+
+	s.health.mu.Lock()
+	s.health.config = config
+	s.health.mu.Unlock()
+	return config, nil
+}
+
+// GetHealth 获取所有节点的健康状态（仅包含当前存在的节点）
+func (s *Service) GetHealth() []*NodeHealth {
+	nodes := s.ListAll()
+
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+
+	result := make([]*NodeHealth, 0, len(nodes))
+	for _, n := range nodes {
+		if h, ok := s.health.results[n.ID]; ok {
+			item := *h
+			item.Name = n.Name
+			result = append(result, &item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Healthy != result[j].Healthy {
+			return !result[i].Healthy
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// IsExcluded 节点是否因健康检查失败而被排除（未开启排除时始终返回 false）
+func (s *Service) IsExcluded(nodeID string) bool {
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+	if !s.health.config.Enabled || !s.health.config.ExcludeDead {
+		return false
+	}
+	h, ok := s.health.results[nodeID]
+	return ok && !h.Healthy
+}
+
+// ListForConfig 获取用于生成配置的节点，排除不可用节点
+// 所有节点都不可用时返回全部节点，避免生成空配置
+func (s *Service) ListForConfig() []*Node {
+	all := s.ListAll()
+	// 停用的节点不参与生成配置
+	nodes := make([]*Node, 0, len(all))
+	for _, n := range all {
+		if n.Enabled {
+			nodes = append(nodes, n)
+		}
+	}
+	result := make([]*Node, 0, len(nodes))
+	for _, n := range nodes {
+		if !s.IsExcluded(n.ID) {
+			result = append(result, n)
+		}
+	}
+	if len(result) == 0 {
+		return nodes
+	}
+	if excluded := len(nodes) - len(result); excluded > 0 {
+		fmt.Printf("🩺 排除 %d 个不可用节点\n", excluded)
+	}
+	return result
+}
+
+// CheckHealth 立即检查所有节点
+func (s *Service) CheckHealth() error {
+	s.health.mu.Lock()
+	if s.health.checking {
+		s.health.mu.Unlock()
+		return fmt.Errorf("健康检查正在进行中")
+	}
+	s.health.checking = true
+	config := s.health.config
+	s.health.mu.Unlock()
+
+	defer func() {
+		s.health.mu.Lock()
+		s.health.checking = false
+		s.health.mu.Unlock()
+	}()
+
+	nodes := s.ListAll()
+	ids := make([]string, 0, len(nodes))
+	names := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+		names[n.ID] = n.Name
+	}
+
+	delays := s.TestDelayBatch(ids, time.Duration(config.Timeout)*time.Millisecond)
+	s.SaveDelayBatch(delays)
+
+	now := time.Now().Unix()
+	s.health.mu.Lock()
+	current := make(map[string]*NodeHealth, len(delays))
+	change := HealthChange{Total: len(delays)}
+	for id, delay := range delays {
+		h, ok := s.health.results[id]
+		if !ok {
+			// 新节点视为可用，首次检查失败时也会通知
+			h = &NodeHealth{NodeID: id, Healthy: true}
+		}
+		wasHealthy := h.Healthy
+		h.Name = names[id]
+		h.Delay = delay
+		h.LastCheck = now
+		if delay > 0 {
+			h.ConsecutiveFailures = 0
+			h.LastSuccess = now
+		} else {
+			h.ConsecutiveFailures++
+		}
+		h.Healthy = h.ConsecutiveFailures < config.FailThreshold
+		if h.Healthy {
+			change.Healthy++
+		}
+		if h.Healthy != wasHealthy {
+			change.Changed = append(change.Changed, *h)
+		}
+		current[id] = h
+	}
+	// 已删除的节点不再保留
+	s.health.results = current
+	onChange := s.health.onChange
+	s.health.mu.Unlock()
+
+	if onChange != nil && len(change.Changed) > 0 {
+		onChange(change)
+	}
+
+	return s.saveHealthResults()
+}
+
+// SetOnHealthChange 设置节点可用状态变化回调
+func (s *Service) SetOnHealthChange(callback func(change HealthChange)) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.onChange = callback
+}
+
+// StartHealthChecker 启动后台健康检查（未开启时只等待配置变化）
+func (s *Service) StartHealthChecker() {
+	go func() {
+		var lastRun time.Time
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			config := s.GetHealthConfig()
+			if !config.Enabled || time.Since(lastRun) < time.Duration(config.Interval)*time.Second {
+				continue
+			}
+			lastRun = time.Now()
+			if err := s.CheckHealth(); err != nil {
+				fmt.Printf("⚠️ 节点健康检查失败: %v\n", err)
+			}
+		}
+	}()
+}
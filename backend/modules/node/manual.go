@@ -0,0 +1,361 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maskedValue 脱敏后的占位值，编辑时提交该值表示保持原值不变
+const maskedValue = "******"
+
+// secretKeys 需要脱敏的节点凭据字段（同时包含 Mihomo 和 Sing-Box 风格的字段名）
+var secretKeys = map[string]bool{
+	"password": true, "uuid": true, "auth": true, "auth-str": true, "auth_str": true,
+	"private-key": true, "private_key": true, "pre-shared-key": true, "pre_shared_key": true,
+	"obfs-password": true, "obfs_password": true, "psk": true, "token": true, "secret": true,
+}
+
+// ErrNodeNotFound 手动节点不存在
+var ErrNodeNotFound = errors.New("节点不存在")
+
+// ManualNodeInput 创建或编辑手动节点的参数
+type ManualNodeInput struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Server     string          `json:"server"`
+	ServerPort int             `json:"serverPort"`
+	Port       int             `json:"port,omitempty"`    // 兼容旧接口的字段名
+	Config     json.RawMessage `json:"config"`            // JSON 对象，或 JSON 对象序列化后的字符串
+	Enabled    *bool           `json:"enabled,omitempty"` // 为空时新建节点默认启用、编辑时保持不变
+}
+
+// parseConfig 解析配置，支持对象和字符串两种形式
+func (in ManualNodeInput) parseConfig() (map[string]interface{}, error) {
+	raw := in.Config
+	if len(raw) == 0 || string(raw) == "null" {
+		return map[string]interface{}{}, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if strings.TrimSpace(text) == "" {
+			return map[string]interface{}{}, nil
+		}
+		raw = json.RawMessage(text)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("节点配置必须是 JSON 对象: %w", err)
+	}
+	return config, nil
+}
+
+func (in *ManualNodeInput) validate() error {
+	if in.ServerPort == 0 {
+		in.ServerPort = in.Port
+	}
+	switch {
+	case strings.TrimSpace(in.Name) == "":
+		return fmt.Errorf("节点名称不能为空")
+	case in.Type == "":
+		return fmt.Errorf("节点类型不能为空")
+	case strings.TrimSpace(in.Server) == "":
+		return fmt.Errorf("服务器地址不能为空")
+	case in.ServerPort <= 0 || in.ServerPort > 65535:
+		return fmt.Errorf("端口无效: %d", in.ServerPort)
+	}
+	return nil
+}
+
+// maskSecrets 递归替换配置中的凭据字段
+func maskSecrets(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if s, ok := item.(string); ok && s != "" && secretKeys[strings.ToLower(k)] {
+				result[k] = maskedValue
+				continue
+			}
+			result[k] = maskSecrets(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = maskSecrets(item)
+		}
+		return result
+	}
+	return v
+}
+
+// restoreSecrets 将提交的配置中仍为占位值的字段还原为原配置中对应位置的值
+func restoreSecrets(updated, previous interface{}) interface{} {
+	switch val := updated.(type) {
+	case map[string]interface{}:
+		prev, _ := previous.(map[string]interface{})
+		for k, item := range val {
+			if s, ok := item.(string); ok && s == maskedValue {
+				if old, ok := prev[k]; ok {
+					val[k] = old
+				}
+				continue
+			}
+			val[k] = restoreSecrets(item, prev[k])
+		}
+	case []interface{}:
+		prev, _ := previous.([]interface{})
+		for i, item := range val {
+			var old interface{}
+			if i < len(prev) {
+				old = prev[i]
+			}
+			val[i] = restoreSecrets(item, old)
+		}
+	}
+	return updated
+}
+
+// maskedNode 返回凭据脱敏后的节点副本，分享链接包含凭据因此不返回
+func maskedNode(n *Node) *Node {
+	copied := *n
+	copied.ShareURL = ""
+	var config interface{}
+	if err := json.Unmarshal([]byte(n.Config), &config); err == nil {
+		if data, err := json.Marshal(maskSecrets(config)); err == nil {
+			copied.Config = string(data)
+		}
+	} else {
+		copied.Config = ""
+	}
+	return &copied
+}
+
+// ListManual 获取手动节点（凭据已脱敏），按名称排序
+func (s *Service) ListManual() []*Node {
+	s.mu.RLock()
+	nodes := make([]*Node, 0, len(s.manualNodes))
+	for _, n := range s.manualNodes {
+		nodes = append(nodes, maskedNode(n))
+	}
+	s.mu.RUnlock()
+
+	for _, n := range nodes {
+		n.Delay = s.GetDelay(n.ID)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// RevealManual 获取手动节点的原始配置（含未脱敏的凭据和分享链接）
+func (s *Service) RevealManual(id string) (*Node, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node, ok := s.manualNodes[id]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	copied := *node
+	return &copied, nil
+}
+
+// CreateManual 创建手动节点
+func (s *Service) CreateManual(in ManualNodeInput) (*Node, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	config, err := in.parseConfig()
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.AddManualAdvanced(strings.TrimSpace(in.Name), in.Type, strings.TrimSpace(in.Server), in.ServerPort, config)
+	if err != nil {
+		return nil, err
+	}
+	if in.Enabled != nil && !*in.Enabled {
+		return s.SetManualEnabled(node.ID, false)
+	}
+	return node, nil
+}
+
+// UpdateManual 编辑手动节点，配置中仍为 "******" 的凭据保持原值
+func (s *Service) UpdateManual(id string, in ManualNodeInput) (*Node, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	config, err := in.parseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	node, ok := s.manualNodes[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrNodeNotFound
+	}
+	var previous interface{}
+	json.Unmarshal([]byte(node.Config), &previous)
+	configJSON, err := json.Marshal(restoreSecrets(config, previous))
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("配置序列化失败: %w", err)
+	}
+
+	updated := *node
+	updated.Name = strings.TrimSpace(in.Name)
+	updated.Type = in.Type
+	updated.Server = strings.TrimSpace(in.Server)
+	updated.ServerPort = in.ServerPort
+	updated.Config = string(configJSON)
+	if in.Enabled != nil {
+		updated.Enabled = *in.Enabled
+	}
+	// 分享链接与编辑后的配置不再一致
+	if updated.Config != node.Config || updated.Server != node.Server || updated.ServerPort != node.ServerPort || updated.Type != node.Type {
+		updated.ShareURL = ""
+	}
+	s.manualNodes[id] = &updated
+	s.mu.Unlock()
+
+	return maskedNode(&updated), s.saveManualNodes()
+}
+
+// SetManualEnabled 启用或停用手动节点，停用的节点不参与生成配置
+func (s *Service) SetManualEnabled(id string, enabled bool) (*Node, error) {
+	s.mu.Lock()
+	node, ok := s.manualNodes[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrNodeNotFound
+	}
+	node.Enabled = enabled
+	result := maskedNode(node)
+	s.mu.Unlock()
+
+	return result, s.saveManualNodes()
+}
+
+// RemoveManual 删除手动节点，节点不存在时返回 ErrNodeNotFound
+func (s *Service) RemoveManual(id string) error {
+	s.mu.RLock()
+	_, ok := s.manualNodes[id]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNodeNotFound
+	}
+	return s.DeleteManual(id)
+}
+
+// manualNodeStatus 节点不存在时返回 404
+func manualNodeStatus(err error) int {
+	if errors.Is(err, ErrNodeNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// ListManual 获取手动节点列表（凭据已脱敏）
+func (h *Handler) ListManual(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.ListManual(),
+	})
+}
+
+// RevealManual 显式获取手动节点的原始凭据
+func (h *Handler) RevealManual(c *gin.Context) {
+	node, err := h.service.RevealManual(c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, manualNodeStatus(err), err)
+		return
+	}
+	node.Delay = h.service.GetDelay(node.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    node,
+	})
+}
+
+// CreateManual 创建手动节点
+func (h *Handler) CreateManual(c *gin.Context) {
+	var req ManualNodeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	node, err := h.service.CreateManual(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    maskedNode(node),
+	})
+}
+
+// UpdateManual 编辑手动节点
+func (h *Handler) UpdateManual(c *gin.Context) {
+	var req ManualNodeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	node, err := h.service.UpdateManual(c.Param("id"), req)
+	if err != nil {
+		apierror.Respond(c, manualNodeStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    node,
+	})
+}
+
+// SetManualEnabled 启用或停用手动节点
+func (h *Handler) SetManualEnabled(c *gin.Context) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	node, err := h.service.SetManualEnabled(c.Param("id"), req.Enabled)
+	if err != nil {
+		apierror.Respond(c, manualNodeStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    node,
+	})
+}
+
+// RemoveManual 删除手动节点
+func (h *Handler) RemoveManual(c *gin.Context) {
+	if err := h.service.RemoveManual(c.Param("id")); err != nil {
+		apierror.Respond(c, manualNodeStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"ProxyStation/backend/modules/subscription"
+	"ProxyStation/backend/secure"
 
 	"github.com/google/uuid"
 )
@@ -35,6 +36,9 @@ type Service struct {
 	delayCache  map[string]int // 节点延迟缓存
 	subService  *subscription.Service
 	mu          sync.RWMutex
+	health      healthChecker // 节点健康检查
+	speed       speedCache    // 下载测速结果
+	geo         geoCache      // 服务器归属信息
 }
 
 func NewService(dataDir string, subService *subscription.Service) *Service {
@@ -46,6 +50,8 @@ func NewService(dataDir string, subService *subscription.Service) *Service {
 	}
 	s.loadManualNodes()
 	s.loadDelayCache()
+	s.loadHealth()
+	s.loadSpeedCache()
 	return s
 }
 
@@ -99,7 +105,7 @@ func (s *Service) GetDelay(nodeID string) int {
 
 func (s *Service) loadManualNodes() {
 	filePath := filepath.Join(s.dataDir, "manual_nodes.json")
-	data, err := os.ReadFile(filePath)
+	data, err := secure.ReadFile(filePath)
 	if err != nil {
 		return
 	}
@@ -125,7 +131,7 @@ func (s *Service) saveManualNodes() error {
 		return err
 	}
 	filePath := filepath.Join(s.dataDir, "manual_nodes.json")
-	return os.WriteFile(filePath, data, 0644)
+	return secure.WriteFile(filePath, data, 0644)
 }
 
 // ListAll 获取所有节点（订阅+手动）
@@ -173,27 +179,6 @@ func (s *Service) ListAll() []*Node {
 	return nodes
 }
 
-// AddManual 手动添加节点
-func (s *Service) AddManual(name, nodeType, server string, port int, config string) (*Node, error) {
-	node := &Node{
-		ID:         uuid.New().String(),
-		Name:       name,
-		Type:       nodeType,
-		Server:     server,
-		ServerPort: port,
-		IsManual:   true,
-		Enabled:    true,
-		Delay:      -1,
-		Config:     config,
-	}
-
-	s.mu.Lock()
-	s.manualNodes[node.ID] = node
-	s.mu.Unlock()
-
-	return node, s.saveManualNodes()
-}
-
 // AddManualAdvanced 高级手动添加节点（支持完整配置）
 func (s *Service) AddManualAdvanced(name, nodeType, server string, port int, config map[string]interface{}) (*Node, error) {
 	// 将 config map 转换为 JSON 字符串
@@ -262,6 +247,51 @@ func (s *Service) ImportURL(url string) (*Node, error) {
 	return node, s.saveManualNodes()
 }
 
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Total    int                           `json:"total"`    // 有效链接总数
+	Imported []*Node                       `json:"imported"` // 导入成功的节点
+	Errors   []subscription.LinkParseError `json:"errors"`   // 解析失败的链接
+}
+
+// ImportLinks 批量导入分享链接（多行链接或 Base64 订阅内容）
+func (s *Service) ImportLinks(content string) (*ImportResult, error) {
+	proxyNodes, parseErrs := subscription.ParseShareLinks(content)
+
+	result := &ImportResult{
+		Total:    len(proxyNodes) + len(parseErrs),
+		Imported: make([]*Node, 0, len(proxyNodes)),
+		Errors:   parseErrs,
+	}
+	if result.Errors == nil {
+		result.Errors = []subscription.LinkParseError{}
+	}
+	if len(proxyNodes) == 0 {
+		return result, nil
+	}
+
+	s.mu.Lock()
+	for _, pn := range proxyNodes {
+		node := &Node{
+			ID:         uuid.New().String(),
+			Name:       pn.Name,
+			Type:       pn.Type,
+			Server:     pn.Server,
+			ServerPort: pn.ServerPort,
+			IsManual:   true,
+			Enabled:    true,
+			Delay:      -1,
+			Config:     pn.Config,
+			ShareURL:   pn.ShareURL,
+		}
+		s.manualNodes[node.ID] = node
+		result.Imported = append(result.Imported, node)
+	}
+	s.mu.Unlock()
+
+	return result, s.saveManualNodes()
+}
+
 // DeleteManual 删除手动节点
 func (s *Service) DeleteManual(id string) error {
 	s.mu.Lock()
@@ -0,0 +1,186 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SpeedResult 节点下载测速结果
+type SpeedResult struct {
+	NodeID        string  `json:"nodeId"`
+	Name          string  `json:"name"`
+	DownloadSpeed float64 `json:"downloadSpeed"` // Mbps
+	Bytes         int64   `json:"bytes"`
+	Duration      float64 `json:"duration"` // 秒
+	TTFB          int64   `json:"ttfb"`     // 首字节时间 ms
+	URL           string  `json:"url,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	TestedAt      int64   `json:"testedAt"`
+}
+
+// SpeedTester 通过核心对指定节点进行下载测速（由 proxy 模块提供）
+type SpeedTester func(ctx context.Context, nodeName, testURL string, duration time.Duration) (*SpeedResult, error)
+
+// speedCache 测速结果缓存
+type speedCache struct {
+	mu      sync.RWMutex
+	results map[string]*SpeedResult
+	tester  SpeedTester
+}
+
+func (s *Service) speedCacheFile() string {
+	return filepath.Join(s.dataDir, "speed_cache.json")
+}
+
+func (s *Service) loadSpeedCache() {
+	s.speed.results = make(map[string]*SpeedResult)
+	if data, err := os.ReadFile(s.speedCacheFile()); err == nil {
+		json.Unmarshal(data, &s.speed.results)
+	}
+}
+
+func (s *Service) saveSpeedCache() error {
+	s.speed.mu.RLock()
+	data, err := json.MarshalIndent(s.speed.results, "", "  ")
+	s.speed.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.speedCacheFile(), data, 0644)
+}
+
+// SetSpeedTester 设置测速实现
+func (s *Service) SetSpeedTester(tester SpeedTester) {
+	s.speed.mu.Lock()
+	defer s.speed.mu.Unlock()
+	s.speed.tester = tester
+}
+
+// GetSpeedResults 获取缓存的测速结果
+func (s *Service) GetSpeedResults() map[string]*SpeedResult {
+	s.speed.mu.RLock()
+	defer s.speed.mu.RUnlock()
+	result := make(map[string]*SpeedResult, len(s.speed.results))
+	for id, r := range s.speed.results {
+		result[id] = r
+	}
+	return result
+}
+
+// SpeedTest 对单个节点测速并缓存结果（失败结果也会缓存，便于识别慢节点；调用方取消时不缓存）
+func (s *Service) SpeedTest(ctx context.Context, nodeID, testURL string, duration time.Duration) (*SpeedResult, error) {
+	s.speed.mu.RLock()
+	tester := s.speed.tester
+	s.speed.mu.RUnlock()
+	if tester == nil {
+		return nil, fmt.Errorf("测速功能未初始化")
+	}
+
+	var target *Node
+	for _, n := range s.ListAll() {
+		if n.ID == nodeID {
+			target = n
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("节点不存在: %s", nodeID)
+	}
+
+	result, err := tester(ctx, target.Name, testURL, duration)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if result == nil {
+		result = &SpeedResult{}
+	}
+	result.NodeID = target.ID
+	result.Name = target.Name
+	result.URL = testURL
+	result.TestedAt = time.Now().Unix()
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.speed.mu.Lock()
+	s.speed.results[target.ID] = result
+	s.speed.mu.Unlock()
+	s.saveSpeedCache()
+
+	return result, err
+}
+
+// SpeedTest 测试单个节点下载速度
+func (h *Handler) SpeedTest(c *gin.Context) {
+	var req struct {
+		URL      string `json:"url"`
+		Duration int    `json:"duration"` // 秒
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	result, err := h.service.SpeedTest(c.Request.Context(), c.Param("id"), req.URL, time.Duration(req.Duration)*time.Second)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "测速失败: "+err.Error()).WithDetails(result))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// SpeedTestBatch 批量测速（节点依次测试，避免相互抢占带宽）
+func (h *Handler) SpeedTestBatch(c *gin.Context) {
+	var req struct {
+		NodeIDs  []string `json:"nodeIds" binding:"required"`
+		URL      string   `json:"url"`
+		Duration int      `json:"duration"` // 秒
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	results := make([]*SpeedResult, 0, len(req.NodeIDs))
+	for _, id := range req.NodeIDs {
+		if c.Request.Context().Err() != nil {
+			break
+		}
+		result, _ := h.service.SpeedTest(c.Request.Context(), id, req.URL, time.Duration(req.Duration)*time.Second)
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    results,
+	})
+}
+
+// GetSpeedResults 获取缓存的测速结果
+func (h *Handler) GetSpeedResults(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetSpeedResults(),
+	})
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 通知 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/events", h.ListEvents)
+	r.GET("/deliveries", h.ListDeliveries)
+	r.GET("/webhooks", h.List)
+	r.POST("/webhooks", h.Add)
+	r.PUT("/webhooks/:id", h.Update)
+	r.DELETE("/webhooks/:id", h.Delete)
+	r.POST("/webhooks/:id/test", h.Test)
+}
+
+// ListEvents 获取支持通知的事件
+func (h *Handler) ListEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    SupportedEvents,
+	})
+}
+
+// ListDeliveries 获取最近的发送记录
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.Deliveries(),
+	})
+}
+
+// List 获取所有通知渠道
+func (h *Handler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.List(),
+	})
+}
+
+// Add 添加通知渠道
+func (h *Handler) Add(c *gin.Context) {
+	req := Webhook{Enabled: true, Retries: defaultRetries}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	webhook, err := h.service.Add(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    webhook,
+	})
+}
+
+// Update 更新通知渠道
+func (h *Handler) Update(c *gin.Context) {
+	var req Webhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	webhook, err := h.service.Update(c.Param("id"), req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    webhook,
+	})
+}
+
+// Delete 删除通知渠道
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Test 发送测试通知
+func (h *Handler) Test(c *gin.Context) {
+	if err := h.service.Test(c.Param("id")); err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "发送失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "测试通知已发送",
+	})
+}
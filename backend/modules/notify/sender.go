@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/events"
+)
+
+// message 通知内容
+type message struct {
+	Event string      `json:"event"`
+	Title string      `json:"title"`
+	Text  string      `json:"message"`
+	Time  string      `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// formatMessage 根据事件生成通知标题和正文
+func formatMessage(e events.Event) message {
+	msg := message{Event: e.Type, Time: e.Time, Data: e.Data}
+
+	// 事件数据来自不同模块，统一转换为 map 读取字段
+	var data map[string]interface{}
+	if raw, err := json.Marshal(e.Data); err == nil {
+		json.Unmarshal(raw, &data)
+	}
+	str := func(key string) string {
+		if v, ok := data[key]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+
+	switch e.Type {
+	case events.CoreCrashed:
+		msg.Title = "核心异常退出"
+		msg.Text = fmt.Sprintf("%s 异常退出 (exit=%s)，已运行 %s 秒", str("coreType"), str("exitCode"), str("uptime"))
+		if errMsg := str("error"); errMsg != "" {
+			msg.Text += "\n错误: " + errMsg
+		}
+		if data["restart"] == true {
+			msg.Text += fmt.Sprintf("\n将在 %s 毫秒后自动重启（第 %s 次）", str("delay"), str("attempt"))
+		} else {
+			msg.Text += "\n不会自动重启"
+		}
+	case events.CoreStarted:
+		msg.Title = "核心已启动"
+		msg.Text = fmt.Sprintf("%s 已启动", str("coreType"))
+	case events.CoreStopped:
+		msg.Title = "核心已停止"
+		msg.Text = fmt.Sprintf("%s 已停止", str("coreType"))
+	case events.SubscriptionFailed:
+		msg.Title = "订阅更新失败"
+		msg.Text = fmt.Sprintf("订阅「%s」更新失败: %s", str("name"), str("error"))
+	case events.NodesAllDead:
+		msg.Title = "所有节点均不可用"
+		msg.Text = fmt.Sprintf("健康检查发现 %s 个节点全部不可用，请检查网络或订阅", str("total"))
+	case events.NodeHealthChanged:
+		msg.Title = "节点可用状态变化"
+		var lines []string
+		if changed, ok := data["changed"].([]interface{}); ok {
+			for _, item := range changed {
+				n, _ := item.(map[string]interface{})
+				state := "不可用"
+				if n["healthy"] == true {
+					state = "恢复"
+				}
+				lines = append(lines, fmt.Sprintf("%v: %s", n["name"], state))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("可用节点 %s/%s", str("healthy"), str("total")))
+		msg.Text = strings.Join(lines, "\n")
+	case events.ConfigGenerated:
+		msg.Title = "配置已重新生成"
+		msg.Text = fmt.Sprintf("%s 配置已生成（%s）", str("coreType"), str("reason"))
+	case events.TransparentChanged:
+		msg.Title = "透明代理模式已变更"
+		msg.Text = fmt.Sprintf("模式: %s，作用域: %s", str("mode"), str("scope"))
+	case events.TransparentRevert:
+		msg.Title = "透明代理已自动关闭"
+		msg.Text = fmt.Sprintf("%s 模式下网络持续不可达，已切换为 off\n原因: %s", str("mode"), str("reason"))
+	default:
+		msg.Title = e.Type
+		msg.Text = e.Type
+	}
+	return msg
+}
+
+// sender 按渠道类型发送通知
+type sender struct {
+	client *http.Client
+}
+
+func newSender() *sender {
+	return &sender{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// send 发送一次通知，非 2xx 响应视为失败
+func (s *sender) send(w Webhook, msg message) error {
+	req, err := buildRequest(w, msg)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ProxyStation/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Telegram 的 Bot Token 在 URL 中，避免出现在错误信息和日志里
+		if w.Token != "" {
+			return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), w.Token, "******"))
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// buildRequest 按渠道类型构造请求
+func buildRequest(w Webhook, msg message) (*http.Request, error) {
+	switch w.Type {
+	case TypeTelegram:
+		base := strings.TrimRight(w.URL, "/")
+		if base == "" {
+			base = "https://api.telegram.org"
+		}
+		return jsonRequest(base+"/bot"+w.Token+"/sendMessage", map[string]interface{}{
+			"chat_id": w.ChatID,
+			"text":    msg.Title + "\n" + msg.Text,
+		})
+
+	case TypeBark:
+		// https://api.day.app/<device_key>
+		return jsonRequest(w.URL, map[string]interface{}{
+			"title": msg.Title,
+			"body":  msg.Text,
+			"group": "ProxyStation",
+		})
+
+	case TypeNtfy:
+		// https://ntfy.sh/<topic>，标题和标签通过请求头传递
+		req, err := http.NewRequest(http.MethodPost, w.URL, strings.NewReader(msg.Text))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Title", mime.BEncoding.Encode("UTF-8", msg.Title))
+		req.Header.Set("Tags", "proxystation")
+		if w.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+w.Token)
+		}
+		return req, nil
+
+	default:
+		req, err := jsonRequest(w.URL, msg)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range w.Headers {
+			req.Header.Set(key, value)
+		}
+		return req, nil
+	}
+}
+
+// jsonRequest 构造 JSON POST 请求
+func jsonRequest(url string, payload interface{}) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
@@ -0,0 +1,359 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/events"
+	"ProxyStation/backend/secure"
+
+	"github.com/google/uuid"
+)
+
+// 通知渠道类型
+const (
+	TypeGeneric  = "generic"  // 通用 JSON Webhook
+	TypeTelegram = "telegram" // Telegram Bot
+	TypeBark     = "bark"     // Bark (iOS)
+	TypeNtfy     = "ntfy"     // ntfy
+)
+
+const (
+	// maxDeliveries 内存中保留的最近发送记录数
+	maxDeliveries = 200
+	// defaultRetries 默认失败重试次数
+	defaultRetries = 3
+)
+
+// EventInfo 可订阅的事件
+type EventInfo struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"` // 新建通知时默认开启
+}
+
+// SupportedEvents 支持通知的事件
+var SupportedEvents = []EventInfo{
+	{Type: events.CoreCrashed, Description: "核心异常退出", Default: true},
+	{Type: events.SubscriptionFailed, Description: "订阅更新失败", Default: true},
+	{Type: events.NodesAllDead, Description: "所有节点均不可用", Default: true},
+	{Type: events.TransparentRevert, Description: "透明代理断网自动关闭", Default: true},
+	{Type: events.CoreStarted, Description: "核心启动"},
+	{Type: events.CoreStopped, Description: "核心停止"},
+	{Type: events.NodeHealthChanged, Description: "节点可用状态变化"},
+	{Type: events.ConfigGenerated, Description: "配置重新生成"},
+	{Type: events.TransparentChanged, Description: "透明代理模式变化"},
+}
+
+// Webhook 通知渠道
+type Webhook struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Type    string            `json:"type"` // generic, telegram, bark, ntfy
+	Enabled bool              `json:"enabled"`
+	URL     string            `json:"url"`               // generic: 接收地址；bark: 含设备 key 的推送地址；ntfy: 含主题的地址；telegram: 可选的 API 地址
+	Token   string            `json:"token,omitempty"`   // telegram: Bot Token；ntfy: 访问令牌（可选）
+	ChatID  string            `json:"chatId,omitempty"`  // telegram: 会话 ID
+	Headers map[string]string `json:"headers,omitempty"` // generic: 自定义请求头
+	Events  map[string]bool   `json:"events"`            // 按事件开关
+	Retries int               `json:"retries"`           // 失败重试次数
+	// 最近一次发送结果
+	LastStatus string `json:"lastStatus,omitempty"` // success, failed
+	LastError  string `json:"lastError,omitempty"`
+	LastSent   int64  `json:"lastSent,omitempty"`
+}
+
+// Delivery 发送记录
+type Delivery struct {
+	Time      string `json:"time"`
+	WebhookID string `json:"webhookId"`
+	Name      string `json:"name"`
+	Event     string `json:"event"`
+	Success   bool   `json:"success"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Service 通知服务
+type Service struct {
+	dataDir    string
+	mu         sync.RWMutex
+	webhooks   []*Webhook
+	deliveries []Delivery
+	sender     *sender
+}
+
+// NewService 创建通知服务
+func NewService(dataDir string) *Service {
+	s := &Service{
+		dataDir: dataDir,
+		sender:  newSender(),
+	}
+	s.load()
+	return s
+}
+
+func (s *Service) configPath() string {
+	return filepath.Join(s.dataDir, "notify.json")
+}
+
+func (s *Service) load() {
+	data, err := secure.ReadFile(s.configPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.webhooks); err != nil {
+		fmt.Printf("⚠️ 解析通知配置失败: %v\n", err)
+	}
+}
+
+// save 保存通知配置（包含令牌，仅所有者可读，调用时需持有 s.mu 锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.webhooks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return secure.WriteFile(s.configPath(), data, 0600)
+}
+
+// List 获取所有通知渠道
+func (s *Service) List() []Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Webhook, 0, len(s.webhooks))
+	for _, w := range s.webhooks {
+		result = append(result, *w)
+	}
+	return result
+}
+
+// Add 添加通知渠道，未指定事件时开启默认事件
+func (s *Service) Add(w Webhook) (*Webhook, error) {
+	if w.Events == nil {
+		w.Events = make(map[string]bool)
+		for _, e := range SupportedEvents {
+			if e.Default {
+				w.Events[e.Type] = true
+			}
+		}
+	}
+	if err := normalize(&w); err != nil {
+		return nil, err
+	}
+	w.ID = uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = append(s.webhooks, &w)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Update 更新通知渠道
+func (s *Service) Update(id string, w Webhook) (*Webhook, error) {
+	if err := normalize(&w); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.webhooks {
+		if existing.ID != id {
+			continue
+		}
+		w.ID = id
+		w.LastStatus, w.LastError, w.LastSent = existing.LastStatus, existing.LastError, existing.LastSent
+		s.webhooks[i] = &w
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+		return &w, nil
+	}
+	return nil, fmt.Errorf("通知渠道不存在")
+}
+
+// Delete 删除通知渠道
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range s.webhooks {
+		if w.ID == id {
+			s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("通知渠道不存在")
+}
+
+// normalize 校验并补全通知渠道配置
+func normalize(w *Webhook) error {
+	w.Name = strings.TrimSpace(w.Name)
+	w.URL = strings.TrimSpace(w.URL)
+	if w.Type == "" {
+		w.Type = TypeGeneric
+	}
+
+	switch w.Type {
+	case TypeGeneric, TypeBark, TypeNtfy:
+		if !strings.HasPrefix(w.URL, "http://") && !strings.HasPrefix(w.URL, "https://") {
+			return fmt.Errorf("URL 必须以 http:// 或 https:// 开头")
+		}
+	case TypeTelegram:
+		if w.Token == "" || w.ChatID == "" {
+			return fmt.Errorf("Telegram 需要填写 Bot Token 和 Chat ID")
+		}
+		if w.URL != "" && !strings.HasPrefix(w.URL, "http://") && !strings.HasPrefix(w.URL, "https://") {
+			return fmt.Errorf("URL 必须以 http:// 或 https:// 开头")
+		}
+	default:
+		return fmt.Errorf("不支持的通知类型: %s", w.Type)
+	}
+
+	if w.Name == "" {
+		w.Name = w.Type
+	}
+	if w.Retries < 0 || w.Retries > 10 {
+		return fmt.Errorf("重试次数需在 0-10 之间")
+	}
+	if w.Events == nil {
+		w.Events = make(map[string]bool)
+	}
+	for eventType := range w.Events {
+		if !isSupported(eventType) {
+			return fmt.Errorf("不支持的事件: %s", eventType)
+		}
+	}
+	return nil
+}
+
+func isSupported(eventType string) bool {
+	for _, e := range SupportedEvents {
+		if e.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Listen 订阅事件总线，将事件发送到开启了该事件的通知渠道
+func (s *Service) Listen(bus *events.Bus) {
+	_, ch, _ := bus.Subscribe(0)
+	go func() {
+		for e := range ch {
+			s.Notify(e)
+		}
+	}()
+}
+
+// Notify 异步发送事件通知
+func (s *Service) Notify(e events.Event) {
+	s.mu.RLock()
+	var targets []Webhook
+	for _, w := range s.webhooks {
+		if w.Enabled && w.Events[e.Type] {
+			targets = append(targets, *w)
+		}
+	}
+	s.mu.RUnlock()
+
+	msg := formatMessage(e)
+	for _, w := range targets {
+		go s.deliver(w, msg, w.Retries)
+	}
+}
+
+// Test 发送测试通知（不重试），同步返回结果
+func (s *Service) Test(id string) error {
+	s.mu.RLock()
+	var target *Webhook
+	for _, w := range s.webhooks {
+		if w.ID == id {
+			copied := *w
+			target = &copied
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if target == nil {
+		return fmt.Errorf("通知渠道不存在")
+	}
+
+	msg := message{
+		Event: "test",
+		Title: "ProxyStation 测试通知",
+		Text:  "如果收到这条消息，说明通知渠道配置正确",
+		Time:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	return s.deliver(*target, msg, 0)
+}
+
+// deliver 发送通知，失败时按指数退避重试，并记录结果
+func (s *Service) deliver(w Webhook, msg message, retries int) error {
+	var err error
+	attempts := 0
+	for attempts <= retries {
+		if attempts > 0 {
+			time.Sleep(time.Duration(1<<uint(attempts)) * time.Second)
+		}
+		attempts++
+		if err = s.sender.send(w, msg); err == nil {
+			break
+		}
+	}
+
+	d := Delivery{
+		Time:      time.Now().Format("2006-01-02 15:04:05"),
+		WebhookID: w.ID,
+		Name:      w.Name,
+		Event:     msg.Event,
+		Success:   err == nil,
+		Attempts:  attempts,
+	}
+	if err != nil {
+		d.Error = err.Error()
+		fmt.Printf("⚠️ 发送通知失败 [%s] %s: %v\n", w.Name, msg.Event, err)
+	}
+	s.record(w.ID, d)
+	return err
+}
+
+// record 保存发送记录和渠道的最近状态
+func (s *Service) record(id string, d Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries = append(s.deliveries, d)
+	if len(s.deliveries) > maxDeliveries {
+		s.deliveries = s.deliveries[len(s.deliveries)-maxDeliveries:]
+	}
+	for _, w := range s.webhooks {
+		if w.ID == id {
+			w.LastSent = time.Now().Unix()
+			w.LastError = d.Error
+			if d.Success {
+				w.LastStatus = "success"
+			} else {
+				w.LastStatus = "failed"
+			}
+			s.save()
+			break
+		}
+	}
+}
+
+// Deliveries 获取最近的发送记录，最新的在前
+func (s *Service) Deliveries() []Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Delivery, 0, len(s.deliveries))
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		result = append(result, s.deliveries[i])
+	}
+	return result
+}
@@ -0,0 +1,329 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coreProcessNames 识别外部核心进程使用的进程名
+var coreProcessNames = map[string]string{
+	"mihomo":     "mihomo",
+	"clash":      "mihomo",
+	"clash-meta": "mihomo",
+	"sing-box":   "singbox",
+}
+
+// ExternalCore 检测到的非 ProxyStation 启动的核心
+type ExternalCore struct {
+	CoreType   string     `json:"coreType,omitempty"`
+	Version    string     `json:"version,omitempty"`
+	Controller string     `json:"controller,omitempty"` // 可访问的控制器地址
+	Reachable  bool       `json:"reachable"`            // 控制器可访问
+	AuthFailed bool       `json:"authFailed,omitempty"` // 控制器密钥不正确
+	Process    *PortOwner `json:"process,omitempty"`
+	Adoptable  bool       `json:"adoptable"`
+	Reason     string     `json:"reason,omitempty"` // 不可接管的原因
+}
+
+// adoptedCore 已接管的外部核心（由 mu 保护）
+type adoptedCore struct {
+	pid        int
+	coreType   string
+	controller string
+	owned      bool // 本实例启动、后端重启后通过 PID 文件重新关联的核心
+	stop       chan struct{}
+}
+
+// probeController 请求控制器 /version 判断核心类型
+func probeController(controller, secret string) (version string, reachable, authFailed bool) {
+	if controller == "" {
+		return "", false, false
+	}
+	host := controller
+	if strings.HasPrefix(host, ":") {
+		host = "127.0.0.1" + host
+	} else if h, p, err := net.SplitHostPort(host); err == nil && (h == "0.0.0.0" || h == "::") {
+		host = net.JoinHostPort("127.0.0.1", p)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+"/version", nil)
+	if err != nil {
+		return "", false, false
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", true, true
+	}
+	var result struct {
+		Version string `json:"version"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(body, &result) != nil {
+		return "", false, false
+	}
+	return result.Version, true, false
+}
+
+// DetectExternalCore 检测外部启动的核心：先探测配置的控制器地址，再扫描进程
+// ProxyStation 自己启动的核心或已接管时返回 nil
+func (s *Service) DetectExternalCore() *ExternalCore {
+	s.mu.RLock()
+	managed := s.process != nil || s.adopted != nil
+	controller := s.config.ExternalController
+	secret := s.config.Secret
+	s.mu.RUnlock()
+	if managed {
+		return nil
+	}
+
+	ext := &ExternalCore{}
+	version, reachable, authFailed := probeController(controller, secret)
+	if reachable {
+		ext.Controller = controller
+		ext.Reachable = true
+		ext.AuthFailed = authFailed
+		ext.Version = version
+		ext.CoreType = "mihomo"
+		if strings.Contains(strings.ToLower(version), "sing-box") {
+			ext.CoreType = "singbox"
+		}
+		if _, portStr, err := net.SplitHostPort(controller); err == nil {
+			if port, err := strconv.Atoi(portStr); err == nil {
+				ext.Process = findPortOwner("tcp", port)
+			}
+		}
+	}
+
+	// 控制器不可访问时按进程名查找
+	if ext.Process == nil {
+		for _, p := range findCoreProcesses() {
+			if p.PID == 0 {
+				continue
+			}
+			ext.Process = p
+			if ext.CoreType == "" {
+				ext.CoreType = coreProcessNames[p.Name]
+			}
+			break
+		}
+	}
+
+	if !ext.Reachable && ext.Process == nil {
+		return nil
+	}
+
+	switch {
+	case ext.AuthFailed:
+		ext.Reason = "控制器密钥不正确，请提供正确的 secret"
+	case !ext.Reachable:
+		ext.Reason = fmt.Sprintf("控制器 %s 无法访问，请提供外部核心的控制器地址", controller)
+	default:
+		ext.Adoptable = true
+	}
+	return ext
+}
+
+// AdoptExternalCore 接管外部核心：不启动新进程，通过控制器 API 管理
+// controller/secret 非空时更新配置，使其他模块使用相同的控制器地址
+// 接管后不修改系统代理和透明代理规则，外部核心退出时自动解除接管
+func (s *Service) AdoptExternalCore(controller, secret string) (*ExternalCore, error) {
+	if controller != "" || secret != "" {
+		s.mu.Lock()
+		if controller != "" {
+			s.config.ExternalController = controller
+		}
+		if secret != "" {
+			s.config.Secret = secret
+		}
+		err := s.saveConfig()
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ext := s.DetectExternalCore()
+	if ext == nil {
+		if s.GetStatus().Running {
+			return nil, apierror.New(apierror.CodeCoreRunning, "核心已在运行，无需接管")
+		}
+		return nil, fmt.Errorf("未检测到外部核心")
+	}
+	if !ext.Adoptable {
+		return ext, fmt.Errorf("无法接管: %s", ext.Reason)
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrCoreRunning
+	}
+	adopted := &adoptedCore{
+		coreType:   ext.CoreType,
+		controller: ext.Controller,
+		stop:       make(chan struct{}),
+	}
+	if ext.Process != nil {
+		adopted.pid = ext.Process.PID
+	}
+	s.adopted = adopted
+	s.running = true
+	s.startTime = time.Now()
+	s.coreVersion = parseCoreVersion(ext.Version)
+	if ext.CoreType != "" {
+		s.coreType = ext.CoreType
+	}
+	s.mu.Unlock()
+
+	s.addLog(fmt.Sprintf("[INFO] 已接管外部核心 %s (PID %d, 控制器 %s)", ext.Version, adopted.pid, ext.Controller))
+	fmt.Printf("🤝 已接管外部核心 %s (PID %d)\n", ext.Version, adopted.pid)
+	go s.watchAdopted(adopted)
+	s.publish(events.CoreStarted, s.GetStatus())
+	return ext, nil
+}
+
+// watchAdopted 定期检查外部核心是否仍在运行，退出后解除接管
+func (s *Service) watchAdopted(adopted *adoptedCore) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-adopted.stop:
+			return
+		case <-ticker.C:
+		}
+
+		alive := false
+		if adopted.pid > 0 {
+			alive = processAlive(adopted.pid)
+		} else {
+			s.mu.RLock()
+			secret := s.config.Secret
+			s.mu.RUnlock()
+			_, alive, _ = probeController(adopted.controller, secret)
+		}
+		if alive {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.adopted != adopted {
+			s.mu.Unlock()
+			return
+		}
+		s.adopted = nil
+		s.running = false
+		uptime := time.Since(s.startTime)
+		s.mu.Unlock()
+
+		if adopted.owned {
+			s.removePidFile(adopted.pid)
+			s.handleCrash(CrashEvent{
+				Time:     time.Now(),
+				CoreType: adopted.coreType,
+				ExitCode: -1,
+				Error:    "核心进程已退出",
+				Uptime:   int64(uptime.Seconds()),
+			}, uptime)
+			return
+		}
+
+		s.addLog("[WARN] 接管的外部核心已退出")
+		fmt.Println("⚠️ 接管的外部核心已退出")
+		s.publish(events.CoreStopped, s.GetStatus())
+		return
+	}
+}
+
+// releaseAdopted 解除接管（不结束外部进程），未接管时返回 false
+func (s *Service) releaseAdopted() bool {
+	s.mu.Lock()
+	adopted := s.adopted
+	if adopted == nil || adopted.owned {
+		s.mu.Unlock()
+		return false
+	}
+	s.adopted = nil
+	s.running = false
+	s.mu.Unlock()
+
+	close(adopted.stop)
+	s.addLog("[INFO] 已解除对外部核心的接管")
+	s.publish(events.CoreStopped, s.GetStatus())
+	return true
+}
+
+// IsAdopted 当前核心是否为接管的外部核心
+func (s *Service) IsAdopted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.adopted != nil && !s.adopted.owned
+}
+
+// GetExternalCore 检测外部启动的核心
+func (h *Handler) GetExternalCore(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.DetectExternalCore(),
+	})
+}
+
+// AdoptExternalCore 接管外部核心
+func (h *Handler) AdoptExternalCore(c *gin.Context) {
+	var req struct {
+		Controller string `json:"controller"`
+		Secret     string `json:"secret"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	ext, err := h.service.AdoptExternalCore(req.Controller, req.Secret)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, err.Error()).WithDetails(ext))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    ext,
+	})
+}
+
+// ReleaseExternalCore 解除接管，外部核心继续运行
+func (h *Handler) ReleaseExternalCore(c *gin.Context) {
+	if !h.service.releaseAdopted() {
+		apierror.Message(c, http.StatusBadRequest, "当前没有接管外部核心")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiSecretBytes 自动生成的核心 API 密钥长度（字节）
+const apiSecretBytes = 24
+
+// generateAPISecret 生成随机的核心 API 密钥
+func generateAPISecret() (string, error) {
+	b := make([]byte, apiSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ensureAPISecret 未设置 secret 时自动生成并保存，避免核心控制器无认证暴露
+// 已生成的核心配置中有 secret 时沿用，保证正在运行的核心仍能访问
+func (s *Service) ensureAPISecret() error {
+	secret := s.GetAPISecret()
+	if secret == "" {
+		var err error
+		if secret, err = generateAPISecret(); err != nil {
+			return fmt.Errorf("生成核心 API 密钥失败: %w", err)
+		}
+		fmt.Println("🔑 已自动生成核心 API 密钥")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.Secret != "" {
+		return nil
+	}
+	s.config.Secret = secret
+	return s.saveConfig()
+}
+
+// RotateAPISecret 重新生成核心 API 密钥，运行中的核心需要重启才能使用新密钥
+func (s *Service) RotateAPISecret() (string, error) {
+	secret, err := generateAPISecret()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Secret = secret
+	return secret, s.saveConfig()
+}
+
+// GetAPISecretInfo 获取核心 API 密钥（只读令牌无权访问）
+func (h *Handler) GetAPISecretInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"secret":     h.service.GetAPISecret(),
+			"controller": h.service.GetConfig().ExternalController,
+		},
+	})
+}
+
+// RotateAPISecret 重新生成核心 API 密钥，核心运行中时重启使其生效
+func (h *Handler) RotateAPISecret(c *gin.Context) {
+	secret, err := h.service.RotateAPISecret()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	restarted := false
+	if h.service.GetStatus().Running {
+		if err := h.service.Restart(); err != nil {
+			apierror.Message(c, http.StatusInternalServerError, "密钥已更新，但重启核心失败: "+err.Error())
+			return
+		}
+		restarted = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"secret":    secret,
+			"restarted": restarted,
+		},
+	})
+}
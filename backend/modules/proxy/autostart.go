@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	"ProxyStation/backend/events"
+)
+
+// 开机自动启动的各个阶段
+const (
+	autoStartStepNetwork     = "network"     // 等待网络就绪
+	autoStartStepConfig      = "config"      // 检查并重新生成配置
+	autoStartStepCore        = "core"        // 启动核心
+	autoStartStepTransparent = "transparent" // 应用透明代理规则
+	autoStartStepDone        = "done"        // 自动启动结束
+)
+
+// networkPollInterval 等待网络就绪时的检查间隔
+const networkPollInterval = 2 * time.Second
+
+// routeProbeAddrs 用于检查默认路由的地址（UDP 连接只查路由表，不发送数据）
+var routeProbeAddrs = []string{"223.5.5.5:53", "[2400:3200::1]:53"}
+
+// AutoStartProgress 自动启动进度，通过 core.autostart 事件推送
+type AutoStartProgress struct {
+	Step    string `json:"step"`
+	Status  string `json:"status"` // running, done, skipped, failed
+	Message string `json:"message,omitempty"`
+	Elapsed int64  `json:"elapsed"` // 自开始以来的毫秒数
+}
+
+// AutoStartIfEnabled 如果开启了自动启动，等待网络就绪后启动代理
+// 依次执行：等待默认路由和 DNS 可用（最多 autoStartDelay 秒）→ 节点或模板有变化时重新生成配置
+// → 启动核心 → 应用透明代理规则，每个阶段发布 core.autostart 事件
+func (h *Handler) AutoStartIfEnabled() {
+	cfg := h.service.GetConfig()
+	if !cfg.AutoStart {
+		return
+	}
+
+	timeout := time.Duration(cfg.AutoStartDelay) * time.Second
+	if timeout < 0 {
+		timeout = 0
+	}
+	fmt.Printf("⏳ 自动启动已开启，等待网络就绪（最多 %d 秒）后启动代理...\n", int(timeout.Seconds()))
+
+	go h.runAutoStart(timeout)
+}
+
+// runAutoStart 按阶段执行自动启动
+func (h *Handler) runAutoStart(networkTimeout time.Duration) {
+	began := time.Now()
+	report := func(step, status, message string) {
+		h.service.publish(events.AutoStartProgress, AutoStartProgress{
+			Step:    step,
+			Status:  status,
+			Message: message,
+			Elapsed: time.Since(began).Milliseconds(),
+		})
+	}
+
+	// 1. 等待网络就绪，超时后仍尝试启动（与原来的固定延迟等效）
+	if networkTimeout > 0 {
+		report(autoStartStepNetwork, "running", "")
+		ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+		err := waitNetworkOnline(ctx, h.service.dnsProbeHost())
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️ 等待网络就绪超时，继续启动: %v\n", err)
+			report(autoStartStepNetwork, "failed", err.Error())
+		} else {
+			fmt.Printf("✓ 网络已就绪（%.1f 秒）\n", time.Since(began).Seconds())
+			report(autoStartStepNetwork, "done", "")
+		}
+	} else {
+		report(autoStartStepNetwork, "skipped", "未设置等待时间")
+	}
+
+	// 等待期间可能已手动启动或关闭了自动启动
+	if !h.service.GetConfig().AutoStart {
+		fmt.Println("✓ 自动启动已关闭，跳过")
+		report(autoStartStepDone, "skipped", "自动启动已关闭")
+		return
+	}
+	if h.service.GetStatus().Running {
+		fmt.Println("✓ 代理已在运行，跳过自动启动")
+		report(autoStartStepDone, "skipped", "代理已在运行")
+		return
+	}
+
+	// 2. 订阅在离线期间可能已更新，节点或模板有变化时才重新生成配置
+	report(autoStartStepConfig, "running", "")
+	changed, err := h.service.prepareAutoStartConfig()
+	switch {
+	case err != nil:
+		// 启动时会再次尝试生成，失败则使用已有配置
+		fmt.Printf("⚠️ 检查配置失败: %v\n", err)
+		report(autoStartStepConfig, "failed", err.Error())
+	case changed:
+		report(autoStartStepConfig, "done", "配置已重新生成")
+	default:
+		report(autoStartStepConfig, "skipped", "配置无变化")
+	}
+
+	// 3. 启动核心（启动回调中应用透明代理规则）
+	fmt.Println("🚀 开始自动启动代理...")
+	report(autoStartStepCore, "running", "")
+	if err := h.service.Start(); err != nil {
+		if errors.Is(err, ErrCoreRunning) {
+			report(autoStartStepCore, "skipped", "代理已在运行")
+			report(autoStartStepDone, "skipped", "代理已在运行")
+			return
+		}
+		fmt.Printf("❌ 自动启动代理失败: %v\n", err)
+		report(autoStartStepCore, "failed", err.Error())
+		report(autoStartStepDone, "failed", err.Error())
+		return
+	}
+	fmt.Println("✓ 代理自动启动成功")
+	report(autoStartStepCore, "done", "")
+
+	// 4. 透明代理规则由启动回调应用，这里汇报结果
+	mode := h.service.GetStatus().TransparentMode
+	if runtime.GOOS != "linux" || !isTransparentMode(mode) {
+		report(autoStartStepTransparent, "skipped", "")
+	} else if err := h.rulesError(); err != nil {
+		report(autoStartStepTransparent, "failed", err.Error())
+	} else {
+		report(autoStartStepTransparent, "done", mode)
+	}
+
+	report(autoStartStepDone, "done", "")
+}
+
+// prepareAutoStartConfig 生成的配置与现有配置不同时重新生成，并让下一次启动直接使用该配置
+// 返回配置是否重新生成
+func (s *Service) prepareAutoStartConfig() (bool, error) {
+	result, err := s.DryRunConfig(nil)
+	if err != nil {
+		return false, err
+	}
+	if result.Changed {
+		if _, err := s.regenerateConfig("autostart"); err != nil {
+			return false, err
+		}
+		fmt.Printf("🔄 节点或模板有变化，已重新生成配置\n")
+	}
+
+	s.mu.Lock()
+	s.keepConfigOnStart = true
+	s.mu.Unlock()
+	return result.Changed, nil
+}
+
+// dnsProbeHost 返回第一个使用域名的节点服务器，用于检查 DNS 是否可用
+// 节点均为 IP 时返回空，此时只检查默认路由
+func (s *Service) dnsProbeHost() string {
+	provider := s.nodeProvider
+	if provider == nil {
+		return ""
+	}
+	for _, n := range provider() {
+		if n.Server != "" && net.ParseIP(n.Server) == nil {
+			return n.Server
+		}
+	}
+	return ""
+}
+
+// waitNetworkOnline 等待存在默认路由且 host 可以解析，ctx 结束时返回最后一次检查的错误
+// 注意：系统 DNS 指向核心自身时（如网关模式）解析会一直失败，只能等到超时
+func waitNetworkOnline(ctx context.Context, host string) error {
+	for {
+		err := checkNetworkOnline(ctx, host)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(networkPollInterval):
+		}
+	}
+}
+
+// checkNetworkOnline 检查默认路由和 DNS 解析
+func checkNetworkOnline(ctx context.Context, host string) error {
+	if !hasDefaultRoute() {
+		return fmt.Errorf("没有默认路由")
+	}
+	if host == "" {
+		return nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(lookupCtx, host); err != nil {
+		return fmt.Errorf("DNS 解析 %s 失败: %w", host, err)
+	}
+	return nil
+}
+
+// hasDefaultRoute 检查是否存在 IPv4 或 IPv6 默认路由
+func hasDefaultRoute() bool {
+	for _, addr := range routeProbeAddrs {
+		conn, err := net.Dial("udp", addr)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
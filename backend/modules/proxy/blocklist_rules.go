@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// blocklistRuleSetName 广告拦截规则集合在生成配置中的名称
+const blocklistRuleSetName = "proxystation-blocklist"
+
+// BlocklistRules 广告拦截规则文件（与 blocklist 包对应，由 server 注入）
+type BlocklistRules struct {
+	MihomoPath  string   // text 格式，每行 +.domain
+	SingBoxPath string   // source 格式规则集
+	Whitelist   []string // 父域名被拦截时需要例外的域名
+}
+
+// SetBlocklistProvider 设置广告拦截规则获取回调
+func (s *Service) SetBlocklistProvider(provider func() *BlocklistRules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocklistProvider = provider
+}
+
+// currentBlocklist 当前生效的广告拦截规则，未启用时返回 nil
+func (s *Service) currentBlocklist() *BlocklistRules {
+	if s.blocklistProvider == nil {
+		return nil
+	}
+	return s.blocklistProvider()
+}
+
+// applyMihomoBlocklist 添加广告拦截规则集合，并在模板规则之前插入 REJECT 规则
+// 白名单使用 AND + NOT 逻辑规则排除，不影响这些域名原本匹配的规则
+func applyMihomoBlocklist(config *MihomoConfig, b *BlocklistRules) {
+	if b == nil {
+		return
+	}
+	if _, err := os.Stat(b.MihomoPath); err != nil {
+		fmt.Printf("⚠️ 广告拦截规则文件不存在: %s\n", b.MihomoPath)
+		return
+	}
+	if config.RuleProviders == nil {
+		config.RuleProviders = make(map[string]RuleProvider)
+	}
+	config.RuleProviders[blocklistRuleSetName] = RuleProvider{
+		Type:     "file",
+		Behavior: "domain",
+		Path:     b.MihomoPath,
+		Format:   "text",
+	}
+
+	rule := "RULE-SET," + blocklistRuleSetName + ",REJECT"
+	if len(b.Whitelist) > 0 {
+		conditions := []string{"(RULE-SET," + blocklistRuleSetName + ")"}
+		for _, d := range b.Whitelist {
+			conditions = append(conditions, "(NOT,((DOMAIN-SUFFIX,"+d+")))")
+		}
+		rule = "AND,(" + strings.Join(conditions, ",") + "),REJECT"
+	}
+	config.Rules = append([]string{rule}, config.Rules...)
+}
+
+// applySingBoxBlocklist 添加广告拦截规则集，并在 sniff / hijack-dns 之后插入 reject 规则
+func applySingBoxBlocklist(config *SingBoxConfig, b *BlocklistRules) {
+	if b == nil || config.Route == nil {
+		return
+	}
+	if _, err := os.Stat(b.SingBoxPath); err != nil {
+		fmt.Printf("⚠️ 广告拦截规则文件不存在: %s\n", b.SingBoxPath)
+		return
+	}
+	config.Route.RuleSet = append(config.Route.RuleSet, SBRuleSet{
+		Tag:    blocklistRuleSetName,
+		Type:   "local",
+		Format: "source",
+		Path:   b.SingBoxPath,
+	})
+
+	rule := SBRouteRule{RuleSet: blocklistRuleSetName, Action: "reject"}
+	if len(b.Whitelist) > 0 {
+		rule = SBRouteRule{
+			Type: "logical",
+			Mode: "and",
+			Rules: []SBRouteRule{
+				{RuleSet: blocklistRuleSetName},
+				{DomainSuffix: b.Whitelist, Invert: true},
+			},
+			Action: "reject",
+		}
+	}
+
+	insertAt := 2
+	if len(config.Route.Rules) < insertAt {
+		insertAt = len(config.Route.Rules)
+	}
+	rules := make([]SBRouteRule, 0, len(config.Route.Rules)+1)
+	rules = append(rules, config.Route.Rules[:insertAt]...)
+	rules = append(rules, rule)
+	rules = append(rules, config.Route.Rules[insertAt:]...)
+	config.Route.Rules = rules
+}
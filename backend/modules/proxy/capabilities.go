@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内核模块状态
+const (
+	moduleLoaded    = "loaded"    // 已加载
+	moduleBuiltin   = "builtin"   // 编译进内核
+	moduleAvailable = "available" // 未加载，使用时由内核自动加载
+	moduleMissing   = "missing"
+	moduleUnknown   = "unknown" // 无法读取模块列表（如容器中）
+)
+
+// ModeSupport 透明代理模式是否可用
+type ModeSupport struct {
+	Supported bool     `json:"supported"`
+	Reasons   []string `json:"reasons"`  // 不可用的原因
+	Warnings  []string `json:"warnings"` // 可用但需要注意的问题
+}
+
+// Capabilities 运行环境的权限和内核功能
+type Capabilities struct {
+	OS          string                 `json:"os"`
+	Kernel      string                 `json:"kernel,omitempty"`
+	Root        bool                   `json:"root"`
+	NetAdmin    bool                   `json:"netAdmin"`          // CAP_NET_ADMIN
+	NftPath     string                 `json:"nftPath,omitempty"` // nft 命令路径，未安装时为空
+	IPPath      string                 `json:"ipPath,omitempty"`  // ip（iproute2）命令路径
+	Modules     map[string]string      `json:"modules,omitempty"` // 内核模块状态
+	TProxy      bool                   `json:"tproxy"`            // 可以设置 IP_TRANSPARENT
+	TProxyError string                 `json:"tproxyError,omitempty"`
+	IPForward   bool                   `json:"ipForward"`
+	IPv6Forward bool                   `json:"ipv6Forward"`
+	TUN         bool                   `json:"tun"`
+	TUNError    string                 `json:"tunError,omitempty"`
+	Modes       map[string]ModeSupport `json:"modes"` // tproxy / redirect / tun / system
+}
+
+// newModeSupport 根据原因列表得到模式支持情况
+func newModeSupport(reasons, warnings []string) ModeSupport {
+	if reasons == nil {
+		reasons = []string{}
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return ModeSupport{Supported: len(reasons) == 0, Reasons: reasons, Warnings: warnings}
+}
+
+// DetectCapabilities 检查运行环境，供界面在应用前禁用不支持的模式
+func DetectCapabilities() *Capabilities {
+	caps := &Capabilities{OS: runtime.GOOS, Root: os.Geteuid() == 0, Modes: make(map[string]ModeSupport)}
+	probeCapabilities(caps)
+	return caps
+}
+
+// GetCapabilities 获取权限和内核功能检查结果
+func (h *Handler) GetCapabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    DetectCapabilities(),
+	})
+}
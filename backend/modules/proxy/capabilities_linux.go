@@ -0,0 +1,138 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 透明代理规则依赖的内核模块
+var (
+	nftBaseModules     = []string{"nf_tables"}
+	nftTProxyModules   = []string{"nft_tproxy", "nft_socket"}
+	nftRedirectModules = []string{"nft_redir"}
+)
+
+// probeCapabilities 检查 Linux 下各透明代理模式的运行条件
+func probeCapabilities(caps *Capabilities) {
+	if data, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		caps.Kernel = strings.TrimSpace(string(data))
+	}
+	caps.NetAdmin = hasCapability(capNetAdmin)
+	caps.NftPath, _ = exec.LookPath("nft")
+	caps.IPPath, _ = exec.LookPath("ip")
+	caps.IPForward = readSysctlFlag("/proc/sys/net/ipv4/ip_forward")
+	caps.IPv6Forward = readSysctlFlag("/proc/sys/net/ipv6/conf/all/forwarding")
+
+	caps.Modules = make(map[string]string)
+	for _, group := range [][]string{nftBaseModules, nftTProxyModules, nftRedirectModules, {"tun"}} {
+		for _, name := range group {
+			caps.Modules[name] = kernelModuleState(caps.Kernel, name)
+		}
+	}
+
+	if err := checkTransparentSockopt(); err != nil {
+		caps.TProxyError = err.Error()
+	} else {
+		caps.TProxy = true
+	}
+	if err := checkTUNSupport(); err != nil {
+		caps.TUNError = err.Error()
+	} else {
+		caps.TUN = true
+	}
+
+	// nftables 规则（tproxy / redirect 共用）
+	var nftReasons, nftWarnings []string
+	if caps.NftPath == "" {
+		nftReasons = append(nftReasons, "未安装 nft 命令（nftables）")
+	}
+	if !caps.NetAdmin {
+		nftReasons = append(nftReasons, "后端没有 root 或 CAP_NET_ADMIN 权限，无法加载 nftables 规则（可导出规则手动应用）")
+	}
+	checkModules := func(modules []string, reasons, warnings *[]string) {
+		for _, name := range modules {
+			switch caps.Modules[name] {
+			case moduleMissing:
+				*reasons = append(*reasons, fmt.Sprintf("内核缺少 %s 模块", name))
+			case moduleUnknown:
+				*warnings = append(*warnings, fmt.Sprintf("无法确认内核模块 %s 是否可用", name))
+			}
+		}
+	}
+	checkModules(nftBaseModules, &nftReasons, &nftWarnings)
+	if !caps.IPForward {
+		nftWarnings = append(nftWarnings, "IP 转发未开启，路由器模式启动时会自动开启")
+	}
+
+	tproxyReasons := append([]string{}, nftReasons...)
+	tproxyWarnings := append([]string{}, nftWarnings...)
+	checkModules(nftTProxyModules, &tproxyReasons, &tproxyWarnings)
+	if !caps.TProxy && caps.NetAdmin {
+		tproxyReasons = append(tproxyReasons, "无法设置 IP_TRANSPARENT: "+caps.TProxyError)
+	}
+	if caps.IPPath == "" {
+		tproxyReasons = append(tproxyReasons, "未安装 ip 命令（iproute2），无法配置策略路由")
+	}
+	caps.Modes["tproxy"] = newModeSupport(tproxyReasons, tproxyWarnings)
+
+	redirectReasons := append([]string{}, nftReasons...)
+	redirectWarnings := append([]string{}, nftWarnings...)
+	checkModules(nftRedirectModules, &redirectReasons, &redirectWarnings)
+	redirectWarnings = append(redirectWarnings, "Redirect 模式只代理 TCP 流量")
+	caps.Modes["redirect"] = newModeSupport(redirectReasons, redirectWarnings)
+
+	var tunReasons []string
+	if !caps.TUN {
+		tunReasons = append(tunReasons, caps.TUNError)
+	}
+	caps.Modes["tun"] = newModeSupport(tunReasons, nil)
+
+	caps.Modes["system"] = newModeSupport([]string{"系统代理模式仅支持 macOS / Windows"}, nil)
+}
+
+// readSysctlFlag 读取值为 0/1 的内核参数
+func readSysctlFlag(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// kernelModuleState 查询内核模块状态
+// /sys/module 中存在为已加载；modules.builtin 中列出为内置；modules.dep 中列出为可自动加载
+func kernelModuleState(release, name string) string {
+	if _, err := os.Stat(filepath.Join("/sys/module", name)); err == nil {
+		return moduleLoaded
+	}
+
+	dir := filepath.Join("/lib/modules", release)
+	builtin, errBuiltin := os.ReadFile(filepath.Join(dir, "modules.builtin"))
+	dep, errDep := os.ReadFile(filepath.Join(dir, "modules.dep"))
+	if errBuiltin != nil && errDep != nil {
+		return moduleUnknown
+	}
+	if moduleListed(string(builtin), name) {
+		return moduleBuiltin
+	}
+	if moduleListed(string(dep), name) {
+		return moduleAvailable
+	}
+	return moduleMissing
+}
+
+// moduleListed 模块列表中是否包含指定模块（kernel/net/netfilter/nft_tproxy.ko.zst: ...）
+func moduleListed(list, name string) bool {
+	for _, line := range strings.Split(list, "\n") {
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		base := filepath.Base(strings.TrimSpace(line))
+		if i := strings.Index(base, ".ko"); i >= 0 && strings.ReplaceAll(base[:i], "-", "_") == name {
+			return true
+		}
+	}
+	return false
+}
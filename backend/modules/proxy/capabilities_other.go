@@ -0,0 +1,14 @@
+//go:build !linux
+
+package proxy
+
+// probeCapabilities 非 Linux 只支持系统代理模式
+func probeCapabilities(caps *Capabilities) {
+	const reason = "仅 Linux 支持"
+	caps.TProxyError = reason
+	caps.TUNError = reason
+	caps.Modes["tproxy"] = newModeSupport([]string{reason}, nil)
+	caps.Modes["redirect"] = newModeSupport([]string{reason}, nil)
+	caps.Modes["tun"] = newModeSupport([]string{"TUN 模式仅支持 Linux"}, nil)
+	caps.Modes["system"] = newModeSupport(nil, nil)
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigChangeSummary 配置变更摘要
+type ConfigChangeSummary struct {
+	NodesAdded    []string `json:"nodesAdded"`
+	NodesRemoved  []string `json:"nodesRemoved"`
+	GroupsAdded   []string `json:"groupsAdded"`
+	GroupsRemoved []string `json:"groupsRemoved"`
+	GroupsChanged []string `json:"groupsChanged"` // 类型或成员发生变化的代理组
+}
+
+// ConfigDryRun 试运行生成结果
+type ConfigDryRun struct {
+	CoreType string              `json:"coreType"`
+	Changed  bool                `json:"changed"`
+	Diff     string              `json:"diff"`
+	Summary  ConfigChangeSummary `json:"summary"`
+	Content  string              `json:"content"`
+}
+
+// configOutline 配置中的节点和代理组（组名 -> 类型与成员）
+type configOutline struct {
+	nodes  map[string]bool
+	groups map[string]string
+}
+
+// DryRunConfig 在内存中生成配置并与当前配置比较，不写入磁盘
+// nodes 为空时从节点提供者获取
+func (s *Service) DryRunConfig(nodes []ProxyNode) (*ConfigDryRun, error) {
+	if len(nodes) == 0 {
+		var err error
+		if nodes, err = s.GetAllNodes(); err != nil {
+			return nil, err
+		}
+	}
+
+	coreType := s.GetCoreType()
+	content, err := s.renderConfig(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(s.activeConfigPath(coreType))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	diff := unifiedDiff("current", "generated", string(current), string(content), 3)
+	return &ConfigDryRun{
+		CoreType: coreType,
+		Changed:  diff != "",
+		Diff:     diff,
+		Summary:  summarizeConfigChanges(parseConfigOutline(coreType, current), parseConfigOutline(coreType, content)),
+		Content:  string(content),
+	}, nil
+}
+
+// parseConfigOutline 提取配置中的节点和代理组，解析失败时返回空结构
+func parseConfigOutline(coreType string, data []byte) configOutline {
+	outline := configOutline{nodes: map[string]bool{}, groups: map[string]string{}}
+	if len(data) == 0 {
+		return outline
+	}
+
+	if coreType == "singbox" {
+		var config struct {
+			Outbounds []struct {
+				Tag       string   `json:"tag"`
+				Type      string   `json:"type"`
+				Outbounds []string `json:"outbounds"`
+			} `json:"outbounds"`
+		}
+		if json.Unmarshal(data, &config) != nil {
+			return outline
+		}
+		for _, ob := range config.Outbounds {
+			switch ob.Type {
+			case "selector", "urltest":
+				outline.groups[ob.Tag] = ob.Type + ":" + strings.Join(ob.Outbounds, ",")
+			case "direct", "block", "dns":
+			default:
+				outline.nodes[ob.Tag] = true
+			}
+		}
+		return outline
+	}
+
+	var config struct {
+		Proxies []struct {
+			Name string `yaml:"name"`
+		} `yaml:"proxies"`
+		ProxyGroups []struct {
+			Name    string   `yaml:"name"`
+			Type    string   `yaml:"type"`
+			Proxies []string `yaml:"proxies"`
+			Use     []string `yaml:"use"`
+			Filter  string   `yaml:"filter"`
+		} `yaml:"proxy-groups"`
+	}
+	if yaml.Unmarshal(data, &config) != nil {
+		return outline
+	}
+	for _, p := range config.Proxies {
+		outline.nodes[p.Name] = true
+	}
+	for _, g := range config.ProxyGroups {
+		outline.groups[g.Name] = g.Type + ":" + strings.Join(g.Proxies, ",") + "|" + strings.Join(g.Use, ",") + "|" + g.Filter
+	}
+	return outline
+}
+
+// summarizeConfigChanges 比较两份配置的节点和代理组
+func summarizeConfigChanges(before, after configOutline) ConfigChangeSummary {
+	summary := ConfigChangeSummary{
+		NodesAdded:    []string{},
+		NodesRemoved:  []string{},
+		GroupsAdded:   []string{},
+		GroupsRemoved: []string{},
+		GroupsChanged: []string{},
+	}
+
+	for name := range after.nodes {
+		if !before.nodes[name] {
+			summary.NodesAdded = append(summary.NodesAdded, name)
+		}
+	}
+	for name := range before.nodes {
+		if !after.nodes[name] {
+			summary.NodesRemoved = append(summary.NodesRemoved, name)
+		}
+	}
+	for name, def := range after.groups {
+		old, ok := before.groups[name]
+		if !ok {
+			summary.GroupsAdded = append(summary.GroupsAdded, name)
+		} else if old != def {
+			summary.GroupsChanged = append(summary.GroupsChanged, name)
+		}
+	}
+	for name := range before.groups {
+		if _, ok := after.groups[name]; !ok {
+			summary.GroupsRemoved = append(summary.GroupsRemoved, name)
+		}
+	}
+
+	sort.Strings(summary.NodesAdded)
+	sort.Strings(summary.NodesRemoved)
+	sort.Strings(summary.GroupsAdded)
+	sort.Strings(summary.GroupsRemoved)
+	sort.Strings(summary.GroupsChanged)
+	return summary
+}
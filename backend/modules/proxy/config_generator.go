@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 
@@ -27,6 +27,8 @@ type MihomoConfig struct {
 	IPv6               bool   `yaml:"ipv6"`
 	ExternalController string `yaml:"external-controller"`
 	Secret             string `yaml:"secret,omitempty"`
+	RoutingMark        int    `yaml:"routing-mark,omitempty"`
+	InterfaceName      string `yaml:"interface-name,omitempty"`
 
 	// 高级配置
 	UnifiedDelay       bool     `yaml:"unified-delay,omitempty"`
@@ -48,10 +50,14 @@ type MihomoConfig struct {
 	DisableKeepAlive  bool `yaml:"disable-keep-alive,omitempty"` // 完全禁用 (省电模式)
 
 	// 模块配置
-	Profile *ProfileConfig `yaml:"profile,omitempty"`
-	DNS     *DNSConfig     `yaml:"dns,omitempty"`
-	TUN     *TUNConfig     `yaml:"tun,omitempty"`
-	Sniffer *SnifferConfig `yaml:"sniffer,omitempty"`
+	Profile *ProfileConfig    `yaml:"profile,omitempty"`
+	DNS     *DNSConfig        `yaml:"dns,omitempty"`
+	Hosts   map[string]string `yaml:"hosts,omitempty"`
+	TUN     *TUNConfig        `yaml:"tun,omitempty"`
+	Sniffer *SnifferConfig    `yaml:"sniffer,omitempty"`
+
+	// 额外入站（测速专用监听等）
+	Listeners []MihomoListener `yaml:"listeners,omitempty"`
 
 	// 代理配置
 	Proxies       []map[string]interface{} `yaml:"proxies"`
@@ -109,6 +115,7 @@ type DNSConfig struct {
 	EnhancedMode          string              `yaml:"enhanced-mode,omitempty"`
 	FakeIPRange           string              `yaml:"fake-ip-range,omitempty"`
 	FakeIPFilter          []string            `yaml:"fake-ip-filter,omitempty"`
+	FakeIPFilterMode      string              `yaml:"fake-ip-filter-mode,omitempty"`
 	RespectRules          bool                `yaml:"respect-rules,omitempty"`
 	DefaultNameserver     []string            `yaml:"default-nameserver,omitempty"`
 	ProxyServerNameserver []string            `yaml:"proxy-server-nameserver,omitempty"`
@@ -158,6 +165,17 @@ type ProxyGroup struct {
 	Proxies  []string `yaml:"proxies"`
 	URL      string   `yaml:"url,omitempty"`
 	Interval int      `yaml:"interval,omitempty"`
+	Strategy string   `yaml:"strategy,omitempty"` // load-balance 策略
+	Hidden   bool     `yaml:"hidden,omitempty"`   // 在面板中隐藏
+}
+
+// MihomoListener 额外入站监听
+type MihomoListener struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	Port   int    `yaml:"port"`
+	Listen string `yaml:"listen,omitempty"`
+	Proxy  string `yaml:"proxy,omitempty"` // 固定使用的出站
 }
 
 // ProxyNode 代理节点
@@ -166,9 +184,11 @@ type ProxyNode struct {
 	Type       string `json:"type"`
 	Server     string `json:"server"`
 	Port       int    `json:"port"`
-	ServerPort int    `json:"serverPort"` // 兼容 node 模块的字段名
-	Config     string `json:"config"`     // JSON 格式的完整配置
-	IsManual   bool   `json:"isManual"`   // 是否手动添加的节点
+	ServerPort int    `json:"serverPort"`       // 兼容 node 模块的字段名
+	Config     string `json:"config"`           // JSON 格式的完整配置
+	IsManual   bool   `json:"isManual"`         // 是否手动添加的节点
+	Source     string `json:"source,omitempty"` // 来源订阅名称
+	Delay      int    `json:"delay,omitempty"`  // 最近一次测速延迟 ms，小于等于 0 为超时或未测试
 }
 
 // GetPort 获取端口（兼容两种字段名）
@@ -195,6 +215,11 @@ type ConfigGeneratorOptions struct {
 	// TUN 模式
 	EnableTUN bool `json:"enableTun"`
 
+	// 核心出站流量标记（透明代理和 TUN 模式），为 0 时使用默认值
+	RoutingMark int `json:"routingMark"`
+	// 核心出站绑定的网卡（多 WAN / VPN 底层网卡），为空时由系统路由决定
+	InterfaceName string `json:"interfaceName"`
+
 	// DNS 设置
 	EnableDNS    bool     `json:"enableDns"`
 	DNSListen    string   `json:"dnsListen"`
@@ -229,6 +254,27 @@ type ConfigGeneratorOptions struct {
 
 	// 配置模板（可选，为 nil 时使用默认生成）
 	Template *ConfigTemplate `json:"-"`
+
+	// 设备策略（按源地址指定出站）
+	DevicePolicies []DevicePolicy `json:"-"`
+
+	// 节点测速专用监听端口（0 表示不启用）
+	SpeedtestPort int `json:"-"`
+
+	// 节点标签设置（代理组按标签填充节点）
+	NodeTags NodeTagConfig `json:"-"`
+
+	// 节点重命名设置（由配置生成管线处理）
+	NodeRename NodeRenameConfig `json:"-"`
+
+	// 跨订阅节点去重设置（由配置生成管线处理）
+	NodeDedup NodeDedupConfig `json:"-"`
+
+	// 链式代理
+	ProxyChains []ProxyChain `json:"-"`
+
+	// 广告拦截规则
+	Blocklist *BlocklistRules `json:"-"`
 }
 
 // ConfigGenerator 配置生成器
@@ -338,6 +384,11 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 		}
 		// Redir 端口 (用于 iptables REDIRECT)
 		config.RedirPort = 7892
+		// 核心自身出站流量打标记，nftables output 链据此跳过，避免回环（含 DNS 劫持）
+		config.RoutingMark = options.RoutingMark
+		if config.RoutingMark == 0 {
+			config.RoutingMark = transparentBypassMark
+		}
 	}
 	// 系统代理模式不设置 redir-port 和 tproxy-port
 
@@ -364,6 +415,8 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 			"192.168.0.0/16", "10.0.0.0/8", "172.16.0.0/12",
 			"127.0.0.0/8", "fc00::/7", "fe80::/10",
 		}
+		var routeAddress []string
+		iproute2TableIndex, iproute2RuleIndex := 0, 0
 
 		// 从设置覆盖
 		if tunSettings != nil {
@@ -394,6 +447,9 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 			if len(tunSettings.RouteExcludeAddress) > 0 {
 				routeExcludeAddress = tunSettings.RouteExcludeAddress
 			}
+			routeAddress = tunSettings.RouteAddress
+			iproute2TableIndex = tunSettings.Iproute2TableIndex
+			iproute2RuleIndex = tunSettings.Iproute2RuleIndex
 		}
 
 		config.TUN = &TUNConfig{
@@ -410,7 +466,17 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 			GSO:                    gso,
 			GSOMaxSize:             gsoMaxSize,
 			EndpointIndependentNat: endpointIndependentNat,
+			RouteAddress:           routeAddress,
 			RouteExcludeAddress:    routeExcludeAddress,
+			Iproute2TableIndex:     iproute2TableIndex,
+			Iproute2RuleIndex:      iproute2RuleIndex,
+		}
+		// 核心出站流量打标记，auto-route 的策略路由据此跳过，避免回环
+		if runtime.GOOS == "linux" && config.RoutingMark == 0 {
+			config.RoutingMark = options.RoutingMark
+			if config.RoutingMark == 0 {
+				config.RoutingMark = transparentBypassMark
+			}
 		}
 		// TUN 模式下调整 DNS 配置
 		if config.DNS != nil {
@@ -437,20 +503,27 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 
 	// 转换代理节点
 	config.Proxies = g.convertProxies(nodes)
+	config.InterfaceName = options.InterfaceName
 
-	// 生成代理组（始终使用模板，确保名称一致）
+	// 始终使用模板，确保代理组名称一致
 	template := options.Template
 	if template == nil {
 		template = GetDefaultConfigTemplate()
 	}
-	config.ProxyGroups = g.generateProxyGroupsFromTemplate(nodes, template.ProxyGroups)
+
+	// 模板 DNS 设置覆盖默认值
+	applyTemplateDNS(config.DNS, template.DNS)
+	if template.DNS != nil && len(template.DNS.Hosts) > 0 {
+		config.Hosts = template.DNS.Hosts
+	}
 
 	// 生成规则提供者
-	config.RuleProviders = g.generateRuleProviders()
+	config.RuleProviders = mergeTemplateRuleProviders(g.generateRuleProviders(), template.RuleProviders)
 
 	// 生成规则（使用模板中的规则）
 	config.Rules = g.generateRulesFromTemplate(template.Rules)
 
+	// 代理组、链式代理、出站标记、广告拦截、设备策略、节点测速等由配置生成管线添加（见 config_pipeline.go）
 	return config, nil
 }
 
@@ -474,65 +547,12 @@ func (g *ConfigGenerator) generateDNSConfig(options ConfigGeneratorOptions) *DNS
 	if options.DNSListen != "" {
 		dns.Listen = options.DNSListen
 	} else {
-		dns.Listen = "0.0.0.0:1053"
+		dns.Listen = fmt.Sprintf("0.0.0.0:%d", defaultDNSListenPort)
 	}
 
 	if dns.EnhancedMode == "fake-ip" {
 		dns.FakeIPRange = "198.18.0.1/16"
-		dns.FakeIPFilter = []string{
-			// === 直连域名使用真实 IP (不使用 fake-ip) ===
-			"geosite:cn",      // 国内域名直接返回真实 IP
-			"geosite:private", // 私有域名
-
-			// === 本地域名 ===
-			"*.lan",
-			"*.local",
-			"*.localhost",
-			"*.localdomain",
-			"*.home.arpa",
-
-			// === 网络检测 ===
-			"+.msftconnecttest.com",
-			"+.msftncsi.com",
-			"connectivitycheck.gstatic.com",
-			"captive.apple.com",
-			"wifi.vivo.com.cn",
-			"connect.rom.miui.com",
-
-			// === NTP 时间同步 ===
-			"time.*.com",
-			"time.*.gov",
-			"time.*.apple.com",
-			"time.*.edu.cn",
-			"ntp.*.com",
-			"pool.ntp.org",
-
-			// === STUN/NAT 穿透 ===
-			"stun.*.*",
-			"stun.*.*.*",
-			"+.stun.playstation.net",
-			"+.stun.xbox.com",
-			"+.stun.l.google.com",
-
-			// === 本地服务发现 ===
-			"+._tcp.*",
-			"+._udp.*",
-
-			// === 国内常用服务 ===
-			"localhost.ptlogin2.qq.com",
-			"+.market.xiaomi.com",
-			"+.qq.com",
-			"+.tencent.com",
-			"+.weixin.qq.com",
-			"+.alipay.com",
-			"+.taobao.com",
-			"+.tmall.com",
-			"+.jd.com",
-			"+.baidu.com",
-			"+.bilibili.com",
-			"+.163.com",
-			"+.126.com",
-		}
+		dns.FakeIPFilter = defaultFakeIPFilter()
 	}
 
 	// 默认 DNS (用于解析 DOH 域名) - 必须是 IP
@@ -1246,49 +1266,50 @@ func (g *ConfigGenerator) generateProxyGroups(nodes []ProxyNode) []ProxyGroup {
 	return groups
 }
 
+// builtinRuleProviderBaseURL 内置规则集下载地址
+const builtinRuleProviderBaseURL = "https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@meta/geo"
+
+// builtinRuleProviders 内置规则集定义
+var builtinRuleProviders = []struct {
+	name     string
+	behavior string
+	urlPath  string
+}{
+	{"private-domain", "domain", "/geosite/private.mrs"},
+	{"private-ip", "ipcidr", "/geoip/private.mrs"},
+	{"ai-domain", "domain", "/geosite/openai.mrs"},
+	{"youtube-domain", "domain", "/geosite/youtube.mrs"},
+	{"google-domain", "domain", "/geosite/google.mrs"},
+	{"google-ip", "ipcidr", "/geoip/google.mrs"},
+	{"telegram-domain", "domain", "/geosite/telegram.mrs"},
+	{"telegram-ip", "ipcidr", "/geoip/telegram.mrs"},
+	{"twitter-domain", "domain", "/geosite/twitter.mrs"},
+	{"twitter-ip", "ipcidr", "/geoip/twitter.mrs"},
+	{"facebook-domain", "domain", "/geosite/facebook.mrs"},
+	{"facebook-ip", "ipcidr", "/geoip/facebook.mrs"},
+	{"github-domain", "domain", "/geosite/github.mrs"},
+	{"apple-domain", "domain", "/geosite/apple.mrs"},
+	{"apple-cn-domain", "domain", "/geosite/apple-cn.mrs"},
+	{"microsoft-domain", "domain", "/geosite/microsoft.mrs"},
+	{"netflix-domain", "domain", "/geosite/netflix.mrs"},
+	{"netflix-ip", "ipcidr", "/geoip/netflix.mrs"},
+	{"spotify-domain", "domain", "/geosite/spotify.mrs"},
+	{"tiktok-domain", "domain", "/geosite/tiktok.mrs"},
+	{"bilibili-domain", "domain", "/geosite/bilibili.mrs"},
+	{"steam-domain", "domain", "/geosite/steam.mrs"},
+	{"epic-domain", "domain", "/geosite/epicgames.mrs"},
+	{"cn-domain", "domain", "/geosite/cn.mrs"},
+	{"cn-ip", "ipcidr", "/geoip/cn.mrs"},
+	{"geolocation-!cn", "domain", "/geosite/geolocation-!cn.mrs"},
+	{"ads-domain", "domain", "/geosite/category-ads-all.mrs"},
+}
+
 // generateRuleProviders 生成规则提供者（优先使用本地文件，使用绝对路径）
 func (g *ConfigGenerator) generateRuleProviders() map[string]RuleProvider {
 	rulesetDir := filepath.Join(g.dataDir, "ruleset")
-	baseURL := "https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@meta/geo"
-
-	// 规则定义
-	rules := []struct {
-		name     string
-		behavior string
-		urlPath  string
-	}{
-		{"private-domain", "domain", "/geosite/private.mrs"},
-		{"private-ip", "ipcidr", "/geoip/private.mrs"},
-		{"ai-domain", "domain", "/geosite/openai.mrs"},
-		{"youtube-domain", "domain", "/geosite/youtube.mrs"},
-		{"google-domain", "domain", "/geosite/google.mrs"},
-		{"google-ip", "ipcidr", "/geoip/google.mrs"},
-		{"telegram-domain", "domain", "/geosite/telegram.mrs"},
-		{"telegram-ip", "ipcidr", "/geoip/telegram.mrs"},
-		{"twitter-domain", "domain", "/geosite/twitter.mrs"},
-		{"twitter-ip", "ipcidr", "/geoip/twitter.mrs"},
-		{"facebook-domain", "domain", "/geosite/facebook.mrs"},
-		{"facebook-ip", "ipcidr", "/geoip/facebook.mrs"},
-		{"github-domain", "domain", "/geosite/github.mrs"},
-		{"apple-domain", "domain", "/geosite/apple.mrs"},
-		{"apple-cn-domain", "domain", "/geosite/apple-cn.mrs"},
-		{"microsoft-domain", "domain", "/geosite/microsoft.mrs"},
-		{"netflix-domain", "domain", "/geosite/netflix.mrs"},
-		{"netflix-ip", "ipcidr", "/geoip/netflix.mrs"},
-		{"spotify-domain", "domain", "/geosite/spotify.mrs"},
-		{"tiktok-domain", "domain", "/geosite/tiktok.mrs"},
-		{"bilibili-domain", "domain", "/geosite/bilibili.mrs"},
-		{"steam-domain", "domain", "/geosite/steam.mrs"},
-		{"epic-domain", "domain", "/geosite/epicgames.mrs"},
-		{"cn-domain", "domain", "/geosite/cn.mrs"},
-		{"cn-ip", "ipcidr", "/geoip/cn.mrs"},
-		{"geolocation-!cn", "domain", "/geosite/geolocation-!cn.mrs"},
-		{"ads-domain", "domain", "/geosite/category-ads-all.mrs"},
-	}
-
 	providers := make(map[string]RuleProvider)
 
-	for _, r := range rules {
+	for _, r := range builtinRuleProviders {
 		localPath := filepath.Join(rulesetDir, r.name+".mrs")
 
 		// 检查本地文件是否存在
@@ -1305,7 +1326,7 @@ func (g *ConfigGenerator) generateRuleProviders() map[string]RuleProvider {
 			providers[r.name] = RuleProvider{
 				Type:     "http",
 				Behavior: r.behavior,
-				URL:      baseURL + r.urlPath,
+				URL:      builtinRuleProviderBaseURL + r.urlPath,
 				Path:     localPath,
 				Interval: 86400,
 				Format:   "mrs",
@@ -1349,6 +1370,64 @@ func (g *ConfigGenerator) generateRuleProviders() map[string]RuleProvider {
 	return providers
 }
 
+// mergeTemplateRuleProviders 合并模板中的规则提供者（如导入的配置），同名时以内置和自定义规则集为准
+func mergeTemplateRuleProviders(providers map[string]RuleProvider, templates []RuleProviderTemplate) map[string]RuleProvider {
+	for _, t := range templates {
+		if t.Name == "" {
+			continue
+		}
+		if _, exists := providers[t.Name]; exists {
+			continue
+		}
+		providers[t.Name] = RuleProvider{
+			Type:     t.Type,
+			Behavior: t.Behavior,
+			URL:      t.URL,
+			Path:     t.Path,
+			Interval: t.Interval,
+			Format:   t.Format,
+		}
+	}
+	return providers
+}
+
+// applyTemplateDNS 用模板 DNS 设置覆盖生成的 DNS 配置（监听地址和增强模式由代理模式决定，不覆盖）
+func applyTemplateDNS(dns *DNSConfig, t *DNSTemplate) {
+	if dns == nil || t == nil {
+		return
+	}
+	if len(t.DefaultNameserver) > 0 {
+		dns.DefaultNameserver = t.DefaultNameserver
+	}
+	if len(t.Nameserver) > 0 {
+		dns.Nameserver = t.Nameserver
+	}
+	if len(t.Fallback) > 0 {
+		dns.Fallback = t.Fallback
+	}
+	if len(t.ProxyServerNameserver) > 0 {
+		dns.ProxyServerNameserver = t.ProxyServerNameserver
+	}
+	if len(t.DirectNameserver) > 0 {
+		dns.DirectNameserver = t.DirectNameserver
+	}
+	if len(t.NameserverPolicy) > 0 {
+		dns.NameserverPolicy = t.NameserverPolicy
+	}
+	if t.FakeIPRange != "" {
+		dns.FakeIPRange = t.FakeIPRange
+	}
+	if len(t.FakeIPFilter) > 0 || t.FakeIPFilterPresets != nil {
+		dns.FakeIPFilter = effectiveFakeIPFilter(t)
+	}
+	if t.FakeIPFilterMode != "" {
+		dns.FakeIPFilterMode = t.FakeIPFilterMode
+	}
+	if t.RespectRules != nil {
+		dns.RespectRules = *t.RespectRules
+	}
+}
+
 // getGeoxURL 获取 GEO 数据文件 URL（优先使用本地文件）
 func (g *ConfigGenerator) getGeoxURL() *GeoxURL {
 	baseURL := "https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@release"
@@ -1357,6 +1436,7 @@ func (g *ConfigGenerator) getGeoxURL() *GeoxURL {
 	geoipPath := filepath.Join(g.dataDir, "geoip.dat")
 	geositePath := filepath.Join(g.dataDir, "geosite.dat")
 	mmdbPath := filepath.Join(g.dataDir, "country.mmdb")
+	metadbPath := filepath.Join(g.dataDir, "geoip.metadb")
 	asnPath := filepath.Join(g.dataDir, "GeoLite2-ASN.mmdb")
 
 	geox := &GeoxURL{}
@@ -1375,8 +1455,10 @@ func (g *ConfigGenerator) getGeoxURL() *GeoxURL {
 		geox.GeoSite = baseURL + "/geosite.dat"
 	}
 
-	// MMDB
-	if _, err := os.Stat(mmdbPath); err == nil {
+	// MMDB（优先使用 GEO 数据模块下载的 geoip.metadb）
+	if _, err := os.Stat(metadbPath); err == nil {
+		geox.MMDB = metadbPath
+	} else if _, err := os.Stat(mmdbPath); err == nil {
 		geox.MMDB = mmdbPath
 	} else {
 		geox.MMDB = baseURL + "/country.mmdb"
@@ -1467,35 +1549,6 @@ func (g *ConfigGenerator) generateRules() []string {
 	}
 }
 
-// SaveConfig 保存配置到文件
-func (g *ConfigGenerator) SaveConfig(config *MihomoConfig, filename string) (string, error) {
-	configDir := filepath.Join(g.dataDir, "configs")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-
-	if !strings.HasSuffix(filename, ".yaml") {
-		filename += ".yaml"
-	}
-
-	filePath := filepath.Join(configDir, filename)
-
-	// 序列化为 YAML
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return "", err
-	}
-
-	// 解码 Unicode 转义序列 (如 \U0001F1ED -> 🇭🇰)
-	yamlStr := decodeUnicodeEscapes(string(data))
-
-	if err := os.WriteFile(filePath, []byte(yamlStr), 0644); err != nil {
-		return "", err
-	}
-
-	return filePath, nil
-}
-
 // decodeUnicodeEscapes 将 YAML 中的 Unicode 转义序列转换回原始字符
 func decodeUnicodeEscapes(s string) string {
 	// 处理 \UXXXXXXXX 格式 (8位 Unicode)
@@ -1556,11 +1609,13 @@ func GetDefaultOptions() ConfigGeneratorOptions {
 }
 
 // generateProxyGroupsFromTemplate 从模板生成代理组
-func (g *ConfigGenerator) generateProxyGroupsFromTemplate(nodes []ProxyNode, templates []ProxyGroupTemplate) []ProxyGroup {
+func (g *ConfigGenerator) generateProxyGroupsFromTemplate(nodes []ProxyNode, templates []ProxyGroupTemplate, tagger *NodeTagger) []ProxyGroup {
 	var nodeNames []string
 	var manualNodeNames []string
+	nodeSet := make(map[string]bool, len(nodes))
 	for _, node := range nodes {
 		nodeNames = append(nodeNames, node.Name)
+		nodeSet[node.Name] = true
 		if node.IsManual {
 			manualNodeNames = append(manualNodeNames, node.Name)
 		}
@@ -1580,32 +1635,69 @@ func (g *ConfigGenerator) generateProxyGroupsFromTemplate(nodes []ProxyNode, tem
 			URL:      t.URL,
 			Interval: t.Interval,
 		}
+		if t.Type == "load-balance" {
+			// 负载均衡需要健康检查才能避开不可用节点，未填写时使用默认值
+			group.Strategy = t.Strategy
+			if group.Strategy == "" {
+				group.Strategy = defaultLoadBalanceStrategy
+			}
+			if group.URL == "" {
+				group.URL = defaultHealthCheckURL
+			}
+			if group.Interval == 0 {
+				group.Interval = defaultHealthCheckInterval
+			}
+		}
+
+		// 节点过滤：排除正则和倍率上限先去掉不需要的节点，Filter 再从剩余节点中选取
+		filter := newNodeFilter(t)
+		candidates := filter.withoutExcluded(nodeNames)
 
 		// 处理代理列表
-		if t.UseAll {
+		if len(t.Tags) > 0 {
+			// 按标签填充节点，模板中引用的代理组和内置策略保留在前面
+			for _, member := range t.Proxies {
+				if !nodeSet[member] {
+					group.Proxies = append(group.Proxies, member)
+				}
+			}
+			tagged := 0
+			for _, nodeName := range candidates {
+				if tagger.HasAny(nodeName, t.Tags) && filter.included(nodeName) {
+					group.Proxies = append(group.Proxies, nodeName)
+					tagged++
+				}
+			}
+			// 与 UseAll 过滤一致：没有匹配的节点时使用全部节点
+			if tagged == 0 && t.UseAll {
+				group.Proxies = append(group.Proxies, candidates...)
+			}
+		} else if t.UseAll {
 			// 特殊处理：手动节点分组
 			if t.Filter == "__MANUAL__" {
-				group.Proxies = manualNodeNames
-			} else if t.Filter != "" {
+				group.Proxies = filter.withoutExcluded(manualNodeNames)
+			} else if filter.include != nil {
 				// 使用模板中的 Filter 正则过滤节点
-				re, err := regexp.Compile(t.Filter)
-				if err == nil {
-					for _, nodeName := range nodeNames {
-						if re.MatchString(nodeName) {
-							group.Proxies = append(group.Proxies, nodeName)
-						}
+				for _, nodeName := range candidates {
+					if filter.included(nodeName) {
+						group.Proxies = append(group.Proxies, nodeName)
 					}
 				}
 				// 如果没匹配到任何节点，使用全部节点
 				if len(group.Proxies) == 0 {
-					group.Proxies = nodeNames
+					group.Proxies = candidates
 				}
 			} else {
-				group.Proxies = nodeNames
+				group.Proxies = candidates
 			}
 		} else {
-			// 使用模板中定义的代理列表
-			group.Proxies = append(group.Proxies, t.Proxies...)
+			// 使用模板中定义的代理列表，其中的节点同样按过滤条件筛选
+			for _, member := range t.Proxies {
+				if nodeSet[member] && !filter.allows(member) {
+					continue
+				}
+				group.Proxies = append(group.Proxies, member)
+			}
 		}
 
 		// 确保有代理
@@ -1627,6 +1719,9 @@ func (g *ConfigGenerator) generateRulesFromTemplate(templates []RuleTemplate) []
 	var rules []string
 
 	for _, t := range templates {
+		if t.Disabled {
+			continue
+		}
 		var rule string
 		// MATCH 规则不需要 Payload
 		if t.Type == "MATCH" {
@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/secure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 保留的历史版本数量
+const configHistoryLimit = 30
+
+// ConfigHistoryEntry 配置历史版本
+type ConfigHistoryEntry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Reason   string    `json:"reason"`   // 触发原因：start / generate / regenerate / reload / rollback 等
+	CoreType string    `json:"coreType"` // mihomo / singbox
+	File     string    `json:"file"`     // 历史目录中的文件名
+	Size     int       `json:"size"`
+}
+
+// configHistory 生成配置的历史归档
+type configHistory struct {
+	mu      sync.Mutex
+	dir     string
+	entries []ConfigHistoryEntry // 按时间升序
+}
+
+func newConfigHistory(dataDir string) *configHistory {
+	h := &configHistory{dir: filepath.Join(dataDir, "configs", "history")}
+	h.load()
+	return h
+}
+
+func (h *configHistory) indexFile() string {
+	return filepath.Join(h.dir, "history.json")
+}
+
+func (h *configHistory) load() {
+	data, err := os.ReadFile(h.indexFile())
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &h.entries)
+}
+
+func (h *configHistory) save() error {
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.indexFile(), data, 0644)
+}
+
+// latest 获取指定核心的最新历史版本
+func (h *configHistory) latest(coreType string) *ConfigHistoryEntry {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].CoreType == coreType {
+			return &h.entries[i]
+		}
+	}
+	return nil
+}
+
+// record 归档一次配置生成
+// previous 为生成前的配置内容：首次归档时先保存它，保证生成前的配置也可回滚
+func (h *configHistory) record(coreType, reason string, previous, current []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return err
+	}
+
+	last := h.latest(coreType)
+	if last == nil && len(previous) > 0 && string(previous) != string(current) {
+		if err := h.add(coreType, "initial", previous); err != nil {
+			return err
+		}
+		last = h.latest(coreType)
+	}
+
+	// 内容未变化时不重复归档
+	if last != nil {
+		if data, err := secure.ReadFile(filepath.Join(h.dir, last.File)); err == nil && string(data) == string(current) {
+			return nil
+		}
+	}
+
+	if err := h.add(coreType, reason, current); err != nil {
+		return err
+	}
+
+	// 清理超出数量的旧版本
+	for len(h.entries) > configHistoryLimit {
+		os.Remove(filepath.Join(h.dir, h.entries[0].File))
+		h.entries = h.entries[1:]
+	}
+	return h.save()
+}
+
+func (h *configHistory) add(coreType, reason string, content []byte) error {
+	id := uuid.New().String()[:8]
+	ext := ".yaml"
+	if coreType == "singbox" {
+		ext = ".json"
+	}
+	entry := ConfigHistoryEntry{
+		ID:       id,
+		Time:     time.Now(),
+		Reason:   reason,
+		CoreType: coreType,
+		File:     time.Now().Format("20060102-150405") + "-" + id + ext,
+		Size:     len(content),
+	}
+	// 历史版本包含节点凭据，启用数据加密时加密保存
+	if err := secure.WriteFile(filepath.Join(h.dir, entry.File), content, 0644); err != nil {
+		return err
+	}
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+// list 返回历史版本（最新在前）
+func (h *configHistory) list() []ConfigHistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := make([]ConfigHistoryEntry, 0, len(h.entries))
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		result = append(result, h.entries[i])
+	}
+	return result
+}
+
+// get 读取指定版本及其内容
+func (h *configHistory) get(id string) (*ConfigHistoryEntry, []byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.entries {
+		if h.entries[i].ID == id {
+			entry := h.entries[i]
+			data, err := secure.ReadFile(filepath.Join(h.dir, entry.File))
+			if err != nil {
+				return nil, nil, err
+			}
+			return &entry, data, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("历史版本不存在: %s", id)
+}
+
+// activeConfigPath 指定核心当前使用的配置文件路径
+func (s *Service) activeConfigPath(coreType string) string {
+	return runnerFor(coreType).ConfigPath(s.dataDir)
+}
+
+// archiveConfig 归档新生成的配置
+func (s *Service) archiveConfig(coreType, reason string, previous []byte) {
+	current, err := os.ReadFile(s.activeConfigPath(coreType))
+	if err != nil {
+		return
+	}
+	if err := s.history.record(coreType, reason, previous, current); err != nil {
+		fmt.Printf("⚠️ 归档配置历史失败: %v\n", err)
+	}
+}
+
+// GetConfigHistory 获取配置历史列表
+func (s *Service) GetConfigHistory() []ConfigHistoryEntry {
+	return s.history.list()
+}
+
+// DiffConfigHistory 比较历史版本与当前配置（against 为空）或另一个历史版本
+func (s *Service) DiffConfigHistory(id, against string) (string, error) {
+	entry, content, err := s.history.get(id)
+	if err != nil {
+		return "", err
+	}
+
+	targetName := "current"
+	var target []byte
+	if against != "" {
+		var other *ConfigHistoryEntry
+		other, target, err = s.history.get(against)
+		if err != nil {
+			return "", err
+		}
+		targetName = other.ID
+	} else {
+		target, err = os.ReadFile(s.activeConfigPath(entry.CoreType))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return unifiedDiff(entry.ID, targetName, string(content), string(target), 3), nil
+}
+
+// RollbackConfig 将历史版本恢复为当前配置
+// 恢复后下一次启动不会重新生成配置，避免回滚内容被覆盖
+func (s *Service) RollbackConfig(id string) (*ConfigHistoryEntry, error) {
+	entry, content, err := s.history.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := s.activeConfigPath(entry.CoreType)
+	previous, _ := os.ReadFile(configPath)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(configPath, content, 0644); err != nil {
+		return nil, err
+	}
+	s.archiveConfig(entry.CoreType, "rollback:"+entry.ID, previous)
+
+	s.mu.Lock()
+	if entry.CoreType == s.coreType {
+		s.configPath = configPath
+		s.keepConfigOnStart = true
+	}
+	s.mu.Unlock()
+
+	return entry, nil
+}
+
+// GetConfigHistory 获取配置历史列表
+func (h *Handler) GetConfigHistory(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfigHistory(),
+	})
+}
+
+// DiffConfigHistory 获取历史版本差异（?against=<id>，默认与当前配置比较），凭据默认脱敏
+func (h *Handler) DiffConfigHistory(c *gin.Context) {
+	reveal, ok := checkReveal(c)
+	if !ok {
+		return
+	}
+	diff, err := h.service.DiffConfigHistory(c.Param("id"), c.Query("against"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if !reveal {
+		diff = redactYAML(diff)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"diff":    diff,
+			"changed": diff != "",
+		},
+	})
+}
+
+// RollbackConfigHistory 回滚到指定历史版本，核心运行中时立即生效
+func (h *Handler) RollbackConfigHistory(c *gin.Context) {
+	entry, err := h.service.RollbackConfig(c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	method := ""
+	if h.service.GetStatus().Running && entry.CoreType == h.service.GetCoreType() {
+		method = "restart"
+		if entry.CoreType == "mihomo" {
+			if err := h.pushMihomoConfig(h.service.activeConfigPath(entry.CoreType)); err == nil {
+				method = "reload"
+			}
+		}
+		if method == "restart" {
+			if err := h.service.Restart(); err != nil {
+				apierror.Message(c, http.StatusInternalServerError, "配置已回滚，但重启核心失败: "+err.Error())
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"entry":  entry,
+			"method": method,
+		},
+	})
+}
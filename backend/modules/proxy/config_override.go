@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigOverride 用户自定义覆写，生成配置时深度合并到结果中
+type ConfigOverride struct {
+	Enabled   bool   `json:"enabled"`
+	Content   string `json:"content"` // Mihomo 为 YAML 片段，Sing-Box 为 JSON 对象
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// overrideListKeys 列表追加键：prepend-xxx / append-xxx 在原列表前后插入，而不是整体替换
+var overrideListKeys = []string{"rules", "proxies", "proxy-groups"}
+
+// overrideProtectedKeys 后端依赖的字段，不允许覆写
+var overrideProtectedKeys = []string{"external-controller", "secret"}
+
+// configOverridePath Mihomo 覆写文件路径
+func configOverridePath(dataDir string) string {
+	return filepath.Join(dataDir, "config_override.json")
+}
+
+// loadConfigOverride 读取覆写配置，文件不存在时返回空覆写
+func loadConfigOverride(path string) *ConfigOverride {
+	override := &ConfigOverride{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return override
+	}
+	json.Unmarshal(data, override)
+	return override
+}
+
+// saveConfigOverride 保存覆写配置
+func saveConfigOverride(path string, override ConfigOverride) error {
+	override.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	data, err := json.MarshalIndent(override, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// applyMihomoOverrideFile 合并 Mihomo 覆写文件，未启用覆写时原样返回
+func applyMihomoOverrideFile(dataDir string, data []byte) ([]byte, error) {
+	override := loadConfigOverride(configOverridePath(dataDir))
+	if !override.Enabled || strings.TrimSpace(override.Content) == "" {
+		return data, nil
+	}
+	node, err := parseOverride(override.Content)
+	if err != nil {
+		return nil, fmt.Errorf("配置覆写无效: %w", err)
+	}
+	if node == nil {
+		return data, nil
+	}
+	if data, err = applyOverride(data, node); err != nil {
+		return nil, fmt.Errorf("合并配置覆写失败: %w", err)
+	}
+	return data, nil
+}
+
+// parseOverride 解析覆写内容，必须是 YAML 映射
+func parseOverride(content string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, fmt.Errorf("YAML 格式错误: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("覆写内容必须是 YAML 映射（key: value）")
+	}
+	for i := 0; i < len(root.Content); i += 2 {
+		for _, key := range overrideProtectedKeys {
+			if root.Content[i].Value == key {
+				return nil, fmt.Errorf("不允许覆写 %s，请在代理配置中修改", key)
+			}
+		}
+	}
+	return root, nil
+}
+
+// applyOverride 将覆写深度合并到已渲染的 YAML 配置中
+// 映射递归合并，标量和列表整体替换，值为 null 时删除该字段
+func applyOverride(data []byte, override *yaml.Node) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("配置不是 YAML 映射")
+	}
+	root := doc.Content[0]
+
+	for i := 0; i < len(override.Content); i += 2 {
+		key, value := override.Content[i].Value, override.Content[i+1]
+		if applyListOverride(root, key, value) {
+			continue
+		}
+		mergeMappingKey(root, key, value)
+	}
+	return yaml.Marshal(&doc)
+}
+
+// applyListOverride 处理 prepend-xxx / append-xxx，返回是否已处理
+func applyListOverride(root *yaml.Node, key string, value *yaml.Node) bool {
+	for _, listKey := range overrideListKeys {
+		prepend := key == "prepend-"+listKey
+		if !prepend && key != "append-"+listKey {
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			return true
+		}
+		target := mappingValue(root, listKey)
+		if target == nil || target.Kind != yaml.SequenceNode {
+			target = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(root, listKey, target)
+		}
+		if prepend {
+			target.Content = append(append([]*yaml.Node{}, value.Content...), target.Content...)
+		} else {
+			target.Content = append(target.Content, value.Content...)
+		}
+		return true
+	}
+	return false
+}
+
+// mergeMappingKey 合并映射中的单个字段
+func mergeMappingKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	if value.Tag == "!!null" {
+		deleteMappingKey(mapping, key)
+		return
+	}
+	existing := mappingValue(mapping, key)
+	if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+		for i := 0; i < len(value.Content); i += 2 {
+			mergeMappingKey(existing, value.Content[i].Value, value.Content[i+1])
+		}
+		return
+	}
+	setMappingValue(mapping, key, value)
+}
+
+// mappingValue 获取映射中的字段值
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue 设置映射中的字段值，不存在时追加
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// deleteMappingKey 删除映射中的字段
+func deleteMappingKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// GetConfigOverride 获取 Mihomo 配置覆写
+func (s *Service) GetConfigOverride() *ConfigOverride {
+	return loadConfigOverride(configOverridePath(s.dataDir))
+}
+
+// SetConfigOverride 保存 Mihomo 配置覆写，下次生成配置时生效
+func (s *Service) SetConfigOverride(override ConfigOverride) error {
+	if strings.TrimSpace(override.Content) != "" {
+		if _, err := parseOverride(override.Content); err != nil {
+			return err
+		}
+	}
+	return saveConfigOverride(configOverridePath(s.dataDir), override)
+}
+
+// GetConfigOverride 获取配置覆写
+func (h *Handler) GetConfigOverride(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfigOverride(),
+	})
+}
+
+// UpdateConfigOverride 保存配置覆写
+func (h *Handler) UpdateConfigOverride(c *gin.Context) {
+	var override ConfigOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.SetConfigOverride(override); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "覆写已保存，重新生成配置后生效",
+		"data":    h.service.GetConfigOverride(),
+	})
+}
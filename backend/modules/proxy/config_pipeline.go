@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigStage 配置生成管线的阶段，各阶段依次执行，同一阶段内按注册顺序执行
+type ConfigStage int
+
+const (
+	// ConfigStageNodes 生成配置前处理节点列表（过滤、重命名、去重等）
+	ConfigStageNodes ConfigStage = iota
+	// ConfigStageGenerated 生成结构化配置后调整代理组、规则、监听等
+	ConfigStageGenerated
+	// ConfigStageRendered 序列化后处理配置内容（用户覆写等）
+	ConfigStageRendered
+)
+
+// ConfigBuild 一次配置生成过程中在各环节之间传递的数据
+type ConfigBuild struct {
+	CoreType string
+	Options  ConfigGeneratorOptions
+	// SingBoxOptions sing-box 生成选项（CoreType 为 singbox 时有效）
+	SingBoxOptions SingBoxGeneratorOptions
+	// Nodes 参与生成的节点，节点阶段可以替换或修改（已复制，不影响调用方）
+	Nodes []ProxyNode
+	// DedupReport 节点去重结果，未开启去重时为空；只在实际写入配置时保存
+	DedupReport *NodeDedupReport
+	// Mihomo / SingBox 生成的结构化配置，按 CoreType 只有一个非空（生成阶段起有效）
+	Mihomo  *MihomoConfig
+	SingBox *SingBoxConfig
+	// Content 序列化后的配置内容（序列化阶段起有效）
+	Content []byte
+}
+
+// marshal 序列化结构化配置：Mihomo 为 YAML，Sing-Box 为 JSON
+func (b *ConfigBuild) marshal() error {
+	var err error
+	if b.SingBox != nil {
+		b.Content, err = json.MarshalIndent(b.SingBox, "", "  ")
+	} else {
+		b.Content, err = yaml.Marshal(b.Mihomo)
+	}
+	return err
+}
+
+// finalize 返回最终写入的配置内容
+func (b *ConfigBuild) finalize() []byte {
+	if b.Mihomo != nil {
+		// 解码 Unicode 转义序列 (如 \U0001F1ED -> 🇭🇰)
+		return []byte(decodeUnicodeEscapes(string(b.Content)))
+	}
+	return b.Content
+}
+
+// ConfigMutator 配置生成管线中的一个环节
+// 新的配置转换功能实现此接口并通过 RegisterConfigMutator 注册，而不是继续扩展生成器
+type ConfigMutator interface {
+	Name() string
+	Stage() ConfigStage
+	Mutate(build *ConfigBuild) error
+}
+
+// RegisterConfigMutator 注册配置生成环节，下一次生成配置时生效
+func (s *Service) RegisterConfigMutator(m ConfigMutator) {
+	s.mutatorsMu.Lock()
+	defer s.mutatorsMu.Unlock()
+	s.mutators = append(s.mutators, m)
+	// 稳定排序保持同一阶段内的注册顺序
+	sort.SliceStable(s.mutators, func(i, j int) bool {
+		return s.mutators[i].Stage() < s.mutators[j].Stage()
+	})
+}
+
+// registerBuiltinMutators 注册内置的配置生成环节
+func (s *Service) registerBuiltinMutators() {
+	s.RegisterConfigMutator(nodeDedupMutator{})
+	s.RegisterConfigMutator(nodeRenameMutator{})
+	s.RegisterConfigMutator(proxyGroupMutator{mihomo: s.configGenerator, singbox: s.singboxGenerator})
+	s.RegisterConfigMutator(proxyChainMutator{})
+	s.RegisterConfigMutator(egressMutator{})
+	s.RegisterConfigMutator(blocklistMutator{})
+	s.RegisterConfigMutator(devicePolicyMutator{})
+	s.RegisterConfigMutator(speedtestMutator{})
+	s.RegisterConfigMutator(configOverrideMutator{dataDir: s.dataDir})
+}
+
+// runConfigMutators 执行指定阶段的所有环节，任一环节失败即中止生成
+func (s *Service) runConfigMutators(stage ConfigStage, build *ConfigBuild) error {
+	s.mutatorsMu.RLock()
+	mutators := append([]ConfigMutator(nil), s.mutators...)
+	s.mutatorsMu.RUnlock()
+
+	for _, m := range mutators {
+		if m.Stage() != stage {
+			continue
+		}
+		if err := m.Mutate(build); err != nil {
+			return fmt.Errorf("%s: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// splitSingBoxOutbounds 将 sing-box 出站拆分为代理组、节点和内置出站（direct/block/dns）
+func splitSingBoxOutbounds(outbounds []SBOutbound) (groups, nodes, builtins []SBOutbound) {
+	for _, out := range outbounds {
+		switch out.Type {
+		case "selector", "urltest":
+			groups = append(groups, out)
+		case "direct", "block", "dns":
+			builtins = append(builtins, out)
+		default:
+			nodes = append(nodes, out)
+		}
+	}
+	return groups, nodes, builtins
+}
+
+// joinSingBoxOutbounds 按 代理组 -> 节点 -> 内置出站 的顺序组合 sing-box 出站
+func joinSingBoxOutbounds(groups, nodes, builtins []SBOutbound) []SBOutbound {
+	result := make([]SBOutbound, 0, len(groups)+len(nodes)+len(builtins))
+	result = append(result, groups...)
+	result = append(result, nodes...)
+	return append(result, builtins...)
+}
+
+// proxyGroupMutator 按模板生成代理组并用节点过滤条件填充成员
+type proxyGroupMutator struct {
+	mihomo  *ConfigGenerator
+	singbox *SingboxGenerator
+}
+
+func (proxyGroupMutator) Name() string       { return "proxy-groups" }
+func (proxyGroupMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (m proxyGroupMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		template := build.Options.Template
+		if template == nil {
+			template = GetDefaultConfigTemplate()
+		}
+		build.Mihomo.ProxyGroups = m.mihomo.generateProxyGroupsFromTemplate(build.Nodes, template.ProxyGroups, NewNodeTagger(build.Options.NodeTags))
+	}
+	if build.SingBox != nil {
+		manual := make(map[string]bool)
+		for _, n := range build.Nodes {
+			if n.IsManual {
+				manual[n.Name] = true
+			}
+		}
+		groups, nodes, builtins := splitSingBoxOutbounds(build.SingBox.Outbounds)
+		// 手动节点名称按出站顺序收集（与 Mihomo 一致）
+		manualNodeNames := make([]string, 0)
+		for _, n := range nodes {
+			if manual[n.Tag] {
+				manualNodeNames = append(manualNodeNames, n.Tag)
+			}
+		}
+		groups = append(m.singbox.generateProxyGroupsV112(nodes, manualNodeNames, build.SingBoxOptions.GroupFilters), groups...)
+		build.SingBox.Outbounds = joinSingBoxOutbounds(groups, nodes, builtins)
+	}
+	return nil
+}
+
+// proxyChainMutator 链式代理，需要在代理组生成之后执行以便加入指定的代理组
+type proxyChainMutator struct{}
+
+func (proxyChainMutator) Name() string       { return "proxy-chains" }
+func (proxyChainMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (proxyChainMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		applyMihomoChains(build.Mihomo, build.Options.ProxyChains)
+	}
+	if build.SingBox != nil {
+		groups, nodes, builtins := splitSingBoxOutbounds(build.SingBox.Outbounds)
+		nodes, groups = applySingBoxChains(nodes, groups, build.SingBoxOptions.ProxyChains)
+		build.SingBox.Outbounds = joinSingBoxOutbounds(groups, nodes, builtins)
+	}
+	return nil
+}
+
+// egressMutator 出站标记和绑定网卡，在链式代理之后执行，经由前一跳连接的节点不设置
+type egressMutator struct{}
+
+func (egressMutator) Name() string       { return "egress" }
+func (egressMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (egressMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		applyMihomoEgress(build.Mihomo.Proxies, build.Mihomo.RoutingMark, build.Mihomo.InterfaceName)
+	}
+	if build.SingBox != nil {
+		applySingBoxEgress(build.SingBox, build.SingBoxOptions.RoutingMark, build.SingBoxOptions.InterfaceName)
+	}
+	return nil
+}
+
+// blocklistMutator 广告拦截规则
+type blocklistMutator struct{}
+
+func (blocklistMutator) Name() string       { return "blocklist" }
+func (blocklistMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (blocklistMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		applyMihomoBlocklist(build.Mihomo, build.Options.Blocklist)
+	}
+	if build.SingBox != nil {
+		applySingBoxBlocklist(build.SingBox, build.Options.Blocklist)
+	}
+	return nil
+}
+
+// devicePolicyMutator 设备策略规则，在广告拦截之后执行以保证设备规则优先匹配
+type devicePolicyMutator struct{}
+
+func (devicePolicyMutator) Name() string       { return "device-policy" }
+func (devicePolicyMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (devicePolicyMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		applyMihomoDeviceRules(build.Mihomo, build.Options.DevicePolicies)
+	}
+	if build.SingBox != nil {
+		applySingBoxDeviceRules(build.SingBox, build.Options.DevicePolicies)
+	}
+	return nil
+}
+
+// speedtestMutator 节点测速专用监听和隐藏代理组（仅 Mihomo）
+type speedtestMutator struct{}
+
+func (speedtestMutator) Name() string       { return "speedtest" }
+func (speedtestMutator) Stage() ConfigStage { return ConfigStageGenerated }
+
+func (speedtestMutator) Mutate(build *ConfigBuild) error {
+	if build.Mihomo != nil {
+		applyMihomoSpeedtest(build.Mihomo, build.Options.SpeedtestPort)
+	}
+	return nil
+}
+
+// configOverrideMutator 合并用户覆写，手动修改在重新生成后仍然保留
+type configOverrideMutator struct {
+	dataDir string
+}
+
+func (configOverrideMutator) Name() string       { return "override" }
+func (configOverrideMutator) Stage() ConfigStage { return ConfigStageRendered }
+
+func (m configOverrideMutator) Mutate(build *ConfigBuild) error {
+	var err error
+	if build.CoreType == "singbox" {
+		build.Content, err = applySingBoxOverrideFile(m.dataDir, build.Content)
+	} else {
+		build.Content, err = applyMihomoOverrideFile(m.dataDir, build.Content)
+	}
+	return err
+}
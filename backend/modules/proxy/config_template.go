@@ -13,17 +13,24 @@ type ProxyGroupTemplate struct {
 	Tolerance   int      `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
 	Lazy        bool     `json:"lazy,omitempty" yaml:"lazy,omitempty"`
 	Hidden      bool     `json:"hidden,omitempty" yaml:"hidden,omitempty"`
-	Filter      string   `json:"filter,omitempty" yaml:"filter,omitempty"` // 节点过滤正则
-	UseAll      bool     `json:"useAll,omitempty" yaml:"-"`                // 使用所有节点
+	Strategy    string   `json:"strategy,omitempty" yaml:"strategy,omitempty"` // load-balance 策略: consistent-hashing, round-robin, sticky-sessions
+	Filter      string   `json:"filter,omitempty" yaml:"filter,omitempty"`     // 节点过滤正则（包含）
+	UseAll      bool     `json:"useAll,omitempty" yaml:"-"`                    // 使用所有节点
+	Tags        []string `json:"tags,omitempty" yaml:"-"`                      // 按节点标签自动填充（如 HK、SG），带有任一标签的节点加入
+
+	ExcludeFilter string  `json:"excludeFilter,omitempty" yaml:"exclude-filter,omitempty"` // 排除节点的正则
+	MaxMultiplier float64 `json:"maxMultiplier,omitempty" yaml:"-"`                        // 排除倍率高于该值的节点（如 x3），0 表示不限制
 }
 
 // RuleTemplate 规则模板
 type RuleTemplate struct {
-	Type        string `json:"type"`        // DOMAIN, DOMAIN-SUFFIX, DOMAIN-KEYWORD, IP-CIDR, GEOIP, RULE-SET, MATCH
-	Payload     string `json:"payload"`     // 规则内容
-	Proxy       string `json:"proxy"`       // 代理组名称
-	NoResolve   bool   `json:"noResolve"`   // 不解析域名
-	Description string `json:"description"` // 说明
+	ID          string `json:"id,omitempty"`       // 规则 ID，用于单条编辑
+	Type        string `json:"type"`               // DOMAIN, DOMAIN-SUFFIX, DOMAIN-KEYWORD, IP-CIDR, GEOIP, RULE-SET, MATCH
+	Payload     string `json:"payload"`            // 规则内容
+	Proxy       string `json:"proxy"`              // 代理组名称
+	NoResolve   bool   `json:"noResolve"`          // 不解析域名
+	Description string `json:"description"`        // 说明
+	Disabled    bool   `json:"disabled,omitempty"` // 禁用后不生成到配置中
 }
 
 // RuleProviderTemplate 规则提供者模板
@@ -43,6 +50,23 @@ type ConfigTemplate struct {
 	ProxyGroups   []ProxyGroupTemplate   `json:"proxyGroups"`
 	Rules         []RuleTemplate         `json:"rules"`
 	RuleProviders []RuleProviderTemplate `json:"ruleProviders"`
+	DNS           *DNSTemplate           `json:"dns,omitempty"`
+}
+
+// DNSTemplate 模板 DNS 设置，非空字段覆盖生成配置中的默认值
+type DNSTemplate struct {
+	DefaultNameserver     []string            `json:"defaultNameserver,omitempty"`
+	Nameserver            []string            `json:"nameserver,omitempty"`
+	Fallback              []string            `json:"fallback,omitempty"`
+	ProxyServerNameserver []string            `json:"proxyServerNameserver,omitempty"`
+	DirectNameserver      []string            `json:"directNameserver,omitempty"`
+	NameserverPolicy      map[string][]string `json:"nameserverPolicy,omitempty"`
+	FakeIPRange           string              `json:"fakeIpRange,omitempty"`
+	FakeIPFilter          []string            `json:"fakeIpFilter,omitempty"`
+	FakeIPFilterMode      string              `json:"fakeIpFilterMode,omitempty"` // blacklist（默认）/ whitelist
+	FakeIPFilterPresets   []string            `json:"fakeIpFilterPresets"`        // 启用的内置分类，nil 表示 FakeIPFilter 完全替换默认列表
+	Hosts                 map[string]string   `json:"hosts,omitempty"`            // 域名 → IP 或域名
+	RespectRules          *bool               `json:"respectRules,omitempty"`     // 未设置时使用默认值（开启）
 }
 
 // GetDefaultProxyGroups 获取默认代理组
@@ -227,7 +251,7 @@ func GetDefaultProxyGroups() []ProxyGroupTemplate {
 			Interval:    300,
 			Tolerance:   50,
 			Lazy:        true,
-			Filter:      "(?i)香港|沪港|呼港|中港|HKT|HKBN|HGC|WTT|CMI|穗港|广港|京港|🇭🇰|HK|Hongkong|Hong Kong|HongKong|HONG KONG",
+			Tags:        []string{"HK"},
 			UseAll:      true,
 		},
 		// 18. 台湾节点
@@ -241,7 +265,7 @@ func GetDefaultProxyGroups() []ProxyGroupTemplate {
 			Interval:    300,
 			Tolerance:   50,
 			Lazy:        true,
-			Filter:      "(?i)台湾|台灣|臺灣|台北|台中|新北|彰化|CHT|HINET|🇨🇳|🇹🇼|TW|Taiwan|TAIWAN",
+			Tags:        []string{"TW"},
 			UseAll:      true,
 		},
 		// 19. 日本节点
@@ -255,7 +279,7 @@ func GetDefaultProxyGroups() []ProxyGroupTemplate {
 			Interval:    300,
 			Tolerance:   50,
 			Lazy:        true,
-			Filter:      "(?i)日本|东京|東京|大阪|埼玉|京日|苏日|沪日|广日|上日|穗日|川日|中日|泉日|杭日|深日|🇯🇵|JP|Japan|JAPAN",
+			Tags:        []string{"JP"},
 			UseAll:      true,
 		},
 		// 20. 新加坡节点
@@ -269,7 +293,7 @@ func GetDefaultProxyGroups() []ProxyGroupTemplate {
 			Interval:    300,
 			Tolerance:   50,
 			Lazy:        true,
-			Filter:      "(?i)新加坡|狮城|獅城|沪新|京新|泉新|穗新|深新|杭新|广新|廣新|滬新|🇸🇬|SG|Singapore|SINGAPORE",
+			Tags:        []string{"SG"},
 			UseAll:      true,
 		},
 		// 21. 美国节点
@@ -283,7 +307,7 @@ func GetDefaultProxyGroups() []ProxyGroupTemplate {
 			Interval:    300,
 			Tolerance:   50,
 			Lazy:        true,
-			Filter:      "(?i)美国|美國|京美|硅谷|凤凰城|洛杉矶|西雅图|圣何塞|芝加哥|哥伦布|纽约|广美|🇺🇸|US|USA|America|United States",
+			Tags:        []string{"US"},
 			UseAll:      true,
 		},
 		// 22. 手动节点
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigValidationError 配置校验错误
+type ConfigValidationError struct {
+	Line    int    `json:"line,omitempty"` // 出错行号（无法定位时为 0）
+	Message string `json:"message"`
+}
+
+// ConfigValidationResult 配置校验结果
+type ConfigValidationResult struct {
+	Valid   bool                    `json:"valid"`
+	Checker string                  `json:"checker"` // mihomo（核心 -t 校验）或 yaml（仅语法校验）
+	Errors  []ConfigValidationError `json:"errors"`
+	Output  string                  `json:"output,omitempty"`
+}
+
+// yamlLinePattern 匹配 yaml / mihomo 错误信息中的行号
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// mihomoCorePath 获取 Mihomo 核心路径（不存在时返回空）
+func (s *Service) mihomoCorePath() string {
+	binName := fmt.Sprintf("mihomo-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		binName += ".exe"
+	}
+	path := filepath.Join(s.dataDir, "cores", binName)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// ValidateMihomoConfig 校验 Mihomo 配置文件
+// 先做 YAML 语法检查，核心存在时再运行 mihomo -t 做完整校验
+func (s *Service) ValidateMihomoConfig(configPath string) (*ConfigValidationResult, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConfigValidationResult{Checker: "yaml", Errors: []ConfigValidationError{}}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		result.Errors = append(result.Errors, parseValidationErrors(err.Error())...)
+		return result, nil
+	}
+
+	corePath := s.mihomoCorePath()
+	if corePath == "" {
+		result.Valid = true
+		return result, nil
+	}
+
+	result.Checker = "mihomo"
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, corePath, "-t", "-d", s.dataDir, "-f", configPath).CombinedOutput()
+	result.Output = strings.TrimSpace(string(output))
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("mihomo 校验超时")
+	}
+	if err != nil {
+		message := result.Output
+		if message == "" {
+			message = err.Error()
+		}
+		result.Errors = append(result.Errors, parseValidationErrors(message)...)
+		return result, nil
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// parseValidationErrors 将校验输出拆分为带行号的错误列表
+func parseValidationErrors(output string) []ConfigValidationError {
+	var items []ConfigValidationError
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// mihomo -t 成功时的提示行和测试结果行不作为错误
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "test is successful") || strings.Contains(lower, "test failed") {
+			continue
+		}
+
+		item := ConfigValidationError{Message: line}
+		if m := yamlLinePattern.FindStringSubmatch(line); m != nil {
+			item.Line, _ = strconv.Atoi(m[1])
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 && output != "" {
+		items = append(items, ConfigValidationError{Message: strings.TrimSpace(output)})
+	}
+	return items
+}
+
+// ValidateConfig 校验 Mihomo 配置
+// 请求体可选 content（校验提交的内容），为空时校验当前生成的 config.yaml
+func (h *Handler) ValidateConfig(c *gin.Context) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	configPath := filepath.Join(h.service.dataDir, "configs", "config.yaml")
+	if req.Content != "" {
+		// 写入临时文件供 mihomo -t 读取，放在配置目录下以便解析相对路径
+		configsDir := filepath.Join(h.service.dataDir, "configs")
+		os.MkdirAll(configsDir, 0755)
+		tmpFile, err := os.CreateTemp(configsDir, "validate-*.yaml")
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		defer os.Remove(tmpFile.Name())
+		_, err = tmpFile.WriteString(req.Content)
+		tmpFile.Close()
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		configPath = tmpFile.Name()
+	} else if _, err := os.Stat(configPath); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "配置文件未生成，请先生成配置")
+		return
+	}
+
+	result, err := h.service.ValidateMihomoConfig(configPath)
+	if err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "配置校验失败: "+err.Error())
+		return
+	}
+
+	if !result.Valid {
+		// 与 sing-box 生成接口一致，校验失败返回 code 2
+		apierror.Respond(c, http.StatusOK, apierror.New(apierror.CodeValidation, "配置验证失败").WithDetails(result))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetGeoResolver 设置 IP 归属查询（由 geodata 模块注入）
+func (s *Service) SetGeoResolver(resolver func(ip string) *GeoInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.geoResolver = resolver
+}
+
+// resolveConnectionGeo 为连接补充目标地址的国家 / ASN（同一地址只查询一次）
+// 核心未解析目标域名时（如 fake-ip 下走代理的连接）没有目标 IP，不做补充
+func (s *Service) resolveConnectionGeo(conns []Connection) {
+	s.mu.RLock()
+	resolver := s.geoResolver
+	s.mu.RUnlock()
+	if resolver == nil {
+		return
+	}
+
+	cache := make(map[string]*GeoInfo)
+	for i := range conns {
+		ip := conns[i].Metadata.DestinationIP
+		if ip == "" {
+			continue
+		}
+		info, ok := cache[ip]
+		if !ok {
+			info = resolver(ip)
+			cache[ip] = info
+		}
+		conns[i].Geo = info
+	}
+}
+
+// filterConnectionsByGeo 按目标国家或 ASN 过滤连接
+func filterConnectionsByGeo(conns []Connection, country, asn string) []Connection {
+	asnNum, _ := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 32)
+	result := make([]Connection, 0)
+	for _, conn := range conns {
+		if conn.Geo == nil {
+			continue
+		}
+		if country != "" && !strings.EqualFold(conn.Geo.Country, country) {
+			continue
+		}
+		if asn != "" && conn.Geo.ASN != uint(asnNum) {
+			continue
+		}
+		result = append(result, conn)
+	}
+	return result
+}
@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 连接相关类型与核心 API 返回一致
+type (
+	ConnectionMetadata  = clashapi.ConnectionMetadata
+	Connection          = clashapi.Connection
+	ConnectionsSnapshot = clashapi.ConnectionsSnapshot
+	GeoInfo             = clashapi.GeoInfo
+)
+
+// fetchConnections 从核心 API 获取当前连接列表
+func (h *Handler) fetchConnections(ctx context.Context) (*ConnectionsSnapshot, error) {
+	return h.clashAPI().Connections(ctx)
+}
+
+// GetConnections 获取活动连接列表（Linux 下为本机发起的连接补充进程信息）
+// 参数: country 只返回目标位于该国家的连接（ISO 代码），asn 只返回目标属于该 ASN 的连接
+func (h *Handler) GetConnections(c *gin.Context) {
+	snapshot, err := h.fetchConnections(c.Request.Context())
+	if err != nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "获取连接列表失败: "+err.Error())
+		return
+	}
+
+	if runtime.GOOS == "linux" {
+		resolveConnectionProcesses(snapshot.Connections)
+	}
+	h.service.resolveConnectionGeo(snapshot.Connections)
+	if country, asn := c.Query("country"), c.Query("asn"); country != "" || asn != "" {
+		snapshot.Connections = filterConnectionsByGeo(snapshot.Connections, country, asn)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    snapshot,
+	})
+}
+
+// CloseConnection 关闭指定连接
+func (h *Handler) CloseConnection(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.clashAPI().CloseConnection(c.Request.Context(), id); err != nil {
+		status := http.StatusServiceUnavailable
+		var apiErr *clashapi.APIError
+		if errors.As(err, &apiErr) {
+			status = http.StatusBadGateway
+		}
+		apierror.Message(c, status, "关闭连接失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// socketKey 本地 socket 标识（协议 + 端口）
+type socketKey struct {
+	network string
+	port    int
+}
+
+// socketEntry /proc/net 中的一条 socket 记录
+type socketEntry struct {
+	ip    net.IP
+	inode string
+}
+
+// resolveConnectionProcesses 通过 /proc 为核心未识别进程的连接补充进程名
+// 仅能识别本机发起的连接，局域网设备的连接保持原样
+func resolveConnectionProcesses(conns []Connection) {
+	var sockets map[socketKey][]socketEntry
+	var owners map[string]int
+
+	for i := range conns {
+		meta := &conns[i].Metadata
+		if meta.Process != "" {
+			continue
+		}
+		port, err := strconv.Atoi(meta.SourcePort)
+		if err != nil {
+			continue
+		}
+		srcIP := net.ParseIP(meta.SourceIP)
+		if srcIP == nil {
+			continue
+		}
+
+		// 按需读取，没有需要解析的连接时不扫描 /proc
+		if sockets == nil {
+			sockets = readProcSockets()
+			owners = readSocketOwners()
+		}
+
+		inode := ""
+		for _, entry := range sockets[socketKey{network: strings.ToLower(meta.Network), port: port}] {
+			if entry.ip.Equal(srcIP) || entry.ip.IsUnspecified() {
+				inode = entry.inode
+				break
+			}
+		}
+		pid, ok := owners[inode]
+		if inode == "" || !ok {
+			continue
+		}
+
+		conns[i].PID = pid
+		if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+			meta.Process = strings.TrimSpace(string(comm))
+		}
+		if exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+			meta.ProcessPath = exe
+		}
+	}
+}
+
+// readProcSockets 读取 /proc/net/{tcp,tcp6,udp,udp6}，按协议和本地端口索引
+func readProcSockets() map[socketKey][]socketEntry {
+	result := make(map[socketKey][]socketEntry)
+	for _, file := range []struct {
+		path    string
+		network string
+	}{
+		{"/proc/net/tcp", "tcp"},
+		{"/proc/net/tcp6", "tcp"},
+		{"/proc/net/udp", "udp"},
+		{"/proc/net/udp6", "udp"},
+	} {
+		f, err := os.Open(file.path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // 跳过表头
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 {
+				continue
+			}
+			ip, port, ok := parseProcAddr(fields[1])
+			if !ok || fields[9] == "0" {
+				continue
+			}
+			key := socketKey{network: file.network, port: port}
+			result[key] = append(result[key], socketEntry{ip: ip, inode: fields[9]})
+		}
+		f.Close()
+	}
+	return result
+}
+
+// parseProcAddr 解析 /proc/net 中的地址，如 0100007F:1F90
+// 地址按 32 位字存储，每个字为主机字节序（小端）
+func parseProcAddr(s string) (net.IP, int, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseInt(parts[1], 16, 32)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, int(port), true
+}
+
+// readSocketOwners 扫描 /proc/*/fd，建立 socket inode 到进程 ID 的映射
+func readSocketOwners() map[string]int {
+	result := make(map[string]int)
+	fdDirs, _ := filepath.Glob("/proc/[0-9]*/fd")
+	for _, dir := range fdDirs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(dir)))
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			link, err := os.Readlink(filepath.Join(dir, entry.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if _, exists := result[inode]; !exists {
+				result[inode] = pid
+			}
+		}
+	}
+	return result
+}
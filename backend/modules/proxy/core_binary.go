@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// binaryPlatform 可执行文件的目标平台（GOOS/GOARCH 命名）
+type binaryPlatform struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (p binaryPlatform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// readBinaryPlatforms 读取 ELF / Mach-O / PE 文件头，返回可执行文件支持的平台
+// macOS 通用二进制可能包含多个架构；脚本文件返回 nil
+func readBinaryPlatforms(path string) ([]binaryPlatform, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = file.Read(magic)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("文件为空或不完整")
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("#!")):
+		return nil, nil
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		return elfPlatforms(path)
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return pePlatforms(path)
+	}
+	if fat, err := macho.OpenFat(path); err == nil {
+		defer fat.Close()
+		var platforms []binaryPlatform
+		for _, arch := range fat.Arches {
+			platforms = append(platforms, binaryPlatform{"darwin", machoArch(arch.Cpu)})
+		}
+		return platforms, nil
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return []binaryPlatform{{"darwin", machoArch(f.Cpu)}}, nil
+	}
+	return nil, fmt.Errorf("无法识别的可执行文件格式")
+}
+
+func elfPlatforms(path string) ([]binaryPlatform, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	goos := "linux"
+	switch f.OSABI {
+	case elf.ELFOSABI_FREEBSD:
+		goos = "freebsd"
+	case elf.ELFOSABI_OPENBSD:
+		goos = "openbsd"
+	case elf.ELFOSABI_NETBSD:
+		goos = "netbsd"
+	}
+
+	little := f.ByteOrder == binary.LittleEndian
+	is64 := f.Class == elf.ELFCLASS64
+	var arch string
+	switch f.Machine {
+	case elf.EM_X86_64:
+		arch = "amd64"
+	case elf.EM_386:
+		arch = "386"
+	case elf.EM_AARCH64:
+		arch = "arm64"
+	case elf.EM_ARM:
+		arch = "arm"
+	case elf.EM_MIPS:
+		arch = "mips"
+		if is64 {
+			arch = "mips64"
+		}
+		if little {
+			arch += "le"
+		}
+	case elf.EM_RISCV:
+		arch = "riscv64"
+	case elf.EM_PPC64:
+		arch = "ppc64"
+		if little {
+			arch += "le"
+		}
+	case elf.EM_S390:
+		arch = "s390x"
+	case elf.EM_LOONGARCH:
+		arch = "loong64"
+	default:
+		arch = strings.ToLower(strings.TrimPrefix(f.Machine.String(), "EM_"))
+	}
+	return []binaryPlatform{{goos, arch}}, nil
+}
+
+func pePlatforms(path string) ([]binaryPlatform, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var arch string
+	switch f.Machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		arch = "amd64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		arch = "386"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		arch = "arm64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		arch = "arm"
+	default:
+		arch = fmt.Sprintf("0x%x", f.Machine)
+	}
+	return []binaryPlatform{{"windows", arch}}, nil
+}
+
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuArm:
+		return "arm"
+	}
+	return strings.ToLower(cpu.String())
+}
+
+// platformCompatible 可执行文件能否在当前系统运行
+// amd64 可以运行 386 程序；Apple Silicon 可以通过 Rosetta 运行 amd64 程序
+func platformCompatible(p binaryPlatform) bool {
+	if p.GOOS != runtime.GOOS {
+		return false
+	}
+	if p.GOARCH == runtime.GOARCH {
+		return true
+	}
+	switch runtime.GOARCH {
+	case "amd64":
+		return p.GOARCH == "386"
+	case "arm64":
+		return runtime.GOOS == "darwin" && p.GOARCH == "amd64"
+	}
+	return false
+}
+
+// checkCoreBinary 启动前检查核心文件：可执行权限以及架构与当前系统是否匹配
+// 返回明确的错误信息（如在 arm64 路由器上下载了 amd64 核心），而不是启动时的 exec format error
+func checkCoreBinary(path string) error {
+	name := filepath.Base(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("核心文件 %s 不可访问: %w", name, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("核心路径 %s 是目录", name)
+	}
+
+	platforms, err := readBinaryPlatforms(path)
+	if err != nil {
+		return fmt.Errorf("核心文件 %s 已损坏或下载不完整（%v），请重新下载核心", name, err)
+	}
+	if len(platforms) > 0 {
+		compatible := false
+		names := make([]string, 0, len(platforms))
+		for _, p := range platforms {
+			names = append(names, p.String())
+			if platformCompatible(p) {
+				compatible = true
+			}
+		}
+		if !compatible {
+			return fmt.Errorf("核心文件 %s 是 %s 程序，无法在当前系统 (%s/%s) 运行，请重新下载 %s/%s 版本的核心",
+				name, strings.Join(names, ", "), runtime.GOOS, runtime.GOARCH, runtime.GOOS, runtime.GOARCH)
+		}
+	}
+
+	// Windows 不使用可执行权限位
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		if err := os.Chmod(path, info.Mode()|0755); err != nil {
+			return fmt.Errorf("核心文件 %s 没有执行权限，且无法修改: %w", name, err)
+		}
+		fmt.Printf("🔧 已为核心文件 %s 添加执行权限\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"ProxyStation/backend/apierror"
+)
+
+// 核心状态错误，handler 据此返回对应的错误码
+var (
+	ErrCoreRunning      = apierror.New(apierror.CodeCoreRunning, "proxy is already running")
+	ErrCoreNotRunning   = apierror.New(apierror.CodeCoreNotRunning, "代理未运行")
+	ErrCoreNotInstalled = apierror.New(apierror.CodeCoreNotInstalled, "核心文件未找到，请先下载核心")
+)
+
+// CoreRunner 核心运行方式抽象，屏蔽 Mihomo 与 Sing-Box 在启动参数、配置文件和热重载上的差异
+type CoreRunner interface {
+	// Type 核心类型标识（mihomo / singbox）
+	Type() string
+	// BinaryPrefix 核心文件名前缀，用于在 cores 目录中查找
+	BinaryPrefix() string
+	// ConfigPath 当前生效的配置文件路径
+	ConfigPath(dataDir string) string
+	// Command 构建启动命令
+	Command(corePath, dataDir, configPath string) *exec.Cmd
+	// VersionArgs 查询版本的命令参数
+	VersionArgs() []string
+	// SupportsSignalReload 是否支持通过 SIGHUP 重新加载配置
+	SupportsSignalReload() bool
+}
+
+// mihomoRunner Mihomo 核心
+type mihomoRunner struct{}
+
+func (mihomoRunner) Type() string         { return "mihomo" }
+func (mihomoRunner) BinaryPrefix() string { return "mihomo" }
+
+func (mihomoRunner) ConfigPath(dataDir string) string {
+	return filepath.Join(dataDir, "configs", "config.yaml")
+}
+
+// Command Mihomo: -d <workdir> -f <config>
+func (mihomoRunner) Command(corePath, dataDir, configPath string) *exec.Cmd {
+	return exec.Command(corePath, "-d", dataDir, "-f", configPath)
+}
+
+func (mihomoRunner) VersionArgs() []string { return []string{"-v"} }
+
+// SupportsSignalReload Mihomo 通过 API 热重载（PUT /configs）
+func (mihomoRunner) SupportsSignalReload() bool { return false }
+
+// singboxRunner Sing-Box 核心
+type singboxRunner struct{}
+
+func (singboxRunner) Type() string         { return "singbox" }
+func (singboxRunner) BinaryPrefix() string { return "sing-box" }
+
+func (singboxRunner) ConfigPath(dataDir string) string {
+	return filepath.Join(dataDir, "configs", "singbox-config.json")
+}
+
+// Command Sing-Box: run -D <workdir> -c <config>
+func (singboxRunner) Command(corePath, dataDir, configPath string) *exec.Cmd {
+	cmd := exec.Command(corePath, "run", "-D", dataDir, "-c", configPath)
+	// 启用已弃用的特殊出站（direct），代理组需要引用"直连"
+	cmd.Env = append(os.Environ(), "ENABLE_DEPRECATED_SPECIAL_OUTBOUNDS=true")
+	return cmd
+}
+
+func (singboxRunner) VersionArgs() []string { return []string{"version"} }
+
+// SupportsSignalReload sing-box run 收到 SIGHUP 时重新加载配置
+func (singboxRunner) SupportsSignalReload() bool { return runtime.GOOS != "windows" }
+
+// coreRunners 已支持的核心
+var coreRunners = map[string]CoreRunner{
+	"mihomo":  mihomoRunner{},
+	"singbox": singboxRunner{},
+}
+
+// runnerFor 获取核心类型对应的运行方式，未知类型按 Mihomo 处理
+func runnerFor(coreType string) CoreRunner {
+	if r, ok := coreRunners[coreType]; ok {
+		return r
+	}
+	return coreRunners["mihomo"]
+}
+
+// runner 当前核心的运行方式（调用方需持有 mu 或确保 coreType 不会并发修改）
+func (s *Service) runner() CoreRunner {
+	return runnerFor(s.coreType)
+}
+
+// coreVersion 执行核心版本命令，返回解析出的版本号
+func coreVersion(r CoreRunner, corePath string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, corePath, r.VersionArgs()...).Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return parseCoreVersion(line)
+}
+
+// ReloadBySignal 向运行中的核心发送 SIGHUP 重新加载配置（仅支持的核心）
+func (s *Service) ReloadBySignal() error {
+	s.mu.RLock()
+	r := s.runner()
+	cmd := s.process
+	s.mu.RUnlock()
+
+	if !r.SupportsSignalReload() {
+		return fmt.Errorf("%s 不支持信号重载", r.Type())
+	}
+	if cmd == nil || cmd.Process == nil {
+		return ErrCoreNotRunning
+	}
+	if err := cmd.Process.Signal(syscall.SIGHUP); err != nil {
+		return err
+	}
+
+	// 配置有误时核心会直接退出，稍等确认仍在运行
+	time.Sleep(time.Second)
+	if !s.GetStatus().Running {
+		return fmt.Errorf("核心重载配置后退出")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Linux capability 编号（见 linux/capability.h）
+const (
+	capNetBindService = 10
+	capNetAdmin       = 12
+)
+
+// coreCapabilities 核心以普通用户运行时保留的能力：
+// CAP_NET_ADMIN 用于 TUN、routing-mark 和 TPROXY，CAP_NET_BIND_SERVICE 用于监听 53 等低端口
+var coreCapabilities = []uintptr{capNetAdmin, capNetBindService}
+
+// coreDataEntries 核心需要读写的数据目录内容（其余文件保持 root 所有）
+var coreDataEntries = []string{"configs", "runtime", "ruleset", "singbox", "providers", "ui"}
+
+// applyCoreUser 让核心以指定用户运行，只保留网络相关能力
+func applyCoreUser(cmd *exec.Cmd, username, corePath, dataDir string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("以指定用户运行核心需要 ProxyStation 以 root 身份运行")
+	}
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %s", username)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("无效的 UID: %s", u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("无效的 GID: %s", u.Gid)
+	}
+	if uid == 0 {
+		return fmt.Errorf("不能指定 root 用户")
+	}
+
+	if err := grantCoreDataAccess(dataDir, int(uid), int(gid)); err != nil {
+		return fmt.Errorf("设置数据目录权限失败: %w", err)
+	}
+
+	// 为核心文件设置 file capabilities，即使 ambient 能力不可用也能正常工作
+	if output, err := exec.Command("setcap", "cap_net_admin,cap_net_bind_service+ep", corePath).CombinedOutput(); err != nil {
+		fmt.Printf("⚠️ setcap 失败，仅依赖 ambient 能力: %v %s\n", err, strings.TrimSpace(string(output)))
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:         uint32(uid),
+			Gid:         uint32(gid),
+			NoSetGroups: true,
+		},
+		AmbientCaps: coreCapabilities,
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "HOME="+dataDir, "USER="+username)
+	fmt.Printf("👤 核心将以用户 %s (uid=%d) 运行\n", username, uid)
+	return nil
+}
+
+// grantCoreDataAccess 将核心使用的目录和 GEO 数据交给核心用户
+// 数据目录本身允许核心用户写入，以便创建 cache.db 等缓存文件
+func grantCoreDataAccess(dataDir string, uid, gid int) error {
+	if err := os.Chown(dataDir, uid, gid); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !isCoreDataEntry(name, entry.IsDir()) {
+			continue
+		}
+		err := filepath.Walk(filepath.Join(dataDir, name), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isCoreDataEntry(name string, isDir bool) bool {
+	if isDir {
+		for _, d := range coreDataEntries {
+			if name == d {
+				return true
+			}
+		}
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".dat", ".mmdb", ".metadb", ".db":
+		return true
+	}
+	return false
+}
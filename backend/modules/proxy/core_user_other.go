@@ -0,0 +1,14 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"os/exec"
+
+	"ProxyStation/backend/apierror"
+)
+
+// applyCoreUser 非 Linux 不支持切换核心运行用户
+func applyCoreUser(cmd *exec.Cmd, username, corePath, dataDir string) error {
+	return apierror.New(apierror.CodeUnsupported, "以指定用户运行核心仅支持 Linux")
+}
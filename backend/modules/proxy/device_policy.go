@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设备策略类型
+const (
+	DevicePolicyDirect = "direct" // 直连（nftables 层面不进入代理）
+	DevicePolicyProxy  = "proxy"  // 走默认规则
+	DevicePolicyGroup  = "group"  // 走指定代理组
+)
+
+// DevicePolicy 路由器模式下的单设备策略
+type DevicePolicy struct {
+	Name    string `json:"name"`            // 设备名称（仅用于展示）
+	IP      string `json:"ip,omitempty"`    // 设备 IP
+	MAC     string `json:"mac,omitempty"`   // 设备 MAC
+	Policy  string `json:"policy"`          // direct / proxy / group
+	Group   string `json:"group,omitempty"` // policy 为 group 时使用的代理组
+	Enabled bool   `json:"enabled"`
+}
+
+// normalizeDevicePolicies 校验并规范化设备策略表
+func normalizeDevicePolicies(policies []DevicePolicy) ([]DevicePolicy, error) {
+	result := make([]DevicePolicy, 0, len(policies))
+	for i, p := range policies {
+		p.Name = strings.TrimSpace(p.Name)
+		p.IP = strings.TrimSpace(p.IP)
+		p.MAC = strings.TrimSpace(p.MAC)
+		p.Group = strings.TrimSpace(p.Group)
+
+		if p.IP == "" && p.MAC == "" {
+			return nil, fmt.Errorf("第 %d 条策略缺少 IP 或 MAC", i+1)
+		}
+		if p.IP != "" {
+			ip := net.ParseIP(p.IP)
+			if ip == nil {
+				return nil, fmt.Errorf("第 %d 条策略 IP 无效: %s", i+1, p.IP)
+			}
+			p.IP = ip.String()
+		}
+		if p.MAC != "" {
+			mac, err := net.ParseMAC(p.MAC)
+			if err != nil || len(mac) != 6 {
+				return nil, fmt.Errorf("第 %d 条策略 MAC 无效: %s", i+1, p.MAC)
+			}
+			p.MAC = mac.String()
+		}
+
+		switch p.Policy {
+		case DevicePolicyDirect, DevicePolicyProxy:
+			p.Group = ""
+		case DevicePolicyGroup:
+			if p.Group == "" {
+				return nil, fmt.Errorf("第 %d 条策略未指定代理组", i+1)
+			}
+			// 核心规则只能按源 IP 匹配，只填写 MAC 时生成配置时从邻居表查询 IP
+		default:
+			return nil, fmt.Errorf("第 %d 条策略类型无效: %s", i+1, p.Policy)
+		}
+
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// sourceCIDR 返回设备 IP 对应的单地址 CIDR
+func (p DevicePolicy) sourceCIDR() string {
+	if ip := net.ParseIP(p.IP); ip != nil && ip.To4() == nil {
+		return p.IP + "/128"
+	}
+	return p.IP + "/32"
+}
+
+// directDeviceBypass 直连设备在 nftables 中与绕过列表一起处理
+func directDeviceBypass(policies []DevicePolicy) TransparentBypass {
+	var bypass TransparentBypass
+	for _, p := range policies {
+		if !p.Enabled || p.Policy != DevicePolicyDirect {
+			continue
+		}
+		if p.IP != "" {
+			bypass.IPs = append(bypass.IPs, p.IP)
+		}
+		if p.MAC != "" {
+			bypass.MACs = append(bypass.MACs, p.MAC)
+		}
+	}
+	return bypass
+}
+
+// transparentBypassWithDevices 合并绕过列表和直连设备
+func (h *Handler) transparentBypassWithDevices() TransparentBypass {
+	bypass := h.service.GetTransparentBypass()
+	direct := directDeviceBypass(h.service.GetDevicePolicies())
+	bypass.IPs = append(bypass.IPs, direct.IPs...)
+	bypass.MACs = append(bypass.MACs, direct.MACs...)
+	if merged, err := bypass.normalize(); err == nil {
+		return merged
+	}
+	return bypass
+}
+
+// buildMihomoDeviceRules 生成 mihomo 源地址规则（需放在规则列表最前面）
+func buildMihomoDeviceRules(policies []DevicePolicy) []string {
+	var rules []string
+	for _, p := range policies {
+		if !p.Enabled || p.IP == "" {
+			continue
+		}
+		switch p.Policy {
+		case DevicePolicyDirect:
+			rules = append(rules, fmt.Sprintf("SRC-IP-CIDR,%s,DIRECT", p.sourceCIDR()))
+		case DevicePolicyGroup:
+			rules = append(rules, fmt.Sprintf("SRC-IP-CIDR,%s,%s", p.sourceCIDR(), p.Group))
+		}
+	}
+	return rules
+}
+
+// buildSingBoxDeviceRules 生成 sing-box 源地址路由规则
+func buildSingBoxDeviceRules(policies []DevicePolicy) []SBRouteRule {
+	var rules []SBRouteRule
+	for _, p := range policies {
+		if !p.Enabled || p.IP == "" {
+			continue
+		}
+		switch p.Policy {
+		case DevicePolicyDirect:
+			rules = append(rules, SBRouteRule{SourceIPCIDR: []string{p.sourceCIDR()}, Action: "route", Outbound: "direct"})
+		case DevicePolicyGroup:
+			rules = append(rules, SBRouteRule{SourceIPCIDR: []string{p.sourceCIDR()}, Action: "route", Outbound: p.Group})
+		}
+	}
+	return rules
+}
+
+// applyMihomoDeviceRules 设备策略规则放在规则列表最前面，优先匹配
+func applyMihomoDeviceRules(config *MihomoConfig, policies []DevicePolicy) {
+	if deviceRules := buildMihomoDeviceRules(policies); len(deviceRules) > 0 {
+		config.Rules = append(deviceRules, config.Rules...)
+	}
+}
+
+// applySingBoxDeviceRules 设备策略规则放在 sniff / hijack-dns 之后、其他规则之前
+func applySingBoxDeviceRules(config *SingBoxConfig, policies []DevicePolicy) {
+	deviceRules := buildSingBoxDeviceRules(policies)
+	if len(deviceRules) == 0 || config.Route == nil {
+		return
+	}
+	insertAt := 2
+	if len(config.Route.Rules) < insertAt {
+		insertAt = len(config.Route.Rules)
+	}
+	rules := make([]SBRouteRule, 0, len(config.Route.Rules)+len(deviceRules))
+	rules = append(rules, config.Route.Rules[:insertAt]...)
+	rules = append(rules, deviceRules...)
+	rules = append(rules, config.Route.Rules[insertAt:]...)
+	config.Route.Rules = rules
+}
+
+// SetMACResolver 设置 MAC 地址查询（由 lan 模块注入）
+func (s *Service) SetMACResolver(resolver func(mac string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.macResolver = resolver
+}
+
+// resolveDevicePolicies 为只填写 MAC 的策略补充当前 IP，查询不到的策略不生成核心规则
+// 生成配置时可能持有 s.mu 锁，这里不再加锁
+func (s *Service) resolveDevicePolicies(policies []DevicePolicy) []DevicePolicy {
+	result := append([]DevicePolicy{}, policies...)
+	if s.macResolver == nil {
+		return result
+	}
+	for i, p := range result {
+		if !p.Enabled || p.IP != "" || p.MAC == "" {
+			continue
+		}
+		if ip := s.macResolver(p.MAC); ip != "" {
+			result[i].IP = ip
+		} else if p.Policy == DevicePolicyGroup {
+			fmt.Printf("⚠️ 设备 %s (%s) 不在线，代理组策略暂不生效\n", p.Name, p.MAC)
+		}
+	}
+	return result
+}
+
+// GetDevicePolicies 获取设备策略表
+func (s *Service) GetDevicePolicies() []DevicePolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]DevicePolicy{}, s.config.DevicePolicies...)
+}
+
+// SetDevicePolicies 设置设备策略表
+func (s *Service) SetDevicePolicies(policies []DevicePolicy) ([]DevicePolicy, error) {
+	normalized, err := normalizeDevicePolicies(policies)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.DevicePolicies = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetDevicePolicies 获取设备策略表
+func (h *Handler) GetDevicePolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetDevicePolicies(),
+	})
+}
+
+// SetDevicePolicies 更新设备策略表
+// 直连设备的 nftables 规则立即生效，代理组规则需重启核心后生效
+func (h *Handler) SetDevicePolicies(c *gin.Context) {
+	var req []DevicePolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	policies, err := h.service.SetDevicePolicies(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "设备策略已保存，但应用规则失败: "+err.Error()).WithDetails(policies))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"policies":        policies,
+			"restartRequired": h.service.GetStatus().Running,
+		},
+	})
+}
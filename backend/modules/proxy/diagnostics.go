@@ -0,0 +1,634 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// 诊断结果状态
+const (
+	DiagPass = "pass"
+	DiagWarn = "warn"
+	DiagFail = "fail"
+	DiagSkip = "skip"
+)
+
+const (
+	defaultDiagDomain  = "www.google.com"
+	defaultDiagEchoURL = "https://api.ipify.org"
+	defaultDiagUDPDNS  = "1.1.1.1:53"
+	diagTimeout        = 8 * time.Second
+)
+
+// fakeIPRange 核心 fake-ip 地址段
+var fakeIPRange = &net.IPNet{IP: net.IPv4(198, 18, 0, 0), Mask: net.CIDRMask(15, 32)}
+
+// DiagnosticCheck 单项检查结果
+type DiagnosticCheck struct {
+	ID      string                 `json:"id"`
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"` // pass, warn, fail, skip
+	Message string                 `json:"message"`
+	Detail  map[string]interface{} `json:"detail,omitempty"`
+	Latency int64                  `json:"latency"` // 毫秒
+}
+
+// DiagnosticReport 诊断报告
+type DiagnosticReport struct {
+	Time            string            `json:"time"`
+	Status          string            `json:"status"` // 所有检查中最差的结果
+	CoreType        string            `json:"coreType"`
+	CoreRunning     bool              `json:"coreRunning"`
+	TransparentMode string            `json:"transparentMode"`
+	ProxyScope      string            `json:"proxyScope"`
+	Checks          []DiagnosticCheck `json:"checks"`
+}
+
+// DiagnosticOptions 诊断参数
+type DiagnosticOptions struct {
+	Domain  string `json:"domain"`  // DNS 测试域名
+	EchoURL string `json:"echoUrl"` // 返回出口 IP 的地址（纯文本）
+	UDPDNS  string `json:"udpDns"`  // UDP 测试使用的 DNS 服务器
+}
+
+// RegisterDiagnosticsRoutes 注册诊断路由
+func (h *Handler) RegisterDiagnosticsRoutes(r *gin.RouterGroup) {
+	r.POST("/diagnostics/run", h.RunDiagnostics)
+	r.GET("/system/capabilities", h.GetCapabilities)
+}
+
+// RunDiagnostics 运行连通性诊断，帮助排查"已启动但没有走代理"的问题
+func (h *Handler) RunDiagnostics(c *gin.Context) {
+	var opts DiagnosticOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if opts.Domain == "" {
+		opts.Domain = defaultDiagDomain
+	}
+	if opts.EchoURL == "" {
+		opts.EchoURL = defaultDiagEchoURL
+	} else if u, err := url.Parse(opts.EchoURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		apierror.Message(c, http.StatusBadRequest, "echoUrl 必须是 http 或 https 地址")
+		return
+	}
+	if opts.UDPDNS == "" {
+		opts.UDPDNS = defaultDiagUDPDNS
+	} else if _, _, err := net.SplitHostPort(opts.UDPDNS); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "udpDns 格式应为 host:port")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.runDiagnostics(c.Request.Context(), opts),
+	})
+}
+
+// runDiagnostics 并发执行所有检查，结果按固定顺序返回
+func (h *Handler) runDiagnostics(ctx context.Context, opts DiagnosticOptions) *DiagnosticReport {
+	status := h.service.GetStatus()
+	config := h.service.GetConfig()
+	report := &DiagnosticReport{
+		Time:            time.Now().Format("2006-01-02 15:04:05"),
+		CoreType:        status.CoreType,
+		CoreRunning:     status.Running,
+		TransparentMode: status.TransparentMode,
+		ProxyScope:      status.ProxyScope,
+	}
+	proxyAddr := fmt.Sprintf("127.0.0.1:%d", config.MixedPort)
+
+	var directIP, proxyIP string
+	checks := []func() DiagnosticCheck{
+		func() DiagnosticCheck { return diagCore(status) },
+		func() DiagnosticCheck { return diagSystemDNS(ctx, opts.Domain, status) },
+		func() DiagnosticCheck { return h.diagCoreDNS(ctx, opts.Domain, status) },
+		func() DiagnosticCheck {
+			check := diagEgressIP(ctx, "ip_direct", "直连出口 IP", opts.EchoURL, "")
+			directIP, _ = check.Detail["ip"].(string)
+			return check
+		},
+		func() DiagnosticCheck {
+			if !status.Running {
+				return skipCheck("ip_proxy", "代理出口 IP", "核心未运行")
+			}
+			check := diagEgressIP(ctx, "ip_proxy", "代理出口 IP", opts.EchoURL, "http://"+proxyAddr)
+			proxyIP, _ = check.Detail["ip"].(string)
+			return check
+		},
+		func() DiagnosticCheck { return diagUDPDirect(opts.UDPDNS, opts.Domain) },
+		func() DiagnosticCheck {
+			if !status.Running {
+				return skipCheck("udp_proxy", "UDP 代理", "核心未运行")
+			}
+			return diagUDPProxy(proxyAddr, opts.UDPDNS, opts.Domain)
+		},
+		func() DiagnosticCheck { return diagTProxySockopt(status.TransparentMode) },
+		func() DiagnosticCheck { return diagTUNDevice(status.TransparentMode) },
+		func() DiagnosticCheck { return diagIPForward(status.ProxyScope) },
+		func() DiagnosticCheck { return h.diagTransparentRules() },
+	}
+
+	results := make([]DiagnosticCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func() DiagnosticCheck) {
+			defer wg.Done()
+			start := time.Now()
+			results[i] = check()
+			results[i].Latency = time.Since(start).Milliseconds()
+		}(i, check)
+	}
+	wg.Wait()
+
+	results = append(results, diagCompareEgress(directIP, proxyIP, status))
+	report.Checks = results
+	report.Status = DiagPass
+	for _, r := range results {
+		if severity(r.Status) > severity(report.Status) {
+			report.Status = r.Status
+		}
+	}
+	return report
+}
+
+func severity(status string) int {
+	switch status {
+	case DiagFail:
+		return 2
+	case DiagWarn:
+		return 1
+	}
+	return 0
+}
+
+func skipCheck(id, name, message string) DiagnosticCheck {
+	return DiagnosticCheck{ID: id, Name: name, Status: DiagSkip, Message: message}
+}
+
+// diagCore 核心运行状态
+func diagCore(status *ProxyStatus) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "core", Name: "核心运行状态"}
+	if !status.Running {
+		check.Status = DiagFail
+		check.Message = "核心未运行"
+		return check
+	}
+	check.Status = DiagPass
+	check.Message = fmt.Sprintf("%s 已运行 %d 秒", status.CoreType, status.Uptime)
+	return check
+}
+
+// diagSystemDNS 通过系统解析器解析，判断 DNS 是否经过核心（fake-ip）
+func diagSystemDNS(ctx context.Context, domain string, status *ProxyStatus) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "dns_system", Name: "系统 DNS 解析", Detail: map[string]interface{}{}}
+	if servers := resolvConfServers(); len(servers) > 0 {
+		check.Detail["nameservers"] = servers
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = "解析失败: " + err.Error()
+		return check
+	}
+
+	ips := make([]string, 0, len(addrs))
+	fake := false
+	for _, a := range addrs {
+		ips = append(ips, a.IP.String())
+		if fakeIPRange.Contains(a.IP) {
+			fake = true
+		}
+	}
+	check.Detail["ips"] = ips
+	check.Detail["fakeIp"] = fake
+
+	check.Status = DiagPass
+	switch {
+	case fake:
+		check.Message = "系统 DNS 经过核心（返回 fake-ip）"
+	case status.Running && isTransparentMode(status.TransparentMode):
+		check.Status = DiagWarn
+		check.Message = "透明代理已开启，但系统 DNS 未返回 fake-ip，DNS 查询可能未经过核心（存在泄漏风险）"
+	default:
+		check.Message = "解析成功（未经过核心 DNS）"
+	}
+	return check
+}
+
+// resolvConfServers 读取 /etc/resolv.conf 中的 DNS 服务器
+func resolvConfServers() []string {
+	data, err := os.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		return nil
+	}
+	var servers []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// diagCoreDNS 直接向核心 DNS 监听发起查询
+func (h *Handler) diagCoreDNS(ctx context.Context, domain string, status *ProxyStatus) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "dns_core", Name: "核心 DNS"}
+	if !status.Running {
+		return skipCheck(check.ID, check.Name, "核心未运行")
+	}
+	if status.CoreType == "singbox" {
+		return skipCheck(check.ID, check.Name, "Sing-Box 未提供独立的 DNS 监听")
+	}
+
+	addr := h.service.coreDNSListen()
+	check.Detail = map[string]interface{}{"listen": addr}
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", addr)
+		},
+	}
+	ctx, cancel := context.WithTimeout(ctx, diagTimeout)
+	defer cancel()
+	ips, err := resolver.LookupHost(ctx, domain)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = fmt.Sprintf("向 %s 查询失败: %v", addr, err)
+		return check
+	}
+	check.Detail["ips"] = ips
+	check.Status = DiagPass
+	check.Message = "核心 DNS 正常响应"
+	return check
+}
+
+// coreDNSListen 从生成的 Mihomo 配置读取 DNS 监听地址，未配置时使用默认端口
+func (s *Service) coreDNSListen() string {
+	listen := fmt.Sprintf("0.0.0.0:%d", defaultDNSListenPort)
+	if data, err := os.ReadFile(runnerFor("mihomo").ConfigPath(s.dataDir)); err == nil {
+		var config struct {
+			DNS struct {
+				Listen string `yaml:"listen"`
+			} `yaml:"dns"`
+		}
+		if yaml.Unmarshal(data, &config) == nil && config.DNS.Listen != "" {
+			listen = config.DNS.Listen
+		}
+	}
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		port = strconv.Itoa(defaultDNSListenPort)
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// diagEgressIP 获取出口 IP，proxyURL 为空时不使用代理（透明代理开启时仍可能被拦截）
+func diagEgressIP(ctx context.Context, id, name, echoURL, proxyURL string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: id, Name: name, Detail: map[string]interface{}{}}
+
+	transport := &http.Transport{Proxy: nil}
+	if proxyURL != "" {
+		u, _ := url.Parse(proxyURL)
+		transport.Proxy = http.ProxyURL(u)
+		check.Detail["proxy"] = proxyURL
+	}
+	client := &http.Client{Transport: transport, Timeout: diagTimeout}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, echoURL, nil)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = err.Error()
+		return check
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = "请求失败: " + err.Error()
+		return check
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	ip := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || net.ParseIP(ip) == nil {
+		check.Status = DiagFail
+		check.Message = fmt.Sprintf("无法解析出口 IP (HTTP %d)", resp.StatusCode)
+		return check
+	}
+	check.Detail["ip"] = ip
+	check.Status = DiagPass
+	check.Message = ip
+	return check
+}
+
+// diagCompareEgress 比较直连和代理的出口 IP
+func diagCompareEgress(directIP, proxyIP string, status *ProxyStatus) DiagnosticCheck {
+	check := DiagnosticCheck{
+		ID:     "egress_compare",
+		Name:   "出口 IP 对比",
+		Detail: map[string]interface{}{"direct": directIP, "proxy": proxyIP},
+	}
+	if directIP == "" || proxyIP == "" {
+		return skipCheck(check.ID, check.Name, "缺少直连或代理出口 IP")
+	}
+
+	transparent := isTransparentMode(status.TransparentMode) || status.TransparentMode == "tun"
+	switch {
+	case directIP != proxyIP && !transparent:
+		check.Status = DiagPass
+		check.Message = "代理出口与直连出口不同，代理正常生效"
+	case directIP != proxyIP && transparent:
+		check.Status = DiagWarn
+		check.Message = "透明代理已开启，但未经代理的请求出口与代理出口不同，透明代理可能未拦截本机流量"
+	case transparent:
+		check.Status = DiagPass
+		check.Message = "未经代理的请求与代理出口一致，透明代理已拦截流量"
+	default:
+		check.Status = DiagWarn
+		check.Message = "代理出口与直连出口相同：测试地址可能匹配了直连规则，或选中的节点未生效"
+	}
+	return check
+}
+
+// diagUDPDirect 直接发送 UDP DNS 查询
+func diagUDPDirect(server, domain string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "udp_direct", Name: "UDP 直连", Detail: map[string]interface{}{"server": server}}
+
+	conn, err := net.DialTimeout("udp", server, diagTimeout)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = err.Error()
+		return check
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(diagTimeout))
+
+	query, id := buildDNSQuery(domain)
+	if _, err := conn.Write(query); err != nil {
+		check.Status = DiagFail
+		check.Message = err.Error()
+		return check
+	}
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		check.Status = DiagFail
+		check.Message = "未收到响应: " + err.Error()
+		return check
+	}
+	if err := checkDNSResponse(buf[:n], id); err != nil {
+		check.Status = DiagFail
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = DiagPass
+	check.Message = "UDP 查询成功"
+	return check
+}
+
+// diagUDPProxy 通过核心混合端口的 SOCKS5 UDP ASSOCIATE 发送 DNS 查询
+func diagUDPProxy(proxyAddr, server, domain string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "udp_proxy", Name: "UDP 代理", Detail: map[string]interface{}{"server": server, "proxy": proxyAddr}}
+	fail := func(format string, args ...interface{}) DiagnosticCheck {
+		check.Status = DiagFail
+		check.Message = fmt.Sprintf(format, args...)
+		return check
+	}
+
+	targetHost, targetPortStr, _ := net.SplitHostPort(server)
+	targetIP := net.ParseIP(targetHost).To4()
+	targetPort, _ := strconv.Atoi(targetPortStr)
+	if targetIP == nil {
+		return skipCheck(check.ID, check.Name, "仅支持 IPv4 DNS 服务器")
+	}
+
+	ctrl, err := net.DialTimeout("tcp", proxyAddr, diagTimeout)
+	if err != nil {
+		return fail("连接混合端口失败: %v", err)
+	}
+	defer ctrl.Close()
+	ctrl.SetDeadline(time.Now().Add(diagTimeout))
+
+	// 握手：无认证
+	if _, err := ctrl.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		return fail("SOCKS5 握手失败: %v", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(ctrl, reply); err != nil || reply[0] != 0x05 || reply[1] != 0x00 {
+		return fail("SOCKS5 握手失败")
+	}
+
+	// UDP ASSOCIATE
+	if _, err := ctrl.Write([]byte{0x05, 0x03, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		return fail("UDP ASSOCIATE 失败: %v", err)
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(ctrl, head); err != nil || head[1] != 0x00 {
+		return fail("核心拒绝 UDP ASSOCIATE，可能未开启 UDP")
+	}
+	var relayIP net.IP
+	switch head[3] {
+	case 0x01:
+		relayIP = make(net.IP, 4)
+		if _, err := io.ReadFull(ctrl, relayIP); err != nil {
+			return fail("读取中继地址失败: %v", err)
+		}
+	case 0x04:
+		relayIP = make(net.IP, 16)
+		if _, err := io.ReadFull(ctrl, relayIP); err != nil {
+			return fail("读取中继地址失败: %v", err)
+		}
+	default:
+		return fail("不支持的中继地址类型")
+	}
+	portBuf := make([]byte, 2)
+	if _, err := io.ReadFull(ctrl, portBuf); err != nil {
+		return fail("读取中继端口失败: %v", err)
+	}
+	if relayIP.IsUnspecified() {
+		relayIP = net.IPv4(127, 0, 0, 1)
+	}
+	relay := &net.UDPAddr{IP: relayIP, Port: int(binary.BigEndian.Uint16(portBuf))}
+
+	conn, err := net.DialUDP("udp", nil, relay)
+	if err != nil {
+		return fail("连接 UDP 中继失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(diagTimeout))
+
+	query, id := buildDNSQuery(domain)
+	packet := []byte{0, 0, 0, 0x01}
+	packet = append(packet, targetIP...)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(targetPort))
+	packet = append(packet, query...)
+	if _, err := conn.Write(packet); err != nil {
+		return fail("发送失败: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return fail("未收到响应，节点可能不支持 UDP: %v", err)
+	}
+	if n < 10 || buf[3] != 0x01 {
+		return fail("响应格式无效")
+	}
+	if err := checkDNSResponse(buf[10:n], id); err != nil {
+		return fail("%v", err)
+	}
+	check.Status = DiagPass
+	check.Message = "UDP 经代理转发成功"
+	return check
+}
+
+// buildDNSQuery 构造 A 记录查询报文
+func buildDNSQuery(domain string) ([]byte, uint16) {
+	id := uint16(rand.Intn(1 << 16))
+	msg := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	msg[2] = 0x01 // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, 0, 1, 0, 1) // 根标签, QTYPE=A, QCLASS=IN
+	return msg, id
+}
+
+// checkDNSResponse 校验响应 ID、返回码和应答数
+func checkDNSResponse(resp []byte, id uint16) error {
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp) != id {
+		return fmt.Errorf("响应格式无效")
+	}
+	if rcode := resp[3] & 0x0f; rcode != 0 {
+		return fmt.Errorf("DNS 返回错误码 %d", rcode)
+	}
+	if binary.BigEndian.Uint16(resp[6:]) == 0 {
+		return fmt.Errorf("DNS 响应没有应答记录")
+	}
+	return nil
+}
+
+// diagTProxySockopt 检查是否可以设置 IP_TRANSPARENT（TProxy 模式需要）
+func diagTProxySockopt(mode string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "tproxy_sockopt", Name: "TProxy 套接字支持"}
+	if runtime.GOOS != "linux" {
+		return skipCheck(check.ID, check.Name, "仅 Linux 支持 TProxy")
+	}
+	if err := checkTransparentSockopt(); err != nil {
+		check.Status = DiagWarn
+		if mode == "tproxy" {
+			check.Status = DiagFail
+		}
+		check.Message = "无法设置 IP_TRANSPARENT: " + err.Error() + "（需要 CAP_NET_ADMIN）"
+		return check
+	}
+	check.Status = DiagPass
+	check.Message = "支持 IP_TRANSPARENT"
+	return check
+}
+
+// diagTUNDevice 检查 TUN 设备和权限（TUN 模式需要）
+func diagTUNDevice(mode string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "tun_device", Name: "TUN 设备"}
+	if runtime.GOOS != "linux" {
+		return skipCheck(check.ID, check.Name, "仅 Linux 支持 TUN 模式")
+	}
+	if err := checkTUNSupport(); err != nil {
+		check.Status = DiagWarn
+		if mode == "tun" {
+			check.Status = DiagFail
+		}
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = DiagPass
+	check.Message = "TUN 设备可用"
+	return check
+}
+
+// diagIPForward 检查 IP 转发（作为局域网网关时需要开启）
+func diagIPForward(scope string) DiagnosticCheck {
+	check := DiagnosticCheck{ID: "ip_forward", Name: "IP 转发"}
+	if runtime.GOOS != "linux" {
+		return skipCheck(check.ID, check.Name, "仅检查 Linux")
+	}
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_forward")
+	if err != nil {
+		check.Status = DiagWarn
+		check.Message = "读取失败: " + err.Error()
+		return check
+	}
+	enabled := strings.TrimSpace(string(data)) == "1"
+	check.Detail = map[string]interface{}{"enabled": enabled}
+	switch {
+	case enabled:
+		check.Status = DiagPass
+		check.Message = "已开启"
+	case scope == "router":
+		check.Status = DiagFail
+		check.Message = "未开启，局域网设备的流量无法经本机转发"
+	default:
+		check.Status = DiagPass
+		check.Message = "未开启（仅本机代理时不需要）"
+	}
+	return check
+}
+
+// diagTransparentRules 检查 nftables 规则与已保存的透明代理模式是否一致
+func (h *Handler) diagTransparentRules() DiagnosticCheck {
+	check := DiagnosticCheck{ID: "transparent_rules", Name: "透明代理规则"}
+	state := h.inspectTransparentState()
+	if !state.Supported && state.Mode == "off" {
+		return skipCheck(check.ID, check.Name, "当前平台不支持透明代理")
+	}
+	check.Detail = map[string]interface{}{
+		"expected":     state.Expected,
+		"tableExists":  state.TableExists,
+		"detectedMode": state.DetectedMode,
+	}
+	if !state.InSync {
+		check.Status = DiagFail
+		check.Message = strings.Join(state.Issues, "；")
+		return check
+	}
+	check.Status = DiagPass
+	if state.Expected {
+		check.Message = fmt.Sprintf("%s 规则已生效", state.Mode)
+	} else {
+		check.Message = "透明代理未开启"
+	}
+	return check
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package proxy
+
+import "syscall"
+
+// checkTransparentSockopt 尝试在 UDP 套接字上设置 IP_TRANSPARENT
+func checkTransparentSockopt() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	return syscall.SetsockoptInt(fd, syscall.SOL_IP, syscall.IP_TRANSPARENT, 1)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package proxy
+
+import "fmt"
+
+// checkTransparentSockopt 非 Linux 不支持 IP_TRANSPARENT
+func checkTransparentSockopt() error {
+	return fmt.Errorf("仅 Linux 支持")
+}
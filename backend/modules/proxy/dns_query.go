@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsQueryTimeout 单个上游 DNS 查询超时
+const dnsQueryTimeout = 5 * time.Second
+
+// DNSTestAnswer DNS 测试结果中的单条记录
+type DNSTestAnswer struct {
+	Type string `json:"type"` // A / AAAA / CNAME
+	Data string `json:"data"`
+	TTL  uint32 `json:"ttl"`
+}
+
+// DNSTestResult 单个 DNS 服务器的测试结果
+type DNSTestResult struct {
+	Server  string          `json:"server"`
+	Latency int64           `json:"latency"` // 毫秒
+	Answers []DNSTestAnswer `json:"answers"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// dnsUpstream 解析后的上游 DNS 地址
+type dnsUpstream struct {
+	scheme string // udp / tcp / tls / https
+	host   string // host:port（https 为完整 URL）
+}
+
+// parseDNSUpstream 解析 Mihomo 风格的 DNS 服务器地址
+// 支持 1.1.1.1、udp://、tcp://、tls://、https://，不支持 quic://、dhcp:// 等
+func parseDNSUpstream(server string) (dnsUpstream, error) {
+	server = strings.TrimSpace(server)
+	// 去掉 Mihomo 的附加参数，如 https://dns.google/dns-query#h3=true
+	if i := strings.Index(server, "#"); i >= 0 {
+		server = server[:i]
+	}
+	if server == "" {
+		return dnsUpstream{}, fmt.Errorf("DNS 服务器地址为空")
+	}
+
+	scheme, rest := "udp", server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, rest = strings.ToLower(server[:i]), server[i+3:]
+	}
+
+	switch scheme {
+	case "https":
+		if _, err := url.Parse(server); err != nil {
+			return dnsUpstream{}, fmt.Errorf("DoH 地址无效: %s", server)
+		}
+		return dnsUpstream{scheme: scheme, host: server}, nil
+	case "udp", "tcp", "tls":
+		defaultPort := "53"
+		if scheme == "tls" {
+			defaultPort = "853"
+		}
+		host := strings.TrimSuffix(rest, "/")
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultPort)
+		}
+		return dnsUpstream{scheme: scheme, host: host}, nil
+	default:
+		return dnsUpstream{}, fmt.Errorf("不支持测试 %s:// 类型的 DNS 服务器", scheme)
+	}
+}
+
+// packDNSQuery 构造指定类型的 DNS 查询报文
+func packDNSQuery(domain string, qtype dnsmessage.Type) ([]byte, error) {
+	if !strings.HasSuffix(domain, ".") {
+		domain += "."
+	}
+	name, err := dnsmessage.NewName(domain)
+	if err != nil {
+		return nil, fmt.Errorf("域名无效: %s", domain)
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(time.Now().UnixNano()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	return msg.Pack()
+}
+
+// parseDNSAnswers 解析响应报文中的 A / AAAA / CNAME 记录
+func parseDNSAnswers(data []byte) ([]DNSTestAnswer, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil {
+		return nil, fmt.Errorf("解析 DNS 响应失败: %v", err)
+	}
+	if msg.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS 服务器返回 %s", msg.RCode)
+	}
+	answers := []DNSTestAnswer{}
+	for _, rr := range msg.Answers {
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			answers = append(answers, DNSTestAnswer{Type: "A", Data: net.IP(body.A[:]).String(), TTL: rr.Header.TTL})
+		case *dnsmessage.AAAAResource:
+			answers = append(answers, DNSTestAnswer{Type: "AAAA", Data: net.IP(body.AAAA[:]).String(), TTL: rr.Header.TTL})
+		case *dnsmessage.CNAMEResource:
+			answers = append(answers, DNSTestAnswer{Type: "CNAME", Data: body.CNAME.String(), TTL: rr.Header.TTL})
+		}
+	}
+	return answers, nil
+}
+
+// queryDNSUpstream 直接向上游 DNS 服务器查询（不经过核心）
+func queryDNSUpstream(ctx context.Context, server, domain string, qtype dnsmessage.Type) DNSTestResult {
+	result := DNSTestResult{Server: server, Answers: []DNSTestAnswer{}}
+	start := time.Now()
+	answers, err := exchangeDNS(ctx, server, domain, qtype)
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Answers = answers
+	return result
+}
+
+func exchangeDNS(ctx context.Context, server, domain string, qtype dnsmessage.Type) ([]DNSTestAnswer, error) {
+	upstream, err := parseDNSUpstream(server)
+	if err != nil {
+		return nil, err
+	}
+	query, err := packDNSQuery(domain, qtype)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	var resp []byte
+	switch upstream.scheme {
+	case "https":
+		resp, err = exchangeDoH(ctx, upstream.host, query)
+	case "udp":
+		resp, err = exchangeDNSConn(ctx, "udp", upstream.host, query, false)
+	case "tcp":
+		resp, err = exchangeDNSConn(ctx, "tcp", upstream.host, query, true)
+	case "tls":
+		resp, err = exchangeDNSConn(ctx, "tls", upstream.host, query, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseDNSAnswers(resp)
+}
+
+// exchangeDNSConn 通过 UDP / TCP / TLS 发送查询，TCP 和 TLS 使用 2 字节长度前缀
+func exchangeDNSConn(ctx context.Context, network, addr string, query []byte, stream bool) ([]byte, error) {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{}
+	if network == "tls" {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if !stream {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	packet := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(packet, uint16(len(query)))
+	copy(packet[2:], query)
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeDoH 通过 DNS over HTTPS（RFC 8484 POST）发送查询
+func exchangeDoH(ctx context.Context, endpoint string, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH 服务器返回 HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
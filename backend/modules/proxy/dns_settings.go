@@ -0,0 +1,442 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServerSchemes Mihomo 支持的 DNS 服务器协议
+var dnsServerSchemes = map[string]bool{
+	"udp": true, "tcp": true, "tls": true, "https": true, "quic": true, "dhcp": true, "system": true,
+}
+
+// validateDNSServer 校验 DNS 服务器地址，ipOnly 为 true 时要求主机为 IP（default-nameserver 不能依赖域名解析）
+func validateDNSServer(server string, ipOnly bool) error {
+	server = strings.TrimSpace(server)
+	if i := strings.Index(server, "#"); i >= 0 {
+		server = server[:i]
+	}
+	if server == "" {
+		return fmt.Errorf("DNS 服务器地址为空")
+	}
+	scheme, rest := "udp", server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, rest = strings.ToLower(server[:i]), server[i+3:]
+	}
+	if !dnsServerSchemes[scheme] {
+		return fmt.Errorf("不支持的 DNS 协议: %s", server)
+	}
+	if scheme == "dhcp" || scheme == "system" {
+		return nil
+	}
+
+	host := rest
+	if scheme == "https" {
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("DoH 地址无效: %s", server)
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(strings.TrimSuffix(rest, "/")); err == nil {
+		host = h
+	}
+	host = strings.Trim(strings.TrimSuffix(host, "/"), "[]")
+	if host == "" || strings.ContainsAny(host, " /") {
+		return fmt.Errorf("DNS 服务器地址无效: %s", server)
+	}
+	if ipOnly && net.ParseIP(host) == nil {
+		return fmt.Errorf("默认 DNS 必须使用 IP 地址: %s", server)
+	}
+	return nil
+}
+
+// validateDNSTemplate 校验模板 DNS 设置
+func validateDNSTemplate(t *DNSTemplate) error {
+	if t == nil {
+		return nil
+	}
+	lists := []struct {
+		name    string
+		servers []string
+		ipOnly  bool
+	}{
+		{"默认 DNS", t.DefaultNameserver, true},
+		{"上游 DNS", t.Nameserver, false},
+		{"后备 DNS", t.Fallback, false},
+		{"节点域名 DNS", t.ProxyServerNameserver, false},
+		{"直连 DNS", t.DirectNameserver, false},
+	}
+	for _, l := range lists {
+		for _, server := range l.servers {
+			if err := validateDNSServer(server, l.ipOnly); err != nil {
+				return fmt.Errorf("%s: %v", l.name, err)
+			}
+		}
+	}
+	for domain, servers := range t.NameserverPolicy {
+		if strings.TrimSpace(domain) == "" {
+			return fmt.Errorf("域名策略缺少域名")
+		}
+		for _, server := range servers {
+			if err := validateDNSServer(server, false); err != nil {
+				return fmt.Errorf("域名策略 %s: %v", domain, err)
+			}
+		}
+	}
+
+	if t.FakeIPRange != "" {
+		if _, _, err := net.ParseCIDR(t.FakeIPRange); err != nil {
+			return fmt.Errorf("fake-ip 地址段无效: %s", t.FakeIPRange)
+		}
+	}
+	if t.FakeIPFilterMode != "" && t.FakeIPFilterMode != "blacklist" && t.FakeIPFilterMode != "whitelist" {
+		return fmt.Errorf("fake-ip 过滤模式只能是 blacklist 或 whitelist")
+	}
+	for _, f := range t.FakeIPFilter {
+		if strings.TrimSpace(f) == "" {
+			return fmt.Errorf("fake-ip 过滤列表包含空项")
+		}
+	}
+
+	for domain, target := range t.Hosts {
+		if strings.TrimSpace(domain) == "" || strings.ContainsAny(domain, " /") {
+			return fmt.Errorf("hosts 域名无效: %q", domain)
+		}
+		if strings.TrimSpace(target) == "" || strings.ContainsAny(target, " /") {
+			return fmt.Errorf("hosts %s 的地址无效: %q", domain, target)
+		}
+	}
+	return nil
+}
+
+// GetTemplateDNS 获取模板 DNS 设置
+func (s *Service) GetTemplateDNS() *DNSTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.configTemplate == nil || s.configTemplate.DNS == nil {
+		return &DNSTemplate{}
+	}
+	t := *s.configTemplate.DNS
+	return &t
+}
+
+// UpdateTemplateDNS 更新模板 DNS 设置，下次生成配置时生效
+func (s *Service) UpdateTemplateDNS(t *DNSTemplate) error {
+	if err := validateDNSTemplate(t); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.configTemplate == nil {
+		s.configTemplate = GetDefaultConfigTemplate()
+	}
+	s.configTemplate.DNS = t
+	return s.saveConfigTemplate()
+}
+
+// effectiveDNSServers 生成配置时实际使用的上游 DNS（默认值 + 模板覆盖），去重后按 nameserver、fallback、direct 排列
+func (s *Service) effectiveDNSServers() []string {
+	dns := s.configGenerator.generateDNSConfig(ConfigGeneratorOptions{})
+	applyTemplateDNS(dns, s.GetTemplateDNS())
+
+	var servers []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{dns.Nameserver, dns.Fallback, dns.DirectNameserver} {
+		for _, server := range list {
+			if !seen[server] {
+				seen[server] = true
+				servers = append(servers, server)
+			}
+		}
+	}
+	return servers
+}
+
+// dnsRecordTypes Mihomo /dns/query 返回的记录类型编号
+var dnsRecordTypes = map[int]string{1: "A", 5: "CNAME", 28: "AAAA"}
+
+// queryCoreDNS 通过运行中的 Mihomo 解析，结果与实际代理流量使用的解析路径一致
+func queryCoreDNS(ctx context.Context, client *clashapi.Client, domain, qtype string) DNSTestResult {
+	result := DNSTestResult{Server: "core", Answers: []DNSTestAnswer{}}
+	start := time.Now()
+	resp, err := client.DNSQuery(ctx, domain, qtype)
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if resp.Status != 0 {
+		result.Error = fmt.Sprintf("DNS 服务器返回 %s", dnsmessage.RCode(resp.Status))
+		return result
+	}
+	for _, a := range resp.Answer {
+		t, ok := dnsRecordTypes[a.Type]
+		if !ok {
+			t = strconv.Itoa(a.Type)
+		}
+		result.Answers = append(result.Answers, DNSTestAnswer{Type: t, Data: a.Data, TTL: uint32(a.TTL)})
+	}
+	return result
+}
+
+// GetTemplateDNS 获取模板 DNS 设置
+func (h *Handler) GetTemplateDNS(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetTemplateDNS(),
+	})
+}
+
+// UpdateTemplateDNS 更新模板 DNS 设置，需重新生成配置后生效
+func (h *Handler) UpdateTemplateDNS(c *gin.Context) {
+	var req DNSTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.UpdateTemplateDNS(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetTemplateDNS(),
+	})
+}
+
+// TestDNS 解析测试域名
+// 指定 server 时只查询该服务器；否则核心运行中（Mihomo）时通过核心解析，并直接查询每个上游 DNS
+func (h *Handler) TestDNS(c *gin.Context) {
+	var req struct {
+		Domain string `json:"domain"`
+		Type   string `json:"type"`   // A（默认）/ AAAA
+		Server string `json:"server"` // 可选，只测试该服务器
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	req.Domain = strings.TrimSpace(req.Domain)
+	if req.Domain == "" {
+		req.Domain = "www.google.com"
+	}
+	var qtype dnsmessage.Type
+	switch strings.ToUpper(req.Type) {
+	case "", "A":
+		req.Type, qtype = "A", dnsmessage.TypeA
+	case "AAAA":
+		req.Type, qtype = "AAAA", dnsmessage.TypeAAAA
+	default:
+		apierror.Message(c, http.StatusBadRequest, "只支持 A 和 AAAA 查询")
+		return
+	}
+
+	servers := h.service.effectiveDNSServers()
+	if req.Server != "" {
+		if err := validateDNSServer(req.Server, false); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		servers = []string{req.Server}
+	}
+
+	ctx := c.Request.Context()
+	var core *DNSTestResult
+	if status := h.service.GetStatus(); req.Server == "" && status.Running && status.CoreType != "singbox" {
+		result := queryCoreDNS(ctx, h.clashAPI(), req.Domain, req.Type)
+		core = &result
+	}
+
+	upstreams := make([]DNSTestResult, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			upstreams[i] = queryDNSUpstream(ctx, server, req.Domain, qtype)
+		}(i, server)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"domain":    req.Domain,
+			"type":      req.Type,
+			"core":      core,
+			"upstreams": upstreams,
+		},
+	})
+}
+
+// sbDNSServer 将 Mihomo 风格的 DNS 地址转换为 Sing-Box DNS 服务器，不支持的协议返回 false
+func sbDNSServer(tag, server string) (SBDNSServer, bool) {
+	upstream, err := parseDNSUpstream(server)
+	if err != nil {
+		return SBDNSServer{}, false
+	}
+	result := SBDNSServer{Tag: tag, Type: upstream.scheme}
+	if upstream.scheme == "https" {
+		u, err := url.Parse(upstream.host)
+		if err != nil || u.Hostname() == "" {
+			return SBDNSServer{}, false
+		}
+		result.Server = u.Hostname()
+		if port, _ := strconv.Atoi(u.Port()); port != 0 && port != 443 {
+			result.ServerPort = port
+		}
+		if u.Path != "" && u.Path != "/dns-query" {
+			result.Path = u.Path
+		}
+		return result, true
+	}
+
+	host, portStr, err := net.SplitHostPort(upstream.host)
+	if err != nil {
+		return SBDNSServer{}, false
+	}
+	result.Server = host
+	defaultPort := 53
+	if upstream.scheme == "tls" {
+		defaultPort = 853
+	}
+	if port, _ := strconv.Atoi(portStr); port != 0 && port != defaultPort {
+		result.ServerPort = port
+	}
+	return result, true
+}
+
+// replaceSBDNSServer 用列表中第一个可转换的地址替换指定 tag 的服务器
+// 直连 DNS 同时是 default_domain_resolver，要求使用 IP，避免解析自身时循环
+func replaceSBDNSServer(dns *SBDNS, tag string, servers []string, ipOnly bool) {
+	for i := range dns.Servers {
+		if dns.Servers[i].Tag != tag {
+			continue
+		}
+		for _, server := range servers {
+			converted, ok := sbDNSServer(tag, server)
+			if !ok || (ipOnly && net.ParseIP(converted.Server) == nil) {
+				continue
+			}
+			dns.Servers[i] = converted
+			return
+		}
+		fmt.Printf("⚠️ Sing-Box 不支持模板中的 DNS 服务器 %v，使用默认值\n", servers)
+		return
+	}
+}
+
+// sbFakeIPFilterRule 将 Mihomo fake-ip-filter 转换为 Sing-Box DNS 规则
+// 只转换 "+.example.com"、"*.example.com" 和完整域名，geosite: 和中间带通配符的条目会被忽略
+func sbFakeIPFilterRule(filters []string) (SBDNSRule, bool) {
+	var rule SBDNSRule
+	for _, f := range filters {
+		f = strings.TrimSpace(f)
+		switch {
+		case strings.HasPrefix(f, "+.") && !strings.ContainsAny(f[2:], "*+:"):
+			rule.DomainSuffix = append(rule.DomainSuffix, f[2:])
+		case strings.HasPrefix(f, "*.") && !strings.ContainsAny(f[2:], "*+:"):
+			rule.DomainSuffix = append(rule.DomainSuffix, "."+f[2:])
+		case f != "" && !strings.ContainsAny(f, "*+:"):
+			rule.Domain = append(rule.Domain, f)
+		}
+	}
+	return rule, len(rule.Domain) > 0 || len(rule.DomainSuffix) > 0
+}
+
+// applySingBoxTemplateDNS 将模板 DNS 设置应用到 Sing-Box DNS 配置
+// 上游 → 代理 DNS，直连 DNS → 本地 DNS，hosts → hosts 服务器；respect-rules 等 Mihomo 专有设置不适用
+// 没有模板 DNS 设置时同样应用默认的 fake-ip 排除列表
+func applySingBoxTemplateDNS(dns *SBDNS, t *DNSTemplate) {
+	if dns == nil {
+		return
+	}
+	if t == nil {
+		t = &DNSTemplate{}
+	}
+
+	// FakeIP 模板使用 google / local，真实 IP 模板使用 proxyDns / localDns
+	proxyTag, localTag, fakeIP := "proxyDns", "localDns", false
+	for _, server := range dns.Servers {
+		if server.Type == "fakeip" {
+			proxyTag, localTag, fakeIP = "google", "local", true
+		}
+	}
+	if len(t.Nameserver) > 0 {
+		replaceSBDNSServer(dns, proxyTag, t.Nameserver, false)
+	}
+	if len(t.DirectNameserver) > 0 {
+		replaceSBDNSServer(dns, localTag, t.DirectNameserver, true)
+	}
+
+	if fakeIP {
+		if t.FakeIPRange != "" {
+			for i := range dns.Servers {
+				if dns.Servers[i].Type == "fakeip" {
+					dns.Servers[i].Inet4Range = t.FakeIPRange
+				}
+			}
+		}
+		if filterRule, ok := sbFakeIPFilterRule(effectiveFakeIPFilter(t)); ok {
+			dns.Rules = applySBFakeIPFilter(dns.Rules, filterRule, localTag, t.FakeIPFilterMode == "whitelist")
+		}
+	}
+
+	if len(t.Hosts) > 0 {
+		predefined := make(map[string][]string, len(t.Hosts))
+		domains := make([]string, 0, len(t.Hosts))
+		for domain, target := range t.Hosts {
+			predefined[domain] = []string{target}
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		dns.Servers = append(dns.Servers, SBDNSServer{Tag: "hosts", Type: "hosts", Predefined: predefined})
+		dns.Rules = append([]SBDNSRule{{Domain: domains, Server: "hosts"}}, dns.Rules...)
+	}
+}
+
+// applySBFakeIPFilter 插入 fake-ip 过滤规则
+// blacklist: 在第一条 fakeip 规则前插入，匹配的域名使用本地 DNS 返回真实 IP
+// whitelist: 去掉原有 fakeip 规则，只有匹配的域名返回 fake-ip
+func applySBFakeIPFilter(rules []SBDNSRule, filter SBDNSRule, localTag string, whitelist bool) []SBDNSRule {
+	result := make([]SBDNSRule, 0, len(rules)+1)
+	inserted := false
+	for _, r := range rules {
+		if r.Server == "fakeip" && !inserted {
+			if whitelist {
+				filter.QueryType = []string{"A", "AAAA"}
+				filter.Server = "fakeip"
+			} else {
+				filter.Server = localTag
+			}
+			result = append(result, filter)
+			inserted = true
+		}
+		if whitelist && r.Server == "fakeip" {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
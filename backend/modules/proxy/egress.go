@@ -0,0 +1,51 @@
+package proxy
+
+// applyMihomoEgress 为每个节点写入 routing-mark 和 interface-name
+// 全局设置可能被订阅节点自带的字段覆盖，标记不一致时核心出站流量会被透明代理规则再次拦截形成回环，
+// 因此标记总是覆盖；网卡只在节点未指定时填充
+func applyMihomoEgress(proxies []map[string]interface{}, routingMark int, iface string) {
+	for _, p := range proxies {
+		// dialer-proxy 经由其他节点连接，标记和网卡由前一跳决定
+		if _, chained := p["dialer-proxy"]; chained {
+			continue
+		}
+		if routingMark > 0 {
+			p["routing-mark"] = routingMark
+		}
+		if iface != "" {
+			if current, _ := p["interface-name"].(string); current == "" {
+				p["interface-name"] = iface
+			}
+		}
+	}
+}
+
+// applySingBoxEgress 设置 sing-box 默认出站标记和网卡，并写入节点和 direct 出站
+func applySingBoxEgress(config *SingBoxConfig, routingMark int, iface string) {
+	if config.Route != nil {
+		config.Route.DefaultMark = routingMark
+		if iface != "" {
+			// default_interface 与 auto_detect_interface 同时设置时不生效
+			config.Route.AutoDetectInterface = false
+			config.Route.DefaultInterface = iface
+		}
+	}
+
+	for i := range config.Outbounds {
+		out := &config.Outbounds[i]
+		switch out.Type {
+		case "selector", "urltest", "block", "dns":
+			continue
+		}
+		// detour 经由其他出站连接，dial 字段不生效
+		if out.Detour != "" {
+			continue
+		}
+		if routingMark > 0 {
+			out.RoutingMark = routingMark
+		}
+		if iface != "" && out.BindInterface == "" {
+			out.BindInterface = iface
+		}
+	}
+}
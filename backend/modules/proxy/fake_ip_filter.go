@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FakeIPFilterPreset 内置的 fake-ip 排除分类（这些域名返回真实 IP）
+type FakeIPFilterPreset struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Domains     []string `json:"domains"`
+	Default     bool     `json:"default"` // 未自定义时是否启用
+}
+
+// fakeIPFilterPresets 按生成顺序排列，默认启用的分类合并后即为原有的默认列表
+var fakeIPFilterPresets = []FakeIPFilterPreset{
+	{
+		ID: "cn", Name: "国内域名", Description: "直连域名使用真实 IP", Default: true,
+		Domains: []string{"geosite:cn", "geosite:private"},
+	},
+	{
+		ID: "local", Name: "本地域名", Description: "局域网设备和服务", Default: true,
+		Domains: []string{"*.lan", "*.local", "*.localhost", "*.localdomain", "*.home.arpa"},
+	},
+	{
+		ID: "connectivity", Name: "网络检测", Description: "系统联网检测和认证页面（Captive Portal）", Default: true,
+		Domains: []string{
+			"+.msftconnecttest.com", "+.msftncsi.com", "connectivitycheck.gstatic.com",
+			"captive.apple.com", "wifi.vivo.com.cn", "connect.rom.miui.com",
+		},
+	},
+	{
+		ID: "ntp", Name: "时间同步", Description: "NTP 服务器", Default: true,
+		Domains: []string{"time.*.com", "time.*.gov", "time.*.apple.com", "time.*.edu.cn", "ntp.*.com", "pool.ntp.org"},
+	},
+	{
+		ID: "stun", Name: "STUN / NAT 穿透", Description: "语音通话、游戏联机", Default: true,
+		Domains: []string{"stun.*.*", "stun.*.*.*", "+.stun.playstation.net", "+.stun.xbox.com", "+.stun.l.google.com"},
+	},
+	{
+		ID: "discovery", Name: "服务发现", Description: "mDNS / DNS-SD 服务发现", Default: true,
+		Domains: []string{"+._tcp.*", "+._udp.*"},
+	},
+	{
+		ID: "cn-services", Name: "国内常用服务", Description: "登录、支付和购物应用", Default: true,
+		Domains: []string{
+			"localhost.ptlogin2.qq.com", "+.market.xiaomi.com", "+.qq.com", "+.tencent.com", "+.weixin.qq.com",
+			"+.alipay.com", "+.taobao.com", "+.tmall.com", "+.jd.com", "+.baidu.com", "+.bilibili.com",
+			"+.163.com", "+.126.com",
+		},
+	},
+	{
+		ID: "banking", Name: "银行和支付", Description: "检测客户端 IP 的银行应用", Default: false,
+		Domains: []string{
+			"+.95516.com", "+.unionpay.com", "+.icbc.com.cn", "+.ccb.com", "+.boc.cn", "+.abchina.com",
+			"+.bankcomm.com", "+.cmbchina.com", "+.psbc.com", "+.paypal.com",
+		},
+	},
+}
+
+// defaultFakeIPFilterPresets 默认启用的分类
+func defaultFakeIPFilterPresets() []string {
+	var ids []string
+	for _, p := range fakeIPFilterPresets {
+		if p.Default {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// defaultFakeIPFilter 默认的 fake-ip 排除列表
+func defaultFakeIPFilter() []string {
+	return fakeIPFilterDomains(defaultFakeIPFilterPresets(), nil)
+}
+
+// fakeIPFilterDomains 合并启用的分类和自定义条目（去重，保持顺序）
+func fakeIPFilterDomains(presets, custom []string) []string {
+	enabled := make(map[string]bool, len(presets))
+	for _, id := range presets {
+		enabled[id] = true
+	}
+	var result []string
+	seen := make(map[string]bool)
+	add := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			result = append(result, d)
+		}
+	}
+	for _, p := range fakeIPFilterPresets {
+		if enabled[p.ID] {
+			for _, d := range p.Domains {
+				add(d)
+			}
+		}
+	}
+	for _, d := range custom {
+		add(d)
+	}
+	return result
+}
+
+// effectiveFakeIPFilter 模板最终使用的 fake-ip 排除列表
+// 未通过分类管理（FakeIPFilterPresets 为 nil）时保持原有行为：设置了 FakeIPFilter 则完全替换默认列表
+func effectiveFakeIPFilter(t *DNSTemplate) []string {
+	if t == nil {
+		return defaultFakeIPFilter()
+	}
+	if t.FakeIPFilterPresets == nil {
+		if len(t.FakeIPFilter) > 0 {
+			return t.FakeIPFilter
+		}
+		return defaultFakeIPFilter()
+	}
+	return fakeIPFilterDomains(t.FakeIPFilterPresets, t.FakeIPFilter)
+}
+
+// FakeIPFilterSettings fake-ip 排除列表设置
+type FakeIPFilterSettings struct {
+	Mode    string   `json:"mode"`    // blacklist（默认）/ whitelist
+	Presets []string `json:"presets"` // 启用的分类 ID
+	Custom  []string `json:"custom"`  // 自定义条目，Mihomo fake-ip-filter 语法
+}
+
+// GetFakeIPFilter 获取 fake-ip 排除列表设置
+func (s *Service) GetFakeIPFilter() FakeIPFilterSettings {
+	t := s.GetTemplateDNS()
+	settings := FakeIPFilterSettings{
+		Mode:    t.FakeIPFilterMode,
+		Presets: t.FakeIPFilterPresets,
+		Custom:  append([]string{}, t.FakeIPFilter...),
+	}
+	if settings.Mode == "" {
+		settings.Mode = "blacklist"
+	}
+	if settings.Presets == nil {
+		// 旧版模板：自定义列表替换全部默认条目
+		settings.Presets = []string{}
+		if len(t.FakeIPFilter) == 0 {
+			settings.Presets = defaultFakeIPFilterPresets()
+		}
+	}
+	return settings
+}
+
+// SetFakeIPFilter 更新 fake-ip 排除列表，下次生成配置时生效
+func (s *Service) SetFakeIPFilter(settings FakeIPFilterSettings) error {
+	known := make(map[string]bool, len(fakeIPFilterPresets))
+	for _, p := range fakeIPFilterPresets {
+		known[p.ID] = true
+	}
+	presets := []string{}
+	for _, id := range settings.Presets {
+		if !known[id] {
+			return fmt.Errorf("未知的分类: %s", id)
+		}
+		presets = append(presets, id)
+	}
+	custom := []string{}
+	for _, d := range settings.Custom {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		if strings.ContainsAny(d, " ,/") {
+			return fmt.Errorf("条目无效: %s", d)
+		}
+		custom = append(custom, d)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.configTemplate == nil {
+		s.configTemplate = GetDefaultConfigTemplate()
+	}
+	t := &DNSTemplate{}
+	if s.configTemplate.DNS != nil {
+		copied := *s.configTemplate.DNS
+		t = &copied
+	}
+	t.FakeIPFilterPresets = presets
+	t.FakeIPFilter = custom
+	t.FakeIPFilterMode = settings.Mode
+	if err := validateDNSTemplate(t); err != nil {
+		return err
+	}
+	s.configTemplate.DNS = t
+	return s.saveConfigTemplate()
+}
+
+// GetFakeIPFilter 获取 fake-ip 排除列表（分类、自定义条目和最终生效的列表）
+func (h *Handler) GetFakeIPFilter(c *gin.Context) {
+	settings := h.service.GetFakeIPFilter()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"settings":  settings,
+			"presets":   fakeIPFilterPresets,
+			"effective": fakeIPFilterDomains(settings.Presets, settings.Custom),
+		},
+	})
+}
+
+// SetFakeIPFilter 更新 fake-ip 排除列表，需重新生成配置后生效
+func (h *Handler) SetFakeIPFilter(c *gin.Context) {
+	var req FakeIPFilterSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.SetFakeIPFilter(req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetFakeIPFilter(),
+	})
+}
@@ -0,0 +1,340 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 检测到其他防火墙管理工具的拦截规则时的处理方式
+const (
+	conflictPolicyWarn   = "warn"   // 输出警告后继续应用规则
+	conflictPolicyRefuse = "refuse" // 存在拦截规则冲突时拒绝应用
+	conflictPolicyIgnore = "ignore" // 不检测
+)
+
+// FirewallConflict 其他工具创建的可能冲突的防火墙规则
+type FirewallConflict struct {
+	Source   string `json:"source"`   // 推测的来源工具，如 OpenClash、v2rayA
+	Kind     string `json:"kind"`     // nftables / iptables
+	Table    string `json:"table"`    // 表名，如 inet fw4、mangle
+	Chain    string `json:"chain"`    // 链名
+	Detail   string `json:"detail"`   // 规则原文或说明
+	Severity string `json:"severity"` // error: 存在拦截规则，叠加会形成回环; warning: 可能冲突
+}
+
+// FirewallConflictError 按 refuse 策略拒绝应用规则时返回
+type FirewallConflictError struct {
+	Conflicts []FirewallConflict
+}
+
+func (e *FirewallConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		if c.Severity == "error" {
+			parts = append(parts, fmt.Sprintf("%s %s %s", c.Source, c.Table, c.Chain))
+		}
+	}
+	return "检测到其他工具的透明代理规则，已拒绝叠加: " + strings.Join(parts, "; ")
+}
+
+// knownFirewallTables 已知代理工具创建的 nftables 表
+var knownFirewallTables = map[string]string{
+	"clash":     "Clash",
+	"mihomo":    "mihomo",
+	"openclash": "OpenClash",
+	"v2raya":    "v2rayA",
+	"sing-box":  "sing-box",
+	"dae":       "dae",
+	"passwall":  "PassWall",
+	"ssrplus":   "ShadowSocksR Plus+",
+	"nikki":     "Nikki",
+}
+
+// knownChainPrefixes 已知代理工具在 iptables 或 fw4 中创建的链名前缀
+var knownChainPrefixes = map[string]string{
+	"openclash": "OpenClash",
+	"clash":     "Clash",
+	"v2ray":     "v2rayA",
+	"xray":      "Xray",
+	"passwall":  "PassWall",
+	"psw":       "PassWall",
+	"ss_spec":   "ShadowSocksR Plus+",
+	"nikki":     "Nikki",
+}
+
+// guessChainSource 根据链名推测来源工具
+func guessChainSource(chain string) string {
+	lower := strings.ToLower(chain)
+	for prefix, source := range knownChainPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return source
+		}
+	}
+	return ""
+}
+
+// isInterceptRule 规则是否把流量转发到本地代理
+func isInterceptRule(rule string) bool {
+	return strings.Contains(rule, "tproxy ") || strings.Contains(rule, "redirect to") ||
+		strings.Contains(rule, "-j TPROXY") || strings.Contains(rule, "-j REDIRECT")
+}
+
+// parseNftConflicts 解析 nft list ruleset 输出，查找其他表中的拦截规则和已知代理工具的表
+func parseNftConflicts(ruleset string) []FirewallConflict {
+	var conflicts []FirewallConflict
+	table, chain := "", ""
+	reported := make(map[string]bool)
+
+	for _, line := range strings.Split(ruleset, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "table "):
+			table = strings.TrimSuffix(strings.TrimPrefix(line, "table "), " {")
+			chain = ""
+			if table == "inet proxystation" {
+				continue
+			}
+			_, name, _ := strings.Cut(table, " ")
+			if source, ok := knownFirewallTables[strings.ToLower(name)]; ok {
+				conflicts = append(conflicts, FirewallConflict{
+					Source:   source,
+					Kind:     "nftables",
+					Table:    table,
+					Detail:   "检测到其他代理工具的 nftables 表",
+					Severity: "warning",
+				})
+			}
+			continue
+		case strings.HasPrefix(line, "chain "):
+			chain = strings.TrimSuffix(strings.TrimPrefix(line, "chain "), " {")
+			continue
+		}
+
+		if table == "inet proxystation" || !isInterceptRule(line) {
+			continue
+		}
+		// 同一条链只报告一次
+		key := table + "/" + chain
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+
+		_, name, _ := strings.Cut(table, " ")
+		source := knownFirewallTables[strings.ToLower(name)]
+		if s := guessChainSource(chain); s != "" {
+			source = s
+		}
+		if source == "" {
+			source = "未知"
+		}
+		conflicts = append(conflicts, FirewallConflict{
+			Source:   source,
+			Kind:     "nftables",
+			Table:    table,
+			Chain:    chain,
+			Detail:   line,
+			Severity: "error",
+		})
+	}
+	return conflicts
+}
+
+// parseIptablesConflicts 解析 iptables-save 输出，查找 TPROXY/REDIRECT 规则和已知代理工具的链
+func parseIptablesConflicts(output string) []FirewallConflict {
+	var conflicts []FirewallConflict
+	table := ""
+	reported := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = strings.TrimPrefix(line, "*")
+			continue
+		case strings.HasPrefix(line, ":"):
+			// 链声明，如 ":CLASH - [0:0]"
+			fields := strings.Fields(strings.TrimPrefix(line, ":"))
+			if len(fields) == 0 {
+				continue
+			}
+			if source := guessChainSource(fields[0]); source != "" && !reported[table+"/"+fields[0]+"/decl"] {
+				reported[table+"/"+fields[0]+"/decl"] = true
+				conflicts = append(conflicts, FirewallConflict{
+					Source:   source,
+					Kind:     "iptables",
+					Table:    table,
+					Chain:    fields[0],
+					Detail:   "检测到其他代理工具的 iptables 链",
+					Severity: "warning",
+				})
+			}
+			continue
+		case !strings.HasPrefix(line, "-A "):
+			continue
+		}
+
+		if !isInterceptRule(line) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		chain := fields[1]
+		if reported[table+"/"+chain] {
+			continue
+		}
+		reported[table+"/"+chain] = true
+
+		source := guessChainSource(chain)
+		if source == "" {
+			source = "未知"
+		}
+		conflicts = append(conflicts, FirewallConflict{
+			Source:   source,
+			Kind:     "iptables",
+			Table:    table,
+			Chain:    chain,
+			Detail:   line,
+			Severity: "error",
+		})
+	}
+	return conflicts
+}
+
+// detectFirewallConflicts 扫描 nftables 和 iptables（含 legacy 后端）中其他工具的拦截规则
+func detectFirewallConflicts() []FirewallConflict {
+	conflicts := []FirewallConflict{}
+	if runtime.GOOS != "linux" {
+		return conflicts
+	}
+
+	if out, err := exec.Command("nft", "list", "ruleset").CombinedOutput(); err == nil {
+		conflicts = append(conflicts, parseNftConflicts(string(out))...)
+	}
+
+	// iptables-nft 的规则已包含在 nft ruleset 中，这里只需检查 legacy 后端
+	seen := make(map[string]bool)
+	for _, name := range []string{"iptables-legacy-save", "ip6tables-legacy-save"} {
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		out, err := exec.Command(name).CombinedOutput()
+		if err != nil {
+			continue
+		}
+		for _, conflict := range parseIptablesConflicts(string(out)) {
+			key := conflict.Table + "/" + conflict.Chain + "/" + conflict.Severity
+			if !seen[key] {
+				seen[key] = true
+				conflict.Kind = strings.TrimSuffix(name, "-save")
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts
+}
+
+// hasInterceptConflict 是否存在会与透明代理规则叠加的拦截规则
+func hasInterceptConflict(conflicts []FirewallConflict) bool {
+	for _, c := range conflicts {
+		if c.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallConflictPolicy 当前的冲突处理方式，默认只警告
+func (s *Service) firewallConflictPolicy() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch s.config.FirewallConflictPolicy {
+	case conflictPolicyRefuse, conflictPolicyIgnore:
+		return s.config.FirewallConflictPolicy
+	default:
+		return conflictPolicyWarn
+	}
+}
+
+// SetFirewallConflictPolicy 设置冲突处理方式
+func (s *Service) SetFirewallConflictPolicy(policy string) error {
+	switch policy {
+	case conflictPolicyWarn, conflictPolicyRefuse, conflictPolicyIgnore:
+	default:
+		return fmt.Errorf("无效的冲突处理方式: %s", policy)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.FirewallConflictPolicy = policy
+	return s.saveConfig()
+}
+
+// checkFirewallCoexistence 应用规则前检查其他防火墙管理工具
+// warn 策略下输出冲突详情后继续，refuse 策略下存在拦截规则时返回错误
+func (h *Handler) checkFirewallCoexistence() error {
+	policy := h.service.firewallConflictPolicy()
+	if policy == conflictPolicyIgnore {
+		return nil
+	}
+
+	conflicts := detectFirewallConflicts()
+	for _, c := range conflicts {
+		location := c.Table
+		if c.Chain != "" {
+			location += " " + c.Chain
+		}
+		fmt.Printf("⚠️ 防火墙共存检查: [%s] %s %s: %s\n", c.Source, c.Kind, location, c.Detail)
+	}
+	if policy == conflictPolicyRefuse && hasInterceptConflict(conflicts) {
+		return &FirewallConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// GetFirewallConflicts 检查其他防火墙管理工具的规则和标记冲突
+func (h *Handler) GetFirewallConflicts(c *gin.Context) {
+	conflicts := detectFirewallConflicts()
+	markConflicts := []string{}
+	if runtime.GOOS == "linux" {
+		markConflicts = detectMarkConflicts(h.service.transparentMarks())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"policy":        h.service.firewallConflictPolicy(),
+			"conflicts":     conflicts,
+			"markConflicts": markConflicts,
+			"intercepting":  hasInterceptConflict(conflicts),
+		},
+	})
+}
+
+// SetFirewallConflictPolicy 设置冲突处理方式
+func (h *Handler) SetFirewallConflictPolicy(c *gin.Context) {
+	var req struct {
+		Policy string `json:"policy" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.service.SetFirewallConflictPolicy(req.Policy); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"policy": req.Policy},
+	})
+}
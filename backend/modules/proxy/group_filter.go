@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// multiplierPatterns 节点名称中的倍率标记，如 "x3"、"×0.5"、"3x"、"2倍"、"倍率: 1.5"
+var multiplierPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9.])[x×*]\s*(\d+(?:\.\d+)?)(?:[^0-9.]|$)`),
+	regexp.MustCompile(`(?i)(?:^|[^0-9.])(\d+(?:\.\d+)?)\s*(?:x|×|倍)(?:[^a-z]|$)`),
+	regexp.MustCompile(`倍率\s*[:：]?\s*(\d+(?:\.\d+)?)`),
+}
+
+// parseMultiplier 解析节点名称中的倍率，有多个时取最大值，没有倍率标记时返回 false
+func parseMultiplier(name string) (float64, bool) {
+	highest, found := 0.0, false
+	for _, re := range multiplierPatterns {
+		for _, m := range re.FindAllStringSubmatch(name, -1) {
+			v, err := strconv.ParseFloat(m[1], 64)
+			if err != nil || v <= 0 {
+				continue
+			}
+			if !found || v > highest {
+				highest, found = v, true
+			}
+		}
+	}
+	return highest, found
+}
+
+// nodeFilter 代理组的节点过滤条件（Filter 包含、ExcludeFilter 排除、MaxMultiplier 倍率上限）
+type nodeFilter struct {
+	include       *regexp.Regexp
+	exclude       *regexp.Regexp
+	maxMultiplier float64
+}
+
+// newNodeFilter 编译代理组的过滤条件，无效的正则会被忽略（保存模板时已校验）
+func newNodeFilter(t ProxyGroupTemplate) nodeFilter {
+	var f nodeFilter
+	if t.Filter != "" && t.Filter != "__MANUAL__" {
+		f.include, _ = regexp.Compile(t.Filter)
+	}
+	if t.ExcludeFilter != "" {
+		f.exclude, _ = regexp.Compile(t.ExcludeFilter)
+	}
+	f.maxMultiplier = t.MaxMultiplier
+	return f
+}
+
+// active 是否设置了任何过滤条件
+func (f nodeFilter) active() bool {
+	return f.include != nil || f.exclude != nil || f.maxMultiplier > 0
+}
+
+// excluded 节点是否被排除正则或倍率上限排除（没有倍率标记的节点不受倍率限制）
+func (f nodeFilter) excluded(name string) bool {
+	if f.exclude != nil && f.exclude.MatchString(name) {
+		return true
+	}
+	if f.maxMultiplier > 0 {
+		if m, ok := parseMultiplier(name); ok && m > f.maxMultiplier {
+			return true
+		}
+	}
+	return false
+}
+
+// included 节点是否匹配包含正则（未设置时全部匹配）
+func (f nodeFilter) included(name string) bool {
+	return f.include == nil || f.include.MatchString(name)
+}
+
+// allows 节点是否通过全部过滤条件
+func (f nodeFilter) allows(name string) bool {
+	return f.included(name) && !f.excluded(name)
+}
+
+// withoutExcluded 去掉被排除的节点
+func (f nodeFilter) withoutExcluded(names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if !f.excluded(name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// groupNodeFilters 模板中设置了过滤条件的代理组，按名称索引（Sing-Box 生成时按同名分组应用）
+func groupNodeFilters(template *ConfigTemplate) map[string]nodeFilter {
+	if template == nil {
+		return nil
+	}
+	filters := make(map[string]nodeFilter)
+	for _, g := range template.ProxyGroups {
+		if !isGroupTemplateActive(g) {
+			continue
+		}
+		if f := newNodeFilter(g); f.active() {
+			filters[g.Name] = f
+		}
+	}
+	return filters
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ProxyGroupError 单个代理组的校验错误
+type ProxyGroupError struct {
+	Group   string `json:"group"`
+	Index   int    `json:"index"`            // 代理组在模板中的序号
+	Field   string `json:"field"`            // name / type / filter / proxies
+	Member  string `json:"member,omitempty"` // 出错的成员名称
+	Message string `json:"message"`
+}
+
+// ProxyGroupErrors 代理组校验错误列表
+type ProxyGroupErrors []ProxyGroupError
+
+func (e ProxyGroupErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", err.Group, err.Message))
+	}
+	return "代理组校验失败: " + strings.Join(msgs, "; ")
+}
+
+// proxyGroupTypes 模板支持的代理组类型
+var proxyGroupTypes = map[string]bool{
+	"select":       true,
+	"url-test":     true,
+	"fallback":     true,
+	"load-balance": true,
+	"relay":        true,
+}
+
+// loadBalanceStrategies Mihomo load-balance 支持的策略
+var loadBalanceStrategies = map[string]bool{
+	"consistent-hashing": true, // 相同目标地址使用同一节点
+	"round-robin":        true, // 轮流使用所有节点
+	"sticky-sessions":    true, // 相同来源和目标地址在一段时间内使用同一节点
+}
+
+const (
+	defaultLoadBalanceStrategy = "consistent-hashing"
+	defaultHealthCheckURL      = "https://www.gstatic.com/generate_204"
+	defaultHealthCheckInterval = 300
+)
+
+// isGroupTemplateActive 代理组是否会生成到配置中（与 generateProxyGroupsFromTemplate 的跳过条件一致）
+func isGroupTemplateActive(g ProxyGroupTemplate) bool {
+	return g.Enabled || g.Description == ""
+}
+
+// validateProxyGroups 校验代理组成员引用和循环引用
+// nodeNames 为 nil 时不检查节点名称（节点提供者未设置）
+func validateProxyGroups(groups []ProxyGroupTemplate, nodeNames map[string]bool) ProxyGroupErrors {
+	var errs ProxyGroupErrors
+
+	indexes := make(map[string]int, len(groups))
+	active := make(map[string]bool, len(groups))
+	for i, g := range groups {
+		if _, dup := indexes[g.Name]; dup {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "name", Message: "代理组名称重复"})
+			continue
+		}
+		indexes[g.Name] = i
+		if isGroupTemplateActive(g) {
+			active[g.Name] = true
+		}
+	}
+
+	for i, g := range groups {
+		if !isGroupTemplateActive(g) {
+			continue
+		}
+		if strings.TrimSpace(g.Name) == "" {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "name", Message: "代理组名称不能为空"})
+		}
+		if !proxyGroupTypes[g.Type] {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "type", Message: fmt.Sprintf("不支持的代理组类型: %s", g.Type)})
+		}
+
+		for _, tag := range g.Tags {
+			if strings.TrimSpace(tag) == "" {
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "tags", Message: "标签不能为空"})
+			}
+		}
+
+		if g.Filter != "" && g.Filter != "__MANUAL__" {
+			if _, err := regexp.Compile(g.Filter); err != nil {
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "filter", Message: fmt.Sprintf("节点过滤正则无效: %v", err)})
+			}
+		}
+		if g.ExcludeFilter != "" {
+			if _, err := regexp.Compile(g.ExcludeFilter); err != nil {
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "excludeFilter", Message: fmt.Sprintf("排除正则无效: %v", err)})
+			}
+		}
+		if g.Strategy != "" {
+			if g.Type != "load-balance" {
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "strategy", Message: "只有 load-balance 代理组可以设置策略"})
+			} else if !loadBalanceStrategies[g.Strategy] {
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "strategy", Message: fmt.Sprintf("不支持的负载均衡策略: %s", g.Strategy)})
+			}
+		}
+		if g.URL != "" && !strings.HasPrefix(g.URL, "http://") && !strings.HasPrefix(g.URL, "https://") {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "url", Message: "健康检查地址必须以 http:// 或 https:// 开头"})
+		}
+		if g.Interval < 0 {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "interval", Message: "健康检查间隔不能为负数"})
+		}
+		if g.MaxMultiplier < 0 {
+			errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "maxMultiplier", Message: "倍率上限不能为负数"})
+		}
+
+		// UseAll 的成员在生成时按节点填充
+		if g.UseAll {
+			continue
+		}
+
+		for _, member := range g.Proxies {
+			switch {
+			case member == g.Name:
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "proxies", Member: member, Message: "代理组不能包含自身"})
+			case builtinRuleTargets[member], active[member]:
+			case hasGroup(indexes, member):
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "proxies", Member: member, Message: fmt.Sprintf("引用的代理组 %q 已禁用", member)})
+			case nodeNames == nil || nodeNames[member]:
+			default:
+				errs = append(errs, ProxyGroupError{Group: g.Name, Index: i, Field: "proxies", Member: member, Message: fmt.Sprintf("引用的节点或代理组 %q 不存在", member)})
+			}
+		}
+	}
+
+	errs = append(errs, detectGroupCycles(groups, active, indexes)...)
+	return errs
+}
+
+// hasGroup 模板中是否定义了该代理组
+func hasGroup(indexes map[string]int, name string) bool {
+	_, ok := indexes[name]
+	return ok
+}
+
+// detectGroupCycles 检测启用的代理组之间的循环引用，每个环只报告一次
+func detectGroupCycles(groups []ProxyGroupTemplate, active map[string]bool, indexes map[string]int) ProxyGroupErrors {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	edges := make(map[string][]string)
+	for _, g := range groups {
+		if !active[g.Name] || g.UseAll {
+			continue
+		}
+		for _, member := range g.Proxies {
+			// 自身引用已单独报告
+			if active[member] && member != g.Name {
+				edges[g.Name] = append(edges[g.Name], member)
+			}
+		}
+	}
+
+	var errs ProxyGroupErrors
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, next := range edges[name] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				// 从栈中截取环
+				start := len(stack) - 1
+				for stack[start] != next {
+					start--
+				}
+				cycle := append(append([]string{}, stack[start:]...), next)
+				errs = append(errs, ProxyGroupError{
+					Group:   next,
+					Index:   indexes[next],
+					Field:   "proxies",
+					Member:  name,
+					Message: "代理组循环引用: " + strings.Join(cycle, " → "),
+				})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+
+	for _, g := range groups {
+		if active[g.Name] && state[g.Name] == unvisited {
+			visit(g.Name)
+		}
+	}
+	return errs
+}
+
+// currentNodeNames 当前节点名称（节点提供者未设置时返回 nil）
+// 注意：调用此方法时不能持有 s.mu 锁
+func (s *Service) currentNodeNames() map[string]bool {
+	provider := s.nodeProvider
+	if provider == nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, n := range provider() {
+		names[n.Name] = true
+	}
+	return names
+}
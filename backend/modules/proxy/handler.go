@@ -1,28 +1,52 @@
 package proxy
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/events"
+
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	service *Service
+	service   *Service
+	netfilter netfilterBackend
+	stats     *trafficStats
+	ruleStats *ruleStats
+	providers *providerTracker
+	latency   *latencyHistory
+
+	// 上次添加策略路由时使用的标记，标记修改后清理规则时仍能删除旧的策略路由
+	marksMu      sync.Mutex
+	appliedMarks *TransparentMarks
+	// 最近一次应用透明代理规则的结果（由 marksMu 保护）
+	rulesErr error
+
+	// 透明代理连通性看门狗状态
+	watchdog watchdogState
 }
 
 func NewHandler(dataDir string) *Handler {
 	h := &Handler{
-		service: NewService(dataDir),
+		service:   NewService(dataDir),
+		netfilter: newNetfilterBackend(),
+		stats:     newTrafficStats(dataDir),
+		ruleStats: newRuleStats(dataDir),
+		providers: newProviderTracker(dataDir),
+		latency:   newLatencyHistory(),
 	}
 
 	// 注册启动/停止回调，确保 nftables 规则随核心生命周期正确应用
@@ -34,7 +58,7 @@ func NewHandler(dataDir string) *Handler {
 		status := h.service.GetStatus()
 		mode := status.TransparentMode
 		scope := status.ProxyScope
-		if mode == "" {
+		if !isTransparentMode(mode) {
 			mode = "off"
 		}
 		if scope == "" {
@@ -55,9 +79,21 @@ func NewHandler(dataDir string) *Handler {
 		fmt.Println("✓ nftables 规则已清除")
 	})
 
+	h.startTrafficStats()
+	h.startProviderRefresh()
+	h.startLatencySampling()
+	h.startTransparentWatchdog()
+
 	return h
 }
 
+// rulesError 返回最近一次应用透明代理规则的错误
+func (h *Handler) rulesError() error {
+	h.marksMu.Lock()
+	defer h.marksMu.Unlock()
+	return h.rulesErr
+}
+
 // GetService 获取服务实例
 func (h *Handler) GetService() *Service {
 	return h.service
@@ -68,20 +104,96 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/start", h.Start)
 	r.POST("/stop", h.Stop)
 	r.POST("/restart", h.Restart)
+	r.POST("/reload", h.Reload)           // 热重载配置（失败时回退为重启）
+	r.GET("/external", h.GetExternalCore) // 检测外部启动的核心
+	r.POST("/adopt", h.AdoptExternalCore)
+	r.POST("/adopt/release", h.ReleaseExternalCore)
 	r.PUT("/mode", h.SetMode)
-	r.PUT("/transparent", h.SetTransparentMode) // 透明代理模式切换
-	r.GET("/config", h.GetConfig)
-	r.PUT("/config", h.UpdateConfig)
+	r.PUT("/transparent", h.SetTransparentMode)          // 透明代理模式切换
+	r.GET("/transparent/status", h.GetTransparentStatus) // 透明代理规则状态检查
+	r.GET("/transparent/bypass", h.GetTransparentBypass) // 绕过设备列表
+	r.PUT("/transparent/bypass", h.SetTransparentBypass)
+	r.GET("/transparent/devices", h.GetDevicePolicies) // 设备策略表
+	r.PUT("/transparent/devices", h.SetDevicePolicies)
+	r.GET("/transparent/interfaces", h.GetTransparentInterfaces) // 路由器模式拦截的网卡
+	r.PUT("/transparent/interfaces", h.SetTransparentInterfaces)
+	r.GET("/transparent/direct", h.GetTransparentDirect) // 直连的目标地址段和端口
+	r.PUT("/transparent/direct", h.SetTransparentDirect)
+	r.GET("/transparent/processes", h.GetTransparentProcesses) // 本机进程分流（按用户或 cgroup）
+	r.PUT("/transparent/processes", h.SetTransparentProcesses)
+
+	// 链式代理（入口 → 出口）
+	r.GET("/chains", h.GetProxyChains)
+	r.PUT("/chains", h.SetProxyChains)
+
+	// 节点标签（代理组按标签自动填充节点）
+	r.GET("/nodes/tags", h.GetNodeTags)
+	r.PUT("/nodes/tags", h.SetNodeTags)
+	r.GET("/nodes/tags/preview", h.PreviewNodeTags)
+	r.GET("/nodes/rename", h.GetNodeRename)
+	r.PUT("/nodes/rename", h.SetNodeRename)
+	r.GET("/nodes/rename/preview", h.PreviewNodeRename)
+	r.GET("/nodes/dedup", h.GetNodeDedup)
+	r.PUT("/nodes/dedup", h.SetNodeDedup)
+	r.GET("/transparent/conflicts", h.GetFirewallConflicts) // 与其他防火墙管理工具的冲突检查
+	r.PUT("/transparent/conflicts/policy", h.SetFirewallConflictPolicy)
+	r.GET("/transparent/export", h.ExportTransparentRules) // 导出规则脚本（手动应用）
+	r.GET("/transparent/template", h.GetNftTemplate)       // nftables 规则模板
+	r.PUT("/transparent/template", h.SetNftTemplate)
+	r.GET("/transparent/watchdog", h.GetTransparentWatchdog) // 连通性看门狗（断网时自动关闭透明代理）
+	r.PUT("/transparent/watchdog", h.SetTransparentWatchdog)
+	configVersion := h.versioned(h.service.ConfigETag)
+	r.GET("/config", configVersion, h.GetConfig)
+	r.PUT("/config", configVersion, h.UpdateConfig)
 	r.POST("/generate", h.GenerateConfig)
+	r.GET("/secret", h.GetAPISecretInfo) // 核心 API 密钥（仅管理员）
+	r.POST("/secret/rotate", h.RotateAPISecret)
 	r.GET("/config/preview", h.GetConfigPreview)
+	r.POST("/config/validate", h.ValidateConfig) // Mihomo 配置校验
+	r.GET("/config/history", h.GetConfigHistory) // 配置历史
+	r.GET("/config/history/:id/diff", h.DiffConfigHistory)
+	r.POST("/config/history/:id/rollback", h.RollbackConfigHistory)
+	r.GET("/config/share", h.GetProfileShare) // 配置托管（其他设备订阅生成的配置）
+	r.PUT("/config/share", h.SetProfileShare)
+	r.POST("/config/share/rotate", h.RotateProfileShareToken)
+	r.GET("/config/override", h.GetConfigOverride) // 配置覆写（深度合并到生成的配置）
+	r.PUT("/config/override", h.UpdateConfigOverride)
+
+	// 配置方案
+	r.GET("/profiles", h.ListProfiles)
+	r.POST("/profiles", h.CreateProfile)
+	r.PUT("/profiles/:id", h.UpdateProfile)
+	r.DELETE("/profiles/:id", h.DeleteProfile)
+	r.POST("/profiles/:id/clone", h.CloneProfile)
+	r.POST("/profiles/:id/activate", h.ActivateProfile)
 	r.GET("/logs", h.GetLogs)
-
-	// 配置模板管理
-	r.GET("/template", h.GetConfigTemplate)
-	r.PUT("/template/groups", h.UpdateProxyGroups)
-	r.PUT("/template/rules", h.UpdateRules)
-	r.PUT("/template/providers", h.UpdateRuleProviders)
-	r.POST("/template/reset", h.ResetTemplate)
+	r.GET("/logs/crashes", h.GetCrashEvents)
+	r.GET("/logs/files", h.GetLogFiles)     // 日志文件列表（含轮转归档）
+	r.GET("/logs/download", h.DownloadLogs) // 下载日志文件，不指定 file 时打包全部
+
+	// 配置模板管理（所有子资源共用模板的 ETag）
+	templateVersion := h.versioned(h.service.TemplateETag)
+	r.GET("/template", templateVersion, h.GetConfigTemplate)
+	r.PUT("/template/groups", templateVersion, h.UpdateProxyGroups)
+	r.PUT("/template/rules", templateVersion, h.UpdateRules)
+	r.POST("/template/rules", templateVersion, h.InsertRule)
+	r.POST("/template/rules/toggle", templateVersion, h.ToggleRules)
+	r.PUT("/template/rules/:id", templateVersion, h.UpdateRule)
+	r.POST("/template/rules/:id/move", templateVersion, h.MoveRule)
+	r.DELETE("/template/rules/:id", templateVersion, h.DeleteRule)
+	r.PUT("/template/providers", templateVersion, h.UpdateRuleProviders)
+	r.POST("/template/reset", templateVersion, h.ResetTemplate)
+	r.POST("/template/import", templateVersion, h.ImportTemplate)
+	r.GET("/template/bundle", h.ExportTemplateBundle) // 模板包（Mihomo、Sing-Box 模板和透明代理设置）
+	r.POST("/template/bundle", templateVersion, h.ImportTemplateBundle)
+	r.GET("/template/dns", templateVersion, h.GetTemplateDNS) // 自定义 DNS（上游、fake-ip、hosts）
+	r.PUT("/template/dns", templateVersion, h.UpdateTemplateDNS)
+	r.POST("/template/dns/test", h.TestDNS)
+	r.GET("/template/dns/fake-ip-filter", templateVersion, h.GetFakeIPFilter) // fake-ip 排除列表（返回真实 IP 的域名）
+	r.PUT("/template/dns/fake-ip-filter", templateVersion, h.SetFakeIPFilter)
+
+	// 规则匹配测试
+	r.POST("/rules/test", h.TestRule)
 
 	// Sing-Box 配置生成
 	r.POST("/singbox/generate", h.GenerateSingBoxConfig)
@@ -92,16 +204,36 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/singbox/template", h.GetSingBoxTemplate)
 	r.PUT("/singbox/template", h.UpdateSingBoxTemplate)
 	r.POST("/singbox/template/reset", h.ResetSingBoxTemplate)
+	r.POST("/singbox/template/convert-from-clash", h.ConvertSingBoxTemplateFromClash)
+	r.GET("/singbox/override", h.GetSingBoxOverride) // 配置覆写（JSON Merge Patch）
+	r.PUT("/singbox/override", h.UpdateSingBoxOverride)
 
 	// Mihomo API 代理 (避免 CORS 问题)
-	r.GET("/mihomo/proxies", h.ProxyMihomoGetProxies)
-	r.GET("/mihomo/proxies/:name", h.ProxyMihomoGetProxy)
-	r.PUT("/mihomo/proxies/:name", h.ProxyMihomoSelectProxy)
-	r.GET("/mihomo/proxies/:name/delay", h.ProxyMihomoTestDelay)
+	r.Any("/mihomo/*path", h.ProxyMihomoAPI)
+
+	// 活动连接（补充进程信息）
+	r.GET("/connections", h.GetConnections)
+	r.DELETE("/connections/:id", h.CloseConnection)
+
+	// 流量统计
+	r.GET("/stats/traffic", h.GetTrafficStats)
+	r.GET("/stats/latency", h.GetLatencyStats) // 自动选择代理组成员的延迟历史
+	r.GET("/stats/rules", h.GetRuleStats)      // 规则命中统计（按连接采样）
+	r.DELETE("/stats/rules", h.ResetRuleStats)
+
+	// 代理集合和规则集合
+	r.GET("/providers", h.GetProviders)
+	r.POST("/providers/refresh", h.RefreshProviders) // 刷新所有远程集合，?stale=true 只刷新过期的
+	r.GET("/providers/schedule", h.GetProviderSchedule)
+	r.PUT("/providers/schedule", h.SetProviderSchedule)
+	r.POST("/providers/:kind/:name/refresh", h.RefreshProvider)
+	r.POST("/providers/proxy/:name/healthcheck", h.HealthCheckProvider)
 }
 
 func (h *Handler) GetStatus(c *gin.Context) {
 	status := h.service.GetStatus()
+	status.Config = h.service.activeConfigStatus()
+	status.Transparent = h.transparentApplyState(status)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -111,10 +243,12 @@ func (h *Handler) GetStatus(c *gin.Context) {
 
 func (h *Handler) Start(c *gin.Context) {
 	if err := h.service.Start(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		var conflict *PortConflictError
+		if errors.As(err, &conflict) {
+			apierror.Respond(c, http.StatusConflict, apierror.Wrap(apierror.CodePortInUse, err).WithDetails(gin.H{"conflicts": conflict.Conflicts}))
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	// nftables 规则由 onStartCallback 自动应用
@@ -126,10 +260,7 @@ func (h *Handler) Start(c *gin.Context) {
 
 func (h *Handler) Stop(c *gin.Context) {
 	if err := h.service.Stop(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	// nftables 规则由 onStopCallback 自动清除
@@ -143,35 +274,74 @@ func (h *Handler) Restart(c *gin.Context) {
 	// service.Restart() 内部调用 Stop() + Start()
 	// onStopCallback 清除规则，onStartCallback 重新应用规则
 	if err := h.service.Restart(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Reload 热重载配置
+// Mihomo 通过 PUT /configs?force=true 推送新配置，保留现有连接；失败或 sing-box 时回退为重启
+func (h *Handler) Reload(c *gin.Context) {
+	method, err := h.reloadCore()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"method": method,
+		},
 	})
 }
 
+// reloadCore 重新生成配置并让运行中的核心加载，返回实际使用的方式（reload / restart）
+func (h *Handler) reloadCore() (string, error) {
+	if !h.service.GetStatus().Running {
+		return "", ErrCoreNotRunning
+	}
+
+	runner := runnerFor(h.service.GetCoreType())
+	if runner.Type() == "mihomo" || runner.SupportsSignalReload() {
+		configPath, err := h.service.regenerateConfig("reload")
+		if err != nil {
+			return "", fmt.Errorf("生成配置失败: %w", err)
+		}
+		if runner.Type() == "mihomo" {
+			err = h.pushMihomoConfig(configPath)
+		} else {
+			err = h.service.ReloadBySignal()
+		}
+		if err != nil {
+			fmt.Printf("⚠️ 热重载失败，改为重启核心: %v\n", err)
+		} else {
+			fmt.Println("✓ 配置已热重载")
+			return "reload", nil
+		}
+	}
+
+	if err := h.service.Restart(); err != nil {
+		return "", err
+	}
+	return "restart", nil
+}
+
 func (h *Handler) SetMode(c *gin.Context) {
 	var req struct {
 		Mode string `json:"mode" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SetMode(req.Mode); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -185,14 +355,14 @@ func (h *Handler) SetMode(c *gin.Context) {
 // scope: local (仅本机 Output 链), router (本机+局域网 Prerouting+Output 链)
 func (h *Handler) SetTransparentMode(c *gin.Context) {
 	var req struct {
-		Mode  string `json:"mode"`
-		Scope string `json:"scope"` // local | router
+		Mode      string `json:"mode"`
+		Scope     string `json:"scope"`     // local | router
+		DNSHijack *bool  `json:"dnsHijack"` // 可选：劫持 DNS 查询到核心
+		IPv6      *bool  `json:"ipv6"`      // 可选：是否拦截 IPv6 流量
+		BlockQUIC *bool  `json:"blockQuic"` // 可选：拦截 QUIC（UDP 443）
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -204,76 +374,155 @@ func (h *Handler) SetTransparentMode(c *gin.Context) {
 	// 仅保存配置，不立即操作 nftables
 	// nftables 规则在核心启动时应用，停止时清除
 	if err := h.service.SetTransparentMode(req.Mode, req.Scope); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
+	if req.DNSHijack != nil {
+		if err := h.service.SetDNSHijack(*req.DNSHijack); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if req.IPv6 != nil {
+		if err := h.service.SetTransparentIPv6(*req.IPv6); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if req.BlockQUIC != nil {
+		if err := h.service.SetBlockQUIC(*req.BlockQUIC); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	modeDesc := map[string]string{
 		"off":      "已保存：关闭透明代理（启动核心后不添加规则，停止时清除已有规则）",
+		"system":   "已保存：系统代理模式（启动核心后设置系统代理，停止时恢复原有设置）",
 		"tproxy":   "已保存：TProxy 模式（启动核心后自动添加 nftables TPROXY 规则）",
 		"redirect": "已保存：Redirect 模式（启动核心后自动添加 nftables REDIRECT 规则）",
+		"tun":      "已保存：TUN 模式（启动核心后由核心创建 TUN 网卡并配置路由，不使用 nftables 规则）",
+	}
+
+	h.service.publish(events.TransparentChanged, map[string]interface{}{
+		"mode":  req.Mode,
+		"scope": req.Scope,
+	})
+
+	// 提前告知其他防火墙管理工具的规则，避免启动核心后才发现回环
+	conflicts := []FirewallConflict{}
+	if isTransparentMode(req.Mode) && h.service.firewallConflictPolicy() != conflictPolicyIgnore {
+		conflicts = detectFirewallConflicts()
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": modeDesc[req.Mode],
 		"data": gin.H{
-			"mode":  req.Mode,
-			"scope": req.Scope,
+			"mode":      req.Mode,
+			"scope":     req.Scope,
+			"dnsHijack": h.service.GetConfig().DNSHijack,
+			"ipv6":      h.service.GetConfig().TransparentIPv6,
+			"blockQuic": h.service.GetConfig().BlockQUIC,
+			"conflicts": conflicts,
 		},
 	})
 }
 
+// reapplyTransparentRules 透明代理设置变更后重新应用规则
+// 只在 Linux 上核心运行中且透明代理已开启时生效，其他情况下新设置在下次启动时应用
+func (h *Handler) reapplyTransparentRules() error {
+	status := h.service.GetStatus()
+	if runtime.GOOS != "linux" || !status.Running || !isTransparentMode(status.TransparentMode) {
+		return nil
+	}
+	return h.applyNftRules(status.TransparentMode, status.ProxyScope)
+}
+
 // applyNftRules 根据模式和作用域应用或清除 nftables 规则
-func (h *Handler) applyNftRules(mode, scope string) error {
-	// 先清除已有规则
-	h.clearNftRules()
+// 新表在同一事务中替换旧表，规则切换期间不会出现无规则的空窗；
+// 加载失败时旧规则保持不变，之后的校验或连通性探测（需开启）失败时自动回滚
+func (h *Handler) applyNftRules(mode, scope string) (err error) {
+	defer func() {
+		h.marksMu.Lock()
+		h.rulesErr = err
+		h.marksMu.Unlock()
+		if err != nil {
+			h.service.recordError("transparent", err)
+		}
+	}()
 
 	if mode == "off" {
+		h.clearNftRules()
 		fmt.Println("✓ nftables 透明代理规则已清除")
 		return nil
 	}
 
 	// 获取当前端口配置
-	cfg := h.service.GetConfig()
-	var listenPort int
-	if mode == "tproxy" {
-		listenPort = cfg.TProxyPort
-		if listenPort == 0 {
-			listenPort = 7893
-		}
-	} else { // redirect
-		listenPort = cfg.RedirPort
-		if listenPort == 0 {
-			listenPort = 7892
-		}
+	listenPort := transparentListenPort(h.service.GetConfig(), mode)
+
+	// 其他工具的拦截规则与本规则叠加会形成回环
+	if err := h.checkFirewallCoexistence(); err != nil {
+		return err
+	}
+
+	// 其他工具占用相同标记或路由表时规则可能不生效，只提示不阻止
+	marks := h.service.transparentMarks()
+	for _, conflict := range detectMarkConflicts(marks) {
+		fmt.Printf("⚠️ 标记冲突: %s\n", conflict)
 	}
 
 	// 生成 nftables 规则
-	nftScript := h.buildNftScript(mode, scope, listenPort)
+	nftScript, err := h.buildNftScript(mode, scope, listenPort, marks)
+	if err != nil {
+		return err
+	}
+
+	// 检查规则（语法、内核模块），失败时现有规则保持不变
+	if err := h.netfilter.CheckRuleset(nftReplacePrefix + nftScript); err != nil {
+		return err
+	}
 
-	// 执行 nft -f -
-	cmd := exec.Command("nft", "-f", "-")
-	cmd.Stdin = strings.NewReader(nftScript)
-	output, err := cmd.CombinedOutput()
+	// 记录现有规则用于回滚
+	snapshot, err := h.snapshotNftState()
 	if err != nil {
-		return fmt.Errorf("nft 执行失败: %v, 输出: %s", err, string(output))
+		fmt.Printf("⚠️ 记录现有 nftables 规则失败，失败时只能清除规则: %v\n", err)
 	}
 
-	// 添加策略路由（tproxy 模式需要）
-	if mode == "tproxy" {
-		if err := h.setupPolicyRouting(); err != nil {
-			return fmt.Errorf("策略路由设置失败: %v", err)
+	// 连通性探测需用户开启；规则应用前网络本身不通时跳过探测
+	probeTarget := h.applyProbeURL()
+	reachable := probeTarget != "" && probeConnectivity(probeTarget) == nil
+
+	// 删除旧表和加载新表在同一事务中完成，失败时内核中的规则保持不变
+	if err := h.netfilter.ApplyRuleset(nftReplacePrefix + nftScript); err != nil {
+		return err
+	}
+
+	// 新规则生效后再调整策略路由
+	if err := h.replacePolicyRouting(mode, marks); err != nil {
+		return h.rollbackNftRules(snapshot, fmt.Errorf("策略路由设置失败: %v", err))
+	}
+
+	if err := h.verifyNftRules(mode, scope, marks); err != nil {
+		return h.rollbackNftRules(snapshot, err)
+	}
+	if err := waitListenPort(listenPort, transparentPortWait); err != nil {
+		return h.rollbackNftRules(snapshot, err)
+	}
+	if reachable {
+		if err := probeConnectivity(probeTarget); err != nil {
+			return h.rollbackNftRules(snapshot, fmt.Errorf("连通性探测失败: %v", err))
 		}
+	} else if probeTarget != "" {
+		fmt.Println("ℹ️ 规则应用前网络不可达，跳过连通性探测")
 	}
 
 	// 启用 IP 转发（路由器模式需要）
 	if scope == "router" {
 		exec.Command("sysctl", "-w", "net.ipv4.ip_forward=1").Run()
-		exec.Command("sysctl", "-w", "net.ipv6.conf.all.forwarding=1").Run()
+		if h.transparentIPv6Enabled() {
+			exec.Command("sysctl", "-w", "net.ipv6.conf.all.forwarding=1").Run()
+		}
 		fmt.Println("✓ IP 转发已启用（路由器模式）")
 	}
 
@@ -281,183 +530,159 @@ func (h *Handler) applyNftRules(mode, scope string) error {
 	return nil
 }
 
-// buildNftScript 生成 nftables 规则脚本
-func (h *Handler) buildNftScript(mode, scope string, port int) string {
-	const mark = 1
-	const serverMark = 255
-	const tableName = "inet proxystation"
-
-	// prerouting 链（仅路由器模式需要，处理局域网设备流量）
-	preroutingRules := ""
-	if scope == "router" {
-		if mode == "tproxy" {
-			preroutingRules = fmt.Sprintf(`
-    chain prerouting {
-        type filter hook prerouting priority mangle; policy accept;
-
-        # IPSec 不代理
-        udp dport { 500, 4500, 1701 } return
-        meta l4proto esp return
-
-        # 已建立的 transparent socket 连接直接打标记
-        meta l4proto { tcp, udp } socket transparent 1 meta mark set %d accept
-
-        # 本地地址不代理
-        ip daddr @local_nets return
-        ip6 daddr @local_nets6 return
-
-        # TCP/UDP 流量 TProxy 到 mihomo
-        meta l4proto { tcp, udp } tproxy to :%d meta mark set %d accept
-    }`, mark, port, mark)
-		} else { // redirect
-			preroutingRules = fmt.Sprintf(`
-    chain prerouting {
-        type filter hook prerouting priority mangle; policy accept;
-
-        # IPSec 不代理
-        udp dport { 500, 4500, 1701 } return
-        meta l4proto esp return
-
-        # 本地地址不代理
-        ip daddr @local_nets return
-        ip6 daddr @local_nets6 return
-
-        # TCP 流量 REDIRECT 到 mihomo (redirect 不支持 UDP)
-        meta l4proto tcp redirect to :%d
-    }`, port)
+// transparentListenPort 透明代理模式对应的核心监听端口
+func transparentListenPort(cfg *ProxyConfig, mode string) int {
+	if mode == "tproxy" {
+		if cfg.TProxyPort == 0 {
+			return 7893
 		}
+		return cfg.TProxyPort
+	}
+	// redirect
+	if cfg.RedirPort == 0 {
+		return 7892
+	}
+	return cfg.RedirPort
+}
+
+// ReconcileTransparentMode 启动时根据已保存的透明代理模式对齐内核状态
+// 后端重启后内核中可能残留上一次的 nftables 规则，或规则已丢失：
+//   - 保存的模式为 off：清除残留规则
+//   - 核心已在运行（或监听端口已被占用）：重新应用 nft 规则和策略路由
+//   - 核心未运行：清除残留规则，等待核心启动时由 onStartCallback 应用
+func (h *Handler) ReconcileTransparentMode() {
+	if runtime.GOOS != "linux" {
+		return
 	}
 
-	// output 链（本机流量，所有模式都需要）
-	var outputRules string
-	if mode == "tproxy" {
-		outputRules = fmt.Sprintf(`
-    chain output {
-        type route hook output priority mangle; policy accept;
-
-        # IPSec 不代理
-        udp dport { 500, 4500, 1701 } return
-        meta l4proto esp return
-
-        # 本地地址不代理
-        ip daddr @local_nets return
-        ip6 daddr @local_nets6 return
-
-        # 已标记的包跳过（避免循环）
-        meta mark %d return
-
-        # 入站服务端流量不代理（mark %d）
-        meta mark %d return
-
-        # 本机出站 TCP/UDP 打标记（触发重路由到 prerouting）
-        meta l4proto { tcp, udp } meta mark set %d
-    }`, mark, serverMark, serverMark, mark)
-	} else { // redirect
-		outputRules = fmt.Sprintf(`
-    chain output {
-        type route hook output priority mangle; policy accept;
-
-        # IPSec 不代理
-        udp dport { 500, 4500, 1701 } return
-        meta l4proto esp return
-
-        # 本地地址不代理
-        ip daddr @local_nets return
-        ip6 daddr @local_nets6 return
-
-        # 已标记的包跳过（避免循环）
-        meta mark %d return
-
-        # 入站服务端流量不代理
-        meta mark %d return
-
-        # 本机出站 TCP REDIRECT 到 mihomo
-        meta l4proto tcp redirect to :%d
-    }`, mark, serverMark, port)
-	}
-
-	script := fmt.Sprintf(`table %s {
-    set local_nets {
-        type ipv4_addr
-        flags interval
-        elements = {
-            0.0.0.0/8,
-            10.0.0.0/8,
-            100.64.0.0/10,
-            127.0.0.0/8,
-            169.254.0.0/16,
-            172.16.0.0/12,
-            192.168.0.0/16,
-            224.0.0.0/4,
-            240.0.0.0/4
-        }
-    }
-
-    set local_nets6 {
-        type ipv6_addr
-        flags interval
-        elements = {
-            ::1/128,
-            fc00::/7,
-            fe80::/10,
-            ff00::/8
-        }
-    }
-%s
-%s
-}`, tableName, preroutingRules, outputRules)
-
-	return script
+	status := h.service.GetStatus()
+	mode := status.TransparentMode
+	scope := status.ProxyScope
+	if !isTransparentMode(mode) {
+		mode = "off"
+	}
+	if scope == "" {
+		scope = "local"
+	}
+
+	if mode == "off" {
+		h.clearNftRules()
+		return
+	}
+
+	listenPort := transparentListenPort(h.service.GetConfig(), mode)
+	if !status.Running && !h.service.isPortInUse(listenPort) {
+		h.clearNftRules()
+		fmt.Printf("ℹ️ 已保存透明代理模式 %s，核心启动后将自动应用规则\n", mode)
+		return
+	}
+
+	if err := h.applyNftRules(mode, scope); err != nil {
+		fmt.Printf("⚠️ 恢复透明代理规则失败: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ 已恢复透明代理模式 %s（scope=%s）\n", mode, scope)
+}
+
+// SwitchTransparentMode 切换透明代理模式，核心运行中时重启核心使新模式立即生效
+// 模式切换可能需要重新生成核心配置（tproxy 端口、TUN），重启后规则由启动回调应用
+func (h *Handler) SwitchTransparentMode(mode, scope string) error {
+	if scope == "" {
+		scope = "local"
+	}
+	if err := h.service.SetTransparentMode(mode, scope); err != nil {
+		return err
+	}
+	h.service.publish(events.TransparentChanged, map[string]interface{}{
+		"mode":  mode,
+		"scope": scope,
+	})
+
+	if !h.service.GetStatus().Running {
+		return nil
+	}
+	return h.service.Restart()
 }
 
 // setupPolicyRouting 设置 tproxy 所需的策略路由
-func (h *Handler) setupPolicyRouting() error {
-	const tableID = 100
-	const mark = 1
+func (h *Handler) setupPolicyRouting(marks TransparentMarks) error {
+	mark, tableID := marks.Mark, marks.TableID
+
+	h.marksMu.Lock()
+	h.appliedMarks = &marks
+	h.marksMu.Unlock()
 
 	// IPv4 策略路由
-	exec.Command("ip", "rule", "add", "fwmark", fmt.Sprintf("%d", mark), "lookup", fmt.Sprintf("%d", tableID)).Run()
-	if out, err := exec.Command("ip", "route", "add", "local", "0.0.0.0/0", "dev", "lo", "table", fmt.Sprintf("%d", tableID)).CombinedOutput(); err != nil {
-		// 路由可能已存在，忽略 EEXIST
-		if !strings.Contains(string(out), "exists") {
-			return fmt.Errorf("添加 IPv4 策略路由失败: %v, %s", err, string(out))
-		}
+	if err := h.netfilter.AddPolicyRoute(PolicyRoute{Mark: mark, TableID: tableID}); err != nil {
+		return fmt.Errorf("添加 IPv4 策略路由失败: %w", err)
 	}
 
-	// IPv6 策略路由
-	exec.Command("ip", "-6", "rule", "add", "fwmark", fmt.Sprintf("%d", mark), "table", fmt.Sprintf("%d", tableID)).Run()
-	exec.Command("ip", "-6", "route", "add", "local", "::/0", "dev", "lo", "table", fmt.Sprintf("%d", tableID)).Run()
+	// IPv6 策略路由（未拦截 IPv6 时不需要）
+	if h.transparentIPv6Enabled() {
+		if err := h.netfilter.AddPolicyRoute(PolicyRoute{IPv6: true, Mark: mark, TableID: tableID}); err != nil {
+			return fmt.Errorf("添加 IPv6 策略路由失败: %w", err)
+		}
+		// 校验 IPv6 策略路由确实生效
+		check := &TransparentStatus{Marks: marks}
+		h.inspectPolicyRouting(check)
+		if !check.PolicyRouting.IPv6Rule || !check.PolicyRouting.IPv6Route {
+			return fmt.Errorf("IPv6 策略路由未生效，可在透明代理设置中关闭 IPv6 拦截")
+		}
+	}
 
 	fmt.Printf("✓ 策略路由已配置 (fwmark %d -> table %d)\n", mark, tableID)
 	return nil
 }
 
+// replacePolicyRouting 按新模式调整策略路由
+// tproxy 模式添加当前标记的策略路由并删除旧标记留下的路由，其他模式删除全部策略路由
+func (h *Handler) replacePolicyRouting(mode string, marks TransparentMarks) error {
+	if mode != "tproxy" {
+		h.clearPolicyRouting()
+		return nil
+	}
+
+	h.marksMu.Lock()
+	previous := h.appliedMarks
+	h.marksMu.Unlock()
+
+	if err := h.setupPolicyRouting(marks); err != nil {
+		return err
+	}
+	if previous != nil && *previous != marks {
+		h.deletePolicyRoutes(*previous)
+	}
+	return nil
+}
+
 // clearNftRules 清除所有 nftables 规则和策略路由
 func (h *Handler) clearNftRules() {
-	const tableName = "inet proxystation"
-	const tableID = 100
-	const mark = 1
-
 	// 删除 nftables 表
-	exec.Command("nft", "delete", "table", tableName).Run()
+	if err := h.netfilter.DeleteTable("inet", "proxystation"); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	h.clearPolicyRouting()
+}
 
-	// 循环删除策略路由（可能有多条）
-	for i := 0; i < 5; i++ {
-		cmd := exec.Command("ip", "rule", "del", "fwmark", fmt.Sprintf("%d", mark), "lookup", fmt.Sprintf("%d", tableID))
-		if err := cmd.Run(); err != nil {
-			break
-		}
+// clearPolicyRouting 删除当前配置和上次应用的标记对应的策略路由
+func (h *Handler) clearPolicyRouting() {
+	marks := []TransparentMarks{h.service.transparentMarks()}
+	h.marksMu.Lock()
+	if h.appliedMarks != nil && *h.appliedMarks != marks[0] {
+		marks = append(marks, *h.appliedMarks)
 	}
-	exec.Command("ip", "route", "del", "local", "0.0.0.0/0", "dev", "lo", "table", fmt.Sprintf("%d", tableID)).Run()
+	h.appliedMarks = nil
+	h.marksMu.Unlock()
 
-	// IPv6
-	for i := 0; i < 5; i++ {
-		cmd := exec.Command("ip", "-6", "rule", "del", "fwmark", fmt.Sprintf("%d", mark), "table", fmt.Sprintf("%d", tableID))
-		if err := cmd.Run(); err != nil {
-			break
-		}
+	for _, m := range marks {
+		h.deletePolicyRoutes(m)
 	}
-	exec.Command("ip", "-6", "route", "del", "local", "::/0", "dev", "lo", "table", fmt.Sprintf("%d", tableID)).Run()
+}
+
+// deletePolicyRoutes 删除指定标记的 IPv4 / IPv6 策略路由
+func (h *Handler) deletePolicyRoutes(marks TransparentMarks) {
+	h.netfilter.DeletePolicyRoute(PolicyRoute{Mark: marks.Mark, TableID: marks.TableID})
+	h.netfilter.DeletePolicyRoute(PolicyRoute{IPv6: true, Mark: marks.Mark, TableID: marks.TableID})
 }
 
 func (h *Handler) GetConfig(c *gin.Context) {
@@ -473,18 +698,12 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 	// 使用 map 接收部分更新
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.PatchConfig(updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -500,6 +719,21 @@ func (h *Handler) GenerateConfig(c *gin.Context) {
 	// 允许空 body，此时自动获取节点
 	c.ShouldBindJSON(&req)
 
+	// 试运行：只返回与当前配置的差异，不写入磁盘
+	if c.Query("dryRun") == "true" {
+		result, err := h.service.DryRunConfig(req.Nodes)
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data":    result,
+		})
+		return
+	}
+
 	var configPath string
 	var err error
 
@@ -512,10 +746,7 @@ func (h *Handler) GenerateConfig(c *gin.Context) {
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -529,7 +760,12 @@ func (h *Handler) GenerateConfig(c *gin.Context) {
 }
 
 // GetConfigPreview 获取生成的 config.yaml 内容用于预览
+// 凭据默认脱敏，?reveal=true 返回完整内容
 func (h *Handler) GetConfigPreview(c *gin.Context) {
+	reveal, ok := checkReveal(c)
+	if !ok {
+		return
+	}
 	content, err := h.service.GetConfigContent()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -542,15 +778,23 @@ func (h *Handler) GetConfigPreview(c *gin.Context) {
 		return
 	}
 
+	if !reveal {
+		content = redactYAML(content)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"content": content,
+			"content":  content,
+			"redacted": !reveal,
 		},
 	})
 }
 
+// GetLogs 获取核心日志
+// 参数: limit 条数, level 级别；指定 since/until/q 时在日志文件（含轮转归档）中搜索
+// since/until 支持 RFC3339、"2006-01-02 15:04:05"、Unix 秒或相对时间（如 2h）；q 为子串，regex=true 时按正则匹配
 func (h *Handler) GetLogs(c *gin.Context) {
 	// 获取参数
 	limitStr := c.DefaultQuery("limit", "200")
@@ -561,25 +805,17 @@ func (h *Handler) GetLogs(c *gin.Context) {
 		limit = l
 	}
 
+	if c.Query("since") != "" || c.Query("until") != "" || c.Query("q") != "" {
+		h.searchLogs(c, level, limit)
+		return
+	}
+
 	logs := h.service.GetLogs(limit)
 
 	// 根据级别过滤
 	var filteredLogs []string
 	for _, log := range logs {
-		switch level {
-		case "error":
-			if strings.Contains(log, "ERR") || strings.Contains(log, "FATA") || strings.Contains(log, "error") {
-				filteredLogs = append(filteredLogs, log)
-			}
-		case "warn":
-			if strings.Contains(log, "WARN") || strings.Contains(log, "warning") {
-				filteredLogs = append(filteredLogs, log)
-			}
-		case "info":
-			if strings.Contains(log, "INFO") || strings.Contains(log, "info") {
-				filteredLogs = append(filteredLogs, log)
-			}
-		default:
+		if matchLogLevel(log, level) {
 			filteredLogs = append(filteredLogs, log)
 		}
 	}
@@ -591,6 +827,99 @@ func (h *Handler) GetLogs(c *gin.Context) {
 	})
 }
 
+// searchLogs 在日志文件中按时间范围和关键字搜索
+func (h *Handler) searchLogs(c *gin.Context, level string, limit int) {
+	now := time.Now()
+	query := LogQuery{Level: level, Limit: limit}
+	var err error
+	if query.Since, err = parseLogTime(c.Query("since"), now); err == nil {
+		query.Until, err = parseLogTime(c.Query("until"), now)
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if q := c.Query("q"); q != "" {
+		if c.Query("regex") == "true" {
+			if query.Pattern, err = regexp.Compile(q); err != nil {
+				apierror.Message(c, http.StatusBadRequest, "正则表达式无效: "+err.Error())
+				return
+			}
+		} else {
+			query.Text = strings.ToLower(q)
+		}
+	}
+
+	logs, err := h.service.logFile.Search(query)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    logs,
+	})
+}
+
+// GetLogFiles 获取核心日志文件列表
+func (h *Handler) GetLogFiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.logFile.Files(),
+	})
+}
+
+// DownloadLogs 下载核心日志
+// 参数: file 日志文件名（见 /logs/files），为空时打包下载全部日志
+func (h *Handler) DownloadLogs(c *gin.Context) {
+	name := c.Query("file")
+	if name == "" {
+		filename := fmt.Sprintf("proxystation-logs-%s.tar.gz", time.Now().Format("20060102-150405"))
+		c.Header("Content-Type", "application/gzip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		// 响应头已发送，出错时只能中断传输
+		if err := h.service.logFile.WriteArchive(c.Writer); err != nil {
+			fmt.Printf("⚠️ 打包日志失败: %v\n", err)
+			c.Abort()
+		}
+		return
+	}
+
+	file, err := h.service.logFile.Open(name)
+	if err != nil {
+		apierror.Message(c, http.StatusNotFound, "日志文件不存在")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if strings.HasSuffix(name, ".gz") {
+		contentType = "application/gzip"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	// 当前文件仍在写入，按打开时的大小截取
+	c.DataFromReader(http.StatusOK, info.Size(), contentType, io.LimitReader(file, info.Size()), nil)
+}
+
+// GetCrashEvents 获取核心崩溃记录
+func (h *Handler) GetCrashEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetCrashEvents(),
+	})
+}
+
 // GetConfigTemplate 获取配置模板
 func (h *Handler) GetConfigTemplate(c *gin.Context) {
 	template := h.service.GetConfigTemplate()
@@ -605,18 +934,17 @@ func (h *Handler) GetConfigTemplate(c *gin.Context) {
 func (h *Handler) UpdateProxyGroups(c *gin.Context) {
 	var groups []ProxyGroupTemplate
 	if err := c.ShouldBindJSON(&groups); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.UpdateProxyGroups(groups); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		var groupErrs ProxyGroupErrors
+		if errors.As(err, &groupErrs) {
+			apierror.Respond(c, http.StatusOK, apierror.Wrap(apierror.CodeValidation, groupErrs).WithDetails(gin.H{"errors": groupErrs}))
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -630,18 +958,12 @@ func (h *Handler) UpdateProxyGroups(c *gin.Context) {
 func (h *Handler) UpdateRules(c *gin.Context) {
 	var rules []RuleTemplate
 	if err := c.ShouldBindJSON(&rules); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.UpdateRules(rules); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -655,18 +977,12 @@ func (h *Handler) UpdateRules(c *gin.Context) {
 func (h *Handler) UpdateRuleProviders(c *gin.Context) {
 	var providers []RuleProviderTemplate
 	if err := c.ShouldBindJSON(&providers); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.UpdateRuleProviders(providers); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -685,112 +1001,6 @@ func (h *Handler) ResetTemplate(c *gin.Context) {
 	})
 }
 
-// ========== Mihomo API 代理 (避免 CORS 问题) ==========
-
-// ProxyMihomoGetProxies 代理获取所有代理组
-func (h *Handler) ProxyMihomoGetProxies(c *gin.Context) {
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
-	}
-
-	resp, err := http.Get("http://" + apiAddr + "/proxies")
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
-}
-
-// ProxyMihomoGetProxy 代理获取单个代理组
-func (h *Handler) ProxyMihomoGetProxy(c *gin.Context) {
-	name := c.Param("name")
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
-	}
-
-	resp, err := http.Get("http://" + apiAddr + "/proxies/" + name)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
-}
-
-// ProxyMihomoSelectProxy 代理切换节点
-func (h *Handler) ProxyMihomoSelectProxy(c *gin.Context) {
-	name := c.Param("name")
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
-	}
-
-	body, _ := io.ReadAll(c.Request.Body)
-	req, _ := http.NewRequest("PUT", "http://"+apiAddr+"/proxies/"+name, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", respBody)
-}
-
-// ProxyMihomoTestDelay 代理测试节点延迟
-func (h *Handler) ProxyMihomoTestDelay(c *gin.Context) {
-	name := c.Param("name")
-	url := c.Query("url")
-	timeout := c.Query("timeout")
-
-	if url == "" {
-		url = "http://www.gstatic.com/generate_204"
-	}
-	if timeout == "" {
-		timeout = "5000"
-	}
-
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
-	}
-
-	targetURL := fmt.Sprintf("http://%s/proxies/%s/delay?url=%s&timeout=%s", apiAddr, name, url, timeout)
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(targetURL)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
-}
-
 // ========== Sing-Box 1.12+ 配置生成 ==========
 
 // GenerateSingBoxConfig 生成 Sing-Box 1.12+ 配置
@@ -848,31 +1058,25 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 	// 获取所有节点
 	nodes, err := h.service.GetAllNodes()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "获取节点失败: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusInternalServerError, "获取节点失败: "+err.Error())
 		return
 	}
 
-	// 生成配置
-	generator := NewSingboxGenerator(h.service.dataDir)
-	config, err := generator.GenerateConfigV112(nodes, opts)
+	// 经配置生成管线生成（节点重命名、去重、广告拦截、设备策略、覆写等）
+	content, err := h.service.RenderSingBoxConfig(nodes, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "生成配置失败: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusInternalServerError, "生成配置失败: "+err.Error())
 		return
 	}
 
 	// 保存配置
-	filePath, err := generator.SaveConfigV112(config, "singbox-config")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "保存配置失败: " + err.Error(),
-		})
+	filePath := filepath.Join(h.service.dataDir, "configs", "singbox-config.json")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "保存配置失败: "+err.Error())
+		return
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "保存配置失败: "+err.Error())
 		return
 	}
 
@@ -888,20 +1092,22 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 			if errorMsg == "" {
 				errorMsg = checkErr.Error()
 			}
-			c.JSON(http.StatusOK, gin.H{
-				"code":    2, // 使用 code 2 表示配置验证失败
-				"message": "配置验证失败",
-				"data": gin.H{
-					"configPath":      filePath,
-					"nodeCount":       len(nodes),
-					"mode":            opts.Mode,
-					"validationError": errorMsg,
-				},
-			})
+			apierror.Respond(c, http.StatusOK, apierror.New(apierror.CodeValidation, "配置验证失败").WithDetails(gin.H{
+				"configPath":      filePath,
+				"nodeCount":       len(nodes),
+				"mode":            opts.Mode,
+				"validationError": errorMsg,
+			}))
 			return
 		}
 	}
 
+	h.service.publish(events.ConfigGenerated, map[string]interface{}{
+		"coreType":   "singbox",
+		"configPath": filePath,
+		"reason":     "generate",
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -915,6 +1121,10 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 
 // GetSingBoxConfigPreview 获取 Sing-Box 配置预览
 func (h *Handler) GetSingBoxConfigPreview(c *gin.Context) {
+	reveal, ok := checkReveal(c)
+	if !ok {
+		return
+	}
 	content, err := h.service.GetSingBoxConfigContent()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -927,26 +1137,36 @@ func (h *Handler) GetSingBoxConfigPreview(c *gin.Context) {
 		return
 	}
 
+	if !reveal {
+		content = redactJSON(content)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"content": content,
+			"content":  content,
+			"redacted": !reveal,
 		},
 	})
 }
 
-// DownloadSingBoxConfig 下载 Sing-Box 配置文件
+// DownloadSingBoxConfig 下载 Sing-Box 配置文件，凭据默认脱敏，?reveal=true 下载完整配置
 func (h *Handler) DownloadSingBoxConfig(c *gin.Context) {
+	reveal, ok := checkReveal(c)
+	if !ok {
+		return
+	}
 	content, err := h.service.GetSingBoxConfigContent()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    1,
-			"message": "配置文件不存在",
-		})
+		apierror.Message(c, http.StatusNotFound, "配置文件不存在")
 		return
 	}
 
+	if !reveal {
+		content = redactJSON(content)
+	}
+
 	c.Header("Content-Disposition", "attachment; filename=singbox-config.json")
 	c.Header("Content-Type", "application/json")
 	c.String(http.StatusOK, content)
@@ -966,18 +1186,12 @@ func (h *Handler) GetSingBoxTemplate(c *gin.Context) {
 func (h *Handler) UpdateSingBoxTemplate(c *gin.Context) {
 	var template SingBoxTemplate
 	if err := c.ShouldBindJSON(&template); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": "参数错误: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
 
 	if err := h.service.UpdateSingBoxTemplate(&template); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "保存失败: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusInternalServerError, "保存失败: "+err.Error())
 		return
 	}
 
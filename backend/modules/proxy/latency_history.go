@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	latencySampleInterval = time.Minute    // 采样间隔（读取核心记录的测速历史）
+	latencyRetention      = 24 * time.Hour // 历史保留时长（仅内存）
+	latencyMaxSwitches    = 200            // 每个代理组保留的切换记录数
+)
+
+// latencyGroupTypes 自动选择节点的代理组类型，记录其选择变化
+var latencyGroupTypes = map[string]bool{"URLTest": true, "Fallback": true, "LoadBalance": true, "Selector": true}
+
+// LatencySample 一次测速记录，Delay 为 0 表示失败
+type LatencySample struct {
+	Time  int64 `json:"time"` // Unix 秒
+	Delay int   `json:"delay"`
+}
+
+// GroupSwitch 代理组选择的节点变化
+type GroupSwitch struct {
+	Time int64  `json:"time"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// LatencyMemberStats 代理组成员在时间范围内的延迟统计
+type LatencyMemberStats struct {
+	Name    string          `json:"name"`
+	Current int             `json:"current"` // 最近一次延迟
+	Samples int             `json:"samples"`
+	Failed  int             `json:"failed"`
+	Min     int             `json:"min"`
+	Max     int             `json:"max"`
+	Avg     int             `json:"avg"`
+	Jitter  int             `json:"jitter"` // 相邻两次成功测速的平均差值
+	Points  []LatencySample `json:"points,omitempty"`
+}
+
+// LatencyGroupStats 代理组延迟统计
+type LatencyGroupStats struct {
+	Name     string               `json:"name"`
+	Type     string               `json:"type"`
+	Now      string               `json:"now"`
+	Members  []LatencyMemberStats `json:"members"`
+	Switches []GroupSwitch        `json:"switches"`
+}
+
+// latencyHistory 定时读取核心中各节点的测速历史（urltest / fallback 健康检查的结果）
+type latencyHistory struct {
+	mu       sync.Mutex
+	samples  map[string][]LatencySample // 节点 → 测速记录（按时间排序）
+	current  map[string]string          // 代理组 → 当前选择
+	switches map[string][]GroupSwitch
+}
+
+func newLatencyHistory() *latencyHistory {
+	return &latencyHistory{
+		samples:  make(map[string][]LatencySample),
+		current:  make(map[string]string),
+		switches: make(map[string][]GroupSwitch),
+	}
+}
+
+// record 合并核心返回的测速历史，只追加比已有记录更新的条目
+func (l *latencyHistory) record(proxies map[string]clashapi.Proxy, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := now.Add(-latencyRetention).Unix()
+	for name, p := range proxies {
+		if latencyGroupTypes[p.Type] && p.Now != "" {
+			if prev, ok := l.current[name]; ok && prev != p.Now {
+				switches := append(l.switches[name], GroupSwitch{Time: now.Unix(), From: prev, To: p.Now})
+				if len(switches) > latencyMaxSwitches {
+					switches = switches[len(switches)-latencyMaxSwitches:]
+				}
+				l.switches[name] = switches
+			}
+			l.current[name] = p.Now
+		}
+		if !p.IsNode() {
+			continue
+		}
+
+		existing := l.samples[name]
+		var last int64
+		if len(existing) > 0 {
+			last = existing[len(existing)-1].Time
+		}
+		for _, h := range p.History {
+			t, err := time.Parse(time.RFC3339Nano, h.Time)
+			if err != nil || t.Unix() <= last {
+				continue
+			}
+			existing = append(existing, LatencySample{Time: t.Unix(), Delay: h.Delay})
+			last = t.Unix()
+		}
+
+		i := sort.Search(len(existing), func(i int) bool { return existing[i].Time >= cutoff })
+		l.samples[name] = existing[i:]
+	}
+
+	for name, switches := range l.switches {
+		i := sort.Search(len(switches), func(i int) bool { return switches[i].Time >= cutoff })
+		l.switches[name] = switches[i:]
+	}
+}
+
+// memberStats 计算节点在 since 之后的延迟统计
+func (l *latencyHistory) memberStats(name string, since int64, withPoints bool) LatencyMemberStats {
+	stats := LatencyMemberStats{Name: name}
+	var sum, jitterSum, jitterCount, prev int
+	for _, s := range l.samples[name] {
+		if s.Time < since {
+			continue
+		}
+		stats.Samples++
+		stats.Current = s.Delay
+		if withPoints {
+			stats.Points = append(stats.Points, s)
+		}
+		if s.Delay <= 0 {
+			stats.Failed++
+			continue
+		}
+		if stats.Min == 0 || s.Delay < stats.Min {
+			stats.Min = s.Delay
+		}
+		if s.Delay > stats.Max {
+			stats.Max = s.Delay
+		}
+		if prev > 0 {
+			jitterSum += int(math.Abs(float64(s.Delay - prev)))
+			jitterCount++
+		}
+		prev = s.Delay
+		sum += s.Delay
+	}
+	if ok := stats.Samples - stats.Failed; ok > 0 {
+		stats.Avg = sum / ok
+	}
+	if jitterCount > 0 {
+		stats.Jitter = jitterSum / jitterCount
+	}
+	return stats
+}
+
+// groupStats 代理组成员统计，可用节点按平均延迟升序，没有成功记录的排在最后
+func (l *latencyHistory) groupStats(group clashapi.Proxy, proxies map[string]clashapi.Proxy, since int64, withPoints bool) LatencyGroupStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := LatencyGroupStats{
+		Name:     group.Name,
+		Type:     group.Type,
+		Now:      group.Now,
+		Members:  []LatencyMemberStats{},
+		Switches: []GroupSwitch{},
+	}
+	for _, member := range group.All {
+		if p, ok := proxies[member]; ok && p.IsNode() {
+			result.Members = append(result.Members, l.memberStats(member, since, withPoints))
+		}
+	}
+	sort.SliceStable(result.Members, func(i, j int) bool {
+		a, b := result.Members[i], result.Members[j]
+		if (a.Avg > 0) != (b.Avg > 0) {
+			return a.Avg > 0
+		}
+		return a.Avg < b.Avg
+	})
+	for _, s := range l.switches[group.Name] {
+		if s.Time >= since {
+			result.Switches = append(result.Switches, s)
+		}
+	}
+	return result
+}
+
+// startLatencySampling 启动后台采样
+func (h *Handler) startLatencySampling() {
+	go func() {
+		ticker := time.NewTicker(latencySampleInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if !h.service.GetStatus().Running {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			proxies, err := h.clashAPI().Proxies(ctx)
+			cancel()
+			if err != nil {
+				continue
+			}
+			h.latency.record(proxies, now)
+		}
+	}()
+}
+
+// GetLatencyStats 代理组成员的延迟历史统计
+// 参数: group 代理组名称（返回测速点），不指定时返回所有自动选择类代理组的汇总；range 时间范围（默认 1h，最长 24h）
+func (h *Handler) GetLatencyStats(c *gin.Context) {
+	rangeStr := c.DefaultQuery("range", "1h")
+	rangeDur, err := parseStatsRange(rangeStr)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if rangeDur > latencyRetention {
+		rangeDur = latencyRetention
+	}
+
+	proxies, err := h.clashAPI().Proxies(c.Request.Context())
+	if err != nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "获取代理信息失败: "+err.Error())
+		return
+	}
+	// 立即合并最新的测速记录
+	now := time.Now()
+	h.latency.record(proxies, now)
+	since := now.Add(-rangeDur).Unix()
+
+	if name := c.Query("group"); name != "" {
+		group, ok := proxies[name]
+		if !ok || group.IsNode() {
+			apierror.Message(c, http.StatusNotFound, "代理组不存在: "+name)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data":    h.latency.groupStats(group, proxies, since, true),
+		})
+		return
+	}
+
+	groups := []LatencyGroupStats{}
+	for _, p := range proxies {
+		if p.Type == "URLTest" || p.Type == "Fallback" || p.Type == "LoadBalance" {
+			groups = append(groups, h.latency.groupStats(p, proxies, since, false))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    groups,
+	})
+}
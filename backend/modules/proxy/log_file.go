@@ -0,0 +1,328 @@
+package proxy
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 核心日志文件默认轮转参数
+const (
+	defaultLogMaxSize    = 10 // MB
+	defaultLogMaxAge     = 7  // 天
+	defaultLogMaxBackups = 10
+
+	// logTimeLayout 日志文件中每行前缀的时间格式
+	logTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// LogFile 日志文件信息
+type LogFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	Compressed bool      `json:"compressed"`
+	Current    bool      `json:"current"` // 正在写入的文件
+}
+
+// logLimits 日志轮转参数
+type logLimits struct {
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+}
+
+// rotatingLog 按大小和日期轮转的日志文件，归档文件使用 gzip 压缩
+type rotatingLog struct {
+	mu     sync.Mutex
+	dir    string
+	name   string // 当前文件名，如 core.log
+	file   *os.File
+	size   int64
+	day    string // 当前文件的日期，跨天时轮转
+	limits func() logLimits
+
+	cachedLimits logLimits
+	limitsAt     time.Time
+}
+
+func newRotatingLog(dir, name string, limits func() logLimits) *rotatingLog {
+	return &rotatingLog{dir: dir, name: name, limits: limits}
+}
+
+// currentLimits 轮转参数每分钟刷新一次，避免每行日志都读取设置
+func (l *rotatingLog) currentLimits() logLimits {
+	if time.Since(l.limitsAt) > time.Minute {
+		l.cachedLimits = l.limits()
+		l.limitsAt = time.Now()
+	}
+	return l.cachedLimits
+}
+
+// WriteLine 写入一行日志，行首添加时间戳
+func (l *rotatingLog) WriteLine(t time.Time, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			return
+		}
+	}
+	limits := l.currentLimits()
+	if (limits.maxSize > 0 && l.size >= limits.maxSize) || t.Format("2006-01-02") != l.day {
+		l.rotate()
+		if err := l.open(); err != nil {
+			return
+		}
+	}
+
+	n, _ := fmt.Fprintf(l.file, "%s %s\n", t.Format(logTimeLayout), line)
+	l.size += int64(n)
+}
+
+// open 以追加方式打开当前日志文件
+func (l *rotatingLog) open() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(l.dir, l.name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	l.day = time.Now().Format("2006-01-02")
+	if l.size > 0 {
+		l.day = info.ModTime().Format("2006-01-02")
+	}
+	return nil
+}
+
+// rotate 关闭当前文件并重命名为归档文件，后台压缩并清理过期归档
+func (l *rotatingLog) rotate() {
+	if l.file == nil {
+		return
+	}
+	l.file.Close()
+	l.file = nil
+	if l.size == 0 {
+		return
+	}
+
+	ext := filepath.Ext(l.name)
+	base := fmt.Sprintf("%s-%s", strings.TrimSuffix(l.name, ext), time.Now().Format("20060102-150405"))
+	archivePath := filepath.Join(l.dir, base+ext)
+	// 同一秒内多次轮转时追加序号，避免覆盖
+	for i := 1; fileExists(archivePath) || fileExists(archivePath+".gz"); i++ {
+		archivePath = filepath.Join(l.dir, fmt.Sprintf("%s.%d%s", base, i, ext))
+	}
+	if err := os.Rename(filepath.Join(l.dir, l.name), archivePath); err != nil {
+		fmt.Printf("⚠️ 日志轮转失败: %v\n", err)
+		return
+	}
+	limits := l.currentLimits()
+	go func() {
+		if err := compressFile(archivePath); err != nil {
+			fmt.Printf("⚠️ 压缩日志失败: %v\n", err)
+		}
+		l.prune(limits)
+	}()
+}
+
+// compressFile 将文件压缩为 .gz 并删除原文件
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune 删除超过保留天数或数量的归档文件
+func (l *rotatingLog) prune(limits logLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	archives := l.archives()
+	for i, f := range archives {
+		expired := limits.maxAge > 0 && time.Since(f.ModTime) > limits.maxAge
+		if expired || (limits.maxBackups > 0 && i >= limits.maxBackups) {
+			os.Remove(filepath.Join(l.dir, f.Name))
+		}
+	}
+}
+
+// archives 列出归档文件，按时间从新到旧排序（调用时需持有 mu 锁）
+func (l *rotatingLog) archives() []LogFile {
+	ext := filepath.Ext(l.name)
+	prefix := strings.TrimSuffix(l.name, ext) + "-"
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []LogFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, LogFile{
+			Name:       name,
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: strings.HasSuffix(name, ".gz"),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.After(files[j].ModTime) })
+	return files
+}
+
+// Files 列出当前日志文件和归档文件，按时间从新到旧排序
+func (l *rotatingLog) Files() []LogFile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files := []LogFile{}
+	if info, err := os.Stat(filepath.Join(l.dir, l.name)); err == nil {
+		files = append(files, LogFile{
+			Name:    l.name,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Current: true,
+		})
+	}
+	return append(files, l.archives()...)
+}
+
+// Open 打开指定的日志文件，只允许访问本日志的文件
+func (l *rotatingLog) Open(name string) (*os.File, error) {
+	if name != filepath.Base(name) {
+		return nil, os.ErrNotExist
+	}
+	for _, f := range l.Files() {
+		if f.Name == name {
+			return os.Open(filepath.Join(l.dir, name))
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// WriteArchive 将所有日志文件打包为 tar.gz
+func (l *rotatingLog) WriteArchive(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range l.Files() {
+		if err := l.addToArchive(tw, f); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (l *rotatingLog) addToArchive(tw *tar.Writer, f LogFile) error {
+	file, err := l.Open(f.Name)
+	if err != nil {
+		return nil // 文件在打包期间被轮转或清理
+	}
+	defer file.Close()
+
+	// 当前文件仍在写入，按打开时的大小截取
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    f.Name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, file, info.Size())
+	return err
+}
+
+// Close 关闭当前日志文件
+func (l *rotatingLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// coreLogLimits 从代理设置读取核心日志轮转参数
+func (s *Service) coreLogLimits() logLimits {
+	limits := logLimits{
+		maxSize:    defaultLogMaxSize << 20,
+		maxAge:     defaultLogMaxAge * 24 * time.Hour,
+		maxBackups: defaultLogMaxBackups,
+	}
+	if s.settingsProvider == nil {
+		return limits
+	}
+	settings := s.settingsProvider()
+	if settings == nil {
+		return limits
+	}
+	if settings.LogMaxSize > 0 {
+		limits.maxSize = int64(settings.LogMaxSize) << 20
+	}
+	if settings.LogMaxAge > 0 {
+		limits.maxAge = time.Duration(settings.LogMaxAge) * 24 * time.Hour
+	}
+	if settings.LogMaxBackups > 0 {
+		limits.maxBackups = settings.LogMaxBackups
+	}
+	return limits
+}
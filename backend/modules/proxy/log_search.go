@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLogSearchResults 单次搜索最多返回的行数
+const maxLogSearchResults = 5000
+
+// LogQuery 日志搜索条件
+type LogQuery struct {
+	Since   time.Time      // 为零时不限制
+	Until   time.Time      // 为零时不限制
+	Pattern *regexp.Regexp // 正则匹配，优先于 Text
+	Text    string         // 子串匹配（不区分大小写）
+	Level   string         // all, info, warn, error
+	Limit   int            // 返回最新的 Limit 条
+}
+
+// match 判断一行日志（不含时间戳前缀）是否符合条件
+func (q *LogQuery) match(line string) bool {
+	if !matchLogLevel(line, q.Level) {
+		return false
+	}
+	if q.Pattern != nil {
+		return q.Pattern.MatchString(line)
+	}
+	return q.Text == "" || strings.Contains(strings.ToLower(line), q.Text)
+}
+
+// matchLogLevel 按级别过滤日志
+func matchLogLevel(line, level string) bool {
+	switch level {
+	case "error":
+		return strings.Contains(line, "ERR") || strings.Contains(line, "FATA") || strings.Contains(line, "error")
+	case "warn":
+		return strings.Contains(line, "WARN") || strings.Contains(line, "warning")
+	case "info":
+		return strings.Contains(line, "INFO") || strings.Contains(line, "info")
+	default:
+		return true
+	}
+}
+
+// parseLogTime 解析时间参数：RFC3339、"2006-01-02 15:04:05"（本地时间）、Unix 秒，或相对时间如 2h（表示 2 小时前）
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(value, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// Search 按时间范围和关键字搜索日志文件（包括已轮转的归档），按时间顺序返回最新的 Limit 条
+func (l *rotatingLog) Search(q LogQuery) ([]string, error) {
+	if q.Limit <= 0 || q.Limit > maxLogSearchResults {
+		q.Limit = maxLogSearchResults
+	}
+
+	// 从旧到新扫描，最后修改时间早于 since 的文件不包含符合条件的行
+	files := l.Files()
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.Before(files[j].ModTime) })
+
+	var results []string
+	for _, f := range files {
+		if !q.Since.IsZero() && f.ModTime.Before(q.Since) {
+			continue
+		}
+		done, err := l.searchFile(f, &q, &results)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
+		}
+	}
+	return results, nil
+}
+
+// searchFile 搜索单个日志文件，超过 until 时返回 done=true
+func (l *rotatingLog) searchFile(f LogFile, q *LogQuery, results *[]string) (done bool, err error) {
+	file, err := os.Open(filepath.Join(l.dir, f.Name))
+	if err != nil {
+		return false, nil // 文件在搜索期间被轮转或清理
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if f.Compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return false, fmt.Errorf("读取 %s 失败: %w", f.Name, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ts, content, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if !q.Since.IsZero() || !q.Until.IsZero() {
+			t, err := time.Parse(logTimeLayout, ts)
+			if err != nil || t.Before(q.Since) {
+				continue
+			}
+			if !q.Until.IsZero() && t.After(q.Until) {
+				return true, nil
+			}
+		}
+		if !q.match(content) {
+			continue
+		}
+		*results = append(*results, line)
+		// 只保留最新的 Limit 条，避免结果过多时占用大量内存
+		if len(*results) >= 2*q.Limit {
+			*results = append((*results)[:0], (*results)[len(*results)-q.Limit:]...)
+		}
+	}
+	if len(*results) > q.Limit {
+		*results = append((*results)[:0], (*results)[len(*results)-q.Limit:]...)
+	}
+	return false, scanner.Err()
+}
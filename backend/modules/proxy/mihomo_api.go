@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// mihomoAllowedMethods 允许转发到 Mihomo API 的请求方法
+var mihomoAllowedMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// mihomoBlockedPaths 禁止转发的接口（核心进程和二进制升级由 ProxyStation 自己管理）
+var mihomoBlockedPaths = map[string]bool{
+	"/restart":    true,
+	"/upgrade":    true,
+	"/upgrade/ui": true,
+}
+
+// mihomoStreamPaths 流式接口，使用 WebSocket 转发
+var mihomoStreamPaths = map[string]bool{
+	"/traffic":     true,
+	"/logs":        true,
+	"/connections": true,
+	"/memory":      true,
+}
+
+// ProxyMihomoAPI 通用 Mihomo REST API 反向代理（/mihomo/*path）
+// 自动注入 secret，新增的控制器接口无需修改后端即可使用
+func (h *Handler) ProxyMihomoAPI(c *gin.Context) {
+	path, rawPath := mihomoProxyPath(c)
+
+	if !mihomoAllowedMethods[c.Request.Method] {
+		apierror.Message(c, http.StatusMethodNotAllowed, "不支持的请求方法: "+c.Request.Method)
+		return
+	}
+	if mihomoBlockedPaths[strings.TrimSuffix(path, "/")] {
+		apierror.Message(c, http.StatusForbidden, "该接口不允许通过代理访问: "+path)
+		return
+	}
+
+	// ProxyStation 扩展接口，Mihomo 本身没有该路径
+	if path == "/proxies/delay/batch" && c.Request.Method == http.MethodPost {
+		h.ProxyMihomoBatchDelay(c)
+		return
+	}
+
+	if mihomoStreamPaths[path] && c.Request.Method == http.MethodGet {
+		h.proxyMihomoStream(c, path)
+		return
+	}
+
+	client := h.clashAPI()
+	apiAddr := client.Addr()
+	headers := client.Header()
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = apiAddr
+			req.URL.Path = path
+			req.URL.RawPath = rawPath
+			req.Host = apiAddr
+			// 不把 ProxyStation 的认证信息转发给核心
+			req.Header.Del("Cookie")
+			req.Header.Del("Authorization")
+			for key, values := range headers {
+				req.Header[key] = values
+			}
+		},
+		Transport:     client.Transport(),
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			apierror.Message(c, http.StatusServiceUnavailable, "核心 API 不可用: "+err.Error())
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// mihomoProxyPath 取出 /mihomo 之后的路径，保留原始转义（代理组名可能包含 /）
+func mihomoProxyPath(c *gin.Context) (string, string) {
+	path := c.Param("path")
+	if path == "" {
+		path = "/"
+	}
+	escaped := c.Request.URL.EscapedPath()
+	idx := strings.Index(escaped, "/mihomo/")
+	if idx < 0 {
+		return path, ""
+	}
+	rawPath := escaped[idx+len("/mihomo"):]
+	if unescaped, err := url.PathUnescape(rawPath); err == nil {
+		return unescaped, rawPath
+	}
+	return path, ""
+}
+
+// coreAPIAddr 获取可连接的核心 API 地址（监听 0.0.0.0 时改为本机回环地址）
+func (h *Handler) coreAPIAddr() string {
+	apiAddr := h.service.GetConfig().ExternalController
+	if apiAddr == "" {
+		return "127.0.0.1:9090"
+	}
+
+	host, port, err := net.SplitHostPort(apiAddr)
+	if err != nil {
+		return apiAddr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// CoreAPIEndpoint 核心 API 地址和 secret（供 WebSocket 转发使用）
+func (h *Handler) CoreAPIEndpoint() (addr, secret string) {
+	return h.coreAPIAddr(), h.service.GetAPISecret()
+}
+
+// clashAPI 当前核心的 Clash API 客户端（Mihomo 与 sing-box 通用）
+func (h *Handler) clashAPI() *clashapi.Client {
+	return clashapi.New(h.coreAPIAddr(), h.service.GetAPISecret())
+}
+
+// pushMihomoConfig 通过 PUT /configs?force=true 让 Mihomo 加载新配置
+// 直接发送配置内容，不依赖 Mihomo 对配置路径的安全目录限制
+func (h *Handler) pushMihomoConfig(configPath string) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	return h.clashAPI().ReloadConfig(context.Background(), string(content), true)
+}
+
+// GetAPISecret 获取核心 API 的 secret
+// 优先使用代理配置中的 secret，未设置时从已生成的核心配置文件中读取
+func (s *Service) GetAPISecret() string {
+	s.mu.RLock()
+	secret := s.config.Secret
+	configPath := s.configPath
+	s.mu.RUnlock()
+
+	if secret != "" || configPath == "" {
+		return secret
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+
+	if strings.HasSuffix(configPath, ".json") {
+		var sbConfig struct {
+			Experimental struct {
+				ClashAPI struct {
+					Secret string `json:"secret"`
+				} `json:"clash_api"`
+			} `json:"experimental"`
+		}
+		if json.Unmarshal(data, &sbConfig) == nil {
+			return sbConfig.Experimental.ClashAPI.Secret
+		}
+		return ""
+	}
+
+	var mihomoConfig struct {
+		Secret string `yaml:"secret"`
+	}
+	if yaml.Unmarshal(data, &mihomoConfig) == nil {
+		return mihomoConfig.Secret
+	}
+	return ""
+}
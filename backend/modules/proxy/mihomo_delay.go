@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDelayTestURL     = "http://www.gstatic.com/generate_204"
+	defaultDelayTimeout     = 5000 // 毫秒
+	defaultDelayConcurrency = 10
+	maxDelayConcurrency     = 50
+)
+
+// DelayResult 单个节点的测速结果
+type DelayResult struct {
+	Name  string `json:"name"`
+	Delay int    `json:"delay"` // 毫秒，0 表示失败
+	Error string `json:"error,omitempty"`
+}
+
+// BatchDelayResult 批量测速结果
+type BatchDelayResult struct {
+	Total   int           `json:"total"`
+	Success int           `json:"success"`
+	Failed  int           `json:"failed"`
+	Results []DelayResult `json:"results"`
+}
+
+// batchDelayTargets 获取测速目标：指定节点列表 > 代理组成员 > 全部节点
+func (h *Handler) batchDelayTargets(ctx context.Context, names []string, group string) ([]string, error) {
+	if len(names) > 0 {
+		return names, nil
+	}
+
+	proxies, err := h.clashAPI().Proxies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	if group != "" {
+		g, ok := proxies[group]
+		if !ok {
+			return nil, fmt.Errorf("代理组不存在: %s", group)
+		}
+		for _, name := range g.All {
+			if p, ok := proxies[name]; ok && p.IsNode() {
+				targets = append(targets, name)
+			}
+		}
+		return targets, nil
+	}
+
+	for name, p := range proxies {
+		if p.IsNode() {
+			targets = append(targets, name)
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// testMihomoDelay 通过核心 API 测试单个节点延迟
+func testMihomoDelay(ctx context.Context, client *clashapi.Client, name, testURL string, timeout int) DelayResult {
+	result := DelayResult{Name: name}
+	delay, err := client.ProxyDelay(ctx, name, testURL, time.Duration(timeout)*time.Millisecond)
+	if err != nil {
+		var apiErr *clashapi.APIError
+		if errors.As(err, &apiErr) {
+			result.Error = apiErr.Message
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+	result.Delay = delay
+	return result
+}
+
+// ProxyMihomoBatchDelay 批量测速（POST /mihomo/proxies/delay/batch）
+// 不指定 names 和 group 时测试全部节点，concurrency 控制并发数
+func (h *Handler) ProxyMihomoBatchDelay(c *gin.Context) {
+	var req struct {
+		Names       []string `json:"names"`
+		Group       string   `json:"group"`
+		URL         string   `json:"url"`
+		Timeout     int      `json:"timeout"` // 毫秒
+		Concurrency int      `json:"concurrency"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if req.URL == "" {
+		req.URL = defaultDelayTestURL
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = defaultDelayTimeout
+	}
+	if req.Concurrency <= 0 {
+		req.Concurrency = defaultDelayConcurrency
+	}
+	if req.Concurrency > maxDelayConcurrency {
+		req.Concurrency = maxDelayConcurrency
+	}
+
+	ctx := c.Request.Context()
+	targets, err := h.batchDelayTargets(ctx, req.Names, req.Group)
+	if err != nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "获取节点列表失败: "+err.Error())
+		return
+	}
+
+	client := h.clashAPI()
+	results := make([]DelayResult, len(targets))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, req.Concurrency)
+	for i, name := range targets {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = testMihomoDelay(ctx, client, name, req.URL, req.Timeout)
+		}(i, name)
+	}
+	wg.Wait()
+
+	summary := BatchDelayResult{Total: len(results), Results: results}
+	for _, r := range results {
+		if r.Delay > 0 {
+			summary.Success++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summary,
+	})
+}
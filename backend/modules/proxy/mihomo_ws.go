@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var mihomoWSUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// proxyMihomoStream 代理 Mihomo 的流式接口
+// WebSocket 请求双向转发，普通 HTTP 请求直接转发响应
+func (h *Handler) proxyMihomoStream(c *gin.Context, path string) {
+	client := h.clashAPI()
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		h.proxyMihomoHTTP(c, client, path)
+		return
+	}
+
+	targetURL := "ws://" + client.Addr() + path
+	if query := c.Request.URL.RawQuery; query != "" {
+		targetURL += "?" + query
+	}
+
+	// 先连接 Mihomo，失败时直接返回 HTTP 错误，避免前端无限重连空连接
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	mihomoConn, _, err := dialer.Dial(targetURL, client.Header())
+	if err != nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "核心 API 不可用: "+err.Error())
+		return
+	}
+	defer mihomoConn.Close()
+
+	clientConn, err := mihomoWSUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer clientConn.Close()
+
+	done := make(chan struct{}, 2)
+
+	// Mihomo -> 前端
+	go func() {
+		defer func() { done <- struct{}{} }()
+		for {
+			msgType, msg, err := mihomoConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := clientConn.WriteMessage(msgType, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 前端 -> Mihomo
+	go func() {
+		defer func() { done <- struct{}{} }()
+		for {
+			msgType, msg, err := clientConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := mihomoConn.WriteMessage(msgType, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 任意一端断开即结束，defer 关闭两端连接使另一个 goroutine 退出
+	<-done
+}
+
+// proxyMihomoHTTP 以普通 HTTP 方式转发（/connections 返回快照，/traffic 和 /logs 持续输出）
+func (h *Handler) proxyMihomoHTTP(c *gin.Context, client *clashapi.Client, path string) {
+	resp, err := client.Stream(c.Request.Context(), path, c.Request.URL.RawQuery)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		var apiErr *clashapi.APIError
+		if errors.As(err, &apiErr) {
+			status = apiErr.StatusCode
+		}
+		apierror.Message(c, status, "核心 API 不可用: "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	// /traffic 和 /logs 在 HTTP 模式下是持续输出的 chunked 流，边读边刷新直到任意一端断开
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(resp.StatusCode)
+	if strings.HasPrefix(path, "/connections") {
+		io.Copy(c.Writer, resp.Body)
+		return
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// NetfilterError nftables / 策略路由操作错误
+type NetfilterError struct {
+	Op     string // 操作名称，如 apply-ruleset、add-route
+	Output string // 命令输出
+	Err    error
+}
+
+func (e *NetfilterError) Error() string {
+	if e.Output != "" {
+		return fmt.Sprintf("%s 失败: %v, 输出: %s", e.Op, e.Err, strings.TrimSpace(e.Output))
+	}
+	return fmt.Sprintf("%s 失败: %v", e.Op, e.Err)
+}
+
+func (e *NetfilterError) Unwrap() error {
+	return e.Err
+}
+
+// PolicyRoute tproxy 所需的策略路由（fwmark -> table, local default dev lo）
+type PolicyRoute struct {
+	IPv6    bool
+	Mark    int
+	TableID int
+}
+
+// args 策略路由命令参数：地址族、标记、路由表和 local 路由的目标网段
+func (r PolicyRoute) args() (family, mark, table, dst string) {
+	family, dst = "-4", "0.0.0.0/0"
+	if r.IPv6 {
+		family, dst = "-6", "::/0"
+	}
+	return family, fmt.Sprintf("%d", r.Mark), fmt.Sprintf("%d", r.TableID), dst
+}
+
+// netfilterBackend 透明代理内核操作接口
+// Linux 下使用 netlinkNetfilter，其他平台退回 nft / ip 命令，测试时可替换为测试桩
+type netfilterBackend interface {
+	// ApplyRuleset 原子地加载一份完整的 nft 脚本
+	ApplyRuleset(script string) error
+	// CheckRuleset 只检查 nft 脚本（nft -c），不修改内核规则
+	CheckRuleset(script string) error
+	// ListTable 获取 nftables 表的当前规则，表不存在时返回空字符串
+	ListTable(family, name string) (string, error)
+	// DeleteTable 删除 nftables 表（不存在时不报错）
+	DeleteTable(family, name string) error
+	// AddPolicyRoute 添加策略路由规则和 local 路由
+	AddPolicyRoute(route PolicyRoute) error
+	// DeletePolicyRoute 删除策略路由规则和 local 路由（不存在时不报错）
+	DeletePolicyRoute(route PolicyRoute) error
+	// PolicyRouteState 检查策略路由规则和 local 路由是否存在
+	PolicyRouteState(route PolicyRoute) (hasRule, hasRoute bool, err error)
+}
+
+// execNetfilter 基于 nft / ip 命令的实现
+type execNetfilter struct{}
+
+func (execNetfilter) ApplyRuleset(script string) error {
+	// nft -f 在单个事务中提交整个脚本，任何一条失败都不会生效
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &NetfilterError{Op: "nft apply", Output: string(output), Err: err}
+	}
+	return nil
+}
+
+func (execNetfilter) CheckRuleset(script string) error {
+	cmd := exec.Command("nft", "-c", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &NetfilterError{Op: "nft check", Output: string(output), Err: err}
+	}
+	return nil
+}
+
+func (execNetfilter) ListTable(family, name string) (string, error) {
+	output, err := exec.Command("nft", "list", "table", family, name).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "No such file or directory") {
+			return "", nil
+		}
+		return "", &NetfilterError{Op: "nft list table", Output: string(output), Err: err}
+	}
+	return string(output), nil
+}
+
+func (execNetfilter) DeleteTable(family, name string) error {
+	output, err := exec.Command("nft", "delete", "table", family, name).CombinedOutput()
+	if err != nil && !strings.Contains(string(output), "No such file or directory") {
+		return &NetfilterError{Op: "nft delete table", Output: string(output), Err: err}
+	}
+	return nil
+}
+
+func (execNetfilter) AddPolicyRoute(route PolicyRoute) error {
+	family, mark, table, dst := route.args()
+
+	// 规则重复添加不会报错，先删除再添加避免堆积
+	exec.Command("ip", family, "rule", "del", "fwmark", mark, "lookup", table).Run()
+	if output, err := exec.Command("ip", family, "rule", "add", "fwmark", mark, "lookup", table).CombinedOutput(); err != nil {
+		return &NetfilterError{Op: "ip rule add", Output: string(output), Err: err}
+	}
+
+	output, err := exec.Command("ip", family, "route", "add", "local", dst, "dev", "lo", "table", table).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "exists") {
+			return nil
+		}
+		return &NetfilterError{Op: "ip route add", Output: string(output), Err: err}
+	}
+	return nil
+}
+
+func (execNetfilter) DeletePolicyRoute(route PolicyRoute) error {
+	family, mark, table, dst := route.args()
+
+	// 循环删除策略路由（可能有多条）
+	for i := 0; i < 5; i++ {
+		if err := exec.Command("ip", family, "rule", "del", "fwmark", mark, "lookup", table).Run(); err != nil {
+			break
+		}
+	}
+	exec.Command("ip", family, "route", "del", "local", dst, "dev", "lo", "table", table).Run()
+	return nil
+}
+
+func (execNetfilter) PolicyRouteState(route PolicyRoute) (hasRule, hasRoute bool, err error) {
+	family, _, table, _ := route.args()
+
+	output, err := exec.Command("ip", family, "rule", "show").CombinedOutput()
+	if err != nil {
+		return false, false, &NetfilterError{Op: "ip rule show", Output: string(output), Err: err}
+	}
+	for _, rule := range parseIPRules(string(output)) {
+		if rule.HasMark && rule.Mark == int64(route.Mark) && rule.Table == table {
+			hasRule = true
+			break
+		}
+	}
+
+	output, err = exec.Command("ip", family, "route", "show", "table", table).CombinedOutput()
+	if err != nil {
+		return hasRule, false, &NetfilterError{Op: "ip route show", Output: string(output), Err: err}
+	}
+	hasRoute = strings.Contains(string(output), "local") && strings.Contains(string(output), "dev lo")
+	return hasRule, hasRoute, nil
+}
@@ -0,0 +1,178 @@
+//go:build linux
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/google/nftables"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// routeNetlink 策略路由所需的 netlink 操作，*netlink.Handle 满足该接口，测试时可替换
+type routeNetlink interface {
+	RuleAdd(rule *netlink.Rule) error
+	RuleDel(rule *netlink.Rule) error
+	RuleList(family int) ([]netlink.Rule, error)
+	RouteReplace(route *netlink.Route) error
+	RouteDel(route *netlink.Route) error
+	RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error)
+	LinkByName(name string) (netlink.Link, error)
+}
+
+// netlinkNetfilter 基于 google/nftables 和 vishvananda/netlink 的实现
+// 只有表删除（DeleteTable）和策略路由（Add/DeletePolicyRoute、PolicyRouteState）通过 netlink 完成，不依赖 nft / ip 命令。
+// ApplyRuleset、CheckRuleset 和 ListTable 沿用内嵌的 execNetfilter，仍需要 nft 命令：
+// 规则由用户可编辑的 nft 文本模板生成，回滚快照也保存为 nft 文本，改用库构建规则需要自行解析 nft 语法
+type netlinkNetfilter struct {
+	execNetfilter
+	routes  routeNetlink
+	nftConn func() (*nftables.Conn, error)
+}
+
+// newNetfilterBackend 创建当前平台的内核操作实现
+func newNetfilterBackend() netfilterBackend {
+	return &netlinkNetfilter{
+		routes: &netlink.Handle{},
+		nftConn: func() (*nftables.Conn, error) {
+			return nftables.New()
+		},
+	}
+}
+
+// nftTableFamilies nft 命令中的地址族名称
+var nftTableFamilies = map[string]nftables.TableFamily{
+	"inet":   nftables.TableFamilyINet,
+	"ip":     nftables.TableFamilyIPv4,
+	"ip6":    nftables.TableFamilyIPv6,
+	"arp":    nftables.TableFamilyARP,
+	"bridge": nftables.TableFamilyBridge,
+	"netdev": nftables.TableFamilyNetdev,
+}
+
+func (n *netlinkNetfilter) DeleteTable(family, name string) error {
+	tableFamily, ok := nftTableFamilies[family]
+	if !ok {
+		return &NetfilterError{Op: "nft delete table", Err: fmt.Errorf("未知的地址族: %s", family)}
+	}
+	conn, err := n.nftConn()
+	if err != nil {
+		return &NetfilterError{Op: "nft delete table", Err: err}
+	}
+	tables, err := conn.ListTablesOfFamily(tableFamily)
+	if err != nil {
+		return &NetfilterError{Op: "nft list tables", Err: err}
+	}
+	for _, table := range tables {
+		if table.Name != name {
+			continue
+		}
+		conn.DelTable(table)
+		if err := conn.Flush(); err != nil {
+			return &NetfilterError{Op: "nft delete table", Err: err}
+		}
+		return nil
+	}
+	return nil
+}
+
+// family 策略路由的地址族和 local 路由的目标网段
+func (r PolicyRoute) family() (int, *net.IPNet) {
+	if r.IPv6 {
+		return unix.AF_INET6, &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+	return unix.AF_INET, &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+}
+
+// rule fwmark -> table 规则
+func (r PolicyRoute) rule() *netlink.Rule {
+	family, _ := r.family()
+	rule := netlink.NewRule()
+	rule.Family = family
+	rule.Mark = uint32(r.Mark)
+	rule.Table = r.TableID
+	return rule
+}
+
+// localRoute 路由表中的 local default dev lo 路由
+func (r PolicyRoute) localRoute(loIndex int) *netlink.Route {
+	_, dst := r.family()
+	return &netlink.Route{
+		LinkIndex: loIndex,
+		Dst:       dst,
+		Table:     r.TableID,
+		Type:      unix.RTN_LOCAL,
+		Scope:     netlink.SCOPE_HOST,
+	}
+}
+
+func (n *netlinkNetfilter) AddPolicyRoute(route PolicyRoute) error {
+	// 规则重复添加不会报错，先删除再添加避免堆积
+	n.deleteRules(route)
+	if err := n.routes.RuleAdd(route.rule()); err != nil {
+		return &NetfilterError{Op: "rule add", Err: err}
+	}
+
+	lo, err := n.routes.LinkByName("lo")
+	if err != nil {
+		return &NetfilterError{Op: "link lo", Err: err}
+	}
+	if err := n.routes.RouteReplace(route.localRoute(lo.Attrs().Index)); err != nil {
+		return &NetfilterError{Op: "route replace", Err: err}
+	}
+	return nil
+}
+
+func (n *netlinkNetfilter) DeletePolicyRoute(route PolicyRoute) error {
+	n.deleteRules(route)
+	if lo, err := n.routes.LinkByName("lo"); err == nil {
+		n.routes.RouteDel(route.localRoute(lo.Attrs().Index))
+	}
+	return nil
+}
+
+// deleteRules 循环删除策略路由（可能有多条），没有匹配规则时内核返回错误
+func (n *netlinkNetfilter) deleteRules(route PolicyRoute) {
+	for i := 0; i < 5; i++ {
+		if err := n.routes.RuleDel(route.rule()); err != nil {
+			break
+		}
+	}
+}
+
+func (n *netlinkNetfilter) PolicyRouteState(route PolicyRoute) (hasRule, hasRoute bool, err error) {
+	family, _ := route.family()
+
+	rules, err := n.routes.RuleList(family)
+	if err != nil {
+		return false, false, &NetfilterError{Op: "rule list", Err: err}
+	}
+	for _, rule := range rules {
+		if rule.Mark == uint32(route.Mark) && rule.Table == route.TableID {
+			hasRule = true
+			break
+		}
+	}
+
+	lo, err := n.routes.LinkByName("lo")
+	if err != nil {
+		return hasRule, false, &NetfilterError{Op: "link lo", Err: err}
+	}
+	routes, err := n.routes.RouteListFiltered(family, &netlink.Route{Table: route.TableID}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return hasRule, false, nil
+		}
+		return hasRule, false, &NetfilterError{Op: "route list", Err: err}
+	}
+	for _, r := range routes {
+		if r.Type == unix.RTN_LOCAL && r.LinkIndex == lo.Attrs().Index {
+			hasRoute = true
+			break
+		}
+	}
+	return hasRule, hasRoute, nil
+}
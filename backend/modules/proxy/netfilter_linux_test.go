@@ -0,0 +1,229 @@
+//go:build linux
+
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/mdlayher/netlink"
+	vnl "github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// fakeRoutes 内存中的规则和路由表
+type fakeRoutes struct {
+	rules  []vnl.Rule
+	routes []vnl.Route
+}
+
+func (f *fakeRoutes) RuleAdd(rule *vnl.Rule) error {
+	f.rules = append(f.rules, *rule)
+	return nil
+}
+
+func (f *fakeRoutes) RuleDel(rule *vnl.Rule) error {
+	for i, r := range f.rules {
+		if r.Family == rule.Family && r.Mark == rule.Mark && r.Table == rule.Table {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return nil
+		}
+	}
+	return unix.ENOENT
+}
+
+func (f *fakeRoutes) RuleList(family int) ([]vnl.Rule, error) {
+	var rules []vnl.Rule
+	for _, r := range f.rules {
+		if r.Family == family {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeRoutes) RouteReplace(route *vnl.Route) error {
+	for i, r := range f.routes {
+		if r.Table == route.Table && r.Dst.String() == route.Dst.String() {
+			f.routes[i] = *route
+			return nil
+		}
+	}
+	f.routes = append(f.routes, *route)
+	return nil
+}
+
+func (f *fakeRoutes) RouteDel(route *vnl.Route) error {
+	for i, r := range f.routes {
+		if r.Table == route.Table && r.Dst.String() == route.Dst.String() {
+			f.routes = append(f.routes[:i], f.routes[i+1:]...)
+			return nil
+		}
+	}
+	return unix.ESRCH
+}
+
+func (f *fakeRoutes) RouteListFiltered(family int, filter *vnl.Route, filterMask uint64) ([]vnl.Route, error) {
+	var routes []vnl.Route
+	for _, r := range f.routes {
+		isIPv6 := r.Dst.IP.To4() == nil
+		if isIPv6 == (family == unix.AF_INET6) && r.Table == filter.Table {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+func (f *fakeRoutes) LinkByName(name string) (vnl.Link, error) {
+	if name != "lo" {
+		return nil, errors.New("link not found")
+	}
+	return &vnl.Device{LinkAttrs: vnl.LinkAttrs{Name: "lo", Index: 1}}, nil
+}
+
+func TestNetlinkPolicyRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		route PolicyRoute
+		dst   string
+	}{
+		{"ipv4", PolicyRoute{Mark: 1, TableID: 100}, "0.0.0.0/0"},
+		{"ipv6", PolicyRoute{IPv6: true, Mark: 1, TableID: 100}, "::/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := &fakeRoutes{}
+			n := &netlinkNetfilter{routes: routes}
+
+			// 重复添加只保留一条规则和一条路由
+			for i := 0; i < 2; i++ {
+				if err := n.AddPolicyRoute(tt.route); err != nil {
+					t.Fatalf("AddPolicyRoute: %v", err)
+				}
+			}
+			if len(routes.rules) != 1 || len(routes.routes) != 1 {
+				t.Fatalf("rules = %d, routes = %d, want 1 and 1", len(routes.rules), len(routes.routes))
+			}
+			route := routes.routes[0]
+			if route.Type != unix.RTN_LOCAL || route.LinkIndex != 1 || route.Table != 100 || route.Dst.String() != tt.dst {
+				t.Errorf("route = %+v", route)
+			}
+
+			hasRule, hasRoute, err := n.PolicyRouteState(tt.route)
+			if err != nil || !hasRule || !hasRoute {
+				t.Errorf("PolicyRouteState = %v, %v, %v, want true, true, nil", hasRule, hasRoute, err)
+			}
+
+			if err := n.DeletePolicyRoute(tt.route); err != nil {
+				t.Fatalf("DeletePolicyRoute: %v", err)
+			}
+			if len(routes.rules) != 0 || len(routes.routes) != 0 {
+				t.Fatalf("rules = %d, routes = %d after delete", len(routes.rules), len(routes.routes))
+			}
+			hasRule, hasRoute, err = n.PolicyRouteState(tt.route)
+			if err != nil || hasRule || hasRoute {
+				t.Errorf("PolicyRouteState after delete = %v, %v, %v", hasRule, hasRoute, err)
+			}
+		})
+	}
+}
+
+func TestNetlinkPolicyRouteFamilies(t *testing.T) {
+	// IPv4 和 IPv6 的规则互不影响
+	routes := &fakeRoutes{}
+	n := &netlinkNetfilter{routes: routes}
+	v4 := PolicyRoute{Mark: 1, TableID: 100}
+	v6 := PolicyRoute{IPv6: true, Mark: 1, TableID: 100}
+	if err := n.AddPolicyRoute(v4); err != nil {
+		t.Fatal(err)
+	}
+	hasRule, hasRoute, _ := n.PolicyRouteState(v6)
+	if hasRule || hasRoute {
+		t.Errorf("ipv6 state = %v, %v after adding ipv4 route", hasRule, hasRoute)
+	}
+	if err := n.AddPolicyRoute(v6); err != nil {
+		t.Fatal(err)
+	}
+	n.DeletePolicyRoute(v6)
+	hasRule, hasRoute, _ = n.PolicyRouteState(v4)
+	if !hasRule || !hasRoute {
+		t.Errorf("ipv4 state = %v, %v after deleting ipv6 route", hasRule, hasRoute)
+	}
+}
+
+// nftTableMessage 内核返回的 nftables 表信息
+func nftTableMessage(family nftables.TableFamily, name string) netlink.Message {
+	attrs, _ := netlink.MarshalAttributes([]netlink.Attribute{
+		{Type: unix.NFTA_TABLE_NAME, Data: []byte(name + "\x00")},
+	})
+	return netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8 | unix.NFT_MSG_NEWTABLE)},
+		Data:   append([]byte{byte(family), unix.NFNETLINK_V0, 0, 0}, attrs...),
+	}
+}
+
+func TestNetlinkDeleteTable(t *testing.T) {
+	delTable := netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8 | unix.NFT_MSG_DELTABLE)
+	tests := []struct {
+		name    string
+		family  string
+		tables  []netlink.Message
+		deleted []string
+		wantErr bool
+	}{
+		{"existing table", "inet", []netlink.Message{
+			nftTableMessage(nftables.TableFamilyINet, "filter"),
+			nftTableMessage(nftables.TableFamilyINet, "proxystation"),
+		}, []string{"proxystation"}, false},
+		{"missing table", "inet", []netlink.Message{nftTableMessage(nftables.TableFamilyINet, "filter")}, nil, false},
+		{"unknown family", "foo", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			dial := func(req []netlink.Message) ([]netlink.Message, error) {
+				if len(req) == 1 && req[0].Header.Type == netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETTABLE) {
+					replies := make([]netlink.Message, len(tt.tables))
+					for i, msg := range tt.tables {
+						msg.Header.Sequence = req[0].Header.Sequence
+						replies[i] = msg
+					}
+					return replies, nil
+				}
+				for _, msg := range req {
+					if msg.Header.Type != delTable {
+						continue
+					}
+					ad, err := netlink.NewAttributeDecoder(msg.Data[4:])
+					if err != nil {
+						t.Fatal(err)
+					}
+					for ad.Next() {
+						if ad.Type() == unix.NFTA_TABLE_NAME {
+							deleted = append(deleted, string(bytes.TrimRight(ad.Bytes(), "\x00")))
+						}
+					}
+				}
+				return req, nil
+			}
+			n := &netlinkNetfilter{nftConn: func() (*nftables.Conn, error) {
+				return nftables.New(nftables.WithTestDial(dial))
+			}}
+
+			err := n.DeleteTable(tt.family, "proxystation")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteTable error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var netfilterErr *NetfilterError
+			if err != nil && !errors.As(err, &netfilterErr) {
+				t.Errorf("error type = %T, want *NetfilterError", err)
+			}
+			if !reflect.DeepEqual(deleted, tt.deleted) {
+				t.Errorf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package proxy
+
+// newNetfilterBackend 创建当前平台的内核操作实现
+func newNetfilterBackend() netfilterBackend {
+	return execNetfilter{}
+}
@@ -0,0 +1,271 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 重复节点处理方式
+const (
+	NodeDedupKeepFirst   = "first"   // 保留第一个（订阅顺序在前，手动节点在最后）
+	NodeDedupKeepFastest = "latency" // 保留延迟最低的，均未测试时保留第一个
+	NodeDedupKeepSuffix  = "suffix"  // 全部保留，重复的节点名称追加来源
+)
+
+// nodeCredentialKeys 用于识别同一节点的凭据字段（按顺序取第一个非空值）
+var nodeCredentialKeys = []string{"uuid", "password", "auth", "auth-str", "auth_str", "psk", "token", "username"}
+
+// NodeDedupConfig 跨订阅节点去重设置
+type NodeDedupConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Strategy string `json:"strategy"` // first / latency / suffix
+}
+
+// normalize 校验去重设置
+func (c NodeDedupConfig) normalize() (NodeDedupConfig, error) {
+	switch c.Strategy {
+	case "":
+		c.Strategy = NodeDedupKeepFirst
+	case NodeDedupKeepFirst, NodeDedupKeepFastest, NodeDedupKeepSuffix:
+	default:
+		return c, fmt.Errorf("无效的去重方式: %s", c.Strategy)
+	}
+	return c, nil
+}
+
+// NodeDedupGroup 一组重复节点的处理结果
+type NodeDedupGroup struct {
+	Endpoint string   `json:"endpoint"` // 类型和服务器地址，如 "vless example.com:443"（不含凭据）
+	Kept     []string `json:"kept"`     // 保留的节点（suffix 方式为追加来源后的名称）
+	Removed  []string `json:"removed"`  // 合并掉的节点
+}
+
+// NodeDedupReport 最近一次去重结果
+type NodeDedupReport struct {
+	Strategy  string           `json:"strategy"`
+	Total     int              `json:"total"`   // 去重前的节点数
+	Result    int              `json:"result"`  // 去重后的节点数
+	Renamed   int              `json:"renamed"` // 追加来源的节点数
+	Groups    []NodeDedupGroup `json:"groups"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// nodeDedupState 最近一次去重结果
+type nodeDedupState struct {
+	mu     sync.Mutex
+	report *NodeDedupReport
+}
+
+// nodeIdentity 节点的识别键：类型 + 服务器:端口 + 凭据，第二个返回值为不含凭据的展示地址
+func nodeIdentity(n ProxyNode) (string, string) {
+	endpoint := fmt.Sprintf("%s %s:%d", strings.ToLower(n.Type), strings.ToLower(n.Server), n.GetPort())
+	var credential string
+	var config map[string]interface{}
+	if json.Unmarshal([]byte(n.Config), &config) == nil {
+		for _, key := range nodeCredentialKeys {
+			if v, ok := config[key].(string); ok && v != "" {
+				credential = v
+				break
+			}
+		}
+	}
+	return endpoint + "/" + credential, endpoint
+}
+
+// dedupeNodes 合并服务器、端口和凭据相同的节点，保持原有顺序
+func dedupeNodes(nodes []ProxyNode, strategy string) ([]ProxyNode, *NodeDedupReport) {
+	report := &NodeDedupReport{Strategy: strategy, Total: len(nodes), Groups: []NodeDedupGroup{}, UpdatedAt: time.Now()}
+
+	keys := make([]string, len(nodes))
+	endpoints := make(map[string]string)
+	members := make(map[string][]int)
+	for i, n := range nodes {
+		key, endpoint := nodeIdentity(n)
+		keys[i] = key
+		endpoints[key] = endpoint
+		members[key] = append(members[key], i)
+	}
+
+	// 每组保留的节点
+	keep := make([]bool, len(nodes))
+	result := make([]ProxyNode, len(nodes))
+	copy(result, nodes)
+	for i, key := range keys {
+		group := members[key]
+		if group[0] != i {
+			continue
+		}
+		if len(group) == 1 {
+			keep[i] = true
+			continue
+		}
+
+		switch strategy {
+		case NodeDedupKeepSuffix:
+			for j, idx := range group {
+				keep[idx] = true
+				if j > 0 {
+					result[idx].Name = nodeDedupSuffix(nodes[idx], j+1)
+					report.Renamed++
+				}
+			}
+		case NodeDedupKeepFastest:
+			best := group[0]
+			for _, idx := range group[1:] {
+				if d := nodes[idx].Delay; d > 0 && (nodes[best].Delay <= 0 || d < nodes[best].Delay) {
+					best = idx
+				}
+			}
+			keep[best] = true
+		default:
+			keep[group[0]] = true
+		}
+
+		entry := NodeDedupGroup{Endpoint: endpoints[key], Kept: []string{}, Removed: []string{}}
+		for _, idx := range group {
+			if keep[idx] {
+				entry.Kept = append(entry.Kept, result[idx].Name)
+			} else {
+				entry.Removed = append(entry.Removed, nodes[idx].Name)
+			}
+		}
+		report.Groups = append(report.Groups, entry)
+	}
+
+	kept := make([]ProxyNode, 0, len(nodes))
+	for i := range result {
+		if keep[i] {
+			kept = append(kept, result[i])
+		}
+	}
+	if strategy == NodeDedupKeepSuffix {
+		names := make([]string, len(kept))
+		for i, n := range kept {
+			names[i] = n.Name
+		}
+		for i, name := range dedupeNames(names) {
+			kept[i].Name = name
+		}
+	}
+	report.Result = len(kept)
+	return kept, report
+}
+
+// nodeDedupSuffix 重复节点追加来源订阅名称，没有来源时追加序号
+func nodeDedupSuffix(n ProxyNode, index int) string {
+	source := n.Source
+	if source == "" && n.IsManual {
+		source = "手动"
+	}
+	if source == "" {
+		return fmt.Sprintf("%s #%d", n.Name, index)
+	}
+	return fmt.Sprintf("%s [%s]", n.Name, source)
+}
+
+// nodeDedupMutator 跨订阅合并重复节点，在重命名之前执行以便按原始名称追加来源
+type nodeDedupMutator struct{}
+
+func (nodeDedupMutator) Name() string       { return "node-dedup" }
+func (nodeDedupMutator) Stage() ConfigStage { return ConfigStageNodes }
+
+func (nodeDedupMutator) Mutate(build *ConfigBuild) error {
+	cfg := build.Options.NodeDedup
+	if !cfg.Enabled {
+		return nil
+	}
+	build.Nodes, build.DedupReport = dedupeNodes(build.Nodes, cfg.Strategy)
+	return nil
+}
+
+// recordNodeDedup 保存实际生成配置时的去重结果，未开启时清空上次的结果
+func (s *Service) recordNodeDedup(report *NodeDedupReport) {
+	if report != nil {
+		if merged := report.Total - report.Result; merged > 0 {
+			fmt.Printf("🧹 合并 %d 个重复节点\n", merged)
+		}
+	}
+
+	s.dedup.mu.Lock()
+	s.dedup.report = report
+	s.dedup.mu.Unlock()
+}
+
+// GetNodeDedup 获取去重设置
+func (s *Service) GetNodeDedup() NodeDedupConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg := s.config.NodeDedup
+	if cfg.Strategy == "" {
+		cfg.Strategy = NodeDedupKeepFirst
+	}
+	return cfg
+}
+
+// SetNodeDedup 更新去重设置，下次生成配置时生效
+func (s *Service) SetNodeDedup(cfg NodeDedupConfig) (NodeDedupConfig, error) {
+	normalized, err := cfg.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.NodeDedup = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetNodeDedup 获取去重设置和最近一次去重结果（?preview=true 时按当前节点重新计算）
+func (h *Handler) GetNodeDedup(c *gin.Context) {
+	cfg := h.service.GetNodeDedup()
+
+	var report *NodeDedupReport
+	if c.Query("preview") == "true" {
+		provider := h.service.nodeProvider
+		if provider == nil {
+			apierror.Message(c, http.StatusServiceUnavailable, "节点提供者未设置")
+			return
+		}
+		_, report = dedupeNodes(provider(), cfg.Strategy)
+	} else {
+		h.service.dedup.mu.Lock()
+		report = h.service.dedup.report
+		h.service.dedup.mu.Unlock()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"config": cfg,
+			"report": report,
+		},
+	})
+}
+
+// SetNodeDedup 更新去重设置
+func (h *Handler) SetNodeDedup(c *gin.Context) {
+	var req NodeDedupConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	cfg, err := h.service.SetNodeDedup(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cfg,
+	})
+}
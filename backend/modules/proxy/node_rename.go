@@ -0,0 +1,369 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 国旗处理方式
+const (
+	NodeFlagKeep  = "keep"  // 保持原样
+	NodeFlagAdd   = "add"   // 统一放到名称开头，缺少时按地区补充
+	NodeFlagStrip = "strip" // 去除国旗
+)
+
+// NodeRenameRule 正则替换规则，替换内容为空时删除匹配部分
+type NodeRenameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// NodeRenameConfig 节点重命名设置，生成 Mihomo 和 Sing-Box 配置前统一处理节点名称
+// 代理组过滤、节点标签、链式代理等均使用处理后的名称
+type NodeRenameConfig struct {
+	Enabled       bool             `json:"enabled"`
+	StripPrefixes []string         `json:"stripPrefixes"` // 去除的名称前缀（如机场名），不区分大小写
+	Rules         []NodeRenameRule `json:"rules"`         // 依次执行的正则替换
+	Flag          string           `json:"flag"`          // keep / add / strip
+	Numbering     bool             `json:"numbering"`     // 按地区重新编号（去掉名称末尾原有的编号）
+	Dedupe        bool             `json:"dedupe"`        // 重名节点追加序号
+}
+
+// normalize 校验并整理重命名设置
+func (c NodeRenameConfig) normalize() (NodeRenameConfig, error) {
+	result := NodeRenameConfig{
+		Enabled:       c.Enabled,
+		StripPrefixes: make([]string, 0, len(c.StripPrefixes)),
+		Rules:         make([]NodeRenameRule, 0, len(c.Rules)),
+		Flag:          c.Flag,
+		Numbering:     c.Numbering,
+		Dedupe:        c.Dedupe,
+	}
+	for _, p := range c.StripPrefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			result.StripPrefixes = append(result.StripPrefixes, p)
+		}
+	}
+	for i, r := range c.Rules {
+		if r.Pattern == "" {
+			return result, fmt.Errorf("第 %d 条替换规则: 正则不能为空", i+1)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return result, fmt.Errorf("第 %d 条替换规则: 正则无效: %v", i+1, err)
+		}
+		result.Rules = append(result.Rules, r)
+	}
+	switch result.Flag {
+	case "":
+		result.Flag = NodeFlagKeep
+	case NodeFlagKeep, NodeFlagAdd, NodeFlagStrip:
+	default:
+		return result, fmt.Errorf("无效的国旗处理方式: %s", c.Flag)
+	}
+	return result, nil
+}
+
+// trailingNumberPattern 名称末尾的编号，如 "香港 01"、"HK-3"、"#12"
+var trailingNumberPattern = regexp.MustCompile(`[\s\-_#|]*\d+$`)
+
+// nodeRenamer 按设置批量处理节点名称
+type nodeRenamer struct {
+	cfg   NodeRenameConfig
+	rules []*regexp.Regexp
+}
+
+// newNodeRenamer 根据设置创建重命名器，无效的正则规则会被跳过
+func newNodeRenamer(cfg NodeRenameConfig) *nodeRenamer {
+	r := &nodeRenamer{cfg: cfg}
+	for _, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			re = nil
+		}
+		r.rules = append(r.rules, re)
+	}
+	return r
+}
+
+// Rename 返回与 names 一一对应的新名称
+// 依次执行：去除前缀 → 正则替换 → 国旗处理 → 按地区编号 → 重名去重，处理后为空的名称保持原样
+func (r *nodeRenamer) Rename(names []string) []string {
+	result := make([]string, len(names))
+	regions := make([]string, len(names))
+	for i, name := range names {
+		renamed := r.clean(name)
+		flag, rest := splitFlags(renamed)
+		region := nodeRegion(name)
+		switch r.cfg.Flag {
+		case NodeFlagAdd:
+			if flag == "" && region != nil {
+				flag = region.Icon
+			}
+			renamed = strings.TrimSpace(flag + " " + rest)
+		case NodeFlagStrip:
+			renamed = rest
+		}
+		if strings.TrimSpace(renamed) == "" {
+			renamed = name
+		}
+		result[i] = renamed
+		if region != nil {
+			regions[i] = region.Code
+		}
+	}
+
+	if r.cfg.Numbering {
+		counters := make(map[string]int)
+		for i, name := range result {
+			base := strings.TrimSpace(trailingNumberPattern.ReplaceAllString(name, ""))
+			if base == "" {
+				base = name
+			}
+			counters[regions[i]]++
+			result[i] = fmt.Sprintf("%s %02d", base, counters[regions[i]])
+		}
+	}
+
+	if r.cfg.Dedupe {
+		result = dedupeNames(result)
+	}
+	return result
+}
+
+// clean 去除前缀并执行正则替换，合并多余的空白
+func (r *nodeRenamer) clean(name string) string {
+	for _, prefix := range r.cfg.StripPrefixes {
+		if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			name = strings.TrimLeft(name[len(prefix):], " -_|")
+			break
+		}
+	}
+	for i, re := range r.rules {
+		if re != nil {
+			name = re.ReplaceAllString(name, r.cfg.Rules[i].Replace)
+		}
+	}
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// splitFlags 拆分名称中的国旗 emoji，返回第一面国旗和去掉所有国旗后的名称
+func splitFlags(name string) (string, string) {
+	const first, last = 0x1F1E6, 0x1F1FF // 🇦 - 🇿
+	var flag string
+	var rest []rune
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		if i+1 < len(runes) && runes[i] >= first && runes[i] <= last && runes[i+1] >= first && runes[i+1] <= last {
+			if flag == "" {
+				flag = string(runes[i : i+2])
+			}
+			i++
+			continue
+		}
+		rest = append(rest, runes[i])
+	}
+	return flag, strings.Join(strings.Fields(string(rest)), " ")
+}
+
+// nodeRegion 识别节点所属地区：有国旗时以国旗为准，否则按地区关键字匹配第一个地区
+func nodeRegion(name string) *RegionPattern {
+	codes := flagCodes(name)
+	for i := range RegionPatterns {
+		region := &RegionPatterns[i]
+		if len(codes) > 0 {
+			if region.Code == codes[0] {
+				return region
+			}
+			continue
+		}
+		if regionTagPatterns[region.Code].MatchString(name) {
+			return region
+		}
+	}
+	return nil
+}
+
+// dedupeNames 重名时从第二个开始追加 " 2"、" 3"，跳过已被占用的名称
+func dedupeNames(names []string) []string {
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		used[name] = true
+	}
+	seen := make(map[string]bool, len(names))
+	result := make([]string, len(names))
+	for i, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result[i] = name
+			continue
+		}
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s %d", name, n)
+			if !used[candidate] {
+				used[candidate] = true
+				seen[candidate] = true
+				result[i] = candidate
+				break
+			}
+		}
+	}
+	return result
+}
+
+// nodeRenameMutator 生成配置前重命名节点，并同步更新模板和链式代理中引用的节点名称
+type nodeRenameMutator struct{}
+
+func (nodeRenameMutator) Name() string       { return "node-rename" }
+func (nodeRenameMutator) Stage() ConfigStage { return ConfigStageNodes }
+
+func (nodeRenameMutator) Mutate(build *ConfigBuild) error {
+	cfg := build.Options.NodeRename
+	if !cfg.Enabled || len(build.Nodes) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(build.Nodes))
+	for i, n := range build.Nodes {
+		names[i] = n.Name
+	}
+	renamed := newNodeRenamer(cfg).Rename(names)
+	mapping := make(map[string]string)
+	for i := range build.Nodes {
+		if renamed[i] != names[i] {
+			// 原名称重复时只映射第一个
+			if _, ok := mapping[names[i]]; !ok {
+				mapping[names[i]] = renamed[i]
+			}
+			build.Nodes[i].Name = renamed[i]
+		}
+	}
+	if len(mapping) == 0 {
+		return nil
+	}
+	rename := func(list []string) []string {
+		result := make([]string, len(list))
+		for i, name := range list {
+			if to, ok := mapping[name]; ok {
+				name = to
+			}
+			result[i] = name
+		}
+		return result
+	}
+
+	// 模板由 Service 持有，复制后再修改
+	if t := build.Options.Template; t != nil {
+		copied := *t
+		copied.ProxyGroups = make([]ProxyGroupTemplate, len(t.ProxyGroups))
+		for i, g := range t.ProxyGroups {
+			g.Proxies = rename(g.Proxies)
+			copied.ProxyGroups[i] = g
+		}
+		build.Options.Template = &copied
+	}
+	chains := make([]ProxyChain, len(build.Options.ProxyChains))
+	for i, c := range build.Options.ProxyChains {
+		c.Hops = rename(c.Hops)
+		chains[i] = c
+	}
+	build.Options.ProxyChains = chains
+	return nil
+}
+
+// GetNodeRename 获取节点重命名设置
+func (s *Service) GetNodeRename() NodeRenameConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg := s.config.NodeRename
+	cfg.StripPrefixes = append([]string{}, cfg.StripPrefixes...)
+	cfg.Rules = append([]NodeRenameRule{}, cfg.Rules...)
+	if cfg.Flag == "" {
+		cfg.Flag = NodeFlagKeep
+	}
+	return cfg
+}
+
+// SetNodeRename 更新节点重命名设置，下次生成配置时生效
+func (s *Service) SetNodeRename(cfg NodeRenameConfig) (NodeRenameConfig, error) {
+	normalized, err := cfg.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.NodeRename = normalized
+	return normalized, s.saveConfig()
+}
+
+// NodeRenameInfo 节点重命名预览
+type NodeRenameInfo struct {
+	Original string `json:"original"`
+	Name     string `json:"name"`
+}
+
+// GetNodeRename 获取节点重命名设置
+func (h *Handler) GetNodeRename(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetNodeRename(),
+	})
+}
+
+// SetNodeRename 更新节点重命名设置
+func (h *Handler) SetNodeRename(c *gin.Context) {
+	var req NodeRenameConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	cfg, err := h.service.SetNodeRename(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cfg,
+	})
+}
+
+// PreviewNodeRename 预览当前节点重命名后的名称（未开启时也按设置计算）
+func (h *Handler) PreviewNodeRename(c *gin.Context) {
+	provider := h.service.nodeProvider
+	if provider == nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "节点提供者未设置")
+		return
+	}
+
+	nodes := provider()
+	names := make([]string, len(nodes))
+	for i, n := range nodes {
+		names[i] = n.Name
+	}
+	renamed := newNodeRenamer(h.service.GetNodeRename()).Rename(names)
+	result := make([]NodeRenameInfo, len(names))
+	changed := 0
+	for i := range names {
+		result[i] = NodeRenameInfo{Original: names[i], Name: renamed[i]}
+		if renamed[i] != names[i] {
+			changed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"nodes":   result,
+			"changed": changed,
+		},
+	})
+}
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/clashapi"
+)
+
+// speedtestGroupName 测速专用的隐藏代理组
+const speedtestGroupName = "ProxyStation-Speedtest"
+
+// DefaultSpeedtestURL 默认测速下载地址
+const DefaultSpeedtestURL = "https://speed.cloudflare.com/__down?bytes=104857600"
+
+// applyMihomoSpeedtest 本机专用监听固定走隐藏的测速组，切换该组不影响正常流量
+func applyMihomoSpeedtest(config *MihomoConfig, port int) {
+	if port <= 0 || len(config.Proxies) == 0 {
+		return
+	}
+	names := make([]string, 0, len(config.Proxies))
+	for _, p := range config.Proxies {
+		if name, ok := p["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	config.ProxyGroups = append(config.ProxyGroups, ProxyGroup{
+		Name:    speedtestGroupName,
+		Type:    "select",
+		Proxies: names,
+		Hidden:  true,
+	})
+	config.Listeners = append(config.Listeners, MihomoListener{
+		Name:   "proxystation-speedtest",
+		Type:   "mixed",
+		Port:   port,
+		Listen: "127.0.0.1",
+		Proxy:  speedtestGroupName,
+	})
+}
+
+// NodeSpeedResult 单节点下载测速结果
+type NodeSpeedResult struct {
+	Name          string  `json:"name"`
+	DownloadSpeed float64 `json:"downloadSpeed"` // Mbps
+	Bytes         int64   `json:"bytes"`
+	Duration      float64 `json:"duration"` // 秒
+	TTFB          int64   `json:"ttfb"`     // 首字节时间 ms
+}
+
+// speedtestMu 测速组同一时间只能指向一个节点，测速需串行执行
+var speedtestMu sync.Mutex
+
+// SpeedTestNode 通过测速专用监听下载测试文件，测量指定节点的持续吞吐量
+// 下载达到 duration 或文件结束时停止，ctx 取消时中止
+func (h *Handler) SpeedTestNode(ctx context.Context, name, testURL string, duration time.Duration) (*NodeSpeedResult, error) {
+	status := h.service.GetStatus()
+	if !status.Running {
+		return nil, ErrCoreNotRunning
+	}
+	if h.service.GetCoreType() != "mihomo" {
+		return nil, fmt.Errorf("节点测速仅支持 Mihomo 核心")
+	}
+	port := h.service.GetConfig().SpeedtestPort
+	if port <= 0 {
+		return nil, fmt.Errorf("未启用测速监听端口")
+	}
+	if testURL == "" {
+		testURL = DefaultSpeedtestURL
+	}
+	if duration <= 0 {
+		duration = 10 * time.Second
+	}
+
+	speedtestMu.Lock()
+	defer speedtestMu.Unlock()
+
+	// 将测速组切换到目标节点
+	if err := h.clashAPI().SelectProxy(ctx, speedtestGroupName, name); err != nil {
+		var apiErr *clashapi.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("核心配置中没有测速组，请重新生成配置并重启")
+		}
+		return nil, fmt.Errorf("切换测速节点失败: %w", err)
+	}
+
+	proxyURL, _ := url.Parse("http://127.0.0.1:" + strconv.Itoa(port))
+	client := &http.Client{
+		Timeout: duration + 15*time.Second,
+		Transport: &http.Transport{
+			Proxy:             http.ProxyURL(proxyURL),
+			DisableKeepAlives: true,
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, testURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("无效的测速地址: %w", err)
+	}
+	start := time.Now()
+	dlResp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	defer dlResp.Body.Close()
+	if dlResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载失败: HTTP %d", dlResp.StatusCode)
+	}
+
+	// 首字节时间包含握手，吞吐量从首字节之后开始计算
+	buf := make([]byte, 64*1024)
+	var total int64
+	var transferStart time.Time
+	var ttfb time.Duration
+	deadline := start.Add(duration)
+	for time.Now().Before(deadline) {
+		n, err := dlResp.Body.Read(buf)
+		if n > 0 {
+			if transferStart.IsZero() {
+				transferStart = time.Now()
+				ttfb = transferStart.Sub(start)
+				deadline = transferStart.Add(duration)
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if total == 0 {
+				return nil, fmt.Errorf("下载失败: %w", err)
+			}
+			// 已有数据时按中断前的数据计算
+			if !strings.Contains(err.Error(), "timeout") {
+				fmt.Printf("⚠️ 节点 %s 测速中断: %v\n", name, err)
+			}
+			break
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("未下载到任何数据")
+	}
+
+	elapsed := time.Since(transferStart)
+	if elapsed <= 0 {
+		elapsed = time.Millisecond
+	}
+	return &NodeSpeedResult{
+		Name:          name,
+		DownloadSpeed: float64(total) * 8 / elapsed.Seconds() / 1e6,
+		Bytes:         total,
+		Duration:      elapsed.Seconds(),
+		TTFB:          ttfb.Milliseconds(),
+	}, nil
+}
@@ -0,0 +1,304 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NodeTagRule 自定义节点标签规则：名称包含任一关键字或匹配正则时打上该标签
+type NodeTagRule struct {
+	Tag      string   `json:"tag"`                // 标签名称，如 "家宽"、"IPLC"
+	Enabled  bool     `json:"enabled"`            // 是否启用
+	Keywords []string `json:"keywords,omitempty"` // 关键字（不区分大小写）
+	Regex    string   `json:"regex,omitempty"`    // 正则表达式
+}
+
+// NodeTagConfig 节点标签设置
+type NodeTagConfig struct {
+	Regions bool          `json:"regions"` // 启用内置地区标签（HK、SG、US ... 及国旗 emoji）
+	Rules   []NodeTagRule `json:"rules"`
+}
+
+// normalize 校验并整理标签规则
+func (c NodeTagConfig) normalize() (NodeTagConfig, error) {
+	result := NodeTagConfig{Regions: c.Regions, Rules: make([]NodeTagRule, 0, len(c.Rules))}
+	for _, r := range c.Rules {
+		r.Tag = strings.TrimSpace(r.Tag)
+		r.Regex = strings.TrimSpace(r.Regex)
+		if r.Tag == "" {
+			return result, fmt.Errorf("标签名称不能为空")
+		}
+		keywords := make([]string, 0, len(r.Keywords))
+		for _, kw := range r.Keywords {
+			if kw = strings.TrimSpace(kw); kw != "" {
+				keywords = append(keywords, kw)
+			}
+		}
+		r.Keywords = keywords
+		if len(r.Keywords) == 0 && r.Regex == "" {
+			return result, fmt.Errorf("标签 %s: 需要填写关键字或正则", r.Tag)
+		}
+		if r.Regex != "" {
+			if _, err := regexp.Compile(r.Regex); err != nil {
+				return result, fmt.Errorf("标签 %s: 正则无效: %v", r.Tag, err)
+			}
+		}
+		result.Rules = append(result.Rules, r)
+	}
+	return result, nil
+}
+
+// regionTagPatterns 地区标签匹配正则
+// 与 RegionPatterns 相同，但 3 个字母以内的缩写（HK、US、IN ...）要求前后不是字母，避免 "Plain" 被识别为印度
+var regionTagPatterns = buildRegionTagPatterns()
+
+func buildRegionTagPatterns() map[string]*regexp.Regexp {
+	short := regexp.MustCompile(`^[A-Za-z]{1,3}$`)
+	patterns := make(map[string]*regexp.Regexp, len(RegionPatterns))
+	for _, region := range RegionPatterns {
+		alternatives := strings.Split(strings.TrimPrefix(region.Pattern.String(), "(?i)"), "|")
+		for i, alt := range alternatives {
+			if short.MatchString(alt) {
+				alternatives[i] = `(?:^|[^A-Za-z])` + alt + `(?:[^A-Za-z]|$)`
+			}
+		}
+		patterns[region.Code] = regexp.MustCompile("(?i)" + strings.Join(alternatives, "|"))
+	}
+	return patterns
+}
+
+// NodeTagger 按节点名称计算标签
+type NodeTagger struct {
+	regions bool
+	rules   []compiledTagRule
+}
+
+type compiledTagRule struct {
+	tag      string
+	keywords []string // 已转为小写
+	re       *regexp.Regexp
+}
+
+// NewNodeTagger 根据标签设置创建标签器，无效的正则规则会被跳过
+func NewNodeTagger(cfg NodeTagConfig) *NodeTagger {
+	t := &NodeTagger{regions: cfg.Regions}
+	for _, r := range cfg.Rules {
+		if !r.Enabled {
+			continue
+		}
+		rule := compiledTagRule{tag: r.Tag}
+		for _, kw := range r.Keywords {
+			rule.keywords = append(rule.keywords, strings.ToLower(kw))
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				continue
+			}
+			rule.re = re
+		}
+		t.rules = append(t.rules, rule)
+	}
+	return t
+}
+
+// Tags 计算节点的标签
+// 地区标签：名称中有国旗 emoji 时以国旗为准，否则按地区关键字匹配（可能命中多个地区）
+func (t *NodeTagger) Tags(name string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if key := strings.ToUpper(tag); !seen[key] {
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	if t.regions {
+		flags := flagCodes(name)
+		for _, code := range flags {
+			add(code)
+		}
+		if len(flags) == 0 {
+			for _, region := range RegionPatterns {
+				if regionTagPatterns[region.Code].MatchString(name) {
+					add(region.Code)
+				}
+			}
+		}
+	}
+
+	lower := strings.ToLower(name)
+	for _, r := range t.rules {
+		matched := r.re != nil && r.re.MatchString(name)
+		for _, kw := range r.keywords {
+			if matched {
+				break
+			}
+			matched = strings.Contains(lower, kw)
+		}
+		if matched {
+			add(r.tag)
+		}
+	}
+	return tags
+}
+
+// HasAny 节点是否带有任一指定标签（不区分大小写）
+func (t *NodeTagger) HasAny(name string, want []string) bool {
+	for _, tag := range t.Tags(name) {
+		for _, w := range want {
+			if strings.EqualFold(tag, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flagCodes 提取名称中的国旗 emoji 对应的地区代码（两个区域指示符组成一面国旗）
+func flagCodes(name string) []string {
+	const first, last = 0x1F1E6, 0x1F1FF // 🇦 - 🇿
+	var codes []string
+	runes := []rune(name)
+	for i := 0; i+1 < len(runes); i++ {
+		a, b := runes[i], runes[i+1]
+		if a < first || a > last || b < first || b > last {
+			continue
+		}
+		codes = append(codes, string([]rune{'A' + a - first, 'A' + b - first}))
+		i++
+	}
+	return codes
+}
+
+// tagFilterPattern 将标签转换为等价的节点过滤正则（用于只支持正则过滤的 Sing-Box 模板）
+func (t *NodeTagger) tagFilterPattern(tags []string) string {
+	var parts []string
+	for _, tag := range tags {
+		if t.regions {
+			if re, ok := regionTagPatterns[strings.ToUpper(tag)]; ok {
+				parts = append(parts, re.String())
+			}
+		}
+		for _, r := range t.rules {
+			if !strings.EqualFold(r.tag, tag) {
+				continue
+			}
+			if r.re != nil {
+				parts = append(parts, r.re.String())
+			}
+			for _, kw := range r.keywords {
+				parts = append(parts, "(?i)"+regexp.QuoteMeta(kw))
+			}
+		}
+	}
+	for i, part := range parts {
+		parts[i] = "(?:" + part + ")"
+	}
+	return strings.Join(parts, "|")
+}
+
+// GetNodeTags 获取节点标签设置
+func (s *Service) GetNodeTags() NodeTagConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg := s.config.NodeTags
+	cfg.Rules = make([]NodeTagRule, 0, len(s.config.NodeTags.Rules))
+	for _, r := range s.config.NodeTags.Rules {
+		r.Keywords = append([]string{}, r.Keywords...)
+		cfg.Rules = append(cfg.Rules, r)
+	}
+	return cfg
+}
+
+// SetNodeTags 更新节点标签设置，下次生成配置时生效
+func (s *Service) SetNodeTags(cfg NodeTagConfig) (NodeTagConfig, error) {
+	normalized, err := cfg.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.NodeTags = normalized
+	return normalized, s.saveConfig()
+}
+
+// NodeTagInfo 节点及其标签
+type NodeTagInfo struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// GetNodeTags 获取节点标签设置
+func (h *Handler) GetNodeTags(c *gin.Context) {
+	regions := make([]gin.H, 0, len(RegionPatterns))
+	for _, r := range RegionPatterns {
+		regions = append(regions, gin.H{"code": r.Code, "name": r.Name, "icon": r.Icon})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"config":  h.service.GetNodeTags(),
+			"regions": regions,
+		},
+	})
+}
+
+// SetNodeTags 更新节点标签设置
+func (h *Handler) SetNodeTags(c *gin.Context) {
+	var req NodeTagConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	cfg, err := h.service.SetNodeTags(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cfg,
+	})
+}
+
+// PreviewNodeTags 预览当前节点的标签和各标签的节点数
+func (h *Handler) PreviewNodeTags(c *gin.Context) {
+	provider := h.service.nodeProvider
+	if provider == nil {
+		apierror.Message(c, http.StatusServiceUnavailable, "节点提供者未设置")
+		return
+	}
+
+	tagger := NewNodeTagger(h.service.GetNodeTags())
+	nodes := []NodeTagInfo{}
+	counts := make(map[string]int)
+	for _, n := range provider() {
+		tags := tagger.Tags(n.Name)
+		if tags == nil {
+			tags = []string{}
+		}
+		for _, tag := range tags {
+			counts[tag]++
+		}
+		nodes = append(nodes, NodeTagInfo{Name: n.Name, Tags: tags})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"nodes":  nodes,
+			"counts": counts,
+		},
+	})
+}
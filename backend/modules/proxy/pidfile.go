@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// corePidFile 核心进程记录，后端重启后据此重新关联仍在运行的核心
+type corePidFile struct {
+	PID        int       `json:"pid"`
+	CoreType   string    `json:"coreType"`
+	CorePath   string    `json:"corePath"`
+	ConfigPath string    `json:"configPath"`
+	StartTime  time.Time `json:"startTime"`
+}
+
+func (s *Service) pidFilePath() string {
+	return filepath.Join(s.dataDir, "run", "core.pid")
+}
+
+// writePidFile 核心启动后写入 PID 文件
+func (s *Service) writePidFile(rec corePidFile) error {
+	path := s.pidFilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// removePidFile 删除 PID 文件（仅当记录的 PID 一致，避免误删重启后新进程的记录）
+func (s *Service) removePidFile(pid int) {
+	data, err := os.ReadFile(s.pidFilePath())
+	if err != nil {
+		return
+	}
+	var rec corePidFile
+	if json.Unmarshal(data, &rec) == nil && rec.PID != pid {
+		return
+	}
+	os.Remove(s.pidFilePath())
+}
+
+// liveCore 读取 PID 文件，确认记录的进程仍是本实例的核心，过期记录会被清理
+func (s *Service) liveCore() *corePidFile {
+	data, err := os.ReadFile(s.pidFilePath())
+	if err != nil {
+		return nil
+	}
+	var rec corePidFile
+	if json.Unmarshal(data, &rec) == nil && rec.PID > 0 && processAlive(rec.PID) &&
+		s.isStaleCore(processInfo(rec.PID), rec.CorePath) {
+		return &rec
+	}
+	// 进程已退出或 PID 已被其他程序复用
+	fmt.Printf("🧹 清理过期的 PID 文件 (PID %d)\n", rec.PID)
+	os.Remove(s.pidFilePath())
+	return nil
+}
+
+// recoverCore 后端重启后重新关联仍在运行的核心，避免重复启动
+// 关联的核心不是子进程，通过轮询检测退出，退出后按崩溃处理
+func (s *Service) recoverCore() {
+	rec := s.liveCore()
+	if rec == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if rec.CoreType != "" {
+		s.coreType = rec.CoreType
+	}
+	adopted := &adoptedCore{
+		pid:        rec.PID,
+		coreType:   s.coreType,
+		controller: s.config.ExternalController,
+		owned:      true,
+		stop:       make(chan struct{}),
+	}
+	s.adopted = adopted
+	s.running = true
+	s.startTime = rec.StartTime
+	s.configPath = rec.ConfigPath
+	s.coreVersion = coreVersion(s.runner(), rec.CorePath)
+	s.mu.Unlock()
+
+	s.addLog(fmt.Sprintf("[INFO] 已重新关联运行中的核心 (PID %d)", rec.PID))
+	fmt.Printf("🔗 已重新关联运行中的核心 (PID %d)\n", rec.PID)
+	go s.watchAdopted(adopted)
+}
+
+// stopRecovered 停止重新关联的核心，未关联时返回 false
+func (s *Service) stopRecovered() (bool, error) {
+	s.mu.Lock()
+	adopted := s.adopted
+	if adopted == nil || !adopted.owned {
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.cancelRestart()
+	if err := killProcess(adopted.pid, s.drainTimeout()); err != nil {
+		s.mu.Unlock()
+		return true, fmt.Errorf("failed to stop core: %w", err)
+	}
+	s.adopted = nil
+	s.running = false
+	s.mu.Unlock()
+
+	close(adopted.stop)
+	s.removePidFile(adopted.pid)
+	s.afterStop()
+	return true, nil
+}
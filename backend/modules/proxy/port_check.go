@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CorePort 核心需要监听的端口
+type CorePort struct {
+	Name     string `json:"name"`     // 配置项，如 mixed-port
+	Address  string `json:"address"`  // 监听地址 host:port
+	Protocol string `json:"protocol"` // tcp, udp
+}
+
+// PortOwner 占用端口的进程
+type PortOwner struct {
+	PID     int    `json:"pid"`
+	Name    string `json:"name"`
+	Exe     string `json:"exe,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
+}
+
+// PortConflict 端口冲突
+type PortConflict struct {
+	CorePort
+	Error string     `json:"error"`
+	Owner *PortOwner `json:"owner,omitempty"` // 无法识别时为空
+}
+
+// PortConflictError 启动前检测到端口被占用
+type PortConflictError struct {
+	Conflicts []PortConflict
+}
+
+func (e *PortConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		desc := fmt.Sprintf("%s %s/%s", c.Name, c.Address, c.Protocol)
+		if c.Owner != nil {
+			desc += fmt.Sprintf(" 被 %s (PID %d) 占用", c.Owner.Name, c.Owner.PID)
+		} else {
+			desc += " 已被占用"
+		}
+		parts = append(parts, desc)
+	}
+	return "端口冲突: " + strings.Join(parts, "；")
+}
+
+// corePorts 从生成的配置中读取核心将要监听的端口
+func corePorts(coreType string, content []byte) ([]CorePort, error) {
+	if coreType == "singbox" {
+		return singboxPorts(content)
+	}
+	return mihomoPorts(content)
+}
+
+// mihomoPorts 读取 Mihomo 配置中的监听端口
+func mihomoPorts(content []byte) ([]CorePort, error) {
+	var config struct {
+		Port               int    `yaml:"port"`
+		SocksPort          int    `yaml:"socks-port"`
+		MixedPort          int    `yaml:"mixed-port"`
+		RedirPort          int    `yaml:"redir-port"`
+		TProxyPort         int    `yaml:"tproxy-port"`
+		AllowLan           bool   `yaml:"allow-lan"`
+		BindAddress        string `yaml:"bind-address"`
+		ExternalController string `yaml:"external-controller"`
+		DNS                struct {
+			Enable bool   `yaml:"enable"`
+			Listen string `yaml:"listen"`
+		} `yaml:"dns"`
+		Listeners []struct {
+			Name   string `yaml:"name"`
+			Port   int    `yaml:"port"`
+			Listen string `yaml:"listen"`
+		} `yaml:"listeners"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	host := "127.0.0.1"
+	if config.AllowLan {
+		host = "0.0.0.0"
+		if config.BindAddress != "" && config.BindAddress != "*" {
+			host = config.BindAddress
+		}
+	}
+
+	var ports []CorePort
+	addPort := func(name string, port int) {
+		if port > 0 {
+			ports = append(ports, CorePort{Name: name, Address: net.JoinHostPort(host, strconv.Itoa(port)), Protocol: "tcp"})
+		}
+	}
+	addPort("port", config.Port)
+	addPort("socks-port", config.SocksPort)
+	addPort("mixed-port", config.MixedPort)
+	addPort("redir-port", config.RedirPort)
+	addPort("tproxy-port", config.TProxyPort)
+
+	if config.ExternalController != "" {
+		ports = append(ports, CorePort{Name: "external-controller", Address: config.ExternalController, Protocol: "tcp"})
+	}
+	if config.DNS.Enable && config.DNS.Listen != "" {
+		ports = append(ports, CorePort{Name: "dns.listen", Address: config.DNS.Listen, Protocol: "udp"})
+	}
+	for _, l := range config.Listeners {
+		if l.Port <= 0 {
+			continue
+		}
+		listen := l.Listen
+		if listen == "" {
+			listen = "0.0.0.0"
+		}
+		ports = append(ports, CorePort{Name: "listeners." + l.Name, Address: net.JoinHostPort(listen, strconv.Itoa(l.Port)), Protocol: "tcp"})
+	}
+	return ports, nil
+}
+
+// singboxPorts 读取 Sing-Box 配置中的入站端口和 Clash API 地址
+func singboxPorts(content []byte) ([]CorePort, error) {
+	var config struct {
+		Inbounds []struct {
+			Type       string `json:"type"`
+			Tag        string `json:"tag"`
+			Listen     string `json:"listen"`
+			ListenPort int    `json:"listen_port"`
+		} `json:"inbounds"`
+		Experimental struct {
+			ClashAPI struct {
+				ExternalController string `json:"external_controller"`
+			} `json:"clash_api"`
+		} `json:"experimental"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	var ports []CorePort
+	for _, in := range config.Inbounds {
+		if in.ListenPort <= 0 {
+			continue
+		}
+		listen := in.Listen
+		if listen == "" || listen == "::" {
+			listen = "0.0.0.0"
+		}
+		name := "inbounds." + in.Tag
+		if in.Tag == "" {
+			name = "inbounds." + in.Type
+		}
+		protocol := "tcp"
+		if in.Type == "direct" {
+			protocol = "udp" // DNS 入站
+		}
+		ports = append(ports, CorePort{Name: name, Address: net.JoinHostPort(listen, strconv.Itoa(in.ListenPort)), Protocol: protocol})
+	}
+	if addr := config.Experimental.ClashAPI.ExternalController; addr != "" {
+		ports = append(ports, CorePort{Name: "clash_api", Address: addr, Protocol: "tcp"})
+	}
+	return ports, nil
+}
+
+// probePort 尝试监听端口，返回错误表示端口不可用
+func probePort(p CorePort) error {
+	if p.Protocol == "udp" {
+		conn, err := net.ListenPacket("udp", p.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	ln, err := net.Listen("tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return ln.Close()
+}
+
+// findPortConflicts 检查所有端口，返回冲突列表
+func findPortConflicts(ports []CorePort) []PortConflict {
+	var conflicts []PortConflict
+	for _, p := range ports {
+		err := probePort(p)
+		if err == nil {
+			continue
+		}
+		conflict := PortConflict{CorePort: p, Error: err.Error()}
+		if _, portStr, err := net.SplitHostPort(p.Address); err == nil {
+			if port, err := strconv.Atoi(portStr); err == nil {
+				conflict.Owner = findPortOwner(p.Protocol, port)
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
+// isStaleCore 占用端口的进程是否为本实例遗留的核心（ProxyStation 异常退出后未结束的核心进程）
+func (s *Service) isStaleCore(owner *PortOwner, corePath string) bool {
+	if owner == nil || owner.PID == os.Getpid() {
+		return false
+	}
+	if owner.Exe != "" {
+		exe := strings.TrimSuffix(owner.Exe, " (deleted)")
+		if resolved, err := filepath.EvalSymlinks(corePath); err == nil && exe == resolved {
+			return true
+		}
+		if exe == corePath {
+			return true
+		}
+	}
+	// 核心以 -d <dataDir> 启动，核心更新后可执行文件路径可能变化
+	coresDir := filepath.Join(s.dataDir, "cores")
+	return owner.Cmdline != "" && strings.Contains(owner.Cmdline, coresDir)
+}
+
+// checkCorePorts 启动前检查端口，遗留的核心进程会被结束，其他进程占用时返回 PortConflictError
+func (s *Service) checkCorePorts(coreType, configPath, corePath string) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil // 配置不存在时由核心报错
+	}
+	ports, err := corePorts(coreType, content)
+	if err != nil || len(ports) == 0 {
+		return nil
+	}
+
+	conflicts := findPortConflicts(ports)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	// 结束遗留的核心进程后重新检查
+	killed := map[int]bool{}
+	for _, c := range conflicts {
+		if c.Owner == nil || killed[c.Owner.PID] || !s.isStaleCore(c.Owner, corePath) {
+			continue
+		}
+		killed[c.Owner.PID] = true
+		s.addLog(fmt.Sprintf("[WARN] 结束遗留的核心进程 %s (PID %d)，占用 %s", c.Owner.Name, c.Owner.PID, c.Address))
+		fmt.Printf("🧹 结束遗留的核心进程 %s (PID %d)\n", c.Owner.Name, c.Owner.PID)
+		if err := killProcess(c.Owner.PID, 3*time.Second); err != nil {
+			fmt.Printf("⚠️ 结束进程 %d 失败: %v\n", c.Owner.PID, err)
+		}
+	}
+	if len(killed) > 0 {
+		conflicts = findPortConflicts(ports)
+		if len(conflicts) == 0 {
+			return nil
+		}
+	}
+
+	return &PortConflictError{Conflicts: conflicts}
+}
+
+// killProcess 结束进程并等待退出，超时后强制结束
+func killProcess(pid int, timeout time.Duration) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		return proc.Kill()
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !processAlive(pid) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return proc.Kill()
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// findPortOwner 通过 /proc/net 和 /proc/<pid>/fd 查找监听端口的进程
+func findPortOwner(protocol string, port int) *PortOwner {
+	// TCP 只看 LISTEN (0A)，UDP 为 CLOSE (07)
+	state := "0A"
+	if protocol == "udp" {
+		state = "07"
+	}
+	inodes := map[string]bool{}
+	for _, file := range []string{protocol, protocol + "6"} {
+		data, err := os.ReadFile(filepath.Join("/proc/net", file))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != state {
+				continue
+			}
+			idx := strings.LastIndex(fields[1], ":")
+			if idx < 0 {
+				continue
+			}
+			if p, err := strconv.ParseInt(fields[1][idx+1:], 16, 32); err == nil && int(p) == port {
+				inodes[fields[9]] = true
+			}
+		}
+	}
+	if len(inodes) == 0 {
+		return nil
+	}
+
+	procs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, dir := range procs {
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				pid, _ := strconv.Atoi(filepath.Base(dir))
+				return processInfo(pid)
+			}
+		}
+	}
+	return nil
+}
+
+// processInfo 读取进程名、可执行文件和命令行
+func processInfo(pid int) *PortOwner {
+	dir := fmt.Sprintf("/proc/%d", pid)
+	owner := &PortOwner{PID: pid}
+	if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+		owner.Name = strings.TrimSpace(string(comm))
+	}
+	owner.Exe, _ = os.Readlink(filepath.Join(dir, "exe"))
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		owner.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	return owner
+}
+
+// processAlive 进程是否仍在运行
+func processAlive(pid int) bool {
+	_, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return err == nil
+}
+
+// findCoreProcesses 按进程名查找正在运行的 mihomo / sing-box 进程
+func findCoreProcesses() []*PortOwner {
+	var result []*PortOwner
+	self := os.Getpid()
+	procs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, dir := range procs {
+		comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil {
+			continue
+		}
+		if _, ok := coreProcessNames[strings.TrimSpace(string(comm))]; !ok {
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(dir))
+		if pid == self {
+			continue
+		}
+		result = append(result, processInfo(pid))
+	}
+	return result
+}
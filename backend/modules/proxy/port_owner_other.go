@@ -0,0 +1,115 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// findPortOwner 使用 lsof 查找监听端口的进程（未安装 lsof 时返回 nil）
+func findPortOwner(protocol string, port int) *PortOwner {
+	if _, err := exec.LookPath("lsof"); err != nil {
+		return nil
+	}
+	args := []string{"-nP", "-F", "pc"}
+	if protocol == "udp" {
+		args = append(args, "-iUDP:"+strconv.Itoa(port))
+	} else {
+		args = append(args, "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN")
+	}
+	output, err := exec.Command("lsof", args...).Output()
+	if err != nil {
+		return nil
+	}
+
+	var owner *PortOwner
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			if owner != nil {
+				return owner
+			}
+			pid, _ := strconv.Atoi(line[1:])
+			owner = &PortOwner{PID: pid}
+		case 'c':
+			if owner != nil {
+				owner.Name = line[1:]
+			}
+		}
+	}
+	if owner != nil {
+		if output, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(owner.PID)).Output(); err == nil {
+			owner.Cmdline = strings.TrimSpace(string(output))
+		}
+	}
+	return owner
+}
+
+// processInfo 使用 ps 读取进程名和命令行（Windows 不支持）
+func processInfo(pid int) *PortOwner {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	output, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return nil
+	}
+	owner := &PortOwner{PID: pid, Name: filepath.Base(strings.TrimSpace(string(output)))}
+	if output, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output(); err == nil {
+		owner.Cmdline = strings.TrimSpace(string(output))
+	}
+	return owner
+}
+
+// processAlive 进程是否仍在运行
+func processAlive(pid int) bool {
+	if runtime.GOOS == "windows" {
+		return false // Windows 上 Kill 同步结束进程
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// findCoreProcesses 使用 ps 按进程名查找正在运行的 mihomo / sing-box 进程（Windows 不支持）
+func findCoreProcesses() []*PortOwner {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	output, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil
+	}
+	var result []*PortOwner
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name := filepath.Base(strings.Join(fields[1:], " "))
+		if _, ok := coreProcessNames[name]; !ok {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[0])
+		if pid <= 0 || pid == os.Getpid() {
+			continue
+		}
+		owner := &PortOwner{PID: pid, Name: name}
+		if output, err := exec.Command("ps", "-o", "command=", "-p", fields[0]).Output(); err == nil {
+			owner.Cmdline = strings.TrimSpace(string(output))
+		}
+		result = append(result, owner)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// ProcessStats 核心进程资源占用
+type ProcessStats struct {
+	CPUPercent float64 `json:"cpuPercent"` // 相对单个 CPU 核心，多核满载时可超过 100
+	RSS        int64   `json:"rss"`        // 常驻内存（字节）
+	FDs        int     `json:"fds"`        // 打开的文件描述符数，无法读取时为 -1
+	Threads    int     `json:"threads"`
+	Uptime     int64   `json:"uptime"` // 进程运行时间（秒）
+}
+
+// cpuSampler 记录上次采样的 CPU 时间，按两次采样的差值计算 CPU 占用
+type cpuSampler struct {
+	mu      sync.Mutex
+	pid     int
+	cpuTime time.Duration
+	at      time.Time
+}
+
+// percent 返回距上次采样的 CPU 占用，首次采样或进程变化时按进程整个生命周期平均
+func (c *cpuSampler) percent(pid int, cpuTime, uptime time.Duration) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var used, elapsed time.Duration
+	if c.pid == pid && !c.at.IsZero() && cpuTime >= c.cpuTime {
+		used, elapsed = cpuTime-c.cpuTime, now.Sub(c.at)
+	} else {
+		used, elapsed = cpuTime, uptime
+	}
+	c.pid, c.cpuTime, c.at = pid, cpuTime, now
+
+	if elapsed <= 0 {
+		return 0
+	}
+	percent := float64(used) / float64(elapsed) * 100
+	return float64(int(percent*10+0.5)) / 10
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks /proc/<pid>/stat 中时间字段的单位（USER_HZ，Linux 上固定为 100）
+const clockTicks = 100
+
+// readProcessStats 从 /proc 读取进程资源占用
+func (s *Service) readProcessStats(pid int) *ProcessStats {
+	dir := fmt.Sprintf("/proc/%d", pid)
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil
+	}
+	// 进程名可能包含空格和括号，从最后一个 ')' 之后解析
+	idx := strings.LastIndexByte(string(data), ')')
+	if idx < 0 {
+		return nil
+	}
+	// fields[0] 为第 3 个字段 state
+	fields := strings.Fields(string(data[idx+1:]))
+	if len(fields) < 22 {
+		return nil
+	}
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	startTicks, _ := strconv.ParseInt(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	stats := &ProcessStats{
+		RSS:     rssPages * int64(os.Getpagesize()),
+		Threads: threads,
+		FDs:     -1,
+	}
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		stats.FDs = len(fds)
+	}
+
+	var uptime time.Duration
+	if bootTime := systemBootTime(); !bootTime.IsZero() {
+		started := bootTime.Add(time.Duration(startTicks) * time.Second / clockTicks)
+		uptime = time.Since(started)
+		stats.Uptime = int64(uptime.Seconds())
+	}
+	cpuTime := time.Duration(utime+stime) * time.Second / clockTicks
+	stats.CPUPercent = s.cpu.percent(pid, cpuTime, uptime)
+	return stats
+}
+
+// systemBootTime 读取 /proc/stat 中的系统启动时间
+func systemBootTime() time.Time {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			if sec, err := strconv.ParseInt(strings.TrimSpace(line[6:]), 10, 64); err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
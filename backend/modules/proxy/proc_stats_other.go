@@ -0,0 +1,55 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// readProcessStats 使用 ps 读取进程资源占用（Windows 不支持）
+func (s *Service) readProcessStats(pid int) *ProcessStats {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	output, err := exec.Command("ps", "-o", "%cpu=,rss=,etime=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 3 {
+		return nil
+	}
+	cpu, _ := strconv.ParseFloat(fields[0], 64)
+	rssKB, _ := strconv.ParseInt(fields[1], 10, 64)
+	stats := &ProcessStats{
+		CPUPercent: cpu,
+		RSS:        rssKB * 1024,
+		FDs:        -1,
+		Uptime:     parseElapsed(fields[2]),
+	}
+	// macOS ps 不支持线程数，使用 ps -M 统计线程行数
+	if output, err := exec.Command("ps", "-M", "-p", strconv.Itoa(pid)).Output(); err == nil {
+		if lines := strings.Count(strings.TrimSpace(string(output)), "\n"); lines > 0 {
+			stats.Threads = lines
+		}
+	}
+	return stats
+}
+
+// parseElapsed 解析 ps etime 格式 [[dd-]hh:]mm:ss
+func parseElapsed(etime string) int64 {
+	var days int64
+	if i := strings.IndexByte(etime, '-'); i >= 0 {
+		days, _ = strconv.ParseInt(etime[:i], 10, 64)
+		etime = etime[i+1:]
+	}
+	var seconds int64
+	for _, part := range strings.Split(etime, ":") {
+		n, _ := strconv.ParseInt(part, 10, 64)
+		seconds = seconds*60 + n
+	}
+	return days*86400 + seconds
+}
@@ -0,0 +1,357 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileShareConfig 配置托管设置：其他设备的 Clash / sing-box 客户端可通过带令牌的 URL 订阅生成的配置
+type ProfileShareConfig struct {
+	Enabled        bool   `json:"enabled"`
+	Token          string `json:"token"`
+	UpdateInterval int    `json:"updateInterval"` // 客户端更新间隔（小时）
+}
+
+// SubscriptionInfo 订阅流量信息，用于 subscription-userinfo 响应头
+type SubscriptionInfo struct {
+	Upload   int64
+	Download int64
+	Total    int64
+	Expire   int64 // Unix 时间戳，0 表示不过期
+}
+
+// profileShare 配置托管状态
+type profileShare struct {
+	mu           sync.RWMutex
+	config       ProfileShareConfig
+	infoProvider func() SubscriptionInfo
+}
+
+// localOnlyKeys Mihomo 配置中只对本机有意义或包含控制器密钥的字段，对外提供时移除
+var localOnlyKeys = map[string]bool{
+	"external-controller":      true,
+	"external-controller-tls":  true,
+	"external-controller-unix": true,
+	"external-ui":              true,
+	"external-ui-name":         true,
+	"external-ui-url":          true,
+	"secret":                   true,
+	"tproxy-port":              true,
+	"redir-port":               true,
+	"routing-mark":             true,
+	"interface-name":           true,
+	"listeners":                true,
+}
+
+func (s *Service) profileSharePath() string {
+	return filepath.Join(s.dataDir, "profile_share.json")
+}
+
+// loadProfileShare 加载配置托管设置
+func (s *Service) loadProfileShare() {
+	s.share.config = ProfileShareConfig{UpdateInterval: 24}
+	data, err := os.ReadFile(s.profileSharePath())
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &s.share.config)
+}
+
+// saveProfileShare 保存配置托管设置（包含令牌，仅所有者可读，调用时需持有 s.share.mu 锁）
+func (s *Service) saveProfileShare() error {
+	data, err := json.MarshalIndent(s.share.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.profileSharePath(), data, 0600)
+}
+
+// SetSubscriptionInfoProvider 设置订阅流量信息提供者（由 subscription 模块注入）
+func (s *Service) SetSubscriptionInfoProvider(provider func() SubscriptionInfo) {
+	s.share.mu.Lock()
+	defer s.share.mu.Unlock()
+	s.share.infoProvider = provider
+}
+
+// GetProfileShare 获取配置托管设置
+func (s *Service) GetProfileShare() ProfileShareConfig {
+	s.share.mu.RLock()
+	defer s.share.mu.RUnlock()
+	return s.share.config
+}
+
+// SetProfileShare 开启或关闭配置托管，首次开启时生成令牌
+func (s *Service) SetProfileShare(enabled bool, updateInterval int) (ProfileShareConfig, error) {
+	s.share.mu.Lock()
+	defer s.share.mu.Unlock()
+
+	if updateInterval < 0 || updateInterval > 720 {
+		return s.share.config, fmt.Errorf("更新间隔需在 1-720 小时之间")
+	}
+	if updateInterval > 0 {
+		s.share.config.UpdateInterval = updateInterval
+	}
+	s.share.config.Enabled = enabled
+	if enabled && s.share.config.Token == "" {
+		s.share.config.Token = newShareToken()
+	}
+	return s.share.config, s.saveProfileShare()
+}
+
+// RotateProfileShareToken 重新生成令牌，旧的订阅地址立即失效
+func (s *Service) RotateProfileShareToken() (ProfileShareConfig, error) {
+	s.share.mu.Lock()
+	defer s.share.mu.Unlock()
+	s.share.config.Token = newShareToken()
+	return s.share.config, s.saveProfileShare()
+}
+
+func newShareToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// checkShareToken 校验托管令牌（常量时间比较）
+func (s *Service) checkShareToken(token string) bool {
+	s.share.mu.RLock()
+	defer s.share.mu.RUnlock()
+	expected := s.share.config.Token
+	return s.share.config.Enabled && expected != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// SharedProfile 读取已生成的配置并移除本机专用字段
+func (s *Service) SharedProfile(coreType string) ([]byte, error) {
+	content, err := os.ReadFile(runnerFor(coreType).ConfigPath(s.dataDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s 配置尚未生成", coreType)
+		}
+		return nil, err
+	}
+	if coreType == "singbox" {
+		return sanitizeSingBoxProfile(content)
+	}
+	return sanitizeMihomoProfile(content)
+}
+
+// sanitizeMihomoProfile 移除控制器、透明代理等本机字段，保留原有字段顺序
+func sanitizeMihomoProfile(content []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("配置格式无效")
+	}
+	root := doc.Content[0]
+	kept := make([]*yaml.Node, 0, len(root.Content))
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if localOnlyKeys[root.Content[i].Value] {
+			continue
+		}
+		// 节点上的路由标记和出站网卡同样只对本机有效
+		if root.Content[i].Value == "proxies" && root.Content[i+1].Kind == yaml.SequenceNode {
+			for _, proxy := range root.Content[i+1].Content {
+				removeYAMLKeys(proxy, "routing-mark", "interface-name")
+			}
+		}
+		kept = append(kept, root.Content[i], root.Content[i+1])
+	}
+	root.Content = kept
+	return yaml.Marshal(&doc)
+}
+
+// sanitizeSingBoxProfile 移除 Clash API、透明代理入站和路由标记
+func sanitizeSingBoxProfile(content []byte) ([]byte, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	if experimental, ok := config["experimental"].(map[string]interface{}); ok {
+		delete(experimental, "clash_api")
+		if len(experimental) == 0 {
+			delete(config, "experimental")
+		}
+	}
+	if inbounds, ok := config["inbounds"].([]interface{}); ok {
+		kept := make([]interface{}, 0, len(inbounds))
+		for _, in := range inbounds {
+			if m, ok := in.(map[string]interface{}); ok {
+				if t := m["type"]; t == "tproxy" || t == "redirect" {
+					continue
+				}
+			}
+			kept = append(kept, in)
+		}
+		config["inbounds"] = kept
+	}
+	if route, ok := config["route"].(map[string]interface{}); ok {
+		delete(route, "default_mark")
+		if _, bound := route["default_interface"]; bound {
+			delete(route, "default_interface")
+			route["auto_detect_interface"] = true
+		}
+	}
+	if outbounds, ok := config["outbounds"].([]interface{}); ok {
+		for _, out := range outbounds {
+			if m, ok := out.(map[string]interface{}); ok {
+				delete(m, "routing_mark")
+				delete(m, "bind_interface")
+			}
+		}
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// removeYAMLKeys 从映射节点中删除指定字段
+func removeYAMLKeys(node *yaml.Node, keys ...string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	kept := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		remove := false
+		for _, key := range keys {
+			if node.Content[i].Value == key {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			kept = append(kept, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = kept
+}
+
+// GetProfileShare 获取配置托管设置和订阅地址
+func (h *Handler) GetProfileShare(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.profileShareData(c, h.service.GetProfileShare()),
+	})
+}
+
+// SetProfileShare 开启或关闭配置托管
+func (h *Handler) SetProfileShare(c *gin.Context) {
+	var req struct {
+		Enabled        bool `json:"enabled"`
+		UpdateInterval int  `json:"updateInterval"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	config, err := h.service.SetProfileShare(req.Enabled, req.UpdateInterval)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.profileShareData(c, config),
+	})
+}
+
+// RotateProfileShareToken 重新生成托管令牌
+func (h *Handler) RotateProfileShareToken(c *gin.Context) {
+	config, err := h.service.RotateProfileShareToken()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.profileShareData(c, config),
+	})
+}
+
+// profileShareData 托管设置及按当前访问地址拼接的订阅 URL
+func (h *Handler) profileShareData(c *gin.Context, config ProfileShareConfig) gin.H {
+	data := gin.H{
+		"enabled":        config.Enabled,
+		"token":          config.Token,
+		"updateInterval": config.UpdateInterval,
+	}
+	if config.Token != "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base := fmt.Sprintf("%s://%s/sub/%s", scheme, c.Request.Host, config.Token)
+		data["urls"] = gin.H{
+			"mihomo":  base + "/mihomo",
+			"singbox": base + "/singbox",
+		}
+	}
+	return data
+}
+
+// ServeSharedProfile 对外提供生成的配置（不经过登录认证，使用托管令牌校验）
+// GET /sub/:token/:core，core 为 mihomo 或 singbox
+func (h *Handler) ServeSharedProfile(c *gin.Context) {
+	if !h.service.checkShareToken(c.Param("token")) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	coreType := c.Param("core")
+	var filename, contentType string
+	switch coreType {
+	case "mihomo", "clash":
+		coreType = "mihomo"
+		filename, contentType = "ProxyStation.yaml", "text/yaml; charset=utf-8"
+	case "singbox", "sing-box":
+		coreType = "singbox"
+		filename, contentType = "ProxyStation.json", "application/json; charset=utf-8"
+	default:
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	content, err := h.service.SharedProfile(coreType)
+	if err != nil {
+		c.String(http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	s := h.service
+	s.share.mu.RLock()
+	provider := s.share.infoProvider
+	interval := s.share.config.UpdateInterval
+	s.share.mu.RUnlock()
+
+	if provider != nil {
+		info := provider()
+		c.Header("subscription-userinfo", fmt.Sprintf("upload=%d; download=%d; total=%d; expire=%d",
+			info.Upload, info.Download, info.Total, info.Expire))
+	}
+	if interval > 0 {
+		c.Header("profile-update-interval", fmt.Sprint(interval))
+	}
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, content)
+}
@@ -0,0 +1,423 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/secure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ConfigProfile 配置方案（代理设置 + 配置模板），如“家庭路由器”“出差笔记本”“游戏”
+type ConfigProfile struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	Config      *ProxyConfig    `json:"config"`
+	Template    *ConfigTemplate `json:"template"`
+}
+
+// ProfileSummary 配置方案列表项
+type ProfileSummary struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Active      bool      `json:"active"`
+}
+
+// profileStore profiles.json 文件结构
+type profileStore struct {
+	Active   string           `json:"active"`
+	Profiles []*ConfigProfile `json:"profiles"`
+}
+
+func (p *profileStore) find(id string) *ConfigProfile {
+	for _, profile := range p.Profiles {
+		if profile.ID == id {
+			return profile
+		}
+	}
+	return nil
+}
+
+func (s *Service) profilesFile() string {
+	return filepath.Join(s.dataDir, "profiles.json")
+}
+
+// loadProfiles 读取配置方案，首次使用时以当前配置创建默认方案
+// 调用方需持有 s.profileMu
+func (s *Service) loadProfiles() (*profileStore, error) {
+	store := &profileStore{}
+	if data, err := secure.ReadFile(s.profilesFile()); err == nil {
+		if err := json.Unmarshal(data, store); err != nil {
+			return nil, fmt.Errorf("读取配置方案失败: %w", err)
+		}
+	}
+
+	if len(store.Profiles) == 0 {
+		config, template, err := s.snapshotLiveConfig()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		store.Profiles = []*ConfigProfile{{
+			ID:        "default",
+			Name:      "默认",
+			CreatedAt: now,
+			UpdatedAt: now,
+			Config:    config,
+			Template:  template,
+		}}
+		store.Active = "default"
+	}
+	if store.find(store.Active) == nil {
+		store.Active = store.Profiles[0].ID
+	}
+	return store, nil
+}
+
+func (s *Service) saveProfiles(store *profileStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return secure.WriteFile(s.profilesFile(), data, 0644)
+}
+
+// snapshotLiveConfig 深拷贝当前生效的代理设置和配置模板
+func (s *Service) snapshotLiveConfig() (*ProxyConfig, *ConfigTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var config ProxyConfig
+	if err := deepCopyJSON(s.config, &config); err != nil {
+		return nil, nil, err
+	}
+	var template ConfigTemplate
+	if err := deepCopyJSON(s.configTemplate, &template); err != nil {
+		return nil, nil, err
+	}
+	return &config, &template, nil
+}
+
+// syncActiveProfile 将当前生效的配置写回激活的方案（配置可能已通过其他接口修改）
+func (s *Service) syncActiveProfile(store *profileStore) error {
+	active := store.find(store.Active)
+	if active == nil {
+		return nil
+	}
+	config, template, err := s.snapshotLiveConfig()
+	if err != nil {
+		return err
+	}
+	active.Config = config
+	active.Template = template
+	active.UpdatedAt = time.Now()
+	return nil
+}
+
+func deepCopyJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func summarizeProfiles(store *profileStore) []ProfileSummary {
+	result := make([]ProfileSummary, 0, len(store.Profiles))
+	for _, p := range store.Profiles {
+		result = append(result, ProfileSummary{
+			ID:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Active:      p.ID == store.Active,
+		})
+	}
+	return result
+}
+
+// ListProfiles 获取配置方案列表
+func (s *Service) ListProfiles() ([]ProfileSummary, error) {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	store, err := s.loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeProfiles(store), nil
+}
+
+// CreateProfile 创建配置方案
+// from 为空时以当前生效的配置创建，否则复制指定方案
+func (s *Service) CreateProfile(name, description, from string) (*ProfileSummary, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("方案名称不能为空")
+	}
+
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	store, err := s.loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncActiveProfile(store); err != nil {
+		return nil, err
+	}
+
+	source := store.find(store.Active)
+	if from != "" {
+		if source = store.find(from); source == nil {
+			return nil, fmt.Errorf("方案不存在: %s", from)
+		}
+	}
+
+	var config ProxyConfig
+	var template ConfigTemplate
+	if err := deepCopyJSON(source.Config, &config); err != nil {
+		return nil, err
+	}
+	if err := deepCopyJSON(source.Template, &template); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	profile := &ConfigProfile{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Config:      &config,
+		Template:    &template,
+	}
+	store.Profiles = append(store.Profiles, profile)
+	if err := s.saveProfiles(store); err != nil {
+		return nil, err
+	}
+
+	return &ProfileSummary{
+		ID:          profile.ID,
+		Name:        profile.Name,
+		Description: profile.Description,
+		CreatedAt:   profile.CreatedAt,
+		UpdatedAt:   profile.UpdatedAt,
+	}, nil
+}
+
+// UpdateProfile 修改方案名称和描述
+func (s *Service) UpdateProfile(id, name, description string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("方案名称不能为空")
+	}
+
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	store, err := s.loadProfiles()
+	if err != nil {
+		return err
+	}
+	profile := store.find(id)
+	if profile == nil {
+		return fmt.Errorf("方案不存在: %s", id)
+	}
+	profile.Name = name
+	profile.Description = strings.TrimSpace(description)
+	profile.UpdatedAt = time.Now()
+	return s.saveProfiles(store)
+}
+
+// DeleteProfile 删除方案（不能删除当前激活的方案）
+func (s *Service) DeleteProfile(id string) error {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	store, err := s.loadProfiles()
+	if err != nil {
+		return err
+	}
+	if id == store.Active {
+		return fmt.Errorf("不能删除当前使用的方案")
+	}
+	for i, p := range store.Profiles {
+		if p.ID == id {
+			store.Profiles = append(store.Profiles[:i], store.Profiles[i+1:]...)
+			return s.saveProfiles(store)
+		}
+	}
+	return fmt.Errorf("方案不存在: %s", id)
+}
+
+// ActivateProfile 切换到指定方案
+// 先把当前配置保存回原方案，再加载目标方案的代理设置和配置模板，下次启动/重启时生效
+func (s *Service) ActivateProfile(id string) error {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	store, err := s.loadProfiles()
+	if err != nil {
+		return err
+	}
+	target := store.find(id)
+	if target == nil {
+		return fmt.Errorf("方案不存在: %s", id)
+	}
+	if err := s.syncActiveProfile(store); err != nil {
+		return err
+	}
+
+	var config ProxyConfig
+	var template ConfigTemplate
+	if err := deepCopyJSON(target.Config, &config); err != nil {
+		return err
+	}
+	if err := deepCopyJSON(target.Template, &template); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.config = &config
+	s.configTemplate = &template
+	s.keepConfigOnStart = false
+	err = s.saveConfig()
+	if err == nil {
+		err = s.saveConfigTemplate()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	store.Active = id
+	return s.saveProfiles(store)
+}
+
+// ListProfiles 获取配置方案列表
+func (h *Handler) ListProfiles(c *gin.Context) {
+	profiles, err := h.service.ListProfiles()
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    profiles,
+	})
+}
+
+// CreateProfile 创建配置方案（from 为要复制的方案 ID，为空时使用当前配置）
+func (h *Handler) CreateProfile(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		From        string `json:"from"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	h.createProfile(c, req.Name, req.Description, req.From)
+}
+
+// CloneProfile 复制配置方案
+func (h *Handler) CloneProfile(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	h.createProfile(c, req.Name, req.Description, c.Param("id"))
+}
+
+func (h *Handler) createProfile(c *gin.Context, name, description, from string) {
+	profile, err := h.service.CreateProfile(name, description, from)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    profile,
+	})
+}
+
+// UpdateProfile 修改方案名称和描述
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.service.UpdateProfile(c.Param("id"), req.Name, req.Description); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// DeleteProfile 删除方案
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	if err := h.service.DeleteProfile(c.Param("id")); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ActivateProfile 切换方案，核心运行中时自动重启以应用新方案
+func (h *Handler) ActivateProfile(c *gin.Context) {
+	if err := h.service.ActivateProfile(c.Param("id")); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// 方案可能改变端口和透明代理模式，需要完整重启（回调会重新应用 nftables 规则）
+	restarted := false
+	if h.service.GetStatus().Running {
+		if err := h.service.Restart(); err != nil {
+			apierror.Message(c, http.StatusInternalServerError, "方案已切换，但重启核心失败: "+err.Error())
+			return
+		}
+		restarted = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"restarted": restarted,
+		},
+	})
+}
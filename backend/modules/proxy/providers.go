@@ -0,0 +1,404 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 集合类型
+const (
+	providerKindProxy = "proxy"
+	providerKindRule  = "rule"
+)
+
+const (
+	providerCheckInterval = time.Minute      // 自动刷新检查间隔
+	providerUpdateTimeout = 60 * time.Second // 单个集合刷新超时（需要下载）
+)
+
+// ProviderRefreshSchedule 集合自动刷新设置
+type ProviderRefreshSchedule struct {
+	Enabled    bool `json:"enabled"`
+	Interval   int  `json:"interval"`   // 自动刷新间隔（分钟）
+	StaleAfter int  `json:"staleAfter"` // 超过多少小时未更新视为过期
+	OnlyStale  bool `json:"onlyStale"`  // 只刷新已过期的集合
+}
+
+// ProviderRecord 集合刷新记录（ProxyStation 本地记录）
+type ProviderRecord struct {
+	LastRefresh int64  `json:"lastRefresh,omitempty"` // 最近一次成功刷新
+	LastAttempt int64  `json:"lastAttempt,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+// ProviderInfo 集合状态
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`        // proxy / rule
+	VehicleType string `json:"vehicleType"` // HTTP / File / Inline
+	Behavior    string `json:"behavior,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Count       int    `json:"count"`           // 节点数或规则数
+	Alive       int    `json:"alive,omitempty"` // 最近一次测试可用的节点数
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+	Stale       bool   `json:"stale"`
+	ProviderRecord
+	SubscriptionInfo *clashapi.SubscriptionInfo `json:"subscriptionInfo,omitempty"`
+}
+
+// providerState 集合刷新设置和记录，持久化到 provider_refresh.json
+type providerState struct {
+	Schedule ProviderRefreshSchedule   `json:"schedule"`
+	Records  map[string]ProviderRecord `json:"records"`
+	LastAuto int64                     `json:"lastAuto,omitempty"`
+}
+
+// providerTracker 集合刷新记录和自动刷新
+type providerTracker struct {
+	mu       sync.Mutex
+	filePath string
+	state    providerState
+}
+
+func newProviderTracker(dataDir string) *providerTracker {
+	t := &providerTracker{
+		filePath: filepath.Join(dataDir, "provider_refresh.json"),
+		state: providerState{
+			Schedule: ProviderRefreshSchedule{Interval: 720, StaleAfter: 48, OnlyStale: true},
+			Records:  make(map[string]ProviderRecord),
+		},
+	}
+	if data, err := os.ReadFile(t.filePath); err == nil {
+		if err := json.Unmarshal(data, &t.state); err != nil {
+			fmt.Printf("⚠️ 解析集合刷新记录失败: %v\n", err)
+		}
+		if t.state.Records == nil {
+			t.state.Records = make(map[string]ProviderRecord)
+		}
+	}
+	return t
+}
+
+// save 保存状态（调用时需持有 t.mu 锁）
+func (t *providerTracker) save() {
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(t.filePath, data, 0644); err != nil {
+		fmt.Printf("⚠️ 保存集合刷新记录失败: %v\n", err)
+	}
+}
+
+func (t *providerTracker) schedule() ProviderRefreshSchedule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.Schedule
+}
+
+func (t *providerTracker) setSchedule(schedule ProviderRefreshSchedule) error {
+	if schedule.Interval < 10 {
+		return fmt.Errorf("刷新间隔不能小于 10 分钟")
+	}
+	if schedule.StaleAfter <= 0 {
+		return fmt.Errorf("过期时间必须大于 0")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Schedule = schedule
+	t.save()
+	return nil
+}
+
+func (t *providerTracker) record(kind, name string) ProviderRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.Records[kind+":"+name]
+}
+
+func (t *providerTracker) recordResult(kind, name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := kind + ":" + name
+	rec := t.state.Records[key]
+	rec.LastAttempt = time.Now().Unix()
+	rec.LastError = ""
+	if err != nil {
+		rec.LastError = err.Error()
+	} else {
+		rec.LastRefresh = rec.LastAttempt
+	}
+	t.state.Records[key] = rec
+	t.save()
+}
+
+// dueForAuto 是否到了自动刷新时间，是则记录本次时间
+func (t *providerTracker) dueForAuto(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.state.Schedule
+	if !s.Enabled || now.Unix()-t.state.LastAuto < int64(s.Interval)*60 {
+		return false
+	}
+	t.state.LastAuto = now.Unix()
+	t.save()
+	return true
+}
+
+// parseProviderTime 解析核心返回的更新时间（RFC3339，零值表示从未更新）
+func parseProviderTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+// listProviders 获取核心中的集合并合并本地刷新记录
+// Compatible 是 Mihomo 为未使用集合的代理组生成的内部集合，不列出
+func (h *Handler) listProviders(ctx context.Context) ([]ProviderInfo, error) {
+	client := h.clashAPI()
+	proxyProviders, err := client.ProxyProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleProviders, err := client.RuleProviders(ctx)
+	if err != nil {
+		// sing-box 的 Clash API 没有规则集合接口
+		var apiErr *clashapi.APIError
+		if !errors.As(err, &apiErr) {
+			return nil, err
+		}
+	}
+
+	staleAfter := time.Duration(h.providers.schedule().StaleAfter) * time.Hour
+	isStale := func(info *ProviderInfo) bool {
+		// 本地文件和内联集合不需要刷新
+		if info.VehicleType != "HTTP" {
+			return false
+		}
+		updated := parseProviderTime(info.UpdatedAt)
+		return updated.IsZero() || time.Since(updated) > staleAfter
+	}
+
+	result := []ProviderInfo{}
+	for name, p := range proxyProviders {
+		if p.VehicleType == "Compatible" {
+			continue
+		}
+		info := ProviderInfo{
+			Name:             name,
+			Kind:             providerKindProxy,
+			VehicleType:      p.VehicleType,
+			Count:            len(p.Proxies),
+			UpdatedAt:        p.UpdatedAt,
+			ProviderRecord:   h.providers.record(providerKindProxy, name),
+			SubscriptionInfo: p.SubscriptionInfo,
+		}
+		for _, proxy := range p.Proxies {
+			if n := len(proxy.History); n > 0 && proxy.History[n-1].Delay > 0 {
+				info.Alive++
+			}
+		}
+		info.Stale = isStale(&info)
+		result = append(result, info)
+	}
+	for name, p := range ruleProviders {
+		info := ProviderInfo{
+			Name:           name,
+			Kind:           providerKindRule,
+			VehicleType:    p.VehicleType,
+			Behavior:       p.Behavior,
+			Format:         p.Format,
+			Count:          p.RuleCount,
+			UpdatedAt:      p.UpdatedAt,
+			ProviderRecord: h.providers.record(providerKindRule, name),
+		}
+		info.Stale = isStale(&info)
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// refreshProvider 让核心重新拉取集合并记录结果
+func (h *Handler) refreshProvider(kind, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), providerUpdateTimeout)
+	defer cancel()
+
+	var err error
+	switch kind {
+	case providerKindProxy:
+		err = h.clashAPI().UpdateProxyProvider(ctx, name)
+	case providerKindRule:
+		err = h.clashAPI().UpdateRuleProvider(ctx, name)
+	default:
+		return fmt.Errorf("未知的集合类型: %s", kind)
+	}
+	h.providers.recordResult(kind, name, err)
+	return err
+}
+
+// refreshProviders 刷新所有远程集合，onlyStale 为 true 时只刷新已过期的
+func (h *Handler) refreshProviders(onlyStale bool) (refreshed []string, failed map[string]string, err error) {
+	providers, err := h.listProviders(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshed = []string{}
+	failed = make(map[string]string)
+	for _, p := range providers {
+		if p.VehicleType != "HTTP" || (onlyStale && !p.Stale) {
+			continue
+		}
+		key := p.Kind + ":" + p.Name
+		if err := h.refreshProvider(p.Kind, p.Name); err != nil {
+			failed[key] = err.Error()
+			continue
+		}
+		refreshed = append(refreshed, key)
+	}
+	return refreshed, failed, nil
+}
+
+// startProviderRefresh 按设置定时刷新集合
+func (h *Handler) startProviderRefresh() {
+	go func() {
+		ticker := time.NewTicker(providerCheckInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if !h.service.GetStatus().Running || !h.providers.dueForAuto(now) {
+				continue
+			}
+			refreshed, failed, err := h.refreshProviders(h.providers.schedule().OnlyStale)
+			if err != nil {
+				fmt.Printf("⚠️ 自动刷新集合失败: %v\n", err)
+				continue
+			}
+			if len(refreshed) > 0 || len(failed) > 0 {
+				fmt.Printf("🔄 已自动刷新 %d 个集合，失败 %d 个\n", len(refreshed), len(failed))
+			}
+		}
+	}()
+}
+
+// providerAPIStatus 核心返回错误时为 502，无法连接核心时为 503
+func providerAPIStatus(err error) int {
+	var apiErr *clashapi.APIError
+	if errors.As(err, &apiErr) {
+		return http.StatusBadGateway
+	}
+	return http.StatusServiceUnavailable
+}
+
+// GetProviders 列出代理集合和规则集合
+func (h *Handler) GetProviders(c *gin.Context) {
+	providers, err := h.listProviders(c.Request.Context())
+	if err != nil {
+		apierror.Message(c, providerAPIStatus(err), "获取集合失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    providers,
+	})
+}
+
+// RefreshProvider 刷新单个集合
+func (h *Handler) RefreshProvider(c *gin.Context) {
+	kind := c.Param("kind")
+	if kind != providerKindProxy && kind != providerKindRule {
+		apierror.Message(c, http.StatusBadRequest, "未知的集合类型: "+kind)
+		return
+	}
+	if err := h.refreshProvider(kind, c.Param("name")); err != nil {
+		apierror.Message(c, providerAPIStatus(err), "刷新集合失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.providers.record(kind, c.Param("name")),
+	})
+}
+
+// RefreshProviders 刷新所有远程集合，stale=true 时只刷新已过期的
+func (h *Handler) RefreshProviders(c *gin.Context) {
+	refreshed, failed, err := h.refreshProviders(c.Query("stale") == "true")
+	if err != nil {
+		apierror.Message(c, providerAPIStatus(err), "刷新集合失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"refreshed": refreshed,
+			"failed":    failed,
+		},
+	})
+}
+
+// HealthCheckProvider 对代理集合执行健康检查
+func (h *Handler) HealthCheckProvider(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerUpdateTimeout)
+	defer cancel()
+	if err := h.clashAPI().HealthCheckProxyProvider(ctx, c.Param("name")); err != nil {
+		apierror.Message(c, providerAPIStatus(err), "健康检查失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// GetProviderSchedule 获取自动刷新设置
+func (h *Handler) GetProviderSchedule(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.providers.schedule(),
+	})
+}
+
+// SetProviderSchedule 更新自动刷新设置
+func (h *Handler) SetProviderSchedule(c *gin.Context) {
+	var req ProviderRefreshSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.providers.setSchedule(req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    req,
+	})
+}
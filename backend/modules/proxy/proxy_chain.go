@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 链式代理生成方式
+const (
+	ChainModeDialer = "dialer" // Mihomo dialer-proxy / Sing-Box detour（推荐）
+	ChainModeRelay  = "relay"  // Mihomo relay 代理组（Sing-Box 同样生成 detour）
+)
+
+// ProxyChain 链式代理：流量依次经过 Hops 中的节点，第一个为入口，最后一个为出口
+type ProxyChain struct {
+	Name    string   `json:"name"`             // 生成到配置中的出站名称
+	Mode    string   `json:"mode"`             // dialer / relay
+	Hops    []string `json:"hops"`             // 入口 → 出口，入口可以是节点或代理组，其余必须是节点
+	Groups  []string `json:"groups,omitempty"` // 加入到这些代理组中供选择
+	Enabled bool     `json:"enabled"`
+}
+
+// normalizeProxyChains 校验并规范化链式代理列表
+func normalizeProxyChains(chains []ProxyChain) ([]ProxyChain, error) {
+	result := make([]ProxyChain, 0, len(chains))
+	names := make(map[string]bool, len(chains))
+	for i, c := range chains {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, fmt.Errorf("第 %d 条链式代理缺少名称", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("链式代理名称重复: %s", c.Name)
+		}
+		names[c.Name] = true
+		if builtinRuleTargets[c.Name] {
+			return nil, fmt.Errorf("链式代理名称不能使用内置策略: %s", c.Name)
+		}
+
+		if c.Mode == "" {
+			c.Mode = ChainModeDialer
+		}
+		if c.Mode != ChainModeDialer && c.Mode != ChainModeRelay {
+			return nil, fmt.Errorf("链式代理 %s: 不支持的方式 %s", c.Name, c.Mode)
+		}
+
+		hops := make([]string, 0, len(c.Hops))
+		for _, hop := range c.Hops {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+		if len(hops) < 2 {
+			return nil, fmt.Errorf("链式代理 %s: 至少需要入口和出口两个节点", c.Name)
+		}
+		for _, hop := range hops {
+			if hop == c.Name {
+				return nil, fmt.Errorf("链式代理 %s: 不能包含自身", c.Name)
+			}
+			if builtinRuleTargets[hop] {
+				return nil, fmt.Errorf("链式代理 %s: 不能使用内置策略 %s", c.Name, hop)
+			}
+		}
+		c.Hops = hops
+
+		groups := make([]string, 0, len(c.Groups))
+		for _, g := range c.Groups {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+		c.Groups = groups
+
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// activeProxyChains 启用的链式代理
+func activeProxyChains(chains []ProxyChain) []ProxyChain {
+	var result []ProxyChain
+	for _, c := range chains {
+		if c.Enabled {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// chainHopName dialer 方式下中间节点副本的名称，出口副本直接使用链名称
+func chainHopName(c ProxyChain, i int) string {
+	if i == len(c.Hops)-1 {
+		return c.Name
+	}
+	return fmt.Sprintf("%s #%d", c.Name, i+1)
+}
+
+// appendChainToGroups 将链式代理加入指定的代理组
+func appendChainToGroups(groups []ProxyGroup, c ProxyChain) {
+	for _, name := range c.Groups {
+		for i := range groups {
+			if groups[i].Name == name {
+				groups[i].Proxies = append(groups[i].Proxies, c.Name)
+			}
+		}
+	}
+}
+
+// applyMihomoChains 生成 Mihomo 链式代理
+// dialer: 复制第 2 个及之后的节点，每个副本的 dialer-proxy 指向前一跳；relay: 生成 relay 代理组
+// 引用不存在的节点或代理组的链会被跳过
+func applyMihomoChains(config *MihomoConfig, chains []ProxyChain) {
+	proxies := make(map[string]map[string]interface{}, len(config.Proxies))
+	for _, p := range config.Proxies {
+		if name, ok := p["name"].(string); ok {
+			proxies[name] = p
+		}
+	}
+	groups := make(map[string]bool, len(config.ProxyGroups))
+	for _, g := range config.ProxyGroups {
+		groups[g.Name] = true
+	}
+
+	for _, c := range activeProxyChains(chains) {
+		if proxies[c.Name] != nil || groups[c.Name] {
+			fmt.Printf("⚠️ 链式代理 %s 与已有节点或代理组重名，已跳过\n", c.Name)
+			continue
+		}
+		if err := checkChainHops(c, func(name string) bool { return proxies[name] != nil }, groups); err != nil {
+			fmt.Printf("⚠️ 链式代理 %s: %v，已跳过\n", c.Name, err)
+			continue
+		}
+
+		if c.Mode == ChainModeRelay {
+			config.ProxyGroups = append(config.ProxyGroups, ProxyGroup{
+				Name:    c.Name,
+				Type:    "relay",
+				Proxies: append([]string{}, c.Hops...),
+			})
+		} else {
+			prev := c.Hops[0]
+			for i := 1; i < len(c.Hops); i++ {
+				hop := make(map[string]interface{}, len(proxies[c.Hops[i]])+1)
+				for k, v := range proxies[c.Hops[i]] {
+					hop[k] = v
+				}
+				hop["name"] = chainHopName(c, i)
+				hop["dialer-proxy"] = prev
+				config.Proxies = append(config.Proxies, hop)
+				prev = chainHopName(c, i)
+			}
+		}
+		appendChainToGroups(config.ProxyGroups, c)
+	}
+}
+
+// applySingBoxChains 生成 Sing-Box 链式代理：复制第 2 个及之后的节点，每个副本的 detour 指向前一跳
+// Sing-Box 没有 relay 代理组，两种方式生成的结果相同
+func applySingBoxChains(nodes []SBOutbound, groups []SBOutbound, chains []ProxyChain) ([]SBOutbound, []SBOutbound) {
+	nodeByTag := make(map[string]SBOutbound, len(nodes))
+	for _, n := range nodes {
+		nodeByTag[n.Tag] = n
+	}
+	groupTags := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		groupTags[g.Tag] = true
+	}
+
+	for _, c := range activeProxyChains(chains) {
+		if _, exists := nodeByTag[c.Name]; exists || groupTags[c.Name] {
+			fmt.Printf("⚠️ 链式代理 %s 与已有节点或代理组重名，已跳过\n", c.Name)
+			continue
+		}
+		if err := checkChainHops(c, func(name string) bool { _, ok := nodeByTag[name]; return ok }, groupTags); err != nil {
+			fmt.Printf("⚠️ 链式代理 %s: %v，已跳过\n", c.Name, err)
+			continue
+		}
+
+		prev := c.Hops[0]
+		for i := 1; i < len(c.Hops); i++ {
+			hop := nodeByTag[c.Hops[i]]
+			hop.Tag = chainHopName(c, i)
+			hop.Detour = prev
+			nodes = append(nodes, hop)
+			prev = hop.Tag
+		}
+		for _, name := range c.Groups {
+			for i := range groups {
+				if groups[i].Tag == name {
+					groups[i].Outbounds = append(groups[i].Outbounds, c.Name)
+				}
+			}
+		}
+	}
+	return nodes, groups
+}
+
+// checkChainHops 检查链的节点是否存在：入口可以是节点或代理组，其余必须是节点
+func checkChainHops(c ProxyChain, isNode func(string) bool, groups map[string]bool) error {
+	if !isNode(c.Hops[0]) && !groups[c.Hops[0]] {
+		return fmt.Errorf("入口 %s 不存在", c.Hops[0])
+	}
+	for _, hop := range c.Hops[1:] {
+		if !isNode(hop) {
+			return fmt.Errorf("节点 %s 不存在", hop)
+		}
+	}
+	return nil
+}
+
+// GetProxyChains 获取链式代理列表
+func (s *Service) GetProxyChains() []ProxyChain {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]ProxyChain, 0, len(s.config.ProxyChains))
+	for _, c := range s.config.ProxyChains {
+		c.Hops = append([]string{}, c.Hops...)
+		c.Groups = append([]string{}, c.Groups...)
+		result = append(result, c)
+	}
+	return result
+}
+
+// SetProxyChains 设置链式代理列表，下次生成配置时生效
+func (s *Service) SetProxyChains(chains []ProxyChain) ([]ProxyChain, error) {
+	normalized, err := normalizeProxyChains(chains)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.ProxyChains = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetProxyChains 获取链式代理列表
+func (h *Handler) GetProxyChains(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetProxyChains(),
+	})
+}
+
+// SetProxyChains 更新链式代理列表，需重新生成配置后生效
+func (h *Handler) SetProxyChains(c *gin.Context) {
+	var req []ProxyChain
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	chains, err := h.service.SetProxyChains(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    chains,
+	})
+}
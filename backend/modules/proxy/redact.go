@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "******"
+
+// redactKeys 需要脱敏的节点凭据字段
+var redactKeys = []string{
+	"password", "uuid", "private-key", "pre-shared-key", "auth-str", "auth", "obfs-password", "psk", "token", "secret",
+	"private_key", "pre_shared_key", "auth_str",
+}
+
+var (
+	keyPattern = `(?:` + strings.Join(quoteKeys(redactKeys), "|") + `)`
+	// yamlBlockSecretRe 块格式 key: value（含 diff 行前缀），遮蔽到行尾
+	yamlBlockSecretRe = regexp.MustCompile(`(?m)^([+\- ]?[ \t]*(?:-[ \t]+)?` + keyPattern + `[ \t]*:[ \t]+)(\S.*)$`)
+	// yamlFlowSecretRe 流格式 {key: value, ...}
+	yamlFlowSecretRe = regexp.MustCompile(`([{,][ \t]*` + keyPattern + `[ \t]*:[ \t]*)("[^"\n]*"|'[^'\n]*'|[^,}\n]+)`)
+	// jsonSecretRe "key": "value"
+	jsonSecretRe = regexp.MustCompile(`("` + keyPattern + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+func quoteKeys(keys []string) []string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return quoted
+}
+
+// redactYAML 遮蔽 Mihomo 配置中的密码、UUID 等凭据，保留原有格式
+func redactYAML(content string) string {
+	content = yamlBlockSecretRe.ReplaceAllString(content, `${1}"`+redactedValue+`"`)
+	return yamlFlowSecretRe.ReplaceAllString(content, `${1}"`+redactedValue+`"`)
+}
+
+// redactJSON 遮蔽 Sing-Box 配置中的密码、UUID 等凭据，保留原有格式
+func redactJSON(content string) string {
+	return jsonSecretRe.ReplaceAllString(content, `${1}"`+redactedValue+`"`)
+}
+
+// checkReveal 解析 ?reveal=true，查看完整凭据需要完全控制权限（未启用认证时不区分权限）
+// 权限不足时写入 403 响应并返回 ok=false
+func checkReveal(c *gin.Context) (reveal bool, ok bool) {
+	if c.Query("reveal") != "true" {
+		return false, true
+	}
+	if scope, exists := c.Get("authScope"); exists && scope != "admin" {
+		apierror.Message(c, http.StatusForbidden, "查看完整凭据需要管理员权限")
+		return false, false
+	}
+	return true, true
+}
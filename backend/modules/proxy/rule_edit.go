@@ -0,0 +1,416 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mihomoRuleTypes Mihomo 支持的规则类型
+var mihomoRuleTypes = map[string]bool{
+	"DOMAIN": true, "DOMAIN-SUFFIX": true, "DOMAIN-KEYWORD": true, "DOMAIN-REGEX": true, "DOMAIN-WILDCARD": true,
+	"GEOSITE": true, "GEOIP": true, "SRC-GEOIP": true, "IP-ASN": true, "SRC-IP-ASN": true,
+	"IP-CIDR": true, "IP-CIDR6": true, "IP-SUFFIX": true, "SRC-IP-CIDR": true, "SRC-IP-SUFFIX": true,
+	"DST-PORT": true, "SRC-PORT": true, "IN-PORT": true, "IN-TYPE": true, "IN-USER": true, "IN-NAME": true,
+	"PROCESS-NAME": true, "PROCESS-PATH": true, "PROCESS-NAME-REGEX": true, "PROCESS-PATH-REGEX": true,
+	"UID": true, "NETWORK": true, "DSCP": true, "RULE-SET": true,
+	"AND": true, "OR": true, "NOT": true, "SUB-RULE": true, "MATCH": true,
+}
+
+// builtinRuleTargets Mihomo 内置出站，规则可直接引用
+var builtinRuleTargets = map[string]bool{
+	"DIRECT": true, "REJECT": true, "REJECT-DROP": true, "PASS": true, "COMPATIBLE": true,
+}
+
+// ensureRuleIDs 为缺少 ID 的规则分配 ID，返回是否有修改
+func ensureRuleIDs(rules []RuleTemplate) bool {
+	changed := false
+	for i := range rules {
+		if rules[i].ID == "" {
+			rules[i].ID = uuid.New().String()[:8]
+			changed = true
+		}
+	}
+	return changed
+}
+
+// validateRule 校验规则语法、规则集和目标代理组（调用方需持有锁）
+func (s *Service) validateRule(rule *RuleTemplate) error {
+	rule.Type = strings.ToUpper(strings.TrimSpace(rule.Type))
+	rule.Payload = strings.TrimSpace(rule.Payload)
+	rule.Proxy = strings.TrimSpace(rule.Proxy)
+
+	if !mihomoRuleTypes[rule.Type] {
+		return fmt.Errorf("不支持的规则类型: %s", rule.Type)
+	}
+	if rule.Proxy == "" {
+		return fmt.Errorf("目标代理组不能为空")
+	}
+	if !builtinRuleTargets[rule.Proxy] && !s.isActiveGroup(rule.Proxy) {
+		return fmt.Errorf("目标代理组 %q 不存在或未启用", rule.Proxy)
+	}
+
+	if rule.Type == "MATCH" {
+		if rule.Payload != "" {
+			return fmt.Errorf("MATCH 规则不需要规则内容")
+		}
+		return nil
+	}
+	if rule.Payload == "" {
+		return fmt.Errorf("%s 规则内容不能为空", rule.Type)
+	}
+
+	switch rule.Type {
+	case "AND", "OR", "NOT":
+		// 逻辑规则内容形如 ((DOMAIN,a.com),(NETWORK,UDP))，只检查括号
+		if !strings.HasPrefix(rule.Payload, "(") || strings.Count(rule.Payload, "(") != strings.Count(rule.Payload, ")") {
+			return fmt.Errorf("逻辑规则格式无效: %s", rule.Payload)
+		}
+		return nil
+	}
+	if strings.Contains(rule.Payload, ",") {
+		return fmt.Errorf("规则内容不能包含逗号: %s", rule.Payload)
+	}
+
+	switch rule.Type {
+	case "IP-CIDR", "IP-CIDR6", "SRC-IP-CIDR":
+		if _, _, err := net.ParseCIDR(rule.Payload); err != nil {
+			return fmt.Errorf("CIDR 格式无效: %s", rule.Payload)
+		}
+	case "DOMAIN-REGEX", "PROCESS-NAME-REGEX", "PROCESS-PATH-REGEX":
+		if _, err := regexp.Compile(rule.Payload); err != nil {
+			return fmt.Errorf("正则表达式无效: %v", err)
+		}
+	case "DST-PORT", "SRC-PORT", "IN-PORT":
+		if !validPortRanges(rule.Payload) {
+			return fmt.Errorf("端口格式无效: %s", rule.Payload)
+		}
+	case "NETWORK":
+		if p := strings.ToLower(rule.Payload); p != "tcp" && p != "udp" {
+			return fmt.Errorf("NETWORK 只支持 tcp 或 udp")
+		}
+	case "RULE-SET":
+		providers := mergeTemplateRuleProviders(s.configGenerator.generateRuleProviders(), s.configTemplate.RuleProviders)
+		if _, ok := providers[rule.Payload]; !ok {
+			return fmt.Errorf("规则集 %q 不存在", rule.Payload)
+		}
+	}
+	return nil
+}
+
+// validPortRanges 校验 80/443 和 1000-2000 格式的端口
+func validPortRanges(payload string) bool {
+	for _, part := range strings.Split(payload, "/") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 0 || start > 65535 {
+			return false
+		}
+		if isRange {
+			end, err := strconv.Atoi(hi)
+			if err != nil || end < start || end > 65535 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isActiveGroup 代理组是否存在且会生成到配置中（与 generateProxyGroupsFromTemplate 的跳过条件一致）
+func (s *Service) isActiveGroup(name string) bool {
+	for _, g := range s.configTemplate.ProxyGroups {
+		if g.Name == name {
+			return g.Enabled || g.Description == ""
+		}
+	}
+	return false
+}
+
+// findRuleIndex 按 ID 或序号查找规则（调用方需持有锁）
+func (s *Service) findRuleIndex(ref string) (int, error) {
+	rules := s.configTemplate.Rules
+	for i, r := range rules {
+		if r.ID == ref {
+			return i, nil
+		}
+	}
+	if idx, err := strconv.Atoi(ref); err == nil && idx >= 0 && idx < len(rules) {
+		return idx, nil
+	}
+	return -1, fmt.Errorf("规则 %s 不存在", ref)
+}
+
+// matchRuleIndex MATCH 规则的位置，不存在时返回 -1
+func matchRuleIndex(rules []RuleTemplate) int {
+	for i, r := range rules {
+		if r.Type == "MATCH" {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkMatchLast 确保 MATCH 规则（如有）位于最后
+func checkMatchLast(rules []RuleTemplate) error {
+	if idx := matchRuleIndex(rules); idx >= 0 && idx != len(rules)-1 {
+		return fmt.Errorf("MATCH 规则必须位于最后")
+	}
+	return nil
+}
+
+// InsertRule 在指定位置插入规则，index 为 nil 时插入到 MATCH 规则之前
+func (s *Service) InsertRule(rule RuleTemplate, index *int) (*RuleTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID = uuid.New().String()[:8]
+
+	rules := s.configTemplate.Rules
+	pos := len(rules)
+	if index != nil {
+		if *index < 0 || *index > len(rules) {
+			return nil, fmt.Errorf("插入位置超出范围: %d", *index)
+		}
+		pos = *index
+	} else if idx := matchRuleIndex(rules); idx >= 0 {
+		pos = idx
+	}
+
+	newRules := make([]RuleTemplate, 0, len(rules)+1)
+	newRules = append(newRules, rules[:pos]...)
+	newRules = append(newRules, rule)
+	newRules = append(newRules, rules[pos:]...)
+	if err := checkMatchLast(newRules); err != nil {
+		return nil, err
+	}
+
+	s.configTemplate.Rules = newRules
+	if err := s.saveConfigTemplate(); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule 修改单条规则（保留 ID 和位置）
+func (s *Service) UpdateRule(ref string, rule RuleTemplate) (*RuleTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.findRuleIndex(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID = s.configTemplate.Rules[idx].ID
+
+	newRules := append([]RuleTemplate{}, s.configTemplate.Rules...)
+	newRules[idx] = rule
+	if err := checkMatchLast(newRules); err != nil {
+		return nil, err
+	}
+
+	s.configTemplate.Rules = newRules
+	if err := s.saveConfigTemplate(); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// MoveRule 将规则移动到指定位置
+func (s *Service) MoveRule(ref string, to int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, err := s.findRuleIndex(ref)
+	if err != nil {
+		return err
+	}
+	rules := s.configTemplate.Rules
+	if to < 0 || to >= len(rules) {
+		return fmt.Errorf("目标位置超出范围: %d", to)
+	}
+	if from == to {
+		return nil
+	}
+
+	rule := rules[from]
+	newRules := make([]RuleTemplate, 0, len(rules))
+	newRules = append(newRules, rules[:from]...)
+	newRules = append(newRules, rules[from+1:]...)
+	newRules = append(newRules[:to], append([]RuleTemplate{rule}, newRules[to:]...)...)
+	if err := checkMatchLast(newRules); err != nil {
+		return err
+	}
+
+	s.configTemplate.Rules = newRules
+	return s.saveConfigTemplate()
+}
+
+// DeleteRule 按 ID 或序号删除规则
+func (s *Service) DeleteRule(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.findRuleIndex(ref)
+	if err != nil {
+		return err
+	}
+	rules := s.configTemplate.Rules
+	newRules := make([]RuleTemplate, 0, len(rules)-1)
+	newRules = append(newRules, rules[:idx]...)
+	newRules = append(newRules, rules[idx+1:]...)
+
+	s.configTemplate.Rules = newRules
+	return s.saveConfigTemplate()
+}
+
+// ToggleRules 批量启用或禁用规则，返回修改的数量
+func (s *Service) ToggleRules(refs []string, enabled bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	indexes := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		idx, err := s.findRuleIndex(ref)
+		if err != nil {
+			return 0, err
+		}
+		indexes = append(indexes, idx)
+	}
+
+	newRules := append([]RuleTemplate{}, s.configTemplate.Rules...)
+	changed := 0
+	for _, idx := range indexes {
+		if newRules[idx].Type == "MATCH" && !enabled {
+			return 0, fmt.Errorf("不能禁用 MATCH 兜底规则")
+		}
+		if newRules[idx].Disabled == !enabled {
+			continue
+		}
+		newRules[idx].Disabled = !enabled
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	s.configTemplate.Rules = newRules
+	return changed, s.saveConfigTemplate()
+}
+
+// InsertRule 插入规则
+func (h *Handler) InsertRule(c *gin.Context) {
+	var req struct {
+		Rule  RuleTemplate `json:"rule"`
+		Index *int         `json:"index"` // 不指定时插入到 MATCH 之前
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	rule, err := h.service.InsertRule(req.Rule, req.Index)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rule,
+	})
+}
+
+// UpdateRule 修改单条规则（:id 可以是规则 ID 或序号）
+func (h *Handler) UpdateRule(c *gin.Context) {
+	var rule RuleTemplate
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := h.service.UpdateRule(c.Param("id"), rule)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    updated,
+	})
+}
+
+// MoveRule 移动规则到指定位置
+func (h *Handler) MoveRule(c *gin.Context) {
+	var req struct {
+		Index *int `json:"index" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.MoveRule(c.Param("id"), *req.Index); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetConfigTemplate().Rules,
+	})
+}
+
+// DeleteRule 删除规则（:id 可以是规则 ID 或序号）
+func (h *Handler) DeleteRule(c *gin.Context) {
+	if err := h.service.DeleteRule(c.Param("id")); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ToggleRules 批量启用/禁用规则
+func (h *Handler) ToggleRules(c *gin.Context) {
+	var req struct {
+		IDs     []string `json:"ids" binding:"required"`
+		Enabled bool     `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	changed, err := h.service.ToggleRules(req.IDs, req.Enabled)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"changed": changed},
+	})
+}
@@ -0,0 +1,327 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ruleStatsBucketSize = time.Hour          // 聚合粒度
+	ruleStatsRetention  = 7 * 24 * time.Hour // 历史保留时长
+	ruleStatsUnmatched  = 50                 // 接口返回的模板外规则数量
+)
+
+// RuleHitCounter 规则命中计数（按采样到的新连接计）
+type RuleHitCounter struct {
+	Hits     int64 `json:"hits"`
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+	LastHit  int64 `json:"lastHit,omitempty"` // Unix 秒
+}
+
+func (c *RuleHitCounter) merge(other *RuleHitCounter) {
+	c.Hits += other.Hits
+	c.Upload += other.Upload
+	c.Download += other.Download
+	if other.LastHit > c.LastHit {
+		c.LastHit = other.LastHit
+	}
+}
+
+// RuleStatsBucket 一个时间桶内的规则命中统计，键为 ruleStatsKey
+type RuleStatsBucket struct {
+	Time  int64                      `json:"time"` // 桶起始时间（Unix 秒）
+	Rules map[string]*RuleHitCounter `json:"rules"`
+}
+
+// RuleHitStats 模板规则的命中统计
+type RuleHitStats struct {
+	Index    int    `json:"index"` // 在模板中的位置
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Payload  string `json:"payload"`
+	Proxy    string `json:"proxy"`
+	Disabled bool   `json:"disabled,omitempty"`
+	RuleHitCounter
+}
+
+// UnmatchedRuleStats 无法对应到模板规则的命中（如配置覆写、设备策略、链式代理生成的规则）
+type UnmatchedRuleStats struct {
+	Rule    string `json:"rule"`
+	Payload string `json:"payload"`
+	RuleHitCounter
+}
+
+// RuleStatsReport 规则命中统计查询结果
+type RuleStatsReport struct {
+	Range     string               `json:"range"`
+	Since     int64                `json:"since,omitempty"` // 最早的统计数据时间（Unix 秒）
+	Total     RuleHitCounter       `json:"total"`
+	Rules     []RuleHitStats       `json:"rules"`
+	NeverHit  int                  `json:"neverHit"` // 统计范围内未命中的启用规则数
+	Groups    []TrafficRanking     `json:"groups"`   // 按目标代理组汇总的流量
+	Unmatched []UnmatchedRuleStats `json:"unmatched"`
+}
+
+// ruleStats 通过连接采样统计规则命中，连接存活时间短于采样间隔时可能漏计
+type ruleStats struct {
+	mu       sync.Mutex
+	filePath string
+	buckets  []*RuleStatsBucket
+
+	// 上一次采样的连接计数，用于识别新连接和计算增量
+	primed    bool
+	lastConns map[string]TrafficCounter
+}
+
+func newRuleStats(dataDir string) *ruleStats {
+	s := &ruleStats{
+		filePath:  filepath.Join(dataDir, "rule_stats.json"),
+		lastConns: make(map[string]TrafficCounter),
+	}
+	s.load()
+	return s
+}
+
+func (s *ruleStats) load() {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.buckets); err != nil {
+		fmt.Printf("⚠️ 读取规则命中统计失败: %v\n", err)
+		s.buckets = nil
+	}
+}
+
+func (s *ruleStats) save() error {
+	data, err := json.Marshal(s.buckets)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filePath, data, 0644)
+}
+
+// flush 将内存中的统计写入磁盘
+func (s *ruleStats) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// reset 核心停止后清空连接基准
+func (s *ruleStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.primed = false
+	s.lastConns = make(map[string]TrafficCounter)
+}
+
+// clear 清空全部统计（精简规则后重新统计）
+func (s *ruleStats) clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets = nil
+	return s.save()
+}
+
+// ruleStatsKey 统一核心返回的规则类型（如 DomainSuffix）和模板中的类型（如 DOMAIN-SUFFIX）
+func ruleStatsKey(ruleType, payload string) string {
+	t := strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(ruleType))
+	if t == "IPCIDR6" {
+		t = "IPCIDR"
+	}
+	return t + "|" + strings.ToLower(payload)
+}
+
+// record 记录一次连接快照
+// 首次采样时已存在的连接只建立基准，不计入命中
+func (s *ruleStats) record(snapshot *ConnectionsSnapshot, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]TrafficCounter, len(snapshot.Connections))
+	for _, conn := range snapshot.Connections {
+		current[conn.ID] = TrafficCounter{Upload: conn.Upload, Download: conn.Download}
+	}
+	if !s.primed {
+		s.primed = true
+		s.lastConns = current
+		return
+	}
+
+	bucket := s.currentBucket(now)
+	for _, conn := range snapshot.Connections {
+		if conn.Rule == "" {
+			continue
+		}
+		key := ruleStatsKey(conn.Rule, conn.RulePayload)
+		counter := bucket.Rules[key]
+		if counter == nil {
+			counter = &RuleHitCounter{}
+			bucket.Rules[key] = counter
+		}
+
+		last, seen := s.lastConns[conn.ID]
+		if !seen {
+			counter.Hits++
+			counter.LastHit = now.Unix()
+		}
+		if up, down := conn.Upload-last.Upload, conn.Download-last.Download; up >= 0 && down >= 0 {
+			counter.Upload += up
+			counter.Download += down
+		}
+	}
+	s.lastConns = current
+}
+
+// currentBucket 获取当前时间所在的桶，跨桶时清理过期数据并落盘
+func (s *ruleStats) currentBucket(now time.Time) *RuleStatsBucket {
+	start := now.Truncate(ruleStatsBucketSize).Unix()
+	if n := len(s.buckets); n > 0 && s.buckets[n-1].Time == start {
+		return s.buckets[n-1]
+	}
+
+	cutoff := now.Add(-ruleStatsRetention).Unix()
+	i := 0
+	for i < len(s.buckets) && s.buckets[i].Time < cutoff {
+		i++
+	}
+	s.buckets = s.buckets[i:]
+
+	if err := s.save(); err != nil {
+		fmt.Printf("⚠️ 保存规则命中统计失败: %v\n", err)
+	}
+
+	bucket := &RuleStatsBucket{Time: start, Rules: make(map[string]*RuleHitCounter)}
+	s.buckets = append(s.buckets, bucket)
+	return bucket
+}
+
+// report 汇总指定时间范围内的命中，并按模板规则顺序对应
+func (s *ruleStats) report(rules []RuleTemplate, rangeDur time.Duration, now time.Time) *RuleStatsReport {
+	totals := make(map[string]*RuleHitCounter)
+	result := &RuleStatsReport{
+		Rules:     make([]RuleHitStats, 0, len(rules)),
+		Unmatched: []UnmatchedRuleStats{},
+	}
+
+	s.mu.Lock()
+	cutoff := now.Add(-rangeDur).Unix()
+	for _, bucket := range s.buckets {
+		if bucket.Time < cutoff {
+			continue
+		}
+		if result.Since == 0 {
+			result.Since = bucket.Time
+		}
+		for key, counter := range bucket.Rules {
+			total, ok := totals[key]
+			if !ok {
+				total = &RuleHitCounter{}
+				totals[key] = total
+			}
+			total.merge(counter)
+			result.Total.merge(counter)
+		}
+	}
+	s.mu.Unlock()
+
+	groups := make(map[string]*TrafficCounter)
+	matched := make(map[string]bool)
+	for i, rule := range rules {
+		key := ruleStatsKey(rule.Type, rule.Payload)
+		if rule.Type == "MATCH" {
+			key = ruleStatsKey("Match", "")
+		}
+		stats := RuleHitStats{
+			Index:    i,
+			ID:       rule.ID,
+			Type:     rule.Type,
+			Payload:  rule.Payload,
+			Proxy:    rule.Proxy,
+			Disabled: rule.Disabled,
+		}
+		// 相同类型和内容的规则只有第一条会命中
+		if counter := totals[key]; counter != nil && !matched[key] {
+			matched[key] = true
+			stats.RuleHitCounter = *counter
+			counterFor(groups, rule.Proxy).add(counter.Upload, counter.Download)
+		}
+		if stats.Hits == 0 && !rule.Disabled {
+			result.NeverHit++
+		}
+		result.Rules = append(result.Rules, stats)
+	}
+
+	for key, counter := range totals {
+		if matched[key] {
+			continue
+		}
+		ruleType, payload, _ := strings.Cut(key, "|")
+		result.Unmatched = append(result.Unmatched, UnmatchedRuleStats{Rule: ruleType, Payload: payload, RuleHitCounter: *counter})
+	}
+	sort.Slice(result.Unmatched, func(i, j int) bool {
+		return result.Unmatched[i].Hits > result.Unmatched[j].Hits
+	})
+	if len(result.Unmatched) > ruleStatsUnmatched {
+		result.Unmatched = result.Unmatched[:ruleStatsUnmatched]
+	}
+	result.Groups = rankCounters(groups, 0)
+	return result
+}
+
+// GetRuleStats 获取规则命中统计（?range=24h，sort=hits 时按命中数降序，默认按模板顺序）
+// 命中统计来自连接采样，仅适用于 Mihomo 核心
+func (h *Handler) GetRuleStats(c *gin.Context) {
+	rangeStr := c.DefaultQuery("range", "24h")
+	rangeDur, err := parseStatsRange(rangeStr)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if rangeDur > ruleStatsRetention {
+		rangeDur = ruleStatsRetention
+	}
+
+	var rules []RuleTemplate
+	if template := h.service.GetConfigTemplate(); template != nil {
+		rules = template.Rules
+	}
+	report := h.ruleStats.report(rules, rangeDur, time.Now())
+	report.Range = rangeStr
+	if c.Query("sort") == "hits" {
+		sort.SliceStable(report.Rules, func(i, j int) bool {
+			return report.Rules[i].Hits > report.Rules[j].Hits
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    report,
+	})
+}
+
+// ResetRuleStats 清空规则命中统计
+func (h *Handler) ResetRuleStats(c *gin.Context) {
+	if err := h.ruleStats.clear(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
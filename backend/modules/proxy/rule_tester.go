@@ -0,0 +1,569 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleTestRequest 规则匹配测试请求
+type RuleTestRequest struct {
+	Host      string `json:"host"`      // 目标域名或 IP
+	Port      int    `json:"port"`      // 目标端口（可选）
+	Network   string `json:"network"`   // tcp / udp，默认 tcp
+	SourceIP  string `json:"sourceIp"`  // 来源 IP（可选，用于设备策略规则）
+	NoResolve bool   `json:"noResolve"` // 不解析域名，IP 类规则直接跳过
+}
+
+// RuleTestSkipped 无法在本地判断的规则
+type RuleTestSkipped struct {
+	Index  int    `json:"index"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// RuleTestResult 规则匹配测试结果
+type RuleTestResult struct {
+	Matched     bool              `json:"matched"`
+	Index       int               `json:"index"` // 命中规则在最终规则列表中的序号（从 0 开始）
+	Rule        string            `json:"rule"`
+	Type        string            `json:"type"`
+	Payload     string            `json:"payload"`
+	Proxy       string            `json:"proxy"`              // 目标代理组
+	Outbound    string            `json:"outbound,omitempty"` // 核心运行时代理组当前实际选中的出口
+	ResolvedIPs []string          `json:"resolvedIps,omitempty"`
+	Skipped     []RuleTestSkipped `json:"skipped"` // 在命中规则之前被跳过的规则，可能影响结果准确性
+}
+
+// ruleTestTarget 待测试的连接
+type ruleTestTarget struct {
+	domain    string
+	ip        net.IP
+	port      int
+	network   string
+	srcIP     net.IP
+	noResolve bool
+	resolved  bool
+	ips       []net.IP
+}
+
+// destIPs 获取目标 IP，域名仅在规则需要时解析一次（与核心的行为一致）
+// 使用本机 DNS 解析，结果可能与核心的 DNS 配置不同
+func (t *ruleTestTarget) destIPs(ruleNoResolve bool) []net.IP {
+	if t.ip != nil {
+		return []net.IP{t.ip}
+	}
+	if ruleNoResolve || t.noResolve {
+		return nil
+	}
+	if !t.resolved {
+		t.resolved = true
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, t.domain)
+		if err == nil {
+			for _, a := range addrs {
+				t.ips = append(t.ips, a.IP)
+			}
+		}
+	}
+	return t.ips
+}
+
+// parsedRule 解析后的 mihomo 规则行
+type parsedRule struct {
+	raw       string
+	ruleType  string
+	payload   string
+	proxy     string
+	noResolve bool
+}
+
+// parseRuleLine 解析 TYPE,PAYLOAD,PROXY[,no-resolve] 格式的规则
+func parseRuleLine(line string) parsedRule {
+	r := parsedRule{raw: line}
+	parts := strings.Split(line, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if len(parts) > 1 && parts[len(parts)-1] == "no-resolve" {
+		r.noResolve = true
+		parts = parts[:len(parts)-1]
+	}
+	r.ruleType = strings.ToUpper(parts[0])
+	switch {
+	case r.ruleType == "MATCH" && len(parts) >= 2:
+		r.proxy = parts[1]
+	case len(parts) >= 3:
+		r.payload = strings.Join(parts[1:len(parts)-1], ",")
+		r.proxy = parts[len(parts)-1]
+	case len(parts) == 2:
+		r.payload = parts[1]
+	}
+	return r
+}
+
+// TestRule 按当前模板规则（含设备策略规则）和规则集测试目标的匹配结果
+func (s *Service) TestRule(req RuleTestRequest) (*RuleTestResult, error) {
+	target := &ruleTestTarget{
+		network:   strings.ToLower(strings.TrimSpace(req.Network)),
+		port:      req.Port,
+		noResolve: req.NoResolve,
+	}
+	host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Host)), ".")
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if target.port == 0 {
+			target.port, _ = strconv.Atoi(p)
+		}
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" {
+		return nil, fmt.Errorf("请输入域名或 IP")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		target.ip = ip
+	} else {
+		target.domain = host
+	}
+	if target.network == "" {
+		target.network = "tcp"
+	}
+	if target.network != "tcp" && target.network != "udp" {
+		return nil, fmt.Errorf("network 只支持 tcp 或 udp")
+	}
+	if req.SourceIP != "" {
+		if target.srcIP = net.ParseIP(strings.TrimSpace(req.SourceIP)); target.srcIP == nil {
+			return nil, fmt.Errorf("来源 IP 无效: %s", req.SourceIP)
+		}
+	}
+
+	s.mu.RLock()
+	template := s.configTemplate
+	policies := s.resolveDevicePolicies(s.config.DevicePolicies)
+	s.mu.RUnlock()
+	if template == nil {
+		template = GetDefaultConfigTemplate()
+	}
+
+	// 与生成配置时的规则顺序保持一致
+	lines := buildMihomoDeviceRules(policies)
+	lines = append(lines, s.configGenerator.generateRulesFromTemplate(template.Rules)...)
+	providers := mergeTemplateRuleProviders(s.configGenerator.generateRuleProviders(), template.RuleProviders)
+
+	result := &RuleTestResult{Index: -1, Skipped: []RuleTestSkipped{}}
+	for i, line := range lines {
+		rule := parseRuleLine(line)
+		matched, reason := s.matchRule(rule, target, providers)
+		if reason != "" {
+			result.Skipped = append(result.Skipped, RuleTestSkipped{Index: i, Rule: line, Reason: reason})
+			continue
+		}
+		if matched {
+			result.Matched = true
+			result.Index = i
+			result.Rule = line
+			result.Type = rule.ruleType
+			result.Payload = rule.payload
+			result.Proxy = rule.proxy
+			break
+		}
+	}
+
+	for _, ip := range target.ips {
+		result.ResolvedIPs = append(result.ResolvedIPs, ip.String())
+	}
+	return result, nil
+}
+
+// matchRule 判断单条规则是否命中，reason 不为空表示无法在本地判断
+func (s *Service) matchRule(rule parsedRule, t *ruleTestTarget, providers map[string]RuleProvider) (bool, string) {
+	switch rule.ruleType {
+	case "MATCH":
+		return true, ""
+	case "RULE-SET":
+		provider, ok := providers[rule.payload]
+		if !ok {
+			return false, "规则集未定义"
+		}
+		set, err := s.loadRuleSet(rule.payload, provider)
+		if err != nil {
+			return false, err.Error()
+		}
+		return set.match(t, rule.noResolve)
+	case "GEOIP":
+		if !strings.EqualFold(rule.payload, "LAN") {
+			return false, "GEOIP 需要核心的 GeoIP 数据库，无法在本地判断"
+		}
+		for _, ip := range t.destIPs(rule.noResolve) {
+			if isLANAddress(ip) {
+				return true, ""
+			}
+		}
+		return false, ""
+	case "GEOSITE":
+		return false, "GEOSITE 需要核心的 GeoSite 数据库，无法在本地判断"
+	case "AND", "OR", "NOT", "SUB-RULE":
+		return false, "暂不支持逻辑规则"
+	}
+	return matchBasicRule(rule.ruleType, rule.payload, rule.noResolve, t)
+}
+
+// matchBasicRule 判断不依赖外部数据的基础规则
+func matchBasicRule(ruleType, payload string, noResolve bool, t *ruleTestTarget) (bool, string) {
+	payload = strings.TrimSpace(payload)
+	switch ruleType {
+	case "DOMAIN":
+		return t.domain != "" && t.domain == strings.ToLower(payload), ""
+	case "DOMAIN-SUFFIX":
+		suffix := strings.ToLower(payload)
+		return t.domain != "" && (t.domain == suffix || strings.HasSuffix(t.domain, "."+suffix)), ""
+	case "DOMAIN-KEYWORD":
+		return t.domain != "" && strings.Contains(t.domain, strings.ToLower(payload)), ""
+	case "DOMAIN-REGEX":
+		re, err := regexp.Compile(payload)
+		if err != nil {
+			return false, "正则表达式无效"
+		}
+		return t.domain != "" && re.MatchString(t.domain), ""
+	case "IP-CIDR", "IP-CIDR6":
+		_, cidr, err := net.ParseCIDR(payload)
+		if err != nil {
+			return false, "CIDR 无效"
+		}
+		for _, ip := range t.destIPs(noResolve) {
+			if cidr.Contains(ip) {
+				return true, ""
+			}
+		}
+		return false, ""
+	case "SRC-IP-CIDR":
+		_, cidr, err := net.ParseCIDR(payload)
+		if err != nil {
+			return false, "CIDR 无效"
+		}
+		if t.srcIP == nil {
+			return false, ""
+		}
+		return cidr.Contains(t.srcIP), ""
+	case "DST-PORT":
+		if t.port == 0 {
+			return false, ""
+		}
+		return matchPortRanges(payload, t.port), ""
+	case "NETWORK":
+		return strings.EqualFold(payload, t.network), ""
+	}
+	return false, fmt.Sprintf("不支持的规则类型 %s", ruleType)
+}
+
+// matchPortRanges 匹配端口，支持 80/443 和 1000-2000 格式
+func matchPortRanges(payload string, port int) bool {
+	for _, part := range strings.Split(payload, "/") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		if port >= start && port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// isLANAddress 是否为局域网或保留地址（对应 GEOIP,LAN）
+func isLANAddress(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// ruleSetMatcher 已加载的规则集内容
+type ruleSetMatcher struct {
+	behavior  string
+	modTime   time.Time
+	exact     map[string]bool
+	suffixes  []string // 匹配自身及子域名（+.example.com）
+	subdomain []string // 仅匹配子域名（.example.com）
+	wildcards []string // 单级通配（*.example.com）
+	cidrs     []*net.IPNet
+	rules     []parsedRule // classical
+}
+
+var (
+	ruleSetCache   = map[string]*ruleSetMatcher{}
+	ruleSetCacheMu sync.Mutex
+)
+
+// loadRuleSet 加载规则集（按文件修改时间缓存）
+// mrs 为二进制格式，改用同一来源发布的 .list 文本版本进行测试
+func (s *Service) loadRuleSet(name string, provider RuleProvider) (*ruleSetMatcher, error) {
+	path := provider.Path
+	format := provider.Format
+	if format == "mrs" {
+		var err error
+		if path, err = s.ruleSetTextCopy(name, provider); err != nil {
+			return nil, err
+		}
+		format = "text"
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("规则集文件不存在，请先更新规则集")
+	}
+
+	ruleSetCacheMu.Lock()
+	defer ruleSetCacheMu.Unlock()
+	if cached, ok := ruleSetCache[path]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var payload []string
+	if format == "yaml" {
+		var doc struct {
+			Payload []string `yaml:"payload"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("解析规则集失败: %v", err)
+		}
+		payload = doc.Payload
+	} else {
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			payload = append(payload, scanner.Text())
+		}
+	}
+
+	set := newRuleSetMatcher(provider.Behavior, payload)
+	set.modTime = info.ModTime()
+	ruleSetCache[path] = set
+	return set, nil
+}
+
+// ruleSetTextCopy 获取 mrs 规则集对应的 .list 文本文件，缓存到 ruleset/text 目录，一天内不重复下载
+func (s *Service) ruleSetTextCopy(name string, provider RuleProvider) (string, error) {
+	sourceURL := provider.URL
+	if sourceURL == "" {
+		sourceURL = s.configGenerator.ruleProviderSourceURL(name)
+	}
+	if !strings.HasSuffix(sourceURL, ".mrs") {
+		return "", fmt.Errorf("mrs 规则集没有可用的文本版本")
+	}
+	textURL := strings.TrimSuffix(sourceURL, ".mrs") + ".list"
+
+	textDir := filepath.Join(s.dataDir, "ruleset", "text")
+	textPath := filepath.Join(textDir, name+".list")
+	if info, err := os.Stat(textPath); err == nil && time.Since(info.ModTime()) < 24*time.Hour {
+		return textPath, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(textURL)
+	if err != nil {
+		if _, statErr := os.Stat(textPath); statErr == nil {
+			return textPath, nil
+		}
+		return "", fmt.Errorf("下载规则集文本版本失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载规则集文本版本失败: HTTP %d", resp.StatusCode)
+	}
+
+	os.MkdirAll(textDir, 0755)
+	tmpPath := textPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, resp.Body)
+	out.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, textPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return textPath, nil
+}
+
+// ruleProviderSourceURL 规则集的下载地址（内置或用户自定义）
+func (g *ConfigGenerator) ruleProviderSourceURL(name string) string {
+	for _, r := range builtinRuleProviders {
+		if r.name == name {
+			return builtinRuleProviderBaseURL + r.urlPath
+		}
+	}
+	if g.customRulesProvider != nil {
+		for _, cr := range g.customRulesProvider() {
+			if cr.Name == name {
+				return cr.URL
+			}
+		}
+	}
+	return ""
+}
+
+// newRuleSetMatcher 按 behavior 解析规则集内容
+func newRuleSetMatcher(behavior string, payload []string) *ruleSetMatcher {
+	set := &ruleSetMatcher{behavior: behavior, exact: map[string]bool{}}
+	for _, line := range payload {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.Trim(line, `'"`)
+		switch behavior {
+		case "ipcidr":
+			if _, cidr, err := net.ParseCIDR(line); err == nil {
+				set.cidrs = append(set.cidrs, cidr)
+			} else if ip := net.ParseIP(line); ip != nil {
+				bits := 32
+				if ip.To4() == nil {
+					bits = 128
+				}
+				set.cidrs = append(set.cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+		case "classical":
+			// 规则集中的规则没有目标代理组，补一个占位目标再按规则行解析
+			noResolve := strings.HasSuffix(line, ",no-resolve")
+			rule := parseRuleLine(strings.TrimSuffix(line, ",no-resolve") + ",_")
+			rule.noResolve = noResolve
+			set.rules = append(set.rules, rule)
+		default:
+			line = strings.ToLower(line)
+			switch {
+			case strings.HasPrefix(line, "+."):
+				set.suffixes = append(set.suffixes, line[2:])
+			case strings.HasPrefix(line, "*."):
+				set.wildcards = append(set.wildcards, line[2:])
+			case strings.HasPrefix(line, "."):
+				set.subdomain = append(set.subdomain, line[1:])
+			default:
+				set.exact[line] = true
+			}
+		}
+	}
+	return set
+}
+
+// match 判断目标是否命中规则集
+func (m *ruleSetMatcher) match(t *ruleTestTarget, noResolve bool) (bool, string) {
+	switch m.behavior {
+	case "ipcidr":
+		for _, ip := range t.destIPs(noResolve) {
+			for _, cidr := range m.cidrs {
+				if cidr.Contains(ip) {
+					return true, ""
+				}
+			}
+		}
+		return false, ""
+	case "classical":
+		for _, r := range m.rules {
+			if r.ruleType == "GEOIP" || r.ruleType == "GEOSITE" || r.ruleType == "RULE-SET" {
+				continue
+			}
+			if matched, _ := matchBasicRule(r.ruleType, r.payload, noResolve || r.noResolve, t); matched {
+				return true, ""
+			}
+		}
+		return false, ""
+	}
+
+	if t.domain == "" {
+		return false, ""
+	}
+	if m.exact[t.domain] {
+		return true, ""
+	}
+	for _, suffix := range m.suffixes {
+		if t.domain == suffix || strings.HasSuffix(t.domain, "."+suffix) {
+			return true, ""
+		}
+	}
+	for _, suffix := range m.subdomain {
+		if strings.HasSuffix(t.domain, "."+suffix) {
+			return true, ""
+		}
+	}
+	for _, suffix := range m.wildcards {
+		if prefix, ok := strings.CutSuffix(t.domain, "."+suffix); ok && !strings.Contains(prefix, ".") {
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// TestRule 测试域名/IP 命中的规则和代理组
+func (h *Handler) TestRule(c *gin.Context) {
+	var req RuleTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.service.TestRule(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// 核心运行中时沿代理组当前选择找到实际出口
+	if result.Matched && h.service.GetStatus().Running {
+		result.Outbound = h.resolveOutbound(c.Request.Context(), result.Proxy)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// resolveOutbound 沿代理组的当前选择查找最终出口，失败时返回空
+func (h *Handler) resolveOutbound(ctx context.Context, group string) string {
+	proxies, err := h.clashAPI().Proxies(ctx)
+	if err != nil {
+		return ""
+	}
+	name := group
+	for i := 0; i < 10; i++ {
+		p, ok := proxies[name]
+		if !ok || p.Now == "" {
+			break
+		}
+		name = p.Now
+	}
+	if name == group {
+		return ""
+	}
+	return name
+}
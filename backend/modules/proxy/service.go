@@ -8,10 +8,14 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/events"
 	"ProxyStation/backend/modules/system"
+	"ProxyStation/backend/secure"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -32,12 +36,30 @@ type ProxyStatus struct {
 	MixedPort       int       `json:"mixedPort"`
 	SocksPort       int       `json:"socksPort"`
 	AllowLan        bool      `json:"allowLan"`
-	TransparentMode string    `json:"transparentMode"` // off, tproxy, redirect
+	TransparentMode string    `json:"transparentMode"` // off, system, tproxy, redirect, tun
 	ProxyScope      string    `json:"proxyScope"`      // local, router
 	StartTime       time.Time `json:"startTime,omitempty"`
 	Uptime          int64     `json:"uptime"`
 	ConfigPath      string    `json:"configPath,omitempty"`
 	ApiAddress      string    `json:"apiAddress,omitempty"`
+
+	// 崩溃自动重启状态
+	Supervisor SupervisorStatus `json:"supervisor"`
+
+	// 接管的外部核心（非 ProxyStation 启动）
+	Adopted    bool `json:"adopted,omitempty"`
+	AdoptedPID int  `json:"adoptedPid,omitempty"`
+
+	// 核心进程 PID（后端重启后通过 PID 文件重新关联的核心同样显示）
+	PID int `json:"pid,omitempty"`
+
+	// 核心进程资源占用（接管的外部核心同样统计）
+	Resources *ProcessStats `json:"resources,omitempty"`
+
+	// 当前配置文件、透明代理规则和最近一次错误（仅状态接口返回，见 status_detail.go）
+	Config      *ActiveConfigStatus    `json:"config,omitempty"`
+	Transparent *TransparentApplyState `json:"transparent,omitempty"`
+	LastError   *StatusError           `json:"lastError,omitempty"`
 }
 
 type ProxyConfig struct {
@@ -50,12 +72,56 @@ type ProxyConfig struct {
 	Mode               string `json:"mode" yaml:"mode"`
 	LogLevel           string `json:"logLevel" yaml:"log-level"`
 	ExternalController string `json:"externalController" yaml:"external-controller"`
-	TransparentMode    string `json:"transparentMode" yaml:"transparent-mode"` // off, tproxy, redirect
+	TransparentMode    string `json:"transparentMode" yaml:"transparent-mode"` // off, system, tproxy, redirect, tun
 	ProxyScope         string `json:"proxyScope" yaml:"proxy-scope"`            // local, router
 	AutoStart          bool   `json:"autoStart" yaml:"auto-start"`              // 开机自动启动
-	AutoStartDelay     int    `json:"autoStartDelay" yaml:"auto-start-delay"`   // 自动启动延迟（秒）
+	AutoStartDelay     int    `json:"autoStartDelay" yaml:"auto-start-delay"`   // 自动启动前等待网络就绪的最长时间（秒）
+
+	// 透明代理绕过设备列表（路由器模式下生效）
+	TransparentBypass TransparentBypass `json:"transparentBypass" yaml:"transparent-bypass"`
+	// 路由器模式下拦截的网卡（LAN 为空时拦截所有网卡）
+	TransparentInterfaces TransparentInterfaces `json:"transparentInterfaces" yaml:"transparent-interfaces"`
+	// 透明代理直连的目标地址段和端口
+	TransparentDirect TransparentDirect `json:"transparentDirect" yaml:"transparent-direct"`
+	// 本机进程分流（按用户或 cgroup 直连或只代理指定进程）
+	TransparentProcesses TransparentProcesses `json:"transparentProcesses" yaml:"transparent-processes"`
+	// 节点标签（按地区/关键字分类节点，代理组按标签自动填充）
+	NodeTags NodeTagConfig `json:"nodeTags" yaml:"node-tags"`
+	// 节点重命名（去除前缀、统一国旗、编号、去重）
+	NodeRename NodeRenameConfig `json:"nodeRename" yaml:"node-rename"`
+	// 跨订阅节点去重（服务器、端口和凭据相同的节点）
+	NodeDedup NodeDedupConfig `json:"nodeDedup" yaml:"node-dedup"`
+	// 链式代理（入口节点 → 出口节点）
+	ProxyChains []ProxyChain `json:"proxyChains" yaml:"proxy-chains"`
+	// 检测到其他防火墙管理工具的拦截规则时的处理方式: warn/refuse/ignore
+	FirewallConflictPolicy string `json:"firewallConflictPolicy" yaml:"firewall-conflict-policy"`
+	// 设备策略表（路由器模式下按设备直连/指定代理组）
+	DevicePolicies []DevicePolicy `json:"devicePolicies" yaml:"device-policies"`
+	// 透明代理模式下劫持 53 端口 DNS 查询到核心
+	DNSHijack bool `json:"dnsHijack" yaml:"dns-hijack"`
+	// 透明代理是否拦截 IPv6 流量（关闭时 IPv6 直连）
+	TransparentIPv6 bool `json:"transparentIpv6" yaml:"transparent-ipv6"`
+	// 透明代理模式下拦截被代理流量的 QUIC（UDP 443），浏览器回退到 TCP
+	BlockQUIC bool `json:"blockQuic" yaml:"block-quic"`
+	// 透明代理连通性看门狗（持续断网时自动关闭透明代理）
+	TransparentWatchdog TransparentWatchdog `json:"transparentWatchdog" yaml:"transparent-watchdog"`
+	// 核心 API 密钥（external-controller secret）
+	Secret string `json:"secret" yaml:"secret"`
+	// 节点测速专用监听端口（仅监听 127.0.0.1）
+	SpeedtestPort int `json:"speedtestPort" yaml:"speedtest-port"`
+	// 核心异常退出时自动重启
+	CrashRestart bool `json:"crashRestart" yaml:"crash-restart"`
+	// 连续崩溃重启的最大次数，超过后放弃
+	MaxRestarts int `json:"maxRestarts" yaml:"max-restarts"`
+	// 以指定的普通用户运行核心（仅 Linux，为空时与 ProxyStation 同一用户）
+	RunAsUser string `json:"runAsUser" yaml:"run-as-user"`
+	// 配置版本，用于迁移旧版本配置
+	ConfigVersion int `json:"configVersion" yaml:"config-version"`
 }
 
+// currentConfigVersion 当前配置版本，loadConfig 据此迁移旧配置
+const currentConfigVersion = 4
+
 // NodeProvider 节点提供者接口
 type NodeProvider func() []ProxyNode
 
@@ -70,10 +136,14 @@ type Service struct {
 	singboxGenerator *SingboxGenerator
 	configTemplate   *ConfigTemplate
 	process          *exec.Cmd
+	coreVersion      string        // 启动时查询的核心版本
+	processDone      chan struct{} // 进程退出后关闭
+	adopted          *adoptedCore  // 接管的外部核心，非空时 process 为空
 	running          bool
 	startTime        time.Time
 	configPath       string
 	mu               sync.RWMutex
+	writeMu          sync.Mutex // 串行化配置和模板的修改（见 write_guard.go）
 
 	// 节点提供者（从节点管理模块获取过滤后的节点）
 	nodeProvider NodeProvider
@@ -84,13 +154,55 @@ type Service struct {
 	// 自定义规则集提供者（从 ruleset 模块获取）
 	customRulesProvider func() []CustomRuleEntry
 
+	// MAC 地址查询（从 lan 模块获取），设备策略只填写 MAC 时据此得到当前 IP
+	macResolver func(mac string) string
+
+	// 广告拦截规则（从 blocklist 模块获取）
+	blocklistProvider func() *BlocklistRules
+
+	// IP 归属查询（从 geodata 模块获取），为连接补充目标国家 / ASN
+	geoResolver func(ip string) *GeoInfo
+
 	// 日志收集
 	logs  []string
 	logMu sync.RWMutex
+	// 日志文件（logs/core.log，按大小和日期轮转）
+	logFile *rotatingLog
 
 	// 启动/停止回调
 	onStartCallback func() // 启动成功后调用
 	onStopCallback  func() // 停止成功后调用
+
+	// 事件发布（核心启停、崩溃、配置生成等状态变化）
+	eventPublisher func(eventType string, data interface{})
+
+	// 配置历史归档
+	history *configHistory
+	// 回滚后下一次启动直接使用当前配置文件，不重新生成
+	keepConfigOnStart bool
+
+	// 配置方案读写锁
+	profileMu sync.Mutex
+
+	// 崩溃自动重启状态（由 mu 保护）
+	supervisor supervisorState
+
+	// 配置托管（对外提供订阅地址）
+	share profileShare
+
+	// 核心进程 CPU 占用采样
+	cpu cpuSampler
+
+	// 最近一次错误（由 mu 保护）
+	lastError *StatusError
+	// 配置漂移检查结果缓存
+	driftCache configDriftCache
+	// 最近一次节点去重结果
+	dedup nodeDedupState
+
+	// 配置生成管线（见 config_pipeline.go）
+	mutators   []ConfigMutator
+	mutatorsMu sync.RWMutex
 }
 
 func NewService(dataDir string) *Service {
@@ -107,51 +219,46 @@ func NewService(dataDir string) *Service {
 			Mode:               "rule",
 			LogLevel:           "info",
 			ExternalController: "127.0.0.1:9090",
-			TransparentMode:    "off",
+			TransparentMode:    defaultTransparentMode(),
 			ProxyScope:         "local",
 			AutoStart:          false,
 			AutoStartDelay:     15, // 默认延迟 15 秒
+			TransparentIPv6:    true,
+			SpeedtestPort:      7899,
+			CrashRestart:       true,
+			MaxRestarts:        5,
+			ConfigVersion:      currentConfigVersion,
+			TransparentInterfaces: TransparentInterfaces{
+				ExcludeDocker:  true,
+				ExcludeOverlay: true,
+			},
+			TransparentDirect: TransparentDirect{
+				LocalNets: append([]string{}, defaultLocalNets...),
+			},
+			NodeTags: NodeTagConfig{Regions: true},
 		},
 		configGenerator:  NewConfigGenerator(dataDir),
 		singboxGenerator: NewSingboxGenerator(dataDir),
 		configTemplate:   GetDefaultConfigTemplate(),
+		history:          newConfigHistory(dataDir),
 	}
+	s.logFile = newRotatingLog(filepath.Join(dataDir, "logs"), "core.log", s.coreLogLimits)
+	s.registerBuiltinMutators()
+	system.SetProxyBackupPath(dataDir)
 	s.loadConfig()
 	s.loadConfigTemplate()
-	return s
-}
-
-// AutoStartIfEnabled 如果开启了自动启动，则在延迟后启动代理
-func (s *Service) AutoStartIfEnabled() {
-	if !s.config.AutoStart {
-		return
-	}
-
-	delay := s.config.AutoStartDelay
-	if delay < 0 {
-		delay = 0
-	}
-
-	fmt.Printf("⏳ 自动启动已开启，将在 %d 秒后启动代理...\n", delay)
-
-	go func() {
-		time.Sleep(time.Duration(delay) * time.Second)
-
-		s.mu.RLock()
-		if s.running {
-			s.mu.RUnlock()
-			fmt.Println("✓ 代理已在运行，跳过自动启动")
-			return
-		}
-		s.mu.RUnlock()
-
-		fmt.Println("🚀 开始自动启动代理...")
-		if err := s.Start(); err != nil {
-			fmt.Printf("❌ 自动启动代理失败: %v\n", err)
-		} else {
-			fmt.Println("✓ 代理自动启动成功")
+	s.loadProfileShare()
+	ensureRuleIDs(s.configTemplate.Rules)
+	s.recoverCore()
+
+	// 上次异常退出时未恢复的系统代理设置
+	if !s.running && system.HasProxyBackup() {
+		fmt.Println("🔧 恢复上次未还原的系统代理设置")
+		if err := system.ClearSystemProxy(); err != nil {
+			fmt.Printf("⚠️ 恢复系统代理失败: %v\n", err)
 		}
-	}()
+	}
+	return s
 }
 
 func (s *Service) loadConfig() {
@@ -164,7 +271,8 @@ func (s *Service) loadConfig() {
 	// 保存默认值
 	defaults := *s.config
 
-	// 加载配置
+	// 加载配置（旧配置文件没有 configVersion 字段）
+	s.config.ConfigVersion = 0
 	json.Unmarshal(data, s.config)
 
 	// 对于零值字段，恢复默认值
@@ -198,6 +306,35 @@ func (s *Service) loadConfig() {
 	if s.config.AutoStartDelay == 0 {
 		s.config.AutoStartDelay = defaults.AutoStartDelay
 	}
+	if s.config.SpeedtestPort == 0 {
+		s.config.SpeedtestPort = defaults.SpeedtestPort
+	}
+	if s.config.MaxRestarts == 0 {
+		s.config.MaxRestarts = defaults.MaxRestarts
+	}
+
+	version := s.config.ConfigVersion
+	// 旧版本在 macOS/Windows 上 off 即表示系统代理，迁移为独立的 system 模式
+	if version < 1 && runtime.GOOS != "linux" && s.config.TransparentMode == "off" {
+		s.config.TransparentMode = "system"
+	}
+	// 版本 2 新增容器和组网网卡排除，默认开启
+	if version < 2 {
+		s.config.TransparentInterfaces.ExcludeDocker = true
+		s.config.TransparentInterfaces.ExcludeOverlay = true
+	}
+	// 版本 3 起直连地址段可编辑，旧配置使用原来固定的地址段
+	if version < 3 {
+		s.config.TransparentDirect.LocalNets = append([]string{}, defaultLocalNets...)
+	}
+	// 版本 4 新增节点标签，默认开启内置地区标签
+	if version < 4 {
+		s.config.NodeTags.Regions = true
+	}
+	if version < currentConfigVersion {
+		s.config.ConfigVersion = currentConfigVersion
+		s.saveConfig()
+	}
 }
 
 func (s *Service) saveConfig() error {
@@ -216,6 +353,7 @@ func (s *Service) GetStatus() *ProxyStatus {
 	status := &ProxyStatus{
 		Running:         s.running,
 		CoreType:        s.coreType,
+		CoreVersion:     s.coreVersion,
 		Mode:            ProxyMode(s.config.Mode),
 		MixedPort:       s.config.MixedPort,
 		SocksPort:       s.config.SocksPort,
@@ -230,37 +368,113 @@ func (s *Service) GetStatus() *ProxyStatus {
 		status.StartTime = s.startTime
 		status.Uptime = int64(time.Since(s.startTime).Seconds())
 	}
+	status.Supervisor = s.supervisorStatus()
+	status.LastError = s.lastError
+	if s.process != nil && s.process.Process != nil {
+		status.PID = s.process.Process.Pid
+	} else if s.adopted != nil {
+		status.PID = s.adopted.pid
+		if !s.adopted.owned {
+			status.Adopted = true
+			status.AdoptedPID = s.adopted.pid
+		}
+	}
+	if status.PID > 0 {
+		status.Resources = s.readProcessStats(status.PID)
+	}
 
 	return status
 }
 
 func (s *Service) Start() error {
+	// 手动启动时重置崩溃重启计数
+	s.resetSupervisor()
+	return s.start(false)
+}
+
+// start 启动核心进程并执行启动后的系统设置
+// reuseConfig 为 true 时直接使用现有配置文件（崩溃重启时使用）
+func (s *Service) start(reuseConfig bool) error {
+	if err := s.launch(reuseConfig); err != nil {
+		s.recordError("start", err)
+		return err
+	}
+	s.afterStart()
+	return nil
+}
+
+// launch 启动核心进程
+func (s *Service) launch(reuseConfig bool) error {
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
-		return fmt.Errorf("proxy is already running")
+		return ErrCoreRunning
 	}
 
 	corePath := s.findCorePath()
 	if corePath == "" {
 		s.mu.Unlock()
-		return fmt.Errorf("核心文件未找到，请先下载核心")
+		return ErrCoreNotInstalled
 	}
+	keepConfig := reuseConfig || s.keepConfigOnStart
+	s.keepConfigOnStart = false
 	s.mu.Unlock() // 释放锁再调用 regenerateConfig
 
-	// 每次启动都重新生成配置（确保配置是最新的）
-	configPath, err := s.regenerateConfig()
+	// 检查核心文件架构和执行权限
+	if err := checkCoreBinary(corePath); err != nil {
+		s.addLog("[ERROR] " + err.Error())
+		return err
+	}
+
+	// TUN 模式需要 TUN 设备和 CAP_NET_ADMIN，提前检查给出明确错误
+	if s.config.TransparentMode == "tun" {
+		if err := checkTUNSupport(); err != nil {
+			s.addLog("[ERROR] " + err.Error())
+			return err
+		}
+		// 作为局域网网关时需要转发局域网设备的流量
+		if s.config.ProxyScope == "router" {
+			if err := enableIPForward(); err != nil {
+				fmt.Printf("⚠️ 开启 IP 转发失败: %v\n", err)
+			}
+		}
+	}
+
+	// Windows 防火墙放行核心，允许局域网设备连接
+	if runtime.GOOS == "windows" && s.config.AllowLan {
+		if err := system.AllowInboundProgram("ProxyStation Core", corePath); err != nil {
+			fmt.Printf("⚠️ 添加防火墙规则失败: %v\n", err)
+		}
+	}
+
+	// 每次启动都重新生成配置（确保配置是最新的），回滚后的首次启动除外
+	var configPath string
+	var err error
+	if keepConfig {
+		configPath = s.activeConfigPath(s.coreType)
+		fmt.Println("📄 使用现有配置启动")
+	} else {
+		configPath, err = s.regenerateConfig("start")
+	}
 	if err != nil {
 		// 如果重新生成失败，尝试使用已有配置
-		if s.coreType == "singbox" {
-			configPath = filepath.Join(s.dataDir, "configs", "singbox-config.json")
-		} else {
-			configPath = filepath.Join(s.dataDir, "configs", "config.yaml")
-		}
+		configPath = s.activeConfigPath(s.coreType)
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
 			return fmt.Errorf("配置文件未找到，请先生成配置")
 		}
 		fmt.Printf("⚠️ 重新生成配置失败，使用已有配置: %v\n", err)
+		s.recordError("config", err)
+	}
+
+	// 其他实例（共用数据目录）启动的核心仍在运行时拒绝重复启动
+	if rec := s.liveCore(); rec != nil {
+		return fmt.Errorf("核心已在运行 (PID %d)，请勿重复启动", rec.PID)
+	}
+
+	// 检查端口占用，避免核心因绑定失败直接退出
+	if err := s.checkCorePorts(s.GetCoreType(), configPath, corePath); err != nil {
+		s.addLog("[ERROR] " + err.Error())
+		return err
 	}
 
 	s.mu.Lock()         // 重新获取锁
@@ -268,7 +482,7 @@ func (s *Service) Start() error {
 
 	// 再次检查是否已经运行（防止并发启动）
 	if s.running {
-		return fmt.Errorf("proxy is already running")
+		return ErrCoreRunning
 	}
 
 	// 创建运行时目录
@@ -276,16 +490,18 @@ func (s *Service) Start() error {
 	os.MkdirAll(runtimeDir, 0755)
 
 	// 构建命令 - 根据核心类型使用不同参数
-	// Mihomo: -d <workdir> -f <config>
-	// Sing-Box: run -D <workdir> -c <config>
-	if s.coreType == "singbox" {
-		s.process = exec.Command(corePath, "run", "-D", s.dataDir, "-c", configPath)
-		// 启用已弃用的特殊出站（direct），代理组需要引用"直连"
-		s.process.Env = append(os.Environ(), "ENABLE_DEPRECATED_SPECIAL_OUTBOUNDS=true")
-	} else {
-		s.process = exec.Command(corePath, "-d", s.dataDir, "-f", configPath)
-	}
+	runner := s.runner()
+	s.process = runner.Command(corePath, s.dataDir, configPath)
 	s.process.Dir = s.dataDir
+	s.coreVersion = coreVersion(runner, corePath)
+
+	// 以普通用户运行核心，仅保留网络相关能力
+	if s.config.RunAsUser != "" {
+		if err := applyCoreUser(s.process, s.config.RunAsUser, corePath, s.dataDir); err != nil {
+			s.process = nil
+			return err
+		}
+	}
 
 	// 创建管道捕获输出
 	stdout, _ := s.process.StdoutPipe()
@@ -302,18 +518,28 @@ func (s *Service) Start() error {
 	s.running = true
 	s.startTime = time.Now()
 	s.configPath = configPath
+	s.processDone = make(chan struct{})
 
-	// 监控进程
-	go func() {
-		s.process.Wait()
-		s.mu.Lock()
-		s.running = false
-		s.process = nil
-		s.mu.Unlock()
-	}()
+	if err := s.writePidFile(corePidFile{
+		PID:        s.process.Process.Pid,
+		CoreType:   s.coreType,
+		CorePath:   corePath,
+		ConfigPath: configPath,
+		StartTime:  s.startTime,
+	}); err != nil {
+		fmt.Printf("⚠️ 写入 PID 文件失败: %v\n", err)
+	}
+
+	// 监控进程，异常退出时自动重启
+	go s.monitorProcess(s.process, s.processDone)
 
-	// 根据透明代理模式自动设置系统代理（macOS/Windows）
-	if s.config.TransparentMode == "off" {
+	return nil
+}
+
+// afterStart 核心启动后设置系统代理并通知其他模块（不持有锁，回调中可以读取状态）
+func (s *Service) afterStart() {
+	// 系统代理模式下设置系统代理（macOS/Windows）
+	if s.config.TransparentMode == "system" {
 		fmt.Println("🔧 检测到系统代理模式，自动设置系统代理...")
 		if err := system.SetSystemProxy("127.0.0.1", s.config.MixedPort); err != nil {
 			fmt.Printf("⚠️  设置系统代理失败: %v\n", err)
@@ -330,7 +556,7 @@ func (s *Service) Start() error {
 		s.onStartCallback()
 	}
 
-	return nil
+	s.publish(events.CoreStarted, s.GetStatus())
 }
 
 // configureAllBrowsers 配置所有浏览器使用系统代理
@@ -345,30 +571,56 @@ func (s *Service) configureAllBrowsers() {
 }
 
 func (s *Service) Stop() error {
+	// 后端重启后重新关联的核心
+	if stopped, err := s.stopRecovered(); stopped || err != nil {
+		return err
+	}
+	// 接管的外部核心只解除接管，不结束进程
+	if s.releaseAdopted() {
+		return nil
+	}
+
 	s.mu.Lock()
 
+	// 取消等待中的崩溃重启
+	pending := s.cancelRestart()
+
 	if !s.running {
 		s.mu.Unlock()
+		// 崩溃后等待重启期间系统代理和透明代理规则仍然保留，需要清理
+		if pending {
+			s.afterStop()
+		}
 		return nil
 	}
 
-	if s.process != nil && s.process.Process != nil {
-		if err := s.process.Process.Kill(); err != nil {
+	// 先清空 process，监控协程据此判断是主动停止而非崩溃
+	cmd := s.process
+	s.process = nil
+	if cmd != nil && cmd.Process != nil {
+		if err := s.terminateProcess(cmd, s.processDone); err != nil {
+			s.process = cmd
 			s.mu.Unlock()
 			return fmt.Errorf("failed to stop core: %w", err)
 		}
-		s.process.Wait()
 	}
 
 	s.running = false
-	s.process = nil
 	s.mu.Unlock()
 
-	// 清除系统代理设置（macOS/Windows）
-	if err := system.ClearSystemProxy(); err != nil {
-		fmt.Printf("⚠️ 清除系统代理失败: %v\n", err)
-	} else {
-		fmt.Println("✓ 系统代理已清除")
+	s.afterStop()
+	return nil
+}
+
+// afterStop 核心停止后恢复系统代理并通知其他模块
+func (s *Service) afterStop() {
+	// 恢复系统代理设置（macOS/Windows），其他模式下不修改用户自己的系统代理
+	if s.config.TransparentMode == "system" || system.HasProxyBackup() {
+		if err := system.ClearSystemProxy(); err != nil {
+			fmt.Printf("⚠️ 清除系统代理失败: %v\n", err)
+		} else {
+			fmt.Println("✓ 系统代理已清除")
+		}
 	}
 
 	// 恢复浏览器代理设置（恢复用户原有配置）
@@ -381,10 +633,13 @@ func (s *Service) Stop() error {
 		s.onStopCallback()
 	}
 
-	return nil
+	s.publish(events.CoreStopped, s.GetStatus())
 }
 
 func (s *Service) Restart() error {
+	if s.IsAdopted() {
+		return fmt.Errorf("外部核心由外部管理，无法重启，请先解除接管")
+	}
 	if err := s.Stop(); err != nil {
 		return err
 	}
@@ -404,6 +659,8 @@ func (s *Service) collectLogs(reader io.Reader) {
 
 // addLog 添加日志
 func (s *Service) addLog(line string) {
+	s.logFile.WriteLine(time.Now(), line)
+
 	s.logMu.Lock()
 	defer s.logMu.Unlock()
 
@@ -476,27 +733,39 @@ func (s *Service) SetOnStopCallback(callback func()) {
 	s.onStopCallback = callback
 }
 
+// SetEventPublisher 设置事件发布函数（由 server 注入事件总线）
+func (s *Service) SetEventPublisher(publisher func(eventType string, data interface{})) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventPublisher = publisher
+}
+
+// publish 发布状态变化事件，调用时不能持有 s.mu 锁
+func (s *Service) publish(eventType string, data interface{}) {
+	s.mu.RLock()
+	publisher := s.eventPublisher
+	s.mu.RUnlock()
+	if publisher != nil {
+		publisher(eventType, data)
+	}
+}
+
 // RegenerateConfig 从节点管理模块获取过滤后的节点并生成配置（公开方法）
 func (s *Service) RegenerateConfig() (string, error) {
-	return s.regenerateConfig()
+	return s.regenerateConfig("regenerate")
 }
 
 // regenerateConfig 从节点管理模块获取过滤后的节点并生成配置
+// reason 为触发原因，记录在配置历史中
 // 注意：调用此方法时不能持有 s.mu 锁
-func (s *Service) regenerateConfig() (string, error) {
-	provider := s.nodeProvider // nodeProvider 在初始化后不会改变，无需加锁
-
-	if provider == nil {
-		return "", fmt.Errorf("节点提供者未设置")
-	}
-
-	allNodes := provider()
-	if len(allNodes) == 0 {
-		return "", fmt.Errorf("没有可用节点")
+func (s *Service) regenerateConfig(reason string) (string, error) {
+	allNodes, err := s.GetAllNodes()
+	if err != nil {
+		return "", err
 	}
 
 	fmt.Printf("🔄 重新生成配置，共 %d 个节点\n", len(allNodes))
-	return s.GenerateConfig(allNodes)
+	return s.generateConfig(allNodes, reason)
 }
 
 // GetConfigContent 读取生成的 config.yaml 文件内容
@@ -524,14 +793,23 @@ func (s *Service) SetMode(mode string) error {
 }
 
 // SetTransparentMode 设置透明代理模式和作用域（仅保存到配置，nft 规则由 handler 负责）
-// mode: off (关闭), tproxy (TPROXY), redirect (REDIRECT)
+// mode: off (关闭), system (系统代理), tproxy (TPROXY), redirect (REDIRECT), tun (核心 TUN 栈)
 // scope: local (仅本机), router (本机+局域网)
 func (s *Service) SetTransparentMode(mode string, scope string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if (isTransparentMode(mode) || mode == "tun") && runtime.GOOS != "linux" {
+		return apierror.New(apierror.CodeUnsupported, "透明代理仅支持 Linux，当前系统请使用系统代理模式")
+	}
+	if mode == "tun" {
+		if err := checkTUNSupport(); err != nil {
+			return err
+		}
+	}
+
 	switch mode {
-	case "off", "tproxy", "redirect":
+	case "off", "system", "tproxy", "redirect", "tun":
 		s.config.TransparentMode = mode
 		if scope == "router" {
 			s.config.ProxyScope = "router"
@@ -546,6 +824,19 @@ func (s *Service) SetTransparentMode(mode string, scope string) error {
 	}
 }
 
+// isTransparentMode 是否为需要 nftables 规则的透明代理模式（off、system 和 tun 不需要，tun 由核心自行配置路由）
+func isTransparentMode(mode string) bool {
+	return mode == "tproxy" || mode == "redirect"
+}
+
+// defaultTransparentMode 默认模式：Linux 不修改系统设置，macOS/Windows 使用系统代理
+func defaultTransparentMode() string {
+	if runtime.GOOS == "linux" {
+		return "off"
+	}
+	return "system"
+}
+
 func (s *Service) GetConfig() *ProxyConfig {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -611,6 +902,11 @@ func (s *Service) PatchConfig(updates map[string]interface{}) error {
 			s.config.ExternalController = val
 		}
 	}
+	if v, ok := updates["secret"]; ok {
+		if val, ok := v.(string); ok {
+			s.config.Secret = val
+		}
+	}
 	if v, ok := updates["transparentMode"]; ok {
 		if val, ok := v.(string); ok {
 			s.config.TransparentMode = val
@@ -626,6 +922,26 @@ func (s *Service) PatchConfig(updates map[string]interface{}) error {
 			s.config.AutoStartDelay = int(val)
 		}
 	}
+	if v, ok := updates["speedtestPort"]; ok {
+		if val, ok := v.(float64); ok {
+			s.config.SpeedtestPort = int(val)
+		}
+	}
+	if v, ok := updates["runAsUser"]; ok {
+		if val, ok := v.(string); ok {
+			s.config.RunAsUser = strings.TrimSpace(val)
+		}
+	}
+	if v, ok := updates["crashRestart"]; ok {
+		if val, ok := v.(bool); ok {
+			s.config.CrashRestart = val
+		}
+	}
+	if v, ok := updates["maxRestarts"]; ok {
+		if val, ok := v.(float64); ok && val > 0 {
+			s.config.MaxRestarts = int(val)
+		}
+	}
 
 	return s.saveConfig()
 }
@@ -634,14 +950,10 @@ func (s *Service) findCorePath() string {
 	coresDir := filepath.Join(s.dataDir, "cores")
 	arch := runtime.GOARCH
 	goos := runtime.GOOS
+	prefix := s.runner().BinaryPrefix()
 
 	// 精确匹配
-	var binName string
-	if s.coreType == "singbox" {
-		binName = fmt.Sprintf("sing-box-%s-%s", goos, arch)
-	} else {
-		binName = fmt.Sprintf("mihomo-%s-%s", goos, arch)
-	}
+	binName := fmt.Sprintf("%s-%s-%s", prefix, goos, arch)
 
 	if goos == "windows" {
 		binName += ".exe"
@@ -652,24 +964,135 @@ func (s *Service) findCorePath() string {
 		return exactPath
 	}
 
-	// 模糊匹配
-	patterns := []string{
-		filepath.Join(coresDir, "mihomo*"),
-		filepath.Join(coresDir, "sing-box*"),
-	}
-
-	for _, pattern := range patterns {
-		matches, _ := filepath.Glob(pattern)
-		if len(matches) > 0 {
-			return matches[0]
+	// 模糊匹配（只匹配当前核心类型，避免用错误的参数启动另一种核心），优先选择架构匹配的文件
+	matches, _ := filepath.Glob(filepath.Join(coresDir, prefix+"*"))
+	for _, match := range matches {
+		if checkCoreBinary(match) == nil {
+			return match
 		}
 	}
+	if len(matches) > 0 {
+		return matches[0]
+	}
 
 	return ""
 }
 
+// GetCoreLaunchInfo 获取当前核心的启动参数（用于生成独立运行的 systemd 单元）
+func (s *Service) GetCoreLaunchInfo() (coreType, corePath, configPath, runAsUser string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.coreType, s.findCorePath(), s.activeConfigPath(s.coreType), s.config.RunAsUser
+}
+
 // GenerateConfig 生成配置文件
 func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
+	return s.generateConfig(nodes, "generate")
+}
+
+// generateConfig 生成配置文件并归档到配置历史
+func (s *Service) generateConfig(nodes []ProxyNode, reason string) (string, error) {
+	configPath := s.activeConfigPath(s.coreType)
+	previous, _ := os.ReadFile(configPath)
+
+	if err := s.ensureAPISecret(); err != nil {
+		return "", err
+	}
+	build := s.newConfigBuild(s.coreType, nodes)
+	if err := s.runConfigBuild(build); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(configPath, build.finalize(), 0644); err != nil {
+		return "", err
+	}
+	s.recordNodeDedup(build.DedupReport)
+
+	s.configPath = configPath
+	s.keepConfigOnStart = false
+	s.archiveConfig(s.coreType, reason, previous)
+	// 启动时在持有 s.mu 锁的情况下生成配置，异步发布避免死锁
+	go s.publish(events.ConfigGenerated, map[string]interface{}{
+		"coreType":   s.coreType,
+		"configPath": configPath,
+		"reason":     reason,
+	})
+	return configPath, nil
+}
+
+// renderConfig 根据当前核心类型在内存中生成配置内容（不写入磁盘）
+func (s *Service) renderConfig(nodes []ProxyNode) ([]byte, error) {
+	build := s.newConfigBuild(s.coreType, nodes)
+	if err := s.runConfigBuild(build); err != nil {
+		return nil, err
+	}
+	return build.finalize(), nil
+}
+
+// RenderSingBoxConfig 按指定的 sing-box 选项生成配置内容（不写入磁盘）
+// 选项只包含入站、DNS 等基础设置，代理组过滤、链式代理、模板 DNS 和出站标记沿用当前设置，
+// 节点处理、广告拦截、覆写等与核心配置一样经过配置生成管线
+func (s *Service) RenderSingBoxConfig(nodes []ProxyNode, opts SingBoxGeneratorOptions) ([]byte, error) {
+	build := s.newConfigBuild("singbox", nodes)
+	base := build.SingBoxOptions
+	opts.GroupFilters = base.GroupFilters
+	opts.ProxyChains = base.ProxyChains
+	opts.TemplateDNS = base.TemplateDNS
+	opts.RoutingMark = base.RoutingMark
+	opts.InterfaceName = base.InterfaceName
+	build.SingBoxOptions = opts
+	if err := s.runConfigBuild(build); err != nil {
+		return nil, err
+	}
+	return build.finalize(), nil
+}
+
+// newConfigBuild 按当前设置创建一次配置生成
+func (s *Service) newConfigBuild(coreType string, nodes []ProxyNode) *ConfigBuild {
+	build := &ConfigBuild{
+		CoreType: coreType,
+		Options:  s.generatorOptions(),
+		Nodes:    append([]ProxyNode(nil), nodes...),
+	}
+	if coreType == "singbox" {
+		build.SingBoxOptions = singboxGeneratorOptions(build.Options)
+	}
+	return build
+}
+
+// runConfigBuild 执行配置生成管线
+// 依次执行：节点阶段环节 → 生成结构化配置 → 生成阶段环节 → 序列化 → 内容阶段环节
+func (s *Service) runConfigBuild(build *ConfigBuild) error {
+	if err := s.runConfigMutators(ConfigStageNodes, build); err != nil {
+		return err
+	}
+
+	var err error
+	if build.CoreType == "singbox" {
+		// 生成 sing-box 1.12+ 配置
+		if build.SingBox, err = s.singboxGenerator.GenerateConfigV112(build.Nodes, build.SingBoxOptions); err != nil {
+			return err
+		}
+	} else {
+		// 生成 Mihomo/Clash 配置
+		if build.Mihomo, err = s.configGenerator.GenerateConfig(build.Nodes, build.Options); err != nil {
+			return err
+		}
+	}
+	if err := s.runConfigMutators(ConfigStageGenerated, build); err != nil {
+		return err
+	}
+
+	if err := build.marshal(); err != nil {
+		return err
+	}
+	return s.runConfigMutators(ConfigStageRendered, build)
+}
+
+// generatorOptions 根据当前设置构建配置生成选项
+func (s *Service) generatorOptions() ConfigGeneratorOptions {
 	// 根据透明代理模式设置
 	enableTProxy := s.config.TransparentMode == "tproxy" || s.config.TransparentMode == "redirect"
 
@@ -680,11 +1103,21 @@ func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
 		LogLevel:           s.config.LogLevel,
 		IPv6:               s.config.IPv6,
 		ExternalController: s.config.ExternalController,
+		Secret:             s.config.Secret,
 		EnableDNS:          true,
 		EnhancedMode:       "fake-ip",
 		EnableTProxy:       enableTProxy,
 		TProxyPort:         s.config.TProxyPort,
+		EnableTUN:          s.config.TransparentMode == "tun",
+		RoutingMark:        s.transparentMarks().BypassMark,
 		Template:           s.configTemplate, // 使用配置模板
+		DevicePolicies:     s.resolveDevicePolicies(s.config.DevicePolicies),
+		SpeedtestPort:      s.config.SpeedtestPort,
+		NodeTags:           s.config.NodeTags,
+		NodeRename:         s.config.NodeRename,
+		NodeDedup:          s.config.NodeDedup,
+		ProxyChains:        s.config.ProxyChains,
+		Blocklist:          s.currentBlocklist(),
 	}
 
 	// 从代理设置获取优化配置
@@ -709,54 +1142,54 @@ func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
 			options.GlobalUA = settings.GlobalUA
 			options.ETagSupport = settings.ETagSupport
 
+			// 出站网卡
+			options.InterfaceName = settings.InterfaceName
+
 			// TUN 设置
 			options.TUNSettings = &settings.TUN
 		}
 	}
 
-	var configPath string
-
-	if s.coreType == "singbox" {
-		// 生成 sing-box 1.12+ 配置
-		sbOpts := SingBoxGeneratorOptions{
-			Mode:                     "system",
-			FakeIP:                   options.EnhancedMode == "fake-ip",
-			MixedPort:                options.MixedPort,
-			LogLevel:                 options.LogLevel,
-			Sniff:                    true,
-			SniffOverrideDestination: true,
-		}
-		// Clash API
-		if options.ExternalController != "" {
-			sbOpts.ClashAPIAddr = options.ExternalController
-		} else {
-			sbOpts.ClashAPIAddr = "127.0.0.1:9090"
-		}
+	return options
+}
 
-		config, err := s.singboxGenerator.GenerateConfigV112(nodes, sbOpts)
-		if err != nil {
-			return "", err
-		}
-		path, err := s.singboxGenerator.SaveConfigV112(config, "singbox-config.json")
-		if err != nil {
-			return "", err
-		}
-		configPath = path
+// singboxGeneratorOptions 将通用生成选项转换为 sing-box 1.12+ 生成选项
+func singboxGeneratorOptions(options ConfigGeneratorOptions) SingBoxGeneratorOptions {
+	sbMode := "system"
+	if options.EnableTUN {
+		sbMode = "tun"
+	}
+	sbOpts := SingBoxGeneratorOptions{
+		Mode:                     sbMode,
+		FakeIP:                   options.EnhancedMode == "fake-ip",
+		MixedPort:                options.MixedPort,
+		LogLevel:                 options.LogLevel,
+		Sniff:                    true,
+		SniffOverrideDestination: true,
+		ClashAPISecret:           options.Secret,
+		GroupFilters:             groupNodeFilters(options.Template),
+		ProxyChains:              options.ProxyChains,
+		InterfaceName:            options.InterfaceName,
+	}
+	// 与 Mihomo 一致：透明代理和 TUN 模式下核心出站流量打标记
+	if runtime.GOOS == "linux" && (options.EnableTProxy || options.EnableTUN) {
+		sbOpts.RoutingMark = options.RoutingMark
+	}
+	if options.Template != nil {
+		sbOpts.TemplateDNS = options.Template.DNS
+	}
+	if options.TUNSettings != nil {
+		sbOpts.TUNStack = options.TUNSettings.Stack
+		sbOpts.TUNMTU = options.TUNSettings.MTU
+	}
+	// Clash API
+	if options.ExternalController != "" {
+		sbOpts.ClashAPIAddr = options.ExternalController
 	} else {
-		// 生成 Mihomo/Clash 配置
-		config, err := s.configGenerator.GenerateConfig(nodes, options)
-		if err != nil {
-			return "", err
-		}
-		path, err := s.configGenerator.SaveConfig(config, "config.yaml")
-		if err != nil {
-			return "", err
-		}
-		configPath = path
+		sbOpts.ClashAPIAddr = "127.0.0.1:9090"
 	}
 
-	s.configPath = configPath
-	return configPath, nil
+	return sbOpts
 }
 
 // SetCoreType 设置核心类型
@@ -781,7 +1214,7 @@ func (s *Service) GetConfigGenerator() *ConfigGenerator {
 // loadConfigTemplate 加载配置模板
 func (s *Service) loadConfigTemplate() {
 	templateFile := filepath.Join(s.dataDir, "config_template.json")
-	data, err := os.ReadFile(templateFile)
+	data, err := secure.ReadFile(templateFile)
 	if err != nil {
 		// 文件不存在，使用默认模板
 		return
@@ -789,6 +1222,10 @@ func (s *Service) loadConfigTemplate() {
 	var template ConfigTemplate
 	if err := json.Unmarshal(data, &template); err == nil {
 		s.configTemplate = &template
+		// 旧版本模板的规则没有 ID
+		if ensureRuleIDs(template.Rules) {
+			s.saveConfigTemplate()
+		}
 		// 自动修复旧的英文名称
 		s.fixLegacyProxyNames()
 		// 自动合并新的默认代理组
@@ -891,7 +1328,7 @@ func (s *Service) saveConfigTemplate() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(templateFile, data, 0644)
+	return secure.WriteFile(templateFile, data, 0644)
 }
 
 // GetConfigTemplate 获取配置模板
@@ -902,7 +1339,12 @@ func (s *Service) GetConfigTemplate() *ConfigTemplate {
 }
 
 // UpdateProxyGroups 更新代理组
+// 成员引用不存在或存在循环引用时返回 ProxyGroupErrors
 func (s *Service) UpdateProxyGroups(groups []ProxyGroupTemplate) error {
+	if errs := validateProxyGroups(groups, s.currentNodeNames()); len(errs) > 0 {
+		return errs
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.configTemplate.ProxyGroups = groups
@@ -913,6 +1355,7 @@ func (s *Service) UpdateProxyGroups(groups []ProxyGroupTemplate) error {
 func (s *Service) UpdateRules(rules []RuleTemplate) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	ensureRuleIDs(rules)
 	s.configTemplate.Rules = rules
 	return s.saveConfigTemplate()
 }
@@ -966,6 +1409,7 @@ func (s *Service) ResetConfigTemplate() {
 		s.configTemplate.Rules = newRules
 	}
 
+	ensureRuleIDs(s.configTemplate.Rules)
 	s.saveConfigTemplate()
 }
 
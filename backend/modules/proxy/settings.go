@@ -28,13 +28,22 @@ type ProxySettings struct {
 	AllowLan       bool   `json:"allowLan" yaml:"allow-lan"`              // 允许局域网连接
 	BindAddress    string `json:"bindAddress" yaml:"bind-address"`        // 绑定地址
 	AutoStart      bool   `json:"autoStart" yaml:"auto-start"`            // 开机自动启动代理
-	AutoStartDelay int    `json:"autoStartDelay" yaml:"auto-start-delay"` // 开机启动延迟（秒）
+	AutoStartDelay int    `json:"autoStartDelay" yaml:"auto-start-delay"` // 开机启动前等待网络就绪的最长时间（秒）
+	DrainTimeout   int    `json:"drainTimeout" yaml:"drain-timeout"`      // 停止核心时等待连接关闭的时间（秒），超时后强制结束
 
 	// === 运行模式 ===
 	Mode     string `json:"mode" yaml:"mode"`          // rule/global/direct
 	LogLevel string `json:"logLevel" yaml:"log-level"` // silent/error/warning/info/debug
 	IPv6     bool   `json:"ipv6" yaml:"ipv6"`          // 启用 IPv6
 
+	// === 日志文件 ===
+	LogMaxSize    int `json:"logMaxSize" yaml:"log-max-size"`       // 核心日志文件轮转大小 (MB)
+	LogMaxAge     int `json:"logMaxAge" yaml:"log-max-age"`         // 归档日志保留天数
+	LogMaxBackups int `json:"logMaxBackups" yaml:"log-max-backups"` // 最多保留的归档文件数
+
+	// === 访问日志 ===
+	AccessLog bool `json:"accessLog" yaml:"access-log"` // 记录 ProxyStation 自身 API 的访问日志
+
 	// === 性能优化 ===
 	UnifiedDelay    bool   `json:"unifiedDelay" yaml:"unified-delay"`        // 统一延迟计算
 	TCPConcurrent   bool   `json:"tcpConcurrent" yaml:"tcp-concurrent"`      // TCP 并发连接
@@ -61,8 +70,12 @@ type ProxySettings struct {
 	ETagSupport bool   `json:"etagSupport" yaml:"etag-support"` // ETag 缓存支持
 
 	// === 网络接口 ===
-	InterfaceName string `json:"interfaceName" yaml:"interface-name"` // 出站接口
-	RoutingMark   int    `json:"routingMark" yaml:"routing-mark"`     // 路由标记 (Linux)
+	InterfaceName string `json:"interfaceName" yaml:"interface-name"` // 出站接口，透明代理规则放行经该网卡出站的流量（默认出口除外）
+	RoutingMark   int    `json:"routingMark" yaml:"routing-mark"`     // 路由标记 (Linux)，为 0 时透明代理使用 255
+
+	// === 透明代理标记 ===
+	TransparentMark  int `json:"transparentMark" yaml:"transparent-mark"`   // tproxy 流量标记 (fwmark)
+	TransparentTable int `json:"transparentTable" yaml:"transparent-table"` // tproxy 策略路由表
 
 	// === DNS 设置 ===
 	DNS DNSSettings `json:"dns" yaml:"dns"`
@@ -72,6 +85,9 @@ type ProxySettings struct {
 
 	// === 嗅探设置 ===
 	Sniffer SnifferSettings `json:"sniffer" yaml:"sniffer"`
+
+	// === 管理界面 ===
+	Web WebSettings `json:"web" yaml:"web"`
 }
 
 // DNSSettings DNS 设置
@@ -100,6 +116,20 @@ type DNSSettings struct {
 	DirectNameserver      []string `json:"directNameserver" yaml:"direct-nameserver"`
 }
 
+// WebSettings 管理界面和 API 的监听设置，修改后重启 ProxyStation 生效
+type WebSettings struct {
+	ListenAddress    string `json:"listenAddress" yaml:"listen-address"`        // 监听地址，为空时使用 config.yaml 中的 server.host
+	Port             int    `json:"port" yaml:"port"`                           // 监听端口，0 时使用 config.yaml 中的 server.port
+	TLS              bool   `json:"tls" yaml:"tls"`                             // 启用 HTTPS
+	CertFile         string `json:"certFile" yaml:"cert-file"`                  // 证书路径 (PEM)，为空时自动生成自签名证书
+	KeyFile          string `json:"keyFile" yaml:"key-file"`                    // 私钥路径 (PEM)
+	HTTPRedirectPort int    `json:"httpRedirectPort" yaml:"http-redirect-port"` // 同时监听的 HTTP 端口，请求重定向到 HTTPS，0 不监听
+
+	// 访问控制，本机访问不受限制
+	AllowedCIDRs []string `json:"allowedCidrs" yaml:"allowed-cidrs"`  // 允许访问的来源地址（CIDR 或 IP），为空不限制，保存后立即生效
+	ClientCAFile string   `json:"clientCaFile" yaml:"client-ca-file"` // 客户端证书 CA (PEM)，设置后要求客户端证书（需启用 HTTPS）
+}
+
 // TUNSettings TUN 设置
 type TUNSettings struct {
 	Enable              bool     `json:"enable" yaml:"enable"`
@@ -160,12 +190,18 @@ func GetDefaultProxySettings() *ProxySettings {
 		BindAddress:    "*",
 		AutoStart:      false,
 		AutoStartDelay: 15, // 默认延迟15秒启动
+		DrainTimeout:   5,
 
 		// 运行模式
 		Mode:     "rule",
 		LogLevel: "info",
 		IPv6:     false,
 
+		// 日志文件
+		LogMaxSize:    10,
+		LogMaxAge:     7,
+		LogMaxBackups: 10,
+
 		// 性能优化
 		UnifiedDelay:    true,  // 更准确的延迟测试
 		TCPConcurrent:   true,  // 并发连接，使用最快的 IP
@@ -195,6 +231,10 @@ func GetDefaultProxySettings() *ProxySettings {
 		InterfaceName: "",
 		RoutingMark:   0,
 
+		// 透明代理标记
+		TransparentMark:  defaultTransparentMark,
+		TransparentTable: defaultTransparentTable,
+
 		// DNS 设置
 		DNS: DNSSettings{
 			Enable:           true,
@@ -7,6 +7,9 @@ import (
 	"path/filepath"
 	"sync"
 
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/secure"
+
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v3"
 )
@@ -17,6 +20,9 @@ type SettingsHandler struct {
 	settings     *ProxySettings
 	mu           sync.RWMutex
 	proxyService *Service // 代理服务引用，用于同步配置
+
+	// 设置变更回调（同步访问日志开关等非核心配置）
+	onChange func(settings *ProxySettings)
 }
 
 // NewSettingsHandler 创建设置处理器
@@ -35,6 +41,11 @@ func (h *SettingsHandler) SetProxyService(s *Service) {
 	h.proxyService = s
 }
 
+// SetOnChange 设置变更回调，更新或重置设置后调用
+func (h *SettingsHandler) SetOnChange(callback func(settings *ProxySettings)) {
+	h.onChange = callback
+}
+
 // RegisterRoutes 注册路由
 func (h *SettingsHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/settings", h.GetSettings)
@@ -52,7 +63,7 @@ func (h *SettingsHandler) loadSettings() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	data, err := os.ReadFile(h.settingsFilePath())
+	data, err := secure.ReadFile(h.settingsFilePath())
 	if err != nil {
 		if os.IsNotExist(err) {
 			// 文件不存在，使用默认设置
@@ -70,6 +81,24 @@ func (h *SettingsHandler) loadSettings() error {
 	if settings.AutoStartDelay == 0 {
 		settings.AutoStartDelay = 15 // 默认延迟 15 秒
 	}
+	if settings.DrainTimeout == 0 {
+		settings.DrainTimeout = 5
+	}
+	if settings.LogMaxSize == 0 {
+		settings.LogMaxSize = 10
+	}
+	if settings.LogMaxAge == 0 {
+		settings.LogMaxAge = 7
+	}
+	if settings.LogMaxBackups == 0 {
+		settings.LogMaxBackups = 10
+	}
+	if settings.TransparentMark == 0 {
+		settings.TransparentMark = defaultTransparentMark
+	}
+	if settings.TransparentTable == 0 {
+		settings.TransparentTable = defaultTransparentTable
+	}
 
 	h.settings = &settings
 	return nil
@@ -88,7 +117,7 @@ func (h *SettingsHandler) saveSettings() error {
 		return err
 	}
 
-	return os.WriteFile(h.settingsFilePath(), data, 0644)
+	return secure.WriteFile(h.settingsFilePath(), data, 0644)
 }
 
 // GetSettings 获取当前设置
@@ -106,10 +135,19 @@ func (h *SettingsHandler) GetSettings(c *gin.Context) {
 func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 	var settings ProxySettings
 	if err := c.ShouldBindJSON(&settings); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": "Invalid settings: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusBadRequest, "Invalid settings: "+err.Error())
+		return
+	}
+	if err := validateTransparentMarks(&settings); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "Invalid settings: "+err.Error())
+		return
+	}
+	if err := validateWebSettings(&settings.Web); err != nil {
+		apierror.Message(c, http.StatusBadRequest, "Invalid settings: "+err.Error())
+		return
+	}
+	if !allowsRemote(settings.Web.AllowedCIDRs, c.RemoteIP()) {
+		apierror.Message(c, http.StatusBadRequest, "Invalid settings: 来源地址白名单不包含当前客户端地址 "+c.RemoteIP())
 		return
 	}
 
@@ -119,10 +157,7 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 	h.mu.Unlock()
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "Failed to save settings: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
 		return
 	}
 
@@ -133,6 +168,9 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 			"autoStartDelay": float64(settings.AutoStartDelay),
 		})
 	}
+	if h.onChange != nil {
+		h.onChange(&settings)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -148,12 +186,12 @@ func (h *SettingsHandler) ResetSettings(c *gin.Context) {
 	h.mu.Unlock()
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "Failed to save settings: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
 		return
 	}
+	if h.onChange != nil {
+		h.onChange(GetDefaultProxySettings())
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+
+	"ProxyStation/backend/modules/system"
+)
+
+// defaultDrainTimeout 停止核心时等待现有连接关闭的默认时间
+const defaultDrainTimeout = 5 * time.Second
+
+// drainTimeout 从代理设置读取优雅停止等待时间
+func (s *Service) drainTimeout() time.Duration {
+	if s.settingsProvider != nil {
+		if settings := s.settingsProvider(); settings != nil && settings.DrainTimeout > 0 {
+			return time.Duration(settings.DrainTimeout) * time.Second
+		}
+	}
+	return defaultDrainTimeout
+}
+
+// terminateProcess 先发送 SIGTERM 让核心关闭连接，超时后强制结束
+// Windows 不支持 SIGTERM，直接结束进程
+func (s *Service) terminateProcess(cmd *exec.Cmd, done <-chan struct{}) error {
+	if runtime.GOOS == "windows" {
+		if err := cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-done
+		return nil
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		// 进程可能已经退出
+		select {
+		case <-done:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	timeout := s.drainTimeout()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		fmt.Printf("⚠️ 核心未在 %v 内退出，强制结束\n", timeout)
+		if err := cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-done
+		return nil
+	}
+}
+
+// Shutdown 后端退出时停止核心、清理透明代理规则并保存内存中的数据
+func (h *Handler) Shutdown() {
+	wasRunning := h.service.GetStatus().Running
+	// Stop 同时会取消等待中的崩溃重启
+	if err := h.service.Stop(); err != nil {
+		fmt.Printf("⚠️ 停止代理核心失败: %v\n", err)
+	} else {
+		fmt.Println("✓ 代理核心已停止")
+	}
+
+	// 核心未运行时 Stop 不会触发停止回调，这里确保不遗留规则
+	if !wasRunning && runtime.GOOS == "linux" && isTransparentMode(h.service.GetConfig().TransparentMode) {
+		h.clearNftRules()
+		fmt.Println("✓ nftables 规则已清除")
+	}
+	// 崩溃重启等待期间被停止等情况下遗留的系统代理设置
+	if system.HasProxyBackup() {
+		if err := system.ClearSystemProxy(); err != nil {
+			fmt.Printf("⚠️ 恢复系统代理失败: %v\n", err)
+		}
+	}
+
+	if err := h.stats.flush(); err != nil {
+		fmt.Printf("⚠️ 保存流量统计失败: %v\n", err)
+	}
+	if err := h.ruleStats.flush(); err != nil {
+		fmt.Printf("⚠️ 保存规则命中统计失败: %v\n", err)
+	}
+	h.service.logFile.Close()
+}
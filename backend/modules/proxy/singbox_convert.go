@@ -0,0 +1,353 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	singBoxGeositeRuleSetURL = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set"
+	singBoxGeoIPRuleSetURL   = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set"
+)
+
+// metaRulesPathRe MetaCubeX meta-rules-dat 规则集路径，如 /geosite/google.mrs
+var metaRulesPathRe = regexp.MustCompile(`/(geosite|geoip)/([^/]+)\.(mrs|yaml|list|txt)$`)
+
+// SingBoxConvertResult Mihomo 模板转换结果
+type SingBoxConvertResult struct {
+	Template *SingBoxTemplate `json:"template"`
+	Warnings []string         `json:"warnings"`
+}
+
+// singBoxConverter 将 Mihomo 模板转换为 Sing-Box 模板
+type singBoxConverter struct {
+	generator *ConfigGenerator
+	tagger    *NodeTagger
+	providers map[string]RuleProvider
+	template  *SingBoxTemplate
+	ruleSets  map[string]bool
+	warnings  []string
+}
+
+func (c *singBoxConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// ConvertSingBoxTemplateFromClash 按当前 Mihomo 模板（代理组、规则、规则集）生成 Sing-Box 模板
+// preview 为 true 时只返回转换结果，否则覆盖保存 Sing-Box 模板
+func (s *Service) ConvertSingBoxTemplateFromClash(preview bool) (*SingBoxConvertResult, error) {
+	s.mu.RLock()
+	template := s.configTemplate
+	s.mu.RUnlock()
+	if template == nil {
+		template = GetDefaultConfigTemplate()
+	}
+
+	c := &singBoxConverter{
+		generator: s.configGenerator,
+		tagger:    NewNodeTagger(s.GetNodeTags()),
+		providers: mergeTemplateRuleProviders(s.configGenerator.generateRuleProviders(), template.RuleProviders),
+		template:  &SingBoxTemplate{},
+		ruleSets:  make(map[string]bool),
+		warnings:  []string{},
+	}
+	c.convertGroups(template.ProxyGroups)
+	c.convertRules(template.Rules)
+
+	result := &SingBoxConvertResult{Template: c.template, Warnings: c.warnings}
+	if preview {
+		return result, nil
+	}
+	if err := SaveSingBoxTemplate(s.dataDir, c.template); err != nil {
+		return nil, err
+	}
+	fmt.Printf("🔄 已从 Mihomo 模板生成 Sing-Box 模板: %d 个代理组, %d 条规则, %d 个规则集, %d 条警告\n",
+		len(c.template.ProxyGroups), len(c.template.Rules), len(c.template.RuleSets), len(c.warnings))
+	return result, nil
+}
+
+// convertGroups 转换代理组，UseAll 的代理组留空 outbounds 由生成器按节点填充
+func (c *singBoxConverter) convertGroups(groups []ProxyGroupTemplate) {
+	for _, g := range groups {
+		sg := SingBoxProxyGroupTemplate{
+			Tag:         g.Name,
+			Name:        g.Name,
+			Description: g.Description,
+			Icon:        g.Icon,
+			Enabled:     isGroupTemplateActive(g),
+			Outbounds:   []string{},
+			URL:         g.URL,
+			Tolerance:   g.Tolerance,
+
+			ExcludeFilter: g.ExcludeFilter,
+			MaxMultiplier: g.MaxMultiplier,
+		}
+		if g.Interval > 0 {
+			sg.Interval = formatSingBoxInterval(g.Interval)
+		}
+
+		switch g.Type {
+		case "select":
+			sg.Type = "selector"
+		case "url-test":
+			sg.Type = "urltest"
+		case "fallback":
+			sg.Type = "urltest"
+			c.warn("代理组 %s: Sing-Box 不支持 %s，已转换为 urltest", g.Name, g.Type)
+		case "load-balance":
+			// Sing-Box 没有负载均衡出站，转换为 urltest 并保留健康检查设置
+			sg.Type = "urltest"
+			if sg.URL == "" {
+				sg.URL = defaultHealthCheckURL
+			}
+			if sg.Interval == "" {
+				sg.Interval = formatSingBoxInterval(defaultHealthCheckInterval)
+			}
+			strategy := g.Strategy
+			if strategy == "" {
+				strategy = defaultLoadBalanceStrategy
+			}
+			c.warn("代理组 %s: Sing-Box 不支持负载均衡（%s），已转换为 urltest", g.Name, strategy)
+		default:
+			c.warn("代理组 %s: Sing-Box 不支持 %s 类型，已跳过", g.Name, g.Type)
+			continue
+		}
+
+		if len(g.Tags) > 0 {
+			// Sing-Box 模板只支持正则过滤，标签转换为等价的正则
+			sg.Filter = c.tagger.tagFilterPattern(g.Tags)
+			if sg.Filter == "" {
+				c.warn("代理组 %s: 标签 %s 没有对应的匹配规则，已跳过过滤", g.Name, strings.Join(g.Tags, ", "))
+			}
+		} else if g.UseAll {
+			if g.Filter != "__MANUAL__" {
+				sg.Filter = g.Filter
+			}
+		} else {
+			for _, member := range g.Proxies {
+				if outbound := singBoxOutboundName(member); outbound != "" {
+					sg.Outbounds = append(sg.Outbounds, outbound)
+				}
+			}
+		}
+		c.template.ProxyGroups = append(c.template.ProxyGroups, sg)
+	}
+}
+
+// singBoxOutboundName Mihomo 内置策略对应的 Sing-Box 出站，PASS 等无对应出站时返回空
+func singBoxOutboundName(name string) string {
+	switch name {
+	case "DIRECT":
+		return "direct"
+	case "REJECT", "REJECT-DROP":
+		return "block"
+	case "PASS", "COMPATIBLE":
+		return ""
+	}
+	return name
+}
+
+// formatSingBoxInterval 秒数转换为 Sing-Box 时长字符串
+func formatSingBoxInterval(seconds int) string {
+	if seconds%60 == 0 {
+		return strconv.Itoa(seconds/60) + "m"
+	}
+	return strconv.Itoa(seconds) + "s"
+}
+
+// convertRules 转换规则，连续的同目标规则集规则合并为一条
+func (c *singBoxConverter) convertRules(rules []RuleTemplate) {
+	for _, r := range rules {
+		if r.Disabled {
+			continue
+		}
+		line := r.Type + "," + r.Payload + "," + r.Proxy
+
+		if r.Type == "MATCH" {
+			c.template.Final = singBoxOutboundName(r.Proxy)
+			break
+		}
+
+		rule, ok := c.convertRule(r)
+		if !ok {
+			continue
+		}
+		switch r.Proxy {
+		case "REJECT", "REJECT-DROP":
+			rule.Action = "reject"
+		case "PASS", "COMPATIBLE":
+			c.warn("规则 %s: Sing-Box 没有对应的策略，已跳过", line)
+			continue
+		default:
+			rule.Outbound = singBoxOutboundName(r.Proxy)
+		}
+
+		if c.mergeRuleSet(rule) {
+			continue
+		}
+		c.template.Rules = append(c.template.Rules, rule)
+	}
+}
+
+// convertRule 转换单条规则的匹配条件
+func (c *singBoxConverter) convertRule(r RuleTemplate) (SingBoxRuleTemplate, bool) {
+	var rule SingBoxRuleTemplate
+	line := r.Type + "," + r.Payload + "," + r.Proxy
+
+	switch r.Type {
+	case "DOMAIN":
+		rule.Domain = []string{r.Payload}
+	case "DOMAIN-SUFFIX":
+		rule.DomainSuffix = []string{r.Payload}
+	case "DOMAIN-KEYWORD":
+		rule.DomainKeyword = []string{r.Payload}
+	case "DOMAIN-REGEX":
+		rule.DomainRegex = []string{r.Payload}
+	case "IP-CIDR", "IP-CIDR6":
+		rule.IPCIDR = []string{r.Payload}
+	case "SRC-IP-CIDR":
+		rule.SourceIPCIDR = []string{r.Payload}
+	case "PROCESS-NAME":
+		rule.ProcessName = []string{r.Payload}
+	case "NETWORK":
+		rule.Network = strings.ToLower(r.Payload)
+	case "DST-PORT":
+		for _, part := range strings.Split(r.Payload, "/") {
+			if port, err := strconv.Atoi(part); err == nil {
+				rule.Port = appendPort(rule.Port, port)
+			} else {
+				rule.PortRange = append(rule.PortRange, strings.Replace(part, "-", ":", 1))
+			}
+		}
+	case "GEOIP":
+		if strings.EqualFold(r.Payload, "LAN") || strings.EqualFold(r.Payload, "private") {
+			rule.IPIsPrivate = true
+		} else {
+			rule.RuleSet = c.addRuleSet("geoip-"+strings.ToLower(r.Payload), singBoxGeoIPRuleSetURL)
+		}
+	case "GEOSITE":
+		rule.RuleSet = c.addRuleSet("geosite-"+strings.ToLower(r.Payload), singBoxGeositeRuleSetURL)
+	case "RULE-SET":
+		tag, ok := c.ruleSetTag(r.Payload)
+		if !ok {
+			c.warn("规则 %s: 规则集无法转换为 Sing-Box 规则集，已跳过", line)
+			return rule, false
+		}
+		if tag == "geoip-private" {
+			rule.IPIsPrivate = true
+		} else {
+			rule.RuleSet = tag
+		}
+	default:
+		c.warn("规则 %s: Sing-Box 模板不支持 %s 规则，已跳过", line, r.Type)
+		return rule, false
+	}
+	return rule, true
+}
+
+// appendPort 追加端口，单个端口保持 int 形式
+func appendPort(current interface{}, port int) interface{} {
+	switch v := current.(type) {
+	case int:
+		return []int{v, port}
+	case []int:
+		return append(v, port)
+	}
+	return port
+}
+
+// ruleSetTag Mihomo 规则集对应的 Sing-Box 规则集标签（按 meta-rules-dat 路径映射到 SagerNet 规则集）
+func (c *singBoxConverter) ruleSetTag(name string) (string, bool) {
+	sourceURL := c.generator.ruleProviderSourceURL(name)
+	if sourceURL == "" {
+		sourceURL = c.providers[name].URL
+	}
+	m := metaRulesPathRe.FindStringSubmatch(sourceURL)
+	if m == nil {
+		return "", false
+	}
+	tag := m[1] + "-" + m[2]
+	if tag == "geoip-private" {
+		return tag, true
+	}
+	baseURL := singBoxGeositeRuleSetURL
+	if m[1] == "geoip" {
+		baseURL = singBoxGeoIPRuleSetURL
+	}
+	return c.addRuleSet(tag, baseURL), true
+}
+
+// addRuleSet 登记 Sing-Box 规则集，本地已下载时使用本地文件
+func (c *singBoxConverter) addRuleSet(tag, baseURL string) string {
+	if c.ruleSets[tag] {
+		return tag
+	}
+	c.ruleSets[tag] = true
+
+	localPath := GetSingBoxRulesetDir() + "/" + tag + ".srs"
+	if fileExists(localPath) {
+		c.template.RuleSets = append(c.template.RuleSets, SingBoxRuleSetTemplate{
+			Tag:    tag,
+			Type:   "local",
+			Format: "binary",
+			Path:   localPath,
+		})
+	} else {
+		c.template.RuleSets = append(c.template.RuleSets, SingBoxRuleSetTemplate{
+			Tag:    tag,
+			Type:   "remote",
+			Format: "binary",
+			URL:    baseURL + "/" + tag + ".srs",
+		})
+	}
+	return tag
+}
+
+// mergeRuleSet 与上一条同目标的纯规则集规则合并，返回是否已合并
+func (c *singBoxConverter) mergeRuleSet(rule SingBoxRuleTemplate) bool {
+	tag, ok := rule.RuleSet.(string)
+	if !ok || len(c.template.Rules) == 0 || !isRuleSetOnly(rule) {
+		return false
+	}
+	last := &c.template.Rules[len(c.template.Rules)-1]
+	if !isRuleSetOnly(*last) || last.Outbound != rule.Outbound || last.Action != rule.Action {
+		return false
+	}
+	switch v := last.RuleSet.(type) {
+	case string:
+		last.RuleSet = []string{v, tag}
+	case []string:
+		last.RuleSet = append(v, tag)
+	}
+	return true
+}
+
+// isRuleSetOnly 规则是否只有规则集匹配条件
+func isRuleSetOnly(rule SingBoxRuleTemplate) bool {
+	return rule.RuleSet != nil && rule.Domain == nil && rule.DomainSuffix == nil && rule.DomainKeyword == nil &&
+		rule.DomainRegex == nil && rule.IPCIDR == nil && rule.SourceIPCIDR == nil && rule.ProcessName == nil &&
+		rule.Network == "" && rule.Port == nil && rule.PortRange == nil && !rule.IPIsPrivate
+}
+
+// ConvertSingBoxTemplateFromClash 从 Mihomo 模板生成 Sing-Box 模板
+// ?preview=true 时只返回转换结果不保存
+func (h *Handler) ConvertSingBoxTemplateFromClash(c *gin.Context) {
+	result, err := h.service.ConvertSingBoxTemplateFromClash(c.Query("preview") == "true")
+	if err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "保存失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
@@ -616,28 +616,19 @@ func (g *SingboxGenerator) GenerateConfigV112(nodes []ProxyNode, opts SingBoxGen
 		config = GetSingBoxSystemTemplate(opts)
 	}
 
-	// 转换节点为 outbounds，并收集手动节点名称
+	// 转换节点为 outbounds
 	nodeOutbounds := make([]SBOutbound, 0, len(nodes))
-	manualNodeNames := make([]string, 0)
 	for _, node := range nodes {
 		outbound, err := ParseNodeToSingBox(node)
 		if err != nil {
 			continue // 跳过无法解析的节点
 		}
 		nodeOutbounds = append(nodeOutbounds, *outbound)
-		// 收集手动节点名称（与 Mihomo 一致）
-		if node.IsManual {
-			manualNodeNames = append(manualNodeNames, outbound.Tag)
-		}
 	}
 
-	// 生成代理组（传入手动节点名称列表）
-	proxyGroups := g.generateProxyGroupsV112(nodeOutbounds, manualNodeNames)
-
 	// 组合所有 outbounds
-	// 顺序: 代理组 -> 节点 -> 特殊出站(direct/block/dns-out)
-	allOutbounds := make([]SBOutbound, 0)
-	allOutbounds = append(allOutbounds, proxyGroups...)
+	// 顺序: 节点 -> 特殊出站(direct/block/dns-out)，代理组由配置生成管线添加到最前面
+	allOutbounds := make([]SBOutbound, 0, len(nodeOutbounds)+3)
 	allOutbounds = append(allOutbounds, nodeOutbounds...)
 	// 添加内置出站 (Sing-Box 内置 direct/block)
 	allOutbounds = append(allOutbounds,
@@ -647,14 +638,16 @@ func (g *SingboxGenerator) GenerateConfigV112(nodes []ProxyNode, opts SingBoxGen
 	)
 
 	config.Outbounds = allOutbounds
+	applySingBoxTemplateDNS(config.DNS, opts.TemplateDNS)
 
 	// 添加路由规则
 	config.Route.Rules = GetDefaultRouteRules()
 	config.Route.RuleSet = GetDefaultRuleSets()
 
+	// 代理组、链式代理、出站标记、广告拦截、设备策略由配置生成管线添加（见 config_pipeline.go）
 	return config, nil
 }
-func (g *SingboxGenerator) generateProxyGroupsV112(nodes []SBOutbound, manualNodeNames []string) []SBOutbound {
+func (g *SingboxGenerator) generateProxyGroupsV112(nodes []SBOutbound, manualNodeNames []string, filters map[string]nodeFilter) []SBOutbound {
 	// 地区过滤关键字 (与 Mihomo 保持一致)
 	regionFilters := map[string][]string{
 		"HongKong":  {"🇭🇰", "HK", "hk", "香港", "港", "HongKong", "Hong Kong", "HONG KONG", "沪港", "呼港", "中港", "HKT", "HKBN", "HGC", "WTT", "CMI", "穗港", "广港", "京港"},
@@ -748,6 +741,26 @@ func (g *SingboxGenerator) generateProxyGroupsV112(nodes []SBOutbound, manualNod
 		}
 	}
 
+	// 按模板中同名分组的过滤条件筛选节点（代理组和内置出站不受影响）
+	nodeSet := make(map[string]bool, len(allNodeTags))
+	for _, tag := range allNodeTags {
+		nodeSet[tag] = true
+	}
+	for i := range groups {
+		filter, ok := filters[groups[i].Tag]
+		if !ok {
+			continue
+		}
+		filtered := make([]string, 0, len(groups[i].Outbounds))
+		for _, out := range groups[i].Outbounds {
+			if nodeSet[out] && !filter.allows(out) {
+				continue
+			}
+			filtered = append(filtered, out)
+		}
+		groups[i].Outbounds = filtered
+	}
+
 	// 过滤掉没有有效 outbounds 的组
 	validGroups := make([]SBOutbound, 0)
 	removedTags := make(map[string]bool)
@@ -785,28 +798,3 @@ func matchesKeywords(name string, keywords []string) bool {
 	}
 	return false
 }
-
-// SaveConfigV112 保存 1.12+ 配置到文件
-func (g *SingboxGenerator) SaveConfigV112(config *SingBoxConfig, filename string) (string, error) {
-	configDir := filepath.Join(g.dataDir, "configs")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-
-	if !strings.HasSuffix(filename, ".json") {
-		filename += ".json"
-	}
-
-	filePath := filepath.Join(configDir, filename)
-
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return "", err
-	}
-
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", err
-	}
-
-	return filePath, nil
-}
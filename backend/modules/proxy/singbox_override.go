@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// singboxOverridePath Sing-Box 覆写文件路径
+func singboxOverridePath(dataDir string) string {
+	return filepath.Join(dataDir, "singbox_override.json")
+}
+
+// parseSingBoxOverride 解析 Sing-Box 覆写内容，必须是 JSON 对象
+func parseSingBoxOverride(content string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var patch map[string]interface{}
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, fmt.Errorf("覆写内容必须是 JSON 对象: %v", err)
+	}
+
+	// Clash API 的地址和密钥由后端管理
+	if experimental, ok := patch["experimental"].(map[string]interface{}); ok {
+		if clashAPI, ok := experimental["clash_api"].(map[string]interface{}); ok {
+			for _, key := range []string{"external_controller", "secret"} {
+				if _, exists := clashAPI[key]; exists {
+					return nil, fmt.Errorf("不允许覆写 experimental.clash_api.%s，请在代理配置中修改", key)
+				}
+			}
+		}
+	}
+	return patch, nil
+}
+
+// mergeJSONPatch 按 JSON Merge Patch（RFC 7386）合并，值为 null 时删除字段
+// 额外支持 prepend_<key> / append_<key>，在同一层的数组前后插入元素（如 append_outbounds、route.prepend_rules）
+func mergeJSONPatch(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if listKey, ok := strings.CutPrefix(key, "prepend_"); ok {
+			if items, isList := value.([]interface{}); isList {
+				existing, _ := target[listKey].([]interface{})
+				target[listKey] = append(append([]interface{}{}, items...), existing...)
+				continue
+			}
+		}
+		if listKey, ok := strings.CutPrefix(key, "append_"); ok {
+			if items, isList := value.([]interface{}); isList {
+				existing, _ := target[listKey].([]interface{})
+				target[listKey] = append(existing, items...)
+				continue
+			}
+		}
+
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchObj, isObj := value.(map[string]interface{})
+		targetObj, targetIsObj := target[key].(map[string]interface{})
+		if isObj && targetIsObj {
+			mergeJSONPatch(targetObj, patchObj)
+			continue
+		}
+		target[key] = value
+	}
+}
+
+// applySingBoxOverrideFile 合并 Sing-Box 覆写文件，未启用覆写时原样返回
+func applySingBoxOverrideFile(dataDir string, data []byte) ([]byte, error) {
+	override := loadConfigOverride(singboxOverridePath(dataDir))
+	if !override.Enabled || strings.TrimSpace(override.Content) == "" {
+		return data, nil
+	}
+	patch, err := parseSingBoxOverride(override.Content)
+	if err != nil {
+		return nil, fmt.Errorf("配置覆写无效: %w", err)
+	}
+	if data, err = applySingBoxOverride(data, patch); err != nil {
+		return nil, fmt.Errorf("合并配置覆写失败: %w", err)
+	}
+	return data, nil
+}
+
+// applySingBoxOverride 将覆写合并到已渲染的 Sing-Box 配置中
+func applySingBoxOverride(data []byte, patch map[string]interface{}) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var config map[string]interface{}
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	mergeJSONPatch(config, patch)
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// GetSingBoxOverride 获取 Sing-Box 配置覆写
+func (s *Service) GetSingBoxOverride() *ConfigOverride {
+	return loadConfigOverride(singboxOverridePath(s.dataDir))
+}
+
+// SetSingBoxOverride 保存 Sing-Box 配置覆写，下次生成配置时生效
+func (s *Service) SetSingBoxOverride(override ConfigOverride) error {
+	if strings.TrimSpace(override.Content) != "" {
+		if _, err := parseSingBoxOverride(override.Content); err != nil {
+			return err
+		}
+	}
+	return saveConfigOverride(singboxOverridePath(s.dataDir), override)
+}
+
+// GetSingBoxOverride 获取 Sing-Box 配置覆写
+func (h *Handler) GetSingBoxOverride(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetSingBoxOverride(),
+	})
+}
+
+// UpdateSingBoxOverride 保存 Sing-Box 配置覆写
+func (h *Handler) UpdateSingBoxOverride(c *gin.Context) {
+	var override ConfigOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.SetSingBoxOverride(override); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "覆写已保存，重新生成配置后生效",
+		"data":    h.service.GetSingBoxOverride(),
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -278,7 +280,7 @@ func handleGetRuleSetConfig(c *gin.Context) {
 func handleSaveRuleSetConfig(c *gin.Context) {
 	var config RuleSetConfig
 	if err := c.ShouldBindJSON(&config); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -289,7 +291,7 @@ func handleSaveRuleSetConfig(c *gin.Context) {
 	currentConfig.CustomProxies = config.CustomProxies
 
 	if err := saveRuleSetConfig(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -356,13 +358,13 @@ func handleUpdateRuleSets(c *gin.Context) {
 		GitHubProxy string `json:"githubProxy"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	// 确保目录存在
 	if err := InitSingBoxRulesetDir(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -468,13 +470,13 @@ func handleDownloadRuleSet(c *gin.Context) {
 		GitHubProxy string `json:"githubProxy"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	// 确保目录存在
 	if err := InitSingBoxRulesetDir(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -500,7 +502,7 @@ func handleDownloadRuleSet(c *gin.Context) {
 	}
 
 	if url == "" || path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则集"})
+		apierror.Message(c, http.StatusBadRequest, "无效的规则集")
 		return
 	}
 
@@ -23,13 +23,33 @@ type SingBoxProxyGroupTemplate struct {
 	URL         string   `json:"url,omitempty"`
 	Interval    string   `json:"interval,omitempty"`
 	Tolerance   int      `json:"tolerance,omitempty"`
+	Filter      string   `json:"filter,omitempty"` // 节点过滤正则，outbounds 为空时按节点动态填充
+
+	ExcludeFilter string  `json:"excludeFilter,omitempty"` // 排除节点的正则
+	MaxMultiplier float64 `json:"maxMultiplier,omitempty"` // 排除倍率高于该值的节点，0 表示不限制
 }
 
 // SingBoxRuleTemplate Sing-Box 规则模板
 type SingBoxRuleTemplate struct {
-	RuleSet  interface{} `json:"rule_set,omitempty"` // string or []string
-	Outbound string      `json:"outbound,omitempty"`
-	Action   string      `json:"action,omitempty"`
+	Type          string                `json:"type,omitempty"` // logical
+	Mode          string                `json:"mode,omitempty"` // and, or
+	Rules         []SingBoxRuleTemplate `json:"rules,omitempty"`
+	Protocol      interface{}           `json:"protocol,omitempty"` // string or []string
+	Network       string                `json:"network,omitempty"`
+	Port          interface{}           `json:"port,omitempty"` // int or []int
+	PortRange     []string              `json:"port_range,omitempty"`
+	Domain        []string              `json:"domain,omitempty"`
+	DomainSuffix  []string              `json:"domain_suffix,omitempty"`
+	DomainKeyword []string              `json:"domain_keyword,omitempty"`
+	DomainRegex   []string              `json:"domain_regex,omitempty"`
+	IPCIDR        []string              `json:"ip_cidr,omitempty"`
+	IPIsPrivate   bool                  `json:"ip_is_private,omitempty"`
+	SourceIPCIDR  []string              `json:"source_ip_cidr,omitempty"`
+	ProcessName   []string              `json:"process_name,omitempty"`
+	RuleSet       interface{}           `json:"rule_set,omitempty"` // string or []string
+	ClashMode     string                `json:"clash_mode,omitempty"`
+	Outbound      string                `json:"outbound,omitempty"`
+	Action        string                `json:"action,omitempty"`
 }
 
 // SingBoxRuleSetTemplate Sing-Box 规则集模板
@@ -46,6 +66,7 @@ type SingBoxTemplate struct {
 	ProxyGroups []SingBoxProxyGroupTemplate `json:"proxyGroups"`
 	Rules       []SingBoxRuleTemplate       `json:"rules"`
 	RuleSets    []SingBoxRuleSetTemplate    `json:"ruleSets"`
+	Final       string                      `json:"final,omitempty"` // 未匹配流量的出站
 }
 
 // GetSingBoxTUNTemplate 获取 TUN 模式配置模板
@@ -68,6 +68,9 @@ type SBDNSServer struct {
 	ServerPort   int    `json:"server_port,omitempty"` // DNS 服务器端口 (sing-box 1.12+)
 	Detour       string `json:"detour,omitempty"`      // 已弃用，保留兼容
 	ClientSubnet string `json:"client_subnet,omitempty"`
+	Path         string `json:"path,omitempty"` // DoH 路径（默认 /dns-query）
+	// hosts 专用
+	Predefined map[string][]string `json:"predefined,omitempty"`
 	// FakeIP 专用
 	Inet4Range string `json:"inet4_range,omitempty"`
 	Inet6Range string `json:"inet6_range,omitempty"`
@@ -204,6 +207,11 @@ type SBOutbound struct {
 	Transport *SBTransport `json:"transport,omitempty"`
 	Multiplex *SBMultiplex `json:"multiplex,omitempty"`
 
+	// ===== Dial 字段 =====
+	Detour        string `json:"detour,omitempty"`         // 经由该出站连接（链式代理）
+	BindInterface string `json:"bind_interface,omitempty"` // 绑定出站网卡
+	RoutingMark   int    `json:"routing_mark,omitempty"`   // 出站流量标记（Linux）
+
 	// ===== Dial 性能优化字段 =====
 	TCPFastOpen  bool `json:"tcp_fast_open,omitempty"`
 	TCPMultiPath bool `json:"tcp_multi_path,omitempty"`
@@ -269,6 +277,7 @@ type SBRoute struct {
 	Final                 string            `json:"final,omitempty"`
 	AutoDetectInterface   bool              `json:"auto_detect_interface,omitempty"`
 	DefaultInterface      string            `json:"default_interface,omitempty"`
+	DefaultMark           int               `json:"default_mark,omitempty"`
 	DefaultDomainResolver *SBDomainResolver `json:"default_domain_resolver,omitempty"`
 }
 
@@ -359,4 +368,17 @@ type SingBoxGeneratorOptions struct {
 
 	// 日志
 	LogLevel string `json:"logLevel"`
+
+	// 代理组节点过滤条件（来自 Mihomo 模板中的同名分组）
+	GroupFilters map[string]nodeFilter `json:"-"`
+
+	// 链式代理（生成为 detour 链）
+	ProxyChains []ProxyChain `json:"-"`
+
+	// 模板中的自定义 DNS 设置
+	TemplateDNS *DNSTemplate `json:"-"`
+
+	// 核心出站流量标记和绑定网卡（与 Mihomo routing-mark / interface-name 一致）
+	RoutingMark   int    `json:"routingMark"`
+	InterfaceName string `json:"interfaceName"`
 }
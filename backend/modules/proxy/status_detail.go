@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configDriftTTL 配置漂移检查结果的缓存时间（状态接口会被频繁轮询，生成配置的开销较大）
+const configDriftTTL = 30 * time.Second
+
+// ActiveConfigStatus 当前生效配置文件的状态
+type ActiveConfigStatus struct {
+	Path       string    `json:"path"`
+	Exists     bool      `json:"exists"`
+	Hash       string    `json:"hash,omitempty"` // SHA-256
+	ModifiedAt time.Time `json:"modifiedAt,omitempty"`
+	// 按当前模板、设置和节点生成的配置与磁盘上的配置不同（如编辑模板后未重新生成、回滚到历史配置）
+	Drift      bool   `json:"drift"`
+	DriftError string `json:"driftError,omitempty"` // 无法生成配置进行比较时的原因
+}
+
+// TransparentApplyState 透明代理规则的应用状态
+type TransparentApplyState struct {
+	Mode    string `json:"mode"`
+	Scope   string `json:"scope"`
+	Applied bool   `json:"applied"`         // 规则已由 ProxyStation 应用
+	Error   string `json:"error,omitempty"` // 最近一次应用规则失败的原因
+}
+
+// StatusError 最近一次错误
+type StatusError struct {
+	Source  string    `json:"source"` // start, crash, config, transparent
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// configDriftCache 按配置文件哈希缓存漂移检查结果
+type configDriftCache struct {
+	mu        sync.Mutex
+	hash      string
+	checkedAt time.Time
+	drift     bool
+	err       string
+}
+
+// coreVersionPattern 匹配核心版本输出中的版本号，如 "Mihomo Meta v1.18.1 linux amd64"、"sing-box version 1.8.0"
+// 以及 Mihomo 测试版的 "alpha-abc1234"（跳过 "with go1.22.0" 中的 Go 版本）
+var coreVersionPattern = regexp.MustCompile(`(?:^|\s)v?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.]+)?|alpha-[0-9a-f]+)`)
+
+// parseCoreVersion 从核心版本输出中提取版本号，无法识别时返回原始内容
+func parseCoreVersion(output string) string {
+	output = strings.TrimSpace(output)
+	if m := coreVersionPattern.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return output
+}
+
+// recordError 记录最近一次错误，在状态接口中展示
+// 注意：调用此方法时不能持有 s.mu 锁
+func (s *Service) recordError(source string, err error) {
+	if err == nil || errors.Is(err, ErrCoreRunning) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = &StatusError{Source: source, Message: err.Error(), Time: time.Now()}
+}
+
+// activeConfigStatus 读取当前配置文件的哈希和修改时间，并检查是否与模板生成的配置一致
+func (s *Service) activeConfigStatus() *ActiveConfigStatus {
+	s.mu.RLock()
+	path := s.configPath
+	coreType := s.coreType
+	s.mu.RUnlock()
+	if path == "" {
+		path = s.activeConfigPath(coreType)
+	}
+
+	result := &ActiveConfigStatus{Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return result
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result
+	}
+	sum := sha256.Sum256(data)
+	result.Exists = true
+	result.Hash = hex.EncodeToString(sum[:])
+	result.ModifiedAt = info.ModTime()
+	result.Drift, result.DriftError = s.configDrift(result.Hash, data)
+	return result
+}
+
+// configDrift 比较磁盘上的配置与按当前模板生成的配置，结果按哈希缓存 configDriftTTL
+func (s *Service) configDrift(hash string, current []byte) (bool, string) {
+	cache := &s.driftCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.hash == hash && time.Since(cache.checkedAt) < configDriftTTL {
+		return cache.drift, cache.err
+	}
+
+	cache.hash = hash
+	cache.checkedAt = time.Now()
+	cache.drift, cache.err = false, ""
+	nodes, err := s.GetAllNodes()
+	if err == nil {
+		var content []byte
+		if content, err = s.renderConfig(nodes); err == nil {
+			cache.drift = !bytes.Equal(content, current)
+		}
+	}
+	if err != nil {
+		cache.err = err.Error()
+	}
+	return cache.drift, cache.err
+}
+
+// transparentApplyState 汇总透明代理模式和规则应用结果（不执行 nft 命令，详细检查见 /transparent/status）
+func (h *Handler) transparentApplyState(status *ProxyStatus) *TransparentApplyState {
+	state := &TransparentApplyState{Mode: status.TransparentMode, Scope: status.ProxyScope}
+	if state.Mode == "" {
+		state.Mode = "off"
+	}
+	if state.Scope == "" {
+		state.Scope = "local"
+	}
+
+	h.marksMu.Lock()
+	state.Applied = h.appliedMarks != nil
+	if h.rulesErr != nil {
+		state.Error = h.rulesErr.Error()
+	}
+	h.marksMu.Unlock()
+	return state
+}
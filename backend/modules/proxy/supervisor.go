@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"ProxyStation/backend/events"
+)
+
+const (
+	crashRestartBaseDelay = 1 * time.Second
+	crashRestartMaxDelay  = 60 * time.Second
+	// 核心稳定运行超过该时长后，连续崩溃计数清零
+	crashStableUptime = 60 * time.Second
+	maxCrashEvents    = 20
+)
+
+// CrashEvent 核心异常退出记录
+type CrashEvent struct {
+	Time     time.Time `json:"time"`
+	CoreType string    `json:"coreType"`
+	ExitCode int       `json:"exitCode"` // -1 表示被信号终止或未能启动
+	Error    string    `json:"error,omitempty"`
+	Uptime   int64     `json:"uptime"`  // 崩溃前运行时长（秒）
+	Attempt  int       `json:"attempt"` // 连续崩溃次数
+	Restart  bool      `json:"restart"` // 是否会自动重启
+	Delay    int64     `json:"delay"`   // 重启等待时间（毫秒）
+	LastLogs []string  `json:"lastLogs,omitempty"`
+}
+
+// SupervisorStatus 崩溃自动重启状态
+type SupervisorStatus struct {
+	Enabled      bool         `json:"enabled"`
+	RestartCount int          `json:"restartCount"` // 当前连续重启次数
+	MaxRestarts  int          `json:"maxRestarts"`
+	NextRestart  *time.Time   `json:"nextRestart,omitempty"`
+	GaveUp       bool         `json:"gaveUp"` // 超过最大次数后放弃重启
+	Crashes      []CrashEvent `json:"crashes"`
+}
+
+// supervisorState 崩溃重启内部状态
+type supervisorState struct {
+	attempts    int
+	timer       *time.Timer
+	nextRestart time.Time
+	gaveUp      bool
+	crashes     []CrashEvent
+}
+
+// crashBackoff 指数退避：1s, 2s, 4s ... 最长 60s
+func crashBackoff(attempt int) time.Duration {
+	delay := crashRestartBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= crashRestartMaxDelay {
+			return crashRestartMaxDelay
+		}
+	}
+	return delay
+}
+
+// monitorProcess 等待核心进程退出，非主动停止时按崩溃处理
+func (s *Service) monitorProcess(cmd *exec.Cmd, done chan struct{}) {
+	err := cmd.Wait()
+	close(done)
+	s.removePidFile(cmd.Process.Pid)
+
+	s.mu.Lock()
+	if s.process != cmd {
+		// Stop 已清空 process，属于主动停止
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.process = nil
+	uptime := time.Since(s.startTime)
+	s.mu.Unlock()
+
+	event := CrashEvent{
+		Time:     time.Now(),
+		CoreType: s.GetCoreType(),
+		ExitCode: -1,
+		Uptime:   int64(uptime.Seconds()),
+	}
+	var exitErr *exec.ExitError
+	if err == nil {
+		event.ExitCode = 0
+	} else if errors.As(err, &exitErr) {
+		event.ExitCode = exitErr.ExitCode()
+		event.Error = exitErr.Error()
+	} else {
+		event.Error = err.Error()
+	}
+
+	s.handleCrash(event, uptime)
+}
+
+// handleCrash 记录崩溃事件并按退避策略安排重启，放弃重启时清理系统设置
+func (s *Service) handleCrash(event CrashEvent, uptime time.Duration) {
+	event.LastLogs = s.GetLogs(20)
+
+	s.mu.Lock()
+	sv := &s.supervisor
+	if uptime >= crashStableUptime {
+		sv.attempts = 0
+	}
+	sv.attempts++
+	event.Attempt = sv.attempts
+	event.Restart = s.config.CrashRestart && sv.attempts <= s.config.MaxRestarts
+	if event.Restart {
+		delay := crashBackoff(sv.attempts)
+		event.Delay = delay.Milliseconds()
+		sv.nextRestart = time.Now().Add(delay)
+		sv.timer = time.AfterFunc(delay, s.restartAfterCrash)
+	} else if s.config.CrashRestart {
+		sv.gaveUp = true
+	}
+	sv.crashes = append(sv.crashes, event)
+	if len(sv.crashes) > maxCrashEvents {
+		sv.crashes = sv.crashes[len(sv.crashes)-maxCrashEvents:]
+	}
+	maxRestarts := s.config.MaxRestarts
+	crashRestart := s.config.CrashRestart
+	s.mu.Unlock()
+
+	msg := fmt.Sprintf("核心异常退出 (exit=%d, 运行 %ds)", event.ExitCode, event.Uptime)
+	if event.Error != "" {
+		msg += ": " + event.Error
+	}
+	s.addLog("[ERROR] [supervisor] " + msg)
+	s.recordError("crash", errors.New(msg))
+	fmt.Printf("💥 %s\n", msg)
+	s.publish(events.CoreCrashed, event)
+
+	if event.Restart {
+		s.addLog(fmt.Sprintf("[WARN] [supervisor] %v 后自动重启（第 %d/%d 次）", time.Duration(event.Delay)*time.Millisecond, event.Attempt, maxRestarts))
+		return
+	}
+	if crashRestart {
+		s.addLog(fmt.Sprintf("[ERROR] [supervisor] 连续崩溃 %d 次，停止自动重启", event.Attempt))
+		fmt.Println("⛔ 核心连续崩溃次数过多，已停止自动重启")
+	}
+
+	// 不再重启时恢复系统代理并清除透明代理规则，避免断网
+	s.afterStop()
+}
+
+// restartAfterCrash 退避时间到达后重新启动核心
+func (s *Service) restartAfterCrash() {
+	s.mu.Lock()
+	// nextRestart 已被清空说明重启在等待期间被取消
+	canceled := s.supervisor.nextRestart.IsZero()
+	s.supervisor.timer = nil
+	s.supervisor.nextRestart = time.Time{}
+	attempt := s.supervisor.attempts
+	if canceled || s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	fmt.Printf("🔄 正在自动重启核心（第 %d 次）...\n", attempt)
+	if err := s.start(true); err != nil {
+		// 启动失败同样计为一次崩溃
+		s.handleCrash(CrashEvent{
+			Time:     time.Now(),
+			CoreType: s.GetCoreType(),
+			ExitCode: -1,
+			Error:    err.Error(),
+		}, 0)
+		return
+	}
+	s.addLog(fmt.Sprintf("[INFO] [supervisor] 核心已自动重启（第 %d 次）", attempt))
+	fmt.Println("✅ 核心已自动重启")
+}
+
+// cancelRestart 取消等待中的重启，返回是否有等待中的重启（调用方需持有 mu）
+func (s *Service) cancelRestart() bool {
+	pending := s.supervisor.timer != nil
+	if pending {
+		s.supervisor.timer.Stop()
+		s.supervisor.timer = nil
+	}
+	s.supervisor.nextRestart = time.Time{}
+	return pending
+}
+
+// resetSupervisor 手动启动时清零连续崩溃计数
+func (s *Service) resetSupervisor() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelRestart()
+	s.supervisor.attempts = 0
+	s.supervisor.gaveUp = false
+}
+
+// supervisorStatus 获取崩溃重启状态（调用方需持有 mu）
+func (s *Service) supervisorStatus() SupervisorStatus {
+	sv := s.supervisor
+	status := SupervisorStatus{
+		Enabled:      s.config.CrashRestart,
+		RestartCount: sv.attempts,
+		MaxRestarts:  s.config.MaxRestarts,
+		GaveUp:       sv.gaveUp,
+		Crashes:      make([]CrashEvent, len(sv.crashes)),
+	}
+	copy(status.Crashes, sv.crashes)
+	if !sv.nextRestart.IsZero() {
+		next := sv.nextRestart
+		status.NextRestart = &next
+	}
+	return status
+}
+
+// GetCrashEvents 获取最近的崩溃记录
+func (s *Service) GetCrashEvents() []CrashEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.supervisorStatus().Crashes
+}
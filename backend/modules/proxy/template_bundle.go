@@ -0,0 +1,354 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// templateBundleKind 模板包标识，用于识别导入的文件
+	templateBundleKind = "proxystation.template-bundle"
+	// templateBundleVersion 当前模板包格式版本，修改格式时递增并在 templateBundleMigrations 中添加迁移
+	templateBundleVersion = 1
+)
+
+// 模板包包含的部分
+const (
+	bundlePartMihomo      = "mihomo"
+	bundlePartSingBox     = "singbox"
+	bundlePartTransparent = "transparent"
+)
+
+var bundleParts = []string{bundlePartMihomo, bundlePartSingBox, bundlePartTransparent}
+
+// TemplateBundle 可分享的模板包：Mihomo 模板、Sing-Box 模板和透明代理设置
+// 只包含与设备无关的设置，绕过设备、拦截网卡、进程分流和透明代理模式不导出
+type TemplateBundle struct {
+	Kind        string                 `json:"kind"`
+	Version     int                    `json:"version"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	ExportedAt  time.Time              `json:"exportedAt"`
+	Mihomo      *ConfigTemplate        `json:"mihomo,omitempty"`
+	SingBox     *SingBoxTemplate       `json:"singbox,omitempty"`
+	Transparent *TransparentBundleData `json:"transparent,omitempty"`
+}
+
+// TransparentBundleData 模板包中的透明代理设置
+type TransparentBundleData struct {
+	DNSHijack   bool                `json:"dnsHijack"`
+	IPv6        bool                `json:"ipv6"`
+	BlockQUIC   bool                `json:"blockQuic"`
+	Direct      TransparentDirect   `json:"direct"`
+	Watchdog    TransparentWatchdog `json:"watchdog"`
+	NftTemplate string              `json:"nftTemplate,omitempty"` // 为空表示使用默认 nftables 模板
+}
+
+// TemplateBundleImportResult 模板包导入结果
+type TemplateBundleImportResult struct {
+	SourceVersion int             `json:"sourceVersion"` // 导入文件的格式版本
+	Migrated      bool            `json:"migrated"`      // 是否从旧版本格式迁移
+	Imported      []string        `json:"imported"`      // 已导入（预览时为将要导入）的部分
+	Warnings      []string        `json:"warnings"`
+	Bundle        *TemplateBundle `json:"bundle"` // 迁移和整理后的模板包
+}
+
+// templateBundleMigrations 第 i 项把 i 版模板包升级为 i+1 版
+var templateBundleMigrations = []func(raw map[string]json.RawMessage) error{
+	migrateTemplateBundleV0,
+}
+
+// migrateTemplateBundleV0 0 版为直接保存的 Mihomo 模板（GET /template 的返回数据），没有包装
+func migrateTemplateBundleV0(raw map[string]json.RawMessage) error {
+	template, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	for key := range raw {
+		delete(raw, key)
+	}
+	raw["mihomo"] = template
+	return nil
+}
+
+// decodeTemplateBundle 解析模板包并迁移到当前版本，返回原始版本
+func decodeTemplateBundle(data []byte) (*TemplateBundle, int, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, apierror.New(apierror.CodeBadRequest, fmt.Sprintf("模板包格式错误: %v", err))
+	}
+
+	version := 0
+	if kind, ok := raw["kind"]; ok {
+		var k string
+		if json.Unmarshal(kind, &k) != nil || k != templateBundleKind {
+			return nil, 0, apierror.New(apierror.CodeBadRequest, "不是 ProxyStation 模板包")
+		}
+		if err := json.Unmarshal(raw["version"], &version); err != nil || version < 1 {
+			return nil, 0, apierror.New(apierror.CodeBadRequest, "模板包版本无效")
+		}
+	} else if raw["proxyGroups"] == nil && raw["rules"] == nil {
+		return nil, 0, apierror.New(apierror.CodeBadRequest, "不是 ProxyStation 模板包")
+	}
+	if version > templateBundleVersion {
+		return nil, version, apierror.New(apierror.CodeValidation, fmt.Sprintf("模板包版本 %d 高于当前支持的版本 %d，请升级 ProxyStation", version, templateBundleVersion))
+	}
+
+	for v := version; v < templateBundleVersion; v++ {
+		if err := templateBundleMigrations[v](raw); err != nil {
+			return nil, version, fmt.Errorf("模板包从版本 %d 迁移失败: %w", v, err)
+		}
+	}
+	raw["kind"], _ = json.Marshal(templateBundleKind)
+	raw["version"], _ = json.Marshal(templateBundleVersion)
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, err
+	}
+	var bundle TemplateBundle
+	if err := json.Unmarshal(migrated, &bundle); err != nil {
+		return nil, version, apierror.New(apierror.CodeBadRequest, fmt.Sprintf("模板包格式错误: %v", err))
+	}
+	return &bundle, version, nil
+}
+
+// parseBundleParts 解析 parts 参数（逗号分隔），为空时包含全部
+func parseBundleParts(value string) (map[string]bool, error) {
+	parts := make(map[string]bool)
+	if strings.TrimSpace(value) == "" {
+		for _, p := range bundleParts {
+			parts[p] = true
+		}
+		return parts, nil
+	}
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		switch p {
+		case bundlePartMihomo, bundlePartSingBox, bundlePartTransparent:
+			parts[p] = true
+		case "":
+		default:
+			return nil, apierror.New(apierror.CodeBadRequest, fmt.Sprintf("未知的模板包内容: %s", p))
+		}
+	}
+	return parts, nil
+}
+
+// ExportTemplateBundle 导出模板包
+func (s *Service) ExportTemplateBundle(parts map[string]bool, name, description string) *TemplateBundle {
+	bundle := &TemplateBundle{
+		Kind:        templateBundleKind,
+		Version:     templateBundleVersion,
+		Name:        name,
+		Description: description,
+		ExportedAt:  time.Now(),
+	}
+	if parts[bundlePartMihomo] {
+		s.mu.RLock()
+		if s.configTemplate != nil {
+			template := *s.configTemplate
+			bundle.Mihomo = &template
+		}
+		s.mu.RUnlock()
+	}
+	if parts[bundlePartSingBox] {
+		bundle.SingBox = s.GetSingBoxTemplate()
+	}
+	if parts[bundlePartTransparent] {
+		s.mu.RLock()
+		data := &TransparentBundleData{
+			DNSHijack: s.config.DNSHijack,
+			IPv6:      s.config.TransparentIPv6,
+			BlockQUIC: s.config.BlockQUIC,
+		}
+		s.mu.RUnlock()
+		data.Direct = s.GetTransparentDirect()
+		data.Watchdog = s.transparentWatchdog()
+		if content, custom := s.GetNftTemplate(); custom {
+			data.NftTemplate = content
+		}
+		bundle.Transparent = data
+	}
+	return bundle
+}
+
+// pruneMissingMembers 移除代理组中本机不存在的节点（模板包来自其他实例时节点名通常不同）
+func pruneMissingMembers(groups []ProxyGroupTemplate, nodeNames map[string]bool, warn func(string, ...interface{})) {
+	if nodeNames == nil {
+		return
+	}
+	groupNames := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		groupNames[g.Name] = true
+	}
+	for i := range groups {
+		g := &groups[i]
+		kept := g.Proxies[:0]
+		for _, member := range g.Proxies {
+			if builtinRuleTargets[member] || groupNames[member] || nodeNames[member] {
+				kept = append(kept, member)
+				continue
+			}
+			warn("代理组 %s 的成员 %s 在本机不存在，已移除", g.Name, member)
+		}
+		g.Proxies = kept
+	}
+}
+
+// importTemplateBundle 校验并导入模板包的指定部分，全部校验通过后才写入
+func (h *Handler) importTemplateBundle(bundle *TemplateBundle, parts map[string]bool, preview bool) (*TemplateBundleImportResult, error) {
+	s := h.service
+	result := &TemplateBundleImportResult{Imported: []string{}, Warnings: []string{}, Bundle: bundle}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if !parts[bundlePartMihomo] {
+		bundle.Mihomo = nil
+	}
+	if !parts[bundlePartSingBox] {
+		bundle.SingBox = nil
+	}
+	if !parts[bundlePartTransparent] {
+		bundle.Transparent = nil
+	}
+	if bundle.Mihomo == nil && bundle.SingBox == nil && bundle.Transparent == nil {
+		return nil, apierror.New(apierror.CodeBadRequest, "模板包中没有可导入的内容")
+	}
+
+	if t := bundle.Mihomo; t != nil {
+		nodeNames := s.currentNodeNames()
+		pruneMissingMembers(t.ProxyGroups, nodeNames, warn)
+		if errs := validateProxyGroups(t.ProxyGroups, nodeNames); len(errs) > 0 {
+			return nil, errs
+		}
+		ensureRuleIDs(t.Rules)
+		result.Imported = append(result.Imported, bundlePartMihomo)
+	}
+	if bundle.SingBox != nil {
+		result.Imported = append(result.Imported, bundlePartSingBox)
+	}
+	if t := bundle.Transparent; t != nil {
+		direct, err := t.Direct.normalize()
+		if err != nil {
+			return nil, apierror.Wrap(apierror.CodeValidation, fmt.Errorf("直连设置: %w", err))
+		}
+		watchdog, err := t.Watchdog.normalize()
+		if err != nil {
+			return nil, apierror.Wrap(apierror.CodeValidation, fmt.Errorf("看门狗设置: %w", err))
+		}
+		if err := h.validateNftTemplate(t.NftTemplate); err != nil {
+			return nil, apierror.Wrap(apierror.CodeValidation, fmt.Errorf("nftables 模板: %w", err))
+		}
+		t.Direct, t.Watchdog = direct, watchdog
+		result.Imported = append(result.Imported, bundlePartTransparent)
+	}
+
+	if preview {
+		return result, nil
+	}
+
+	if bundle.Mihomo != nil {
+		if err := s.ReplaceConfigTemplate(*bundle.Mihomo); err != nil {
+			return nil, err
+		}
+	}
+	if bundle.SingBox != nil {
+		if err := s.UpdateSingBoxTemplate(bundle.SingBox); err != nil {
+			return nil, fmt.Errorf("Sing-Box 模板: %w", err)
+		}
+	}
+	if t := bundle.Transparent; t != nil {
+		if err := s.importTransparentSettings(t); err != nil {
+			return nil, fmt.Errorf("透明代理设置: %w", err)
+		}
+		// 核心运行中时立即按新设置重新应用规则
+		if err := h.reapplyTransparentRules(); err != nil {
+			warn("透明代理设置已导入，但应用规则失败: %v", err)
+		}
+	}
+
+	fmt.Printf("📥 已导入模板包 %q: %s, %d 条警告\n", bundle.Name, strings.Join(result.Imported, ", "), len(result.Warnings))
+	return result, nil
+}
+
+// importTransparentSettings 保存模板包中的透明代理设置
+func (s *Service) importTransparentSettings(t *TransparentBundleData) error {
+	s.mu.Lock()
+	s.config.DNSHijack = t.DNSHijack
+	s.config.TransparentIPv6 = t.IPv6
+	s.config.BlockQUIC = t.BlockQUIC
+	s.config.TransparentDirect = t.Direct
+	s.config.TransparentWatchdog = t.Watchdog
+	err := s.saveConfig()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.SetNftTemplate(t.NftTemplate)
+}
+
+// ExportTemplateBundle 导出模板包
+// 参数: parts 逗号分隔的内容（mihomo,singbox,transparent，默认全部），name/description 模板包说明
+func (h *Handler) ExportTemplateBundle(c *gin.Context) {
+	parts, err := parseBundleParts(c.Query("parts"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.ExportTemplateBundle(parts, c.Query("name"), c.Query("description")),
+	})
+}
+
+// ImportTemplateBundle 导入模板包，请求体为导出的模板包（旧版本格式自动迁移）
+// 参数: parts 只导入指定内容，preview=true 只校验并返回整理后的模板包
+func (h *Handler) ImportTemplateBundle(c *gin.Context) {
+	parts, err := parseBundleParts(c.Query("parts"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	bundle, version, err := decodeTemplateBundle(data)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.importTemplateBundle(bundle, parts, c.Query("preview") == "true")
+	if err != nil {
+		var groupErrs ProxyGroupErrors
+		if errors.As(err, &groupErrs) {
+			apierror.Respond(c, http.StatusOK, apierror.Wrap(apierror.CodeValidation, groupErrs).WithDetails(gin.H{"errors": groupErrs}))
+			return
+		}
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	result.SourceVersion = version
+	result.Migrated = version < templateBundleVersion
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
@@ -0,0 +1,357 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// clashImportConfig 导入时关心的 Clash/Mihomo 配置字段
+type clashImportConfig struct {
+	Proxies []struct {
+		Name string `yaml:"name"`
+	} `yaml:"proxies"`
+	ProxyGroups   []clashImportGroup                 `yaml:"proxy-groups"`
+	Rules         []string                           `yaml:"rules"`
+	RuleProviders map[string]clashImportRuleProvider `yaml:"rule-providers"`
+	DNS           *clashImportDNS                    `yaml:"dns"`
+}
+
+type clashImportGroup struct {
+	Name              string   `yaml:"name"`
+	Type              string   `yaml:"type"`
+	Icon              string   `yaml:"icon"`
+	Proxies           []string `yaml:"proxies"`
+	Use               []string `yaml:"use"`
+	URL               string   `yaml:"url"`
+	Interval          int      `yaml:"interval"`
+	Tolerance         int      `yaml:"tolerance"`
+	Lazy              bool     `yaml:"lazy"`
+	Hidden            bool     `yaml:"hidden"`
+	Filter            string   `yaml:"filter"`
+	ExcludeFilter     string   `yaml:"exclude-filter"`
+	IncludeAll        bool     `yaml:"include-all"`
+	IncludeAllProxies bool     `yaml:"include-all-proxies"`
+}
+
+type clashImportRuleProvider struct {
+	Type     string `yaml:"type"`
+	Behavior string `yaml:"behavior"`
+	URL      string `yaml:"url"`
+	Path     string `yaml:"path"`
+	Interval int    `yaml:"interval"`
+	Format   string `yaml:"format"`
+}
+
+type clashImportDNS struct {
+	DefaultNameserver     []string               `yaml:"default-nameserver"`
+	Nameserver            []string               `yaml:"nameserver"`
+	Fallback              []string               `yaml:"fallback"`
+	ProxyServerNameserver []string               `yaml:"proxy-server-nameserver"`
+	DirectNameserver      []string               `yaml:"direct-nameserver"`
+	NameserverPolicy      map[string]interface{} `yaml:"nameserver-policy"`
+	FakeIPRange           string                 `yaml:"fake-ip-range"`
+	FakeIPFilter          []string               `yaml:"fake-ip-filter"`
+}
+
+// TemplateImportResult 导入结果
+type TemplateImportResult struct {
+	ProxyGroups   int             `json:"proxyGroups"`
+	Rules         int             `json:"rules"`
+	RuleProviders int             `json:"ruleProviders"`
+	DNS           bool            `json:"dns"`
+	Warnings      []string        `json:"warnings"`
+	Template      *ConfigTemplate `json:"template"`
+}
+
+// ImportClashTemplate 从完整的 Clash/Mihomo 配置中提取代理组、规则、规则集和 DNS 设置作为模板
+// 配置中存在的部分整体替换当前模板，不存在的部分保持不变；preview 为 true 时只返回结果不保存
+// 节点（proxies）不会导入，引用节点的代理组改为使用全部节点
+func (s *Service) ImportClashTemplate(content string, preview bool) (*TemplateImportResult, error) {
+	var cfg clashImportConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return nil, fmt.Errorf("YAML 格式错误: %v", err)
+	}
+	if len(cfg.ProxyGroups) == 0 && len(cfg.Rules) == 0 && len(cfg.RuleProviders) == 0 && cfg.DNS == nil {
+		return nil, fmt.Errorf("配置中没有可导入的代理组、规则、规则集或 DNS 设置")
+	}
+
+	result := &TemplateImportResult{Warnings: []string{}}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	localNodes := s.currentNodeNames()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	template := &ConfigTemplate{}
+	if s.configTemplate != nil {
+		*template = *s.configTemplate
+	}
+
+	if len(cfg.ProxyGroups) > 0 {
+		template.ProxyGroups = s.importProxyGroups(cfg, localNodes, warn)
+		if errs := validateProxyGroups(template.ProxyGroups, localNodes); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	if len(cfg.RuleProviders) > 0 {
+		template.RuleProviders = s.importRuleProviders(cfg.RuleProviders, warn)
+	}
+	if len(cfg.Rules) > 0 {
+		template.Rules = s.importRules(cfg.Rules, template, warn)
+		ensureRuleIDs(template.Rules)
+	}
+	if cfg.DNS != nil {
+		template.DNS = importDNS(cfg.DNS, warn)
+	}
+
+	result.ProxyGroups = len(template.ProxyGroups)
+	result.Rules = len(template.Rules)
+	result.RuleProviders = len(template.RuleProviders)
+	result.DNS = template.DNS != nil
+	result.Template = template
+
+	if preview {
+		return result, nil
+	}
+
+	previous := s.configTemplate
+	s.configTemplate = template
+	if err := s.saveConfigTemplate(); err != nil {
+		s.configTemplate = previous
+		return nil, err
+	}
+	fmt.Printf("📥 已导入 Clash 配置模板: %d 个代理组, %d 条规则, %d 个规则集, %d 条警告\n",
+		result.ProxyGroups, result.Rules, result.RuleProviders, len(result.Warnings))
+	return result, nil
+}
+
+// ReplaceConfigTemplate 整体替换配置模板（其他实例推送），代理组校验失败时保持原模板
+func (s *Service) ReplaceConfigTemplate(template ConfigTemplate) error {
+	if errs := validateProxyGroups(template.ProxyGroups, s.currentNodeNames()); len(errs) > 0 {
+		return errs
+	}
+	ensureRuleIDs(template.Rules)
+
+	unlock := s.lockWrites()
+	defer unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.configTemplate
+	s.configTemplate = &template
+	if err := s.saveConfigTemplate(); err != nil {
+		s.configTemplate = previous
+		return err
+	}
+	return nil
+}
+
+// importProxyGroups 转换代理组，只保留可解析的成员
+func (s *Service) importProxyGroups(cfg clashImportConfig, localNodes map[string]bool, warn func(string, ...interface{})) []ProxyGroupTemplate {
+	groupNames := make(map[string]bool, len(cfg.ProxyGroups))
+	for _, g := range cfg.ProxyGroups {
+		groupNames[g.Name] = true
+	}
+	importedNodes := make(map[string]bool, len(cfg.Proxies))
+	for _, p := range cfg.Proxies {
+		importedNodes[p.Name] = true
+	}
+
+	groups := make([]ProxyGroupTemplate, 0, len(cfg.ProxyGroups))
+	for _, g := range cfg.ProxyGroups {
+		t := ProxyGroupTemplate{
+			Name:      g.Name,
+			Type:      g.Type,
+			Icon:      g.Icon,
+			Enabled:   true,
+			URL:       g.URL,
+			Interval:  g.Interval,
+			Tolerance: g.Tolerance,
+			Lazy:      g.Lazy,
+			Hidden:    g.Hidden,
+			Filter:    g.Filter,
+		}
+		if !proxyGroupTypes[t.Type] {
+			warn("代理组 %s 的类型 %s 不受支持，已改为 select", g.Name, g.Type)
+			t.Type = "select"
+		}
+		if g.ExcludeFilter != "" {
+			warn("代理组 %s 的 exclude-filter 不受支持，已忽略", g.Name)
+		}
+
+		hasNodes := g.IncludeAll || g.IncludeAllProxies || len(g.Use) > 0
+		var groupMembers []string
+		for _, member := range g.Proxies {
+			switch {
+			case groupNames[member] || builtinRuleTargets[member]:
+				groupMembers = append(groupMembers, member)
+				t.Proxies = append(t.Proxies, member)
+			case localNodes[member]:
+				t.Proxies = append(t.Proxies, member)
+			case importedNodes[member]:
+				hasNodes = true
+			default:
+				warn("代理组 %s 的成员 %s 不存在，已忽略", g.Name, member)
+			}
+		}
+
+		// 原配置中的节点不会导入，改为按过滤条件使用本地全部节点
+		if hasNodes {
+			t.UseAll = true
+			t.Proxies = nil
+			if len(groupMembers) > 0 {
+				warn("代理组 %s 改为使用全部节点，原有的代理组成员 %s 已移除", g.Name, strings.Join(groupMembers, ", "))
+			}
+		}
+		groups = append(groups, t)
+	}
+	return groups
+}
+
+// importRuleProviders 转换规则集，相对路径按 Mihomo 工作目录（数据目录）解析
+func (s *Service) importRuleProviders(providers map[string]clashImportRuleProvider, warn func(string, ...interface{})) []RuleProviderTemplate {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]RuleProviderTemplate, 0, len(providers))
+	for _, name := range names {
+		p := providers[name]
+		if p.Type != "http" && p.Type != "file" {
+			warn("规则集 %s 的类型 %s 不受支持，已跳过", name, p.Type)
+			continue
+		}
+		path := p.Path
+		if path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(s.dataDir, path)
+		}
+		result = append(result, RuleProviderTemplate{
+			Name:        name,
+			Type:        p.Type,
+			Behavior:    p.Behavior,
+			URL:         p.URL,
+			Path:        path,
+			Interval:    p.Interval,
+			Format:      p.Format,
+			Description: "导入",
+		})
+	}
+	return result
+}
+
+// importRules 转换规则，跳过不支持的类型和目标不存在的规则
+func (s *Service) importRules(lines []string, template *ConfigTemplate, warn func(string, ...interface{})) []RuleTemplate {
+	targets := make(map[string]bool, len(template.ProxyGroups))
+	for _, g := range template.ProxyGroups {
+		if isGroupTemplateActive(g) {
+			targets[g.Name] = true
+		}
+	}
+	providers := mergeTemplateRuleProviders(s.configGenerator.generateRuleProviders(), template.RuleProviders)
+
+	rules := make([]RuleTemplate, 0, len(lines))
+	for i, line := range lines {
+		r := parseRuleLine(line)
+		if r.ruleType == "FINAL" {
+			r.ruleType = "MATCH"
+		}
+		switch {
+		case !mihomoRuleTypes[r.ruleType]:
+			warn("规则 %s 的类型不受支持，已跳过", line)
+			continue
+		case !targets[r.proxy] && !builtinRuleTargets[r.proxy]:
+			warn("规则 %s 的目标 %s 不存在，已跳过", line, r.proxy)
+			continue
+		case r.ruleType == "RULE-SET":
+			if _, ok := providers[r.payload]; !ok {
+				warn("规则 %s 引用的规则集不存在，已跳过", line)
+				continue
+			}
+		}
+
+		rules = append(rules, RuleTemplate{
+			Type:      r.ruleType,
+			Payload:   r.payload,
+			Proxy:     r.proxy,
+			NoResolve: r.noResolve,
+		})
+		if r.ruleType == "MATCH" {
+			if i < len(lines)-1 {
+				warn("MATCH 之后的 %d 条规则不会生效，已忽略", len(lines)-1-i)
+			}
+			break
+		}
+	}
+	return rules
+}
+
+// importDNS 提取 DNS 服务器设置，nameserver-policy 的值可以是字符串或列表
+func importDNS(dns *clashImportDNS, warn func(string, ...interface{})) *DNSTemplate {
+	t := &DNSTemplate{
+		DefaultNameserver:     dns.DefaultNameserver,
+		Nameserver:            dns.Nameserver,
+		Fallback:              dns.Fallback,
+		ProxyServerNameserver: dns.ProxyServerNameserver,
+		DirectNameserver:      dns.DirectNameserver,
+		FakeIPRange:           dns.FakeIPRange,
+		FakeIPFilter:          dns.FakeIPFilter,
+	}
+	if len(dns.NameserverPolicy) > 0 {
+		t.NameserverPolicy = make(map[string][]string, len(dns.NameserverPolicy))
+		for domain, value := range dns.NameserverPolicy {
+			switch v := value.(type) {
+			case string:
+				t.NameserverPolicy[domain] = []string{v}
+			case []interface{}:
+				for _, item := range v {
+					t.NameserverPolicy[domain] = append(t.NameserverPolicy[domain], fmt.Sprint(item))
+				}
+			default:
+				warn("nameserver-policy 中 %s 的格式无效，已跳过", domain)
+			}
+		}
+	}
+	return t
+}
+
+// ImportTemplate 导入 Clash/Mihomo 配置作为模板
+// 请求体 {"content": "<YAML>"}，?preview=true 时只返回转换结果不保存
+func (h *Handler) ImportTemplate(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.service.ImportClashTemplate(req.Content, c.Query("preview") == "true")
+	if err != nil {
+		var groupErrs ProxyGroupErrors
+		if errors.As(err, &groupErrs) {
+			apierror.Respond(c, http.StatusOK, apierror.Wrap(apierror.CodeValidation, groupErrs).WithDetails(gin.H{"errors": groupErrs}))
+			return
+		}
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
@@ -0,0 +1,488 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	trafficSampleInterval = 10 * time.Second   // 采样间隔
+	trafficBucketSize     = 5 * time.Minute    // 聚合粒度
+	trafficRetention      = 7 * 24 * time.Hour // 历史保留时长
+	trafficBucketDomains  = 100                // 每个时间桶保留的域名数量
+	trafficTopDomains     = 50                 // 接口返回的域名数量
+)
+
+// TrafficCounter 上传/下载字节计数
+type TrafficCounter struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+}
+
+func (t *TrafficCounter) add(up, down int64) {
+	t.Upload += up
+	t.Download += down
+}
+
+// TrafficBucket 一个时间桶内的流量统计
+type TrafficBucket struct {
+	Time    int64                      `json:"time"` // 桶起始时间（Unix 秒）
+	Total   TrafficCounter             `json:"total"`
+	Groups  map[string]*TrafficCounter `json:"groups"`
+	Domains map[string]*TrafficCounter `json:"domains"`
+}
+
+// TrafficPoint 图表数据点
+type TrafficPoint struct {
+	Time     int64 `json:"time"`
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+}
+
+// TrafficRanking 按代理组或域名汇总的流量
+type TrafficRanking struct {
+	Name     string `json:"name"`
+	Upload   int64  `json:"upload"`
+	Download int64  `json:"download"`
+}
+
+// TrafficHistory 历史流量查询结果
+type TrafficHistory struct {
+	Range    string           `json:"range"`
+	Interval int              `json:"interval"` // 数据点间隔（秒）
+	Total    TrafficCounter   `json:"total"`
+	Points   []TrafficPoint   `json:"points"`
+	Groups   []TrafficRanking `json:"groups"`
+	Domains  []TrafficRanking `json:"domains"`
+}
+
+// trafficStats 定时采样核心连接信息，按代理组和域名累计流量
+// 已完成的时间桶保存在 bbolt 数据库中，以桶起始时间为键，按时间范围查询时只读取范围内的记录
+type trafficStats struct {
+	mu      sync.Mutex
+	db      *bolt.DB       // 打开失败时为 nil，只统计当前时间桶
+	current *TrafficBucket // 当前未完成的时间桶
+
+	// 上一次采样的计数，用于计算增量
+	primed    bool
+	lastTotal TrafficCounter
+	lastConns map[string]TrafficCounter
+}
+
+// trafficDBBucket 保存时间桶的 bbolt bucket
+var trafficDBBucket = []byte("buckets")
+
+func newTrafficStats(dataDir string) *trafficStats {
+	s := &trafficStats{lastConns: make(map[string]TrafficCounter)}
+
+	db, err := bolt.Open(filepath.Join(dataDir, "traffic_stats.db"), 0644, &bolt.Options{Timeout: time.Second})
+	if err == nil {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(trafficDBBucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+		}
+	}
+	if err != nil {
+		fmt.Printf("⚠️ 打开流量统计数据库失败，历史流量不会保存: %v\n", err)
+		return s
+	}
+	s.db = db
+	s.migrateLegacy(filepath.Join(dataDir, "traffic_stats.json"))
+	return s
+}
+
+// trafficKey 时间桶的键：起始时间的大端序编码，按键排序即按时间排序
+func trafficKey(t int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t))
+	return key
+}
+
+// migrateLegacy 将旧版整体写入的 traffic_stats.json 导入数据库
+func (s *trafficStats) migrateLegacy(legacyPath string) {
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return
+	}
+	var buckets []*TrafficBucket
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		fmt.Printf("⚠️ 读取旧版流量统计失败: %v\n", err)
+		return
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(trafficDBBucket)
+		for _, bucket := range buckets {
+			value, err := json.Marshal(bucket)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(trafficKey(bucket.Time), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("⚠️ 迁移流量统计失败: %v\n", err)
+		return
+	}
+	os.Remove(legacyPath)
+}
+
+// saveBucket 保存时间桶（同一时间的记录会被覆盖）
+func (s *trafficStats) saveBucket(bucket *TrafficBucket) error {
+	if s.db == nil {
+		return nil
+	}
+	value, err := json.Marshal(bucket)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(trafficDBBucket).Put(trafficKey(bucket.Time), value)
+	})
+}
+
+// loadBucket 读取指定时间的桶，不存在时返回 nil
+func (s *trafficStats) loadBucket(t int64) *TrafficBucket {
+	if s.db == nil {
+		return nil
+	}
+	var bucket *TrafficBucket
+	s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(trafficDBBucket).Get(trafficKey(t))
+		if value == nil {
+			return nil
+		}
+		var b TrafficBucket
+		if err := json.Unmarshal(value, &b); err != nil {
+			return err
+		}
+		bucket = &b
+		return nil
+	})
+	return bucket
+}
+
+// prune 删除早于 cutoff 的时间桶
+func (s *trafficStats) prune(cutoff int64) error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(trafficDBBucket)
+		var expired [][]byte
+		end := trafficKey(cutoff)
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// flush 保存当前未完成的时间桶并关闭数据库（停止时调用）
+func (s *trafficStats) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	var err error
+	if s.current != nil {
+		err = s.saveBucket(s.current)
+	}
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	s.db = nil
+	return err
+}
+
+// reset 核心停止后清空增量基准，避免重启后把旧计数算作新流量
+func (s *trafficStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.primed = false
+	s.lastConns = make(map[string]TrafficCounter)
+}
+
+// record 记录一次连接快照
+func (s *trafficStats) record(snapshot *ConnectionsSnapshot, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]TrafficCounter, len(snapshot.Connections))
+	for _, conn := range snapshot.Connections {
+		current[conn.ID] = TrafficCounter{Upload: conn.Upload, Download: conn.Download}
+	}
+	total := TrafficCounter{Upload: snapshot.UploadTotal, Download: snapshot.DownloadTotal}
+
+	// 首次采样只建立基准
+	if !s.primed {
+		s.primed = true
+		s.lastTotal = total
+		s.lastConns = current
+		return
+	}
+
+	bucket := s.currentBucket(now)
+
+	// 总流量以核心累计值为准（包含采样间隔内已关闭的连接），计数回退说明核心已重启
+	up, down := total.Upload-s.lastTotal.Upload, total.Download-s.lastTotal.Download
+	if up < 0 || down < 0 {
+		up, down = total.Upload, total.Download
+	}
+	bucket.Total.add(up, down)
+
+	for _, conn := range snapshot.Connections {
+		last := s.lastConns[conn.ID]
+		up, down := conn.Upload-last.Upload, conn.Download-last.Download
+		if up < 0 || down < 0 || (up == 0 && down == 0) {
+			continue
+		}
+
+		// chains 最后一项为规则命中的代理组
+		group := "DIRECT"
+		if len(conn.Chains) > 0 {
+			group = conn.Chains[len(conn.Chains)-1]
+		}
+		counterFor(bucket.Groups, group).add(up, down)
+
+		domain := conn.Metadata.Host
+		if domain == "" {
+			domain = conn.Metadata.DestinationIP
+		}
+		if domain != "" {
+			counterFor(bucket.Domains, domain).add(up, down)
+		}
+	}
+
+	s.lastTotal = total
+	s.lastConns = current
+}
+
+// currentBucket 获取当前时间所在的桶，跨桶时整理并保存上一个桶、清理过期数据
+// 重启后仍在同一时间段内时继续累计已保存的桶
+func (s *trafficStats) currentBucket(now time.Time) *TrafficBucket {
+	start := now.Truncate(trafficBucketSize).Unix()
+	if s.current != nil && s.current.Time == start {
+		return s.current
+	}
+
+	if s.current != nil {
+		s.current.Domains = topCounters(s.current.Domains, trafficBucketDomains)
+		if err := s.saveBucket(s.current); err != nil {
+			fmt.Printf("⚠️ 保存流量统计失败: %v\n", err)
+		}
+	}
+	if err := s.prune(now.Add(-trafficRetention).Unix()); err != nil {
+		fmt.Printf("⚠️ 清理过期流量统计失败: %v\n", err)
+	}
+
+	bucket := s.loadBucket(start)
+	if bucket == nil {
+		bucket = &TrafficBucket{Time: start}
+	}
+	if bucket.Groups == nil {
+		bucket.Groups = make(map[string]*TrafficCounter)
+	}
+	if bucket.Domains == nil {
+		bucket.Domains = make(map[string]*TrafficCounter)
+	}
+	s.current = bucket
+	return bucket
+}
+
+// history 汇总指定时间范围内的流量，已保存的桶从数据库按时间范围读取
+func (s *trafficStats) history(rangeDur time.Duration, now time.Time) *TrafficHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 长时间范围按小时合并数据点，避免图表点数过多
+	interval := trafficBucketSize
+	if rangeDur > 24*time.Hour {
+		interval = time.Hour
+	}
+
+	result := &TrafficHistory{
+		Interval: int(interval.Seconds()),
+		Points:   []TrafficPoint{},
+	}
+	groups := make(map[string]*TrafficCounter)
+	domains := make(map[string]*TrafficCounter)
+	cutoff := now.Add(-rangeDur).Unix()
+
+	add := func(bucket *TrafficBucket) {
+		result.Total.add(bucket.Total.Upload, bucket.Total.Download)
+
+		pointTime := time.Unix(bucket.Time, 0).Truncate(interval).Unix()
+		if n := len(result.Points); n > 0 && result.Points[n-1].Time == pointTime {
+			result.Points[n-1].Upload += bucket.Total.Upload
+			result.Points[n-1].Download += bucket.Total.Download
+		} else {
+			result.Points = append(result.Points, TrafficPoint{
+				Time:     pointTime,
+				Upload:   bucket.Total.Upload,
+				Download: bucket.Total.Download,
+			})
+		}
+
+		for name, counter := range bucket.Groups {
+			counterFor(groups, name).add(counter.Upload, counter.Download)
+		}
+		for name, counter := range bucket.Domains {
+			counterFor(domains, name).add(counter.Upload, counter.Download)
+		}
+	}
+
+	if s.db != nil {
+		err := s.db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket(trafficDBBucket).Cursor()
+			for k, v := c.Seek(trafficKey(cutoff)); k != nil; k, v = c.Next() {
+				var bucket TrafficBucket
+				if err := json.Unmarshal(v, &bucket); err != nil {
+					continue
+				}
+				// 当前桶以内存中的数据为准
+				if s.current != nil && bucket.Time == s.current.Time {
+					continue
+				}
+				add(&bucket)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("⚠️ 读取流量统计失败: %v\n", err)
+		}
+	}
+	if s.current != nil && s.current.Time >= cutoff {
+		add(s.current)
+	}
+
+	result.Groups = rankCounters(groups, 0)
+	result.Domains = rankCounters(domains, trafficTopDomains)
+	return result
+}
+
+func counterFor(m map[string]*TrafficCounter, name string) *TrafficCounter {
+	counter, ok := m[name]
+	if !ok {
+		counter = &TrafficCounter{}
+		m[name] = counter
+	}
+	return counter
+}
+
+// rankCounters 按总流量降序排列，limit 为 0 时不限制数量
+func rankCounters(m map[string]*TrafficCounter, limit int) []TrafficRanking {
+	result := make([]TrafficRanking, 0, len(m))
+	for name, counter := range m {
+		result = append(result, TrafficRanking{Name: name, Upload: counter.Upload, Download: counter.Download})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Upload+result[i].Download > result[j].Upload+result[j].Download
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// topCounters 只保留流量最大的 limit 项
+func topCounters(m map[string]*TrafficCounter, limit int) map[string]*TrafficCounter {
+	if len(m) <= limit {
+		return m
+	}
+	result := make(map[string]*TrafficCounter, limit)
+	for _, r := range rankCounters(m, limit) {
+		result[r.Name] = m[r.Name]
+	}
+	return result
+}
+
+// startTrafficStats 启动后台采样
+func (h *Handler) startTrafficStats() {
+	go func() {
+		ticker := time.NewTicker(trafficSampleInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if !h.service.GetStatus().Running {
+				h.stats.reset()
+				h.ruleStats.reset()
+				continue
+			}
+			snapshot, err := h.fetchConnections(context.Background())
+			if err != nil {
+				continue
+			}
+			h.stats.record(snapshot, now)
+			h.ruleStats.record(snapshot, now)
+		}
+	}()
+}
+
+// parseStatsRange 解析统计范围，支持 30m、24h、7d 等格式
+func parseStatsRange(s string) (time.Duration, error) {
+	if s == "" {
+		return 24 * time.Hour, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("无效的时间范围: %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("无效的时间范围: %s", s)
+	}
+	return d, nil
+}
+
+// GetTrafficStats 获取历史流量统计（?range=24h）
+func (h *Handler) GetTrafficStats(c *gin.Context) {
+	rangeStr := c.DefaultQuery("range", "24h")
+	rangeDur, err := parseStatsRange(rangeStr)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if rangeDur > trafficRetention {
+		rangeDur = trafficRetention
+	}
+
+	history := h.stats.history(rangeDur, time.Now())
+	history.Range = rangeStr
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    history,
+	})
+}
@@ -0,0 +1,390 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transparentBypassMark 核心出站流量标记，nftables 规则遇到此标记直接放行
+const transparentBypassMark = 255
+
+// defaultDNSListenPort 核心 DNS 默认监听端口（DNS 劫持的目标端口）
+const defaultDNSListenPort = 1053
+
+// ipv6Available 检查内核是否启用了 IPv6
+func ipv6Available() bool {
+	_, err := os.Stat("/proc/sys/net/ipv6")
+	return err == nil
+}
+
+// SetTransparentIPv6 设置透明代理是否拦截 IPv6
+func (s *Service) SetTransparentIPv6(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentIPv6 = enabled
+	return s.saveConfig()
+}
+
+// transparentIPv6Enabled 配置开启且内核支持 IPv6 时才拦截 IPv6
+func (h *Handler) transparentIPv6Enabled() bool {
+	return h.service.GetConfig().TransparentIPv6 && ipv6Available()
+}
+
+// SetDNSHijack 设置透明代理 DNS 劫持开关
+func (s *Service) SetDNSHijack(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.DNSHijack = enabled
+	return s.saveConfig()
+}
+
+// SetBlockQUIC 设置透明代理是否拦截 QUIC
+func (s *Service) SetBlockQUIC(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.BlockQUIC = enabled
+	return s.saveConfig()
+}
+
+// TransparentBypass 透明代理绕过列表
+// 列表中的局域网设备在路由器模式下不经过代理（游戏主机、IoT 设备等）
+type TransparentBypass struct {
+	IPs  []string `json:"ips"`  // IPv4/IPv6 地址或 CIDR
+	MACs []string `json:"macs"` // MAC 地址
+}
+
+// normalize 校验并规范化绕过列表，去除重复项
+func (b TransparentBypass) normalize() (TransparentBypass, error) {
+	result := TransparentBypass{IPs: []string{}, MACs: []string{}}
+	seen := make(map[string]bool)
+
+	for _, raw := range b.IPs {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, ipNet, err := net.ParseCIDR(item)
+			if err != nil {
+				return result, fmt.Errorf("无效的 CIDR: %s", raw)
+			}
+			item = ipNet.String()
+		} else {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return result, fmt.Errorf("无效的 IP 地址: %s", raw)
+			}
+			item = ip.String()
+		}
+		if !seen[item] {
+			seen[item] = true
+			result.IPs = append(result.IPs, item)
+		}
+	}
+
+	for _, raw := range b.MACs {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(item)
+		if err != nil || len(mac) != 6 {
+			return result, fmt.Errorf("无效的 MAC 地址: %s", raw)
+		}
+		item = mac.String()
+		if !seen[item] {
+			seen[item] = true
+			result.MACs = append(result.MACs, item)
+		}
+	}
+
+	return result, nil
+}
+
+// splitIPs 按地址族拆分 IP 列表
+func (b TransparentBypass) splitIPs() (ipv4 []string, ipv6 []string) {
+	for _, item := range b.IPs {
+		host := item
+		if i := strings.Index(item, "/"); i >= 0 {
+			host = item[:i]
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			ipv4 = append(ipv4, item)
+		} else {
+			ipv6 = append(ipv6, item)
+		}
+	}
+	return ipv4, ipv6
+}
+
+// GetTransparentBypass 获取透明代理绕过列表
+func (s *Service) GetTransparentBypass() TransparentBypass {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bypass := TransparentBypass{
+		IPs:  append([]string{}, s.config.TransparentBypass.IPs...),
+		MACs: append([]string{}, s.config.TransparentBypass.MACs...),
+	}
+	return bypass
+}
+
+// SetTransparentBypass 设置透明代理绕过列表
+func (s *Service) SetTransparentBypass(bypass TransparentBypass) (TransparentBypass, error) {
+	normalized, err := bypass.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentBypass = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetTransparentBypass 获取透明代理绕过列表
+func (h *Handler) GetTransparentBypass(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetTransparentBypass(),
+	})
+}
+
+// SetTransparentBypass 更新透明代理绕过列表，核心运行中时立即重新应用规则
+func (h *Handler) SetTransparentBypass(c *gin.Context) {
+	var req TransparentBypass
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	bypass, err := h.service.SetTransparentBypass(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "绕过列表已保存，但应用规则失败: "+err.Error()).WithDetails(bypass))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bypass,
+	})
+}
+
+// buildBypassSets 生成绕过列表对应的 nft 集合定义
+func buildBypassSets(bypass TransparentBypass) string {
+	ipv4, ipv6 := bypass.splitIPs()
+
+	// auto-merge 允许绕过列表与直连设备的地址段重叠
+	return nftSet("bypass_ipv4", "ipv4_addr", "interval; auto-merge", ipv4) +
+		nftSet("bypass_ipv6", "ipv6_addr", "interval; auto-merge", ipv6) +
+		nftSet("bypass_mac", "ether_addr", "", bypass.MACs)
+}
+
+// nftSet 生成 nft 集合定义，元素为空时只定义集合
+func nftSet(name, typ, flags string, elements []string) string {
+	body := fmt.Sprintf("\n    set %s {\n        type %s\n", name, typ)
+	if flags != "" {
+		body += fmt.Sprintf("        flags %s\n", flags)
+	}
+	if len(elements) > 0 {
+		body += fmt.Sprintf("        elements = { %s }\n", strings.Join(elements, ", "))
+	}
+	return body + "    }\n"
+}
+
+// TransparentPolicyRouting 策略路由状态
+type TransparentPolicyRouting struct {
+	IPv4Rule  bool `json:"ipv4Rule"`  // ip rule fwmark -> table
+	IPv4Route bool `json:"ipv4Route"` // local 0.0.0.0/0 dev lo
+	IPv6Rule  bool `json:"ipv6Rule"`
+	IPv6Route bool `json:"ipv6Route"`
+}
+
+// TransparentStatus 透明代理内核状态
+type TransparentStatus struct {
+	Supported     bool                     `json:"supported"`     // 当前平台是否支持 nftables 透明代理
+	Mode          string                   `json:"mode"`          // 已保存的模式
+	Scope         string                   `json:"scope"`         // 已保存的作用域
+	CoreRunning   bool                     `json:"coreRunning"`   // 核心是否运行中
+	IPv6          bool                     `json:"ipv6"`          // 是否拦截 IPv6
+	Expected      bool                     `json:"expected"`      // 当前是否应存在规则
+	TableExists   bool                     `json:"tableExists"`   // nftables 表是否存在
+	Chains        []string                 `json:"chains"`        // 实际存在的链
+	Sets          []string                 `json:"sets"`          // 实际存在的集合
+	RuleCount     int                      `json:"ruleCount"`     // tproxy/redirect 规则数量
+	DetectedMode  string                   `json:"detectedMode"`  // 根据规则推断的模式
+	PolicyRouting TransparentPolicyRouting `json:"policyRouting"` // 策略路由状态
+	InSync        bool                     `json:"inSync"`        // 已保存模式与内核状态是否一致
+	Issues        []string                 `json:"issues"`        // 不一致的具体原因
+	NftAvailable  bool                     `json:"nftAvailable"`  // 是否找到 nft 命令
+	Marks         TransparentMarks         `json:"marks"`         // 当前使用的标记和路由表
+	Conflicts     []string                 `json:"conflicts"`     // 与其他工具冲突的策略路由或 nftables 规则
+}
+
+// GetTransparentStatus 获取透明代理规则的实际状态，用于检测配置与内核状态的偏差
+func (h *Handler) GetTransparentStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.inspectTransparentState(),
+	})
+}
+
+// inspectTransparentState 检查 nftables 表、链和策略路由是否与已保存的模式一致
+func (h *Handler) inspectTransparentState() *TransparentStatus {
+	status := h.service.GetStatus()
+	result := &TransparentStatus{
+		Supported:   runtime.GOOS == "linux",
+		Mode:        status.TransparentMode,
+		Scope:       status.ProxyScope,
+		CoreRunning: status.Running,
+		Chains:      []string{},
+		Sets:        []string{},
+		Issues:      []string{},
+		Marks:       h.service.transparentMarks(),
+		Conflicts:   []string{},
+	}
+	if result.Mode == "" {
+		result.Mode = "off"
+	}
+	if result.Scope == "" {
+		result.Scope = "local"
+	}
+
+	if !result.Supported {
+		result.InSync = result.Mode == "off"
+		if !result.InSync {
+			result.Issues = append(result.Issues, "当前平台不支持 nftables 透明代理")
+		}
+		return result
+	}
+
+	// 规则只在核心运行期间存在
+	result.Expected = result.Mode != "off" && result.CoreRunning
+	result.IPv6 = h.transparentIPv6Enabled()
+
+	if _, err := exec.LookPath("nft"); err == nil {
+		result.NftAvailable = true
+		h.inspectNftTable(result)
+	} else if result.Expected {
+		result.Issues = append(result.Issues, "未找到 nft 命令")
+	}
+	h.inspectPolicyRouting(result)
+	result.Conflicts = detectMarkConflicts(result.Marks)
+
+	if result.Expected {
+		if !result.TableExists {
+			result.Issues = append(result.Issues, "nftables 表 proxystation 不存在")
+		} else {
+			expectedChains := []string{"output"}
+			if result.Scope == "router" {
+				expectedChains = append(expectedChains, "prerouting")
+			}
+			for _, chain := range expectedChains {
+				if !containsString(result.Chains, chain) {
+					result.Issues = append(result.Issues, fmt.Sprintf("缺少 %s 链", chain))
+				}
+			}
+			for _, set := range []string{"local_nets", "local_nets6"} {
+				if !containsString(result.Sets, set) {
+					result.Issues = append(result.Issues, fmt.Sprintf("缺少 %s 集合", set))
+				}
+			}
+			if result.DetectedMode != "" && result.DetectedMode != result.Mode {
+				result.Issues = append(result.Issues, fmt.Sprintf("内核规则为 %s 模式，与已保存的 %s 不一致", result.DetectedMode, result.Mode))
+			}
+		}
+
+		if result.Mode == "tproxy" {
+			pr := result.PolicyRouting
+			if !pr.IPv4Rule || !pr.IPv4Route {
+				result.Issues = append(result.Issues, "IPv4 策略路由缺失")
+			}
+			if result.IPv6 && (!pr.IPv6Rule || !pr.IPv6Route) {
+				result.Issues = append(result.Issues, "IPv6 策略路由缺失")
+			}
+		}
+	} else if result.TableExists {
+		result.Issues = append(result.Issues, "存在残留的 nftables 规则")
+	}
+
+	result.InSync = len(result.Issues) == 0
+	return result
+}
+
+// inspectNftTable 解析 nft list table 输出
+func (h *Handler) inspectNftTable(result *TransparentStatus) {
+	output, err := exec.Command("nft", "list", "table", "inet", "proxystation").CombinedOutput()
+	if err != nil {
+		return
+	}
+
+	result.TableExists = true
+
+	hasTProxy, hasRedirect := false, false
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "chain":
+			result.Chains = append(result.Chains, fields[1])
+		case "set":
+			result.Sets = append(result.Sets, fields[1])
+		}
+		if strings.Contains(line, "tproxy to") {
+			hasTProxy = true
+			result.RuleCount++
+		} else if strings.Contains(line, "redirect to") {
+			hasRedirect = true
+			result.RuleCount++
+		}
+	}
+
+	switch {
+	case hasTProxy:
+		result.DetectedMode = "tproxy"
+	case hasRedirect:
+		result.DetectedMode = "redirect"
+	case containsString(result.Chains, "output"):
+		// local 作用域的 tproxy 模式只在 output 链打标记
+		if strings.Contains(string(output), "meta mark set") {
+			result.DetectedMode = "tproxy"
+		}
+	}
+}
+
+// inspectPolicyRouting 检查 tproxy 所需的策略路由
+func (h *Handler) inspectPolicyRouting(result *TransparentStatus) {
+	marks := result.Marks
+	state := &result.PolicyRouting
+	state.IPv4Rule, state.IPv4Route, _ = h.netfilter.PolicyRouteState(PolicyRoute{Mark: marks.Mark, TableID: marks.TableID})
+	state.IPv6Rule, state.IPv6Route, _ = h.netfilter.PolicyRouteState(PolicyRoute{IPv6: true, Mark: marks.Mark, TableID: marks.TableID})
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
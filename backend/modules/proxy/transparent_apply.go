@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// nftReplacePrefix 在同一事务中先删除旧表（add 保证表不存在时 delete 不报错）
+	nftReplacePrefix = "add table inet proxystation\ndelete table inet proxystation\n"
+
+	transparentPortWait     = 5 * time.Second // 等待核心监听透明代理端口
+	transparentProbeTimeout = 3 * time.Second // 看门狗连通性探测超时
+	applyProbeTimeout       = 2 * time.Second // 应用规则时的连通性探测超时
+)
+
+// nftSnapshot 应用新规则前的内核状态，失败时据此回滚
+type nftSnapshot struct {
+	table  string            // nft list table 输出，表不存在时为空
+	marks  *TransparentMarks // 已配置策略路由使用的标记
+	routes []PolicyRoute     // 实际存在的策略路由
+}
+
+// snapshotNftState 记录当前的 proxystation 表和策略路由
+func (h *Handler) snapshotNftState() (*nftSnapshot, error) {
+	table, err := h.netfilter.ListTable("inet", "proxystation")
+	if err != nil {
+		return nil, err
+	}
+	snap := &nftSnapshot{table: table}
+
+	h.marksMu.Lock()
+	if h.appliedMarks != nil {
+		marks := *h.appliedMarks
+		snap.marks = &marks
+	}
+	h.marksMu.Unlock()
+
+	if snap.marks != nil {
+		check := &TransparentStatus{Marks: *snap.marks}
+		h.inspectPolicyRouting(check)
+		pr := check.PolicyRouting
+		if pr.IPv4Rule && pr.IPv4Route {
+			snap.routes = append(snap.routes, PolicyRoute{Mark: snap.marks.Mark, TableID: snap.marks.TableID})
+		}
+		if pr.IPv6Rule && pr.IPv6Route {
+			snap.routes = append(snap.routes, PolicyRoute{IPv6: true, Mark: snap.marks.Mark, TableID: snap.marks.TableID})
+		}
+	}
+	return snap, nil
+}
+
+// restoreNftState 恢复快照，快照中没有表时清除新规则
+func (h *Handler) restoreNftState(snap *nftSnapshot) error {
+	if snap == nil || snap.table == "" {
+		h.clearNftRules()
+		if snap == nil {
+			return nil
+		}
+	} else {
+		// 旧表在同一事务中替换新表
+		if err := h.netfilter.ApplyRuleset(nftReplacePrefix + snap.table); err != nil {
+			h.clearNftRules()
+			return err
+		}
+		h.clearPolicyRouting()
+	}
+	for _, route := range snap.routes {
+		if err := h.netfilter.AddPolicyRoute(route); err != nil {
+			return err
+		}
+	}
+	if len(snap.routes) > 0 {
+		h.marksMu.Lock()
+		h.appliedMarks = snap.marks
+		h.marksMu.Unlock()
+	}
+	return nil
+}
+
+// rollbackNftRules 应用失败时回滚到之前的规则，返回带回滚结果的错误
+func (h *Handler) rollbackNftRules(snap *nftSnapshot, cause error) error {
+	if err := h.restoreNftState(snap); err != nil {
+		fmt.Printf("❌ 回滚 nftables 规则失败: %v\n", err)
+		return fmt.Errorf("%v（回滚失败，规则已清除: %v）", cause, err)
+	}
+	if snap != nil && snap.table != "" {
+		fmt.Println("↩️ 已回滚到之前的 nftables 规则")
+		return fmt.Errorf("%v（已回滚到之前的规则）", cause)
+	}
+	fmt.Println("↩️ 已清除未完成的 nftables 规则")
+	return fmt.Errorf("%v（已清除未完成的规则）", cause)
+}
+
+// verifyNftRules 检查内核中的规则与期望的模式一致
+func (h *Handler) verifyNftRules(mode, scope string, marks TransparentMarks) error {
+	result := &TransparentStatus{Marks: marks}
+	h.inspectNftTable(result)
+	if !result.TableExists {
+		return fmt.Errorf("规则校验失败: nftables 表 proxystation 不存在")
+	}
+
+	expectedChains := []string{"output"}
+	if scope == "router" {
+		expectedChains = append(expectedChains, "prerouting")
+	}
+	for _, chain := range expectedChains {
+		if !containsString(result.Chains, chain) {
+			return fmt.Errorf("规则校验失败: 缺少 %s 链", chain)
+		}
+	}
+	if result.DetectedMode != mode {
+		return fmt.Errorf("规则校验失败: 内核规则为 %q 模式，期望 %s", result.DetectedMode, mode)
+	}
+
+	if mode == "tproxy" {
+		h.inspectPolicyRouting(result)
+		pr := result.PolicyRouting
+		if !pr.IPv4Rule || !pr.IPv4Route {
+			return fmt.Errorf("规则校验失败: IPv4 策略路由缺失")
+		}
+		if h.transparentIPv6Enabled() && (!pr.IPv6Rule || !pr.IPv6Route) {
+			return fmt.Errorf("规则校验失败: IPv6 策略路由缺失")
+		}
+	}
+	return nil
+}
+
+// waitListenPort 等待核心监听透明代理端口（核心刚启动时可能尚未就绪）
+func waitListenPort(port int, timeout time.Duration) error {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("核心未监听透明代理端口 %d: %v", port, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// applyProbeURL 应用规则时使用的探测地址，未开启 ProbeOnApply 时返回空字符串
+func (h *Handler) applyProbeURL() string {
+	cfg := h.service.transparentWatchdog()
+	if !cfg.ProbeOnApply {
+		return ""
+	}
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+	return defaultDelayTestURL
+}
+
+// probeConnectivity 请求探测地址，检查本机出站流量是否正常
+// 规则生效后本机流量经过核心，探测失败说明规则导致断网
+func probeConnectivity(url string) error {
+	return probeURL(url, 0, applyProbeTimeout)
+}
+
+// probeURL 请求探测地址，mark 非 0 时为 socket（包括 DNS 查询）设置该标记
+// 使用核心出站标记时流量不经过透明代理规则，可用于判断直连是否正常
+func probeURL(url string, mark int, timeout time.Duration) error {
+	dialer := &net.Dialer{Timeout: timeout}
+	if mark != 0 {
+		dialer.Control = markControl(mark)
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Control: markControl(mark)}
+				return d.DialContext(ctx, network, address)
+			},
+		}
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		// 不使用环境变量中的代理
+		Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// builtinLocalNets 始终直连的地址段：本机、未指定、链路本地和组播地址，代理这些地址没有意义
+var builtinLocalNets = []string{
+	"0.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fe80::/10", "ff00::/8",
+}
+
+// defaultLocalNets 默认直连的私有和保留地址段，可在透明代理设置中修改
+var defaultLocalNets = []string{
+	"10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.168.0.0/16",
+	"::ffff:0:0/96", "64:ff9b::/96", "fc00::/7",
+}
+
+// DirectIPSet 一组直连的目标地址（如公司内网、NAS 所在网段）
+type DirectIPSet struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	CIDRs   []string `json:"cidrs"`
+}
+
+// TransparentDirect 透明代理直连的目标地址和端口
+type TransparentDirect struct {
+	LocalNets []string      `json:"localNets"` // 直连地址段，提交 null 时恢复默认
+	Ports     []string      `json:"ports"`     // 直连目标端口，如 22、6881-6889
+	IPSets    []DirectIPSet `json:"ipSets"`
+}
+
+// normalizeCIDRs 校验地址段，单个 IP 转换为 /32 或 /128，去除重复项
+func normalizeCIDRs(items []string) ([]string, error) {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, raw := range items {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", raw)
+			}
+			if ip.To4() != nil {
+				item = ip.String() + "/32"
+			} else {
+				item = ip.String() + "/128"
+			}
+		}
+		item, err := canonicalCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 CIDR: %s", raw)
+		}
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// canonicalCIDR 返回地址段的标准写法
+// net 包会把 ::ffff:0:0/96 这类 IPv4 映射地址转换成 IPv4 写法（0.0.0.0/0），这里保持 IPv6 写法
+func canonicalCIDR(item string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(item)
+	if err != nil {
+		return "", err
+	}
+	if ipNet.IP.To4() != nil && strings.Contains(item, ":") {
+		return strings.ToLower(item), nil
+	}
+	return ipNet.String(), nil
+}
+
+// normalizePorts 校验端口和端口范围（如 6881-6889），去除重复项
+func normalizePorts(items []string) ([]string, error) {
+	result := []string{}
+	seen := make(map[string]bool)
+	parse := func(s string) (int, bool) {
+		port, err := strconv.Atoi(strings.TrimSpace(s))
+		return port, err == nil && port > 0 && port <= 65535
+	}
+	for _, raw := range items {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+		if from, to, ok := strings.Cut(item, "-"); ok {
+			start, ok1 := parse(from)
+			end, ok2 := parse(to)
+			if !ok1 || !ok2 || start > end {
+				return nil, fmt.Errorf("无效的端口范围: %s", raw)
+			}
+			item = fmt.Sprintf("%d-%d", start, end)
+			if start == end {
+				item = strconv.Itoa(start)
+			}
+		} else {
+			port, ok := parse(item)
+			if !ok {
+				return nil, fmt.Errorf("无效的端口: %s", raw)
+			}
+			item = strconv.Itoa(port)
+		}
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// normalize 校验并规范化直连配置
+func (d TransparentDirect) normalize() (TransparentDirect, error) {
+	result := TransparentDirect{IPSets: []DirectIPSet{}}
+	var err error
+
+	localNets := d.LocalNets
+	if localNets == nil {
+		localNets = defaultLocalNets
+	}
+	if result.LocalNets, err = normalizeCIDRs(localNets); err != nil {
+		return result, err
+	}
+	if result.Ports, err = normalizePorts(d.Ports); err != nil {
+		return result, err
+	}
+
+	names := make(map[string]bool)
+	for i, set := range d.IPSets {
+		set.Name = strings.TrimSpace(set.Name)
+		if set.Name == "" {
+			return result, fmt.Errorf("第 %d 个地址集合缺少名称", i+1)
+		}
+		if names[set.Name] {
+			return result, fmt.Errorf("地址集合名称重复: %s", set.Name)
+		}
+		names[set.Name] = true
+		if set.CIDRs, err = normalizeCIDRs(set.CIDRs); err != nil {
+			return result, fmt.Errorf("地址集合 %s: %w", set.Name, err)
+		}
+		result.IPSets = append(result.IPSets, set)
+	}
+	return result, nil
+}
+
+// destinations 合并内置、自定义和已启用集合中的地址段，按地址族拆分
+func (d TransparentDirect) destinations() (ipv4, ipv6 []string) {
+	all := append([]string{}, builtinLocalNets...)
+	all = append(all, d.LocalNets...)
+	for _, set := range d.IPSets {
+		if set.Enabled {
+			all = append(all, set.CIDRs...)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, item := range all {
+		cidr, err := canonicalCIDR(item)
+		if err != nil || seen[cidr] {
+			continue
+		}
+		seen[cidr] = true
+		if strings.Contains(cidr, ":") {
+			ipv6 = append(ipv6, cidr)
+		} else {
+			ipv4 = append(ipv4, cidr)
+		}
+	}
+	return ipv4, ipv6
+}
+
+// buildLocalNetSets 生成直连地址段集合，auto-merge 允许自定义地址段与内置地址段重叠
+func buildLocalNetSets(direct TransparentDirect) string {
+	ipv4, ipv6 := direct.destinations()
+	return nftSet("local_nets", "ipv4_addr", "interval; auto-merge", ipv4) +
+		nftSet("local_nets6", "ipv6_addr", "interval; auto-merge", ipv6)
+}
+
+// buildDirectPortRule 生成直连目标端口规则
+func buildDirectPortRule(ports []string) string {
+	if len(ports) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+        # 直连的目标端口
+        meta l4proto { tcp, udp } th dport { %s } return
+`, strings.Join(ports, ", "))
+}
+
+// GetTransparentDirect 获取透明代理直连配置
+func (s *Service) GetTransparentDirect() TransparentDirect {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	direct := s.config.TransparentDirect
+	direct.LocalNets = append([]string{}, direct.LocalNets...)
+	direct.Ports = append([]string{}, direct.Ports...)
+	direct.IPSets = make([]DirectIPSet, 0, len(s.config.TransparentDirect.IPSets))
+	for _, set := range s.config.TransparentDirect.IPSets {
+		set.CIDRs = append([]string{}, set.CIDRs...)
+		direct.IPSets = append(direct.IPSets, set)
+	}
+	return direct
+}
+
+// SetTransparentDirect 设置透明代理直连配置
+func (s *Service) SetTransparentDirect(direct TransparentDirect) (TransparentDirect, error) {
+	normalized, err := direct.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentDirect = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetTransparentDirect 获取透明代理直连配置，同时返回不可修改的内置地址段
+func (h *Handler) GetTransparentDirect(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"direct":   h.service.GetTransparentDirect(),
+			"builtin":  builtinLocalNets,
+			"defaults": defaultLocalNets,
+		},
+	})
+}
+
+// SetTransparentDirect 更新透明代理直连配置，核心运行中时立即重新应用规则
+func (h *Handler) SetTransparentDirect(c *gin.Context) {
+	var req TransparentDirect
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	direct, err := h.service.SetTransparentDirect(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "直连设置已保存，但应用规则失败: "+err.Error()).WithDetails(direct))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    direct,
+	})
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransparentExport 透明代理规则导出，内容与启动核心时应用的规则一致
+type TransparentExport struct {
+	Mode      string           `json:"mode"`
+	Scope     string           `json:"scope"`
+	Port      int              `json:"port"`
+	Marks     TransparentMarks `json:"marks"`
+	NftScript string           `json:"nftScript"` // nft -f 加载的完整规则
+	IPRules   []string         `json:"ipRules"`   // 策略路由命令（仅 tproxy 模式）
+	Sysctl    []string         `json:"sysctl"`    // 内核参数（仅路由器模式）
+	Teardown  []string         `json:"teardown"`  // 清除规则的命令
+	Script    string           `json:"script"`    // 可直接执行的 shell 脚本（参数 stop 时清除）
+}
+
+// buildTransparentExport 生成指定模式和作用域的规则，不修改系统状态
+func (h *Handler) buildTransparentExport(mode, scope string) (*TransparentExport, error) {
+	marks := h.service.transparentMarks()
+	port := transparentListenPort(h.service.GetConfig(), mode)
+	ipv6 := h.transparentIPv6Enabled()
+	nftScript, err := h.buildNftScript(mode, scope, port, marks)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &TransparentExport{
+		Mode:      mode,
+		Scope:     scope,
+		Port:      port,
+		Marks:     marks,
+		NftScript: nftScript,
+		IPRules:   []string{},
+		Sysctl:    []string{},
+		Teardown:  []string{"nft delete table inet proxystation"},
+	}
+
+	routes := []PolicyRoute{{Mark: marks.Mark, TableID: marks.TableID}}
+	if ipv6 {
+		routes = append(routes, PolicyRoute{IPv6: true, Mark: marks.Mark, TableID: marks.TableID})
+	}
+	if mode == "tproxy" {
+		for _, r := range routes {
+			family, mark, table, dst := r.args()
+			export.IPRules = append(export.IPRules,
+				fmt.Sprintf("ip %s rule add fwmark %s lookup %s", family, mark, table),
+				fmt.Sprintf("ip %s route add local %s dev lo table %s", family, dst, table),
+			)
+			export.Teardown = append(export.Teardown,
+				fmt.Sprintf("ip %s rule del fwmark %s lookup %s", family, mark, table),
+				fmt.Sprintf("ip %s route del local %s dev lo table %s", family, dst, table),
+			)
+		}
+	}
+
+	if scope == "router" {
+		export.Sysctl = append(export.Sysctl, "net.ipv4.ip_forward=1")
+		if ipv6 {
+			export.Sysctl = append(export.Sysctl, "net.ipv6.conf.all.forwarding=1")
+		}
+	}
+
+	export.Script = export.shellScript()
+	return export, nil
+}
+
+// shellScript 生成 shell 脚本：默认应用规则，参数 stop 时清除
+// 清除命令忽略错误（规则可能不存在），应用命令出错即退出
+func (e *TransparentExport) shellScript() string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# ProxyStation 透明代理规则 (mode=%s, scope=%s, port=%d)\n", e.Mode, e.Scope, e.Port)
+	fmt.Fprintf(&b, "# 生成时间: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	b.WriteString("# 用法: sh proxystation-transparent.sh [start|stop]\n\n")
+
+	b.WriteString("stop() {\n")
+	for _, cmd := range e.Teardown {
+		fmt.Fprintf(&b, "    %s 2>/dev/null || true\n", cmd)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("start() {\n")
+	b.WriteString("    stop\n")
+	b.WriteString("    set -e\n")
+	b.WriteString("    nft -f - <<'NFT'\n")
+	b.WriteString(e.NftScript)
+	b.WriteString("\nNFT\n")
+	for _, cmd := range e.IPRules {
+		fmt.Fprintf(&b, "    %s\n", cmd)
+	}
+	for _, kv := range e.Sysctl {
+		fmt.Fprintf(&b, "    sysctl -w %s\n", kv)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("case \"$1\" in\n")
+	b.WriteString("    stop) stop ;;\n")
+	b.WriteString("    *) start ;;\n")
+	b.WriteString("esac\n")
+	return b.String()
+}
+
+// ExportTransparentRules 导出透明代理规则，便于审查或在后端没有 root 权限时手动应用
+// 参数: mode / scope 默认为当前保存的设置；format=script 时直接下载 shell 脚本
+func (h *Handler) ExportTransparentRules(c *gin.Context) {
+	status := h.service.GetStatus()
+	mode := c.DefaultQuery("mode", status.TransparentMode)
+	scope := c.DefaultQuery("scope", status.ProxyScope)
+	if scope == "" {
+		scope = "local"
+	}
+
+	if !isTransparentMode(mode) {
+		apierror.Message(c, http.StatusBadRequest, fmt.Sprintf("模式 %s 不使用 nftables 规则，仅支持 tproxy / redirect", mode))
+		return
+	}
+	if scope != "local" && scope != "router" {
+		apierror.Message(c, http.StatusBadRequest, "scope 只能为 local 或 router")
+		return
+	}
+
+	export, err := h.buildTransparentExport(mode, scope)
+	if err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "生成规则失败: "+err.Error())
+		return
+	}
+	if c.Query("format") == "script" {
+		c.Header("Content-Disposition", "attachment; filename=proxystation-transparent.sh")
+		c.Header("Content-Type", "text/x-shellscript")
+		c.String(http.StatusOK, export.Script)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    export,
+	})
+}
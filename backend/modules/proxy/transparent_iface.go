@@ -0,0 +1,277 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/modules/lan"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ifaceNamePattern 网卡名（最长 15 个字符），末尾的 * 为通配符
+var ifaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.@:+-]{1,15}\*?$`)
+
+// TransparentInterfaces 路由器模式下拦截的网卡
+// LAN 为空时拦截所有网卡（旁路由等单网口场景），WAN 中的网卡始终不拦截
+type TransparentInterfaces struct {
+	LAN []string `json:"lan"` // 局域网网卡，只拦截从这些网卡进入的流量
+	WAN []string `json:"wan"` // 外网网卡，支持 ppp* 等通配符
+
+	// 容器和组网网卡的流量及其地址段不拦截（tproxy 这些流量会导致容器网络和组网异常）
+	ExcludeDocker  bool     `json:"excludeDocker"`  // docker0、veth*、docker 自定义网络的 br-xxxx 网桥
+	ExcludeOverlay bool     `json:"excludeOverlay"` // tailscale*、zt*（ZeroTier）
+	Exclude        []string `json:"exclude"`        // 其他不拦截的网卡，支持通配符
+}
+
+// overlayInterfaces 组网软件使用的网卡名
+var overlayInterfaces = []string{"tailscale*", "zt*"}
+
+// normalize 校验网卡名并去重，同一网卡不能同时作为 LAN 和 WAN
+func (t TransparentInterfaces) normalize() (TransparentInterfaces, error) {
+	result := TransparentInterfaces{
+		LAN:            []string{},
+		WAN:            []string{},
+		ExcludeDocker:  t.ExcludeDocker,
+		ExcludeOverlay: t.ExcludeOverlay,
+		Exclude:        []string{},
+	}
+	seen := make(map[string]string)
+
+	add := func(list *[]string, raw, role string) error {
+		name := strings.TrimSpace(raw)
+		if name == "" {
+			return nil
+		}
+		if !ifaceNamePattern.MatchString(name) {
+			return fmt.Errorf("无效的网卡名: %s", raw)
+		}
+		// LAN 列表生成 iifname != {...} 集合，不支持通配符
+		if role == "LAN" && strings.HasSuffix(name, "*") {
+			return fmt.Errorf("LAN 网卡不支持通配符: %s", raw)
+		}
+		if name == "lo" {
+			return fmt.Errorf("不能选择回环网卡 lo")
+		}
+		if prev, ok := seen[name]; ok {
+			if prev != role {
+				return fmt.Errorf("网卡 %s 不能同时出现在 %s 和 %s 列表中", name, prev, role)
+			}
+			return nil
+		}
+		seen[name] = role
+		*list = append(*list, name)
+		return nil
+	}
+
+	for _, name := range t.LAN {
+		if err := add(&result.LAN, name, "LAN"); err != nil {
+			return result, err
+		}
+	}
+	for _, name := range t.WAN {
+		if err := add(&result.WAN, name, "WAN"); err != nil {
+			return result, err
+		}
+	}
+	for _, name := range t.Exclude {
+		if err := add(&result.Exclude, name, "排除"); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// matchInterface 网卡名是否匹配（支持末尾 * 通配符）
+func matchInterface(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// excludedInterfaces 计算不拦截的网卡及其地址段
+// docker 自定义网络的网桥与 OpenWrt 的 br-lan 同为 br- 前缀，存在其他 br- 网卡时只排除已检测到的 docker 网桥
+func excludedInterfaces(ifaces TransparentInterfaces, detected []lan.NetworkInterface) (names, nets4, nets6 []string) {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		for _, l := range ifaces.LAN {
+			// 用户明确选择的 LAN 网卡优先
+			if matchInterface(name, l) {
+				return
+			}
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if ifaces.ExcludeDocker {
+		add("docker*")
+		add("veth*")
+		wildcardBridge := true
+		for _, iface := range detected {
+			if iface.Kind == lan.KindDocker && strings.HasPrefix(iface.Name, "br-") {
+				add(iface.Name)
+			} else if strings.HasPrefix(iface.Name, "br-") {
+				wildcardBridge = false
+			}
+		}
+		for _, l := range ifaces.LAN {
+			if strings.HasPrefix(l, "br-") {
+				wildcardBridge = false
+			}
+		}
+		if wildcardBridge {
+			add("br-*")
+		}
+	}
+	if ifaces.ExcludeOverlay {
+		for _, name := range overlayInterfaces {
+			add(name)
+		}
+	}
+	for _, name := range ifaces.Exclude {
+		add(name)
+	}
+
+	// 已存在的网卡的地址段，本机访问容器和组网地址时同样不拦截
+	netSeen := make(map[string]bool)
+	for _, iface := range detected {
+		matched := false
+		for _, name := range names {
+			if matchInterface(name, iface.Name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, addr := range iface.Addresses {
+			_, ipNet, err := net.ParseCIDR(addr)
+			if err != nil || ipNet.IP.IsLinkLocalUnicast() || netSeen[ipNet.String()] {
+				continue
+			}
+			netSeen[ipNet.String()] = true
+			if ipNet.IP.To4() != nil {
+				nets4 = append(nets4, ipNet.String())
+			} else {
+				nets6 = append(nets6, ipNet.String())
+			}
+		}
+	}
+	return names, nets4, nets6
+}
+
+// buildExcludedNetSets 生成不拦截地址段的 nft 集合定义
+func buildExcludedNetSets(nets4, nets6 []string) string {
+	return nftSet("excluded_nets", "ipv4_addr", "interval; auto-merge", nets4) +
+		nftSet("excluded_nets6", "ipv6_addr", "interval; auto-merge", nets6)
+}
+
+// excludedNetRules 各链中跳过不拦截地址段的规则
+const excludedNetRules = `
+        # 容器、组网网卡的地址段不代理
+        ip daddr @excluded_nets return
+        ip6 daddr @excluded_nets6 return
+`
+
+// buildInterfaceFilter 生成 prerouting 链开头的网卡过滤规则
+// tproxy 模式下本机流量经 lo 重新进入 prerouting，因此 lo 始终拦截
+func buildInterfaceFilter(ifaces TransparentInterfaces, excluded []string) string {
+	rules := ""
+	if len(ifaces.LAN) > 0 {
+		names := make([]string, 0, len(ifaces.LAN)+1)
+		names = append(names, `"lo"`)
+		for _, name := range ifaces.LAN {
+			names = append(names, fmt.Sprintf("%q", name))
+		}
+		rules += fmt.Sprintf(`
+        # 只拦截局域网网卡进入的流量
+        iifname != { %s } return
+`, strings.Join(names, ", "))
+	}
+	if len(ifaces.WAN) > 0 {
+		rules += `
+        # 外网网卡进入的流量不拦截
+`
+		for _, name := range ifaces.WAN {
+			rules += fmt.Sprintf("        iifname %q return\n", name)
+		}
+	}
+	if len(excluded) > 0 {
+		rules += `
+        # 容器、组网等虚拟网卡进入的流量不拦截
+`
+		for _, name := range excluded {
+			rules += fmt.Sprintf("        iifname %q return\n", name)
+		}
+	}
+	return rules
+}
+
+// GetTransparentInterfaces 获取路由器模式拦截的网卡
+func (s *Service) GetTransparentInterfaces() TransparentInterfaces {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ifaces := s.config.TransparentInterfaces
+	ifaces.LAN = append([]string{}, ifaces.LAN...)
+	ifaces.WAN = append([]string{}, ifaces.WAN...)
+	ifaces.Exclude = append([]string{}, ifaces.Exclude...)
+	return ifaces
+}
+
+// SetTransparentInterfaces 设置路由器模式拦截的网卡
+func (s *Service) SetTransparentInterfaces(ifaces TransparentInterfaces) (TransparentInterfaces, error) {
+	normalized, err := ifaces.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentInterfaces = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetTransparentInterfaces 获取路由器模式拦截的网卡
+func (h *Handler) GetTransparentInterfaces(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetTransparentInterfaces(),
+	})
+}
+
+// SetTransparentInterfaces 更新路由器模式拦截的网卡，核心运行中时立即重新应用规则
+// 可选的网卡见 GET /api/network/interfaces
+func (h *Handler) SetTransparentInterfaces(c *gin.Context) {
+	var req TransparentInterfaces
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ifaces, err := h.service.SetTransparentInterfaces(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "网卡设置已保存，但应用规则失败: "+err.Error()).WithDetails(ifaces))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    ifaces,
+	})
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// 透明代理默认使用的标记和路由表
+const (
+	defaultTransparentMark  = 1
+	defaultTransparentTable = 100
+)
+
+// TransparentMarks 透明代理使用的 fwmark 和策略路由表
+// 默认值可能与其他工具（clash verge、OpenWrt fw4 等）冲突，可在代理设置中修改
+type TransparentMarks struct {
+	Mark       int `json:"mark"`       // tproxy 流量标记，策略路由据此查表
+	BypassMark int `json:"bypassMark"` // 核心出站流量标记，nftables 遇到此标记直接放行
+	TableID    int `json:"tableId"`    // tproxy 策略路由表
+}
+
+// marksFromSettings 从代理设置读取标记，未设置时使用默认值
+func marksFromSettings(settings *ProxySettings) TransparentMarks {
+	marks := TransparentMarks{
+		Mark:       defaultTransparentMark,
+		BypassMark: transparentBypassMark,
+		TableID:    defaultTransparentTable,
+	}
+	if settings == nil {
+		return marks
+	}
+	if settings.TransparentMark > 0 {
+		marks.Mark = settings.TransparentMark
+	}
+	if settings.RoutingMark > 0 {
+		marks.BypassMark = settings.RoutingMark
+	}
+	if settings.TransparentTable > 0 {
+		marks.TableID = settings.TransparentTable
+	}
+	return marks
+}
+
+// validateTransparentMarks 校验代理设置中的标记、路由表和出站接口
+func validateTransparentMarks(settings *ProxySettings) error {
+	// 出站接口会写入 nftables 规则，不允许通配符
+	if name := settings.InterfaceName; name != "" && (!ifaceNamePattern.MatchString(name) || strings.HasSuffix(name, "*")) {
+		return fmt.Errorf("无效的出站接口名称: %s", name)
+	}
+
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"透明代理标记", settings.TransparentMark},
+		{"路由标记", settings.RoutingMark},
+		{"策略路由表", settings.TransparentTable},
+	} {
+		if v.value < 0 || v.value > 0x7fffffff {
+			return fmt.Errorf("%s超出范围: %d", v.name, v.value)
+		}
+	}
+
+	marks := marksFromSettings(settings)
+	if marks.Mark == marks.BypassMark {
+		return fmt.Errorf("透明代理标记不能与路由标记相同: %d", marks.Mark)
+	}
+	// 253-255 是系统保留的 default/main/local 路由表
+	if marks.TableID >= 253 && marks.TableID <= 255 {
+		return fmt.Errorf("策略路由表 %d 是系统保留表", marks.TableID)
+	}
+	if settings.TUN.Iproute2TableIndex > 0 && marks.TableID == settings.TUN.Iproute2TableIndex {
+		return fmt.Errorf("策略路由表 %d 与 TUN 路由表冲突", marks.TableID)
+	}
+	return nil
+}
+
+// egressInterface 核心绑定的出站网卡，未设置时为空
+func (s *Service) egressInterface() string {
+	if s.settingsProvider == nil {
+		return ""
+	}
+	if settings := s.settingsProvider(); settings != nil {
+		return settings.InterfaceName
+	}
+	return ""
+}
+
+// transparentMarks 当前生效的标记和路由表
+func (s *Service) transparentMarks() TransparentMarks {
+	if s.settingsProvider != nil {
+		return marksFromSettings(s.settingsProvider())
+	}
+	return marksFromSettings(nil)
+}
+
+// ipRule ip rule show 输出中的一条规则
+type ipRule struct {
+	Mark    int64
+	HasMark bool
+	Table   string
+	Raw     string
+}
+
+// parseIPRules 解析 ip rule show 输出，如 "32765: from all fwmark 0x1 lookup 100"
+func parseIPRules(output string) []ipRule {
+	var rules []ipRule
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rule := ipRule{Raw: line}
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "fwmark":
+				value, _, _ := strings.Cut(fields[i+1], "/")
+				if mark, err := strconv.ParseInt(value, 0, 64); err == nil {
+					rule.Mark = mark
+					rule.HasMark = true
+				}
+			case "lookup", "table":
+				rule.Table = fields[i+1]
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isOwnRule 是否为 ProxyStation 添加的策略路由规则
+func (m TransparentMarks) isOwnRule(rule ipRule) bool {
+	return rule.HasMark && rule.Mark == int64(m.Mark) && rule.Table == strconv.Itoa(m.TableID)
+}
+
+// detectMarkConflicts 检查其他工具是否占用了相同的标记或路由表
+func detectMarkConflicts(marks TransparentMarks) []string {
+	conflicts := []string{}
+	table := strconv.Itoa(marks.TableID)
+
+	for _, family := range []string{"-4", "-6"} {
+		out, err := exec.Command("ip", family, "rule", "show").CombinedOutput()
+		if err != nil {
+			continue
+		}
+		for _, rule := range parseIPRules(string(out)) {
+			if marks.isOwnRule(rule) {
+				continue
+			}
+			switch {
+			case rule.HasMark && rule.Mark == int64(marks.Mark):
+				conflicts = append(conflicts, fmt.Sprintf("fwmark %d 已被其他策略路由使用: %s", marks.Mark, rule.Raw))
+			case rule.HasMark && rule.Mark == int64(marks.BypassMark):
+				conflicts = append(conflicts, fmt.Sprintf("路由标记 %d 已被其他策略路由使用: %s", marks.BypassMark, rule.Raw))
+			case rule.Table == table:
+				conflicts = append(conflicts, fmt.Sprintf("路由表 %d 已被其他策略路由使用: %s", marks.TableID, rule.Raw))
+			}
+		}
+
+		// 路由表中除 tproxy 的 local 路由外不应有其他路由
+		if out, err := exec.Command("ip", family, "route", "show", "table", table).CombinedOutput(); err == nil {
+			for _, line := range strings.Split(string(out), "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" && !(strings.HasPrefix(line, "local") && strings.Contains(line, "dev lo")) {
+					conflicts = append(conflicts, fmt.Sprintf("路由表 %d 中存在其他路由: %s", marks.TableID, line))
+				}
+			}
+		}
+	}
+
+	if out, err := exec.Command("nft", "list", "ruleset").CombinedOutput(); err == nil {
+		conflicts = append(conflicts, nftMarkConflicts(string(out), marks)...)
+	}
+	return conflicts
+}
+
+// nftMarkConflicts 查找其他 nftables 表中设置相同标记的规则
+func nftMarkConflicts(ruleset string, marks TransparentMarks) []string {
+	var conflicts []string
+	currentTable := ""
+	for _, line := range strings.Split(ruleset, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "table ") {
+			currentTable = strings.TrimSuffix(strings.TrimPrefix(line, "table "), " {")
+			continue
+		}
+		if currentTable == "inet proxystation" {
+			continue
+		}
+		_, value, ok := strings.Cut(line, "meta mark set ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		mark, err := strconv.ParseInt(fields[0], 0, 64)
+		if err != nil {
+			continue
+		}
+		if mark == int64(marks.Mark) || mark == int64(marks.BypassMark) {
+			conflicts = append(conflicts, fmt.Sprintf("nftables 表 %s 也设置了标记 %d: %s", currentTable, mark, line))
+		}
+	}
+	return conflicts
+}
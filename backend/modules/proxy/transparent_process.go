@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按进程分流的匹配类型
+const (
+	processMatchUID    = "uid"    // 用户名或 UID，匹配 meta skuid
+	processMatchCgroup = "cgroup" // cgroup v2 路径，匹配 socket cgroupv2
+)
+
+// cgroupRoot cgroup v2 挂载点
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupPathPattern cgroup 路径只允许常见字符，避免注入 nft 脚本
+var cgroupPathPattern = regexp.MustCompile(`^[A-Za-z0-9_.@:-]+(/[A-Za-z0-9_.@:-]+)*$`)
+
+// userNamePattern Linux 用户名
+var userNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*\$?$`)
+
+// ProcessRule 按用户或 cgroup 匹配本机进程
+type ProcessRule struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`  // uid / cgroup
+	Value   string `json:"value"` // 用户名、UID 或 cgroup 路径（如 system.slice/qbittorrent.service）
+	Enabled bool   `json:"enabled"`
+}
+
+// TransparentProcesses 本机进程分流（仅作用于本机出站流量）
+type TransparentProcesses struct {
+	Mode  string        `json:"mode"` // exclude: 列表中的进程直连; include: 只代理列表中的进程
+	Rules []ProcessRule `json:"rules"`
+}
+
+// normalize 校验并规范化进程分流配置
+func (p TransparentProcesses) normalize() (TransparentProcesses, error) {
+	result := TransparentProcesses{Mode: p.Mode, Rules: []ProcessRule{}}
+	if result.Mode == "" {
+		result.Mode = "exclude"
+	}
+	if result.Mode != "exclude" && result.Mode != "include" {
+		return result, fmt.Errorf("无效的分流模式: %s", p.Mode)
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range p.Rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Value = strings.TrimSpace(rule.Value)
+		if rule.Value == "" {
+			return result, fmt.Errorf("进程规则缺少匹配值")
+		}
+		switch rule.Type {
+		case processMatchUID:
+			if _, err := strconv.ParseUint(rule.Value, 10, 32); err != nil && !userNamePattern.MatchString(rule.Value) {
+				return result, fmt.Errorf("无效的用户: %s", rule.Value)
+			}
+		case processMatchCgroup:
+			rule.Value = strings.Trim(strings.TrimPrefix(rule.Value, cgroupRoot), "/")
+			if !cgroupPathPattern.MatchString(rule.Value) {
+				return result, fmt.Errorf("无效的 cgroup 路径: %s", rule.Value)
+			}
+		default:
+			return result, fmt.Errorf("无效的匹配类型: %s", rule.Type)
+		}
+		key := rule.Type + ":" + rule.Value
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if rule.Name == "" {
+			rule.Name = rule.Value
+		}
+		result.Rules = append(result.Rules, rule)
+	}
+	return result, nil
+}
+
+// resolveUID 用户名转换为 UID
+func resolveUID(value string) (string, error) {
+	if _, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return value, nil
+	}
+	u, err := user.Lookup(value)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid, nil
+}
+
+// processMatches 解析已启用的规则，返回 UID 列表和存在的 cgroup 路径
+// nft 加载规则时会解析 cgroup 路径，不存在的路径会导致整个脚本加载失败，这里跳过
+func (p TransparentProcesses) processMatches() (uids, cgroups []string) {
+	seen := make(map[string]bool)
+	for _, rule := range p.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		switch rule.Type {
+		case processMatchUID:
+			uid, err := resolveUID(rule.Value)
+			if err != nil {
+				fmt.Printf("⚠️ 进程分流: 用户 %s 不存在，已跳过\n", rule.Value)
+				continue
+			}
+			if !seen["uid:"+uid] {
+				seen["uid:"+uid] = true
+				uids = append(uids, uid)
+			}
+		case processMatchCgroup:
+			if _, err := os.Stat(filepath.Join(cgroupRoot, rule.Value)); err != nil {
+				fmt.Printf("⚠️ 进程分流: cgroup %s 不存在，已跳过\n", rule.Value)
+				continue
+			}
+			if !seen["cgroup:"+rule.Value] {
+				seen["cgroup:"+rule.Value] = true
+				cgroups = append(cgroups, rule.Value)
+			}
+		}
+	}
+	return uids, cgroups
+}
+
+// cgroupMatch 生成 cgroup v2 匹配表达式，level 为路径层级（需要内核 5.13+ 和 nft 1.0+）
+func cgroupMatch(path, op string) string {
+	level := len(strings.Split(path, "/"))
+	return fmt.Sprintf(`socket cgroupv2 level %d %s"%s"`, level, op, path)
+}
+
+// buildProcessFilter 生成本机进程分流规则（output 链和 dns_output 链使用）
+// exclude 模式下匹配的进程直接放行；include 模式下不匹配任何规则的进程直接放行
+func buildProcessFilter(processes TransparentProcesses) string {
+	uids, cgroups := processes.processMatches()
+	if len(uids) == 0 && len(cgroups) == 0 {
+		if processes.Mode == "include" && len(processes.Rules) > 0 {
+			fmt.Println("⚠️ 进程分流: 没有可用的规则，include 模式未生效")
+		}
+		return ""
+	}
+
+	var b strings.Builder
+	if processes.Mode == "include" {
+		// 多个不等条件写在同一条规则中，全部不匹配时放行
+		var conds []string
+		if len(uids) > 0 {
+			conds = append(conds, fmt.Sprintf("meta skuid != { %s }", strings.Join(uids, ", ")))
+		}
+		for _, path := range cgroups {
+			conds = append(conds, cgroupMatch(path, "!= "))
+		}
+		b.WriteString("\n        # 只代理列表中的进程\n")
+		b.WriteString("        " + strings.Join(conds, " ") + " return\n")
+		return b.String()
+	}
+
+	b.WriteString("\n        # 列表中的进程直连\n")
+	if len(uids) > 0 {
+		b.WriteString(fmt.Sprintf("        meta skuid { %s } return\n", strings.Join(uids, ", ")))
+	}
+	for _, path := range cgroups {
+		b.WriteString("        " + cgroupMatch(path, "") + " return\n")
+	}
+	return b.String()
+}
+
+// GetTransparentProcesses 获取进程分流配置
+func (s *Service) GetTransparentProcesses() TransparentProcesses {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	processes := s.config.TransparentProcesses
+	if processes.Mode == "" {
+		processes.Mode = "exclude"
+	}
+	processes.Rules = append([]ProcessRule{}, processes.Rules...)
+	return processes
+}
+
+// SetTransparentProcesses 设置进程分流配置
+func (s *Service) SetTransparentProcesses(processes TransparentProcesses) (TransparentProcesses, error) {
+	normalized, err := processes.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentProcesses = normalized
+	return normalized, s.saveConfig()
+}
+
+// GetTransparentProcesses 获取进程分流配置
+func (h *Handler) GetTransparentProcesses(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetTransparentProcesses(),
+	})
+}
+
+// SetTransparentProcesses 更新进程分流配置，核心运行中时立即重新应用规则
+func (h *Handler) SetTransparentProcesses(c *gin.Context) {
+	var req TransparentProcesses
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	processes, err := h.service.SetTransparentProcesses(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "进程分流设置已保存，但应用规则失败: "+err.Error()).WithDetails(processes))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    processes,
+	})
+}
@@ -0,0 +1,353 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/modules/lan"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nftTableHeader 规则必须位于此表中，清除规则时按表名删除
+const nftTableHeader = "table inet proxystation {"
+
+// defaultNftTemplate 默认 nftables 规则模板（text/template 语法）
+// dns_bypass / ipv6_bypass 为各链共用的片段，自定义模板可以直接引用
+const defaultNftTemplate = `{{define "dns_bypass"}}{{if .DNSHijack}}
+        # DNS 查询由 dns 链劫持
+        meta l4proto { tcp, udp } th dport 53 return
+{{end}}{{end -}}
+
+{{define "ipv6_bypass"}}{{if not .IPv6}}
+        # 不拦截 IPv6
+        meta nfproto ipv6 return
+{{end}}{{end -}}
+
+{{define "egress_bypass"}}{{if .EgressInterface}}
+        # 核心绑定的出站网卡不拦截（多 WAN / VPN 底层网卡，不只依赖路由标记）
+        oifname "{{.EgressInterface}}" return
+{{end}}{{end -}}
+
+{{define "quic_reject"}}{{if .BlockQUIC}}
+        # 拒绝 QUIC（UDP 443），浏览器回退到 TCP（核心出站流量已在上方放行）
+        udp dport 443 reject
+{{end}}{{end -}}
+
+table inet proxystation {
+{{.Sets}}{{if .Router}}{{if .TProxy}}
+    chain prerouting {
+        type filter hook prerouting priority mangle; policy accept;
+{{.InterfaceFilter}}
+        # IPSec 不代理
+        udp dport { 500, 4500, 1701 } return
+        meta l4proto esp return
+{{template "dns_bypass" .}}{{template "ipv6_bypass" .}}
+        # 绕过列表中的设备不代理
+        ip saddr @bypass_ipv4 return
+        ip6 saddr @bypass_ipv6 return
+        ether saddr @bypass_mac return
+
+        # 已建立的 transparent socket 连接直接打标记
+        meta l4proto { tcp, udp } socket transparent 1 meta mark set {{.Mark}} accept
+
+        # 本地地址不代理
+        ip daddr @local_nets return
+        ip6 daddr @local_nets6 return
+{{.DirectRules}}{{if .BlockQUIC}}
+        # 丢弃 QUIC（UDP 443），浏览器回退到 TCP（旧内核的 prerouting 链不支持 reject）
+        udp dport 443 drop
+{{end}}
+        # TCP/UDP 流量 TProxy 到 mihomo
+        meta l4proto { tcp, udp } tproxy to :{{.Port}} meta mark set {{.Mark}} accept
+    }{{else}}
+    chain prerouting {
+        type filter hook prerouting priority mangle; policy accept;
+{{.InterfaceFilter}}
+        # IPSec 不代理
+        udp dport { 500, 4500, 1701 } return
+        meta l4proto esp return
+{{template "dns_bypass" .}}{{template "ipv6_bypass" .}}
+        # 绕过列表中的设备不代理
+        ip saddr @bypass_ipv4 return
+        ip6 saddr @bypass_ipv6 return
+        ether saddr @bypass_mac return
+
+        # 本地地址不代理
+        ip daddr @local_nets return
+        ip6 daddr @local_nets6 return
+{{.DirectRules}}{{if .BlockQUIC}}
+        # 丢弃 QUIC（UDP 443），浏览器回退到 TCP（旧内核的 prerouting 链不支持 reject）
+        udp dport 443 drop
+{{end}}
+        # TCP 流量 REDIRECT 到 mihomo (redirect 不支持 UDP)
+        meta l4proto tcp redirect to :{{.Port}}
+    }{{end}}{{end}}
+{{if .TProxy}}
+    chain output {
+        type route hook output priority mangle; policy accept;
+
+        # IPSec 不代理
+        udp dport { 500, 4500, 1701 } return
+        meta l4proto esp return
+{{template "dns_bypass" .}}{{template "ipv6_bypass" .}}
+        # 本地地址不代理
+        ip daddr @local_nets return
+        ip6 daddr @local_nets6 return
+{{.DirectRules}}{{.ProcessFilter}}
+        # 已标记的包跳过（避免循环）
+        meta mark {{.Mark}} return
+
+        # 入站服务端流量不代理（mark {{.BypassMark}}）
+        meta mark {{.BypassMark}} return
+{{template "egress_bypass" .}}{{template "quic_reject" .}}
+        # 本机出站 TCP/UDP 打标记（触发重路由到 prerouting）
+        meta l4proto { tcp, udp } meta mark set {{.Mark}}
+    }{{else}}
+    chain output {
+        type route hook output priority mangle; policy accept;
+
+        # IPSec 不代理
+        udp dport { 500, 4500, 1701 } return
+        meta l4proto esp return
+{{template "dns_bypass" .}}{{template "ipv6_bypass" .}}
+        # 本地地址不代理
+        ip daddr @local_nets return
+        ip6 daddr @local_nets6 return
+{{.DirectRules}}{{.ProcessFilter}}
+        # 已标记的包跳过（避免循环）
+        meta mark {{.Mark}} return
+
+        # 入站服务端流量不代理
+        meta mark {{.BypassMark}} return
+{{template "egress_bypass" .}}{{template "quic_reject" .}}
+        # 本机出站 TCP REDIRECT 到 mihomo
+        meta l4proto tcp redirect to :{{.Port}}
+    }{{end}}
+{{if .DNSHijack}}{{if .Router}}
+    chain dns_prerouting {
+        type nat hook prerouting priority dstnat; policy accept;
+{{.InterfaceFilter}}{{template "ipv6_bypass" .}}
+        # 绕过列表中的设备不劫持
+        ip saddr @bypass_ipv4 return
+        ip6 saddr @bypass_ipv6 return
+        ether saddr @bypass_mac return
+
+        # 局域网设备 DNS 查询重定向到核心
+        meta l4proto { tcp, udp } th dport 53 redirect to :{{.DNSPort}}
+    }{{end}}
+    chain dns_output {
+        type nat hook output priority -100; policy accept;
+{{template "ipv6_bypass" .}}{{.ProcessFilter}}
+        # 核心自身的上游查询不劫持
+        meta mark {{.BypassMark}} return
+{{template "egress_bypass" .}}
+        # 本机 DNS 查询重定向到核心
+        meta l4proto { tcp, udp } th dport 53 redirect to :{{.DNSPort}}
+    }{{end}}
+}`
+
+// NftTemplateData nftables 规则模板变量
+type NftTemplateData struct {
+	Mode            string `json:"mode"`            // tproxy / redirect
+	Scope           string `json:"scope"`           // local / router
+	TProxy          bool   `json:"tproxy"`          // Mode 为 tproxy
+	Router          bool   `json:"router"`          // Scope 为 router（拦截局域网设备）
+	Port            int    `json:"port"`            // 核心透明代理监听端口
+	DNSPort         int    `json:"dnsPort"`         // 核心 DNS 监听端口
+	Mark            int    `json:"mark"`            // tproxy 流量标记
+	BypassMark      int    `json:"bypassMark"`      // 核心出站流量标记
+	TableID         int    `json:"tableId"`         // 策略路由表
+	IPv6            bool   `json:"ipv6"`            // 拦截 IPv6
+	DNSHijack       bool   `json:"dnsHijack"`       // 劫持 53 端口 DNS 查询
+	BlockQUIC       bool   `json:"blockQuic"`       // 拦截 QUIC（UDP 443）
+	EgressInterface string `json:"egressInterface"` // 核心绑定的出站网卡（默认出口网卡时为空）
+	Sets            string `json:"sets"`            // local_nets / bypass / excluded_nets 等集合定义
+	InterfaceFilter string `json:"interfaceFilter"` // prerouting 链开头的网卡过滤规则
+	DirectRules     string `json:"directRules"`     // 直连的地址段和端口
+	ProcessFilter   string `json:"processFilter"`   // 本机进程分流规则（仅 output 链）
+}
+
+// nftTemplatePath 自定义模板文件，不存在时使用默认模板
+func (s *Service) nftTemplatePath() string {
+	return filepath.Join(s.dataDir, "nftables.tmpl")
+}
+
+// GetNftTemplate 获取当前模板，custom 表示为用户自定义
+func (s *Service) GetNftTemplate() (content string, custom bool) {
+	data, err := os.ReadFile(s.nftTemplatePath())
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return defaultNftTemplate, false
+	}
+	return string(data), true
+}
+
+// SetNftTemplate 保存自定义模板，内容为空时恢复默认模板
+func (s *Service) SetNftTemplate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		if err := os.Remove(s.nftTemplatePath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(s.nftTemplatePath(), []byte(content), 0644)
+}
+
+// renderNftTemplate 渲染模板并检查表名
+func renderNftTemplate(content string, data NftTemplateData) (string, error) {
+	tmpl, err := template.New("nftables").Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("模板语法错误: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("模板渲染失败: %w", err)
+	}
+	script := buf.String()
+	if !strings.Contains(script, nftTableHeader) {
+		return "", fmt.Errorf("规则必须定义在 %q 中", strings.TrimSuffix(nftTableHeader, " {"))
+	}
+	return script, nil
+}
+
+// nftTemplateData 收集生成规则所需的变量
+func (h *Handler) nftTemplateData(mode, scope string, port int, marks TransparentMarks) NftTemplateData {
+	// 路由器模式只拦截 LAN 网卡进入的流量，不处理 WAN、容器和组网网卡
+	ifaces := h.service.GetTransparentInterfaces()
+	detected, _ := lan.ListInterfaces()
+	excluded, excludedNets4, excludedNets6 := excludedInterfaces(ifaces, detected)
+
+	// 默认出口网卡上放行会让本机流量全部绕过代理，只依赖路由标记
+	egress := h.service.egressInterface()
+	for _, iface := range detected {
+		if iface.Name == egress && iface.DefaultRoute {
+			egress = ""
+		}
+	}
+
+	// 直连的目标地址段和端口（local_nets 集合之外的部分）
+	direct := h.service.GetTransparentDirect()
+	sets := buildLocalNetSets(direct) + buildBypassSets(h.transparentBypassWithDevices()) + buildExcludedNetSets(excludedNets4, excludedNets6)
+
+	return NftTemplateData{
+		Mode:            mode,
+		Scope:           scope,
+		TProxy:          mode == "tproxy",
+		Router:          scope == "router",
+		Port:            port,
+		DNSPort:         defaultDNSListenPort,
+		Mark:            marks.Mark,
+		BypassMark:      marks.BypassMark,
+		TableID:         marks.TableID,
+		IPv6:            h.transparentIPv6Enabled(),
+		DNSHijack:       h.service.GetConfig().DNSHijack,
+		BlockQUIC:       h.service.GetConfig().BlockQUIC,
+		EgressInterface: egress,
+		Sets:            strings.TrimPrefix(sets, "\n"),
+		InterfaceFilter: buildInterfaceFilter(ifaces, excluded),
+		DirectRules:     excludedNetRules + buildDirectPortRule(direct.Ports),
+		// 本机进程分流（按用户或 cgroup），只作用于本机出站流量
+		ProcessFilter: buildProcessFilter(h.service.GetTransparentProcesses()),
+	}
+}
+
+// buildNftScript 使用当前模板生成 nftables 规则脚本
+func (h *Handler) buildNftScript(mode, scope string, port int, marks TransparentMarks) (string, error) {
+	content, _ := h.service.GetNftTemplate()
+	return renderNftTemplate(content, h.nftTemplateData(mode, scope, port, marks))
+}
+
+// GetNftTemplate 获取 nftables 规则模板、默认模板和当前变量
+func (h *Handler) GetNftTemplate(c *gin.Context) {
+	content, custom := h.service.GetNftTemplate()
+	mode, scope := h.currentNftMode()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"template":  content,
+			"custom":    custom,
+			"default":   defaultNftTemplate,
+			"variables": h.nftTemplateData(mode, scope, transparentListenPort(h.service.GetConfig(), mode), h.service.transparentMarks()),
+		},
+	})
+}
+
+// SetNftTemplate 保存 nftables 规则模板，template 为空时恢复默认
+// 保存前按所有模式和作用域试渲染，透明代理运行中时立即重新应用规则
+func (h *Handler) SetNftTemplate(c *gin.Context) {
+	var req struct {
+		Template string `json:"template"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validateNftTemplate(req.Template); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.SetNftTemplate(req.Template); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.reapplyTransparentRules(); err != nil {
+		apierror.Message(c, http.StatusInternalServerError, "模板已保存，但应用规则失败: "+err.Error())
+		return
+	}
+
+	_, custom := h.service.GetNftTemplate()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"custom": custom},
+	})
+}
+
+// validateNftTemplate 按所有模式和作用域渲染模板，避免切换模式时才发现错误
+func (h *Handler) validateNftTemplate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		content = defaultNftTemplate
+	}
+	marks := h.service.transparentMarks()
+	for _, mode := range []string{"tproxy", "redirect"} {
+		for _, scope := range []string{"local", "router"} {
+			data := h.nftTemplateData(mode, scope, transparentListenPort(h.service.GetConfig(), mode), marks)
+			if _, err := renderNftTemplate(content, data); err != nil {
+				return fmt.Errorf("%s / %s: %v", mode, scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ReplaceNftTemplate 校验并保存模板（其他实例推送），内容为空时恢复默认模板
+// 不重新应用规则，由调用方重启核心生效
+func (h *Handler) ReplaceNftTemplate(content string) error {
+	if err := h.validateNftTemplate(content); err != nil {
+		return err
+	}
+	return h.service.SetNftTemplate(content)
+}
+
+// currentNftMode 当前保存的透明代理模式，未使用 nftables 时按 tproxy 展示变量
+func (h *Handler) currentNftMode() (mode, scope string) {
+	status := h.service.GetStatus()
+	mode, scope = status.TransparentMode, status.ProxyScope
+	if !isTransparentMode(mode) {
+		mode = "tproxy"
+	}
+	if scope == "" {
+		scope = "local"
+	}
+	return mode, scope
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultWatchdogTimeout  = 60 // 秒
+	defaultWatchdogInterval = 10 // 秒
+	minWatchdogInterval     = 5
+)
+
+// TransparentWatchdog 透明代理连通性看门狗配置
+// 开启后定期经代理探测外网，持续不可达超过 Timeout 秒时自动切换为 off，
+// 避免规则配置错误的无头路由器无法再登录管理界面
+type TransparentWatchdog struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Timeout  int    `json:"timeout" yaml:"timeout"`   // 持续不可达多少秒后关闭，默认 60
+	Interval int    `json:"interval" yaml:"interval"` // 探测间隔（秒），默认 10
+	URL      string `json:"url" yaml:"url"`           // 探测地址，为空时使用 generate_204
+	// 应用透明代理规则前后各探测一次，失败时回滚；离线或仅局域网部署应保持关闭
+	ProbeOnApply bool `json:"probeOnApply" yaml:"probe-on-apply"`
+}
+
+// normalize 填充默认值并校验
+func (w TransparentWatchdog) normalize() (TransparentWatchdog, error) {
+	if w.Interval == 0 {
+		w.Interval = defaultWatchdogInterval
+	}
+	if w.Timeout == 0 {
+		w.Timeout = defaultWatchdogTimeout
+	}
+	if w.Interval < minWatchdogInterval {
+		return w, fmt.Errorf("探测间隔不能小于 %d 秒", minWatchdogInterval)
+	}
+	if w.Timeout < w.Interval {
+		return w, fmt.Errorf("超时时间不能小于探测间隔")
+	}
+	if w.URL != "" {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return w, fmt.Errorf("无效的探测地址: %s", w.URL)
+		}
+	}
+	return w, nil
+}
+
+// WatchdogRevert 看门狗自动关闭透明代理的记录
+type WatchdogRevert struct {
+	Time   time.Time `json:"time"`
+	Mode   string    `json:"mode"`
+	Scope  string    `json:"scope"`
+	Reason string    `json:"reason"`
+}
+
+// WatchdogStatus 看门狗运行状态
+type WatchdogStatus struct {
+	TransparentWatchdog
+	Active       bool            `json:"active"`                 // 当前是否在监测（核心运行且为透明代理模式）
+	LastProbe    *time.Time      `json:"lastProbe,omitempty"`    // 最近一次探测时间
+	LastError    string          `json:"lastError,omitempty"`    // 最近一次探测失败原因
+	FailingSince *time.Time      `json:"failingSince,omitempty"` // 开始持续不可达的时间
+	LastRevert   *WatchdogRevert `json:"lastRevert,omitempty"`
+}
+
+// watchdogState 看门狗内部状态
+type watchdogState struct {
+	mu           sync.Mutex
+	active       bool
+	lastProbe    time.Time
+	lastError    string
+	failingSince time.Time
+	lastRevert   *WatchdogRevert
+}
+
+// transparentWatchdog 获取看门狗配置（已填充默认值）
+func (s *Service) transparentWatchdog() TransparentWatchdog {
+	s.mu.RLock()
+	w := s.config.TransparentWatchdog
+	s.mu.RUnlock()
+
+	if normalized, err := w.normalize(); err == nil {
+		return normalized
+	}
+	w.Interval, w.Timeout = defaultWatchdogInterval, defaultWatchdogTimeout
+	return w
+}
+
+// SetTransparentWatchdog 设置看门狗配置
+func (s *Service) SetTransparentWatchdog(w TransparentWatchdog) (TransparentWatchdog, error) {
+	normalized, err := w.normalize()
+	if err != nil {
+		return normalized, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.TransparentWatchdog = normalized
+	return normalized, s.saveConfig()
+}
+
+// startTransparentWatchdog 后台定期检查透明代理连通性
+func (h *Handler) startTransparentWatchdog() {
+	go func() {
+		for {
+			time.Sleep(time.Duration(h.service.transparentWatchdog().Interval) * time.Second)
+			h.checkTransparentWatchdog()
+		}
+	}()
+}
+
+// checkTransparentWatchdog 执行一次探测
+// 经代理不可达但直连（核心出站标记）可达时才计为规则故障，上游网络中断时不关闭透明代理
+func (h *Handler) checkTransparentWatchdog() {
+	cfg := h.service.transparentWatchdog()
+	status := h.service.GetStatus()
+	w := &h.watchdog
+
+	if !cfg.Enabled || runtime.GOOS != "linux" || !status.Running || !isTransparentMode(status.TransparentMode) {
+		w.mu.Lock()
+		w.active = false
+		w.failingSince = time.Time{}
+		w.lastError = ""
+		w.mu.Unlock()
+		return
+	}
+
+	probeURLStr := cfg.URL
+	if probeURLStr == "" {
+		probeURLStr = defaultDelayTestURL
+	}
+	err := probeURL(probeURLStr, 0, transparentProbeTimeout)
+	var directErr error
+	if err != nil {
+		directErr = probeURL(probeURLStr, h.service.transparentMarks().BypassMark, transparentProbeTimeout)
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	w.active = true
+	w.lastProbe = now
+	switch {
+	case err == nil:
+		w.failingSince = time.Time{}
+		w.lastError = ""
+	case directErr != nil:
+		// 直连同样不可达，属于上游网络故障
+		w.failingSince = time.Time{}
+		w.lastError = fmt.Sprintf("代理和直连均不可达（上游网络故障，不处理）: %v", err)
+	default:
+		if w.failingSince.IsZero() {
+			w.failingSince = now
+		}
+		w.lastError = err.Error()
+	}
+	failing := now.Sub(w.failingSince)
+	broken := !w.failingSince.IsZero()
+	w.mu.Unlock()
+
+	if !broken {
+		return
+	}
+	fmt.Printf("⚠️ 透明代理连通性探测失败（已持续 %d 秒）: %v\n", int(failing.Seconds()), err)
+	if failing >= time.Duration(cfg.Timeout)*time.Second {
+		reason := fmt.Sprintf("经代理访问 %s 持续 %d 秒失败，直连正常: %v", probeURLStr, int(failing.Seconds()), err)
+		h.revertTransparentMode(status.TransparentMode, status.ProxyScope, reason)
+	}
+}
+
+// revertTransparentMode 立即清除规则恢复网络，并将透明代理切换为 off
+func (h *Handler) revertTransparentMode(mode, scope, reason string) {
+	fmt.Printf("🚨 看门狗关闭透明代理 %s 模式: %s\n", mode, reason)
+	h.clearNftRules()
+
+	revert := &WatchdogRevert{Time: time.Now(), Mode: mode, Scope: scope, Reason: reason}
+	h.watchdog.mu.Lock()
+	h.watchdog.lastRevert = revert
+	h.watchdog.failingSince = time.Time{}
+	h.watchdog.active = false
+	h.watchdog.mu.Unlock()
+
+	if err := h.SwitchTransparentMode("off", scope); err != nil {
+		fmt.Printf("⚠️ 切换透明代理模式失败: %v\n", err)
+	}
+	h.service.publish(events.TransparentRevert, revert)
+}
+
+// watchdogStatus 看门狗配置和运行状态
+func (h *Handler) watchdogStatus() WatchdogStatus {
+	w := &h.watchdog
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := WatchdogStatus{
+		TransparentWatchdog: h.service.transparentWatchdog(),
+		Active:              w.active,
+		LastError:           w.lastError,
+		LastRevert:          w.lastRevert,
+	}
+	if !w.lastProbe.IsZero() {
+		t := w.lastProbe
+		status.LastProbe = &t
+	}
+	if !w.failingSince.IsZero() {
+		t := w.failingSince
+		status.FailingSince = &t
+	}
+	return status
+}
+
+// GetTransparentWatchdog 获取看门狗配置和状态
+func (h *Handler) GetTransparentWatchdog(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdogStatus(),
+	})
+}
+
+// SetTransparentWatchdog 更新看门狗配置
+func (h *Handler) SetTransparentWatchdog(c *gin.Context) {
+	var req TransparentWatchdog
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.service.SetTransparentWatchdog(req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdogStatus(),
+	})
+}
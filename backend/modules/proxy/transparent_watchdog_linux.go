@@ -0,0 +1,18 @@
+//go:build linux
+
+package proxy
+
+import "syscall"
+
+// markControl 为 socket 设置 SO_MARK（需要 CAP_NET_ADMIN）
+func markControl(mark int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, mark)
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
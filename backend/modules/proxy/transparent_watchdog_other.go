@@ -0,0 +1,10 @@
+//go:build !linux
+
+package proxy
+
+import "syscall"
+
+// markControl 非 Linux 不支持 SO_MARK，透明代理规则也只在 Linux 上使用
+func markControl(mark int) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tunDevice TUN 设备节点
+const tunDevice = "/dev/net/tun"
+
+// checkTUNSupport 检查 TUN 模式的运行条件：设备节点存在且可打开、具有 CAP_NET_ADMIN
+func checkTUNSupport() error {
+	info, err := os.Stat(tunDevice)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s 不存在，请加载 tun 内核模块（modprobe tun），容器中需添加 --device %s", tunDevice, tunDevice)
+	}
+	if err != nil {
+		return fmt.Errorf("无法访问 %s: %w", tunDevice, err)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("%s 不是字符设备", tunDevice)
+	}
+
+	if !hasCapability(capNetAdmin) {
+		return fmt.Errorf("TUN 模式需要 root 或 CAP_NET_ADMIN 权限")
+	}
+
+	file, err := os.OpenFile(tunDevice, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("无法打开 %s: %w", tunDevice, err)
+	}
+	file.Close()
+	return nil
+}
+
+// hasCapability 当前进程的有效能力集是否包含指定能力（核心以普通用户运行时通过 ambient 能力继承）
+func hasCapability(capability uint) bool {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return os.Geteuid() == 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<capability) != 0
+	}
+	return false
+}
+
+// enableIPForward 开启 IPv4/IPv6 转发，TUN 模式作为局域网网关时需要
+func enableIPForward() error {
+	for _, path := range []string{"/proc/sys/net/ipv4/ip_forward", "/proc/sys/net/ipv6/conf/all/forwarding"} {
+		if data, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(data)) == "1" {
+			continue
+		}
+		if err := os.WriteFile(path, []byte("1"), 0644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", path, err)
+		}
+	}
+	return nil
+}
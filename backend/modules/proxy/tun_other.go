@@ -0,0 +1,15 @@
+//go:build !linux
+
+package proxy
+
+import "ProxyStation/backend/apierror"
+
+// checkTUNSupport 非 Linux 暂不支持由 ProxyStation 管理 TUN 模式
+func checkTUNSupport() error {
+	return apierror.New(apierror.CodeUnsupported, "TUN 模式仅支持 Linux")
+}
+
+// enableIPForward 非 Linux 不需要
+func enableIPForward() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// validateWebSettings 校验管理界面监听和访问控制设置
+// 监听和证书设置在重启后才生效，保存时检查证书，避免重启后无法访问管理界面
+func validateWebSettings(web *WebSettings) error {
+	web.ListenAddress = strings.TrimSpace(web.ListenAddress)
+	web.CertFile = strings.TrimSpace(web.CertFile)
+	web.KeyFile = strings.TrimSpace(web.KeyFile)
+
+	if web.ListenAddress != "" && net.ParseIP(web.ListenAddress) == nil {
+		return fmt.Errorf("无效的监听地址: %s", web.ListenAddress)
+	}
+	for _, port := range []int{web.Port, web.HTTPRedirectPort} {
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("无效的端口: %d", port)
+		}
+	}
+
+	cidrs := web.AllowedCIDRs[:0]
+	for _, cidr := range web.AllowedCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			return fmt.Errorf("无效的来源地址: %s", cidr)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	web.AllowedCIDRs = cidrs
+
+	web.ClientCAFile = strings.TrimSpace(web.ClientCAFile)
+	if web.ClientCAFile != "" {
+		if !web.TLS {
+			return fmt.Errorf("客户端证书校验需要启用 HTTPS")
+		}
+		data, err := os.ReadFile(web.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端 CA 失败: %v", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(data) {
+			return fmt.Errorf("客户端 CA 中没有有效的 PEM 证书")
+		}
+	}
+
+	if !web.TLS {
+		return nil
+	}
+	if web.HTTPRedirectPort != 0 && web.HTTPRedirectPort == web.Port {
+		return fmt.Errorf("HTTP 重定向端口不能与 HTTPS 端口相同")
+	}
+	if (web.CertFile == "") != (web.KeyFile == "") {
+		return fmt.Errorf("证书和私钥需要同时设置，都为空时使用自签名证书")
+	}
+	if web.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(web.CertFile, web.KeyFile); err != nil {
+			return fmt.Errorf("加载证书失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// allowsRemote 来源地址白名单是否允许该地址访问，用于防止保存设置后把当前客户端拒之门外
+func allowsRemote(cidrs []string, remote string) bool {
+	ip := net.ParseIP(remote)
+	if len(cidrs) == 0 || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(cidr); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errEditConflict 提交的 If-Match 与当前版本不一致（其他页面或会话已修改）
+const errEditConflict = "配置已被其他会话修改，请刷新后重试"
+
+// versionTag 计算对象的版本标识（JSON 内容的哈希），作为 HTTP ETag 使用
+func versionTag(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ConfigETag 当前代理配置的版本标识
+func (s *Service) ConfigETag() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return versionTag(s.config)
+}
+
+// TemplateETag 当前配置模板的版本标识（包括 DNS 和 fake-ip 排除列表）
+func (s *Service) TemplateETag() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return versionTag(s.configTemplate)
+}
+
+// lockWrites 串行化配置和模板的修改，使版本检查和写入之间不会插入其他修改
+func (s *Service) lockWrites() func() {
+	s.writeMu.Lock()
+	return s.writeMu.Unlock
+}
+
+// etagMatches 检查 If-Match 请求头，未提供时不检查（兼容旧客户端和脚本）
+func etagMatches(ifMatch, current string) bool {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter 在写出响应头前附加最新的 ETag，修改成功后客户端可直接用于下一次提交
+type etagWriter struct {
+	gin.ResponseWriter
+	etag    func() string
+	written bool
+}
+
+func (w *etagWriter) setETag() {
+	if w.written {
+		return
+	}
+	w.written = true
+	if w.Status() < http.StatusMultipleChoices {
+		if tag := w.etag(); tag != "" {
+			w.Header().Set("ETag", tag)
+		}
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	w.setETag()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	w.setETag()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	w.setETag()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// versioned 为配置 / 模板路由附加乐观并发控制
+// 读取请求返回 ETag；修改请求串行执行，If-Match 与当前版本不一致时返回 412，避免多个页面互相覆盖修改
+func (h *Handler) versioned(etag func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &etagWriter{ResponseWriter: c.Writer, etag: etag}
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		unlock := h.service.lockWrites()
+		defer unlock()
+
+		if current := etag(); !etagMatches(c.GetHeader("If-Match"), current) {
+			c.Header("ETag", current)
+			apierror.Abort(c, http.StatusPreconditionFailed, apierror.New(apierror.CodeEditConflict, errEditConflict).WithDetails(gin.H{"etag": current}))
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,8 +1,11 @@
 package ruleset
 
 import (
+	"fmt"
 	"net/http"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -67,18 +70,12 @@ func (h *Handler) GetConfig(c *gin.Context) {
 func (h *Handler) SetConfig(c *gin.Context) {
 	var config RuleSetConfig
 	if err := c.ShouldBindJSON(&config); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    -1,
-			"message": "参数错误: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
 
 	if err := h.service.SetConfig(&config); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    -1,
-			"message": "保存配置失败: " + err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, fmt.Errorf("保存配置失败: %w", err))
 		return
 	}
 
@@ -91,10 +88,7 @@ func (h *Handler) SetConfig(c *gin.Context) {
 // UpdateAll 更新所有规则文件
 func (h *Handler) UpdateAll(c *gin.Context) {
 	if h.service.IsUpdating() {
-		c.JSON(http.StatusOK, gin.H{
-			"code":    1,
-			"message": "正在更新中，请稍后再试",
-		})
+		apierror.Message(c, http.StatusOK, "正在更新中，请稍后再试")
 		return
 	}
 
@@ -145,18 +139,12 @@ func (h *Handler) GetCustomRules(c *gin.Context) {
 func (h *Handler) AddCustomRule(c *gin.Context) {
 	var entry CustomRuleEntry
 	if err := c.ShouldBindJSON(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    -1,
-			"message": "参数错误: " + err.Error(),
-		})
+		apierror.Message(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
 
 	if err := h.service.AddCustomRule(entry); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    -1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -170,18 +158,12 @@ func (h *Handler) AddCustomRule(c *gin.Context) {
 func (h *Handler) DeleteCustomRule(c *gin.Context) {
 	name := c.Param("name")
 	if name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    -1,
-			"message": "规则名称不能为空",
-		})
+		apierror.Message(c, http.StatusBadRequest, "规则名称不能为空")
 		return
 	}
 
 	if err := h.service.DeleteCustomRule(name); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    -1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
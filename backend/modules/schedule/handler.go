@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"errors"
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 定时计划 API 处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建处理器
+func NewHandler(dataDir string) *Handler {
+	return &Handler{service: NewService(dataDir)}
+}
+
+// GetService 获取服务实例
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("", h.List)
+	r.POST("", h.Add)
+	r.GET("/:id", h.Get)
+	r.PUT("/:id", h.Update)
+	r.DELETE("/:id", h.Delete)
+	r.POST("/:id/run", h.Run) // 立即执行一次
+}
+
+// errorStatus 计划不存在时返回 404
+func errorStatus(err error, fallback int) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// List 获取所有计划
+func (h *Handler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.List(),
+	})
+}
+
+// Get 获取计划
+func (h *Handler) Get(c *gin.Context) {
+	sch, err := h.service.Get(c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sch,
+	})
+}
+
+// Add 添加计划
+func (h *Handler) Add(c *gin.Context) {
+	req := Schedule{Enabled: true}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sch, err := h.service.Add(req)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sch,
+	})
+}
+
+// Update 更新计划
+func (h *Handler) Update(c *gin.Context) {
+	var req Schedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sch, err := h.service.Update(c.Param("id"), req)
+	if err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sch,
+	})
+}
+
+// Delete 删除计划
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Run 立即执行计划的动作
+func (h *Handler) Run(c *gin.Context) {
+	if err := h.service.Run(c.Param("id")); err != nil {
+		apierror.Respond(c, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
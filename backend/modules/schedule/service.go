@@ -0,0 +1,481 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/google/uuid"
+)
+
+// 计划执行的动作
+const (
+	ActionStart       = "start"       // 启动核心
+	ActionStop        = "stop"        // 停止核心
+	ActionTransparent = "transparent" // 切换透明代理模式
+)
+
+// checkInterval 检查计划的间隔
+const checkInterval = 30 * time.Second
+
+// ErrNotFound 计划不存在
+var ErrNotFound = apierror.New(apierror.CodeNotFound, "计划不存在")
+
+// ProxyState 代理运行状态，计划结束时据此恢复
+type ProxyState struct {
+	Running bool   `json:"running"`
+	Mode    string `json:"mode"`
+	Scope   string `json:"scope"`
+}
+
+// Controller 计划执行时对代理核心的操作，由 server 注入
+type Controller struct {
+	State              func() ProxyState
+	Start              func() error
+	Stop               func() error
+	SetTransparentMode func(mode, scope string) error
+}
+
+// Schedule 每周定时计划
+// 设置了 End 时为时间段计划：时间段内执行动作，结束后恢复到开始前的状态；
+// End 为空时只在 Start 时刻执行一次
+type Schedule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Days    []int  `json:"days"`  // 星期几，0 为周日，为空表示每天
+	Start   string `json:"start"` // HH:MM
+	End     string `json:"end"`   // HH:MM，早于 Start 时表示跨越午夜，24:00 表示当天结束
+	Action  string `json:"action"`
+	Mode    string `json:"mode,omitempty"`  // transparent 动作的目标模式
+	Scope   string `json:"scope,omitempty"` // transparent 动作的目标作用域
+
+	// 执行状态
+	Active    bool        `json:"active"`             // 是否处于时间段内（已执行动作，尚未恢复）
+	Previous  *ProxyState `json:"previous,omitempty"` // 时间段开始前的状态
+	LastRun   int64       `json:"lastRun,omitempty"`
+	LastError string      `json:"lastError,omitempty"`
+}
+
+// Service 定时计划服务
+type Service struct {
+	dataDir    string
+	mu         sync.RWMutex
+	schedules  []*Schedule
+	controller *Controller
+	stopChan   chan struct{}
+}
+
+// NewService 创建定时计划服务
+func NewService(dataDir string) *Service {
+	s := &Service{
+		dataDir:  dataDir,
+		stopChan: make(chan struct{}),
+	}
+	s.load()
+	return s
+}
+
+func (s *Service) configPath() string {
+	return filepath.Join(s.dataDir, "schedules.json")
+}
+
+func (s *Service) load() {
+	data, err := os.ReadFile(s.configPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.schedules); err != nil {
+		fmt.Printf("⚠️ 解析定时计划失败: %v\n", err)
+	}
+}
+
+// save 保存定时计划（调用时需持有 s.mu 锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.configPath(), data, 0644)
+}
+
+// SetController 设置代理控制器并启动检查循环
+func (s *Service) SetController(controller Controller) {
+	s.mu.Lock()
+	started := s.controller != nil
+	s.controller = &controller
+	s.mu.Unlock()
+
+	if !started {
+		go s.loop()
+	}
+}
+
+// Stop 停止检查循环
+func (s *Service) Stop() {
+	close(s.stopChan)
+}
+
+func (s *Service) loop() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	s.check(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			s.check(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// List 获取所有计划
+func (s *Service) List() []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Schedule, 0, len(s.schedules))
+	for _, sch := range s.schedules {
+		result = append(result, *sch)
+	}
+	return result
+}
+
+// Get 获取计划
+func (s *Service) Get(id string) (*Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sch := range s.schedules {
+		if sch.ID == id {
+			result := *sch
+			return &result, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Add 添加计划
+func (s *Service) Add(sch Schedule) (*Schedule, error) {
+	if err := normalize(&sch); err != nil {
+		return nil, err
+	}
+	sch.ID = uuid.New().String()
+	sch.Active, sch.Previous, sch.LastRun, sch.LastError = false, nil, 0, ""
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules = append(s.schedules, &sch)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+// Update 更新计划，执行状态保持不变
+// 时间段内修改的计划在下次检查时按新时间判断，不在时间段内则恢复原状态
+func (s *Service) Update(id string, sch Schedule) (*Schedule, error) {
+	if err := normalize(&sch); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.schedules {
+		if existing.ID != id {
+			continue
+		}
+		sch.ID = id
+		sch.Active, sch.Previous = existing.Active, existing.Previous
+		sch.LastRun, sch.LastError = existing.LastRun, existing.LastError
+		s.schedules[i] = &sch
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+		return &sch, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Delete 删除计划，处于时间段内的计划会先恢复原状态
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sch := range s.schedules {
+		if sch.ID != id {
+			continue
+		}
+		if sch.Active && sch.Previous != nil {
+			s.restore(sch)
+		}
+		s.schedules = append(s.schedules[:i], s.schedules[i+1:]...)
+		return s.save()
+	}
+	return ErrNotFound
+}
+
+// Run 立即执行计划的动作（不改变时间段状态）
+func (s *Service) Run(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sch := range s.schedules {
+		if sch.ID != id {
+			continue
+		}
+		err := s.apply(sch)
+		s.record(sch, err)
+		s.save()
+		return err
+	}
+	return ErrNotFound
+}
+
+// check 检查所有计划，进入时间段时执行动作，离开时间段时恢复
+func (s *Service) check(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.controller == nil {
+		return
+	}
+
+	changed := false
+	for _, sch := range s.schedules {
+		if sch.End == "" {
+			// 单次计划：到达 Start 后的一个检查周期内执行
+			at := occurrence(sch, now)
+			if !sch.Enabled || at.IsZero() || sch.LastRun >= at.Unix() {
+				continue
+			}
+			fmt.Printf("⏰ 执行定时计划: %s\n", sch.Name)
+			s.record(sch, s.apply(sch))
+			changed = true
+			continue
+		}
+
+		active := sch.Enabled && inWindow(sch, now)
+		switch {
+		case active && !sch.Active:
+			state := s.controller.State()
+			fmt.Printf("⏰ 定时计划开始: %s\n", sch.Name)
+			err := s.apply(sch)
+			s.record(sch, err)
+			if err == nil {
+				sch.Active = true
+				sch.Previous = &state
+			}
+			changed = true
+		case !active && sch.Active:
+			fmt.Printf("⏰ 定时计划结束，恢复原状态: %s\n", sch.Name)
+			s.restore(sch)
+			changed = true
+		}
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			fmt.Printf("⚠️ 保存定时计划失败: %v\n", err)
+		}
+	}
+}
+
+// apply 执行计划的动作（调用时需持有 s.mu 锁）
+func (s *Service) apply(sch *Schedule) error {
+	if s.controller == nil {
+		return fmt.Errorf("代理服务未就绪")
+	}
+	state := s.controller.State()
+	switch sch.Action {
+	case ActionStart:
+		if state.Running {
+			return nil
+		}
+		return s.controller.Start()
+	case ActionStop:
+		if !state.Running {
+			return nil
+		}
+		return s.controller.Stop()
+	case ActionTransparent:
+		if state.Mode == sch.Mode && state.Scope == sch.Scope {
+			return nil
+		}
+		return s.controller.SetTransparentMode(sch.Mode, sch.Scope)
+	}
+	return fmt.Errorf("未知的动作: %s", sch.Action)
+}
+
+// restore 恢复时间段开始前的状态（调用时需持有 s.mu 锁）
+func (s *Service) restore(sch *Schedule) {
+	prev := sch.Previous
+	sch.Active = false
+	sch.Previous = nil
+	if prev == nil || s.controller == nil {
+		return
+	}
+
+	var err error
+	state := s.controller.State()
+	switch sch.Action {
+	case ActionTransparent:
+		if state.Mode != prev.Mode || state.Scope != prev.Scope {
+			err = s.controller.SetTransparentMode(prev.Mode, prev.Scope)
+		}
+	case ActionStart, ActionStop:
+		if state.Running != prev.Running {
+			if prev.Running {
+				err = s.controller.Start()
+			} else {
+				err = s.controller.Stop()
+			}
+		}
+	}
+	s.record(sch, err)
+	if err != nil {
+		fmt.Printf("⚠️ 恢复定时计划 %s 之前的状态失败: %v\n", sch.Name, err)
+	}
+}
+
+// record 记录执行结果
+func (s *Service) record(sch *Schedule, err error) {
+	sch.LastRun = time.Now().Unix()
+	sch.LastError = ""
+	if err != nil {
+		sch.LastError = err.Error()
+		fmt.Printf("⚠️ 定时计划 %s 执行失败: %v\n", sch.Name, err)
+	}
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数（24:00 为 1440）
+func parseClock(value string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// matchDay 计划是否在指定星期执行
+func matchDay(sch *Schedule, day time.Weekday) bool {
+	if len(sch.Days) == 0 {
+		return true
+	}
+	for _, d := range sch.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// atMinutes 返回 t 所在日期的第 minutes 分钟
+func atMinutes(t time.Time, minutes int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(time.Duration(minutes) * time.Minute)
+}
+
+// inWindow 当前时间是否处于时间段内，跨越午夜的时间段按开始那天的星期判断
+func inWindow(sch *Schedule, now time.Time) bool {
+	start, err1 := parseClock(sch.Start)
+	end, err2 := parseClock(sch.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if end <= start {
+		end += 24 * 60
+	}
+	for _, offset := range []int{0, -1} {
+		day := now.AddDate(0, 0, offset)
+		if !matchDay(sch, day.Weekday()) {
+			continue
+		}
+		from, to := atMinutes(day, start), atMinutes(day, end)
+		if !now.Before(from) && now.Before(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// occurrence 单次计划今天的执行时刻，不在今天或已超过一个检查周期时返回零值
+func occurrence(sch *Schedule, now time.Time) time.Time {
+	start, err := parseClock(sch.Start)
+	if err != nil || !matchDay(sch, now.Weekday()) {
+		return time.Time{}
+	}
+	at := atMinutes(now, start)
+	if now.Before(at) || now.Sub(at) > 2*checkInterval {
+		return time.Time{}
+	}
+	return at
+}
+
+// normalize 校验计划配置
+func normalize(sch *Schedule) error {
+	sch.Name = strings.TrimSpace(sch.Name)
+	if sch.Name == "" {
+		return fmt.Errorf("计划名称不能为空")
+	}
+
+	start, err := parseClock(sch.Start)
+	if err != nil {
+		return err
+	}
+	if start == 24*60 {
+		return fmt.Errorf("开始时间不能为 24:00")
+	}
+	sch.Start = fmt.Sprintf("%02d:%02d", start/60, start%60)
+	sch.End = strings.TrimSpace(sch.End)
+	if sch.End != "" {
+		end, err := parseClock(sch.End)
+		if err != nil {
+			return err
+		}
+		if end == start {
+			return fmt.Errorf("结束时间不能与开始时间相同")
+		}
+		sch.End = fmt.Sprintf("%02d:%02d", end/60, end%60)
+	}
+
+	seen := make(map[int]bool)
+	days := []int{}
+	for _, d := range sch.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("无效的星期: %d", d)
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	sch.Days = days
+
+	switch sch.Action {
+	case ActionStart, ActionStop:
+		sch.Mode, sch.Scope = "", ""
+	case ActionTransparent:
+		switch sch.Mode {
+		case "off", "system", "tproxy", "redirect", "tun":
+		default:
+			return fmt.Errorf("无效的透明代理模式: %s", sch.Mode)
+		}
+		if sch.Scope != "router" {
+			sch.Scope = "local"
+		}
+	default:
+		return fmt.Errorf("无效的动作: %s", sch.Action)
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"sync"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
@@ -171,10 +173,7 @@ func (h *Handler) StartSpeedTest(c *gin.Context) {
 	ctx := context.Background()
 	result, err := h.SimpleSpeedtest(ctx, req.Source, req.DownloadThreads, req.UploadThreads)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    -1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -55,6 +55,52 @@ func ParseURL(proxyURL string) (*ProxyNode, error) {
 	return nil, errors.New("不支持的协议格式")
 }
 
+// LinkParseError 单条分享链接的解析错误
+type LinkParseError struct {
+	Line  int    `json:"line"`  // 行号（从 1 开始）
+	Link  string `json:"link"`  // 原始链接
+	Error string `json:"error"` // 错误信息
+}
+
+// ParseShareLinks 批量解析分享链接
+// content 可以是多行分享链接，也可以是 Base64 编码的订阅内容
+// 返回成功解析的节点和每条失败链接的错误信息
+func ParseShareLinks(content string) ([]*ProxyNode, []LinkParseError) {
+	content = strings.TrimSpace(content)
+
+	// 不包含协议头时尝试按 Base64 订阅解码
+	if !strings.Contains(content, "://") {
+		if decoded, err := DecodeBase64(strings.Join(strings.Fields(content), "")); err == nil {
+			content = decoded
+		}
+	}
+
+	var nodes []*ProxyNode
+	var errs []LinkParseError
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		node, err := ParseURL(line)
+		if err != nil {
+			errs = append(errs, LinkParseError{Line: i + 1, Link: line, Error: err.Error()})
+			continue
+		}
+		if node.Name == "" {
+			node.Name = node.Server + ":" + strconv.Itoa(node.ServerPort)
+		}
+		// 保存原始链接用于分享
+		node.ShareURL = line
+		nodes = append(nodes, node)
+	}
+
+	return nodes, errs
+}
+
 // ParseQueryParams 解析URL查询参数
 func ParseQueryParams(query string) map[string]string {
 	params := make(map[string]string)
@@ -3,6 +3,8 @@ package subscription
 import (
 	"net/http"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -45,10 +47,7 @@ func (h *Handler) Get(c *gin.Context) {
 	id := c.Param("id")
 	sub, err := h.service.Get(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -63,10 +62,7 @@ func (h *Handler) GetNodes(c *gin.Context) {
 	id := c.Param("id")
 	nodes, err := h.service.GetNodes(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -80,19 +76,13 @@ func (h *Handler) GetNodes(c *gin.Context) {
 func (h *Handler) Add(c *gin.Context) {
 	var req AddRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
-	sub, err := h.service.Add(&req)
+	sub, err := h.service.Add(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -107,18 +97,12 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 	id := c.Param("id")
 	var req AddRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.UpdateConfig(id, &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -131,10 +115,7 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 func (h *Handler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.service.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -146,11 +127,8 @@ func (h *Handler) Delete(c *gin.Context) {
 
 func (h *Handler) Update(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.service.Update(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+	if err := h.service.Update(c.Request.Context(), id); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -161,11 +139,8 @@ func (h *Handler) Update(c *gin.Context) {
 }
 
 func (h *Handler) UpdateAll(c *gin.Context) {
-	if err := h.service.UpdateAll(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+	if err := h.service.UpdateAll(c.Request.Context()); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
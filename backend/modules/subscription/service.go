@@ -1,8 +1,10 @@
 package subscription
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +14,9 @@ import (
 	"sync"
 	"time"
 
+	"ProxyStation/backend/httpclient"
+	"ProxyStation/backend/secure"
+
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
 )
@@ -58,6 +63,9 @@ type Service struct {
 	subscriptions map[string]*Subscription
 	stopChan      chan struct{}
 	mu            sync.RWMutex
+
+	// 更新失败回调（用于通知）
+	onUpdateFailed func(sub *Subscription, err error)
 }
 
 func NewService(dataDir string) *Service {
@@ -109,7 +117,7 @@ func (s *Service) checkAndUpdate() {
 
 	// 更新订阅
 	for _, sub := range subs {
-		s.updateSubscription(sub)
+		s.updateSubscription(context.Background(), sub)
 	}
 	if len(subs) > 0 {
 		s.saveSubscriptions()
@@ -123,7 +131,7 @@ func (s *Service) Stop() {
 
 func (s *Service) loadSubscriptions() {
 	filePath := filepath.Join(s.dataDir, "subscriptions.json")
-	data, err := os.ReadFile(filePath)
+	data, err := secure.ReadFile(filePath)
 	if err != nil {
 		return
 	}
@@ -151,7 +159,7 @@ func (s *Service) saveSubscriptions() error {
 	}
 
 	filePath := filepath.Join(s.dataDir, "subscriptions.json")
-	return os.WriteFile(filePath, data, 0644)
+	return secure.WriteFile(filePath, data, 0644)
 }
 
 func (s *Service) List() []*Subscription {
@@ -192,7 +200,7 @@ type AddRequest struct {
 	CustomHeaders  map[string]string `json:"customHeaders"`
 }
 
-func (s *Service) Add(req *AddRequest) (*Subscription, error) {
+func (s *Service) Add(ctx context.Context, req *AddRequest) (*Subscription, error) {
 	sub := &Subscription{
 		ID:             uuid.New().String(),
 		Name:           req.Name,
@@ -212,8 +220,8 @@ func (s *Service) Add(req *AddRequest) (*Subscription, error) {
 		sub.FilterMode = "exclude" // 默认排除模式
 	}
 
-	// 获取订阅内容
-	if err := s.updateSubscription(sub); err != nil {
+	// 获取订阅内容（添加失败直接返回给用户，不发送通知）
+	if err := s.fetchSubscription(ctx, sub); err != nil {
 		return nil, err
 	}
 
@@ -270,7 +278,7 @@ func (s *Service) GetNodes(id string) ([]*SubscriptionNode, error) {
 
 	// 读取节点文件
 	nodesPath := filepath.Join(s.dataDir, "configs", id+"_nodes.json")
-	data, err := os.ReadFile(nodesPath)
+	data, err := secure.ReadFile(nodesPath)
 	if err != nil {
 		return nil, fmt.Errorf("nodes not found")
 	}
@@ -328,7 +336,8 @@ func (s *Service) GetFilteredNodeCount(id string) int {
 	return count
 }
 
-func (s *Service) Update(id string) error {
+// Update 立即更新订阅，ctx 取消时中止下载
+func (s *Service) Update(ctx context.Context, id string) error {
 	s.mu.RLock()
 	sub, ok := s.subscriptions[id]
 	s.mu.RUnlock()
@@ -337,14 +346,15 @@ func (s *Service) Update(id string) error {
 		return fmt.Errorf("subscription not found")
 	}
 
-	if err := s.updateSubscription(sub); err != nil {
+	if err := s.updateSubscription(ctx, sub); err != nil {
 		return err
 	}
 
 	return s.saveSubscriptions()
 }
 
-func (s *Service) UpdateAll() error {
+// UpdateAll 依次更新所有订阅，ctx 取消时跳过剩余的订阅
+func (s *Service) UpdateAll(ctx context.Context) error {
 	s.mu.RLock()
 	subs := make([]*Subscription, 0, len(s.subscriptions))
 	for _, sub := range s.subscriptions {
@@ -353,13 +363,39 @@ func (s *Service) UpdateAll() error {
 	s.mu.RUnlock()
 
 	for _, sub := range subs {
-		s.updateSubscription(sub)
+		if ctx.Err() != nil {
+			break
+		}
+		s.updateSubscription(ctx, sub)
 	}
 
 	return s.saveSubscriptions()
 }
 
-func (s *Service) updateSubscription(sub *Subscription) error {
+// SetOnUpdateFailed 设置订阅更新失败回调
+func (s *Service) SetOnUpdateFailed(callback func(sub *Subscription, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onUpdateFailed = callback
+}
+
+// updateSubscription 更新订阅，失败时调用更新失败回调（调用方取消时不回调）
+func (s *Service) updateSubscription(ctx context.Context, sub *Subscription) error {
+	err := s.fetchSubscription(ctx, sub)
+	if err != nil && ctx.Err() == nil {
+		s.mu.RLock()
+		callback := s.onUpdateFailed
+		s.mu.RUnlock()
+		if callback != nil {
+			callback(sub, err)
+		}
+	}
+	return err
+}
+
+// fetchSubscription 下载并解析订阅内容
+// 超时使用配置的下载超时；ctx 被调用方取消时不修改订阅状态
+func (s *Service) fetchSubscription(ctx context.Context, sub *Subscription) error {
 	// 辅助函数：设置失败状态
 	setFailed := func(errMsg string) {
 		sub.LastUpdateStatus = "failed"
@@ -367,8 +403,11 @@ func (s *Service) updateSubscription(sub *Subscription) error {
 		sub.UpdatedAt = time.Now()
 	}
 
+	ctx, cancel := httpclient.WithTimeout(ctx, httpclient.FetchTimeout())
+	defer cancel()
+
 	// 创建请求
-	req, err := http.NewRequest("GET", sub.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", sub.URL, nil)
 	if err != nil {
 		setFailed(fmt.Sprintf("创建请求失败: %v", err))
 		return fmt.Errorf("failed to create request: %w", err)
@@ -381,8 +420,10 @@ func (s *Service) updateSubscription(sub *Subscription) error {
 	}
 
 	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := httpclient.Client.Do(req)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("已取消: %w", err)
+	}
 	if err != nil {
 		setFailed(fmt.Sprintf("请求失败: %v", err))
 		return fmt.Errorf("failed to fetch subscription: %w", err)
@@ -395,6 +436,9 @@ func (s *Service) updateSubscription(sub *Subscription) error {
 	}
 
 	body, err := io.ReadAll(resp.Body)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("已取消: %w", err)
+	}
 	if err != nil {
 		setFailed(fmt.Sprintf("读取响应失败: %v", err))
 		return fmt.Errorf("failed to read response: %w", err)
@@ -491,12 +535,12 @@ func (s *Service) updateSubscription(sub *Subscription) error {
 	os.MkdirAll(filepath.Dir(configPath), 0755)
 
 	// 保存原始内容
-	os.WriteFile(configPath, body, 0644)
+	secure.WriteFile(configPath, body, 0644)
 
 	// 保存解析后的节点
 	if len(nodes) > 0 {
 		nodesJSON, _ := json.MarshalIndent(nodes, "", "  ")
-		os.WriteFile(nodesPath, nodesJSON, 0644)
+		secure.WriteFile(nodesPath, nodesJSON, 0644)
 	}
 
 	return nil
@@ -6,6 +6,8 @@ import (
 	"net/url"
 	"time"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 	"golang.org/x/net/proxy"
 )
@@ -41,6 +43,15 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/browsers/firefox/clear", h.ClearFirefox)
 	// 出口 IP 信息
 	r.GET("/geoip", h.GetGeoIP)
+	// systemd 集成
+	r.GET("/systemd", h.GetSystemdStatus)
+	r.POST("/systemd/install", h.InstallSystemdUnit)
+	r.POST("/systemd/uninstall", h.UninstallSystemdUnit)
+}
+
+// GetService 获取系统服务
+func (h *Handler) GetService() *Service {
+	return h.service
 }
 
 // GetResources 获取系统资源信息
@@ -69,12 +80,12 @@ func (h *Handler) SetAutoStart(c *gin.Context) {
 		Enabled bool `json:"enabled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SetAutoStart(req.Enabled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -91,12 +102,12 @@ func (h *Handler) SetIPForward(c *gin.Context) {
 		Enabled bool `json:"enabled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SetIPForward(req.Enabled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -113,12 +124,12 @@ func (h *Handler) SetBBR(c *gin.Context) {
 		Enabled bool `json:"enabled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SetBBR(req.Enabled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -135,12 +146,12 @@ func (h *Handler) SetTUNOptimize(c *gin.Context) {
 		Enabled bool `json:"enabled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.SetTUNOptimize(req.Enabled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -154,7 +165,7 @@ func (h *Handler) SetTUNOptimize(c *gin.Context) {
 // OptimizeAll 一键优化
 func (h *Handler) OptimizeAll(c *gin.Context) {
 	if err := h.service.ApplyAllOptimizations(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -170,7 +181,7 @@ func (h *Handler) EnableSystemProxy(c *gin.Context) {
 		Port int    `json:"port"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -182,7 +193,7 @@ func (h *Handler) EnableSystemProxy(c *gin.Context) {
 	}
 
 	if err := SetSystemProxy(req.Host, req.Port); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -195,7 +206,7 @@ func (h *Handler) EnableSystemProxy(c *gin.Context) {
 // DisableSystemProxy 禁用系统代理
 func (h *Handler) DisableSystemProxy(c *gin.Context) {
 	if err := ClearSystemProxy(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -406,10 +417,7 @@ func (h *Handler) GetGeoIP(c *gin.Context) {
 	}
 
 	if geoInfo.IP == "" {
-		c.JSON(http.StatusOK, gin.H{
-			"code":    1,
-			"message": "获取 IP 信息失败",
-		})
+		apierror.Message(c, http.StatusOK, "获取 IP 信息失败")
 		return
 	}
 
@@ -433,10 +441,7 @@ func (h *Handler) GetBrowsers(c *gin.Context) {
 // ConfigureFirefox 配置 Firefox 使用系统代理
 func (h *Handler) ConfigureFirefox(c *gin.Context) {
 	if err := ConfigureFirefoxProxy(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -449,10 +454,7 @@ func (h *Handler) ConfigureFirefox(c *gin.Context) {
 // ClearFirefox 清除 Firefox 代理配置
 func (h *Handler) ClearFirefox(c *gin.Context) {
 	if err := ClearFirefoxProxy(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
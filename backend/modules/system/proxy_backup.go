@@ -0,0 +1,59 @@
+package system
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// proxyBackupPath 系统代理备份文件，修改系统代理前保存用户原有设置
+var proxyBackupPath string
+
+// SetProxyBackupPath 设置系统代理备份文件所在目录
+func SetProxyBackupPath(dataDir string) {
+	proxyBackupPath = filepath.Join(dataDir, "system_proxy_backup.json")
+}
+
+// saveProxyBackup 保存原有系统代理设置
+// 备份已存在时不覆盖（说明上次未正常恢复，当前系统设置是 ProxyStation 写入的）
+func saveProxyBackup(state interface{}) error {
+	if proxyBackupPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(proxyBackupPath); err == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(proxyBackupPath, data, 0644)
+}
+
+// loadProxyBackup 读取原有系统代理设置，没有备份时返回 false
+func loadProxyBackup(state interface{}) bool {
+	if proxyBackupPath == "" {
+		return false
+	}
+	data, err := os.ReadFile(proxyBackupPath)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, state) == nil
+}
+
+// HasProxyBackup 是否存在未恢复的系统代理备份（ProxyStation 异常退出时遗留）
+func HasProxyBackup() bool {
+	if proxyBackupPath == "" {
+		return false
+	}
+	_, err := os.Stat(proxyBackupPath)
+	return err == nil
+}
+
+// removeProxyBackup 恢复完成后删除备份
+func removeProxyBackup() {
+	if proxyBackupPath != "" {
+		os.Remove(proxyBackupPath)
+	}
+}
@@ -8,10 +8,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// getNetworkServices 获取所有网络服务
+// getNetworkServices 获取所有已启用的网络服务
 func getNetworkServices() ([]string, error) {
 	cmd := exec.Command("networksetup", "-listallnetworkservices")
 	output, err := cmd.Output()
@@ -23,8 +24,8 @@ func getNetworkServices() ([]string, error) {
 	var services []string
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
-		// 跳过第一行说明和空行
-		if line == "" || strings.HasPrefix(line, "An asterisk") {
+		// 跳过第一行说明和空行，带 * 前缀的是已禁用的服务
+		if line == "" || strings.HasPrefix(line, "An asterisk") || strings.HasPrefix(line, "*") {
 			continue
 		}
 		services = append(services, line)
@@ -32,36 +33,118 @@ func getNetworkServices() ([]string, error) {
 	return services, nil
 }
 
-// SetSystemProxy 设置系统代理（参考 flyclash 实现）
+// darwinProxyKinds networksetup 的代理类型：HTTP、HTTPS、SOCKS
+var darwinProxyKinds = []struct {
+	name string
+	get  string
+	set  string
+	// setState 单独开关代理，不修改服务器地址
+	setState string
+}{
+	{"HTTP", "-getwebproxy", "-setwebproxy", "-setwebproxystate"},
+	{"HTTPS", "-getsecurewebproxy", "-setsecurewebproxy", "-setsecurewebproxystate"},
+	{"SOCKS", "-getsocksfirewallproxy", "-setsocksfirewallproxy", "-setsocksfirewallproxystate"},
+}
+
+// darwinProxyEntry 单个代理类型的设置
+type darwinProxyEntry struct {
+	Enabled bool   `json:"enabled"`
+	Server  string `json:"server,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+// darwinServiceState 网络服务的代理设置
+type darwinServiceState struct {
+	Proxies       map[string]darwinProxyEntry `json:"proxies"` // 键为 HTTP/HTTPS/SOCKS
+	BypassDomains []string                    `json:"bypassDomains,omitempty"`
+}
+
+// darwinProxyState 修改前的系统代理设置，按网络服务保存
+type darwinProxyState struct {
+	Services map[string]darwinServiceState `json:"services"`
+}
+
+// readProxyEntry 解析 networksetup -getwebproxy 等命令的输出
+func readProxyEntry(service, getFlag string) (darwinProxyEntry, error) {
+	var entry darwinProxyEntry
+	output, err := exec.Command("networksetup", getFlag, service).Output()
+	if err != nil {
+		return entry, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Enabled":
+			entry.Enabled = value == "Yes"
+		case "Server":
+			entry.Server = value
+		case "Port":
+			entry.Port, _ = strconv.Atoi(value)
+		}
+	}
+	return entry, nil
+}
+
+// readBypassDomains 读取绕过代理的域名，未设置时返回 nil
+func readBypassDomains(service string) []string {
+	output, err := exec.Command("networksetup", "-getproxybypassdomains", service).Output()
+	if err != nil {
+		return nil
+	}
+	var domains []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		// 未设置时输出 "There aren't any bypass domains set on <service>."
+		if line == "" || strings.HasPrefix(line, "There aren't any") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains
+}
+
+// snapshotProxyState 读取所有网络服务当前的代理设置
+func snapshotProxyState(services []string) darwinProxyState {
+	state := darwinProxyState{Services: make(map[string]darwinServiceState)}
+	for _, service := range services {
+		serviceState := darwinServiceState{Proxies: make(map[string]darwinProxyEntry)}
+		for _, kind := range darwinProxyKinds {
+			if entry, err := readProxyEntry(service, kind.get); err == nil {
+				serviceState.Proxies[kind.name] = entry
+			}
+		}
+		serviceState.BypassDomains = readBypassDomains(service)
+		state.Services[service] = serviceState
+	}
+	return state
+}
+
+// SetSystemProxy 设置系统代理（参考 flyclash 实现），修改前备份各网络服务原有设置
 func SetSystemProxy(host string, port int) error {
 	services, err := getNetworkServices()
 	if err != nil {
 		return fmt.Errorf("failed to get network services: %v", err)
 	}
 
-	portStr := fmt.Sprintf("%d", port)
+	if err := saveProxyBackup(snapshotProxyState(services)); err != nil {
+		fmt.Printf("⚠️ 备份系统代理设置失败: %v\n", err)
+	}
+
+	portStr := strconv.Itoa(port)
 	fmt.Printf("🔧 设置系统代理: %s:%s\n", host, portStr)
 
 	for _, service := range services {
-		// 设置 HTTP 代理（会自动启用）
-		if err := exec.Command("networksetup", "-setwebproxy", service, host, portStr).Run(); err != nil {
-			fmt.Printf("⚠ %s: HTTP 代理设置失败\n", service)
-			continue
-		}
-		fmt.Printf("✓ %s: HTTP 代理已启用\n", service)
-
-		// 设置 HTTPS 代理（会自动启用）
-		if err := exec.Command("networksetup", "-setsecurewebproxy", service, host, portStr).Run(); err != nil {
-			fmt.Printf("⚠ %s: HTTPS 代理设置失败\n", service)
-		} else {
-			fmt.Printf("✓ %s: HTTPS 代理已启用\n", service)
-		}
-
-		// 设置 SOCKS 代理（会自动启用）
-		if err := exec.Command("networksetup", "-setsocksfirewallproxy", service, host, portStr).Run(); err != nil {
-			fmt.Printf("⚠ %s: SOCKS 代理设置失败\n", service)
-		} else {
-			fmt.Printf("✓ %s: SOCKS 代理已启用\n", service)
+		// 设置 HTTP/HTTPS/SOCKS 代理（会自动启用）
+		for _, kind := range darwinProxyKinds {
+			if err := exec.Command("networksetup", kind.set, service, host, portStr).Run(); err != nil {
+				fmt.Printf("⚠ %s: %s 代理设置失败\n", service, kind.name)
+			} else {
+				fmt.Printf("✓ %s: %s 代理已启用\n", service, kind.name)
+			}
 		}
 
 		// 设置绕过代理的域名（与 flyclash 一致）
@@ -73,22 +156,70 @@ func SetSystemProxy(host string, port int) error {
 	return nil
 }
 
-// ClearSystemProxy 清除系统代理
+// ClearSystemProxy 清除系统代理：有备份时恢复各网络服务原有设置，否则关闭代理
 func ClearSystemProxy() error {
+	var backup darwinProxyState
+	if loadProxyBackup(&backup) {
+		restoreProxyState(backup)
+		removeProxyBackup()
+		return nil
+	}
+
 	services, err := getNetworkServices()
 	if err != nil {
 		return fmt.Errorf("failed to get network services: %v", err)
 	}
 
 	for _, service := range services {
-		exec.Command("networksetup", "-setwebproxystate", service, "off").Run()
-		exec.Command("networksetup", "-setsecurewebproxystate", service, "off").Run()
-		exec.Command("networksetup", "-setsocksfirewallproxystate", service, "off").Run()
+		for _, kind := range darwinProxyKinds {
+			exec.Command("networksetup", kind.setState, service, "off").Run()
+		}
 	}
 
 	return nil
 }
 
+// restoreProxyState 恢复备份的代理设置，备份后新增的网络服务直接关闭代理
+func restoreProxyState(backup darwinProxyState) {
+	services, err := getNetworkServices()
+	if err != nil {
+		fmt.Printf("⚠️ 获取网络服务失败: %v\n", err)
+		return
+	}
+
+	for _, service := range services {
+		serviceState, ok := backup.Services[service]
+		for _, kind := range darwinProxyKinds {
+			entry := serviceState.Proxies[kind.name]
+			if ok && entry.Server != "" {
+				// 设置服务器地址会同时启用代理，原来关闭的需要再关闭
+				exec.Command("networksetup", kind.set, service, entry.Server, strconv.Itoa(entry.Port)).Run()
+				if entry.Enabled {
+					continue
+				}
+			}
+			exec.Command("networksetup", kind.setState, service, "off").Run()
+		}
+
+		if !ok {
+			continue
+		}
+		// networksetup 使用 "Empty" 清空绕过域名
+		domains := serviceState.BypassDomains
+		if len(domains) == 0 {
+			domains = []string{"Empty"}
+		}
+		args := append([]string{"-setproxybypassdomains", service}, domains...)
+		exec.Command("networksetup", args...).Run()
+	}
+	fmt.Println("✓ 已恢复原有系统代理设置")
+}
+
+// AllowInboundProgram 防火墙放行核心（仅 Windows 需要）
+func AllowInboundProgram(name, program string) error {
+	return nil
+}
+
 // GetSystemProxyStatus 获取系统代理状态
 func GetSystemProxyStatus() (bool, string, int, error) {
 	services, err := getNetworkServices()
@@ -16,6 +16,11 @@ func ClearSystemProxy() error {
 	return nil
 }
 
+// AllowInboundProgram 防火墙放行核心（仅 Windows 需要）
+func AllowInboundProgram(name, program string) error {
+	return nil
+}
+
 // GetSystemProxyStatus 获取系统代理状态
 func GetSystemProxyStatus() (bool, string, int, error) {
 	return false, "", 0, fmt.Errorf("not supported on Linux, use TUN mode instead")
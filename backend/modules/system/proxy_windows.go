@@ -4,52 +4,181 @@ package system
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 )
 
+// internetSettingsKey WinINET 代理设置所在的注册表项
+const internetSettingsKey = `HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+// proxyBypass 不经过代理的地址（本机和局域网）
+const proxyBypass = "localhost;127.*;10.*;172.16.*;172.17.*;172.18.*;172.19.*;172.2*;172.30.*;172.31.*;192.168.*;<local>"
+
+// windowsProxyState 修改前的 WinINET 代理设置，值为 nil 表示原本不存在
+type windowsProxyState struct {
+	ProxyEnable   *string `json:"proxyEnable,omitempty"`
+	ProxyServer   *string `json:"proxyServer,omitempty"`
+	ProxyOverride *string `json:"proxyOverride,omitempty"`
+	AutoConfigURL *string `json:"autoConfigUrl,omitempty"`
+}
+
+// regQuery 读取注册表值，不存在时返回 nil
+func regQuery(name string) *string {
+	output, err := exec.Command("reg", "query", internetSettingsKey, "/v", name).Output()
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != name {
+			continue
+		}
+		value := ""
+		if len(fields) > 2 {
+			value = strings.TrimSpace(line[strings.Index(line, fields[1])+len(fields[1]):])
+		}
+		return &value
+	}
+	return nil
+}
+
+// regSet 写入注册表值
+func regSet(name, valueType, value string) error {
+	return exec.Command("reg", "add", internetSettingsKey, "/v", name, "/t", valueType, "/d", value, "/f").Run()
+}
+
+// regDelete 删除注册表值（不存在时忽略）
+func regDelete(name string) {
+	exec.Command("reg", "delete", internetSettingsKey, "/v", name, "/f").Run()
+}
+
+// restoreRegValue 恢复注册表值，原本不存在时删除
+func restoreRegValue(name, valueType string, value *string) error {
+	if value == nil {
+		regDelete(name)
+		return nil
+	}
+	v := *value
+	if valueType == "REG_DWORD" {
+		// reg query 输出为 0x1 形式
+		if n, err := strconv.ParseInt(strings.TrimPrefix(v, "0x"), 16, 64); err == nil {
+			v = strconv.FormatInt(n, 10)
+		}
+	}
+	return regSet(name, valueType, v)
+}
+
+// refreshInternetSettings 通知 WinINET 设置已变更，使浏览器等程序立即生效
+func refreshInternetSettings() {
+	const (
+		internetOptionSettingsChanged = 39
+		internetOptionRefresh         = 37
+	)
+	proc := syscall.NewLazyDLL("wininet.dll").NewProc("InternetSetOptionW")
+	if proc.Find() != nil {
+		return
+	}
+	proc.Call(0, internetOptionSettingsChanged, 0, 0)
+	proc.Call(0, internetOptionRefresh, 0, 0)
+}
+
 // SetSystemProxy 设置系统代理 (Windows)
+// 修改前备份原有设置（包括 PAC 地址），停止时由 ClearSystemProxy 恢复
 func SetSystemProxy(host string, port int) error {
-	proxyServer := fmt.Sprintf("%s:%d", host, port)
+	if err := saveProxyBackup(windowsProxyState{
+		ProxyEnable:   regQuery("ProxyEnable"),
+		ProxyServer:   regQuery("ProxyServer"),
+		ProxyOverride: regQuery("ProxyOverride"),
+		AutoConfigURL: regQuery("AutoConfigURL"),
+	}); err != nil {
+		fmt.Printf("⚠️ 备份系统代理设置失败: %v\n", err)
+	}
 
-	// 启用代理
-	cmd := exec.Command("reg", "add",
-		`HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`,
-		"/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "1", "/f")
-	if err := cmd.Run(); err != nil {
+	proxyServer := fmt.Sprintf("%s:%d", host, port)
+	if err := regSet("ProxyServer", "REG_SZ", proxyServer); err != nil {
 		return err
 	}
-
-	// 设置代理服务器
-	cmd = exec.Command("reg", "add",
-		`HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`,
-		"/v", "ProxyServer", "/t", "REG_SZ", "/d", proxyServer, "/f")
-	return cmd.Run()
+	if err := regSet("ProxyOverride", "REG_SZ", proxyBypass); err != nil {
+		return err
+	}
+	// PAC 优先于手动代理，需要移除
+	regDelete("AutoConfigURL")
+	if err := regSet("ProxyEnable", "REG_DWORD", "1"); err != nil {
+		return err
+	}
+	refreshInternetSettings()
+	return nil
 }
 
-// ClearSystemProxy 清除系统代理
+// ClearSystemProxy 清除系统代理，有备份时恢复用户原有设置
 func ClearSystemProxy() error {
-	cmd := exec.Command("reg", "add",
-		`HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`,
-		"/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "0", "/f")
-	return cmd.Run()
+	defer refreshInternetSettings()
+
+	var state windowsProxyState
+	if !loadProxyBackup(&state) {
+		return regSet("ProxyEnable", "REG_DWORD", "0")
+	}
+
+	var firstErr error
+	for _, v := range []struct {
+		name, valueType string
+		value           *string
+	}{
+		{"ProxyServer", "REG_SZ", state.ProxyServer},
+		{"ProxyOverride", "REG_SZ", state.ProxyOverride},
+		{"AutoConfigURL", "REG_SZ", state.AutoConfigURL},
+		{"ProxyEnable", "REG_DWORD", state.ProxyEnable},
+	} {
+		if err := restoreRegValue(v.name, v.valueType, v.value); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	removeProxyBackup()
+	return nil
 }
 
 // GetSystemProxyStatus 获取系统代理状态
 func GetSystemProxyStatus() (bool, string, int, error) {
-	cmd := exec.Command("reg", "query",
-		`HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`,
-		"/v", "ProxyEnable")
-	output, err := cmd.Output()
-	if err != nil {
-		return false, "", 0, err
+	enable := regQuery("ProxyEnable")
+	if enable == nil {
+		return false, "", 0, nil
+	}
+	enabled := strings.TrimPrefix(*enable, "0x") == "1"
+
+	var host string
+	var port int
+	if server := regQuery("ProxyServer"); server != nil {
+		// 可能为 host:port 或 http=host:port;https=host:port
+		addr := *server
+		if i := strings.Index(addr, "="); i >= 0 {
+			addr = strings.SplitN(addr[i+1:], ";", 2)[0]
+		}
+		if h, p, err := net.SplitHostPort(addr); err == nil {
+			host = h
+			port, _ = strconv.Atoi(p)
+		}
 	}
+	return enabled, host, port, nil
+}
 
-	// 简单检查是否包含 0x1
-	enabled := string(output)
-	return enabled != "" && (enabled[len(enabled)-2] == '1'), "", 0, nil
+// AllowInboundProgram 添加 Windows 防火墙入站规则，允许局域网设备连接核心
+// 同名规则先删除再添加，路径变化（核心更新）后规则仍然有效
+func AllowInboundProgram(name, program string) error {
+	exec.Command("netsh", "advfirewall", "firewall", "delete", "rule", "name="+name).Run()
+	output, err := exec.Command("netsh", "advfirewall", "firewall", "add", "rule",
+		"name="+name, "dir=in", "action=allow", "program="+program, "enable=yes", "profile=private,domain").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
 }
 
 // BrowserInfo 浏览器信息
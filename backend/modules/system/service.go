@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -55,6 +54,11 @@ func parseVmStatValue(line string) uint64 {
 type Service struct {
 	dataDir    string
 	binaryPath string
+
+	// 后端监听地址（生成 socket 单元时使用）
+	listenAddress string
+	// 核心启动参数提供者（生成核心单元时使用）
+	coreLaunchProvider CoreLaunchProvider
 }
 
 // NewService 创建系统服务
@@ -403,43 +407,7 @@ func (s *Service) IsAutoStartEnabled() bool {
 
 // enableAutoStart 启用开机自启
 func (s *Service) enableAutoStart() error {
-	// 生成 systemd 服务文件
-	serviceContent := fmt.Sprintf(`[Unit]
-Description=ProxyStation Proxy Gateway
-After=network.target
-
-[Service]
-Type=simple
-ExecStart=%s
-WorkingDirectory=%s
-Restart=always
-RestartSec=5
-LimitNOFILE=1048576
-
-# 安全配置
-NoNewPrivileges=false
-AmbientCapabilities=CAP_NET_ADMIN CAP_NET_BIND_SERVICE CAP_NET_RAW
-
-[Install]
-WantedBy=multi-user.target
-`, s.binaryPath, filepath.Dir(s.binaryPath))
-
-	servicePath := "/etc/systemd/system/proxystation.service"
-	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
-		return fmt.Errorf("写入服务文件失败: %v", err)
-	}
-
-	// 重新加载 systemd
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
-		return fmt.Errorf("重新加载 systemd 失败: %v", err)
-	}
-
-	// 启用服务
-	if err := exec.Command("systemctl", "enable", "proxystation").Run(); err != nil {
-		return fmt.Errorf("启用服务失败: %v", err)
-	}
-
-	return nil
+	return s.InstallSystemdUnit(SystemdInstallOptions{Unit: "backend", Enable: true})
 }
 
 // disableAutoStart 禁用开机自启
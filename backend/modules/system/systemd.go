@@ -0,0 +1,367 @@
+package system
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	systemdUnitDir      = "/etc/systemd/system"
+	backendUnitName     = "proxystation"
+	coreUnitName        = "proxystation-core"
+	backendSocketName   = "proxystation.socket"
+	defaultListenStream = "8383"
+)
+
+// systemdProperties systemctl show 查询的属性
+var systemdProperties = []string{
+	"LoadState", "ActiveState", "SubState", "UnitFileState",
+	"MainPID", "NRestarts", "ActiveEnterTimestamp", "FragmentPath",
+}
+
+// CoreLaunchInfo 核心启动参数（由 proxy 模块提供）
+type CoreLaunchInfo struct {
+	CoreType   string
+	CorePath   string
+	ConfigPath string
+	RunAsUser  string
+}
+
+// CoreLaunchProvider 获取核心启动参数
+type CoreLaunchProvider func() CoreLaunchInfo
+
+// SystemdInstallOptions 安装单元选项
+type SystemdInstallOptions struct {
+	Unit             string `json:"unit"`             // backend, core
+	Enable           bool   `json:"enable"`           // 开机启动
+	Start            bool   `json:"start"`            // 安装后立即启动
+	SocketActivation bool   `json:"socketActivation"` // 后端使用 socket 激活
+}
+
+// SystemdUnitStatus 单元状态
+type SystemdUnitStatus struct {
+	Name        string            `json:"name"`
+	Installed   bool              `json:"installed"`
+	Enabled     bool              `json:"enabled"`
+	Active      bool              `json:"active"`
+	ActiveState string            `json:"activeState"`
+	SubState    string            `json:"subState"`
+	MainPID     int               `json:"mainPid"`
+	Properties  map[string]string `json:"properties"`
+}
+
+// SetCoreLaunchProvider 设置核心启动参数提供者
+func (s *Service) SetCoreLaunchProvider(provider CoreLaunchProvider) {
+	s.coreLaunchProvider = provider
+}
+
+// SetListenAddress 设置后端监听地址（用于生成 socket 单元）
+func (s *Service) SetListenAddress(addr string) {
+	s.listenAddress = addr
+}
+
+func systemdAvailable() error {
+	if runtime.GOOS != "linux" {
+		return apierror.New(apierror.CodeUnsupported, "systemd 仅支持 Linux")
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return fmt.Errorf("当前系统未使用 systemd")
+	}
+	return nil
+}
+
+func unitPath(name string) string {
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+	return filepath.Join(systemdUnitDir, name)
+}
+
+// quoteExecArgs 拼接 ExecStart 参数，包含空白的参数加引号
+func quoteExecArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// renderBackendUnit 生成后端服务单元，保留当前的启动参数
+func (s *Service) renderBackendUnit(socketActivation bool) string {
+	execStart := quoteExecArgs(append([]string{s.binaryPath}, os.Args[1:]...))
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=ProxyStation Proxy Gateway\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	if socketActivation {
+		b.WriteString("Requires=" + backendSocketName + "\n")
+		b.WriteString("After=" + backendSocketName + "\n")
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=notify\n")
+	b.WriteString("NotifyAccess=main\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", execStart)
+	fmt.Fprintf(&b, "WorkingDirectory=%s\n", filepath.Dir(s.binaryPath))
+	b.WriteString("Restart=always\n")
+	b.WriteString("RestartSec=5\n")
+	b.WriteString("TimeoutStopSec=30\n")
+	b.WriteString("KillMode=mixed\n")
+	b.WriteString("LimitNOFILE=1048576\n")
+	b.WriteString("\n# 安全配置\n")
+	b.WriteString("NoNewPrivileges=false\n")
+	b.WriteString("AmbientCapabilities=CAP_NET_ADMIN CAP_NET_BIND_SERVICE CAP_NET_RAW\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// renderBackendSocket 生成 socket 激活单元
+func (s *Service) renderBackendSocket() string {
+	listen := s.listenAddress
+	if listen == "" {
+		listen = defaultListenStream
+	}
+	// 0.0.0.0 等价于监听所有地址，systemd 直接使用端口
+	listen = strings.TrimPrefix(listen, "0.0.0.0:")
+	listen = strings.TrimPrefix(listen, ":")
+	return fmt.Sprintf(`[Unit]
+Description=ProxyStation API Socket
+
+[Socket]
+ListenStream=%s
+NoDelay=true
+
+[Install]
+WantedBy=sockets.target
+`, listen)
+}
+
+// renderCoreUnit 生成独立运行核心的服务单元
+// 后端停止后核心仍可由 systemd 管理；与后端托管的核心互斥，启动前需先在面板中停止核心
+func (s *Service) renderCoreUnit() (string, error) {
+	if s.coreLaunchProvider == nil {
+		return "", fmt.Errorf("核心信息不可用")
+	}
+	info := s.coreLaunchProvider()
+	if info.CorePath == "" {
+		return "", fmt.Errorf("核心文件未找到，请先下载核心")
+	}
+
+	var args []string
+	if info.CoreType == "singbox" {
+		args = []string{info.CorePath, "run", "-D", s.dataDir, "-c", info.ConfigPath}
+	} else {
+		args = []string{info.CorePath, "-d", s.dataDir, "-f", info.ConfigPath}
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=ProxyStation Core (%s)\n", info.CoreType)
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", quoteExecArgs(args))
+	fmt.Fprintf(&b, "WorkingDirectory=%s\n", s.dataDir)
+	if info.CoreType == "singbox" {
+		b.WriteString("Environment=ENABLE_DEPRECATED_SPECIAL_OUTBOUNDS=true\n")
+	}
+	if info.RunAsUser != "" {
+		fmt.Fprintf(&b, "User=%s\n", info.RunAsUser)
+		b.WriteString("CapabilityBoundingSet=CAP_NET_ADMIN CAP_NET_BIND_SERVICE\n")
+	}
+	b.WriteString("AmbientCapabilities=CAP_NET_ADMIN CAP_NET_BIND_SERVICE\n")
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n")
+	b.WriteString("LimitNOFILE=1048576\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String(), nil
+}
+
+func systemctl(args ...string) error {
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s 失败: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// InstallSystemdUnit 安装并按需启用/启动单元
+func (s *Service) InstallSystemdUnit(opts SystemdInstallOptions) error {
+	if err := systemdAvailable(); err != nil {
+		return err
+	}
+
+	var name, content string
+	switch opts.Unit {
+	case "", "backend":
+		name = backendUnitName
+		content = s.renderBackendUnit(opts.SocketActivation)
+	case "core":
+		name = coreUnitName
+		unit, err := s.renderCoreUnit()
+		if err != nil {
+			return err
+		}
+		content = unit
+	default:
+		return fmt.Errorf("未知的单元: %s", opts.Unit)
+	}
+
+	if err := os.WriteFile(unitPath(name), []byte(content), 0644); err != nil {
+		return fmt.Errorf("写入服务文件失败: %v", err)
+	}
+
+	// socket 激活时由 socket 单元负责开机启动
+	enableUnit := name
+	if name == backendUnitName {
+		socketPath := unitPath(backendSocketName)
+		if opts.SocketActivation {
+			if err := os.WriteFile(socketPath, []byte(s.renderBackendSocket()), 0644); err != nil {
+				return fmt.Errorf("写入 socket 文件失败: %v", err)
+			}
+			enableUnit = backendSocketName
+		} else {
+			exec.Command("systemctl", "disable", backendSocketName).Run()
+			os.Remove(socketPath)
+		}
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if opts.Enable {
+		if err := systemctl("enable", enableUnit); err != nil {
+			return err
+		}
+	}
+	// 后端正在运行时，启动后端单元会与当前进程争用端口，只对核心单元执行启动
+	if opts.Start && name == coreUnitName {
+		if err := systemctl("start", name); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✅ systemd 单元已安装: %s\n", name)
+	return nil
+}
+
+// UninstallSystemdUnit 停用并删除单元
+func (s *Service) UninstallSystemdUnit(unit string) error {
+	if err := systemdAvailable(); err != nil {
+		return err
+	}
+
+	var names []string
+	switch unit {
+	case "", "backend":
+		// 不停止后端服务本身，否则会终止当前进程
+		names = []string{backendSocketName, backendUnitName}
+		exec.Command("systemctl", "disable", backendSocketName, backendUnitName).Run()
+	case "core":
+		names = []string{coreUnitName}
+		exec.Command("systemctl", "disable", "--now", coreUnitName).Run()
+	default:
+		return fmt.Errorf("未知的单元: %s", unit)
+	}
+
+	for _, name := range names {
+		if err := os.Remove(unitPath(name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除服务文件失败: %v", err)
+		}
+	}
+	return systemctl("daemon-reload")
+}
+
+// GetSystemdUnitStatus 通过 systemctl show 查询单元状态
+func (s *Service) GetSystemdUnitStatus(name string) *SystemdUnitStatus {
+	status := &SystemdUnitStatus{Name: name, Properties: map[string]string{}}
+	output, err := exec.Command("systemctl", "show", name, "--no-pager", "--property="+strings.Join(systemdProperties, ",")).Output()
+	if err != nil {
+		return status
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			status.Properties[key] = value
+		}
+	}
+
+	p := status.Properties
+	status.Installed = p["LoadState"] == "loaded"
+	status.Enabled = p["UnitFileState"] == "enabled"
+	status.ActiveState = p["ActiveState"]
+	status.SubState = p["SubState"]
+	status.Active = status.ActiveState == "active"
+	fmt.Sscanf(p["MainPID"], "%d", &status.MainPID)
+	return status
+}
+
+// GetSystemdStatus 获取后端、socket 和核心单元状态
+func (s *Service) GetSystemdStatus() ([]*SystemdUnitStatus, error) {
+	if err := systemdAvailable(); err != nil {
+		return nil, err
+	}
+	names := []string{backendUnitName + ".service", backendSocketName, coreUnitName + ".service"}
+	result := make([]*SystemdUnitStatus, 0, len(names))
+	for _, name := range names {
+		result = append(result, s.GetSystemdUnitStatus(name))
+	}
+	return result, nil
+}
+
+// GetSystemdStatus 获取 systemd 单元状态
+func (h *Handler) GetSystemdStatus(c *gin.Context) {
+	units, err := h.service.GetSystemdStatus()
+	if err != nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": units})
+}
+
+// InstallSystemdUnit 安装 systemd 单元
+func (h *Handler) InstallSystemdUnit(c *gin.Context) {
+	var opts SystemdInstallOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.service.InstallSystemdUnit(opts); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "systemd 单元已安装"})
+}
+
+// UninstallSystemdUnit 卸载 systemd 单元
+func (h *Handler) UninstallSystemdUnit(c *gin.Context) {
+	var req struct {
+		Unit string `json:"unit"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if err := h.service.UninstallSystemdUnit(req.Unit); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "systemd 单元已卸载"})
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"ProxyStation/backend/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -97,7 +99,7 @@ func getLocalIP() string {
 // Install 安装 WireGuard
 func (h *Handler) Install(c *gin.Context) {
 	if !IsLinux() {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "仅支持 Linux 系统"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.New(apierror.CodeUnsupported, "仅支持 Linux 系统"))
 		return
 	}
 	if h.service.CheckInstalled() {
@@ -105,7 +107,7 @@ func (h *Handler) Install(c *gin.Context) {
 		return
 	}
 	if err := h.service.InstallWireGuard(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "安装成功"})
@@ -127,7 +129,7 @@ func (h *Handler) GetServer(c *gin.Context) {
 	id := c.Param("id")
 	server, err := h.service.GetServer(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusNotFound, err)
 		return
 	}
 	running, _ := h.service.GetStatus(server.Tag)
@@ -138,18 +140,18 @@ func (h *Handler) GetServer(c *gin.Context) {
 // CreateServer 创建服务器
 func (h *Handler) CreateServer(c *gin.Context) {
 	if !IsLinux() {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "WireGuard 服务仅支持 Linux"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.New(apierror.CodeUnsupported, "WireGuard 服务仅支持 Linux"))
 		return
 	}
 
 	var server WireGuardServer
 	if err := c.ShouldBindJSON(&server); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.CreateServer(&server); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -160,7 +162,7 @@ func (h *Handler) CreateServer(c *gin.Context) {
 func (h *Handler) DeleteServer(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.service.DeleteServer(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "删除成功"})
@@ -169,13 +171,13 @@ func (h *Handler) DeleteServer(c *gin.Context) {
 // ApplyConfig 应用配置并启动
 func (h *Handler) ApplyConfig(c *gin.Context) {
 	if !IsLinux() {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "WireGuard 服务仅支持 Linux"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.New(apierror.CodeUnsupported, "WireGuard 服务仅支持 Linux"))
 		return
 	}
 
 	id := c.Param("id")
 	if err := h.service.ApplyConfig(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "启动成功"})
@@ -186,11 +188,11 @@ func (h *Handler) StopServer(c *gin.Context) {
 	id := c.Param("id")
 	server, err := h.service.GetServer(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusNotFound, err)
 		return
 	}
 	if err := h.service.StopInterface(server.Tag); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "停止成功"})
@@ -201,7 +203,7 @@ func (h *Handler) GetServerStatus(c *gin.Context) {
 	id := c.Param("id")
 	server, err := h.service.GetServer(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusNotFound, err)
 		return
 	}
 	running, output := h.service.GetStatus(server.Tag)
@@ -219,12 +221,12 @@ func (h *Handler) AddClient(c *gin.Context) {
 	serverID := c.Param("id")
 	var client WireGuardClient
 	if err := c.ShouldBindJSON(&client); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.service.AddClient(serverID, &client); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -237,7 +239,7 @@ func (h *Handler) DeleteClient(c *gin.Context) {
 	clientID := c.Param("clientId")
 
 	if err := h.service.DeleteClient(serverID, clientID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "删除成功"})
@@ -251,7 +253,7 @@ func (h *Handler) GetClientConfig(c *gin.Context) {
 
 	config, err := h.service.GenerateClientConfig(serverID, clientID, endpoint)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -268,13 +270,13 @@ func (h *Handler) UpdateServer(c *gin.Context) {
 		Description string `json:"description"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	server, err := h.service.GetServer(serverID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 1, "message": "服务器不存在"})
+		apierror.Message(c, http.StatusNotFound, "服务器不存在")
 		return
 	}
 
@@ -287,7 +289,7 @@ func (h *Handler) UpdateServer(c *gin.Context) {
 	server.Description = req.Description
 
 	if err := h.service.UpdateServer(server); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -304,13 +306,13 @@ func (h *Handler) UpdateClient(c *gin.Context) {
 		Enabled     bool   `json:"enabled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "参数错误"})
+		apierror.Message(c, http.StatusBadRequest, "参数错误")
 		return
 	}
 
 	client, err := h.service.UpdateClient(serverID, clientID, req.Name, req.Description, req.Enabled)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 1, "message": err.Error()})
+		apierror.Respond(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"ProxyStation/backend/config"
+	"ProxyStation/backend/secure"
 
 	"github.com/google/uuid"
 )
@@ -37,7 +38,7 @@ func NewService(dataDir string) *Service {
 func (s *Service) loadConfig() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	data, err := os.ReadFile(s.configPath)
+	data, err := secure.ReadFile(s.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			s.config = WireGuardConfig{Servers: []WireGuardServer{}}
@@ -53,7 +54,7 @@ func (s *Service) saveConfig() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.configPath, data, 0644)
+	return secure.WriteFile(s.configPath, data, 0644)
 }
 
 // IsLinux 检查是否为 Linux
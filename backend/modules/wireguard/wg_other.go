@@ -2,7 +2,10 @@
 
 package wireguard
 
-import "fmt"
+import "ProxyStation/backend/apierror"
+
+// errUnsupported 非 Linux 不支持 WireGuard 服务
+var errUnsupported = apierror.New(apierror.CodeUnsupported, "WireGuard 服务仅支持 Linux")
 
 // GetTunInterface 非 Linux 返回空
 func GetTunInterface() string {
@@ -11,17 +14,17 @@ func GetTunInterface() string {
 
 // GenerateWGConfig 非 Linux 不支持
 func (s *Service) GenerateWGConfig(serverID string) (string, error) {
-	return "", fmt.Errorf("WireGuard 服务仅支持 Linux")
+	return "", errUnsupported
 }
 
 // ApplyConfig 非 Linux 不支持
 func (s *Service) ApplyConfig(serverID string) error {
-	return fmt.Errorf("WireGuard 服务仅支持 Linux")
+	return errUnsupported
 }
 
 // StopInterface 非 Linux 不支持
 func (s *Service) StopInterface(tag string) error {
-	return fmt.Errorf("WireGuard 服务仅支持 Linux")
+	return errUnsupported
 }
 
 // GetStatus 非 Linux 返回 false
@@ -31,12 +34,12 @@ func (s *Service) GetStatus(tag string) (bool, string) {
 
 // GenerateClientConfig 非 Linux 不支持
 func (s *Service) GenerateClientConfig(serverID, clientID, endpoint string) (string, error) {
-	return "", fmt.Errorf("WireGuard 服务仅支持 Linux")
+	return "", errUnsupported
 }
 
 // InstallWireGuard 非 Linux 不支持
 func (s *Service) InstallWireGuard() error {
-	return fmt.Errorf("WireGuard 自动安装仅支持 Linux")
+	return apierror.New(apierror.CodeUnsupported, "WireGuard 自动安装仅支持 Linux")
 }
 
 // ForceCleanupInterface 非 Linux 不支持
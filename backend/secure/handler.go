@@ -0,0 +1,81 @@
+package secure
+
+import (
+	"net/http"
+
+	"ProxyStation/backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 数据加密 API 处理器
+type Handler struct{}
+
+// NewHandler 创建处理器
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/encryption", h.GetStatus)
+	r.POST("/encryption/enable", h.Enable)
+	r.POST("/encryption/disable", h.Disable)
+}
+
+// GetStatus 获取加密状态
+func (h *Handler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    GetStatus(),
+	})
+}
+
+// Enable 启用数据加密
+// 密码模式下重启前需要设置主密码环境变量，否则启动后数据无法读取；
+// 需要 acceptPlaintext 确认生成的核心配置仍为明文
+func (h *Handler) Enable(c *gin.Context) {
+	var req struct {
+		Mode            string `json:"mode" binding:"required"`
+		Password        string `json:"password"`
+		AcceptPlaintext bool   `json:"acceptPlaintext"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := Enable(req.Mode, req.Password, req.AcceptPlaintext); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    GetStatus(),
+	})
+}
+
+// Disable 关闭数据加密，密码模式需要提供主密码
+func (h *Handler) Disable(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := Disable(req.Password); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    GetStatus(),
+	})
+}
@@ -0,0 +1,461 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"ProxyStation/backend/apierror"
+)
+
+const (
+	// fileMagic 加密文件的文件头
+	fileMagic = "PSSEC1"
+	// checkPlain 用于校验密钥的明文
+	checkPlain = "ProxyStation"
+
+	// ModePassword 使用主密码派生密钥，启动时从环境变量读取
+	ModePassword = "password"
+	// ModeMachine 使用本机随机密钥，密钥文件不参与备份
+	ModeMachine = "machine"
+
+	// PasswordEnv 主密码环境变量
+	PasswordEnv = "PROXYSTATION_MASTER_PASSWORD"
+
+	minPasswordLength = 8
+)
+
+var (
+	// ErrLocked 已启用加密但密钥不可用
+	ErrLocked = apierror.New(apierror.CodeDataLocked, "数据已加密，但未提供主密码或密钥不正确")
+	// ErrWrongPassword 主密码错误
+	ErrWrongPassword = errors.New("主密码错误")
+	// ErrPlaintextNotAcknowledged 启用加密前未确认核心配置保持明文
+	ErrPlaintextNotAcknowledged = apierror.New(apierror.CodeBadRequest, PlaintextWarning+"，确认后再启用加密")
+)
+
+// PlaintextWarning 启用加密后仍保持明文的数据
+const PlaintextWarning = "生成的核心配置（configs/config.yaml、configs/singbox-config.json）包含全部节点的凭据，由核心直接读取，启用加密后仍为明文"
+
+// managedFiles 启用加密时保存的文件（相对数据目录）：包含节点凭据、密码和令牌的数据
+// 生成的核心配置不在其中：核心进程（包括 systemd 托管时）在启动、自动重启和热重载时直接读取，
+// 只能保持明文，启用加密时需要用户确认（见 PlaintextWarning）
+var managedFiles = []string{
+	"subscriptions.json",
+	"configs/????????-????-????-????-????????????.yaml", // 订阅原始内容
+	"configs/*_nodes.json",
+	"manual_nodes.json",
+	"profiles.json",
+	"config_template.json",
+	"proxy_settings.yaml",
+	"wireguard.json",
+	"notify.json",
+	"fleet.json",
+	"configs/history/????????-??????-*", // 配置历史版本（索引 history.json 不含凭据）
+}
+
+// keyInfo 加密设置，保存在 encryption.json（不含密钥）
+type keyInfo struct {
+	Enabled bool   `json:"enabled"`
+	Mode    string `json:"mode"`
+	Salt    []byte `json:"salt"`
+	Check   []byte `json:"check"` // 加密后的 checkPlain，用于校验密钥
+}
+
+// Status 加密状态
+type Status struct {
+	Enabled  bool   `json:"enabled"`
+	Mode     string `json:"mode,omitempty"`
+	Unlocked bool   `json:"unlocked"`          // 密钥可用
+	Files    int    `json:"files"`             // 已加密的文件数
+	Error    string `json:"error,omitempty"`   // 密钥不可用的原因
+	Warning  string `json:"warning,omitempty"` // 启用加密后仍为明文的数据
+}
+
+// store 数据目录加密存储
+type store struct {
+	mu      sync.RWMutex
+	dataDir string
+	info    keyInfo
+	aead    cipher.AEAD // 为 nil 时不加密（未启用或已锁定）
+	lockErr error
+}
+
+var defaultStore = &store{}
+
+// Init 读取加密设置，已启用时派生密钥
+// 密码模式从环境变量读取主密码，密钥不可用时读写加密文件返回 ErrLocked
+func Init(dataDir string) {
+	s := defaultStore
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dataDir = dataDir
+	data, err := os.ReadFile(s.infoPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.info); err != nil {
+		s.lockErr = fmt.Errorf("解析加密设置失败: %v", err)
+		fmt.Printf("⚠️ %v\n", s.lockErr)
+		return
+	}
+	if !s.info.Enabled {
+		return
+	}
+
+	aead, err := s.deriveAEAD(s.info.Mode, os.Getenv(PasswordEnv))
+	if err == nil {
+		err = verifyCheck(aead, s.info.Check)
+	}
+	if err != nil {
+		s.lockErr = err
+		fmt.Printf("🔒 数据加密已启用，但无法解锁: %v\n", err)
+		return
+	}
+	s.aead = aead
+	fmt.Printf("🔓 已解锁加密数据（%s 模式）\n", s.info.Mode)
+}
+
+func (s *store) infoPath() string {
+	return filepath.Join(s.dataDir, "encryption.json")
+}
+
+func (s *store) machineKeyPath() string {
+	// 以点开头，备份时不包含
+	return filepath.Join(s.dataDir, ".machine.key")
+}
+
+// deriveAEAD 派生密钥（调用时需持有锁）
+func (s *store) deriveAEAD(mode, password string) (cipher.AEAD, error) {
+	var secret []byte
+	switch mode {
+	case ModePassword:
+		if password == "" {
+			return nil, fmt.Errorf("%w（设置环境变量 %s）", ErrLocked, PasswordEnv)
+		}
+		secret = []byte(password)
+	case ModeMachine:
+		key, err := os.ReadFile(s.machineKeyPath())
+		if err != nil {
+			return nil, fmt.Errorf("%w: 读取本机密钥失败: %v", ErrLocked, err)
+		}
+		secret = key
+	default:
+		return nil, fmt.Errorf("不支持的加密模式: %s", mode)
+	}
+
+	key, err := scrypt.Key(secret, s.info.Salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func verifyCheck(aead cipher.AEAD, check []byte) error {
+	plain, err := open(aead, check)
+	if err != nil || string(plain) != checkPlain {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// seal 加密：文件头 + nonce + AES-GCM 密文
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(fileMagic)+len(nonce)+len(plain)+aead.Overhead())
+	out = append(out, fileMagic...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plain, []byte(fileMagic)), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if !IsEncrypted(sealed) {
+		return nil, fmt.Errorf("不是加密文件")
+	}
+	sealed = sealed[len(fileMagic):]
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("加密文件已损坏")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(fileMagic))
+}
+
+// IsEncrypted 数据是否为加密文件
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(fileMagic))
+}
+
+// ReadFile 读取文件，加密文件在内存中解密；未加密的文件原样返回
+func ReadFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil || !IsEncrypted(data) {
+		return data, err
+	}
+
+	s := defaultStore
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.aead == nil {
+		return nil, ErrLocked
+	}
+	plain, err := open(s.aead, data)
+	if err != nil {
+		return nil, fmt.Errorf("解密 %s 失败: %v", filepath.Base(name), err)
+	}
+	return plain, nil
+}
+
+// WriteFile 写入文件，启用加密时加密后写入（权限限制为仅所有者可读写）
+// 已启用加密但密钥不可用时拒绝写入，避免明文覆盖无法读取的加密数据
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	s := defaultStore
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.info.Enabled && s.aead == nil {
+		return ErrLocked
+	}
+	if s.aead == nil {
+		return os.WriteFile(name, data, perm)
+	}
+	sealed, err := seal(s.aead, data)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(name, sealed, 0600)
+}
+
+// writeAtomic 写入临时文件后重命名，避免写入中断损坏加密文件
+func writeAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// GetStatus 获取加密状态
+func GetStatus() Status {
+	s := defaultStore
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := Status{
+		Enabled:  s.info.Enabled,
+		Unlocked: !s.info.Enabled || s.aead != nil,
+	}
+	if s.info.Enabled {
+		status.Mode = s.info.Mode
+		status.Warning = PlaintextWarning
+	}
+	if s.lockErr != nil {
+		status.Error = s.lockErr.Error()
+	}
+	for _, name := range s.managedPaths() {
+		if data, err := os.ReadFile(name); err == nil && IsEncrypted(data) {
+			status.Files++
+		}
+	}
+	return status
+}
+
+// managedPaths 数据目录中存在的需要加密的文件（调用时需持有锁）
+func (s *store) managedPaths() []string {
+	var paths []string
+	filepath.WalkDir(s.dataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dataDir, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		// 只需要检查数据目录、configs 目录和配置历史
+		if d.IsDir() {
+			if rel != "." && rel != "configs" && rel != "configs/history" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		for _, pattern := range managedFiles {
+			if ok, _ := path.Match(pattern, rel); ok {
+				paths = append(paths, p)
+				break
+			}
+		}
+		return nil
+	})
+	return paths
+}
+
+// Enable 启用加密并加密已有的数据文件
+// acceptPlaintext 表示用户已确认生成的核心配置仍为明文
+func Enable(mode, password string, acceptPlaintext bool) error {
+	s := defaultStore
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.info.Enabled {
+		return fmt.Errorf("数据加密已启用")
+	}
+	if !acceptPlaintext {
+		return ErrPlaintextNotAcknowledged
+	}
+	switch mode {
+	case ModePassword:
+		if len(password) < minPasswordLength {
+			return fmt.Errorf("主密码至少 %d 位", minPasswordLength)
+		}
+	case ModeMachine:
+		if _, err := os.Stat(s.machineKeyPath()); os.IsNotExist(err) {
+			key := make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return err
+			}
+			if err := os.WriteFile(s.machineKeyPath(), key, 0600); err != nil {
+				return fmt.Errorf("保存本机密钥失败: %v", err)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的加密模式: %s", mode)
+	}
+
+	info := keyInfo{Enabled: true, Mode: mode, Salt: make([]byte, 16)}
+	if _, err := rand.Read(info.Salt); err != nil {
+		return err
+	}
+	s.info = info
+	aead, err := s.deriveAEAD(mode, password)
+	if err != nil {
+		s.info = keyInfo{}
+		return err
+	}
+	if info.Check, err = seal(aead, []byte(checkPlain)); err != nil {
+		s.info = keyInfo{}
+		return err
+	}
+
+	// 先加密数据文件，全部成功后再保存设置
+	paths := s.managedPaths()
+	for _, name := range paths {
+		data, err := os.ReadFile(name)
+		if err != nil || IsEncrypted(data) {
+			continue
+		}
+		sealed, err := seal(aead, data)
+		if err == nil {
+			err = writeAtomic(name, sealed, 0600)
+		}
+		if err != nil {
+			s.info = keyInfo{}
+			decryptFiles(paths, aead)
+			return fmt.Errorf("加密 %s 失败: %v", filepath.Base(name), err)
+		}
+	}
+	if err := s.saveInfo(info); err != nil {
+		s.info = keyInfo{}
+		decryptFiles(paths, aead)
+		return err
+	}
+
+	s.info = info
+	s.aead = aead
+	s.lockErr = nil
+	fmt.Printf("🔐 已启用数据加密（%s 模式），已加密 %d 个文件\n", mode, len(paths))
+	fmt.Printf("⚠️ %s\n", PlaintextWarning)
+	return nil
+}
+
+// Disable 解密数据文件并关闭加密，密码模式需要提供主密码
+func Disable(password string) error {
+	s := defaultStore
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.info.Enabled {
+		return fmt.Errorf("数据加密未启用")
+	}
+	if s.aead == nil {
+		return ErrLocked
+	}
+	if s.info.Mode == ModePassword {
+		aead, err := s.deriveAEAD(ModePassword, password)
+		if err != nil {
+			return err
+		}
+		if err := verifyCheck(aead, s.info.Check); err != nil {
+			return err
+		}
+	}
+
+	if err := decryptFiles(s.managedPaths(), s.aead); err != nil {
+		return err
+	}
+	if err := s.saveInfo(keyInfo{}); err != nil {
+		return err
+	}
+	s.info = keyInfo{}
+	s.aead = nil
+	fmt.Println("🔓 已关闭数据加密，数据文件已恢复为明文")
+	return nil
+}
+
+// decryptFiles 将加密文件恢复为明文
+func decryptFiles(paths []string, aead cipher.AEAD) error {
+	for _, name := range paths {
+		data, err := os.ReadFile(name)
+		if err != nil || !IsEncrypted(data) {
+			continue
+		}
+		plain, err := open(aead, data)
+		if err != nil {
+			return fmt.Errorf("解密 %s 失败: %v", filepath.Base(name), err)
+		}
+		if err := writeAtomic(name, plain, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *store) saveInfo(info keyInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(s.infoPath(), data, 0600)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/modules/auth"
+)
+
+// openAPIPublicRoutes 不需要认证的接口
+var openAPIPublicRoutes = map[string]bool{
+	"/api/health":       true,
+	"/api/openapi.json": true,
+	"/api/auth/config":  true,
+	"/api/auth/login":   true,
+	"/api/auth/logout":  true,
+	"/api/auth/check":   true,
+	"/sub/:token/:core": true, // 使用托管令牌校验
+}
+
+// pathParamPattern gin 路径参数（:id 和 *path）
+var pathParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// openAPISpec 返回 OpenAPI 3 文档，供第三方自动化客户端和 SDK 生成使用
+func (s *Server) openAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPI)
+}
+
+// buildOpenAPISpec 根据已注册的路由生成 OpenAPI 3 文档
+// 所有 JSON 接口使用统一的 {code, message, data} 响应，请求和响应体不区分具体结构
+func buildOpenAPISpec(routes gin.RoutesInfo, version string) gin.H {
+	paths := gin.H{}
+	usedIDs := map[string]bool{}
+	var errorCodes []string
+	for _, entry := range apierror.Catalog() {
+		errorCodes = append(errorCodes, string(entry.Code))
+	}
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") && !strings.HasPrefix(route.Path, "/ws/") && !strings.HasPrefix(route.Path, "/sub/") {
+			continue // 前端静态文件
+		}
+
+		path := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+
+		tag := openAPITag(route.Path)
+		name := handlerFuncName(route.Handler)
+		op := gin.H{
+			"operationId": uniqueOperationID(tag, name, route, usedIDs),
+			"summary":     name,
+			"tags":        []string{tag},
+			"responses":   openAPIResponses(route),
+		}
+
+		var params []gin.H
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, gin.H{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   gin.H{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		switch route.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			op["requestBody"] = gin.H{
+				"content": gin.H{"application/json": gin.H{"schema": gin.H{"type": "object"}}},
+			}
+		}
+
+		if openAPIPublicRoutes[route.Path] {
+			op["security"] = []gin.H{} // 覆盖全局认证要求
+		} else if route.Method == http.MethodGet && auth.ReadOnlyRoute(route.Path) {
+			op["x-required-scope"] = auth.ScopeRead
+		} else {
+			op["x-required-scope"] = auth.ScopeAdmin
+		}
+
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "ProxyStation API",
+			"version":     version,
+			"description": "JSON 接口统一返回 {code, message, data}，code 为 0 表示成功；失败时 error.code 为稳定的错误码（见 /api/system/errors）。认证使用 Authorization: Bearer <令牌>，只读令牌只能访问 x-required-scope 为 read 的接口。",
+		},
+		"servers":  []gin.H{{"url": "/"}},
+		"security": []gin.H{{"bearerAuth": []string{}}, {"cookieAuth": []string{}}},
+		"paths":    paths,
+		"components": gin.H{
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer"},
+				"cookieAuth": gin.H{"type": "apiKey", "in": "cookie", "name": "ProxyStation-token"},
+			},
+			"schemas": gin.H{
+				"Response": gin.H{
+					"type":     "object",
+					"required": []string{"code", "message"},
+					"properties": gin.H{
+						"code":    gin.H{"type": "integer", "description": "0 表示成功"},
+						"message": gin.H{"type": "string"},
+						"data":    gin.H{},
+					},
+				},
+				"Error": gin.H{
+					"type":     "object",
+					"required": []string{"code", "message", "error"},
+					"properties": gin.H{
+						"code":    gin.H{"type": "integer", "description": "1 表示失败，2 表示配置校验失败"},
+						"message": gin.H{"type": "string"},
+						"error": gin.H{
+							"type":     "object",
+							"required": []string{"code", "category"},
+							"properties": gin.H{
+								"code":     gin.H{"type": "string", "enum": errorCodes},
+								"category": gin.H{"type": "string"},
+								"hint":     gin.H{"type": "string"},
+								"details":  gin.H{},
+							},
+						},
+						"data": gin.H{},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPIResponses 按接口类型生成响应说明
+func openAPIResponses(route gin.RouteInfo) gin.H {
+	jsonContent := func(ref string) gin.H {
+		return gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/" + ref}}}
+	}
+
+	responses := gin.H{}
+	switch {
+	case strings.HasPrefix(route.Path, "/ws/"):
+		responses["101"] = gin.H{"description": "WebSocket 连接"}
+	case route.Path == "/api/events":
+		responses["200"] = gin.H{
+			"description": "Server-Sent Events 事件流",
+			"content":     gin.H{"text/event-stream": gin.H{"schema": gin.H{"type": "string"}}},
+		}
+	case strings.HasPrefix(route.Path, "/sub/"):
+		responses["200"] = gin.H{
+			"description": "核心配置文件",
+			"content":     gin.H{"text/plain": gin.H{"schema": gin.H{"type": "string"}}},
+		}
+	default:
+		responses["200"] = gin.H{"description": "成功", "content": jsonContent("Response")}
+		responses["400"] = gin.H{"description": "请求参数错误或操作失败", "content": jsonContent("Error")}
+	}
+
+	if !openAPIPublicRoutes[route.Path] {
+		responses["401"] = gin.H{"description": "未认证", "content": jsonContent("Error")}
+		responses["403"] = gin.H{"description": "令牌权限不足", "content": jsonContent("Error")}
+	}
+	if strings.ContainsAny(route.Path, ":*") && !strings.HasPrefix(route.Path, "/ws/") {
+		responses["404"] = gin.H{"description": "资源不存在", "content": jsonContent("Error")}
+	}
+	return responses
+}
+
+// openAPITag 使用路径的第一段作为分组，如 /api/proxy/status -> proxy
+func openAPITag(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if parts[0] == "api" && len(parts) > 1 {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// handlerFuncName 从处理函数全名中提取方法名
+// 如 ProxyStation/backend/modules/proxy.(*Handler).GetStatus-fm -> GetStatus，匿名函数返回空字符串
+func handlerFuncName(handler string) string {
+	name := strings.TrimSuffix(handler, "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	if strings.HasPrefix(name, "func") {
+		return ""
+	}
+	return name
+}
+
+// uniqueOperationID 生成唯一的 operationId，如 proxy.GetStatus
+// 匿名函数或名称重复时使用请求方法和路径
+func uniqueOperationID(tag, name string, route gin.RouteInfo, used map[string]bool) string {
+	id := tag + "." + name
+	if name == "" || used[id] {
+		id = tag + "." + strings.ToLower(route.Method) + camelPath(route.Path)
+	}
+	used[id] = true
+	return id
+}
+
+// camelPath 将路径转换为驼峰形式，如 /api/proxy/:id/run -> ProxyIdRun
+func camelPath(path string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if part == "api" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
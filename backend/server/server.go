@@ -2,23 +2,41 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"ProxyStation/backend/apierror"
+	"ProxyStation/backend/clashapi"
 	"ProxyStation/backend/config"
+	"ProxyStation/backend/events"
+	"ProxyStation/backend/httpclient"
 	"ProxyStation/backend/middleware"
+	"ProxyStation/backend/modules/audit"
 	"ProxyStation/backend/modules/auth"
+	"ProxyStation/backend/modules/backup"
+	"ProxyStation/backend/modules/blocklist"
 	"ProxyStation/backend/modules/core"
+	"ProxyStation/backend/modules/dnsserver"
+	"ProxyStation/backend/modules/fleet"
+	"ProxyStation/backend/modules/geodata"
+	"ProxyStation/backend/modules/lan"
 	"ProxyStation/backend/modules/node"
+	"ProxyStation/backend/modules/notify"
 	"ProxyStation/backend/modules/proxy"
 	"ProxyStation/backend/modules/ruleset"
+	"ProxyStation/backend/modules/schedule"
 	"ProxyStation/backend/modules/speedtest"
 	"ProxyStation/backend/modules/subscription"
 	"ProxyStation/backend/modules/system"
+	"ProxyStation/backend/secure"
 	"ProxyStation/backend/websocket"
 )
 
@@ -34,8 +52,18 @@ type Server struct {
 	router       *gin.Engine
 	httpServer   *http.Server
 	wsHub        *websocket.Hub
+	eventBus     *events.Bus
+	accessLog    *middleware.AccessLog
+	access       *middleware.AccessControl
 	proxyHandler *proxy.Handler
 	authHandler  *auth.Handler
+	dnsServer    *dnsserver.Service
+	openAPI      gin.H // 启动时根据已注册的路由生成
+
+	proxySettings  *proxy.SettingsHandler
+	listen         listenConfig
+	tlsConfig      *tls.Config
+	redirectServer *http.Server // HTTP 重定向到 HTTPS
 }
 
 // New 创建服务器实例
@@ -48,16 +76,31 @@ func New(cfg *config.Config) *Server {
 	}
 
 	router := gin.New()
+	// 不信任任何代理的 X-Forwarded-For，ClientIP 与 RemoteIP 一致，避免客户端伪造来源地址
+	router.SetTrustedProxies(nil)
 	wsHub := websocket.NewHub()
 
 	s := &Server{
-		config: cfg,
-		router: router,
-		wsHub:  wsHub,
+		config:    cfg,
+		router:    router,
+		wsHub:     wsHub,
+		eventBus:  events.NewBus(),
+		accessLog: middleware.NewAccessLog(),
+		access:    middleware.NewAccessControl(),
 	}
 
+	// 加密数据需要在各模块读取配置前解锁
+	secure.Init(cfg.DataDir)
+
+	// 外部请求超时
+	timeouts := cfg.Timeouts
+	httpclient.Configure(time.Duration(timeouts.Dial)*time.Second, time.Duration(timeouts.Fetch)*time.Second)
+	clashapi.SetDefaultTimeout(time.Duration(timeouts.CoreAPI) * time.Second)
+
 	s.setupMiddleware()
 	s.setupRoutes()
+	s.openAPI = buildOpenAPISpec(router.Routes(), Version)
+	s.setupListen()
 
 	return s
 }
@@ -70,6 +113,17 @@ func (s *Server) setupMiddleware() {
 	// 日志中间件
 	s.router.Use(middleware.Logger())
 
+	// API 访问日志（在代理设置中开启）
+	s.router.Use(s.accessLog.Middleware())
+
+	// 访问控制：来源地址白名单和客户端证书（在代理设置中配置）
+	s.router.Use(s.access.Middleware())
+
+	// 限流和请求大小限制（仅 API 和 WebSocket）
+	limits := s.config.Limits
+	s.router.Use(middleware.RateLimit(limits.RateLimit, limits.Burst, limits.ExemptLoopback))
+	s.router.Use(middleware.MaxBodySize(limits.MaxBodySize, limits.MaxUploadSize))
+
 	// CORS 中间件
 	s.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -99,6 +153,9 @@ func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.GET("/api/health", s.healthCheck)
 
+	// OpenAPI 文档（不需要认证）
+	s.router.GET("/api/openapi.json", s.openAPISpec)
+
 	// 认证模块
 	authService := auth.NewService(s.config.DataDir)
 	s.authHandler = auth.NewHandler(authService)
@@ -106,6 +163,10 @@ func (s *Server) setupRoutes() {
 	// API 路由组
 	api := s.router.Group("/api")
 
+	// 审计日志：记录所有修改状态的请求（包括登录和未授权的请求）
+	auditHandler := audit.NewHandler(s.config.DataDir)
+	api.Use(auditHandler.Middleware())
+
 	// 认证路由（不需要认证中间件）
 	s.authHandler.RegisterRoutes(api)
 
@@ -116,23 +177,128 @@ func (s *Server) setupRoutes() {
 		// 系统信息
 		api.GET("/system/info", s.systemInfo)
 
+		// API 访问日志
+		api.GET("/system/access-log", s.getAccessLog)
+		api.DELETE("/system/access-log", s.clearAccessLog)
+
+		// 错误码目录
+		api.GET("/system/errors", s.errorCatalog)
+
+		// 审计日志
+		auditHandler.RegisterRoutes(api.Group("/audit"))
+
+		// 备份与恢复
+		backupHandler := backup.NewHandler(s.config.DataDir)
+		backupHandler.GetService().SetVersion(Version)
+		backupHandler.RegisterRoutes(api)
+
+		// 敏感配置文件加密存储
+		secure.NewHandler().RegisterRoutes(api.Group("/system"))
+
+		// 状态变化事件推送 (SSE)
+		api.GET("/events", s.eventBus.HandleSSE)
+
+		// 事件通知（Webhook / Telegram / Bark / ntfy）
+		notifyHandler := notify.NewHandler(s.config.DataDir)
+		notifyHandler.RegisterRoutes(api.Group("/notify"))
+		notifyHandler.GetService().Listen(s.eventBus)
+
 		// 代理模块
 		s.proxyHandler = proxy.NewHandler(s.config.DataDir)
 		s.proxyHandler.RegisterRoutes(api.Group("/proxy"))
+		s.proxyHandler.RegisterDiagnosticsRoutes(api)
+		s.proxyHandler.GetService().SetEventPublisher(s.eventBus.Publish)
+		// WebSocket 转发到核心时注入 API secret
+		s.wsHub.SetCoreAPIProvider(s.proxyHandler.CoreAPIEndpoint)
 
 		// 代理设置模块
 		settingsHandler := proxy.NewSettingsHandler(s.config.DataDir)
+		s.proxySettings = settingsHandler
 		settingsHandler.RegisterRoutes(api.Group("/proxy"))
 		// 设置代理服务引用，用于同步 autoStart 等设置
 		settingsHandler.SetProxyService(s.proxyHandler.GetService())
 
+		// 访问日志开关
+		s.accessLog.SetEnabled(settingsHandler.GetCurrentSettings().AccessLog)
+		s.setAllowedCIDRs(settingsHandler.GetCurrentSettings().Web.AllowedCIDRs)
+		settingsHandler.SetOnChange(func(settings *proxy.ProxySettings) {
+			s.accessLog.SetEnabled(settings.AccessLog)
+			s.setAllowedCIDRs(settings.Web.AllowedCIDRs)
+		})
+
 		// 设置代理设置提供者（让 proxy service 能获取优化配置）
 		s.proxyHandler.GetService().SetSettingsProvider(func() *proxy.ProxySettings {
 			return settingsHandler.GetCurrentSettings()
 		})
 
+		// 对齐透明代理规则（后端重启后恢复或清理残留的 nftables 规则）
+		s.proxyHandler.ReconcileTransparentMode()
+
 		// 检查自动启动
-		s.proxyHandler.GetService().AutoStartIfEnabled()
+		s.proxyHandler.AutoStartIfEnabled()
+
+		// 定时计划：按每周时间表启停核心或切换透明代理模式
+		scheduleHandler := schedule.NewHandler(s.config.DataDir)
+		scheduleHandler.RegisterRoutes(api.Group("/schedules"))
+		scheduleHandler.GetService().SetController(schedule.Controller{
+			State: func() schedule.ProxyState {
+				status := s.proxyHandler.GetService().GetStatus()
+				return schedule.ProxyState{
+					Running: status.Running,
+					Mode:    status.TransparentMode,
+					Scope:   status.ProxyScope,
+				}
+			},
+			Start:              s.proxyHandler.GetService().Start,
+			Stop:               s.proxyHandler.GetService().Stop,
+			SetTransparentMode: s.proxyHandler.SwitchTransparentMode,
+		})
+
+		// 多实例同步：向其他 ProxyStation 推送配置模板和 nftables 规则模板
+		fleetHandler := fleet.NewHandler(s.config.DataDir)
+		fleetHandler.RegisterRoutes(api.Group("/fleet"))
+		fleetHandler.GetService().SetController(fleet.Controller{
+			Export: func() (*fleet.Bundle, error) {
+				template, err := json.Marshal(s.proxyHandler.GetService().GetConfigTemplate())
+				if err != nil {
+					return nil, err
+				}
+				// 使用默认模板时推送空字符串，远程实例同样恢复默认
+				nftTemplate, custom := s.proxyHandler.GetService().GetNftTemplate()
+				if !custom {
+					nftTemplate = ""
+				}
+				return &fleet.Bundle{Template: template, NftTemplate: &nftTemplate}, nil
+			},
+			Import: func(bundle *fleet.Bundle) (*fleet.ImportResult, error) {
+				proxyService := s.proxyHandler.GetService()
+				result := &fleet.ImportResult{}
+				if len(bundle.Template) > 0 {
+					var template proxy.ConfigTemplate
+					if err := json.Unmarshal(bundle.Template, &template); err != nil {
+						return nil, fmt.Errorf("配置模板格式错误: %v", err)
+					}
+					if err := proxyService.ReplaceConfigTemplate(template); err != nil {
+						return nil, fmt.Errorf("配置模板: %v", err)
+					}
+					result.Template = true
+				}
+				if bundle.NftTemplate != nil {
+					if err := s.proxyHandler.ReplaceNftTemplate(*bundle.NftTemplate); err != nil {
+						return result, fmt.Errorf("nftables 模板: %v", err)
+					}
+					result.NftTemplate = true
+				}
+				// 重启核心重新生成配置，启动回调会重新应用 nftables 规则
+				if bundle.Apply && proxyService.GetStatus().Running && !proxyService.IsAdopted() {
+					if err := proxyService.Restart(); err != nil {
+						return result, fmt.Errorf("配置已导入，但重启核心失败: %v", err)
+					}
+					result.Restarted = true
+				}
+				return result, nil
+			},
+		})
 
 		// 核心模块
 		coreHandler := core.NewHandler(s.config.DataDir)
@@ -144,6 +310,16 @@ func (s *Server) setupRoutes() {
 			fmt.Printf("🔄 核心已切换为: %s\n", coreType)
 		})
 
+		// 核心更新后，如果代理正在使用该核心则重启
+		coreHandler.GetService().SetOnCoreUpdated(func(coreType string) error {
+			proxyService := s.proxyHandler.GetService()
+			if !proxyService.GetStatus().Running || proxyService.GetCoreType() != coreType {
+				return nil
+			}
+			fmt.Printf("🔄 核心 %s 已更新，正在重启代理服务...\n", coreType)
+			return proxyService.Restart()
+		})
+
 		// 初始化时同步核心类型
 		s.proxyHandler.GetService().SetCoreType(coreHandler.GetService().GetCurrentCore())
 
@@ -151,13 +327,47 @@ func (s *Server) setupRoutes() {
 		subHandler := subscription.NewHandler(s.config.DataDir)
 		subHandler.RegisterRoutes(api.Group("/subscriptions"))
 
+		// 配置托管的 subscription-userinfo 汇总所有订阅的流量和最早的到期时间
+		s.proxyHandler.GetService().SetSubscriptionInfoProvider(func() proxy.SubscriptionInfo {
+			var info proxy.SubscriptionInfo
+			for _, sub := range subHandler.GetService().List() {
+				if sub.Traffic != nil {
+					info.Upload += sub.Traffic.Upload
+					info.Download += sub.Traffic.Download
+					info.Total += sub.Traffic.Total
+				}
+				if sub.ExpireTime != nil && (info.Expire == 0 || sub.ExpireTime.Unix() < info.Expire) {
+					info.Expire = sub.ExpireTime.Unix()
+				}
+			}
+			return info
+		})
+		subHandler.GetService().SetOnUpdateFailed(func(sub *subscription.Subscription, err error) {
+			s.eventBus.Publish(events.SubscriptionFailed, map[string]interface{}{
+				"id":    sub.ID,
+				"name":  sub.Name,
+				"error": err.Error(),
+			})
+		})
+
 		// 节点模块
 		nodeHandler := node.NewHandler(s.config.DataDir, subHandler.GetService())
 		nodeHandler.RegisterRoutes(api.Group("/nodes"))
+		nodeHandler.GetService().SetOnHealthChange(func(change node.HealthChange) {
+			s.eventBus.Publish(events.NodeHealthChanged, change)
+			if change.Total > 0 && change.Healthy == 0 {
+				s.eventBus.Publish(events.NodesAllDead, change)
+			}
+		})
 
 		// 设置节点提供者（让 proxy service 能获取过滤后的节点）
 		s.proxyHandler.GetService().SetNodeProvider(func() []proxy.ProxyNode {
-			nodes := nodeHandler.GetService().ListAll()
+			// 健康检查开启排除时不包含不可用节点
+			nodes := nodeHandler.GetService().ListForConfig()
+			sources := make(map[string]string)
+			for _, sub := range subHandler.GetService().List() {
+				sources[sub.ID] = sub.Name
+			}
 			result := make([]proxy.ProxyNode, 0, len(nodes))
 			for _, n := range nodes {
 				result = append(result, proxy.ProxyNode{
@@ -167,14 +377,40 @@ func (s *Server) setupRoutes() {
 					ServerPort: n.ServerPort,
 					Config:     n.Config,
 					IsManual:   n.IsManual,
+					Source:     sources[n.SubscriptionID],
+					Delay:      n.Delay,
 				})
 			}
 			return result
 		})
 
+		// 节点下载测速通过核心的测速专用监听进行
+		nodeHandler.GetService().SetSpeedTester(func(ctx context.Context, nodeName, testURL string, duration time.Duration) (*node.SpeedResult, error) {
+			r, err := s.proxyHandler.SpeedTestNode(ctx, nodeName, testURL, duration)
+			if err != nil {
+				return nil, err
+			}
+			return &node.SpeedResult{
+				DownloadSpeed: r.DownloadSpeed,
+				Bytes:         r.Bytes,
+				Duration:      r.Duration,
+				TTFB:          r.TTFB,
+			}, nil
+		})
+
 		// 系统管理模块
 		systemHandler := system.NewHandler(s.config.DataDir)
 		systemHandler.RegisterRoutes(api.Group("/system"))
+		systemHandler.GetService().SetListenAddress(fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port))
+		systemHandler.GetService().SetCoreLaunchProvider(func() system.CoreLaunchInfo {
+			coreType, corePath, configPath, runAsUser := s.proxyHandler.GetService().GetCoreLaunchInfo()
+			return system.CoreLaunchInfo{
+				CoreType:   coreType,
+				CorePath:   corePath,
+				ConfigPath: configPath,
+				RunAsUser:  runAsUser,
+			}
+		})
 
 		// 规则集模块 (Mihomo)
 		rulesetService := ruleset.NewService(s.config.DataDir)
@@ -215,14 +451,83 @@ func (s *Server) setupRoutes() {
 		})
 		proxy.RegisterSingBoxRulesetRoutes(api)
 
+		// GEO 数据模块
+		geodataHandler := geodata.NewHandler(s.config.DataDir)
+		geodataHandler.RegisterRoutes(api.Group("/geodata"))
+		// Mihomo 将 GEO 数据缓存在内存中，替换文件后需要重启核心才能生效
+		geodataHandler.GetService().SetOnUpdated(func() error {
+			proxyService := s.proxyHandler.GetService()
+			if !proxyService.GetStatus().Running || proxyService.GetCoreType() != "mihomo" {
+				return nil
+			}
+			fmt.Println("🔄 GEO 数据已更新，正在重启代理服务...")
+			return proxyService.Restart()
+		})
+		// 连接和节点服务器的国家 / ASN 信息（离线 MMDB 查询）
+		s.proxyHandler.GetService().SetGeoResolver(func(ip string) *proxy.GeoInfo {
+			info := geodataHandler.GetService().LookupIP(ip)
+			if info == nil {
+				return nil
+			}
+			return &proxy.GeoInfo{IP: info.IP, Country: info.Country, ASN: info.ASN, ASOrg: info.ASOrg}
+		})
+		nodeHandler.GetService().SetGeoResolver(func(ip string) *node.GeoInfo {
+			info := geodataHandler.GetService().LookupIP(ip)
+			if info == nil {
+				return nil
+			}
+			return &node.GeoInfo{IP: info.IP, Country: info.Country, ASN: info.ASN, ASOrg: info.ASOrg}
+		})
+
+		// 广告拦截列表，生成为 REJECT 规则集合
+		blocklistHandler := blocklist.NewHandler(s.config.DataDir)
+		blocklistHandler.RegisterRoutes(api.Group("/blocklist"))
+		s.proxyHandler.GetService().SetBlocklistProvider(func() *proxy.BlocklistRules {
+			rules := blocklistHandler.GetService().GetRules()
+			if rules == nil {
+				return nil
+			}
+			return &proxy.BlocklistRules{
+				MihomoPath:  rules.MihomoPath,
+				SingBoxPath: rules.SingBoxPath,
+				Whitelist:   rules.Whitelist,
+			}
+		})
+		// 规则变化后重新生成配置，核心运行中时重启使规则集合生效
+		blocklistHandler.GetService().SetOnChanged(func() error {
+			proxyService := s.proxyHandler.GetService()
+			if !proxyService.GetStatus().Running {
+				return nil
+			}
+			if _, err := proxyService.RegenerateConfig(); err != nil {
+				return err
+			}
+			fmt.Println("🔄 广告拦截规则已更新，正在重启代理服务...")
+			return proxyService.Restart()
+		})
+
+		// 局域网设备发现，设备策略可以只填写 MAC
+		lanHandler := lan.NewHandler(s.config.DataDir)
+		lanHandler.RegisterRoutes(api.Group("/lan"))
+		lanHandler.RegisterNetworkRoutes(api.Group("/network"))
+		s.proxyHandler.GetService().SetMACResolver(lanHandler.GetService().LookupIP)
+
+		// 本地 DNS 服务（独立于核心，按域名分流、缓存和拦截）
+		dnsServerHandler := dnsserver.NewHandler(s.config.DataDir)
+		dnsServerHandler.RegisterRoutes(api.Group("/dns-server"))
+		s.dnsServer = dnsServerHandler.GetService()
+
 		// 测速模块
 		speedtestHandler := speedtest.NewHandler()
 		speedtestHandler.RegisterRoutes(api.Group("/speedtest"))
 
 	}
 
+	// 配置托管：其他设备的客户端订阅生成的配置（使用托管令牌校验，不需要登录）
+	s.router.GET("/sub/:token/:core", s.proxyHandler.ServeSharedProfile)
+
 	// WebSocket 路由
-	ws := s.router.Group("/ws")
+	ws := s.router.Group("/ws", s.authHandler.AuthMiddleware())
 	{
 		ws.GET("/traffic", s.wsHub.HandleTraffic)
 		ws.GET("/logs", s.wsHub.HandleLogs)
@@ -260,36 +565,150 @@ func (s *Server) systemInfo(c *gin.Context) {
 	})
 }
 
+// getAccessLog 获取最近的 API 访问记录
+// 参数: limit 条数（默认 200），minStatus 只返回状态码不小于该值的记录（如 400）
+func (s *Server) getAccessLog(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	minStatus, _ := strconv.Atoi(c.Query("minStatus"))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"enabled": s.accessLog.Enabled(),
+			"entries": s.accessLog.Recent(limit, minStatus),
+		},
+	})
+}
+
+// clearAccessLog 清空 API 访问记录
+func (s *Server) clearAccessLog(c *gin.Context) {
+	s.accessLog.Clear()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// errorCatalog 获取错误码目录（错误码、分类和处理建议）
+func (s *Server) errorCatalog(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    apierror.Catalog(),
+	})
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	// 启动 WebSocket Hub
 	go s.wsHub.Run()
 
-	addr := fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port)
+	addr := net.JoinHostPort(s.listen.Host, strconv.Itoa(s.listen.Port))
 	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: s.router,
+		Addr:              addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+	}
+
+	// 优先使用 systemd socket 激活传入的监听
+	listener, err := systemdListener()
+	if err != nil {
+		return fmt.Errorf("获取 systemd socket 失败: %w", err)
+	}
+	if listener != nil {
+		fmt.Printf("🔌 使用 systemd socket 激活: %s\n", listener.Addr())
+	} else {
+		listener, err = net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.tlsConfig != nil {
+		s.httpServer.TLSConfig = s.tlsConfig
+		listener = tls.NewListener(listener, s.tlsConfig)
+		s.startRedirectServer()
 	}
 
-	return s.httpServer.ListenAndServe()
+	sdNotify("READY=1")
+	return s.httpServer.Serve(listener)
+}
+
+// setupListen 读取管理界面监听设置并加载证书
+// 证书不可用时回退到 HTTP，避免配置错误后无法访问管理界面
+func (s *Server) setupListen() {
+	s.listen = resolveListenConfig(s.config.Server, s.proxySettings.GetCurrentSettings().Web)
+	// 设置了客户端 CA 时始终要求客户端证书，证书加载失败时只允许本机访问
+	s.access.SetRequireClientCert(s.listen.ClientCAFile != "")
+	if !s.listen.TLS {
+		return
+	}
+	tlsConfig, err := loadTLSConfig(s.config.DataDir, s.listen)
+	if err != nil {
+		fmt.Printf("⚠️ %v，管理界面使用 HTTP\n", err)
+		s.listen.TLS = false
+		s.listen.RedirectPort = 0
+		return
+	}
+	if s.listen.ClientCAFile != "" {
+		if err := loadClientCA(tlsConfig, s.listen.ClientCAFile); err != nil {
+			fmt.Printf("⚠️ %v，只允许本机访问管理界面\n", err)
+		}
+	}
+	s.tlsConfig = tlsConfig
+}
+
+// setAllowedCIDRs 更新来源地址白名单
+func (s *Server) setAllowedCIDRs(cidrs []string) {
+	if err := s.access.SetAllowedCIDRs(cidrs); err != nil {
+		fmt.Printf("⚠️ 来源地址白名单无效，未生效: %v\n", err)
+	}
+}
+
+// startRedirectServer 监听 HTTP 端口并重定向到 HTTPS
+func (s *Server) startRedirectServer() {
+	if s.listen.RedirectPort == 0 {
+		return
+	}
+	s.redirectServer = &http.Server{
+		Addr:              net.JoinHostPort(s.listen.Host, strconv.Itoa(s.listen.RedirectPort)),
+		Handler:           httpsRedirectHandler(s.listen.Port),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("⚠️ HTTP 重定向服务启动失败: %v\n", err)
+		}
+	}()
+}
+
+// ListenURL 本机访问管理界面的地址
+func (s *Server) ListenURL() string {
+	return s.listen.URL()
 }
 
 // Shutdown 关闭服务器
 func (s *Server) Shutdown() {
+	sdNotify("STOPPING=1")
+
 	// 先停止代理核心
 	if s.proxyHandler != nil {
 		fmt.Println("正在停止代理核心...")
-		if err := s.proxyHandler.GetService().Stop(); err != nil {
-			fmt.Printf("停止代理核心失败: %v\n", err)
-		} else {
-			fmt.Println("代理核心已停止")
-		}
+		s.proxyHandler.Shutdown()
+	}
+
+	if s.dnsServer != nil {
+		s.dnsServer.Stop()
 	}
 
 	// 再关闭 HTTP 服务器
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if s.redirectServer != nil {
+		s.redirectServer.Shutdown(ctx)
+	}
 	if s.httpServer != nil {
 		s.httpServer.Shutdown(ctx)
 	}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+)
+
+// sdListenFdsStart systemd 传入的第一个文件描述符编号
+const sdListenFdsStart = 3
+
+// systemdListener 获取 systemd socket 激活传入的监听，未使用 socket 激活时返回 nil
+func systemdListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if n < 1 {
+		return nil, nil
+	}
+
+	// 避免子进程（代理核心）误认为自己也被 socket 激活
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(sdListenFdsStart, "systemd-socket")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// sdNotify 向 systemd 报告服务状态（Type=notify），未由 systemd 启动时忽略
+func sdNotify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return
+	}
+	// 抽象命名空间 socket
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
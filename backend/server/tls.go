@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ProxyStation/backend/config"
+	"ProxyStation/backend/modules/proxy"
+)
+
+const (
+	selfSignedValidity = 10 * 365 * 24 * time.Hour
+	// selfSignedRenewBefore 自签名证书到期前重新生成
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// listenConfig 管理界面实际使用的监听设置
+type listenConfig struct {
+	Host         string
+	Port         int
+	TLS          bool
+	CertFile     string // 为空时使用自签名证书
+	KeyFile      string
+	RedirectPort int
+	ClientCAFile string
+}
+
+// resolveListenConfig 合并 config.yaml 和代理设置中的管理界面设置，代理设置优先
+func resolveListenConfig(server config.ServerConfig, web proxy.WebSettings) listenConfig {
+	cfg := listenConfig{
+		Host:         server.Host,
+		Port:         server.Port,
+		TLS:          web.TLS,
+		CertFile:     web.CertFile,
+		KeyFile:      web.KeyFile,
+		RedirectPort: web.HTTPRedirectPort,
+		ClientCAFile: web.ClientCAFile,
+	}
+	if web.ListenAddress != "" {
+		cfg.Host = web.ListenAddress
+	}
+	if web.Port != 0 {
+		cfg.Port = web.Port
+	}
+	if !cfg.TLS {
+		cfg.RedirectPort = 0
+	}
+	return cfg
+}
+
+// URL 本机访问管理界面的地址
+func (l listenConfig) URL() string {
+	scheme := "http"
+	if l.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, l.Port)
+}
+
+// loadTLSConfig 加载证书，未设置证书时使用数据目录中的自签名证书（不存在或即将过期时生成）
+func loadTLSConfig(dataDir string, listen listenConfig) (*tls.Config, error) {
+	certFile, keyFile := listen.CertFile, listen.KeyFile
+	if certFile == "" {
+		certFile = filepath.Join(dataDir, "tls", "cert.pem")
+		keyFile = filepath.Join(dataDir, "tls", "key.pem")
+		if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+			return nil, fmt.Errorf("生成自签名证书失败: %w", err)
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载证书失败: %w", err)
+	}
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		fmt.Printf("🔒 HTTPS 证书: %s（SHA256 %s，有效期至 %s）\n",
+			certFile, certFingerprint(leaf), leaf.NotAfter.Format("2006-01-02"))
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// loadClientCA 加载客户端证书 CA，由访问控制中间件检查客户端是否提供了有效证书
+// 使用 VerifyClientCertIfGiven 而不是在握手时强制要求，本机访问可以不提供证书
+func loadClientCA(cfg *tls.Config, caFile string) error {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("读取客户端 CA 失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("客户端 CA 中没有有效的 PEM 证书: %s", caFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	fmt.Printf("🔏 已启用客户端证书校验: %s\n", caFile)
+	return nil
+}
+
+// certFingerprint 证书 SHA256 指纹，浏览器提示自签名证书时可据此核对
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ensureSelfSignedCert 检查自签名证书，不存在、无法解析或即将过期时重新生成
+func ensureSelfSignedCert(certFile, keyFile string) error {
+	if data, err := os.ReadFile(certFile); err == nil {
+		if block, _ := pem.Decode(data); block != nil {
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil &&
+				time.Until(cert.NotAfter) > selfSignedRenewBefore {
+				if _, err := os.Stat(keyFile); err == nil {
+					return nil
+				}
+			}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "ProxyStation", Organization: []string{"ProxyStation"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname != "" && hostname != "localhost" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	// 包含本机所有地址，通过局域网 IP 访问时证书名称匹配
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				template.IPAddresses = append(template.IPAddresses, ipNet.IP)
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("🔐 已生成自签名证书: %s\n", certFile)
+	return nil
+}
+
+// httpsRedirectHandler 将 HTTP 请求重定向到 HTTPS 端口
+func httpsRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -17,13 +17,21 @@ var upgrader = websocket.Upgrader{
 }
 
 // Hub WebSocket 连接管理中心 (代理模式)
-type Hub struct{}
+type Hub struct {
+	// 核心 API 地址和 secret，未设置时使用默认地址且不带认证
+	coreAPI func() (addr, secret string)
+}
 
 // NewHub 创建 Hub
 func NewHub() *Hub {
 	return &Hub{}
 }
 
+// SetCoreAPIProvider 设置核心 API 地址和 secret 提供者
+func (h *Hub) SetCoreAPIProvider(provider func() (addr, secret string)) {
+	h.coreAPI = provider
+}
+
 // Run 运行 Hub (保留接口兼容)
 func (h *Hub) Run() {
 	// 代理模式不需要运行循环
@@ -58,16 +66,27 @@ func (h *Hub) proxyMihomoWebSocket(c *gin.Context, path string) {
 
 	// 连接到 Mihomo WebSocket
 	// Mihomo API 默认监听 127.0.0.1:9090
-	mihomoURL := "ws://127.0.0.1:9090" + path
+	addr, secret := "127.0.0.1:9090", ""
+	if h.coreAPI != nil {
+		addr, secret = h.coreAPI()
+	}
+	mihomoURL := "ws://" + addr + path
 
-	// 转发所有查询参数 (token, level 等)
-	queryString := c.Request.URL.RawQuery
-	if queryString != "" {
+	// 转发查询参数 (level 等)，token 是 ProxyStation 的登录令牌，不转发给核心
+	query := c.Request.URL.Query()
+	query.Del("token")
+	if queryString := query.Encode(); queryString != "" {
 		mihomoURL += "?" + queryString
 	}
 
+	// 由后端注入核心 API secret
+	header := http.Header{}
+	if secret != "" {
+		header.Set("Authorization", "Bearer "+secret)
+	}
+
 	log.Printf("[WebSocket] 连接 Mihomo: %s", mihomoURL)
-	mihomoConn, _, err := websocket.DefaultDialer.Dial(mihomoURL, nil)
+	mihomoConn, _, err := websocket.DefaultDialer.Dial(mihomoURL, header)
 	if err != nil {
 		log.Printf("[WebSocket] 连接 Mihomo 失败: %v", err)
 		clientConn.WriteMessage(websocket.TextMessage, []byte(`{"error":"无法连接到 Mihomo: `+err.Error()+`"}`))
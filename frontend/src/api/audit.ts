@@ -0,0 +1,39 @@
+import api from './client'
+
+export interface AuditEntry {
+  id: number
+  time: string
+  method: string
+  path: string
+  route?: string
+  status: number
+  sourceIp: string
+  // X-Forwarded-For header as sent by the client; not verified
+  forwardedFor?: string
+  scope?: string
+  summary?: string
+  latency: number
+}
+
+export interface AuditQuery {
+  page?: number
+  pageSize?: number
+  method?: string
+  path?: string
+  ip?: string
+  status?: 'success' | 'error'
+  since?: string
+  until?: string
+}
+
+export interface AuditPage {
+  items: AuditEntry[]
+  total: number
+  page: number
+  pageSize: number
+}
+
+export const auditApi = {
+  list: (query: AuditQuery = {}) => api.get<AuditPage>('/audit', { params: query }),
+  clear: () => api.delete('/audit'),
+}
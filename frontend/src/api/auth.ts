@@ -3,11 +3,24 @@ import api from './client'
 export interface AuthStatus {
   enabled: boolean
   authenticated: boolean
+  scope?: ApiTokenScope
+}
+
+export type ApiTokenScope = 'read' | 'admin'
+
+export interface ApiToken {
+  id: string
+  name: string
+  scope: ApiTokenScope
+  createdAt: string
+  lastUsedAt?: string
 }
 
 export interface AuthConfig {
   enabled: boolean
   username: string
+  sessionTTL: number
+  apiTokens: number
 }
 
 export interface LoginRequest {
@@ -41,6 +54,19 @@ export const authApi = {
   // Change password
   changePassword: (oldPassword: string, newPassword: string) => 
     api.put('/auth/password', { oldPassword, newPassword }),
+
+  // Set session lifetime (hours)
+  setSessionTTL: (sessionTTL: number) => api.put('/auth/session', { sessionTTL }),
+
+  // List API tokens
+  listApiTokens: () => api.get<ApiToken[]>('/auth/tokens'),
+
+  // Create API token (plaintext is only returned once)
+  createApiToken: (name: string, scope: ApiTokenScope) =>
+    api.post<{ token: string; info: ApiToken; message: string }>('/auth/tokens', { name, scope }),
+
+  // Delete API token
+  deleteApiToken: (id: string) => api.delete(`/auth/tokens/${id}`),
 }
 
 // Clear auth token
@@ -0,0 +1,38 @@
+import api from './client'
+
+export interface BackupManifest {
+  app: string
+  version: string
+  createdAt: string
+  hostname?: string
+  history: boolean
+  files: string[]
+}
+
+export interface RestoreResult {
+  manifest: BackupManifest
+  restored: number
+  skipped?: string[]
+  preRestore: string
+  restartRequired: boolean
+}
+
+export const backupApi = {
+  // Download backup as a Blob; the password (if any) is sent in a header, never in the URL
+  download: (options: { history?: boolean; password?: string } = {}) =>
+    api.get<Blob>('/backup', {
+      params: options.history ? { history: true } : undefined,
+      headers: options.password ? { 'X-Backup-Password': options.password } : undefined,
+      responseType: 'blob',
+      timeout: 300000,
+    }),
+  restore: (file: File, password?: string) => {
+    const form = new FormData()
+    form.append('file', file)
+    if (password) form.append('password', password)
+    return api.post<RestoreResult>('/restore', form, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      timeout: 300000,
+    })
+  },
+}
@@ -0,0 +1,37 @@
+import api from './client'
+
+// Ad/tracker blocklists (hosts, adblock or plain domain format) merged into a REJECT rule set
+export interface BlocklistList {
+  id: string
+  name: string
+  url: string
+  enabled: boolean
+  domainCount: number
+  lastUpdate?: string
+  lastError?: string
+}
+
+export interface BlocklistConfig {
+  enabled: boolean
+  lists: BlocklistList[]
+  whitelist: string[] // never blocked, including subdomains
+  autoUpdate: boolean
+  updateInterval: number // hours
+  lastCheck: string
+}
+
+export interface BlocklistStatus {
+  updating: boolean
+  lastCheck: string
+  domainCount: number
+  builtAt?: string
+}
+
+export const blocklistApi = {
+  getConfig: () => api.get<BlocklistConfig>('/blocklist/config'),
+  setConfig: (config: BlocklistConfig) => api.put<BlocklistConfig>('/blocklist/config', config),
+  setListEnabled: (id: string, enabled: boolean) =>
+    api.put<BlocklistConfig>(`/blocklist/lists/${encodeURIComponent(id)}/enabled`, { enabled }),
+  getStatus: () => api.get<BlocklistStatus>('/blocklist/status'),
+  update: () => api.post('/blocklist/update'),
+}
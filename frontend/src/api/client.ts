@@ -8,6 +8,56 @@ const client = axios.create({
   },
 })
 
+// Stable error codes returned in the error object of failed responses (see GET /system/errors)
+export type ErrorCategory =
+  | 'invalid_request'
+  | 'auth'
+  | 'not_found'
+  | 'conflict'
+  | 'environment'
+  | 'upstream'
+  | 'internal'
+
+export interface ErrorDetail {
+  code: string
+  category: ErrorCategory
+  hint?: string
+  details?: unknown
+}
+
+// Error thrown by the API client; check `code` (e.g. 'core_not_installed', 'port_in_use') instead of the message
+export class ApiError extends Error {
+  code?: string
+  category?: ErrorCategory
+  hint?: string
+  details?: unknown
+  status?: number
+
+  constructor(message: string, detail?: ErrorDetail, status?: number) {
+    super(message)
+    this.name = 'ApiError'
+    this.code = detail?.code
+    this.category = detail?.category
+    this.hint = detail?.hint
+    this.details = detail?.details
+    this.status = status
+  }
+}
+
+// Older servers (and the auth middleware before error codes existed) return error as a plain string
+const errorDetail = (data: Record<string, unknown> | undefined): ErrorDetail | undefined =>
+  data && typeof data.error === 'object' && data.error !== null ? (data.error as ErrorDetail) : undefined
+
+// Resources protected by ETag/If-Match; all /proxy/template/* endpoints share the template version
+const versionedResource = (url = ''): string | undefined => {
+  if (url === '/proxy/config') return 'config'
+  if (url === '/proxy/template' || url.startsWith('/proxy/template/')) return 'template'
+  return undefined
+}
+
+// Last version seen by this tab, sent back as If-Match so edits from another tab are not overwritten
+const resourceVersions = new Map<string, string>()
+
 // Request interceptor - add token
 client.interceptors.request.use(
   (config: InternalAxiosRequestConfig) => {
@@ -15,6 +65,11 @@ client.interceptors.request.use(
     if (token && config.headers) {
       config.headers.Authorization = `Bearer ${token}`
     }
+    const resource = versionedResource(config.url)
+    const version = resource && resourceVersions.get(resource)
+    if (version && config.method === 'put' && config.headers) {
+      config.headers['If-Match'] = version
+    }
     return config
   },
   (error) => Promise.reject(error)
@@ -23,10 +78,15 @@ client.interceptors.request.use(
 // Response interceptor
 client.interceptors.response.use(
   (response: AxiosResponse) => {
+    const resource = versionedResource(response.config.url)
+    const etag = response.headers?.etag
+    if (resource && etag) {
+      resourceVersions.set(resource, etag)
+    }
     const data = response.data
     // Check business status code
     if (data && data.code !== undefined && data.code !== 0) {
-      const error = new Error(data.message || '请求失败')
+      const error = new ApiError(data.message || '请求失败', errorDetail(data), response.status)
       return Promise.reject(error)
     }
     // Return data.data or data
@@ -40,20 +100,24 @@ client.interceptors.response.use(
       if (!window.location.pathname.includes('/login')) {
         window.location.href = '/login'
       }
-      return Promise.reject(new Error('未授权，请先登录'))
+      return Promise.reject(new ApiError('未授权，请先登录', { code: 'unauthorized', category: 'auth' }, 401))
     }
 
     // Network or server error
     let message = '网络请求失败'
+    let detail: ErrorDetail | undefined
     if (error.response) {
       const data = error.response.data as Record<string, unknown>
-      message = (data?.error || data?.message || `服务器错误 (${error.response.status})`) as string
+      detail = errorDetail(data)
+      const legacy = typeof data?.error === 'string' ? data.error : undefined
+      message = (data?.message || legacy || `服务器错误 (${error.response.status})`) as string
     } else if (error.code === 'ECONNABORTED') {
       message = '请求超时'
+      detail = { code: 'timeout', category: 'upstream' }
     } else if (!navigator.onLine) {
       message = '网络连接已断开'
     }
-    return Promise.reject(new Error(message))
+    return Promise.reject(new ApiError(message, detail, error.response?.status))
   }
 )
 
@@ -11,6 +11,8 @@ export interface CoreInfo {
   latestVersion: string
   installed: boolean
   path: string
+  hasUpdate: boolean
+  sha256?: string
 }
 
 export interface CoreStatus {
@@ -22,6 +24,9 @@ export interface DownloadProgress {
   downloading: boolean
   progress: number
   speed: number
+  verified: boolean
+  restarted: boolean
+  stage?: 'downloading' | 'verifying' | 'installing' | 'restarting' | 'done'
   error?: string
 }
 
@@ -43,9 +48,9 @@ export const coreApi = {
     await client.post('/core/switch', { coreType })
   },
 
-  // Download core
-  downloadCore: async (coreType: string): Promise<void> => {
-    await client.post(`/core/download/${coreType}`)
+  // Download core (restart: 更新完成后自动重启正在使用该核心的代理服务)
+  downloadCore: async (coreType: string, restart = false): Promise<void> => {
+    await client.post(`/core/download/${coreType}`, null, { params: restart ? { restart: true } : undefined })
   },
 
   // Get download progress
@@ -0,0 +1,33 @@
+import api from './client'
+
+export type DiagnosticStatus = 'pass' | 'warn' | 'fail' | 'skip'
+
+export interface DiagnosticCheck {
+  id: string
+  name: string
+  status: DiagnosticStatus
+  message: string
+  detail?: Record<string, unknown>
+  latency: number
+}
+
+export interface DiagnosticReport {
+  time: string
+  status: DiagnosticStatus
+  coreType: string
+  coreRunning: boolean
+  transparentMode: string
+  proxyScope: string
+  checks: DiagnosticCheck[]
+}
+
+export interface DiagnosticOptions {
+  domain?: string
+  echoUrl?: string
+  udpDns?: string
+}
+
+export const diagnosticsApi = {
+  run: (options: DiagnosticOptions = {}) =>
+    api.post<DiagnosticReport>('/diagnostics/run', options, { timeout: 60000 }),
+}
@@ -0,0 +1,32 @@
+import api from './client'
+
+// Local DNS forwarder run by ProxyStation itself (independent of the core)
+export interface DNSDomainRule {
+  domain: string // matches the domain and its subdomains, longest suffix wins
+  upstreams: string[]
+}
+
+export interface DNSServerConfig {
+  enabled: boolean
+  listen: string // UDP + TCP
+  upstreams: string[] // 1.1.1.1, udp://, tcp://, tls://, https://
+  rules: DNSDomainRule[]
+  blocklist: string[]
+  blockResponse: 'nxdomain' | 'zero'
+  cacheSize: number // 0 disables the cache
+}
+
+export interface DNSServerStatus {
+  running: boolean
+  listen?: string
+  error?: string
+  cacheEntries: number
+  stats: { queries: number; cacheHits: number; blocked: number; failed: number }
+}
+
+export const dnsServerApi = {
+  getConfig: () => api.get<DNSServerConfig>('/dns-server/config'),
+  setConfig: (config: DNSServerConfig) => api.put<DNSServerConfig>('/dns-server/config', config),
+  getStatus: () => api.get<DNSServerStatus>('/dns-server/status'),
+  flushCache: () => api.post('/dns-server/cache/flush'),
+}
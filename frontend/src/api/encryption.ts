@@ -0,0 +1,23 @@
+import api from './client'
+
+export type EncryptionMode = 'password' | 'machine'
+
+export interface EncryptionStatus {
+  enabled: boolean
+  mode?: EncryptionMode
+  unlocked: boolean
+  files: number
+  error?: string
+  // Data that stays in plaintext while encryption is enabled (generated core configs)
+  warning?: string
+}
+
+export const encryptionApi = {
+  getStatus: () => api.get<EncryptionStatus>('/system/encryption'),
+  // Password mode requires PROXYSTATION_MASTER_PASSWORD to be set before the next restart.
+  // acceptPlaintext confirms that generated core configs stay unencrypted.
+  enable: (mode: EncryptionMode, password?: string, acceptPlaintext = false) =>
+    api.post<EncryptionStatus>('/system/encryption/enable', { mode, password, acceptPlaintext }),
+  disable: (password?: string) =>
+    api.post<EncryptionStatus>('/system/encryption/disable', { password }),
+}
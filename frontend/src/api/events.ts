@@ -0,0 +1,55 @@
+export type ServerEventType =
+  | 'core.started'
+  | 'core.stopped'
+  | 'core.crashed'
+  | 'core.autostart'
+  | 'config.generated'
+  | 'transparent.changed'
+  | 'node.health.changed'
+  | 'node.all_dead'
+  | 'subscription.failed'
+
+// Data of core.autostart events, one per stage of the boot-time auto start
+export interface AutoStartProgress {
+  step: 'network' | 'config' | 'core' | 'transparent' | 'done'
+  status: 'running' | 'done' | 'skipped' | 'failed'
+  message?: string
+  elapsed: number // ms since auto start began
+}
+
+export interface ServerEvent<T = unknown> {
+  id: number
+  type: ServerEventType
+  time: string
+  data?: T
+}
+
+const eventTypes: ServerEventType[] = [
+  'core.started',
+  'core.stopped',
+  'core.crashed',
+  'core.autostart',
+  'config.generated',
+  'transparent.changed',
+  'node.health.changed',
+  'node.all_dead',
+  'subscription.failed',
+]
+
+export const eventsApi = {
+  // Status change events (SSE); the browser reconnects automatically and replays missed events
+  subscribe(onEvent: (event: ServerEvent) => void, types?: string[]): EventSource {
+    const query = types?.length ? `?types=${encodeURIComponent(types.join(','))}` : ''
+    const source = new EventSource(`/api/events${query}`, { withCredentials: true })
+    for (const type of eventTypes) {
+      source.addEventListener(type, (e) => {
+        try {
+          onEvent(JSON.parse((e as MessageEvent).data))
+        } catch {
+          // ignore parse errors
+        }
+      })
+    }
+    return source
+  },
+}
@@ -0,0 +1,67 @@
+import api from './client'
+
+export interface FleetImportResult {
+  template: boolean
+  nftTemplate: boolean
+  restarted: boolean
+}
+
+export interface FleetPeerStatus {
+  online: boolean
+  version?: string
+  lastCheck?: number
+  lastPush?: number
+  pushOk: boolean
+  pushResult?: FleetImportResult
+  lastError?: string
+}
+
+// Remote ProxyStation instance. The token needs admin scope on the remote side and is
+// returned masked; send it back unchanged (or empty) to keep the stored one.
+export interface FleetPeer {
+  id: string
+  name: string
+  url: string // e.g. http://192.168.1.2:8080
+  token: string
+  enabled: boolean // included when pushing without an explicit peer list
+  insecure: boolean // skip HTTPS certificate verification
+  status: FleetPeerStatus
+}
+
+export type FleetPeerInput = Omit<FleetPeer, 'id' | 'status'>
+
+// Only device-independent config is pushed: the config template (groups, rules,
+// providers, DNS) and the nftables rules template. Nodes, ports and interfaces stay local.
+export interface FleetPushRequest {
+  peers?: string[] // empty = all enabled peers
+  template: boolean
+  nftTemplate: boolean
+  apply: boolean // restart the remote core if it is running
+}
+
+export interface FleetPushResult {
+  id: string
+  name: string
+  ok: boolean
+  result?: FleetImportResult
+  error?: string
+}
+
+export interface FleetBundle {
+  source?: string
+  createdAt: number
+  template?: unknown
+  nftTemplate?: string // '' = default template
+  apply: boolean
+}
+
+export const fleetApi = {
+  list: () => api.get<FleetPeer[]>('/fleet/peers'),
+  add: (data: Partial<FleetPeerInput>) => api.post<FleetPeer>('/fleet/peers', data),
+  update: (id: string, data: FleetPeerInput) => api.put<FleetPeer>(`/fleet/peers/${id}`, data),
+  delete: (id: string) => api.delete(`/fleet/peers/${id}`),
+  check: (id: string) => api.post<FleetPeer>(`/fleet/peers/${id}/check`),
+  bundle: (template = true, nftTemplate = true) =>
+    api.get<FleetBundle>('/fleet/bundle', { params: { template, nftTemplate } }),
+  push: (data: FleetPushRequest) => api.post<FleetPushResult[]>('/fleet/push', data, { timeout: 120000 }),
+}
@@ -0,0 +1,60 @@
+import api from './client'
+
+export interface GeoDataConfig {
+  mirrors: string[]
+  files: string[]
+  verifyChecksum: boolean
+  autoUpdate: boolean
+  updateInterval: number
+  lastCheck: string
+}
+
+export interface GeoInstalledFile {
+  name: string
+  sha256: string
+  size: number
+  version: string
+  mirror: string
+  verified: boolean
+  installedAt: string
+}
+
+export interface GeoFileStatus {
+  name: string
+  description: string
+  path: string
+  exists: boolean
+  installed?: GeoInstalledFile
+}
+
+export interface GeoUpdateResult {
+  name: string
+  updated: boolean
+  version?: string
+  mirror?: string
+  error?: string
+}
+
+export interface GeoUpdateStatus {
+  updating: boolean
+  lastCheck: string
+  lastResults: GeoUpdateResult[]
+}
+
+// IP 归属信息
+export interface IPGeoInfo {
+  ip: string
+  country?: string // ISO 3166 国家代码（大写）
+  asn?: number
+  asOrg?: string
+}
+
+export const geodataApi = {
+  getFiles: () => api.get<GeoFileStatus[]>('/geodata'),
+  getConfig: () => api.get<GeoDataConfig>('/geodata/config'),
+  setConfig: (config: Partial<GeoDataConfig>) => api.put<GeoDataConfig>('/geodata/config', config),
+  getStatus: () => api.get<GeoUpdateStatus>('/geodata/status'),
+  // force: 忽略校验值比对，强制重新下载
+  update: (force = false) => api.post('/geodata/update', null, { params: force ? { force: true } : undefined }),
+  lookup: (ips: string[]) => api.get<IPGeoInfo[]>('/geodata/lookup', { params: { ip: ips.join(',') } }),
+}
@@ -1,4 +1,5 @@
-export { default as client, api } from './client'
+export { default as client, api, ApiError } from './client'
+export type { ErrorCategory, ErrorDetail } from './client'
 export * from './proxy'
 export * from './subscription'
 export * from './node'
@@ -6,3 +7,15 @@ export * from './core'
 export * from './system'
 export * from './auth'
 export * from './mihomo'
+export * from './geodata'
+export * from './audit'
+export * from './events'
+export * from './notify'
+export * from './backup'
+export * from './diagnostics'
+export * from './lan'
+export * from './schedule'
+export * from './dnsServer'
+export * from './blocklist'
+export * from './fleet'
+export * from './encryption'
@@ -0,0 +1,35 @@
+import api from './client'
+
+export interface LanDevice {
+  mac: string
+  ip?: string
+  ipv6?: string[]
+  hostname?: string
+  vendor?: string
+  randomized: boolean
+  interface?: string
+  state?: string
+  online: boolean
+  sources: ('neighbor' | 'dhcp')[]
+  leaseExpire?: string
+}
+
+export interface NetworkInterface {
+  name: string
+  index: number
+  mac?: string
+  mtu: number
+  up: boolean
+  kind?: 'loopback' | 'physical' | 'bridge' | 'docker' | 'tunnel' | 'virtual' | 'wireless' | 'vlan'
+  addresses: string[]
+  defaultRoute: boolean
+  suggested?: 'lan' | 'wan'
+}
+
+export const lanApi = {
+  // leases=false skips DHCP lease files; resolve=true reverse-resolves devices without a hostname
+  getDevices: (params: { leases?: boolean; resolve?: boolean } = {}) =>
+    api.get<LanDevice[]>('/lan/devices', { params }),
+  getInterfaces: () => api.get<NetworkInterface[]>('/network/interfaces'),
+  updateOUI: () => api.post<{ prefixes: number }>('/lan/oui/update', undefined, { timeout: 180000 }),
+}
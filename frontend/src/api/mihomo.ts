@@ -3,12 +3,6 @@
 // Use backend proxy (avoid CORS issues)
 const getProxyApiBase = () => '/api/proxy/mihomo'
 
-// Direct access to Mihomo API (only for WebSocket)
-const getDirectApiBase = () => {
-  const host = window.location.hostname || '127.0.0.1'
-  return `http://${host}:9090`
-}
-
 export interface ProxyNode {
   name: string
   type: string
@@ -24,6 +18,13 @@ export interface ProxyGroup {
   all?: string[]
 }
 
+export interface BatchDelayResult {
+  total: number
+  success: number
+  failed: number
+  results: { name: string; delay: number; error?: string }[]
+}
+
 export interface MihomoConfig {
   mode: string
   'mixed-port': number
@@ -43,22 +44,22 @@ export const mihomoApi = {
     }
   },
 
-  // Get version (via backend)
+  // Get version (via backend proxy)
   async getVersion(): Promise<string> {
-    const res = await fetch(`${getDirectApiBase()}/version`)
+    const res = await fetch(`${getProxyApiBase()}/version`)
     const data = await res.json()
     return data.version
   },
 
   // Get configs
   async getConfigs(): Promise<MihomoConfig> {
-    const res = await fetch(`${getDirectApiBase()}/configs`)
+    const res = await fetch(`${getProxyApiBase()}/configs`)
     return res.json()
   },
 
   // Update configs
   async patchConfigs(config: Partial<MihomoConfig>): Promise<void> {
-    await fetch(`${getDirectApiBase()}/configs`, {
+    await fetch(`${getProxyApiBase()}/configs`, {
       method: 'PATCH',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(config),
@@ -106,21 +107,41 @@ export const mihomoApi = {
     }
   },
 
-  // Get connections (direct access)
+  // Batch delay test with concurrency limit (via backend)
+  async testDelayBatch(options: {
+    names?: string[]
+    group?: string
+    url?: string
+    timeout?: number
+    concurrency?: number
+  } = {}): Promise<BatchDelayResult> {
+    const res = await fetch(`${getProxyApiBase()}/proxies/delay/batch`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    })
+    const data = await res.json()
+    if (!res.ok || data.code !== 0) {
+      throw new Error(data.message || '批量测速失败')
+    }
+    return data.data
+  },
+
+  // Get connections (via backend proxy)
   async getConnections(): Promise<{ downloadTotal: number; uploadTotal: number; connections: unknown[] }> {
-    const res = await fetch(`${getDirectApiBase()}/connections`)
+    const res = await fetch(`${getProxyApiBase()}/connections`)
     return res.json()
   },
 
   // Close all connections
   async closeAllConnections(): Promise<void> {
-    await fetch(`${getDirectApiBase()}/connections`, { method: 'DELETE' })
+    await fetch(`${getProxyApiBase()}/connections`, { method: 'DELETE' })
   },
 
   // 快速控制 API
   // 重载配置（重载核心）
   async reloadConfig(): Promise<void> {
-    const res = await fetch(`${getDirectApiBase()}/configs?force=true`, {
+    const res = await fetch(`${getProxyApiBase()}/configs?force=true`, {
       method: 'PUT',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({}),
@@ -132,7 +153,7 @@ export const mihomoApi = {
 
   // 刷新 DNS 缓存
   async flushDns(): Promise<void> {
-    const res = await fetch(`${getDirectApiBase()}/cache/flushdns`, {
+    const res = await fetch(`${getProxyApiBase()}/cache/flushdns`, {
       method: 'POST',
     })
     if (!res.ok) {
@@ -142,7 +163,7 @@ export const mihomoApi = {
 
   // 更新 GeoIP/GeoSite 数据库
   async updateGeo(): Promise<void> {
-    const res = await fetch(`${getDirectApiBase()}/upgrade/geo`, {
+    const res = await fetch(`${getProxyApiBase()}/upgrade/geo`, {
       method: 'POST',
     })
     if (!res.ok) {
@@ -152,7 +173,7 @@ export const mihomoApi = {
 
   // Close single connection
   async closeConnection(id: string): Promise<void> {
-    await fetch(`${getDirectApiBase()}/connections/${id}`, { method: 'DELETE' })
+    await fetch(`${getProxyApiBase()}/connections/${id}`, { method: 'DELETE' })
   },
 
   // Connections real-time update WebSocket (via backend WebSocket proxy)
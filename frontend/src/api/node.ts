@@ -1,4 +1,5 @@
 import api from './client'
+import type { IPGeoInfo } from './geodata'
 
 export interface Node {
   id: string
@@ -13,6 +14,25 @@ export interface Node {
   lastTest: number   // Last test timestamp
   config: string
   shareUrl?: string
+  geo?: IPGeoInfo // 服务器归属，首次查询在后台进行
+}
+
+// 创建或编辑手动节点
+export interface ManualNodeInput {
+  name: string
+  type: string
+  server: string
+  serverPort?: number
+  port?: number // legacy field name of serverPort
+  config?: string | Record<string, unknown>
+  enabled?: boolean
+}
+
+// 按国家统计的节点
+export interface NodeCountrySummary {
+  country: string // 未知时为空
+  count: number
+  nodes: string[]
 }
 
 // 协议字段定义
@@ -54,18 +74,67 @@ export const PROTOCOL_LIST = [
   { value: 'ssh', label: 'SSH' },
 ]
 
+// 节点健康检查配置
+export interface HealthConfig {
+  enabled: boolean
+  interval: number      // seconds
+  timeout: number       // ms
+  failThreshold: number
+  excludeDead: boolean
+}
+
+export interface NodeHealth {
+  nodeId: string
+  name: string
+  healthy: boolean
+  delay: number
+  consecutiveFailures: number
+  lastCheck: number
+  lastSuccess?: number
+}
+
+// 节点下载测速结果
+export interface SpeedResult {
+  nodeId: string
+  name: string
+  downloadSpeed: number // Mbps
+  bytes: number
+  duration: number      // seconds
+  ttfb: number          // ms
+  url?: string
+  error?: string
+  testedAt: number
+}
+
 export const nodeApi = {
   // Get all nodes
-  list: () => api.get<Node[]>('/nodes'),
+  list: (country?: string) => api.get<Node[]>('/nodes', { params: country ? { country } : undefined }),
+  getCountries: () => api.get<NodeCountrySummary[]>('/nodes/countries'),
 
   // Import node from URL
   importUrl: (url: string) => 
     api.post<Node>('/nodes/import', { url }),
 
+  // Manual nodes; credentials in config are masked as "******" and kept unchanged when sent back
+  listManual: () => api.get<Node[]>('/nodes/manual'),
+
   // Add manual node (simple)
-  addManual: (data: { name: string; type: string; server: string; port: number; config?: string }) =>
+  addManual: (data: ManualNodeInput) =>
     api.post<Node>('/nodes/manual', data),
 
+  // Explicitly fetch a manual node with unmasked credentials and share URL
+  revealManual: (id: string) =>
+    api.get<Node>(`/nodes/manual/${id}/reveal`),
+
+  updateManual: (id: string, data: ManualNodeInput) =>
+    api.put<Node>(`/nodes/manual/${id}`, data),
+
+  setManualEnabled: (id: string, enabled: boolean) =>
+    api.put<Node>(`/nodes/manual/${id}/enabled`, { enabled }),
+
+  deleteManual: (id: string) =>
+    api.delete(`/nodes/manual/${id}`),
+
   // Add manual node (advanced with full config)
   addManualAdvanced: (data: ManualNodeRequest) =>
     api.post<Node>('/nodes/manual/advanced', data),
@@ -86,6 +155,26 @@ export const nodeApi = {
   getShareUrl: (id: string) =>
     api.get<{ url: string }>(`/nodes/${id}/share`),
 
+  // Node health
+  getHealth: () =>
+    api.get<{ config: HealthConfig; results: NodeHealth[] }>('/nodes/health'),
+
+  setHealthConfig: (config: HealthConfig) =>
+    api.put<HealthConfig>('/nodes/health/config', config),
+
+  checkHealth: () =>
+    api.post<NodeHealth[]>('/nodes/health/check'),
+
+  // Download speed test
+  speedTest: (id: string, url?: string, duration?: number) =>
+    api.post<SpeedResult>(`/nodes/${id}/speedtest`, { url, duration }),
+
+  speedTestBatch: (nodeIds: string[], url?: string, duration?: number) =>
+    api.post<SpeedResult[]>('/nodes/speedtest/batch', { nodeIds, url, duration }),
+
+  getSpeedResults: () =>
+    api.get<Record<string, SpeedResult>>('/nodes/speedtest'),
+
   // Get protocol field definitions
   getProtocolFields: (protocol: string) =>
     api.get<{ protocol: string; fields: ProtocolField[] }>(`/nodes/protocols/${protocol}/fields`),
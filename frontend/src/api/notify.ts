@@ -0,0 +1,47 @@
+import api from './client'
+
+export type WebhookType = 'generic' | 'telegram' | 'bark' | 'ntfy'
+
+export interface Webhook {
+  id: string
+  name: string
+  type: WebhookType
+  enabled: boolean
+  url: string
+  token?: string
+  chatId?: string
+  headers?: Record<string, string>
+  events: Record<string, boolean>
+  retries: number
+  lastStatus?: 'success' | 'failed'
+  lastError?: string
+  lastSent?: number
+}
+
+export type WebhookInput = Omit<Webhook, 'id' | 'lastStatus' | 'lastError' | 'lastSent'>
+
+export interface NotifyEventInfo {
+  type: string
+  description: string
+  default: boolean
+}
+
+export interface NotifyDelivery {
+  time: string
+  webhookId: string
+  name: string
+  event: string
+  success: boolean
+  attempts: number
+  error?: string
+}
+
+export const notifyApi = {
+  listEvents: () => api.get<NotifyEventInfo[]>('/notify/events'),
+  listDeliveries: () => api.get<NotifyDelivery[]>('/notify/deliveries'),
+  listWebhooks: () => api.get<Webhook[]>('/notify/webhooks'),
+  addWebhook: (data: Partial<WebhookInput>) => api.post<Webhook>('/notify/webhooks', data),
+  updateWebhook: (id: string, data: WebhookInput) => api.put<Webhook>(`/notify/webhooks/${id}`, data),
+  deleteWebhook: (id: string) => api.delete(`/notify/webhooks/${id}`),
+  testWebhook: (id: string) => api.post(`/notify/webhooks/${id}/test`),
+}
@@ -1,6 +1,7 @@
 import api from './client'
+import type { IPGeoInfo } from './geodata'
 
-export type TransparentMode = 'off' | 'tproxy' | 'redirect'
+export type TransparentMode = 'off' | 'system' | 'tproxy' | 'redirect' | 'tun' // system=macOS/Windows 系统代理, tun=核心 TUN 栈（仅 Linux）
 export type ProxyScope = 'local' | 'router' // local=仅本机, router=本机+局域网
 
 export interface ProxyStatus {
@@ -15,6 +16,65 @@ export interface ProxyStatus {
   transparentMode: TransparentMode
   proxyScope: ProxyScope
   uptime: number
+  supervisor?: SupervisorStatus
+  adopted?: boolean
+  adoptedPid?: number
+  pid?: number
+  resources?: ProcessStats
+  config?: ActiveConfigStatus
+  transparent?: TransparentApplyState
+  lastError?: StatusError
+}
+
+export interface ActiveConfigStatus {
+  path: string
+  exists: boolean
+  hash?: string // SHA-256 of the config file
+  modifiedAt?: string
+  drift: boolean // config on disk differs from what the current template and nodes would generate
+  driftError?: string
+}
+
+export interface TransparentApplyState {
+  mode: TransparentMode
+  scope: ProxyScope
+  applied: boolean
+  error?: string // last rule apply failure
+}
+
+export interface StatusError {
+  source: 'start' | 'crash' | 'config' | 'transparent'
+  message: string
+  time: string
+}
+
+export interface ProcessStats {
+  cpuPercent: number
+  rss: number
+  fds: number
+  threads: number
+  uptime: number
+}
+
+export interface CrashEvent {
+  time: string
+  coreType: string
+  exitCode: number
+  error?: string
+  uptime: number
+  attempt: number
+  restart: boolean
+  delay: number
+  lastLogs?: string[]
+}
+
+export interface SupervisorStatus {
+  enabled: boolean
+  restartCount: number
+  maxRestarts: number
+  nextRestart?: string
+  gaveUp: boolean
+  crashes: CrashEvent[]
 }
 
 export interface ProxyConfig {
@@ -27,12 +87,486 @@ export interface ProxyConfig {
   mode: string
   logLevel: string
   externalController: string
+  secret?: string
   tunEnabled: boolean
   tunStack: string
   transparentMode: TransparentMode
   proxyScope: ProxyScope
   autoStart: boolean
   autoStartDelay: number
+  dnsHijack: boolean
+  transparentIpv6: boolean
+  blockQuic?: boolean // reject QUIC (UDP 443) so browsers fall back to TCP
+  transparentWatchdog?: TransparentWatchdog
+  speedtestPort?: number
+  crashRestart?: boolean
+  maxRestarts?: number
+  runAsUser?: string
+}
+
+export interface ConnectionMetadata {
+  network: string
+  type: string
+  sourceIP: string
+  destinationIP: string
+  sourcePort: string
+  destinationPort: string
+  host: string
+  dnsMode: string
+  process: string
+  processPath: string
+}
+
+export interface Connection {
+  id: string
+  metadata: ConnectionMetadata
+  upload: number
+  download: number
+  start: string
+  chains: string[]
+  rule: string
+  rulePayload: string
+  pid?: number
+  geo?: IPGeoInfo // 目标地址归属
+}
+
+export interface ConnectionsSnapshot {
+  downloadTotal: number
+  uploadTotal: number
+  connections: Connection[]
+}
+
+export interface TrafficCounter {
+  upload: number
+  download: number
+}
+
+export interface TrafficRanking extends TrafficCounter {
+  name: string
+}
+
+export interface RuleHitCounter {
+  hits: number // new connections seen matching the rule
+  upload: number
+  download: number
+  lastHit?: number // unix seconds
+}
+
+export interface RuleHitStats extends RuleHitCounter {
+  index: number
+  id?: string
+  type: string
+  payload: string
+  proxy: string
+  disabled?: boolean
+}
+
+export interface RuleStatsReport {
+  range: string
+  since?: number
+  total: RuleHitCounter
+  rules: RuleHitStats[]
+  neverHit: number // enabled rules with no hits in range
+  groups: TrafficRanking[]
+  unmatched: (RuleHitCounter & { rule: string; payload: string })[] // hits from rules not in the template
+}
+
+export interface TrafficHistory {
+  range: string
+  interval: number
+  total: TrafficCounter
+  points: (TrafficCounter & { time: number })[]
+  groups: TrafficRanking[]
+  domains: TrafficRanking[]
+}
+
+export interface LatencySample {
+  time: number
+  delay: number
+}
+
+export interface LatencyMemberStats {
+  name: string
+  current: number
+  samples: number
+  failed: number
+  min: number
+  max: number
+  avg: number
+  jitter: number
+  points?: LatencySample[]
+}
+
+export interface LatencyGroupStats {
+  name: string
+  type: string
+  now: string
+  members: LatencyMemberStats[]
+  switches: { time: number; from: string; to: string }[]
+}
+
+export interface ProviderInfo {
+  name: string
+  kind: 'proxy' | 'rule'
+  vehicleType: string
+  behavior?: string
+  format?: string
+  count: number
+  alive?: number
+  updatedAt?: string
+  stale: boolean
+  lastRefresh?: number
+  lastAttempt?: number
+  lastError?: string
+  subscriptionInfo?: { Upload: number; Download: number; Total: number; Expire: number }
+}
+
+export interface ProviderRefreshSchedule {
+  enabled: boolean
+  interval: number // minutes
+  staleAfter: number // hours
+  onlyStale: boolean
+}
+
+export interface ConfigValidationResult {
+  valid: boolean
+  checker: 'mihomo' | 'yaml'
+  errors: { line?: number; message: string }[]
+  output?: string
+}
+
+export interface ConfigHistoryEntry {
+  id: string
+  time: string
+  reason: string
+  coreType: 'mihomo' | 'singbox'
+  file: string
+  size: number
+}
+
+export interface ConfigDryRun {
+  coreType: 'mihomo' | 'singbox'
+  changed: boolean
+  diff: string
+  summary: {
+    nodesAdded: string[]
+    nodesRemoved: string[]
+    groupsAdded: string[]
+    groupsRemoved: string[]
+    groupsChanged: string[]
+  }
+  content: string
+}
+
+export interface ConfigProfile {
+  id: string
+  name: string
+  description?: string
+  createdAt: string
+  updatedAt: string
+  active: boolean
+}
+
+export interface ConfigOverride {
+  enabled: boolean
+  // YAML 片段，支持 prepend-rules / append-rules 等列表追加键，值为 null 时删除字段
+  content: string
+  updatedAt?: string
+}
+
+export interface TemplateRule {
+  id?: string
+  type: string
+  payload: string
+  proxy: string
+  noResolve: boolean
+  description: string
+  disabled?: boolean
+}
+
+export interface TemplateImportResult {
+  proxyGroups: number
+  rules: number
+  ruleProviders: number
+  dns: boolean
+  warnings: string[]
+  template: unknown
+}
+
+export type TemplateBundlePart = 'mihomo' | 'singbox' | 'transparent'
+
+// Shareable bundle of templates and device-independent transparent proxy settings
+export interface TemplateBundle {
+  kind: 'proxystation.template-bundle'
+  version: number
+  name?: string
+  description?: string
+  exportedAt: string
+  mihomo?: unknown
+  singbox?: unknown
+  transparent?: {
+    dnsHijack: boolean
+    ipv6: boolean
+    blockQuic: boolean
+    direct: unknown
+    watchdog: TransparentWatchdog
+    nftTemplate?: string // empty means the default nftables template
+  }
+}
+
+export interface TemplateBundleImportResult {
+  sourceVersion: number
+  migrated: boolean // bundle was upgraded from an older format
+  imported: TemplateBundlePart[]
+  warnings: string[]
+  bundle: TemplateBundle
+}
+
+export interface RuleTestRequest {
+  host: string
+  port?: number
+  network?: 'tcp' | 'udp'
+  sourceIp?: string
+  noResolve?: boolean
+}
+
+export interface RuleTestResult {
+  matched: boolean
+  index: number
+  rule: string
+  type: string
+  payload: string
+  proxy: string
+  outbound?: string
+  resolvedIps?: string[]
+  skipped: { index: number; rule: string; reason: string }[]
+}
+
+export interface ProfileShare {
+  enabled: boolean
+  token: string
+  updateInterval: number
+  urls?: { mihomo: string; singbox: string }
+}
+
+export interface LogFile {
+  name: string
+  size: number
+  modTime: string
+  compressed: boolean
+  current: boolean
+}
+
+// Router-mode interception: only traffic entering the LAN interfaces is proxied (all when empty), WAN is never touched
+export interface TransparentInterfaces {
+  lan: string[]
+  wan: string[]
+  excludeDocker: boolean // docker0, veth*, docker network bridges
+  excludeOverlay: boolean // tailscale*, zt* (ZeroTier)
+  exclude: string[] // extra interfaces, trailing * wildcard allowed
+}
+
+// Destinations that always go direct in transparent mode (loopback/link-local/multicast are built in)
+export interface DirectIPSet {
+  name: string
+  enabled: boolean
+  cidrs: string[]
+}
+
+export interface TransparentDirect {
+  localNets: string[] | null // null resets to defaults
+  ports: string[] // e.g. "22", "6881-6889"
+  ipSets: DirectIPSet[]
+}
+
+// Split tunneling for local processes (output chain only): by user/UID or cgroup v2 path
+export interface ProcessRule {
+  name: string
+  type: 'uid' | 'cgroup'
+  value: string // user name, UID, or e.g. system.slice/qbittorrent.service
+  enabled: boolean
+}
+
+export interface TransparentProcesses {
+  mode: 'exclude' | 'include' // exclude: listed processes go direct; include: only listed processes are proxied
+  rules: ProcessRule[]
+}
+
+// Relay chain: traffic goes through hops in order (entry -> exit). The entry may be a node or a group, the rest must be nodes.
+// dialer: mihomo dialer-proxy copies / sing-box detour; relay: mihomo relay group (sing-box still uses detour)
+export interface ProxyChain {
+  name: string
+  mode: 'dialer' | 'relay'
+  hops: string[]
+  groups?: string[] // groups the chain is appended to
+  enabled: boolean
+}
+
+// Custom DNS stored in the config template; empty fields keep the generated defaults
+export interface TemplateDNS {
+  defaultNameserver?: string[] // must be IPs
+  nameserver?: string[]
+  fallback?: string[]
+  proxyServerNameserver?: string[]
+  directNameserver?: string[]
+  nameserverPolicy?: Record<string, string[]>
+  fakeIpRange?: string
+  fakeIpFilter?: string[]
+  fakeIpFilterMode?: 'blacklist' | 'whitelist'
+  fakeIpFilterPresets?: string[] | null // null: fakeIpFilter replaces the built-in list entirely
+  hosts?: Record<string, string>
+  respectRules?: boolean
+}
+
+// Built-in fake-ip exclusion categories; domains in them resolve to real IPs
+export interface FakeIPFilterPreset {
+  id: string
+  name: string
+  description: string
+  domains: string[]
+  default: boolean
+}
+
+export interface FakeIPFilterSettings {
+  mode: 'blacklist' | 'whitelist'
+  presets: string[]
+  custom: string[] // mihomo fake-ip-filter syntax (+.example.com, *.lan, geosite:cn)
+}
+
+export interface DNSTestResult {
+  server: string // "core" when resolved through the running core
+  latency: number
+  answers: { type: string; data: string; ttl: number }[]
+  error?: string
+}
+
+// Node tags: built-in region codes (flag emoji first, then region keywords) plus custom keyword/regex rules
+export interface NodeTagRule {
+  tag: string
+  enabled: boolean
+  keywords?: string[]
+  regex?: string
+}
+
+export interface NodeTagConfig {
+  regions: boolean
+  rules: NodeTagRule[]
+}
+
+// Node name transformations applied before generating mihomo and sing-box configs
+export interface NodeRenameRule {
+  pattern: string
+  replace: string
+}
+
+export interface NodeRenameConfig {
+  enabled: boolean
+  stripPrefixes: string[]
+  rules: NodeRenameRule[]
+  flag: 'keep' | 'add' | 'strip'
+  numbering: boolean
+  dedupe: boolean
+}
+
+// Merge nodes sharing the same server, port and credential across subscriptions
+export interface NodeDedupConfig {
+  enabled: boolean
+  strategy: 'first' | 'latency' | 'suffix'
+}
+
+export interface NodeDedupGroup {
+  endpoint: string // type and server:port, without credentials
+  kept: string[]
+  removed: string[]
+}
+
+export interface NodeDedupReport {
+  strategy: string
+  total: number
+  result: number
+  renamed: number
+  groups: NodeDedupGroup[]
+  updatedAt: string
+}
+
+// Interception rules left by other firewall managers (fw4/OpenClash, v2rayA, iptables-legacy TPROXY ...)
+export type FirewallConflictPolicy = 'warn' | 'refuse' | 'ignore'
+
+// 透明代理规则导出（与启动核心时应用的规则一致）
+export interface TransparentExport {
+  mode: 'tproxy' | 'redirect'
+  scope: 'local' | 'router'
+  port: number
+  marks: { mark: number; bypassMark: number; tableId: number }
+  nftScript: string
+  ipRules: string[] // 策略路由命令（仅 tproxy）
+  sysctl: string[] // 内核参数（仅路由器模式）
+  teardown: string[]
+  script: string // shell 脚本，参数 stop 时清除
+}
+
+// Variables available to the nftables rule template (Go text/template syntax)
+export interface NftTemplateData {
+  mode: 'tproxy' | 'redirect'
+  scope: 'local' | 'router'
+  tproxy: boolean
+  router: boolean
+  port: number
+  dnsPort: number
+  mark: number
+  bypassMark: number
+  tableId: number
+  ipv6: boolean
+  dnsHijack: boolean
+  blockQuic: boolean
+  egressInterface: string // empty when unset or when it is the default-route interface
+  sets: string
+  interfaceFilter: string
+  directRules: string
+  processFilter: string
+}
+
+export interface NftTemplate {
+  template: string
+  custom: boolean // false when the built-in default is used
+  default: string
+  variables: NftTemplateData
+}
+
+export interface TransparentWatchdog {
+  enabled: boolean
+  timeout: number // seconds of continuous failure before reverting to off
+  interval: number // probe interval in seconds
+  url: string // empty = generate_204
+  probeOnApply?: boolean // also probe before/after applying transparent rules (off for offline/LAN-only setups)
+}
+
+export interface TransparentWatchdogStatus extends TransparentWatchdog {
+  active: boolean
+  lastProbe?: string
+  lastError?: string
+  failingSince?: string
+  lastRevert?: { time: string; mode: string; scope: string; reason: string }
+}
+
+export interface FirewallConflict {
+  source: string
+  kind: string // nftables / iptables-legacy / ip6tables-legacy
+  table: string
+  chain: string
+  detail: string
+  severity: 'error' | 'warning' // error: stacked interception rules, will loop
+}
+
+export interface ExternalCore {
+  coreType?: string
+  version?: string
+  controller?: string
+  reachable: boolean
+  authFailed?: boolean
+  process?: { pid: number; name: string; exe?: string; cmdline?: string }
+  adoptable: boolean
+  reason?: string
 }
 
 export const proxyApi = {
@@ -40,9 +574,167 @@ export const proxyApi = {
   start: () => api.post('/proxy/start'),
   stop: () => api.post('/proxy/stop'),
   restart: () => api.post('/proxy/restart'),
+  reload: () => api.post<{ method: 'reload' | 'restart' }>('/proxy/reload'),
+  detectExternalCore: () => api.get<ExternalCore | null>('/proxy/external'),
+  adoptExternalCore: (controller?: string, secret?: string) =>
+    api.post<ExternalCore>('/proxy/adopt', { controller, secret }),
+  releaseExternalCore: () => api.post('/proxy/adopt/release'),
   setMode: (mode: string) => api.put('/proxy/mode', { mode }),
-  setTransparentMode: (mode: TransparentMode, scope: ProxyScope) =>
-    api.put('/proxy/transparent', { mode, scope }),
+  setTransparentMode: (
+    mode: TransparentMode,
+    scope: ProxyScope,
+    dnsHijack?: boolean,
+    ipv6?: boolean,
+    blockQuic?: boolean
+  ) => api.put('/proxy/transparent', { mode, scope, dnsHijack, ipv6, blockQuic }),
+  getTransparentInterfaces: () => api.get<TransparentInterfaces>('/proxy/transparent/interfaces'),
+  setTransparentInterfaces: (ifaces: TransparentInterfaces) =>
+    api.put<TransparentInterfaces>('/proxy/transparent/interfaces', ifaces),
+  getTransparentDirect: () =>
+    api.get<{ direct: TransparentDirect; builtin: string[]; defaults: string[] }>('/proxy/transparent/direct'),
+  setTransparentDirect: (direct: TransparentDirect) =>
+    api.put<TransparentDirect>('/proxy/transparent/direct', direct),
+  getTransparentProcesses: () => api.get<TransparentProcesses>('/proxy/transparent/processes'),
+  setTransparentProcesses: (processes: TransparentProcesses) =>
+    api.put<TransparentProcesses>('/proxy/transparent/processes', processes),
+  getChains: () => api.get<ProxyChain[]>('/proxy/chains'),
+  setChains: (chains: ProxyChain[]) => api.put<ProxyChain[]>('/proxy/chains', chains),
+  getNodeTags: () =>
+    api.get<{ config: NodeTagConfig; regions: { code: string; name: string; icon: string }[] }>('/proxy/nodes/tags'),
+  setNodeTags: (config: NodeTagConfig) => api.put<NodeTagConfig>('/proxy/nodes/tags', config),
+  previewNodeTags: () =>
+    api.get<{ nodes: { name: string; tags: string[] }[]; counts: Record<string, number> }>('/proxy/nodes/tags/preview'),
+  getNodeRename: () => api.get<NodeRenameConfig>('/proxy/nodes/rename'),
+  setNodeRename: (config: NodeRenameConfig) => api.put<NodeRenameConfig>('/proxy/nodes/rename', config),
+  previewNodeRename: () =>
+    api.get<{ nodes: { original: string; name: string }[]; changed: number }>('/proxy/nodes/rename/preview'),
+  // preview=true recomputes the report from current nodes instead of returning the last one
+  getNodeDedup: (preview = false) =>
+    api.get<{ config: NodeDedupConfig; report: NodeDedupReport | null }>('/proxy/nodes/dedup', {
+      params: preview ? { preview: true } : undefined,
+    }),
+  setNodeDedup: (config: NodeDedupConfig) => api.put<NodeDedupConfig>('/proxy/nodes/dedup', config),
+  getFirewallConflicts: () =>
+    api.get<{
+      policy: FirewallConflictPolicy
+      conflicts: FirewallConflict[]
+      markConflicts: string[]
+      intercepting: boolean
+    }>('/proxy/transparent/conflicts'),
+  setFirewallConflictPolicy: (policy: FirewallConflictPolicy) =>
+    api.put<{ policy: FirewallConflictPolicy }>('/proxy/transparent/conflicts/policy', { policy }),
+  // mode / scope 默认为当前保存的设置
+  exportTransparentRules: (params?: { mode?: 'tproxy' | 'redirect'; scope?: 'local' | 'router' }) =>
+    api.get<TransparentExport>('/proxy/transparent/export', { params }),
+  getNftTemplate: () => api.get<NftTemplate>('/proxy/transparent/template'),
+  // Empty template resets to the built-in default
+  setNftTemplate: (template: string) => api.put<{ custom: boolean }>('/proxy/transparent/template', { template }),
+  getTransparentWatchdog: () => api.get<TransparentWatchdogStatus>('/proxy/transparent/watchdog'),
+  setTransparentWatchdog: (watchdog: TransparentWatchdog) =>
+    api.put<TransparentWatchdogStatus>('/proxy/transparent/watchdog', watchdog),
   getConfig: () => api.get<ProxyConfig>('/proxy/config'),
+  // Core API (external-controller) secret, auto-generated when unset; admin only
+  getSecret: () => api.get<{ secret: string; controller: string }>('/proxy/secret'),
+  rotateSecret: () => api.post<{ secret: string; restarted: boolean }>('/proxy/secret/rotate'),
   updateConfig: (config: ProxyConfig) => api.put('/proxy/config', config),
+  validateConfig: (content?: string) =>
+    api.post<ConfigValidationResult>('/proxy/config/validate', content ? { content } : {}),
+  dryRunGenerate: () => api.post<ConfigDryRun>('/proxy/generate?dryRun=true', { nodes: [] }),
+  getConfigHistory: () => api.get<ConfigHistoryEntry[]>('/proxy/config/history'),
+  diffConfigHistory: (id: string, against?: string) =>
+    api.get<{ diff: string; changed: boolean }>(
+      `/proxy/config/history/${id}/diff${against ? `?against=${against}` : ''}`
+    ),
+  rollbackConfig: (id: string) =>
+    api.post<{ entry: ConfigHistoryEntry; method: '' | 'reload' | 'restart' }>(`/proxy/config/history/${id}/rollback`),
+  getProfileShare: () => api.get<ProfileShare>('/proxy/config/share'),
+  setProfileShare: (enabled: boolean, updateInterval?: number) =>
+    api.put<ProfileShare>('/proxy/config/share', { enabled, updateInterval }),
+  rotateProfileShareToken: () => api.post<ProfileShare>('/proxy/config/share/rotate'),
+  getProfiles: () => api.get<ConfigProfile[]>('/proxy/profiles'),
+  createProfile: (name: string, description?: string, from?: string) =>
+    api.post<ConfigProfile>('/proxy/profiles', { name, description, from }),
+  updateProfile: (id: string, name: string, description?: string) =>
+    api.put(`/proxy/profiles/${id}`, { name, description }),
+  deleteProfile: (id: string) => api.delete(`/proxy/profiles/${id}`),
+  cloneProfile: (id: string, name: string) => api.post<ConfigProfile>(`/proxy/profiles/${id}/clone`, { name }),
+  activateProfile: (id: string) => api.post<{ restarted: boolean }>(`/proxy/profiles/${id}/activate`),
+  // country / asn: 只返回目标位于该国家或 ASN 的连接
+  getConnections: (filter?: { country?: string; asn?: string }) =>
+    api.get<ConnectionsSnapshot>('/proxy/connections', { params: filter }),
+  closeConnection: (id: string) => api.delete(`/proxy/connections/${encodeURIComponent(id)}`),
+  getTrafficStats: (range = '24h') => api.get<TrafficHistory>(`/proxy/stats/traffic?range=${range}`),
+  // Rule hits sampled from core connections (mihomo only); sort=hits orders by hit count instead of template order
+  getRuleStats: (range = '24h', sort?: 'hits') =>
+    api.get<RuleStatsReport>('/proxy/stats/rules', { params: { range, sort } }),
+  resetRuleStats: () => api.delete('/proxy/stats/rules'),
+  getLatencyStats: (range = '1h') => api.get<LatencyGroupStats[]>(`/proxy/stats/latency?range=${range}`),
+  getGroupLatency: (group: string, range = '1h') =>
+    api.get<LatencyGroupStats>(`/proxy/stats/latency?group=${encodeURIComponent(group)}&range=${range}`),
+  getProviders: () => api.get<ProviderInfo[]>('/proxy/providers'),
+  refreshProvider: (kind: 'proxy' | 'rule', name: string) =>
+    api.post(`/proxy/providers/${kind}/${encodeURIComponent(name)}/refresh`),
+  refreshProviders: (staleOnly = false) =>
+    api.post<{ refreshed: string[]; failed: Record<string, string> }>(
+      `/proxy/providers/refresh${staleOnly ? '?stale=true' : ''}`
+    ),
+  healthCheckProvider: (name: string) => api.post(`/proxy/providers/proxy/${encodeURIComponent(name)}/healthcheck`),
+  getProviderSchedule: () => api.get<ProviderRefreshSchedule>('/proxy/providers/schedule'),
+  setProviderSchedule: (schedule: ProviderRefreshSchedule) =>
+    api.put<ProviderRefreshSchedule>('/proxy/providers/schedule', schedule),
+  getConfigOverride: () => api.get<ConfigOverride>('/proxy/config/override'),
+  updateConfigOverride: (override: Pick<ConfigOverride, 'enabled' | 'content'>) =>
+    api.put<ConfigOverride>('/proxy/config/override', override),
+  testRule: (req: RuleTestRequest) => api.post<RuleTestResult>('/proxy/rules/test', req),
+  // 规则可以用 ID 或序号引用
+  insertRule: (rule: TemplateRule, index?: number) =>
+    api.post<TemplateRule>('/proxy/template/rules', { rule, index }),
+  updateRule: (ref: string | number, rule: TemplateRule) =>
+    api.put<TemplateRule>(`/proxy/template/rules/${encodeURIComponent(String(ref))}`, rule),
+  moveRule: (ref: string | number, index: number) =>
+    api.post<TemplateRule[]>(`/proxy/template/rules/${encodeURIComponent(String(ref))}/move`, { index }),
+  deleteRule: (ref: string | number) => api.delete(`/proxy/template/rules/${encodeURIComponent(String(ref))}`),
+  toggleRules: (ids: string[], enabled: boolean) =>
+    api.post<{ changed: number }>('/proxy/template/rules/toggle', { ids, enabled }),
+  importTemplate: (content: string, preview = false) =>
+    api.post<TemplateImportResult>(`/proxy/template/import${preview ? '?preview=true' : ''}`, { content }),
+  exportTemplateBundle: (options: { parts?: TemplateBundlePart[]; name?: string; description?: string } = {}) =>
+    api.get<TemplateBundle>('/proxy/template/bundle', {
+      params: { parts: options.parts?.join(','), name: options.name, description: options.description },
+    }),
+  // Accepts bundles from older versions (including a bare template export); the server migrates them
+  importTemplateBundle: (bundle: unknown, options: { parts?: TemplateBundlePart[]; preview?: boolean } = {}) =>
+    api.post<TemplateBundleImportResult>('/proxy/template/bundle', bundle, {
+      params: { parts: options.parts?.join(','), preview: options.preview ? 'true' : undefined },
+    }),
+  getTemplateDNS: () => api.get<TemplateDNS>('/proxy/template/dns'),
+  updateTemplateDNS: (dns: TemplateDNS) => api.put<TemplateDNS>('/proxy/template/dns', dns),
+  getFakeIPFilter: () =>
+    api.get<{ settings: FakeIPFilterSettings; presets: FakeIPFilterPreset[]; effective: string[] }>(
+      '/proxy/template/dns/fake-ip-filter'
+    ),
+  setFakeIPFilter: (settings: FakeIPFilterSettings) =>
+    api.put<FakeIPFilterSettings>('/proxy/template/dns/fake-ip-filter', settings),
+  testDNS: (domain: string, type: 'A' | 'AAAA' = 'A', server?: string) =>
+    api.post<{ domain: string; type: string; core: DNSTestResult | null; upstreams: DNSTestResult[] }>(
+      '/proxy/template/dns/test',
+      { domain, type, server }
+    ),
+  // since/until accept RFC3339, "YYYY-MM-DD HH:mm:ss", unix seconds or a relative duration such as "2h"
+  getLogs: (params: {
+    limit?: number
+    level?: 'all' | 'info' | 'warn' | 'error'
+    since?: string
+    until?: string
+    q?: string
+    regex?: boolean
+  } = {}) => api.get<string[]>('/proxy/logs', { params }),
+  getLogFiles: () => api.get<LogFile[]>('/proxy/logs/files'),
+  // Download one log file, or all of them as a tar.gz when file is omitted
+  downloadLogs: (file?: string) =>
+    api.get<Blob>('/proxy/logs/download', {
+      params: file ? { file } : undefined,
+      responseType: 'blob',
+      timeout: 300000,
+    }),
 }
@@ -55,6 +55,19 @@ export interface SnifferSettings {
   skipDomain: string[]
 }
 
+// 管理界面监听设置（重启 ProxyStation 后生效）
+export interface WebSettings {
+  listenAddress: string // empty = server.host from config.yaml
+  port: number // 0 = server.port from config.yaml
+  tls: boolean
+  certFile: string // PEM paths; both empty = auto-generated self-signed certificate
+  keyFile: string
+  httpRedirectPort: number // plain HTTP port redirecting to HTTPS, 0 = off
+  // Access control (loopback is always allowed)
+  allowedCidrs: string[] // source CIDRs / IPs allowed to reach the UI and API, empty = any; applies immediately
+  clientCaFile: string // PEM CA; when set, remote clients need a certificate signed by it (requires tls)
+}
+
 // 认证用户
 export interface AuthUser {
   username: string
@@ -83,13 +96,22 @@ export interface ProxySettings {
   allowLan: boolean
   bindAddress: string
   autoStart: boolean
-  autoStartDelay: number
+  autoStartDelay: number // max seconds to wait for the network before auto start
+  drainTimeout: number  // seconds
 
   // 运行模式
   mode: string
   logLevel: string
   ipv6: boolean
 
+  // 日志文件
+  logMaxSize: number     // MB
+  logMaxAge: number      // days
+  logMaxBackups: number
+
+  // 访问日志
+  accessLog: boolean
+
   // 性能优化
   unifiedDelay: boolean
   tcpConcurrent: boolean
@@ -116,13 +138,18 @@ export interface ProxySettings {
   etagSupport: boolean
 
   // 网络接口
-  interfaceName: string
-  routingMark: number
+  interfaceName: string // core outbound interface (multi-WAN / VPN underlay); nftables lets its traffic through
+  routingMark: number // core outbound mark, 0 = 255
+
+  // Transparent proxy fwmark / policy routing table (change if they clash with other tools)
+  transparentMark: number
+  transparentTable: number
 
   // 子设置
   dns: DNSSettings
   tun: TUNSettings
   sniffer: SnifferSettings
+  web: WebSettings
 }
 
 // API
@@ -0,0 +1,39 @@
+import api from './client'
+import type { ProxyScope, TransparentMode } from './proxy'
+
+export type ScheduleAction = 'start' | 'stop' | 'transparent'
+
+export interface ScheduleProxyState {
+  running: boolean
+  mode: string
+  scope: string
+}
+
+// Weekly schedule. With `end` set it's a window: the action applies inside it and the
+// previous state is restored afterwards; without `end` it fires once at `start`.
+export interface Schedule {
+  id: string
+  name: string
+  enabled: boolean
+  days: number[] // 0 = Sunday, empty = every day
+  start: string // HH:MM
+  end: string // HH:MM, earlier than start = crosses midnight, 24:00 = end of day
+  action: ScheduleAction
+  mode?: TransparentMode
+  scope?: ProxyScope
+  active: boolean
+  previous?: ScheduleProxyState
+  lastRun?: number
+  lastError?: string
+}
+
+export type ScheduleInput = Omit<Schedule, 'id' | 'active' | 'previous' | 'lastRun' | 'lastError'>
+
+export const scheduleApi = {
+  list: () => api.get<Schedule[]>('/schedules'),
+  get: (id: string) => api.get<Schedule>(`/schedules/${id}`),
+  add: (data: Partial<ScheduleInput>) => api.post<Schedule>('/schedules', data),
+  update: (id: string, data: ScheduleInput) => api.put<Schedule>(`/schedules/${id}`, data),
+  delete: (id: string) => api.delete(`/schedules/${id}`),
+  run: (id: string) => api.post(`/schedules/${id}/run`),
+}
@@ -151,15 +151,15 @@ export const singboxApi = {
     return res.data
   },
 
-  // 获取配置预览
-  getConfigPreview: async (): Promise<string> => {
-    const res = await client.get('/proxy/singbox/preview')
+  // 获取配置预览（凭据默认脱敏，reveal 为 true 时返回完整内容）
+  getConfigPreview: async (reveal = false): Promise<string> => {
+    const res = await client.get('/proxy/singbox/preview', { params: reveal ? { reveal: true } : undefined })
     return res.data.data.content
   },
 
-  // 获取配置下载 URL
+  // 获取配置下载 URL（下载完整配置）
   getDownloadUrl: (): string => {
-    return '/api/proxy/singbox/download'
+    return '/api/proxy/singbox/download?reveal=true'
   },
 
   // 保存设置到本地存储
@@ -1,3 +1,5 @@
+import type { ConfigOverride } from './proxy'
+
 // Sing-Box 配置模板 - 代理组和规则定义
 // 参考 singforge-web 项目的规则配置
 
@@ -24,6 +26,8 @@ export interface SingBoxProxyGroup {
   url?: string
   interval?: string
   tolerance?: number
+  // outbounds 为空时按节点过滤正则动态填充
+  filter?: string
 }
 
 // Sing-Box 规则定义
@@ -41,7 +45,9 @@ export interface SingBoxRule {
   domain_keyword?: string[]
   domain_regex?: string[]
   ip_cidr?: string[]
+  ip_is_private?: boolean
   source_ip_cidr?: string[]
+  process_name?: string[]
   rule_set?: string | string[]
   clash_mode?: string
   // 动作
@@ -613,6 +619,7 @@ export interface SingBoxTemplate {
   proxyGroups: SingBoxProxyGroup[]
   rules: SingBoxRule[]
   ruleSets: SingBoxRuleSet[]
+  final?: string
 }
 
 // 默认模板
@@ -668,3 +675,51 @@ export const resetSingBoxTemplate = async (): Promise<SingBoxTemplate> => {
   }
   return defaultSingBoxTemplate
 }
+
+// 从 Mihomo 模板生成 Sing-Box 模板，preview 为 true 时只返回转换结果不保存
+export const convertSingBoxTemplateFromClash = async (
+  preview = false
+): Promise<{ template: SingBoxTemplate; warnings: string[] } | null> => {
+  try {
+    const res = await fetch(`${API_BASE}/singbox/template/convert-from-clash${preview ? '?preview=true' : ''}`, {
+      method: 'POST'
+    })
+    const data = await res.json()
+    if (data.code === 0 && data.data) {
+      return data.data
+    }
+  } catch (e) {
+    console.error('转换 Sing-Box 模板失败:', e)
+  }
+  return null
+}
+
+// 加载配置覆写（JSON Merge Patch，支持 append_outbounds、route.prepend_rules 等数组追加键）
+export const loadSingBoxOverride = async (): Promise<ConfigOverride> => {
+  try {
+    const res = await fetch(`${API_BASE}/singbox/override`)
+    const data = await res.json()
+    if (data.code === 0 && data.data) {
+      return data.data as ConfigOverride
+    }
+  } catch (e) {
+    console.error('加载 Sing-Box 配置覆写失败:', e)
+  }
+  return { enabled: false, content: '' }
+}
+
+// 保存配置覆写，失败时返回错误信息
+export const saveSingBoxOverride = async (override: Pick<ConfigOverride, 'enabled' | 'content'>): Promise<string | null> => {
+  try {
+    const res = await fetch(`${API_BASE}/singbox/override`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(override)
+    })
+    const data = await res.json()
+    return data.code === 0 ? null : data.message
+  } catch (e) {
+    console.error('保存 Sing-Box 配置覆写失败:', e)
+    return String(e)
+  }
+}
@@ -1,4 +1,5 @@
 import api from './client'
+import type { ErrorCategory } from './client'
 
 export interface SystemConfig {
   autoStart: boolean
@@ -40,9 +41,75 @@ export interface GeoIPInfo {
   org: string
 }
 
+export type SystemdUnit = 'backend' | 'core'
+
+export interface SystemdUnitStatus {
+  name: string
+  installed: boolean
+  enabled: boolean
+  active: boolean
+  activeState: string
+  subState: string
+  mainPid: number
+  properties: Record<string, string>
+}
+
+export interface AccessEntry {
+  time: string
+  method: string
+  path: string
+  route?: string
+  status: number
+  latency: number // ms
+  clientIp: string
+  size: number
+}
+
+export interface ModeSupport {
+  supported: boolean
+  reasons: string[] // Why the mode cannot be used
+  warnings: string[]
+}
+
+// Privileges and kernel features, used to disable unsupported transparent modes
+export interface SystemCapabilities {
+  os: string
+  kernel?: string
+  root: boolean
+  netAdmin: boolean
+  nftPath?: string
+  ipPath?: string
+  modules?: Record<string, 'loaded' | 'builtin' | 'available' | 'missing' | 'unknown'>
+  tproxy: boolean
+  tproxyError?: string
+  ipForward: boolean
+  ipv6Forward: boolean
+  tun: boolean
+  tunError?: string
+  modes: Record<'tproxy' | 'redirect' | 'tun' | 'system', ModeSupport>
+}
+
+export interface ErrorCatalogEntry {
+  code: string
+  category: ErrorCategory
+  status: number
+  hint: string
+}
+
 export const systemApi = {
   // Get system info (version etc)
   getInfo: () => api.get<SystemInfo>('/system/info'),
+
+  // OpenAPI 3 spec generated from the registered routes (no auth required)
+  getOpenAPISpec: () => api.get<Record<string, unknown>>('/openapi.json'),
+
+  // API access log (toggled via proxy settings accessLog)
+  getAccessLog: (limit = 200, minStatus?: number) =>
+    api.get<{ enabled: boolean; entries: AccessEntry[] }>('/system/access-log', { params: { limit, minStatus } }),
+  clearAccessLog: () => api.delete('/system/access-log'),
+
+  // Error code catalog: stable codes with category, typical HTTP status and remediation hint
+  getErrorCatalog: () => api.get<ErrorCatalogEntry[]>('/system/errors'),
   
   // Get system resources (CPU, memory, disk)
   getResources: () => api.get<SystemResources>('/system/resources'),
@@ -72,4 +139,12 @@ export const systemApi = {
   
   // 出口 IP 信息
   getGeoIP: (lang: string = 'zh') => api.get<GeoIPInfo>(`/system/geoip?lang=${lang}`),
+
+  // systemd
+  getSystemdStatus: () => api.get<SystemdUnitStatus[]>('/system/systemd'),
+  installSystemdUnit: (options: { unit: SystemdUnit; enable?: boolean; start?: boolean; socketActivation?: boolean }) =>
+    api.post('/system/systemd/install', options),
+  uninstallSystemdUnit: (unit: SystemdUnit) => api.post('/system/systemd/uninstall', { unit }),
+
+  getCapabilities: () => api.get<SystemCapabilities>('/system/capabilities'),
 }
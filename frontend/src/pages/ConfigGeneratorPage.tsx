@@ -88,16 +88,22 @@ interface ProxyGroup {
   interval?: number
   tolerance?: number
   lazy?: boolean
+  strategy?: 'consistent-hashing' | 'round-robin' | 'sticky-sessions' // load-balance only
   filter?: string
   useAll?: boolean
+  tags?: string[] // auto-filled with nodes carrying any of these tags (e.g. HK, SG)
+  excludeFilter?: string // regex, matching nodes are dropped
+  maxMultiplier?: number // drop nodes whose rate marker (x3, 2倍 ...) is higher; 0 = no limit
 }
 
 interface Rule {
+  id?: string
   type: string
   payload: string
   proxy: string
   noResolve: boolean
   description: string
+  disabled?: boolean
 }
 
 interface RuleProvider {
@@ -440,8 +446,9 @@ function ProxyGroupsTab({
     try {
       await api.put('/proxy/template/groups', groups)
       setTemplate({ ...template, proxyGroups: groups })
-    } catch {
-      // Ignore
+    } catch (err) {
+      // 后端校验成员引用和循环引用，失败时不更新本地模板
+      toast.error(err instanceof Error ? err.message : '保存代理组失败')
     }
   }
 
@@ -1193,7 +1200,16 @@ function PreviewTab() {
   }, [activeCore])
 
   const handleCopy = async () => {
-    await navigator.clipboard.writeText(configContent)
+    // 预览中的凭据已脱敏，复制时获取完整配置
+    let content = configContent
+    try {
+      content = activeCore === 'singbox'
+        ? await singboxApi.getConfigPreview(true)
+        : (await api.get<{ content: string }>('/proxy/config/preview', { params: { reveal: true } })).content
+    } catch {
+      // 获取失败时复制预览内容
+    }
+    await navigator.clipboard.writeText(content)
     setCopied(true)
     setTimeout(() => setCopied(false), 2000)
   }
@@ -1336,7 +1352,54 @@ function EditGroupDialog({
             />
           </div>
 
-          {(group.type === 'url-test' || group.type === 'fallback') && (
+          {group.type === 'load-balance' && (
+            <div className="grid grid-cols-3 gap-3">
+              <div>
+                <label className={cn(
+                  'block text-sm font-medium mb-1.5',
+                  themeStyle === 'apple-glass' ? 'text-slate-700' : 'text-slate-300'
+                )}>负载均衡策略</label>
+                <select
+                  value={group.strategy || 'consistent-hashing'}
+                  onChange={(e) => onChange({ ...group, strategy: e.target.value as ProxyGroup['strategy'] })}
+                  className="form-input"
+                >
+                  <option value="consistent-hashing">consistent-hashing - 一致性哈希</option>
+                  <option value="round-robin">round-robin - 轮询</option>
+                  <option value="sticky-sessions">sticky-sessions - 粘性会话</option>
+                </select>
+              </div>
+              <div>
+                <label className={cn(
+                  'block text-sm font-medium mb-1.5',
+                  themeStyle === 'apple-glass' ? 'text-slate-700' : 'text-slate-300'
+                )}>健康检查地址</label>
+                <input
+                  type="text"
+                  value={group.url || ''}
+                  onChange={(e) => onChange({ ...group, url: e.target.value })}
+                  className="form-input font-mono"
+                  placeholder="https://www.gstatic.com/generate_204"
+                />
+              </div>
+              <div>
+                <label className={cn(
+                  'block text-sm font-medium mb-1.5',
+                  themeStyle === 'apple-glass' ? 'text-slate-700' : 'text-slate-300'
+                )}>检查间隔（秒）</label>
+                <input
+                  type="number"
+                  min={0}
+                  value={group.interval ?? ''}
+                  onChange={(e) => onChange({ ...group, interval: e.target.value === '' ? undefined : Number(e.target.value) })}
+                  className="form-input"
+                  placeholder="300"
+                />
+              </div>
+            </div>
+          )}
+
+          {(group.type === 'url-test' || group.type === 'fallback' || group.type === 'load-balance') && (
             <div>
               <label className={cn(
                 'block text-sm font-medium mb-1.5',